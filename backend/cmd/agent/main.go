@@ -7,27 +7,42 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/clarateach/backend/internal/agentapi"
+	"github.com/clarateach/backend/internal/logging"
 	"github.com/clarateach/backend/internal/network"
 	"github.com/clarateach/backend/internal/orchestrator"
+	"github.com/clarateach/backend/internal/tracing"
 	"github.com/clarateach/backend/internal/tunnel"
 )
 
 const (
-	defaultPort     = "9090"
-	defaultCapacity = 50
+	defaultPort          = "9090"
+	defaultCapacity      = 50
+	defaultRecordingsDir = "/var/lib/clarateach/recordings"
 )
 
+// logger is set up first thing in main.
+var logger *slog.Logger
+
+// fatal logs an error that stops the agent and exits.
+func fatal(msg string, args ...any) {
+	logger.Error(msg, args...)
+	os.Exit(1)
+}
+
 func main() {
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	// Structured logging; the standard log package is routed through it too
+	logging.Setup(logging.ConfigFromEnv())
+	logger = logging.Component("agent")
 
 	// Load configuration from environment
 	port := getEnv("PORT", defaultPort)
@@ -36,14 +51,15 @@ func main() {
 	capacity := getCapacity()
 
 	// Log startup info
-	log.Printf("Starting ClaraTeach Worker Agent")
-	log.Printf("  Worker ID: %s", workerID)
-	log.Printf("  Port: %s", port)
-	log.Printf("  Capacity: %d VMs", capacity)
-	if agentToken != "" {
-		log.Printf("  Auth: enabled")
-	} else {
-		log.Printf("  Auth: disabled (no AGENT_TOKEN set)")
+	logger.Info("starting ClaraTeach Worker Agent", "worker_id", workerID, "port", port, "capacity", capacity, "auth", agentToken != "")
+	if agentToken == "" {
+		logger.Warn("auth disabled; no AGENT_TOKEN set")
+	}
+
+	// Initialize tracing; spans join traces propagated by the control plane
+	shutdownTracing, err := tracing.Setup(context.Background(), getTracingConfig())
+	if err != nil {
+		fatal("failed to initialize tracing", "error", err)
 	}
 
 	// Setup MicroVM bridge network (replaces setup-microvm-network.sh)
@@ -55,7 +71,7 @@ func main() {
 		bridgeCfg.BridgeIP = bridgeIP
 	}
 	if err := network.SetupBridge(bridgeCfg); err != nil {
-		log.Fatalf("FATAL: Failed to setup bridge network: %v", err)
+		fatal("failed to set up bridge network", "error", err)
 	}
 
 	// Initialize Firecracker provider
@@ -79,14 +95,19 @@ func main() {
 
 	provider, err := orchestrator.NewFirecrackerProviderWithConfig(fcConfig)
 	if err != nil {
-		log.Fatalf("Failed to create Firecracker provider: %v", err)
+		fatal("failed to create Firecracker provider", "error", err)
 	}
 
 	// Create API server
 	server := agentapi.NewServer(provider, agentapi.Config{
-		AgentToken: agentToken,
-		WorkerID:   workerID,
-		Capacity:   capacity,
+		AgentToken:    agentToken,
+		WorkerID:      workerID,
+		Capacity:      capacity,
+		DiskPath:      fcConfig.SocketDir,
+		MetricsToken:  getMetricsToken(),
+		RecordingsDir: getRecordingsDir(),
+		ResumeGrace:   getResumeGrace(),
+		PreviewPorts:  getPreviewPorts(),
 	})
 
 	// Create HTTP server
@@ -100,9 +121,9 @@ func main() {
 
 	// Start server in goroutine
 	go func() {
-		log.Printf("Listening on :%s", port)
+		logger.Info("listening", "port", port)
 		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("HTTP server error: %v", err)
+			fatal("HTTP server error", "error", err)
 		}
 	}()
 
@@ -114,32 +135,33 @@ func main() {
 	backendURL, backendErr := getGCPMetadata("backend-url")
 
 	if devMode {
-		log.Printf("DEV_MODE=true, skipping tunnel setup")
+		logger.Info("DEV_MODE=true, skipping tunnel setup")
 	} else {
 		// Production mode - tunnel is required
 		if workshopID == "" {
-			log.Fatalf("FATAL: workshop-id metadata not found (error: %v). Set DEV_MODE=true for local development.", workshopErr)
+			fatal("workshop-id metadata not found; set DEV_MODE=true for local development", "error", workshopErr)
 		}
 		if backendURL == "" {
-			log.Fatalf("FATAL: backend-url metadata not found (error: %v). Set DEV_MODE=true for local development.", backendErr)
+			fatal("backend-url metadata not found; set DEV_MODE=true for local development", "error", backendErr)
 		}
 
-		log.Printf("Starting tunnel manager for workshop %s", workshopID)
+		logger.Info("starting tunnel manager", logging.KeyWorkshopID, workshopID)
 		tunnelMgr = tunnel.NewManager(tunnel.Config{
 			WorkshopID: workshopID,
 			BackendURL: backendURL,
 			LocalPort:  9090,
 		})
 		if err := tunnelMgr.Start(); err != nil {
-			log.Fatalf("FATAL: Failed to start tunnel: %v", err)
+			fatal("failed to start tunnel", "error", err)
 		}
+		server.SetTunnelManager(tunnelMgr)
 
 		// Wait for tunnel registration (2 minute timeout)
-		log.Printf("Waiting for tunnel registration...")
+		logger.Info("waiting for tunnel registration")
 		if err := tunnelMgr.WaitForRegistration(2 * time.Minute); err != nil {
-			log.Fatalf("FATAL: Tunnel registration failed: %v", err)
+			fatal("tunnel registration failed", "error", err)
 		}
-		log.Printf("Tunnel registered successfully: %s", tunnelMgr.TunnelURL())
+		logger.Info("tunnel registered", "tunnel_url", tunnelMgr.TunnelURL())
 	}
 
 	// Wait for interrupt signal
@@ -147,7 +169,7 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("Shutting down server...")
+	logger.Info("shutting down server")
 
 	// Stop tunnel manager if running
 	if tunnelMgr != nil {
@@ -159,10 +181,13 @@ func main() {
 	defer cancel()
 
 	if err := httpServer.Shutdown(ctx); err != nil {
-		log.Printf("Server forced to shutdown: %v", err)
+		logger.Warn("server forced to shut down", "error", err)
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Warn("failed to flush traces", "error", err)
 	}
 
-	log.Println("Server stopped")
+	logger.Info("server stopped")
 }
 
 // getEnv returns the value of an environment variable or a default value.
@@ -183,12 +208,54 @@ func getAgentToken() string {
 	// Try GCP metadata service
 	token, err := getGCPMetadata("agent-token")
 	if err != nil {
-		log.Printf("Could not get agent-token from GCP metadata: %v", err)
+		logger.Warn("could not get agent-token from GCP metadata", "error", err)
 		return ""
 	}
 	return token
 }
 
+// getMetricsToken returns the /metrics bearer token from environment or GCP metadata.
+// An empty token leaves /metrics unauthenticated.
+func getMetricsToken() string {
+	if token := os.Getenv("METRICS_TOKEN"); token != "" {
+		return token
+	}
+	token, _ := getGCPMetadata("metrics-token")
+	return token
+}
+
+// getRecordingsDir returns where learner terminals are recorded. RECORDINGS_DIR
+// sets it directly; otherwise "record-terminals" metadata set to "true" by the
+// control plane enables the default directory. Empty disables recording.
+func getRecordingsDir() string {
+	if dir := os.Getenv("RECORDINGS_DIR"); dir != "" {
+		return dir
+	}
+	if record, _ := getGCPMetadata("record-terminals"); record == "true" {
+		return defaultRecordingsDir
+	}
+	return ""
+}
+
+// getTracingConfig builds the tracing config from environment or GCP metadata.
+// OTEL_TRACES_EXPORTER selects the exporter; when unset, an "otlp-endpoint"
+// metadata value enables OTLP export to that collector.
+func getTracingConfig() tracing.Config {
+	cfg := tracing.Config{
+		ServiceName:  "clarateach-agent",
+		Exporter:     os.Getenv("OTEL_TRACES_EXPORTER"),
+		OTLPEndpoint: os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"),
+		FilePath:     getEnv("TRACES_FILE", "traces.json"),
+	}
+	if cfg.Exporter == "" {
+		if endpoint, err := getGCPMetadata("otlp-endpoint"); err == nil && endpoint != "" {
+			cfg.Exporter = tracing.ExporterOTLP
+			cfg.OTLPEndpoint = endpoint
+		}
+	}
+	return cfg
+}
+
 // getWorkerID returns the worker ID from environment, GCP metadata, or hostname.
 func getWorkerID() string {
 	// Check environment variable first
@@ -220,6 +287,29 @@ func getCapacity() int {
 	return defaultCapacity
 }
 
+// getResumeGrace returns how long a disconnected learner's shell is kept for
+// resuming, from TERMINAL_RESUME_GRACE (e.g. "5m"). Zero uses the default.
+func getResumeGrace() time.Duration {
+	if v := os.Getenv("TERMINAL_RESUME_GRACE"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// getPreviewPorts returns the MicroVM ports learners can preview, from
+// PREVIEW_PORTS (e.g. "3000,5173,8080"). Empty uses the agent's defaults.
+func getPreviewPorts() []int {
+	var ports []int
+	for _, v := range strings.Split(os.Getenv("PREVIEW_PORTS"), ",") {
+		if port, err := strconv.Atoi(strings.TrimSpace(v)); err == nil && port > 0 {
+			ports = append(ports, port)
+		}
+	}
+	return ports
+}
+
 // getGCPMetadata fetches a value from the GCP metadata service.
 func getGCPMetadata(key string) (string, error) {
 	url := fmt.Sprintf("http://metadata.google.internal/computeMetadata/v1/instance/attributes/%s", key)
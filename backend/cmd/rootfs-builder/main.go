@@ -23,8 +23,8 @@ import (
 	"os/signal"
 	"syscall"
 
+	"github.com/clarateach/backend/internal/logging"
 	"github.com/clarateach/backend/internal/rootfs"
-	"github.com/sirupsen/logrus"
 )
 
 func main() {
@@ -80,11 +80,18 @@ func main() {
 		customInitScript = string(data)
 	}
 
-	// Create builder
-	builder := rootfs.NewBuilder()
+	// Setup logging (human-readable unless LOG_FORMAT says otherwise)
+	logCfg := logging.ConfigFromEnv()
+	if logCfg.Format == "" {
+		logCfg.Format = "text"
+	}
 	if *verbose {
-		builder.SetLogLevel(logrus.DebugLevel)
+		logCfg.Level = "debug"
 	}
+	logging.Setup(logCfg)
+
+	// Create builder
+	builder := rootfs.NewBuilder()
 
 	// Setup context with cancellation
 	ctx, cancel := context.WithCancel(context.Background())
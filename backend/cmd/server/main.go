@@ -1,49 +1,129 @@
 package main
 
 import (
-	"log"
+	"context"
+	"log/slog"
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/clarateach/backend/internal/api"
 	"github.com/clarateach/backend/internal/config"
+	"github.com/clarateach/backend/internal/logging"
+	"github.com/clarateach/backend/internal/lti"
+	"github.com/clarateach/backend/internal/notify"
+	"github.com/clarateach/backend/internal/oidc"
 	"github.com/clarateach/backend/internal/provisioner"
 	"github.com/clarateach/backend/internal/store"
+	"github.com/clarateach/backend/internal/tracing"
 	"github.com/go-chi/cors"
 )
 
+// fatal logs an error that stops the server from starting and exits.
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, "error", err)
+	os.Exit(1)
+}
+
 func main() {
 	// 1. Load Configuration (GCP Secret Manager -> env -> .env)
 	cfg, err := config.Load()
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		fatal(logging.Component("server"), "failed to load config", err)
 	}
 
+	// Route all logging (including the standard log package) through slog
+	logging.Setup(logging.Config{Level: cfg.LogLevel, Format: cfg.LogFormat})
+	logger := logging.Component("server")
+
 	if err := cfg.Validate(); err != nil {
-		log.Fatalf("Invalid config: %v", err)
+		fatal(logger, "invalid config", err)
+	}
+
+	// 2. Initialize Tracing (no-op unless OTEL_TRACES_EXPORTER is set)
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		ServiceName:  "clarateach-server",
+		Exporter:     cfg.TracesExporter,
+		OTLPEndpoint: cfg.OTLPEndpoint,
+		FilePath:     cfg.TracesFile,
+	})
+	if err != nil {
+		fatal(logger, "failed to initialize tracing", err)
 	}
+	defer shutdownTracing(context.Background())
+	logger.Info("tracing configured", "exporter", cfg.TracesExporter)
 
-	// 2. Initialize Store (PostgreSQL)
+	// 3. Initialize Store (PostgreSQL)
 	db, err := store.InitPostgresDB(cfg.DatabaseURL)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		fatal(logger, "failed to connect to database", err)
 	}
 	defer db.Close()
 	st := store.NewPostgresStore(db)
 
-	// 3. Initialize GCP Provisioner
-	log.Printf("GCP provisioning: project=%s, zone=%s, registry=%s", cfg.GCPProject, cfg.GCPZone, cfg.GCPRegistry)
+	// 4. Initialize GCP Provisioner
+	logger.Info("GCP provisioning", "project", cfg.GCPProject, "zone", cfg.GCPZone, "registry", cfg.GCPRegistry)
 	vmProvisioner := provisioner.NewGCPProvider(provisioner.GCPConfig{
 		Project:     cfg.GCPProject,
 		Zone:        cfg.GCPZone,
 		RegistryURL: cfg.GCPRegistry,
 	})
 
-	// 4. Initialize API Server
+	// 5. Initialize API Server
 	apiServer := api.NewServer(st, vmProvisioner, cfg.GCPUseSpot)
+	st.SetQueryObserver(apiServer.Metrics().ObserveDBQuery)
+	if cfg.MetricsToken != "" {
+		apiServer.SetMetricsToken(cfg.MetricsToken)
+		logger.Info("metrics endpoint enabled", "path", "/metrics", "token_required", true)
+	} else {
+		logger.Info("metrics endpoint enabled", "path", "/metrics", "token_required", false)
+	}
+	apiServer.SetLoginLinkTTL(cfg.LoginLinkTTL)
+	apiServer.SetRefreshTokenTTL(cfg.RefreshTokenTTL)
+	go apiServer.RunTokenCleanup(context.Background(), time.Hour)
+	apiServer.SetWebhookAllowPrivate(cfg.WebhookAllowPrivate)
+	go apiServer.RunWebhooks(context.Background(), 30*time.Second)
+	if cfg.LTIPrivateKey != "" {
+		ltiKey, err := lti.ParseToolKey([]byte(cfg.LTIPrivateKey))
+		if err != nil {
+			fatal(logger, "invalid LTI_PRIVATE_KEY", err)
+		}
+		apiServer.SetLTI(ltiKey, cfg.BackendURL, cfg.PublicURL)
+		logger.Info("LTI 1.3 enabled", "key_id", ltiKey.ID)
+	}
+	if cfg.OIDCProviders != "" {
+		providers, err := oidc.ParseProviders(cfg.OIDCProviders)
+		if err != nil {
+			fatal(logger, "invalid OIDC_PROVIDERS", err)
+		}
+		apiServer.SetOIDC(providers, cfg.BackendURL, cfg.PublicURL)
+		logger.Info("single sign-on enabled", "providers", len(providers))
+	}
+
+	// 6. Initialize learner emails (no-op unless NOTIFY_DRIVER is set)
+	notifier, err := notify.New(notify.Config{
+		Driver: cfg.NotifyDriver,
+		SMTP: notify.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+		},
+		FilePath: cfg.NotifyFile,
+	})
+	if err != nil {
+		fatal(logger, "failed to initialize notifier", err)
+	}
+	if notifier != nil {
+		apiServer.SetNotifier(notifier, cfg.PublicURL)
+		go apiServer.RunReminders(context.Background(), 5*time.Minute, cfg.ReminderLead)
+	}
+	logger.Info("learner emails configured", "driver", cfg.NotifyDriver)
 
-	// 5. Initialize GCP Firecracker Provisioner (optional)
+	// 7. Initialize GCP Firecracker Provisioner (optional)
 	if cfg.FCSnapshotName != "" {
-		log.Printf("Initializing GCP Firecracker provisioner with snapshot: %s", cfg.FCSnapshotName)
+		logger.Info("initializing GCP Firecracker provisioner", "snapshot", cfg.FCSnapshotName)
 		fcProvisioner := provisioner.NewGCPFirecrackerProvider(provisioner.GCPFirecrackerConfig{
 			Project:              cfg.GCPProject,
 			Zone:                 cfg.GCPZone,
@@ -51,27 +131,29 @@ func main() {
 			AgentToken:           cfg.FCAgentToken,
 			BackendURL:           cfg.BackendURL,
 			WorkspaceTokenSecret: cfg.WorkspaceTokenSecret,
+			MetricsToken:         cfg.MetricsToken,
+			OTLPEndpoint:         cfg.OTLPEndpoint,
+			RecordTerminals:      cfg.RecordTerminals,
 		})
 		apiServer.SetGCPFirecrackerProvisioner(fcProvisioner, cfg.FCSnapshotName)
 	}
 
-	// 6. CORS Middleware
-	log.Printf("CORS allowed origins: %v", cfg.CORSOrigins)
+	// 8. CORS Middleware
+	logger.Info("CORS configured", "allowed_origins", cfg.CORSOrigins)
 	corsHandler := cors.Handler(cors.Options{
 		AllowedOrigins:   cfg.CORSOrigins,
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	})
 
-	// 7. Root Handler
+	// 9. Root Handler
 	rootHandler := corsHandler(apiServer)
 
-	log.Printf("ClaraTeach Backend running on port %s", cfg.Port)
-	log.Printf("Database: PostgreSQL (connected)")
+	logger.Info("ClaraTeach Backend running", "port", cfg.Port, "database", "postgres")
 
 	if err := http.ListenAndServe(":"+cfg.Port, rootHandler); err != nil {
-		log.Fatalf("Server failed: %v", err)
+		fatal(logger, "server failed", err)
 	}
 }
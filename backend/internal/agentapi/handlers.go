@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/clarateach/backend/internal/logging"
 	"github.com/clarateach/backend/internal/orchestrator"
 	"github.com/go-chi/chi/v5"
 )
@@ -52,7 +53,7 @@ func (s *Server) handleListVMs(w http.ResponseWriter, r *http.Request) {
 
 	instances, err := s.provider.List(ctx, workshopID)
 	if err != nil {
-		s.logger.Errorf("Failed to list VMs: %v", err)
+		s.logger.ErrorContext(ctx, "failed to list VMs", "error", err)
 		s.writeError(w, http.StatusInternalServerError, "list_failed", "Failed to list VMs")
 		return
 	}
@@ -97,7 +98,7 @@ func (s *Server) handleCreateVM(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	ctx, cancel := context.WithTimeout(logging.WithSeat(r.Context(), req.WorkshopID, req.SeatID), 60*time.Second)
 	defer cancel()
 
 	cfg := orchestrator.InstanceConfig{
@@ -105,7 +106,9 @@ func (s *Server) handleCreateVM(w http.ResponseWriter, r *http.Request) {
 		SeatID:     req.SeatID,
 	}
 
+	done := s.metrics.CreateStarted()
 	instance, err := s.provider.Create(ctx, cfg)
+	done(err)
 	if err != nil {
 		// Check for specific error types
 		errStr := err.Error()
@@ -113,12 +116,12 @@ func (s *Server) handleCreateVM(w http.ResponseWriter, r *http.Request) {
 			s.writeError(w, http.StatusConflict, "vm_exists", "VM already exists for this workshop and seat")
 			return
 		}
-		s.logger.Errorf("Failed to create VM: %v", err)
+		s.logger.ErrorContext(ctx, "failed to create VM", "error", err)
 		s.writeError(w, http.StatusInternalServerError, "create_failed", "Failed to create VM: "+errStr)
 		return
 	}
 
-	s.logger.Infof("Created VM for workshop=%s seat=%d ip=%s", req.WorkshopID, req.SeatID, instance.IP)
+	s.logger.InfoContext(ctx, "created VM", "ip", instance.IP)
 
 	s.writeJSON(w, http.StatusCreated, VMResponse{
 		WorkshopID: instance.WorkshopID,
@@ -139,7 +142,7 @@ func (s *Server) handleGetVM(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(logging.WithSeat(r.Context(), workshopID, seatID), 10*time.Second)
 	defer cancel()
 
 	ip, err := s.provider.GetIP(ctx, workshopID, seatID)
@@ -148,7 +151,7 @@ func (s *Server) handleGetVM(w http.ResponseWriter, r *http.Request) {
 			s.writeError(w, http.StatusNotFound, "vm_not_found", "VM not found")
 			return
 		}
-		s.logger.Errorf("Failed to get VM: %v", err)
+		s.logger.ErrorContext(ctx, "failed to get VM", "error", err)
 		s.writeError(w, http.StatusInternalServerError, "get_failed", "Failed to get VM")
 		return
 	}
@@ -172,20 +175,26 @@ func (s *Server) handleDestroyVM(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(logging.WithSeat(r.Context(), workshopID, seatID), 30*time.Second)
 	defer cancel()
 
-	if err := s.provider.Destroy(ctx, workshopID, seatID); err != nil {
+	start := time.Now()
+	err = s.provider.Destroy(ctx, workshopID, seatID)
+	s.metrics.ObserveDestroy(time.Since(start), err)
+	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			s.writeError(w, http.StatusNotFound, "vm_not_found", "VM not found")
 			return
 		}
-		s.logger.Errorf("Failed to destroy VM: %v", err)
+		s.logger.ErrorContext(ctx, "failed to destroy VM", "error", err)
 		s.writeError(w, http.StatusInternalServerError, "destroy_failed", "Failed to destroy VM")
 		return
 	}
 
-	s.logger.Infof("Destroyed VM for workshop=%s seat=%d", workshopID, seatID)
+	s.logger.InfoContext(ctx, "destroyed VM")
+	if remaining, err := s.provider.List(ctx, workshopID); err == nil && len(remaining) == 0 {
+		s.metrics.ForgetWorkshop(workshopID)
+	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
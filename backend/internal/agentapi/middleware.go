@@ -29,7 +29,7 @@ func (s *Server) authMiddleware(next http.Handler) http.Handler {
 
 		token := parts[1]
 		if token != s.agentToken {
-			s.logger.Warnf("Invalid token attempt from %s", r.RemoteAddr)
+			s.logger.WarnContext(r.Context(), "invalid agent token", "remote_addr", r.RemoteAddr)
 			s.writeError(w, http.StatusUnauthorized, "invalid_token", "Invalid token")
 			return
 		}
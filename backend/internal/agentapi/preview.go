@@ -0,0 +1,220 @@
+package agentapi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/clarateach/backend/internal/auth"
+	"github.com/clarateach/backend/internal/logging"
+	"github.com/go-chi/chi/v5"
+)
+
+// defaultPreviewPorts are the MicroVM ports learners can preview: the usual
+// dev server ports of Create React App/Express, Angular, Flask, Vite, Django,
+// generic HTTP servers and Jupyter.
+var defaultPreviewPorts = []int{3000, 4200, 5000, 5173, 8000, 8080, 8888}
+
+// previewCookie carries the preview token for requests a previewed page
+// makes on its own (scripts, styles, XHR), which have no ?token=. It is scoped
+// to the preview's path.
+const previewCookie = "clarateach_preview"
+
+// handlePreviewProxy proxies HTTP and WebSocket requests to a web app a
+// learner runs in their MicroVM:
+// /proxy/{workshopID}/{seatID}/preview/{port}/path -> http://<vm>:{port}/path
+func (s *Server) handlePreviewProxy(w http.ResponseWriter, r *http.Request) {
+	workshopID := chi.URLParam(r, "workshopID")
+	seatIDStr := chi.URLParam(r, "seatID")
+	portStr := chi.URLParam(r, "port")
+
+	seatID, err := strconv.Atoi(seatIDStr)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid_seat_id", "seat_id must be an integer")
+		return
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || !s.previewPorts[port] {
+		s.writeError(w, http.StatusForbidden, "port_not_allowed", "Port is not available for preview")
+		return
+	}
+
+	ctx := logging.WithSeat(r.Context(), workshopID, seatID)
+	prefix := fmt.Sprintf("/proxy/%s/%s/preview/%s", workshopID, seatIDStr, portStr)
+
+	// Only a preview token for this seat and port will do, as the link may be
+	// shared; it falls back to the cookie set by an earlier request
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		if c, err := r.Cookie(previewCookie); err == nil {
+			token = c.Value
+		}
+	}
+	if err := s.checkPreviewToken(token, workshopID, seatID, port); err != nil && isTokenValidationEnabled() {
+		s.logger.WarnContext(ctx, "preview token validation failed", "error", err)
+		s.writeError(w, http.StatusUnauthorized, "unauthorized", "Invalid or missing preview token")
+		return
+	}
+
+	// Verify VM exists
+	if _, err := s.provider.GetIP(r.Context(), workshopID, seatID); err != nil {
+		s.writeError(w, http.StatusNotFound, "vm_not_found", "VM not found")
+		return
+	}
+
+	// Remember the token for the page's own requests
+	if token := r.URL.Query().Get("token"); token != "" {
+		secure := r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+		cookie := &http.Cookie{
+			Name:     previewCookie,
+			Value:    token,
+			Path:     prefix + "/",
+			HttpOnly: true,
+			Secure:   secure,
+			SameSite: http.SameSiteLaxMode,
+		}
+		if secure {
+			cookie.SameSite = http.SameSiteNoneMode // Lets the preview load in the workspace's iframe
+		}
+		http.SetCookie(w, cookie)
+	}
+
+	// Relative links only resolve under the preview with a trailing slash
+	if r.URL.Path == prefix {
+		target := prefix + "/"
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, target, http.StatusFound)
+		return
+	}
+
+	targetURL, _ := url.Parse(fmt.Sprintf("http://%s:%d", getMicroVMIP(seatID), port))
+	proxy := s.previewProxy(ctx, prefix, targetURL)
+
+	// WebSockets (e.g. dev server hot reload) outlive the request timeout
+	if r.Header.Get("Upgrade") != "" {
+		r = r.WithContext(context.WithoutCancel(r.Context()))
+		defer s.metrics.WebSocketOpened("preview")()
+	}
+
+	s.logger.DebugContext(ctx, "proxying preview request", "port", port, "method", r.Method, "path", r.URL.Path)
+
+	if r.Body != nil {
+		r.Body = &countingReadCloser{ReadCloser: r.Body}
+	}
+	cw := &countingResponseWriter{ResponseWriter: w}
+	proxy.ServeHTTP(cw, r)
+	if body, ok := r.Body.(*countingReadCloser); ok {
+		s.metrics.AddProxiedBytes(workshopID, seatID, "preview", "in", body.n)
+	}
+	s.metrics.AddProxiedBytes(workshopID, seatID, "preview", "out", cw.n)
+}
+
+// previewProxy returns a reverse proxy from the preview at prefix to the app
+// at target. The prefix is stripped from request paths and added back to
+// relative redirects, and the preview token never reaches the app.
+func (s *Server) previewProxy(ctx context.Context, prefix string, target *url.URL) *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			forwardedHost := req.Host
+			req.URL.Scheme = target.Scheme
+			req.URL.Host = target.Host
+			// Dev servers check the Host header, so present the address they listen on
+			req.Host = target.Host
+
+			// Rewrite path: /proxy/{workshopID}/{seatID}/preview/{port}/path -> /path
+			req.URL.Path = strings.TrimPrefix(req.URL.Path, prefix)
+			req.URL.RawPath = ""
+
+			// Keep the preview token away from the learner's app
+			q := req.URL.Query()
+			if q.Has("token") {
+				q.Del("token")
+				req.URL.RawQuery = q.Encode()
+			}
+			stripCookie(req, previewCookie)
+
+			if clientIP, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+				req.Header.Set("X-Forwarded-For", clientIP)
+			}
+			req.Header.Set("X-Forwarded-Host", forwardedHost)
+			req.Header.Set("X-Forwarded-Prefix", prefix)
+		},
+		Transport: &http.Transport{
+			DialContext: (&net.Dialer{
+				Timeout:   10 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			MaxIdleConns:    10,
+			IdleConnTimeout: 90 * time.Second,
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			// Keep redirects inside the preview
+			if loc := resp.Header.Get("Location"); strings.HasPrefix(loc, "/") && !strings.HasPrefix(loc, "//") {
+				resp.Header.Set("Location", prefix+loc)
+			}
+			// Agent middleware handles CORS
+			for _, h := range []string{"Access-Control-Allow-Origin", "Access-Control-Allow-Methods", "Access-Control-Allow-Headers",
+				"Access-Control-Allow-Credentials", "Access-Control-Max-Age", "Access-Control-Expose-Headers"} {
+				resp.Header.Del(h)
+			}
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			s.logger.WarnContext(ctx, "preview proxy error", "port", target.Port(), "error", err)
+			http.Error(w, fmt.Sprintf("Nothing is listening on port %s in your workspace yet", target.Port()), http.StatusBadGateway)
+		},
+	}
+}
+
+// checkPreviewToken validates a preview token and verifies it was issued for
+// the seat and port and has not been revoked.
+func (s *Server) checkPreviewToken(token, workshopID string, seatID, port int) error {
+	if token == "" {
+		return fmt.Errorf("missing token")
+	}
+	claims, err := auth.ValidatePreviewToken(token)
+	if err != nil {
+		return fmt.Errorf("invalid token: %v", err)
+	}
+	if claims.WorkshopID != workshopID || claims.Seat != seatID || claims.Port != port {
+		return fmt.Errorf("token is for another preview")
+	}
+	if s.revocations.isRevoked(&claims.WorkspaceClaims) {
+		return fmt.Errorf("token revoked")
+	}
+	return nil
+}
+
+// stripCookie removes one cookie from a request's Cookie headers.
+func stripCookie(r *http.Request, name string) {
+	cookies := r.Cookies()
+	r.Header.Del("Cookie")
+	for _, c := range cookies {
+		if c.Name != name {
+			r.AddCookie(c)
+		}
+	}
+}
+
+// previewPortSet builds the preview allowlist. The terminal and file server
+// ports are never previewable, as that would bypass their own checks.
+func previewPortSet(ports []int) map[int]bool {
+	if len(ports) == 0 {
+		ports = defaultPreviewPorts
+	}
+	set := make(map[int]bool, len(ports))
+	for _, p := range ports {
+		if p > 0 && p < 65536 && p != terminalPort && p != filesPort {
+			set[p] = true
+		}
+	}
+	return set
+}
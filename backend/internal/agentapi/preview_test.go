@@ -0,0 +1,145 @@
+package agentapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/clarateach/backend/internal/auth"
+	"github.com/clarateach/backend/internal/orchestrator"
+)
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	t.Setenv("WORKSPACE_TOKEN_SECRET", "test-workspace-secret")
+	provider, err := orchestrator.NewFirecrackerProviderWithConfig(orchestrator.FirecrackerConfig{SocketDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("NewFirecrackerProviderWithConfig() error = %v", err)
+	}
+	return NewServer(provider, Config{AgentToken: "agent-secret", WorkerID: "worker-1"})
+}
+
+func TestPreviewPortSet(t *testing.T) {
+	defaults := previewPortSet(nil)
+	if !defaults[5173] || !defaults[3000] || defaults[terminalPort] || defaults[filesPort] {
+		t.Errorf("Default preview ports = %v, want dev server ports only", defaults)
+	}
+
+	// The terminal and file server ports are never previewable
+	custom := previewPortSet([]int{8080, terminalPort, filesPort, 0, 70000})
+	if len(custom) != 1 || !custom[8080] {
+		t.Errorf("Preview ports = %v, want only 8080", custom)
+	}
+}
+
+func TestPreviewProxyAccess(t *testing.T) {
+	s := newTestServer(t)
+
+	previewToken, _ := auth.GeneratePreviewToken("ws-1", 1, 5173, "reg-1")
+	otherPortToken, _ := auth.GeneratePreviewToken("ws-1", 1, 3000, "reg-1")
+	otherSeatToken, _ := auth.GeneratePreviewToken("ws-1", 2, 5173, "reg-2")
+	workspaceToken, _ := auth.GenerateLearnerToken("ws-1", 1, "reg-1")
+
+	get := func(path string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rr := httptest.NewRecorder()
+		s.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Ports off the allowlist are refused before the token is looked at
+	for _, port := range []string{"3001", "3002", "9999", "nope"} {
+		if rr := get("/proxy/ws-1/1/preview/"+port+"/?token="+previewToken, nil); rr.Code != http.StatusForbidden {
+			t.Errorf("Port %s = %d, want 403", port, rr.Code)
+		}
+	}
+
+	// Only a preview token for this seat and port is accepted
+	for name, token := range map[string]string{
+		"missing":    "",
+		"workspace":  workspaceToken,
+		"other port": otherPortToken,
+		"other seat": otherSeatToken,
+	} {
+		if rr := get("/proxy/ws-1/1/preview/5173/?token="+token, nil); rr.Code != http.StatusUnauthorized {
+			t.Errorf("%s token = %d, want 401", name, rr.Code)
+		}
+	}
+
+	// A valid token gets as far as looking up the VM, from the query or the cookie
+	if rr := get("/proxy/ws-1/1/preview/5173/?token="+previewToken, nil); rr.Code != http.StatusNotFound {
+		t.Errorf("Preview token in query = %d, want 404 for the missing VM", rr.Code)
+	}
+	if rr := get("/proxy/ws-1/1/preview/5173/", &http.Cookie{Name: previewCookie, Value: previewToken}); rr.Code != http.StatusNotFound {
+		t.Errorf("Preview token in cookie = %d, want 404 for the missing VM", rr.Code)
+	}
+
+	// Revoking the learner's tokens revokes their previews too
+	s.revocations.revoke("ws-1", 1, "reg-1", time.Now().Add(time.Second))
+	if rr := get("/proxy/ws-1/1/preview/5173/?token="+previewToken, nil); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Revoked preview token = %d, want 401", rr.Code)
+	}
+}
+
+func TestPreviewProxyRewrites(t *testing.T) {
+	s := newTestServer(t)
+
+	var got *http.Request
+	app := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		switch r.URL.Path {
+		case "/login":
+			http.Redirect(w, r, "/welcome", http.StatusFound)
+		case "/elsewhere":
+			http.Redirect(w, r, "//example.com/", http.StatusFound)
+		default:
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer app.Close()
+	target, _ := url.Parse(app.URL)
+
+	prefix := "/proxy/ws-1/1/preview/5173"
+	proxy := s.previewProxy(context.Background(), prefix, target)
+	serve := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.AddCookie(&http.Cookie{Name: previewCookie, Value: "preview-token"})
+		req.AddCookie(&http.Cookie{Name: "app_session", Value: "abc"})
+		rr := httptest.NewRecorder()
+		proxy.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// The prefix is stripped and the preview token and cookie stay behind
+	rr := serve(prefix + "/about?token=preview-token&tab=2")
+	if rr.Code != http.StatusOK || got == nil {
+		t.Fatalf("Proxied request = %d - %s", rr.Code, rr.Body.String())
+	}
+	if got.URL.Path != "/about" || got.URL.RawQuery != "tab=2" {
+		t.Errorf("App got %s?%s, want /about?tab=2", got.URL.Path, got.URL.RawQuery)
+	}
+	if cookie := got.Header.Get("Cookie"); cookie != "app_session=abc" {
+		t.Errorf("App got cookies %q, want only app_session", cookie)
+	}
+	if got.Header.Get("X-Forwarded-Prefix") != prefix || got.Host != target.Host {
+		t.Errorf("App got prefix %q and host %q, want %q and %q", got.Header.Get("X-Forwarded-Prefix"), got.Host, prefix, target.Host)
+	}
+	if rr.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Error("App CORS headers should be left to the agent")
+	}
+
+	// Relative redirects stay inside the preview; others are left alone
+	if loc := serve(prefix + "/login").Header().Get("Location"); loc != prefix+"/welcome" {
+		t.Errorf("Redirect to %q, want %s/welcome", loc, prefix)
+	}
+	if loc := serve(prefix + "/elsewhere").Header().Get("Location"); loc != "//example.com/" {
+		t.Errorf("Redirect to %q, want //example.com/", loc)
+	}
+}
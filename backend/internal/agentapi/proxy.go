@@ -1,6 +1,7 @@
 package agentapi
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net"
@@ -13,6 +14,7 @@ import (
 	"time"
 
 	"github.com/clarateach/backend/internal/auth"
+	"github.com/clarateach/backend/internal/logging"
 	"github.com/go-chi/chi/v5"
 	"github.com/gorilla/websocket"
 )
@@ -49,11 +51,10 @@ func isTokenValidationEnabled() bool {
 // Token can be provided via:
 // - Query param: ?token=xxx (for WebSocket connections)
 // - Authorization header: Bearer xxx (for HTTP requests)
-func validateWorkspaceToken(r *http.Request, workshopID string, seatID int) error {
-	if !isTokenValidationEnabled() {
-		return nil // Skip validation in dev mode
-	}
-
+//
+// In dev mode any request is accepted as the seat's learner unless it carries
+// a valid token saying otherwise (e.g. an observer token).
+func (s *Server) validateWorkspaceToken(r *http.Request, workshopID string, seatID int) (*auth.WorkspaceClaims, error) {
 	// Try to get token from query param first (WebSocket)
 	token := r.URL.Query().Get("token")
 
@@ -65,25 +66,38 @@ func validateWorkspaceToken(r *http.Request, workshopID string, seatID int) erro
 		}
 	}
 
+	claims, err := s.checkWorkspaceToken(token, workshopID, seatID)
+	if err != nil && !isTokenValidationEnabled() {
+		return &auth.WorkspaceClaims{WorkshopID: workshopID, Seat: seatID}, nil // Skip validation in dev mode
+	}
+	return claims, err
+}
+
+// checkWorkspaceToken validates token and verifies it was issued for the seat
+// and has not been revoked.
+func (s *Server) checkWorkspaceToken(token, workshopID string, seatID int) (*auth.WorkspaceClaims, error) {
 	if token == "" {
-		return fmt.Errorf("missing token")
+		return nil, fmt.Errorf("missing token")
 	}
 
 	// Validate token
 	claims, err := auth.ValidateWorkspaceToken(token)
 	if err != nil {
-		return fmt.Errorf("invalid token: %v", err)
+		return nil, fmt.Errorf("invalid token: %v", err)
 	}
 
 	// Verify claims match request
 	if claims.WorkshopID != workshopID {
-		return fmt.Errorf("token workshop_id mismatch")
+		return nil, fmt.Errorf("token workshop_id mismatch")
 	}
 	if claims.Seat != seatID {
-		return fmt.Errorf("token seat mismatch")
+		return nil, fmt.Errorf("token seat mismatch")
+	}
+	if s.revocations.isRevoked(claims) {
+		return nil, fmt.Errorf("token revoked")
 	}
 
-	return nil
+	return claims, nil
 }
 
 // getMicroVMIP returns the IP address for a MicroVM based on seat ID
@@ -102,13 +116,22 @@ func (s *Server) handleTerminalProxy(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ctx := logging.WithSeat(r.Context(), workshopID, seatID)
+
 	// Validate workspace token
-	if err := validateWorkspaceToken(r, workshopID, seatID); err != nil {
-		s.logger.Warnf("Token validation failed for terminal %s/%d: %v", workshopID, seatID, err)
+	claims, err := s.validateWorkspaceToken(r, workshopID, seatID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "terminal token validation failed", "error", err)
 		s.writeError(w, http.StatusUnauthorized, "unauthorized", "Invalid or missing workspace token")
 		return
 	}
 
+	// Staff mirror the learner's live terminal instead of opening their own
+	if claims.IsStaff() {
+		s.observeTerminal(w, r, workshopID, seatID, claims)
+		return
+	}
+
 	// Verify VM exists
 	_, err = s.provider.GetIP(r.Context(), workshopID, seatID)
 	if err != nil {
@@ -116,20 +139,51 @@ func (s *Server) handleTerminalProxy(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	vmIP := getMicroVMIP(seatID)
-	// MicroVM server expects /terminal route
-	targetURL := fmt.Sprintf("ws://%s:%d/terminal", vmIP, terminalPort)
-
-	s.logger.Infof("Proxying terminal WebSocket: %s seat %d -> %s", workshopID, seatID, targetURL)
-
 	// Upgrade client connection
 	clientConn, err := wsUpgrader.Upgrade(w, r, nil)
 	if err != nil {
-		s.logger.Errorf("Failed to upgrade WebSocket: %v", err)
+		s.logger.ErrorContext(ctx, "failed to upgrade WebSocket", "error", err)
 		return
 	}
 	defer clientConn.Close()
 
+	// Reattach to the seat's shell if the learner is resuming within the grace
+	// period; otherwise start a new one
+	term := s.terminals.resume(workshopID, seatID, r.URL.Query().Get("resume"), clientConn)
+	if term != nil {
+		s.logger.InfoContext(ctx, "resumed terminal session")
+	} else if term = s.startTerminal(ctx, r, workshopID, seatID, clientConn); term == nil {
+		return
+	}
+	defer s.metrics.WebSocketOpened("terminal")()
+
+	// Client -> Backend. Output flows the other way in pumpTerminal, which
+	// outlives this connection.
+	for {
+		messageType, message, err := clientConn.ReadMessage()
+		if err != nil {
+			break
+		}
+		s.metrics.AddProxiedBytes(workshopID, seatID, "terminal", "in", len(message))
+		if err := term.fromLearner(messageType, message); err != nil {
+			break
+		}
+	}
+
+	s.terminals.release(workshopID, seatID, term, clientConn)
+	s.logger.InfoContext(ctx, "terminal proxy closed")
+}
+
+// startTerminal connects to the MicroVM's terminal server and starts a new
+// terminal session for the learner. It returns nil, having told the client,
+// if that fails.
+func (s *Server) startTerminal(ctx context.Context, r *http.Request, workshopID string, seatID int, clientConn *websocket.Conn) *seatTerminal {
+	vmIP := getMicroVMIP(seatID)
+	// MicroVM server expects /terminal route
+	targetURL := fmt.Sprintf("ws://%s:%d/terminal", vmIP, terminalPort)
+
+	s.logger.InfoContext(ctx, "proxying terminal WebSocket", "target", targetURL)
+
 	// Connect to MicroVM
 	dialer := websocket.Dialer{
 		HandshakeTimeout: 10 * time.Second,
@@ -143,52 +197,34 @@ func (s *Server) handleTerminalProxy(w http.ResponseWriter, r *http.Request) {
 
 	backendConn, resp, err := dialer.Dial(targetURL, headers)
 	if err != nil {
-		s.logger.Errorf("Failed to connect to MicroVM terminal: %v", err)
+		status := 0
 		if resp != nil {
-			s.logger.Errorf("Backend response: %d", resp.StatusCode)
+			status = resp.StatusCode
 		}
+		s.logger.ErrorContext(ctx, "failed to connect to MicroVM terminal", "error", err, "backend_status", status)
 		clientConn.WriteMessage(websocket.CloseMessage,
 			websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "Failed to connect to terminal"))
-		return
+		return nil
 	}
-	defer backendConn.Close()
-
-	// Bidirectional proxy
-	errChan := make(chan error, 2)
-
-	// Client -> Backend
-	go func() {
-		for {
-			messageType, message, err := clientConn.ReadMessage()
-			if err != nil {
-				errChan <- err
-				return
-			}
-			if err := backendConn.WriteMessage(messageType, message); err != nil {
-				errChan <- err
-				return
-			}
-		}
-	}()
-
-	// Backend -> Client
-	go func() {
-		for {
-			messageType, message, err := backendConn.ReadMessage()
-			if err != nil {
-				errChan <- err
-				return
-			}
-			if err := clientConn.WriteMessage(messageType, message); err != nil {
-				errChan <- err
-				return
-			}
+
+	// Record the session when the worker is configured to
+	var rec *castRecorder
+	if s.recordingsDir != "" {
+		if rec, err = newCastRecorder(s.recordingsDir, workshopID, seatID); err != nil {
+			s.logger.WarnContext(ctx, "failed to start terminal recording", "error", err)
 		}
-	}()
+	}
 
-	// Wait for either direction to close
-	<-errChan
-	s.logger.Infof("Terminal proxy closed: %s seat %d", workshopID, seatID)
+	// Share the terminal with staff and keep it for resuming
+	term, err := s.terminals.attach(workshopID, seatID, clientConn, backendConn, rec)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to start terminal session", "error", err)
+		backendConn.Close()
+		rec.close()
+		return nil
+	}
+	go s.pumpTerminal(context.WithoutCancel(ctx), workshopID, seatID, term)
+	return term
 }
 
 // handleFilesProxy proxies HTTP requests to the MicroVM's file server
@@ -202,16 +238,26 @@ func (s *Server) handleFilesProxy(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ctx := logging.WithSeat(r.Context(), workshopID, seatID)
+
 	// Validate workspace token
-	if err := validateWorkspaceToken(r, workshopID, seatID); err != nil {
-		s.logger.Warnf("Token validation failed for files %s/%d: %v", workshopID, seatID, err)
+	claims, err := s.validateWorkspaceToken(r, workshopID, seatID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "files token validation failed", "error", err)
+		s.metrics.FileProxyError("unauthorized")
 		s.writeError(w, http.StatusUnauthorized, "unauthorized", "Invalid or missing workspace token")
 		return
 	}
+	if claims.IsStaff() {
+		s.metrics.FileProxyError("forbidden")
+		s.writeError(w, http.StatusForbidden, "forbidden", "Staff tokens only grant terminal access")
+		return
+	}
 
 	// Verify VM exists
 	_, err = s.provider.GetIP(r.Context(), workshopID, seatID)
 	if err != nil {
+		s.metrics.FileProxyError("vm_not_found")
 		s.writeError(w, http.StatusNotFound, "vm_not_found", "VM not found")
 		return
 	}
@@ -235,12 +281,16 @@ func (s *Server) handleFilesProxy(w http.ResponseWriter, r *http.Request) {
 
 	// Custom error handler
 	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
-		s.logger.Errorf("Proxy error for %s seat %d: %v", workshopID, seatID, err)
+		s.logger.ErrorContext(ctx, "files proxy error", "error", err)
+		s.metrics.FileProxyError("upstream")
 		http.Error(w, "Failed to connect to file server", http.StatusBadGateway)
 	}
 
 	// Strip CORS headers from upstream response (agent middleware handles CORS)
 	proxy.ModifyResponse = func(resp *http.Response) error {
+		if resp.StatusCode >= 500 {
+			s.metrics.FileProxyError("upstream_status")
+		}
 		resp.Header.Del("Access-Control-Allow-Origin")
 		resp.Header.Del("Access-Control-Allow-Methods")
 		resp.Header.Del("Access-Control-Allow-Headers")
@@ -272,8 +322,54 @@ func (s *Server) handleFilesProxy(w http.ResponseWriter, r *http.Request) {
 		req.Header.Set("X-Forwarded-Proto", "http")
 	}
 
-	s.logger.Debugf("Proxying files request: %s seat %d %s %s", workshopID, seatID, r.Method, r.URL.Path)
-	proxy.ServeHTTP(w, r)
+	s.logger.DebugContext(ctx, "proxying files request", "method", r.Method, "path", r.URL.Path)
+
+	// Count bytes in both directions for per-seat bandwidth metrics
+	if r.Body != nil {
+		r.Body = &countingReadCloser{ReadCloser: r.Body}
+	}
+	cw := &countingResponseWriter{ResponseWriter: w}
+	proxy.ServeHTTP(cw, r)
+	if body, ok := r.Body.(*countingReadCloser); ok {
+		s.metrics.AddProxiedBytes(workshopID, seatID, "files", "in", body.n)
+	}
+	s.metrics.AddProxiedBytes(workshopID, seatID, "files", "out", cw.n)
+}
+
+// countingReadCloser counts bytes read from a request body.
+type countingReadCloser struct {
+	io.ReadCloser
+	n int
+}
+
+func (c *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n += n
+	return n, err
+}
+
+// countingResponseWriter counts bytes written to the client.
+type countingResponseWriter struct {
+	http.ResponseWriter
+	n int
+}
+
+func (c *countingResponseWriter) Write(p []byte) (int, error) {
+	n, err := c.ResponseWriter.Write(p)
+	c.n += n
+	return n, err
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (c *countingResponseWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// Flush supports streamed downloads through the reverse proxy.
+func (c *countingResponseWriter) Flush() {
+	if f, ok := c.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
 }
 
 // handleHealthProxy proxies health check to MicroVM (for debugging)
@@ -0,0 +1,223 @@
+package agentapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// recordingTimeFormat is the start time in recording file names.
+const recordingTimeFormat = "20060102T150405.000Z"
+
+// castRecorder writes one learner terminal connection as an asciicast v2 file:
+// a JSON header line followed by [elapsed_seconds, code, data] event lines,
+// where code is "o" for output, "i" for input and "r" for resize. Input
+// events keep only the timing of keystrokes, not the keys.
+type castRecorder struct {
+	mu    sync.Mutex
+	f     *os.File
+	start time.Time
+}
+
+// castHeader is the first line of an asciicast v2 file.
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// recordedMessage is the terminal server's JSON message framing.
+type recordedMessage struct {
+	Type string `json:"type"` // "output", "input" or "resize"
+	Data string `json:"data"`
+	Cols int    `json:"cols"`
+	Rows int    `json:"rows"`
+}
+
+// newCastRecorder starts a recording under dir/<workshopID>/. Recordings are
+// named seat-<N>-<start time>.cast so each new shell on a seat gets its own
+// file; a learner resuming a shell keeps writing to the same one.
+func newCastRecorder(dir, workshopID string, seatID int) (*castRecorder, error) {
+	wsDir := filepath.Join(dir, workshopID)
+	if err := os.MkdirAll(wsDir, 0o750); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	name := fmt.Sprintf("seat-%d-%s.cast", seatID, start.UTC().Format(recordingTimeFormat))
+	f, err := os.OpenFile(filepath.Join(wsDir, name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o640)
+	if err != nil {
+		return nil, err
+	}
+
+	header, _ := json.Marshal(castHeader{
+		Version:   2,
+		Width:     80, // Terminal server's initial PTY size; resizes are recorded as events
+		Height:    24,
+		Timestamp: start.Unix(),
+		Title:     fmt.Sprintf("%s seat %d", workshopID, seatID),
+		Env:       map[string]string{"TERM": "xterm-256color"},
+	})
+	if _, err := f.Write(append(header, '\n')); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &castRecorder{f: f, start: start}, nil
+}
+
+// output records a message from the MicroVM terminal. A nil recorder records
+// nothing.
+func (c *castRecorder) output(raw []byte) {
+	if c == nil {
+		return
+	}
+	var msg recordedMessage
+	if json.Unmarshal(raw, &msg) == nil && msg.Type == "output" {
+		c.event("o", msg.Data)
+	}
+}
+
+// input records a message sent to the MicroVM terminal. Keystrokes are
+// recorded without their data, since passwords typed at prompts that do not
+// echo would otherwise end up in the recording.
+func (c *castRecorder) input(raw []byte) {
+	if c == nil {
+		return
+	}
+	var msg recordedMessage
+	if json.Unmarshal(raw, &msg) != nil {
+		return
+	}
+	switch msg.Type {
+	case "input":
+		c.event("i", "")
+	case "resize":
+		if msg.Cols > 0 && msg.Rows > 0 {
+			c.event("r", fmt.Sprintf("%dx%d", msg.Cols, msg.Rows))
+		}
+	}
+}
+
+func (c *castRecorder) event(code, data string) {
+	line, _ := json.Marshal([]interface{}{time.Since(c.start).Seconds(), code, data})
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.f != nil {
+		c.f.Write(append(line, '\n'))
+	}
+}
+
+func (c *castRecorder) close() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.f != nil {
+		c.f.Close()
+		c.f = nil
+	}
+}
+
+// RecordingResponse describes a terminal recording stored on the worker.
+type RecordingResponse struct {
+	WorkshopID string    `json:"workshop_id"`
+	SeatID     int       `json:"seat_id"`
+	Name       string    `json:"name"`
+	SizeBytes  int64     `json:"size_bytes"`
+	StartedAt  time.Time `json:"started_at"`
+}
+
+// handleListRecordings lists a workshop's terminal recordings, oldest first.
+func (s *Server) handleListRecordings(w http.ResponseWriter, r *http.Request) {
+	workshopID := r.URL.Query().Get("workshop_id")
+	if workshopID == "" || !validPathElement(workshopID) {
+		s.writeError(w, http.StatusBadRequest, "missing_field", "workshop_id is required")
+		return
+	}
+
+	recordings := []RecordingResponse{}
+	if s.recordingsDir != "" {
+		entries, err := os.ReadDir(filepath.Join(s.recordingsDir, workshopID))
+		if err != nil && !os.IsNotExist(err) {
+			s.logger.ErrorContext(r.Context(), "failed to list recordings", "error", err)
+			s.writeError(w, http.StatusInternalServerError, "list_failed", "Failed to list recordings")
+			return
+		}
+		for _, e := range entries {
+			seatID, startedAt, ok := parseRecordingName(e.Name())
+			info, err := e.Info()
+			if !ok || err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			recordings = append(recordings, RecordingResponse{
+				WorkshopID: workshopID,
+				SeatID:     seatID,
+				Name:       e.Name(),
+				SizeBytes:  info.Size(),
+				StartedAt:  startedAt,
+			})
+		}
+		sort.Slice(recordings, func(i, j int) bool { return recordings[i].Name < recordings[j].Name })
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"recordings": recordings,
+	})
+}
+
+// handleGetRecording downloads one recording.
+func (s *Server) handleGetRecording(w http.ResponseWriter, r *http.Request) {
+	workshopID := chi.URLParam(r, "workshopID")
+	name := chi.URLParam(r, "name")
+	if _, _, ok := parseRecordingName(name); !ok || !validPathElement(workshopID) || s.recordingsDir == "" {
+		s.writeError(w, http.StatusNotFound, "not_found", "Recording not found")
+		return
+	}
+
+	f, err := os.Open(filepath.Join(s.recordingsDir, workshopID, name))
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, "not_found", "Recording not found")
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, "read_failed", "Failed to read recording")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-asciicast")
+	http.ServeContent(w, r, name, info.ModTime(), f)
+}
+
+// parseRecordingName parses the seat and start time from a recording file
+// name made by newCastRecorder.
+func parseRecordingName(name string) (seatID int, startedAt time.Time, ok bool) {
+	if !validPathElement(name) || !strings.HasPrefix(name, "seat-") || !strings.HasSuffix(name, ".cast") {
+		return 0, time.Time{}, false
+	}
+	seat, stamp, _ := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(name, "seat-"), ".cast"), "-")
+	seatID, err := strconv.Atoi(seat)
+	if err != nil {
+		return 0, time.Time{}, false
+	}
+	startedAt, err = time.Parse(recordingTimeFormat, stamp)
+	return seatID, startedAt, err == nil
+}
+
+// validPathElement reports whether s is safe to use as a single file name.
+func validPathElement(s string) bool {
+	return s != "" && s != "." && s != ".." && !strings.ContainsAny(s, `/\`)
+}
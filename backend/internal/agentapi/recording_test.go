@@ -0,0 +1,47 @@
+package agentapi
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCastRecorder(t *testing.T) {
+	dir := t.TempDir()
+	rec, err := newCastRecorder(dir, "ws-1", 2)
+	if err != nil {
+		t.Fatalf("newCastRecorder() error = %v", err)
+	}
+	rec.output([]byte(`{"type":"output","data":"Password: "}`))
+	rec.input([]byte(`{"type":"input","data":"hunter2\r"}`))
+	rec.input([]byte(`{"type":"resize","cols":120,"rows":40}`))
+	rec.input([]byte(`not json`))
+	rec.close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "ws-1", "seat-2-*.cast"))
+	if len(files) != 1 {
+		t.Fatalf("Recordings = %v, want one for seat 2", files)
+	}
+	data, _ := os.ReadFile(files[0])
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Recording has %d lines, want a header and 3 events:\n%s", len(lines), data)
+	}
+
+	// Keystrokes keep their timing but never their data
+	want := [][2]string{{"o", "Password: "}, {"i", ""}, {"r", "120x40"}}
+	for i, line := range lines[1:] {
+		var event []interface{}
+		if err := json.Unmarshal([]byte(line), &event); err != nil || len(event) != 3 {
+			t.Fatalf("Event %d = %s, want [time, code, data]", i, line)
+		}
+		if event[1] != want[i][0] || event[2] != want[i][1] {
+			t.Errorf("Event %d = %v %q, want %s %q", i, event[1], event[2], want[i][0], want[i][1])
+		}
+	}
+	if strings.Contains(string(data), "hunter2") {
+		t.Error("Recording contains typed input")
+	}
+}
@@ -0,0 +1,78 @@
+package agentapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/clarateach/backend/internal/auth"
+	"github.com/clarateach/backend/internal/logging"
+)
+
+// tokenRevocations records learner tokens the control plane has revoked, by
+// workshop, seat and registration. A revocation covers every token for that
+// registration and seat issued before it, so a learner moved back to a seat
+// later gets in with a fresh token.
+type tokenRevocations struct {
+	mu      sync.RWMutex
+	revoked map[string]time.Time
+}
+
+func newTokenRevocations() *tokenRevocations {
+	return &tokenRevocations{revoked: make(map[string]time.Time)}
+}
+
+func revocationKey(workshopID string, seatID int, registrationID string) string {
+	return seatKey(workshopID, seatID) + "/" + registrationID
+}
+
+func (t *tokenRevocations) revoke(workshopID string, seatID int, registrationID string, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.revoked[revocationKey(workshopID, seatID, registrationID)] = at
+}
+
+// isRevoked reports whether a learner token was revoked. Tokens not issued
+// for a registration cannot be revoked.
+func (t *tokenRevocations) isRevoked(claims *auth.WorkspaceClaims) bool {
+	if claims.RegistrationID == "" || claims.IssuedAt == nil {
+		return false
+	}
+	t.mu.RLock()
+	at, ok := t.revoked[revocationKey(claims.WorkshopID, claims.Seat, claims.RegistrationID)]
+	t.mu.RUnlock()
+	// Issue times have second precision, so a token issued in the same second
+	// as the revocation counts as revoked
+	return ok && claims.IssuedAt.Time.Before(at)
+}
+
+// RevokeTokensRequest is the request body for POST /tokens/revoke.
+type RevokeTokensRequest struct {
+	WorkshopID     string `json:"workshop_id"`
+	SeatID         int    `json:"seat_id"`
+	RegistrationID string `json:"registration_id"`
+}
+
+// handleRevokeTokens revokes a learner's workspace tokens for a seat and ends
+// the seat's terminal session, disconnecting the learner and any observers.
+func (s *Server) handleRevokeTokens(w http.ResponseWriter, r *http.Request) {
+	var req RevokeTokensRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid_request", "Invalid JSON body")
+		return
+	}
+	if req.WorkshopID == "" || req.SeatID <= 0 || req.RegistrationID == "" {
+		s.writeError(w, http.StatusBadRequest, "missing_field", "workshop_id, seat_id and registration_id are required")
+		return
+	}
+
+	s.revocations.revoke(req.WorkshopID, req.SeatID, req.RegistrationID, time.Now())
+	if t := s.terminals.get(req.WorkshopID, req.SeatID); t != nil {
+		s.terminals.detach(req.WorkshopID, req.SeatID, t)
+	}
+
+	ctx := logging.WithSeat(r.Context(), req.WorkshopID, req.SeatID)
+	s.logger.InfoContext(ctx, "revoked learner tokens", "registration_id", req.RegistrationID)
+	w.WriteHeader(http.StatusNoContent)
+}
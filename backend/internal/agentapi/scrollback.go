@@ -0,0 +1,53 @@
+package agentapi
+
+// scrollbackBytes bounds the terminal output kept per seat for replay when a
+// learner resumes. It comfortably covers a few screens of output.
+const scrollbackBytes = 64 * 1024
+
+// scrollback is a ring buffer of the most recent terminal output messages,
+// holding at most scrollbackBytes of message data. Whole messages are evicted
+// so escape sequences and UTF-8 characters are never cut in half. The newest
+// message is always kept, even if it is larger than the limit on its own.
+type scrollback struct {
+	msgs  []terminalMessage
+	start int // Index of the oldest message
+	count int
+	size  int
+}
+
+// add appends msg, evicting the oldest messages to stay within the limit.
+func (b *scrollback) add(msg terminalMessage) {
+	for b.count > 0 && b.size+len(msg.data) > scrollbackBytes {
+		b.size -= len(b.msgs[b.start].data)
+		b.msgs[b.start] = terminalMessage{}
+		b.start = (b.start + 1) % len(b.msgs)
+		b.count--
+	}
+	if b.count == len(b.msgs) {
+		b.grow()
+	}
+	b.msgs[(b.start+b.count)%len(b.msgs)] = msg
+	b.count++
+	b.size += len(msg.data)
+}
+
+// grow doubles the buffer's capacity, unwrapping it to start at index 0.
+func (b *scrollback) grow() {
+	msgs := make([]terminalMessage, max(2*len(b.msgs), 64))
+	b.copyTo(msgs)
+	b.msgs = msgs
+	b.start = 0
+}
+
+// messages returns the buffered messages, oldest first.
+func (b *scrollback) messages() []terminalMessage {
+	msgs := make([]terminalMessage, b.count)
+	b.copyTo(msgs)
+	return msgs
+}
+
+func (b *scrollback) copyTo(dst []terminalMessage) {
+	for i := 0; i < b.count; i++ {
+		dst[i] = b.msgs[(b.start+i)%len(b.msgs)]
+	}
+}
@@ -3,60 +3,91 @@
 package agentapi
 
 import (
+	"context"
 	"encoding/json"
+	"log/slog"
 	"net/http"
 	"sync"
 	"time"
 
+	"github.com/clarateach/backend/internal/logging"
+	"github.com/clarateach/backend/internal/metrics"
 	"github.com/clarateach/backend/internal/orchestrator"
+	"github.com/clarateach/backend/internal/tracing"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
-	"github.com/sirupsen/logrus"
 )
 
 // Server is the Worker Agent HTTP API server.
 type Server struct {
-	router     *chi.Mux
-	provider   *orchestrator.FirecrackerProvider
-	agentToken string
-	workerID   string
-	capacity   int
-	startTime  time.Time
-	logger     *logrus.Logger
-	mu         sync.RWMutex
+	router        *chi.Mux
+	provider      *orchestrator.FirecrackerProvider
+	agentToken    string
+	workerID      string
+	capacity      int
+	startTime     time.Time
+	logger        *slog.Logger
+	metrics       *metrics.AgentMetrics
+	metricsTok    string
+	terminals     *terminalHub
+	revocations   *tokenRevocations
+	recordingsDir string
+	previewPorts  map[int]bool
+	mu            sync.RWMutex
 }
 
 // Config holds configuration for the Worker Agent server.
 type Config struct {
-	AgentToken string // Token for authenticating requests from Control Plane
-	WorkerID   string // Unique identifier for this worker
-	Capacity   int    // Maximum number of VMs this worker can host
+	AgentToken    string        // Token for authenticating requests from Control Plane
+	WorkerID      string        // Unique identifier for this worker
+	Capacity      int           // Maximum number of VMs this worker can host
+	DiskPath      string        // Filesystem reported in host disk headroom metrics (optional)
+	MetricsToken  string        // Bearer token for /metrics; empty leaves it open
+	RecordingsDir string        // Where learner terminals are recorded; empty disables recording
+	ResumeGrace   time.Duration // How long a disconnected learner's shell is kept for resuming (default 2m)
+	PreviewPorts  []int         // MicroVM ports learners can preview in a browser (default defaultPreviewPorts)
 }
 
 // NewServer creates a new Worker Agent HTTP server.
 func NewServer(provider *orchestrator.FirecrackerProvider, cfg Config) *Server {
-	logger := logrus.New()
-	logger.SetLevel(logrus.InfoLevel)
-
 	if cfg.Capacity == 0 {
 		cfg.Capacity = 50 // Default capacity
 	}
+	if cfg.ResumeGrace == 0 {
+		cfg.ResumeGrace = defaultResumeGrace
+	}
 
 	s := &Server{
-		router:     chi.NewRouter(),
-		provider:   provider,
-		agentToken: cfg.AgentToken,
-		workerID:   cfg.WorkerID,
-		capacity:   cfg.Capacity,
-		startTime:  time.Now(),
-		logger:     logger,
+		router:        chi.NewRouter(),
+		provider:      provider,
+		agentToken:    cfg.AgentToken,
+		workerID:      cfg.WorkerID,
+		capacity:      cfg.Capacity,
+		startTime:     time.Now(),
+		logger:        logging.Component("agentapi"),
+		metricsTok:    cfg.MetricsToken,
+		terminals:     newTerminalHub(cfg.ResumeGrace),
+		revocations:   newTokenRevocations(),
+		recordingsDir: cfg.RecordingsDir,
+		previewPorts:  previewPortSet(cfg.PreviewPorts),
 	}
+	s.metrics = metrics.NewAgentMetrics(metrics.AgentConfig{
+		WorkerID: cfg.WorkerID,
+		Capacity: cfg.Capacity,
+		DiskPath: cfg.DiskPath,
+		VMCount:  s.getVMCount,
+	})
 
 	s.routes()
 	return s
 }
 
+// SetTunnelManager reports the tunnel's registration status in /metrics.
+func (s *Server) SetTunnelManager(t metrics.TunnelStatus) {
+	s.metrics.SetTunnel(t)
+}
+
 // ServeHTTP implements http.Handler.
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	s.router.ServeHTTP(w, r)
@@ -64,8 +95,9 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 // routes sets up the HTTP routes for the Worker Agent API.
 func (s *Server) routes() {
-	s.router.Use(middleware.Logger)
+	s.router.Use(logging.Middleware) // Picks up request/workshop IDs forwarded by the control plane
 	s.router.Use(middleware.Recoverer)
+	s.router.Use(tracing.Middleware) // Continues traces started by the control plane
 	s.router.Use(middleware.Timeout(60 * time.Second))
 
 	// CORS middleware for cross-origin requests from browser
@@ -82,6 +114,9 @@ func (s *Server) routes() {
 	// Health check - no auth required
 	s.router.Get("/health", s.handleHealth)
 
+	// Prometheus metrics - protected by its own token when configured
+	s.router.Method("GET", "/metrics", s.metrics.Handler(s.metricsTok))
+
 	// Control plane routes require agent authentication
 	s.router.Group(func(r chi.Router) {
 		r.Use(s.authMiddleware)
@@ -96,6 +131,13 @@ func (s *Server) routes() {
 			r.Get("/{workshopID}/{seatID}", s.handleGetVM)
 			r.Delete("/{workshopID}/{seatID}", s.handleDestroyVM)
 		})
+
+		// Terminal recordings, collected by the control plane when a workshop stops
+		r.Get("/recordings", s.handleListRecordings)
+		r.Get("/recordings/{workshopID}/{name}", s.handleGetRecording)
+
+		// Learner token revocation when a registration is revoked or moved
+		r.Post("/tokens/revoke", s.handleRevokeTokens)
 	})
 
 	// Proxy routes are public - auth handled by MicroVM's workspace server
@@ -108,6 +150,10 @@ func (s *Server) routes() {
 		r.Get("/{workshopID}/{seatID}/files", s.handleFilesProxy)
 		r.HandleFunc("/{workshopID}/{seatID}/files/*", s.handleFilesProxy)
 		r.Get("/{workshopID}/{seatID}/health", s.handleHealthProxy)
+		// Preview proxy for web apps learners run in their MicroVM (HTTP and WebSocket)
+		r.Options("/{workshopID}/{seatID}/preview/{port}/*", s.handleCORSPreflight)
+		r.HandleFunc("/{workshopID}/{seatID}/preview/{port}", s.handlePreviewProxy)
+		r.HandleFunc("/{workshopID}/{seatID}/preview/{port}/*", s.handlePreviewProxy)
 	})
 }
 
@@ -160,7 +206,7 @@ func (s *Server) writeJSON(w http.ResponseWriter, status int, v interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	if err := json.NewEncoder(w).Encode(v); err != nil {
-		s.logger.Errorf("Failed to encode JSON response: %v", err)
+		s.logger.Error("failed to encode JSON response", "error", err)
 	}
 }
 
@@ -173,13 +219,10 @@ func (s *Server) writeError(w http.ResponseWriter, status int, code, message str
 
 // getVMCount returns the current number of VMs managed by this worker.
 func (s *Server) getVMCount() int {
-	instances, err := s.provider.List(nil, "")
+	instances, err := s.provider.List(context.Background(), "")
 	if err != nil {
 		return 0
 	}
-	// List with empty workshopID returns all VMs, but we need to count all
-	// Since the provider tracks VMs in a map, we'll use a workaround
-	// For now, return 0 and we'll fix this in the orchestrator
 	return len(instances)
 }
 
@@ -0,0 +1,544 @@
+package agentapi
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/clarateach/backend/internal/auth"
+	"github.com/clarateach/backend/internal/logging"
+	"github.com/gorilla/websocket"
+)
+
+// observerBuffer is how many terminal messages an observer may fall behind
+// before it is disconnected. Dropping output instead would garble the mirror.
+const observerBuffer = 256
+
+// learnerWriteTimeout is how long a write to the learner may block. Writes
+// happen while holding the session's learnerMu, so a learner that stops
+// reading is disconnected rather than stalling the terminal for everyone; it
+// can resume once it reconnects.
+const learnerWriteTimeout = 10 * time.Second
+
+// defaultResumeGrace is how long a seat's shell outlives its learner's
+// WebSocket, waiting for the learner to reconnect with its resume token.
+const defaultResumeGrace = 2 * time.Minute
+
+// Who is typing into a seat's terminal, as reported in control messages.
+const (
+	controllerLearner    = "learner"
+	controllerInstructor = "instructor"
+)
+
+// terminalMessage is one WebSocket message from a seat's terminal.
+type terminalMessage struct {
+	messageType int
+	data        []byte
+}
+
+// controlMessage tells the learner and observers who controls the terminal.
+// It uses the same JSON framing as the terminal server's output messages.
+type controlMessage struct {
+	Type       string `json:"type"` // Always "control"
+	Controller string `json:"controller"`
+	UserID     string `json:"user_id,omitempty"`
+}
+
+// sessionMessage is the first message on every learner connection. The
+// learner passes resume_token back as ?resume= to reattach to the same shell
+// after a disconnect. Resumed is set when that happened, in which case the
+// buffered output follows and the client should clear its screen first.
+type sessionMessage struct {
+	Type        string `json:"type"` // Always "session"
+	ResumeToken string `json:"resume_token"`
+	Resumed     bool   `json:"resumed"`
+}
+
+// observerMessage is the subset of terminal client messages the hub inspects.
+type observerMessage struct {
+	Type   string `json:"type"`   // "input", "resize" or "control"
+	Action string `json:"action"` // For "control": "take" or "release"
+}
+
+// terminalHub tracks the terminal session of each seat: the MicroVM terminal
+// connection, the learner connected to it (if any) and staff mirroring it. A
+// session survives learner disconnects for the grace period so the learner
+// can resume it.
+type terminalHub struct {
+	mu    sync.Mutex
+	seats map[string]*seatTerminal
+	grace time.Duration
+}
+
+func newTerminalHub(grace time.Duration) *terminalHub {
+	return &terminalHub{seats: make(map[string]*seatTerminal), grace: grace}
+}
+
+func seatKey(workshopID string, seatID int) string {
+	return workshopID + "/" + strconv.Itoa(seatID)
+}
+
+// attach starts a terminal session for the seat with a new MicroVM terminal
+// connection and tells the learner its resume token. An earlier session for
+// the seat stops being shared with staff and can no longer be resumed. rec is
+// nil when recording is off.
+func (h *terminalHub) attach(workshopID string, seatID int, learner, backend *websocket.Conn, rec *castRecorder) (*seatTerminal, error) {
+	token, err := newResumeToken()
+	if err != nil {
+		return nil, err
+	}
+	t := &seatTerminal{
+		learner:     learner,
+		backend:     backend,
+		rec:         rec,
+		resumeToken: token,
+		observers:   make(map[chan terminalMessage]struct{}),
+	}
+	if err := t.writeLearner(t.sessionMessage(false)); err != nil {
+		return nil, err
+	}
+
+	h.mu.Lock()
+	old := h.seats[seatKey(workshopID, seatID)]
+	h.seats[seatKey(workshopID, seatID)] = t
+	h.mu.Unlock()
+	if old != nil {
+		old.orphan()
+	}
+	return t, nil
+}
+
+// resume reattaches a learner connection to the seat's session if token
+// matches it, replaying buffered output. A connection still attached to the
+// session is closed. It returns nil if there is no session to resume.
+func (h *terminalHub) resume(workshopID string, seatID int, token string, learner *websocket.Conn) *seatTerminal {
+	t := h.get(workshopID, seatID)
+	if t == nil || token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(t.resumeToken)) != 1 {
+		return nil
+	}
+	if !t.reattach(learner) {
+		return nil
+	}
+	return t
+}
+
+// release detaches a learner connection that has closed. The session stays
+// open for the grace period unless it was replaced in the meantime.
+func (h *terminalHub) release(workshopID string, seatID int, t *seatTerminal, learner *websocket.Conn) {
+	h.mu.Lock()
+	current := h.seats[seatKey(workshopID, seatID)] == t
+	h.mu.Unlock()
+
+	t.learnerMu.Lock()
+	if t.learner != learner {
+		t.learnerMu.Unlock() // Already replaced by a resumed connection
+		return
+	}
+	t.learner = nil
+	if current {
+		t.graceTimer = time.AfterFunc(h.grace, func() { h.expire(workshopID, seatID, t) })
+	}
+	t.learnerMu.Unlock()
+
+	if !current {
+		t.close()
+	}
+}
+
+// expire ends a session whose learner did not come back in time.
+func (h *terminalHub) expire(workshopID string, seatID int, t *seatTerminal) {
+	t.learnerMu.Lock()
+	if t.learner != nil {
+		t.learnerMu.Unlock() // Resumed just as the timer fired
+		return
+	}
+	t.expired = true
+	t.learnerMu.Unlock()
+	h.detach(workshopID, seatID, t)
+}
+
+// detach ends the session, closing its connections and disconnecting its
+// observers.
+func (h *terminalHub) detach(workshopID string, seatID int, t *seatTerminal) {
+	h.mu.Lock()
+	if h.seats[seatKey(workshopID, seatID)] == t {
+		delete(h.seats, seatKey(workshopID, seatID))
+	}
+	h.mu.Unlock()
+	t.close()
+}
+
+// get returns the seat's terminal session, or nil if it has none.
+func (h *terminalHub) get(workshopID string, seatID int) *seatTerminal {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.seats[seatKey(workshopID, seatID)]
+}
+
+func newResumeToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// seatTerminal fans one terminal session's output out to the learner and
+// observers and routes input from whoever is in control to the MicroVM.
+type seatTerminal struct {
+	backend     *websocket.Conn
+	backendMu   sync.Mutex // Serializes writes to backend
+	rec         *castRecorder
+	resumeToken string
+
+	learnerMu  sync.Mutex      // Guards learner, graceTimer and expired and serializes writes to learner. Taken before mu.
+	learner    *websocket.Conn // Nil while the learner is disconnected
+	graceTimer *time.Timer     // Running while the learner is disconnected
+	expired    bool
+
+	mu         sync.Mutex
+	scrollback scrollback
+	observers  map[chan terminalMessage]struct{}
+	controller string // User ID of the instructor in control; empty while the learner is
+	closed     bool
+}
+
+// subscribe returns a channel of terminal output that is closed when the
+// session ends or is replaced, or the observer falls too far behind. ok is
+// false if the terminal has already closed.
+func (t *seatTerminal) subscribe() (ch chan terminalMessage, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil, false
+	}
+	ch = make(chan terminalMessage, observerBuffer)
+	if t.controller != "" {
+		ch <- t.controlMessageLocked()
+	}
+	t.observers[ch] = struct{}{}
+	return ch, true
+}
+
+func (t *seatTerminal) unsubscribe(ch chan terminalMessage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.observers[ch]; ok {
+		delete(t.observers, ch)
+		close(ch)
+	}
+}
+
+// fromBackend delivers MicroVM terminal output to the learner and observers
+// and keeps it for replay. Output while the learner is disconnected is only
+// kept. learnerMu is held throughout so a resuming learner gets each message
+// exactly once, either in the replay or live.
+func (t *seatTerminal) fromBackend(messageType int, data []byte) {
+	t.rec.output(data)
+	msg := terminalMessage{messageType: messageType, data: data}
+
+	t.learnerMu.Lock()
+	defer t.learnerMu.Unlock()
+	t.mu.Lock()
+	t.scrollback.add(msg)
+	t.broadcastLocked(msg)
+	t.mu.Unlock()
+
+	// A failed write means the learner is gone; its read loop notices too
+	t.writeLearnerLocked(msg)
+}
+
+// reattach hands the session to a resuming learner connection, closing any
+// connection still attached, and replays the buffered output. It returns false
+// if the session has already ended.
+func (t *seatTerminal) reattach(learner *websocket.Conn) bool {
+	t.learnerMu.Lock()
+	defer t.learnerMu.Unlock()
+	if t.expired {
+		return false
+	}
+
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return false
+	}
+	replay := t.scrollback.messages()
+	var control *terminalMessage
+	if t.controller != "" {
+		msg := t.controlMessageLocked()
+		control = &msg
+	}
+	t.mu.Unlock()
+
+	if t.graceTimer != nil {
+		t.graceTimer.Stop()
+		t.graceTimer = nil
+	}
+	if t.learner != nil {
+		t.learner.Close() // Its read loop ends and releases nothing, as it is no longer attached
+	}
+	t.learner = learner
+
+	if t.writeLearnerLocked(t.sessionMessage(true)) != nil {
+		return true
+	}
+	for _, msg := range replay {
+		if t.writeLearnerLocked(msg) != nil {
+			return true
+		}
+	}
+	if control != nil {
+		t.writeLearnerLocked(*control)
+	}
+	return true
+}
+
+// fromLearner forwards learner input to the MicroVM unless an instructor has
+// taken control.
+func (t *seatTerminal) fromLearner(messageType int, data []byte) error {
+	t.mu.Lock()
+	controlled := t.controller != ""
+	t.mu.Unlock()
+	if controlled {
+		return nil
+	}
+	return t.writeBackend(messageType, data)
+}
+
+// fromObserver handles a message from a staff connection. Observers are read
+// only; instructors may send {"type":"control","action":"take"|"release"} and,
+// while in control, terminal input.
+func (t *seatTerminal) fromObserver(claims *auth.WorkspaceClaims, messageType int, data []byte) error {
+	if !claims.CanTakeControl() {
+		return nil
+	}
+	var msg observerMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil
+	}
+
+	if msg.Type == "control" {
+		switch msg.Action {
+		case "take":
+			t.setController(claims.UserID, "")
+		case "release":
+			t.setController("", claims.UserID)
+		}
+		return nil
+	}
+
+	t.mu.Lock()
+	inControl := t.controller == claims.UserID
+	t.mu.Unlock()
+	if !inControl {
+		return nil
+	}
+	return t.writeBackend(messageType, data)
+}
+
+// setController hands control to userID ("" for the learner) and announces
+// it. If only is set, the change applies only while only holds control.
+func (t *seatTerminal) setController(userID, only string) {
+	t.mu.Lock()
+	if t.closed || t.controller == userID || (only != "" && t.controller != only) {
+		t.mu.Unlock()
+		return
+	}
+	t.controller = userID
+	msg := t.controlMessageLocked()
+	t.broadcastLocked(msg)
+	t.mu.Unlock()
+
+	t.writeLearner(msg)
+}
+
+func (t *seatTerminal) controlMessageLocked() terminalMessage {
+	msg := controlMessage{Type: "control", Controller: controllerLearner}
+	if t.controller != "" {
+		msg.Controller = controllerInstructor
+		msg.UserID = t.controller
+	}
+	data, _ := json.Marshal(msg)
+	return terminalMessage{messageType: websocket.TextMessage, data: data}
+}
+
+func (t *seatTerminal) sessionMessage(resumed bool) terminalMessage {
+	data, _ := json.Marshal(sessionMessage{Type: "session", ResumeToken: t.resumeToken, Resumed: resumed})
+	return terminalMessage{messageType: websocket.TextMessage, data: data}
+}
+
+// broadcastLocked sends a message to every observer, disconnecting any that
+// have fallen too far behind.
+func (t *seatTerminal) broadcastLocked(msg terminalMessage) {
+	for ch := range t.observers {
+		select {
+		case ch <- msg:
+		default:
+			delete(t.observers, ch)
+			close(ch)
+		}
+	}
+}
+
+// writeLearner sends a message to the learner if one is connected.
+func (t *seatTerminal) writeLearner(msg terminalMessage) error {
+	t.learnerMu.Lock()
+	defer t.learnerMu.Unlock()
+	return t.writeLearnerLocked(msg)
+}
+
+// writeLearnerLocked sends a message to the learner if one is connected,
+// closing the connection if the write fails or times out. The learner's read
+// loop then releases the session. Callers hold learnerMu.
+func (t *seatTerminal) writeLearnerLocked(msg terminalMessage) error {
+	if t.learner == nil {
+		return nil
+	}
+	t.learner.SetWriteDeadline(time.Now().Add(learnerWriteTimeout))
+	if err := t.learner.WriteMessage(msg.messageType, msg.data); err != nil {
+		t.learner.Close()
+		return err
+	}
+	return nil
+}
+
+func (t *seatTerminal) writeBackend(messageType int, data []byte) error {
+	t.backendMu.Lock()
+	defer t.backendMu.Unlock()
+	t.rec.input(data)
+	return t.backend.WriteMessage(messageType, data)
+}
+
+// orphan is called when a new session replaces this one. Staff stop
+// mirroring it and it ends as soon as its learner leaves, or now if the
+// learner already has.
+func (t *seatTerminal) orphan() {
+	t.mu.Lock()
+	for ch := range t.observers {
+		delete(t.observers, ch)
+		close(ch)
+	}
+	t.mu.Unlock()
+
+	t.learnerMu.Lock()
+	detached := t.learner == nil
+	t.learnerMu.Unlock()
+	if detached {
+		t.close()
+	}
+}
+
+// close ends the session: the MicroVM terminal connection, the learner's
+// connection, observers and the recording. It is safe to call more than once.
+func (t *seatTerminal) close() {
+	t.learnerMu.Lock()
+	t.expired = true
+	if t.graceTimer != nil {
+		t.graceTimer.Stop()
+		t.graceTimer = nil
+	}
+	if t.learner != nil {
+		t.learner.Close()
+	}
+	t.learnerMu.Unlock()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return
+	}
+	t.closed = true
+	for ch := range t.observers {
+		close(ch)
+	}
+	t.observers = nil
+	t.backend.Close()
+	t.rec.close()
+}
+
+// pumpTerminal reads MicroVM terminal output for a session until the
+// connection closes, which ends the session.
+func (s *Server) pumpTerminal(ctx context.Context, workshopID string, seatID int, t *seatTerminal) {
+	defer s.terminals.detach(workshopID, seatID, t)
+	for {
+		messageType, message, err := t.backend.ReadMessage()
+		if err != nil {
+			s.logger.InfoContext(ctx, "terminal session ended", "reason", err.Error())
+			return
+		}
+		s.metrics.AddProxiedBytes(workshopID, seatID, "terminal", "out", len(message))
+		t.fromBackend(messageType, message)
+	}
+}
+
+// observeTerminal mirrors the learner's live terminal to a staff connection.
+// Observers are read only; instructors can also take control (see
+// seatTerminal.fromObserver), which is handed back when they disconnect.
+func (s *Server) observeTerminal(w http.ResponseWriter, r *http.Request, workshopID string, seatID int, claims *auth.WorkspaceClaims) {
+	ctx := logging.WithSeat(r.Context(), workshopID, seatID)
+
+	term := s.terminals.get(workshopID, seatID)
+	if term == nil {
+		s.writeError(w, http.StatusConflict, "not_connected", "Learner does not have a terminal open")
+		return
+	}
+	ch, ok := term.subscribe()
+	if !ok {
+		s.writeError(w, http.StatusConflict, "not_connected", "Learner does not have a terminal open")
+		return
+	}
+	defer term.unsubscribe(ch)
+
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to upgrade WebSocket", "error", err)
+		return
+	}
+	defer conn.Close()
+	defer s.metrics.WebSocketOpened("terminal_observer")()
+	if claims.CanTakeControl() {
+		defer term.setController("", claims.UserID)
+	}
+
+	s.logger.InfoContext(ctx, "observer attached to terminal", "user_id", claims.UserID, "access", claims.Access)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			messageType, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := term.fromObserver(claims, messageType, message); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			s.logger.InfoContext(ctx, "observer detached from terminal", "user_id", claims.UserID)
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseNormalClosure, fmt.Sprintf("Seat %d terminal closed", seatID)))
+				return
+			}
+			s.metrics.AddProxiedBytes(workshopID, seatID, "terminal_observer", "out", len(msg.data))
+			if err := conn.WriteMessage(msg.messageType, msg.data); err != nil {
+				return
+			}
+		}
+	}
+}
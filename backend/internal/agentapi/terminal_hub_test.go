@@ -0,0 +1,180 @@
+package agentapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// wsPair returns the server and client ends of a WebSocket connection.
+func wsPair(t *testing.T) (server, client *websocket.Conn) {
+	t.Helper()
+	conns := make(chan *websocket.Conn, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := wsUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Upgrade() error = %v", err)
+			return
+		}
+		conns <- conn
+	}))
+	t.Cleanup(ts.Close)
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	server = <-conns
+	t.Cleanup(func() { server.Close() })
+	return server, client
+}
+
+func readText(t *testing.T, conn *websocket.Conn) string {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+	return string(data)
+}
+
+func readSession(t *testing.T, conn *websocket.Conn) sessionMessage {
+	t.Helper()
+	var msg sessionMessage
+	if err := json.Unmarshal([]byte(readText(t, conn)), &msg); err != nil || msg.Type != "session" {
+		t.Fatalf("First message = %+v (%v), want a session message", msg, err)
+	}
+	return msg
+}
+
+func TestScrollback(t *testing.T) {
+	var b scrollback
+	chunk := strings.Repeat("x", scrollbackBytes/4)
+	for i := 0; i < 10; i++ {
+		b.add(terminalMessage{messageType: websocket.TextMessage, data: []byte(fmt.Sprintf("%d%s", i, chunk[1:]))})
+	}
+
+	// Only the newest messages that fit are kept, oldest first
+	msgs := b.messages()
+	if len(msgs) != 4 {
+		t.Fatalf("Kept %d messages, want 4", len(msgs))
+	}
+	for i, msg := range msgs {
+		if want := byte('6' + i); msg.data[0] != want {
+			t.Errorf("Message %d starts with %q, want %q", i, msg.data[0], want)
+		}
+	}
+
+	// A message larger than the limit evicts everything else but is kept
+	b.add(terminalMessage{messageType: websocket.TextMessage, data: make([]byte, scrollbackBytes+1)})
+	if msgs := b.messages(); len(msgs) != 1 || len(msgs[0].data) != scrollbackBytes+1 {
+		t.Errorf("After oversized message: %d messages, want only it", len(msgs))
+	}
+
+	// Many small messages wrap around the ring in order
+	b = scrollback{}
+	for i := 0; i < 20000; i++ {
+		b.add(terminalMessage{messageType: websocket.TextMessage, data: []byte(fmt.Sprintf("%08d", i))})
+	}
+	msgs = b.messages()
+	first := 20000 - scrollbackBytes/8
+	if len(msgs) != scrollbackBytes/8 || string(msgs[0].data) != fmt.Sprintf("%08d", first) || string(msgs[len(msgs)-1].data) != "00019999" {
+		t.Errorf("Ring holds %d messages from %s to %s, want %d from %08d to 00019999", len(msgs), msgs[0].data, msgs[len(msgs)-1].data, scrollbackBytes/8, first)
+	}
+}
+
+func TestTerminalResumeWithinGrace(t *testing.T) {
+	hub := newTerminalHub(time.Minute)
+	learner, learnerClient := wsPair(t)
+	backend, _ := wsPair(t)
+
+	term, err := hub.attach("ws-1", 1, learner, backend, nil)
+	if err != nil {
+		t.Fatalf("attach() error = %v", err)
+	}
+	session := readSession(t, learnerClient)
+	if session.Resumed || session.ResumeToken == "" {
+		t.Fatalf("Session = %+v, want a new session with a resume token", session)
+	}
+
+	term.fromBackend(websocket.TextMessage, []byte("before"))
+	if got := readText(t, learnerClient); got != "before" {
+		t.Errorf("Live output = %q, want before", got)
+	}
+
+	// Output while the learner is away is kept for the resume
+	hub.release("ws-1", 1, term, learner)
+	term.fromBackend(websocket.TextMessage, []byte("while away"))
+
+	other, _ := wsPair(t)
+	if hub.resume("ws-1", 1, "wrong-token", other) != nil {
+		t.Error("resume() with the wrong token should fail")
+	}
+
+	resumed, resumedClient := wsPair(t)
+	if hub.resume("ws-1", 1, session.ResumeToken, resumed) != term {
+		t.Fatal("resume() within the grace period should reattach the session")
+	}
+	if session := readSession(t, resumedClient); !session.Resumed {
+		t.Errorf("Session = %+v, want resumed", session)
+	}
+	for _, want := range []string{"before", "while away"} {
+		if got := readText(t, resumedClient); got != want {
+			t.Errorf("Replayed %q, want %q", got, want)
+		}
+	}
+
+	term.fromBackend(websocket.TextMessage, []byte("after"))
+	if got := readText(t, resumedClient); got != "after" {
+		t.Errorf("Live output after resume = %q, want after", got)
+	}
+}
+
+func TestTerminalGraceExpiry(t *testing.T) {
+	hub := newTerminalHub(20 * time.Millisecond)
+	learner, learnerClient := wsPair(t)
+	backend, backendClient := wsPair(t)
+
+	term, err := hub.attach("ws-1", 1, learner, backend, nil)
+	if err != nil {
+		t.Fatalf("attach() error = %v", err)
+	}
+	session := readSession(t, learnerClient)
+	ch, ok := term.subscribe()
+	if !ok {
+		t.Fatal("subscribe() failed on an open session")
+	}
+
+	hub.release("ws-1", 1, term, learner)
+	time.Sleep(100 * time.Millisecond)
+
+	// The session ends: it cannot be resumed, observers are disconnected and
+	// the MicroVM terminal connection is closed
+	if hub.get("ws-1", 1) != nil {
+		t.Error("Session still registered after the grace period")
+	}
+	resumed, _ := wsPair(t)
+	if hub.resume("ws-1", 1, session.ResumeToken, resumed) != nil {
+		t.Error("resume() after the grace period should fail")
+	}
+	if _, open := <-ch; open {
+		t.Error("Observer channel still open after the grace period")
+	}
+	backendClient.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, _, err := backendClient.ReadMessage(); err == nil || isTimeout(err) {
+		t.Errorf("MicroVM terminal connection read = %v, want closed", err)
+	}
+}
+
+func isTimeout(err error) bool {
+	netErr, ok := err.(interface{ Timeout() bool })
+	return ok && netErr.Timeout()
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/clarateach/backend/internal/auth"
+	"github.com/clarateach/backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// permission is an action on a workshop that access checks guard.
+type permission int
+
+const (
+	permView            permission = iota // Workshop details, timeline and live stream
+	permManageLearners                    // Learner lists and seat management
+	permStartStop                         // Start, stop and update (including seats)
+	permControlTerminal                   // Take control of a learner's terminal
+	permDelete                            // Delete the workshop
+	permManageMembers                     // Add and remove co-instructors and TAs
+)
+
+// Workshop access roles. The owner and admins hold every permission; members
+// hold the permissions of their store.MemberRole*.
+const (
+	accessRoleOwner = "owner"
+	accessRoleAdmin = "admin"
+)
+
+var rolePermissions = map[string][]permission{
+	store.MemberRoleCoInstructor: {permView, permManageLearners, permStartStop, permControlTerminal},
+	store.MemberRoleTA:           {permView, permManageLearners},
+}
+
+// workshopRole returns the user's role on a workshop, or "" if they have no
+// access. Workshops without an owner predate authentication and only admins
+// can reach them.
+func (s *Server) workshopRole(user *store.User, workshop *store.Workshop) (string, error) {
+	if user.Role == "admin" {
+		return accessRoleAdmin, nil
+	}
+	if workshop.OwnerID == "" {
+		return "", nil
+	}
+	if workshop.OwnerID == user.ID {
+		return accessRoleOwner, nil
+	}
+	member, err := s.store.GetWorkshopMember(workshop.ID, user.ID)
+	if err != nil || member == nil {
+		return "", err
+	}
+	return member.Role, nil
+}
+
+// roleAllows reports whether role grants perm.
+func roleAllows(role string, perm permission) bool {
+	if role == accessRoleOwner || role == accessRoleAdmin {
+		return true
+	}
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// requireWorkshop returns middleware that lets the request through only if
+// the authenticated user holds perm on the workshop named by the {id} URL
+// parameter. Users with no access get 404 so workshop IDs are not disclosed.
+func (s *Server) requireWorkshop(perm permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := auth.GetUserFromContext(r.Context())
+			if user == nil {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			workshop, err := s.store.GetWorkshop(chi.URLParam(r, "id"))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if workshop == nil {
+				http.Error(w, "Workshop not found", http.StatusNotFound)
+				return
+			}
+
+			role, err := s.workshopRole(user, workshop)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if role == "" {
+				http.Error(w, "Workshop not found", http.StatusNotFound)
+				return
+			}
+			if !roleAllows(role, perm) {
+				http.Error(w, "You do not have permission to do this on this workshop", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ================== Workshop Member Handlers ==================
+
+func (s *Server) listWorkshopMembers(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	members, err := s.store.ListWorkshopMembers(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	result := make([]map[string]interface{}, 0, len(members))
+	for _, m := range members {
+		entry := map[string]interface{}{
+			"user_id":    m.UserID,
+			"role":       m.Role,
+			"added_by":   m.AddedBy,
+			"created_at": m.CreatedAt,
+		}
+		if u, err := s.store.GetUser(m.UserID); err == nil && u != nil {
+			entry["email"] = u.Email
+			entry["name"] = u.Name
+		}
+		result = append(result, entry)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"members": result})
+}
+
+func (s *Server) addWorkshopMember(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req struct {
+		Email string `json:"email"`
+		Role  string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if _, ok := rolePermissions[req.Role]; !ok {
+		http.Error(w, "Role must be co_instructor or ta", http.StatusBadRequest)
+		return
+	}
+
+	// Members must already have an instructor account
+	member, err := s.store.GetUserByEmail(strings.TrimSpace(req.Email))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if member == nil {
+		http.Error(w, "No user with that email", http.StatusNotFound)
+		return
+	}
+
+	workshop, err := s.store.GetWorkshop(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if member.ID == workshop.OwnerID {
+		http.Error(w, "The owner already has full access", http.StatusConflict)
+		return
+	}
+
+	// Re-adding a member changes their role
+	if err := s.store.RemoveWorkshopMember(id, member.ID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	m := &store.WorkshopMember{
+		WorkshopID: id,
+		UserID:     member.ID,
+		Role:       req.Role,
+		AddedBy:    auth.GetUserFromContext(r.Context()).ID,
+		CreatedAt:  time.Now(),
+	}
+	if err := s.store.AddWorkshopMember(m); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"member": m})
+}
+
+func (s *Server) removeWorkshopMember(w http.ResponseWriter, r *http.Request) {
+	if err := s.store.RemoveWorkshopMember(chi.URLParam(r, "id"), chi.URLParam(r, "userID")); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
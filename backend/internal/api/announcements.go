@@ -0,0 +1,177 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/clarateach/backend/internal/auth"
+	"github.com/clarateach/backend/internal/events"
+	"github.com/clarateach/backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxAnnouncementLength caps an announcement, in characters.
+const maxAnnouncementLength = 1000
+
+// createAnnouncement stores a message for every learner in the workshop and
+// pushes it to learners with the workspace open.
+func (s *Server) createAnnouncement(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req struct {
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	message := strings.TrimSpace(req.Message)
+	if message == "" {
+		http.Error(w, "Message is required", http.StatusBadRequest)
+		return
+	}
+	if utf8.RuneCountInString(message) > maxAnnouncementLength {
+		http.Error(w, "Message is too long", http.StatusBadRequest)
+		return
+	}
+
+	user := auth.GetUserFromContext(r.Context())
+	a := &store.Announcement{
+		WorkshopID: id,
+		Message:    message,
+		AuthorID:   user.ID,
+		AuthorName: user.Name,
+		CreatedAt:  time.Now(),
+	}
+	if err := s.store.CreateAnnouncement(a); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.events.Publish(events.Event{
+		Type:       events.TypeAnnouncement,
+		WorkshopID: id,
+		Data:       announcementData(a),
+		Time:       a.CreatedAt,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"announcement": a})
+}
+
+func (s *Server) listAnnouncements(w http.ResponseWriter, r *http.Request) {
+	s.writeAnnouncements(w, chi.URLParam(r, "id"))
+}
+
+// listSessionAnnouncements returns the announcements of a learner's workshop
+// so learners who join late catch up. The access code is the credential.
+func (s *Server) listSessionAnnouncements(w http.ResponseWriter, r *http.Request) {
+	registration, ok := s.sessionRegistration(w, r)
+	if !ok {
+		return
+	}
+	s.writeAnnouncements(w, registration.WorkshopID)
+}
+
+// streamSessionAnnouncements streams a learner's workshop announcements as
+// Server-Sent Events: a snapshot with every announcement so far, then each
+// new one as it is posted.
+func (s *Server) streamSessionAnnouncements(w http.ResponseWriter, r *http.Request) {
+	registration, ok := s.sessionRegistration(w, r)
+	if !ok {
+		return
+	}
+
+	// Subscribe before loading history so no announcement falls in between
+	ch, unsubscribe := s.events.Subscribe(registration.WorkshopID)
+	defer unsubscribe()
+
+	announcements, err := s.store.ListAnnouncements(registration.WorkshopID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	history := make([]map[string]interface{}, 0, len(announcements))
+	for _, a := range announcements {
+		history = append(history, announcementData(a))
+	}
+
+	s.serveEvents(w, r, filterEvents(ch, events.TypeAnnouncement), events.Event{
+		Type:       events.TypeSnapshot,
+		WorkshopID: registration.WorkshopID,
+		Data:       map[string]interface{}{"announcements": history},
+	})
+}
+
+// sessionRegistration looks up the registration for the {code} URL parameter,
+// responding with 404 if there is none and 403 if it was revoked or is still
+// waitlisted.
+func (s *Server) sessionRegistration(w http.ResponseWriter, r *http.Request) (*store.Registration, bool) {
+	registration, err := s.store.GetRegistration(chi.URLParam(r, "code"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if registration == nil {
+		http.Error(w, "Invalid access code", http.StatusNotFound)
+		return nil, false
+	}
+	if registration.Status == "revoked" {
+		http.Error(w, "Registration has been revoked", http.StatusForbidden)
+		return nil, false
+	}
+	if registration.Status == "waitlisted" {
+		http.Error(w, "Registration is on the waitlist", http.StatusForbidden)
+		return nil, false
+	}
+	return registration, true
+}
+
+func (s *Server) writeAnnouncements(w http.ResponseWriter, workshopID string) {
+	announcements, err := s.store.ListAnnouncements(workshopID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	result := make([]map[string]interface{}, 0, len(announcements))
+	for _, a := range announcements {
+		result = append(result, announcementData(a))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"announcements": result})
+}
+
+// announcementData is the announcement payload shared by lists, snapshots and
+// announcement events. Learners see the author's name but not their ID.
+func announcementData(a *store.Announcement) map[string]interface{} {
+	return map[string]interface{}{
+		"id":          a.ID,
+		"message":     a.Message,
+		"author_name": a.AuthorName,
+		"created_at":  a.CreatedAt,
+	}
+}
+
+// filterEvents passes on only events of the given types. The returned
+// channel closes when ch does.
+func filterEvents(ch <-chan events.Event, types ...string) <-chan events.Event {
+	out := make(chan events.Event, cap(ch))
+	go func() {
+		defer close(out)
+		for e := range ch {
+			if !slices.Contains(types, e.Type) {
+				continue
+			}
+			select {
+			case out <- e:
+			default:
+				// Reader is not keeping up; drop like the bus does
+			}
+		}
+	}()
+	return out
+}
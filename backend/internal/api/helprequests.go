@@ -0,0 +1,282 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/clarateach/backend/internal/auth"
+	"github.com/clarateach/backend/internal/events"
+	"github.com/clarateach/backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxHelpMessageLength caps the optional note on a help request, in characters.
+const maxHelpMessageLength = 500
+
+// getSessionHelp returns the learner's open or claimed help request, if any,
+// and its place in the queue. The access code is the credential.
+func (s *Server) getSessionHelp(w http.ResponseWriter, r *http.Request) {
+	registration, ok := s.sessionRegistration(w, r)
+	if !ok {
+		return
+	}
+	h, err := s.store.GetActiveHelpRequest(registration.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.writeSessionHelp(w, h)
+}
+
+// raiseHand queues a help request for the learner's seat. Raising a hand
+// again while one is queued returns the existing request.
+func (s *Server) raiseHand(w http.ResponseWriter, r *http.Request) {
+	registration, ok := s.sessionRegistration(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Message string `json:"message"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	message := strings.TrimSpace(req.Message)
+	if utf8.RuneCountInString(message) > maxHelpMessageLength {
+		http.Error(w, "Message is too long", http.StatusBadRequest)
+		return
+	}
+
+	if registration.SeatID == nil {
+		http.Error(w, "Join the workshop before asking for help", http.StatusConflict)
+		return
+	}
+	workshop, err := s.store.GetWorkshop(registration.WorkshopID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if workshop == nil || workshop.Status != "running" {
+		http.Error(w, "Workshop is not running", http.StatusConflict)
+		return
+	}
+
+	h, err := s.store.GetActiveHelpRequest(registration.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if h == nil {
+		h = &store.HelpRequest{
+			WorkshopID:     registration.WorkshopID,
+			RegistrationID: registration.ID,
+			SeatID:         *registration.SeatID,
+			LearnerName:    registration.Name,
+			Message:        message,
+			Status:         store.HelpRequestOpen,
+			CreatedAt:      time.Now(),
+		}
+		if err := s.store.CreateHelpRequest(h); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.publishHelpRequest(h)
+	}
+	s.writeSessionHelp(w, h)
+}
+
+// lowerHand withdraws the learner's help request.
+func (s *Server) lowerHand(w http.ResponseWriter, r *http.Request) {
+	registration, ok := s.sessionRegistration(w, r)
+	if !ok {
+		return
+	}
+	h, err := s.store.GetActiveHelpRequest(registration.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if h != nil {
+		if _, ok := s.resolveHelpRequest(w, h); !ok {
+			return
+		}
+	}
+	s.writeSessionHelp(w, nil)
+}
+
+// writeSessionHelp responds with a learner's help request and, while it is
+// open, how many open requests are ahead of it plus one.
+func (s *Server) writeSessionHelp(w http.ResponseWriter, h *store.HelpRequest) {
+	resp := map[string]interface{}{"help_request": nil}
+	if h != nil {
+		resp["help_request"] = helpRequestData(h)
+		if h.Status == store.HelpRequestOpen {
+			requests, err := s.store.ListHelpRequests(h.WorkshopID)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			position := 1
+			for _, other := range requests {
+				if other.Status == store.HelpRequestOpen && other.ID < h.ID {
+					position++
+				}
+			}
+			resp["position"] = position
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// listHelpRequests returns the workshop's help queue, oldest first. By default
+// only open and claimed requests are listed; ?status= selects one status or
+// "all".
+func (s *Server) listHelpRequests(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	status := r.URL.Query().Get("status")
+
+	requests, err := s.store.ListHelpRequests(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	result := make([]*store.HelpRequest, 0, len(requests))
+	for _, h := range requests {
+		switch status {
+		case "":
+			if h.Status == store.HelpRequestResolved {
+				continue
+			}
+		case "all":
+		default:
+			if h.Status != status {
+				continue
+			}
+		}
+		result = append(result, h)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"help_requests": result})
+}
+
+// claimHelpRequest assigns an open help request to the calling staff member.
+// The response carries the seat so the client can open the learner's
+// terminal with /learners/{seat}/observe.
+func (s *Server) claimHelpRequest(w http.ResponseWriter, r *http.Request) {
+	h, ok := s.workshopHelpRequest(w, r)
+	if !ok {
+		return
+	}
+
+	user := auth.GetUserFromContext(r.Context())
+	claimed, err := s.store.ClaimHelpRequest(h.ID, user.ID, user.Name, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !claimed {
+		http.Error(w, "Help request is not open", http.StatusConflict)
+		return
+	}
+	if h, err = s.store.GetHelpRequest(h.ID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.publishHelpRequest(h)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"help_request": h})
+}
+
+// completeHelpRequest marks a help request resolved, whether or not it was
+// claimed.
+func (s *Server) completeHelpRequest(w http.ResponseWriter, r *http.Request) {
+	h, ok := s.workshopHelpRequest(w, r)
+	if !ok {
+		return
+	}
+	h, ok = s.resolveHelpRequest(w, h)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"help_request": h})
+}
+
+// resolveHelpRequest resolves h and publishes the change, responding with 409
+// if it was already resolved. It returns the updated request.
+func (s *Server) resolveHelpRequest(w http.ResponseWriter, h *store.HelpRequest) (*store.HelpRequest, bool) {
+	resolved, err := s.store.ResolveHelpRequest(h.ID, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if !resolved {
+		http.Error(w, "Help request is already resolved", http.StatusConflict)
+		return nil, false
+	}
+	if h, err = s.store.GetHelpRequest(h.ID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	s.publishHelpRequest(h)
+	return h, true
+}
+
+// workshopHelpRequest looks up the {requestID} URL parameter, responding with
+// 404 unless it belongs to the {id} workshop.
+func (s *Server) workshopHelpRequest(w http.ResponseWriter, r *http.Request) (*store.HelpRequest, bool) {
+	requestID, err := strconv.ParseInt(chi.URLParam(r, "requestID"), 10, 64)
+	if err != nil {
+		http.Error(w, "Help request not found", http.StatusNotFound)
+		return nil, false
+	}
+	h, err := s.store.GetHelpRequest(requestID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if h == nil || h.WorkshopID != chi.URLParam(r, "id") {
+		http.Error(w, "Help request not found", http.StatusNotFound)
+		return nil, false
+	}
+	return h, true
+}
+
+func (s *Server) publishHelpRequest(h *store.HelpRequest) {
+	s.events.Publish(events.Event{
+		Type:       events.TypeHelpRequest,
+		WorkshopID: h.WorkshopID,
+		SeatID:     h.SeatID,
+		Data:       helpRequestData(h),
+		Time:       time.Now(),
+	})
+}
+
+// helpRequestData is the help request payload for learners and events. It
+// leaves out the registration and staff user IDs.
+func helpRequestData(h *store.HelpRequest) map[string]interface{} {
+	return map[string]interface{}{
+		"id":              h.ID,
+		"seat_id":         h.SeatID,
+		"learner_name":    h.LearnerName,
+		"message":         h.Message,
+		"status":          h.Status,
+		"claimed_by_name": h.ClaimedByName,
+		"created_at":      h.CreatedAt,
+		"claimed_at":      h.ClaimedAt,
+		"resolved_at":     h.ResolvedAt,
+	}
+}
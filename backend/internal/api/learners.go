@@ -0,0 +1,133 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/clarateach/backend/internal/auth"
+	"github.com/go-chi/chi/v5"
+)
+
+// listLearners returns every seat with the learner occupying it, for staff
+// following along during a workshop.
+func (s *Server) listLearners(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	sessions, err := s.store.ListSessions(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	registrations, err := s.store.ListRegistrations(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	emails := make(map[int]string)
+	for _, reg := range registrations {
+		if reg.SeatID != nil {
+			emails[*reg.SeatID] = reg.Email
+		}
+	}
+
+	learners := make([]map[string]interface{}, 0, len(sessions))
+	for _, sess := range sessions {
+		learners = append(learners, map[string]interface{}{
+			"seat_id":   sess.SeatID,
+			"name":      sess.Name,
+			"email":     emails[sess.SeatID],
+			"status":    sess.Status,
+			"joined_at": sess.JoinedAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"learners": learners})
+}
+
+// observeLearner issues a read-only workspace token for mirroring a learner's
+// terminal through the agent proxy. Observer tokens cannot send input or use
+// the files API.
+func (s *Server) observeLearner(w http.ResponseWriter, r *http.Request) {
+	s.issueStaffTerminalToken(w, r, false)
+}
+
+// controlLearner issues an instructor workspace token that mirrors a learner's
+// terminal like an observer token but can also take control of it. The
+// learner is told while an instructor is in control.
+func (s *Server) controlLearner(w http.ResponseWriter, r *http.Request) {
+	s.issueStaffTerminalToken(w, r, true)
+}
+
+// issueStaffTerminalToken responds with an observer token, or an instructor
+// token if control is set, and the agent proxy terminal URL for the seat.
+func (s *Server) issueStaffTerminalToken(w http.ResponseWriter, r *http.Request, control bool) {
+	id := chi.URLParam(r, "id")
+	seat, err := strconv.Atoi(chi.URLParam(r, "seat"))
+	if err != nil {
+		http.Error(w, "Seat must be a number", http.StatusBadRequest)
+		return
+	}
+
+	workshop, err := s.store.GetWorkshop(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if workshop.RuntimeType != "firecracker" {
+		http.Error(w, "Terminal mirroring requires the firecracker runtime", http.StatusConflict)
+		return
+	}
+	if workshop.Status != "running" {
+		http.Error(w, "Workshop is not running", http.StatusConflict)
+		return
+	}
+
+	session, err := s.store.GetSessionBySeat(id, seat)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if session == nil {
+		http.Error(w, "Seat not found", http.StatusNotFound)
+		return
+	}
+
+	vm, err := s.store.GetVM(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if vm == nil {
+		http.Error(w, "Workshop VM not found", http.StatusServiceUnavailable)
+		return
+	}
+
+	user := auth.GetUserFromContext(r.Context())
+	generate := auth.GenerateObserverToken
+	if control {
+		generate = auth.GenerateInstructorToken
+	}
+	token, err := generate(id, seat, user.ID)
+	if err != nil {
+		http.Error(w, "Failed to generate access token", http.StatusInternalServerError)
+		return
+	}
+
+	endpoint := workspaceEndpoint(workshop, vm)
+	wsBase := strings.Replace(strings.Replace(endpoint, "https://", "wss://", 1), "http://", "ws://", 1)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"workshop_id":  id,
+		"seat":         seat,
+		"name":         session.Name,
+		"endpoint":     endpoint,
+		"token":        token,
+		"terminal_url": fmt.Sprintf("%s/proxy/%s/%d/terminal?token=%s", wsBase, id, seat, url.QueryEscape(token)),
+		"read_only":    !control,
+	})
+}
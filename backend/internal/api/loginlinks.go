@@ -0,0 +1,269 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/clarateach/backend/internal/auth"
+	"github.com/clarateach/backend/internal/logging"
+	"github.com/clarateach/backend/internal/notify"
+	"github.com/clarateach/backend/internal/store"
+)
+
+// loginLinkInterval is how often a learner may ask for a new login link.
+const loginLinkInterval = time.Minute
+
+// SetLoginLinkTTL sets how long emailed login links work.
+func (s *Server) SetLoginLinkTTL(ttl time.Duration) {
+	s.loginLinkTTL = ttl
+}
+
+// issueLoginLink records a one-time login link for the registration and
+// returns its URL. The URL is relative when no public URL is configured.
+func (s *Server) issueLoginLink(registration *store.Registration) (*store.LoginLink, string, error) {
+	now := time.Now()
+	link := &store.LoginLink{
+		ID:             "link-" + generateID(16),
+		RegistrationID: registration.ID,
+		WorkshopID:     registration.WorkshopID,
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(s.loginLinkTTL),
+	}
+	token, err := auth.GenerateLoginLinkToken(registration.ID, link.ID, link.ExpiresAt)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := s.store.CreateLoginLink(link); err != nil {
+		return nil, "", err
+	}
+	return link, s.publicURL + "/login/" + token, nil
+}
+
+// emailLoginLink issues a login link for the registration and emails it.
+// Unlike notifyLearner, it sends every time it is called.
+func (s *Server) emailLoginLink(ctx context.Context, workshop *store.Workshop, registration *store.Registration) error {
+	link, url, err := s.issueLoginLink(registration)
+	if err != nil {
+		return err
+	}
+	return s.sendLoginLink(ctx, workshop, registration, link, url)
+}
+
+// sendLoginLink emails an issued login link to the registration.
+func (s *Server) sendLoginLink(ctx context.Context, workshop *store.Workshop, registration *store.Registration, link *store.LoginLink, url string) error {
+	data := s.notificationData(workshop, registration)
+	data.LoginURL = url
+	data.LoginExpiresAt = link.ExpiresAt
+	return s.sendMessage(ctx, workshop.ID, notify.KindLoginLink, data)
+}
+
+// requestLoginLink emails a learner a one-time login link. It answers the
+// same way whether or not the email is registered, so it cannot be used to
+// find out who is.
+func (s *Server) requestLoginLink(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		WorkshopCode string `json:"workshop_code"`
+		Email        string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.WorkshopCode == "" || req.Email == "" {
+		http.Error(w, "Workshop code and email are required", http.StatusBadRequest)
+		return
+	}
+	if s.notifier == nil {
+		http.Error(w, "Login links are not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	workshop, err := s.store.GetWorkshopByCode(req.WorkshopCode)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var registration *store.Registration
+	if workshop != nil {
+		registration, err = s.store.GetRegistrationByEmail(workshop.ID, strings.TrimSpace(req.Email))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if registration != nil && registration.Status != "revoked" {
+		ctx := logging.WithWorkshop(context.WithoutCancel(r.Context()), workshop.ID)
+		last, err := s.store.GetLatestLoginLink(registration.ID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if last == nil || time.Since(last.CreatedAt) >= loginLinkInterval {
+			go func() {
+				ctx, cancel := context.WithTimeout(ctx, time.Minute)
+				defer cancel()
+				if err := s.emailLoginLink(ctx, workshop, registration); err != nil {
+					s.logger.WarnContext(ctx, "failed to send login link", "registration_id", registration.ID, "error", err)
+				}
+			}()
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "If that email is registered for the workshop, a login link is on its way",
+	})
+}
+
+// loginWithLink exchanges a login link for the learner's session, the same
+// as GET /api/session/{code}. Each link works once.
+func (s *Server) loginWithLink(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	claims, err := auth.ValidateLoginLinkToken(req.Token)
+	if err != nil {
+		http.Error(w, "Invalid or expired login link", http.StatusUnauthorized)
+		return
+	}
+	registration, err := s.store.GetRegistrationByID(claims.RegistrationID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if registration == nil {
+		http.Error(w, "Invalid or expired login link", http.StatusUnauthorized)
+		return
+	}
+	used, err := s.store.UseLoginLink(claims.ID, registration.ID, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !used {
+		http.Error(w, "Login link has already been used or replaced", http.StatusGone)
+		return
+	}
+
+	state, status, err := s.resolveSession(r.Context(), registration.AccessCode)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	state.AccessCode = registration.AccessCode
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(state)
+}
+
+// sendRegistrationLoginLink issues a login link for a learner and emails it
+// when emails are enabled. The link is returned so staff can pass it on
+// another way.
+func (s *Server) sendRegistrationLoginLink(w http.ResponseWriter, r *http.Request) {
+	registration, ok := s.workshopRegistration(w, r)
+	if !ok {
+		return
+	}
+	if registration.Status == "revoked" {
+		http.Error(w, "Registration is revoked", http.StatusConflict)
+		return
+	}
+	workshop, err := s.store.GetWorkshop(registration.WorkshopID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	link, url, err := s.issueLoginLink(registration)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	emailed := false
+	if s.notifier != nil && registration.Email != "" {
+		if err := s.sendLoginLink(r.Context(), workshop, registration, link, url); err != nil {
+			http.Error(w, "Failed to send login link: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		emailed = true
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"login_url":  url,
+		"expires_at": link.ExpiresAt,
+		"emailed":    emailed,
+	})
+}
+
+// rotateAccessCode replaces a learner's access code, for when it has leaked.
+// The learner keeps their registration and seat; the old code, outstanding
+// login links and workspace tokens stop working, and the learner is emailed
+// a login link with the new code. Tokens are revoked and the email sent in
+// the background; emailed reports whether an email is being sent.
+func (s *Server) rotateAccessCode(w http.ResponseWriter, r *http.Request) {
+	registration, ok := s.workshopRegistration(w, r)
+	if !ok {
+		return
+	}
+	if registration.Status == "revoked" {
+		http.Error(w, "Registration is revoked", http.StatusConflict)
+		return
+	}
+	workshop, err := s.store.GetWorkshop(registration.WorkshopID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// The old code must not resolve a seat while it is being replaced
+	s.seatMu.Lock()
+	registration.AccessCode = generateAccessCode()
+	err = s.store.UpdateRegistrationAccessCode(registration.ID, registration.AccessCode)
+	s.seatMu.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	ctx := logging.WithWorkshop(r.Context(), workshop.ID)
+	if err := s.store.ExpireLoginLinks(registration.ID, time.Now()); err != nil {
+		s.logger.WarnContext(ctx, "failed to expire login links", "registration_id", registration.ID, "error", err)
+	}
+
+	// Revoking tokens and sending the email call out to the worker and the
+	// mail provider, so they run in the background. Tokens are revoked before
+	// the email goes out, so a learner who follows the emailed link is not
+	// caught by the revocation.
+	emailed := s.notifier != nil && registration.Email != ""
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Minute)
+		defer cancel()
+
+		// Whoever opened the workspace with the old code loses access
+		if registration.SeatID != nil && registration.JoinedAt != nil {
+			s.revokeSeatTokens(ctx, registration, *registration.SeatID)
+		}
+		if emailed {
+			if err := s.emailLoginLink(ctx, workshop, registration); err != nil {
+				s.logger.WarnContext(ctx, "failed to send login link", "registration_id", registration.ID, "error", err)
+			}
+		}
+	}()
+
+	s.logger.InfoContext(ctx, "access code rotated", "registration_id", registration.ID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"registration": registration,
+		"emailed":      emailed,
+	})
+}
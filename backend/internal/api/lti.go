@@ -0,0 +1,650 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/clarateach/backend/internal/auth"
+	"github.com/clarateach/backend/internal/logging"
+	"github.com/clarateach/backend/internal/lti"
+	"github.com/clarateach/backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// ltiLoginTTL is how long a platform may take to come back from an LTI
+// login with the launch.
+const ltiLoginTTL = 10 * time.Minute
+
+// ltiDeepLinkTTL is how long an instructor has to pick a workshop.
+const ltiDeepLinkTTL = time.Hour
+
+// errLTINotLinked is returned by ltiWorkshop when a launch names a workshop
+// that was not deep linked into the course it came from.
+var errLTINotLinked = errors.New("This workshop was not added to this course; ask your instructor to add it again")
+
+// SetLTI enables LTI 1.3 launches. key signs deep linking responses,
+// toolURL is the backend's own base URL that platforms send launches to and
+// publicURL the frontend base URL launched users are redirected to.
+func (s *Server) SetLTI(key *lti.ToolKey, toolURL, publicURL string) {
+	s.ltiKey = key
+	s.ltiToolURL = strings.TrimRight(toolURL, "/")
+	s.ltiAppURL = strings.TrimRight(publicURL, "/")
+}
+
+// requireLTI answers 503 unless LTI is enabled.
+func (s *Server) requireLTI(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.ltiKey == nil {
+			http.Error(w, "LTI is not configured", http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ltiPlatform converts a stored platform for the lti package.
+func ltiPlatform(p *store.LTIPlatform) lti.Platform {
+	return lti.Platform{Issuer: p.Issuer, ClientID: p.ClientID, JWKSURL: p.JWKSURL}
+}
+
+// findLTIPlatform returns the platform registered for issuer and, when the
+// platform sent one, clientID. Without a client ID the issuer must be
+// registered only once.
+func (s *Server) findLTIPlatform(issuer, clientID string) (*store.LTIPlatform, error) {
+	platforms, err := s.store.ListLTIPlatforms()
+	if err != nil {
+		return nil, err
+	}
+	var found *store.LTIPlatform
+	for _, p := range platforms {
+		if p.Issuer != issuer || (clientID != "" && p.ClientID != clientID) {
+			continue
+		}
+		if found != nil {
+			return nil, errors.New("Several LTI registrations match; the platform must send client_id")
+		}
+		found = p
+	}
+	return found, nil
+}
+
+// useLTINonce records nonce as used and reports whether it was unused, so
+// an ID token cannot be launched twice.
+func (s *Server) useLTINonce(nonce string) bool {
+	s.ltiMu.Lock()
+	defer s.ltiMu.Unlock()
+
+	now := time.Now()
+	for n, expires := range s.ltiNonces {
+		if now.After(expires) {
+			delete(s.ltiNonces, n)
+		}
+	}
+	if _, used := s.ltiNonces[nonce]; used {
+		return false
+	}
+	s.ltiNonces[nonce] = now.Add(ltiLoginTTL)
+	return true
+}
+
+// ltiLogin handles OIDC third-party login initiation: it sends the browser
+// back to the platform's authorization endpoint, which posts the ID token to
+// ltiLaunch. The nonce travels in the signed state rather than a cookie,
+// because LMSs embed tools in iframes where third-party cookies are blocked.
+func (s *Server) ltiLogin(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	issuer := r.Form.Get("iss")
+	loginHint := r.Form.Get("login_hint")
+	if issuer == "" || loginHint == "" {
+		http.Error(w, "iss and login_hint are required", http.StatusBadRequest)
+		return
+	}
+	platform, err := s.findLTIPlatform(issuer, r.Form.Get("client_id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if platform == nil {
+		http.Error(w, "Unknown LTI platform", http.StatusBadRequest)
+		return
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	state, err := auth.GenerateLTIStateToken(platform.ID, hex.EncodeToString(nonce), ltiLoginTTL)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	query := url.Values{
+		"scope":         {"openid"},
+		"response_type": {"id_token"},
+		"response_mode": {"form_post"},
+		"prompt":        {"none"},
+		"client_id":     {platform.ClientID},
+		"redirect_uri":  {s.ltiToolURL + "/api/lti/launch"},
+		"login_hint":    {loginHint},
+		"state":         {state},
+		"nonce":         {hex.EncodeToString(nonce)},
+	}
+	if hint := r.Form.Get("lti_message_hint"); hint != "" {
+		query.Set("lti_message_hint", hint)
+	}
+	target := platform.AuthLoginURL
+	if strings.Contains(target, "?") {
+		target += "&" + query.Encode()
+	} else {
+		target += "?" + query.Encode()
+	}
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// ltiLaunch verifies the platform's ID token and, for a resource link,
+// registers the learner for the linked workshop and redirects them into
+// their seat; instructors go to the workshop's dashboard. For a deep linking
+// request it redirects the instructor to pick a workshop.
+func (s *Server) ltiLaunch(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if msg := r.PostForm.Get("error"); msg != "" {
+		http.Error(w, "The LMS refused the launch: "+msg, http.StatusBadRequest)
+		return
+	}
+	state, err := auth.ValidateLTIStateToken(r.PostForm.Get("state"))
+	if err != nil {
+		http.Error(w, "Invalid or expired launch; open the link from your course again", http.StatusUnauthorized)
+		return
+	}
+	platform, err := s.store.GetLTIPlatform(state.PlatformID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if platform == nil {
+		http.Error(w, "Unknown LTI platform", http.StatusBadRequest)
+		return
+	}
+
+	claims, err := lti.ParseLaunch(r.Context(), s.jwks, ltiPlatform(platform), r.PostForm.Get("id_token"))
+	if err != nil {
+		s.logger.WarnContext(r.Context(), "rejected LTI launch", "platform_id", platform.ID, "error", err)
+		http.Error(w, "Invalid launch: "+err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if claims.Nonce != state.Nonce || !s.useLTINonce(claims.Nonce) {
+		http.Error(w, "Invalid or replayed launch", http.StatusUnauthorized)
+		return
+	}
+	if len(platform.DeploymentIDs) > 0 && !slices.Contains(platform.DeploymentIDs, claims.DeploymentID) {
+		http.Error(w, "Unknown LTI deployment", http.StatusForbidden)
+		return
+	}
+
+	if claims.MessageType == lti.MessageDeepLinkingRequest {
+		s.ltiStartDeepLinking(w, r, platform, claims)
+		return
+	}
+
+	workshop, err := s.ltiWorkshop(platform, claims)
+	if errors.Is(err, errLTINotLinked) {
+		s.logger.WarnContext(r.Context(), "rejected LTI launch for a workshop not linked to the course", "platform_id", platform.ID, "workshop_id", claims.CustomValue("workshop_id"))
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if workshop == nil {
+		http.Error(w, "This link is not connected to a workshop yet; ask your instructor", http.StatusNotFound)
+		return
+	}
+	if claims.IsInstructor() {
+		http.Redirect(w, r, s.ltiAppURL+"/workshop/"+workshop.ID, http.StatusSeeOther)
+		return
+	}
+
+	email := strings.TrimSpace(claims.Email)
+	if email == "" {
+		http.Error(w, "Your LMS did not share your email address, which is needed to register; ask your instructor", http.StatusBadRequest)
+		return
+	}
+	name := claims.DisplayName()
+	if name == "" {
+		name = email
+	}
+	ctx := logging.WithWorkshop(r.Context(), workshop.ID)
+	registration, created, status, err := s.registerLearner(ctx, workshop, email, name)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	if created {
+		s.logger.InfoContext(ctx, "registered learner from LTI launch", "platform_id", platform.ID, "registration_id", registration.ID)
+	}
+	http.Redirect(w, r, s.ltiAppURL+"/s/"+registration.AccessCode, http.StatusSeeOther)
+}
+
+// ltiWorkshop returns the workshop a resource link launches: the one named
+// by its workshop_id custom parameter, which deep linking sets, or else the
+// one the course is linked to. Custom parameters can be edited in the LMS, so
+// a named workshop must have been deep linked into the same course on the
+// same platform; otherwise it returns errLTINotLinked.
+func (s *Server) ltiWorkshop(platform *store.LTIPlatform, claims *lti.LaunchClaims) (*store.Workshop, error) {
+	contextID := ""
+	if claims.Context != nil {
+		contextID = claims.Context.ID
+	}
+	workshopID := claims.CustomValue("workshop_id")
+	if workshopID != "" {
+		link, err := s.store.GetLTILink(platform.ID, contextID, workshopID)
+		if err != nil {
+			return nil, err
+		}
+		if link == nil {
+			return nil, errLTINotLinked
+		}
+	} else if contextID != "" {
+		link, err := s.store.GetLTIContext(platform.ID, contextID)
+		if err != nil || link == nil {
+			return nil, err
+		}
+		workshopID = link.WorkshopID
+	}
+	if workshopID == "" {
+		return nil, nil
+	}
+	workshop, err := s.store.GetWorkshop(workshopID)
+	if err != nil || workshop == nil || workshop.Status == "deleted" {
+		return nil, err
+	}
+	return workshop, nil
+}
+
+// ltiStartDeepLinking sends an instructor on to the frontend to pick a
+// workshop. They act as the account with the email the LMS sent, so the
+// platform must be trusted to vouch for its users' emails.
+func (s *Server) ltiStartDeepLinking(w http.ResponseWriter, r *http.Request, platform *store.LTIPlatform, claims *lti.LaunchClaims) {
+	if !claims.IsInstructor() {
+		http.Error(w, "Only instructors can add workshops to a course", http.StatusForbidden)
+		return
+	}
+	if !platform.TrustEmail {
+		http.Error(w, "This LMS is not trusted to identify ClaraTeach accounts; an administrator must turn on trust_email for it", http.StatusForbidden)
+		return
+	}
+	user, err := s.store.GetUserByEmail(strings.TrimSpace(claims.Email))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, "No ClaraTeach account uses your LMS email address; sign up with it first", http.StatusForbidden)
+		return
+	}
+
+	session := auth.LTIDeepLinkClaims{
+		PlatformID:   platform.ID,
+		DeploymentID: claims.DeploymentID,
+		ReturnURL:    claims.DeepLinking.ReturnURL,
+		Data:         claims.DeepLinking.Data,
+	}
+	if claims.Context != nil {
+		session.ContextID = claims.Context.ID
+		session.ContextTitle = claims.Context.Title
+	}
+	token, err := auth.GenerateLTIDeepLinkToken(user.ID, session, ltiDeepLinkTTL)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, s.ltiAppURL+"/lti/deep-link/"+token, http.StatusSeeOther)
+}
+
+// ltiDeepLinkSession validates the deep linking session in the request body
+// and returns it with the instructor, or writes the error.
+func (s *Server) ltiDeepLinkSession(w http.ResponseWriter, token string) (*auth.LTIDeepLinkClaims, *store.User, bool) {
+	session, err := auth.ValidateLTIDeepLinkToken(token)
+	if err != nil {
+		http.Error(w, "Invalid or expired deep linking session; start again from your course", http.StatusUnauthorized)
+		return nil, nil, false
+	}
+	user, err := s.store.GetUser(session.Subject)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return nil, nil, false
+	}
+	return session, user, true
+}
+
+// linkableWorkshops returns the workshops the user may link into a course:
+// those they can start and stop.
+func (s *Server) linkableWorkshops(user *store.User) ([]*store.Workshop, error) {
+	var candidates []*store.Workshop
+	if user.Role == "admin" {
+		all, err := s.store.ListWorkshops()
+		if err != nil {
+			return nil, err
+		}
+		candidates = all
+	} else {
+		owned, err := s.store.ListWorkshopsByOwner(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		shared, err := s.store.ListWorkshopsByMember(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(owned, shared...)
+	}
+
+	workshops := make([]*store.Workshop, 0, len(candidates))
+	for _, ws := range candidates {
+		if ws.Status == "ended" || ws.Status == "deleted" {
+			continue
+		}
+		role, err := s.workshopRole(user, ws)
+		if err != nil {
+			return nil, err
+		}
+		if roleAllows(role, permStartStop) {
+			workshops = append(workshops, ws)
+		}
+	}
+	return workshops, nil
+}
+
+// listLTIDeepLinkWorkshops lists the workshops an instructor in a deep
+// linking session can pick from.
+func (s *Server) listLTIDeepLinkWorkshops(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	session, user, ok := s.ltiDeepLinkSession(w, req.Token)
+	if !ok {
+		return
+	}
+	workshops, err := s.linkableWorkshops(user)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"workshops":     workshops,
+		"context_title": session.ContextTitle,
+	})
+}
+
+// completeLTIDeepLink links the chosen workshop to the course and returns
+// the signed deep linking response for the browser to post back to the
+// platform.
+func (s *Server) completeLTIDeepLink(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token      string `json:"token"`
+		WorkshopID string `json:"workshop_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	session, user, ok := s.ltiDeepLinkSession(w, req.Token)
+	if !ok {
+		return
+	}
+	workshop, err := s.store.GetWorkshop(req.WorkshopID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if workshop == nil {
+		http.Error(w, "Workshop not found", http.StatusNotFound)
+		return
+	}
+	role, err := s.workshopRole(user, workshop)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !roleAllows(role, permStartStop) {
+		http.Error(w, "Workshop not found", http.StatusNotFound)
+		return
+	}
+	platform, err := s.store.GetLTIPlatform(session.PlatformID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if platform == nil {
+		http.Error(w, "Unknown LTI platform", http.StatusBadRequest)
+		return
+	}
+
+	// Launches of the resource link are checked against this
+	if err := s.store.AddLTILink(&store.LTILink{
+		PlatformID: platform.ID,
+		ContextID:  session.ContextID,
+		WorkshopID: workshop.ID,
+		CreatedAt:  time.Now(),
+	}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if session.ContextID != "" {
+		link := &store.LTIContext{
+			PlatformID: platform.ID,
+			ContextID:  session.ContextID,
+			WorkshopID: workshop.ID,
+			Title:      session.ContextTitle,
+			UpdatedAt:  time.Now(),
+		}
+		if err := s.store.SetLTIContext(link); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	response, err := s.ltiKey.DeepLinkingResponse(ltiPlatform(platform), session.DeploymentID, session.Data, []lti.ContentItem{{
+		Type:   "ltiResourceLink",
+		Title:  workshop.Name,
+		URL:    s.ltiToolURL + "/api/lti/launch",
+		Custom: map[string]string{"workshop_id": workshop.ID},
+	}})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"return_url": session.ReturnURL,
+		"jwt":        response,
+	})
+}
+
+// ltiKeySet serves the tool's public key set.
+func (s *Server) ltiKeySet(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	json.NewEncoder(w).Encode(s.ltiKey.KeySet())
+}
+
+// listLTIPlatforms lists registered platforms along with the URLs to
+// register the tool with on them.
+func (s *Server) listLTIPlatforms(w http.ResponseWriter, r *http.Request) {
+	platforms, err := s.store.ListLTIPlatforms()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if platforms == nil {
+		platforms = []*store.LTIPlatform{}
+	}
+
+	tool := map[string]interface{}{"enabled": s.ltiKey != nil}
+	if s.ltiKey != nil {
+		tool["login_url"] = s.ltiToolURL + "/api/lti/login"
+		tool["launch_url"] = s.ltiToolURL + "/api/lti/launch"
+		tool["jwks_url"] = s.ltiToolURL + "/api/lti/jwks"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"platforms": platforms,
+		"tool":      tool,
+	})
+}
+
+// createLTIPlatform registers a platform.
+func (s *Server) createLTIPlatform(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name          string   `json:"name"`
+		Issuer        string   `json:"issuer"`
+		ClientID      string   `json:"client_id"`
+		AuthLoginURL  string   `json:"auth_login_url"`
+		JWKSURL       string   `json:"jwks_url"`
+		DeploymentIDs []string `json:"deployment_ids"`
+		TrustEmail    bool     `json:"trust_email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Issuer = strings.TrimSpace(req.Issuer)
+	req.ClientID = strings.TrimSpace(req.ClientID)
+	if req.Issuer == "" || req.ClientID == "" {
+		http.Error(w, "Issuer and client ID are required", http.StatusBadRequest)
+		return
+	}
+	for _, u := range []string{req.AuthLoginURL, req.JWKSURL} {
+		if parsed, err := url.Parse(u); err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+			http.Error(w, "Auth login URL and JWKS URL must be absolute http or https URLs", http.StatusBadRequest)
+			return
+		}
+	}
+	deployments := make([]string, 0, len(req.DeploymentIDs))
+	for _, d := range req.DeploymentIDs {
+		d = strings.TrimSpace(d)
+		if strings.Contains(d, ",") {
+			http.Error(w, "Deployment IDs must not contain commas", http.StatusBadRequest)
+			return
+		}
+		if d != "" && !slices.Contains(deployments, d) {
+			deployments = append(deployments, d)
+		}
+	}
+	if req.Name == "" {
+		req.Name = req.Issuer
+	}
+
+	existing, err := s.findLTIPlatform(req.Issuer, req.ClientID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if existing != nil {
+		http.Error(w, "This platform and client ID are already registered", http.StatusConflict)
+		return
+	}
+
+	p := &store.LTIPlatform{
+		ID:            "lti-" + generateID(8),
+		Name:          req.Name,
+		Issuer:        req.Issuer,
+		ClientID:      req.ClientID,
+		AuthLoginURL:  req.AuthLoginURL,
+		JWKSURL:       req.JWKSURL,
+		DeploymentIDs: deployments,
+		TrustEmail:    req.TrustEmail,
+		CreatedAt:     time.Now(),
+	}
+	if err := s.store.CreateLTIPlatform(p); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"platform": p})
+}
+
+// updateLTIPlatform sets whether deep linking from the platform trusts its
+// email claim to pick the instructor's account.
+func (s *Server) updateLTIPlatform(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TrustEmail *bool `json:"trust_email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.TrustEmail == nil {
+		http.Error(w, "trust_email is required", http.StatusBadRequest)
+		return
+	}
+	p, err := s.store.GetLTIPlatform(chi.URLParam(r, "platformID"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if p == nil {
+		http.Error(w, "LTI platform not found", http.StatusNotFound)
+		return
+	}
+
+	if err := s.store.SetLTIPlatformTrustEmail(p.ID, *req.TrustEmail); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	p.TrustEmail = *req.TrustEmail
+	s.logger.InfoContext(r.Context(), "updated LTI platform", "platform_id", p.ID, "trust_email", p.TrustEmail)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"platform": p})
+}
+
+// deleteLTIPlatform removes a platform and its course links. Launches from
+// it stop working.
+func (s *Server) deleteLTIPlatform(w http.ResponseWriter, r *http.Request) {
+	p, err := s.store.GetLTIPlatform(chi.URLParam(r, "platformID"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if p == nil {
+		http.Error(w, "LTI platform not found", http.StatusNotFound)
+		return
+	}
+	if err := s.store.DeleteLTIPlatform(p.ID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+}
@@ -0,0 +1,268 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/clarateach/backend/internal/logging"
+	"github.com/clarateach/backend/internal/notify"
+	"github.com/clarateach/backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// SetNotifier enables learner emails. publicURL is the frontend's base URL,
+// used to link learners straight to their workspace; it may be empty.
+func (s *Server) SetNotifier(n notify.Notifier, publicURL string) {
+	s.notifier = n
+	s.publicURL = strings.TrimSuffix(publicURL, "/")
+}
+
+// notifyLearner emails one registration a message of the given kind, unless
+// it was already sent. The message is rendered from the workshop's template
+// for the kind, or the built-in one, and sent in the background.
+func (s *Server) notifyLearner(ctx context.Context, workshop *store.Workshop, registration *store.Registration, kind string) {
+	if s.notifier == nil || registration.Email == "" {
+		return
+	}
+	ctx = logging.WithWorkshop(context.WithoutCancel(ctx), workshop.ID)
+
+	n := &store.Notification{
+		WorkshopID:     workshop.ID,
+		RegistrationID: registration.ID,
+		Kind:           kind,
+		Recipient:      registration.Email,
+		CreatedAt:      time.Now(),
+	}
+	claimed, err := s.store.ClaimNotification(n)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to record notification", "registration_id", registration.ID, "kind", kind, "error", err)
+		return
+	}
+	if !claimed {
+		return // Already sent, or being sent
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+
+		err := s.sendNotification(ctx, workshop, registration, kind)
+		if err != nil {
+			s.logger.WarnContext(ctx, "failed to send notification", "registration_id", registration.ID, "kind", kind, "error", err)
+			n.Status = store.NotificationFailed
+			n.Error = err.Error()
+		} else {
+			now := time.Now()
+			n.Status = store.NotificationSent
+			n.SentAt = &now
+		}
+		if err := s.store.UpdateNotification(n); err != nil {
+			s.logger.WarnContext(ctx, "failed to update notification", "registration_id", registration.ID, "kind", kind, "error", err)
+		}
+	}()
+}
+
+// sendNotification renders and sends one message.
+func (s *Server) sendNotification(ctx context.Context, workshop *store.Workshop, registration *store.Registration, kind string) error {
+	return s.sendMessage(ctx, workshop.ID, kind, s.notificationData(workshop, registration))
+}
+
+// notificationData returns the template data shared by every kind of message.
+func (s *Server) notificationData(workshop *store.Workshop, registration *store.Registration) notify.Data {
+	data := notify.Data{
+		LearnerName:      registration.Name,
+		LearnerEmail:     registration.Email,
+		WorkshopName:     workshop.Name,
+		WorkshopCode:     workshop.Code,
+		AccessCode:       registration.AccessCode,
+		StartsAt:         workshop.StartsAt,
+		WaitlistPosition: registration.WaitlistPosition,
+	}
+	if s.publicURL != "" {
+		data.AccessURL = s.publicURL + "/s/" + registration.AccessCode
+	}
+	return data
+}
+
+// sendMessage renders the workshop's template for kind, or the built-in one,
+// against data and sends it.
+func (s *Server) sendMessage(ctx context.Context, workshopID, kind string, data notify.Data) error {
+	tmpl := notify.DefaultTemplate(kind)
+	custom, err := s.store.GetNotificationTemplate(workshopID, kind)
+	if err != nil {
+		return err
+	}
+	if custom != nil {
+		tmpl = notify.Template{Subject: custom.Subject, Body: custom.Body}
+	}
+
+	msg, err := tmpl.Render(data)
+	if err != nil {
+		return err
+	}
+	return s.notifier.Send(ctx, msg)
+}
+
+// notifyWorkshop emails every learner holding a place in the workshop.
+// Revoked and waitlisted registrations are skipped.
+func (s *Server) notifyWorkshop(ctx context.Context, workshopID, kind string) {
+	if s.notifier == nil {
+		return
+	}
+	workshop, err := s.store.GetWorkshop(workshopID)
+	if err != nil || workshop == nil {
+		return
+	}
+	registrations, err := s.store.ListRegistrations(workshopID)
+	if err != nil {
+		s.logger.WarnContext(logging.WithWorkshop(ctx, workshopID), "failed to list registrations for notification", "kind", kind, "error", err)
+		return
+	}
+	for _, reg := range registrations {
+		if reg.Status != "revoked" && reg.Status != "waitlisted" {
+			s.notifyLearner(ctx, workshop, reg, kind)
+		}
+	}
+}
+
+// RunReminders emails access-code reminders to learners of workshops starting
+// within lead, checking every interval until ctx is cancelled. Each learner
+// gets one reminder; a failed send is retried on the next check.
+func (s *Server) RunReminders(ctx context.Context, interval, lead time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.sendReminders(ctx, lead)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendReminders sends reminders for workshops starting within lead that have
+// not started yet.
+func (s *Server) sendReminders(ctx context.Context, lead time.Duration) {
+	if s.notifier == nil {
+		return
+	}
+	workshops, err := s.store.ListWorkshops()
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to list workshops for reminders", "error", err)
+		return
+	}
+	now := time.Now()
+	for _, workshop := range workshops {
+		if workshop.StartsAt == nil || workshop.StartsAt.Before(now) || workshop.StartsAt.After(now.Add(lead)) {
+			continue
+		}
+		if workshop.Status != "created" && workshop.Status != "provisioning" {
+			continue
+		}
+		s.notifyWorkshop(ctx, workshop.ID, notify.KindReminder)
+	}
+}
+
+// listNotificationTemplates returns the template used for each kind of
+// email, marking which ones the workshop has customized.
+func (s *Server) listNotificationTemplates(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	custom, err := s.store.ListNotificationTemplates(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	byKind := make(map[string]*store.NotificationTemplate, len(custom))
+	for _, t := range custom {
+		byKind[t.Kind] = t
+	}
+
+	templates := make([]map[string]interface{}, 0, len(notify.Kinds))
+	for _, kind := range notify.Kinds {
+		tmpl := notify.DefaultTemplate(kind)
+		entry := map[string]interface{}{"kind": kind, "custom": false}
+		if t := byKind[kind]; t != nil {
+			tmpl = notify.Template{Subject: t.Subject, Body: t.Body}
+			entry["custom"] = true
+			entry["updated_at"] = t.UpdatedAt
+		}
+		entry["subject"] = tmpl.Subject
+		entry["body"] = tmpl.Body
+		templates = append(templates, entry)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"templates": templates,
+		"enabled":   s.notifier != nil,
+	})
+}
+
+// setNotificationTemplate customizes one kind of email for the workshop. The
+// template is checked by rendering it against sample data.
+func (s *Server) setNotificationTemplate(w http.ResponseWriter, r *http.Request) {
+	kind := chi.URLParam(r, "kind")
+	if !notify.ValidKind(kind) {
+		http.Error(w, "Unknown notification kind", http.StatusNotFound)
+		return
+	}
+
+	var req notify.Template
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	t := &store.NotificationTemplate{
+		WorkshopID: chi.URLParam(r, "id"),
+		Kind:       kind,
+		Subject:    req.Subject,
+		Body:       req.Body,
+		UpdatedAt:  time.Now(),
+	}
+	if err := s.store.SetNotificationTemplate(t); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"template": t})
+}
+
+// resetNotificationTemplate goes back to the built-in email for the kind.
+func (s *Server) resetNotificationTemplate(w http.ResponseWriter, r *http.Request) {
+	kind := chi.URLParam(r, "kind")
+	if !notify.ValidKind(kind) {
+		http.Error(w, "Unknown notification kind", http.StatusNotFound)
+		return
+	}
+	if err := s.store.DeleteNotificationTemplate(chi.URLParam(r, "id"), kind); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// listNotifications returns the emails sent to the workshop's learners, so
+// staff can check whether someone got their access code.
+func (s *Server) listNotifications(w http.ResponseWriter, r *http.Request) {
+	notifications, err := s.store.ListNotifications(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if notifications == nil {
+		notifications = []*store.Notification{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"notifications": notifications})
+}
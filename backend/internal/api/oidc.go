@@ -0,0 +1,444 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/clarateach/backend/internal/auth"
+	"github.com/clarateach/backend/internal/oidc"
+	"github.com/clarateach/backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// oidcLoginTTL is how long a user has to sign in at the provider.
+const oidcLoginTTL = 10 * time.Minute
+
+// oidcLinkTTL is how long a link URL from linkOIDCProvider works, and how
+// long the user then has to confirm the verified account.
+const oidcLinkTTL = 5 * time.Minute
+
+// oidcStateCookie carries the signed state, nonce and PKCE verifier from
+// oidcLogin to oidcCallback.
+const oidcStateCookie = "clarateach_oidc"
+
+// SetOIDC enables single sign-on with providers. baseURL is the backend's
+// own base URL that providers redirect back to and publicURL the frontend
+// base URL signed-in users are sent to.
+func (s *Server) SetOIDC(providers []*oidc.Provider, baseURL, publicURL string) {
+	s.oidcProviders = providers
+	s.oidcBaseURL = strings.TrimRight(baseURL, "/")
+	s.oidcAppURL = strings.TrimRight(publicURL, "/")
+}
+
+func (s *Server) oidcProvider(id string) *oidc.Provider {
+	for _, p := range s.oidcProviders {
+		if p.ID == id {
+			return p
+		}
+	}
+	return nil
+}
+
+func (s *Server) oidcURL(p *oidc.Provider, action string) string {
+	return s.oidcBaseURL + "/api/auth/oidc/" + p.ID + "/" + action
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// oidcFail sends the browser back to the sign-in page with a message.
+func (s *Server) oidcFail(w http.ResponseWriter, r *http.Request, message string) {
+	http.Redirect(w, r, s.oidcAppURL+"/login?sso_error="+url.QueryEscape(message), http.StatusFound)
+}
+
+func (s *Server) setOIDCStateCookie(w http.ResponseWriter, value string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    value,
+		Path:     "/api/auth/oidc/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(s.oidcBaseURL, "https://"),
+		SameSite: http.SameSiteLaxMode, // Sent on the provider's top-level redirect back
+	})
+}
+
+func (s *Server) listOIDCProviders(w http.ResponseWriter, r *http.Request) {
+	providers := make([]map[string]string, 0, len(s.oidcProviders))
+	for _, p := range s.oidcProviders {
+		providers = append(providers, map[string]string{
+			"id":        p.ID,
+			"name":      p.Name,
+			"login_url": s.oidcURL(p, "login"),
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"providers": providers})
+}
+
+// oidcLogin starts a sign-in by redirecting to the provider. With a link
+// token from linkOIDCProvider, the provider account is linked to that user.
+func (s *Server) oidcLogin(w http.ResponseWriter, r *http.Request) {
+	p := s.oidcProvider(chi.URLParam(r, "provider"))
+	if p == nil {
+		http.Error(w, "Unknown sign-in provider", http.StatusNotFound)
+		return
+	}
+
+	userID := ""
+	if link := r.URL.Query().Get("link"); link != "" {
+		claims, err := auth.ValidateOIDCLinkToken(link)
+		if err != nil || claims.Provider != p.ID {
+			s.oidcFail(w, r, "The account link has expired, please try again")
+			return
+		}
+		userID = claims.Subject
+	}
+
+	state, err := randomHex(16)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	nonce, err := randomHex(16)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	verifier, err := oidc.NewVerifier()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	target, err := p.AuthCodeURL(r.Context(), s.oidcURL(p, "callback"), state, nonce, verifier)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "OIDC provider unavailable", "provider", p.ID, "error", err)
+		s.oidcFail(w, r, fmt.Sprintf("%s sign-in is unavailable right now", p.Name))
+		return
+	}
+	cookie, err := auth.GenerateOIDCStateToken(userID, auth.OIDCStateClaims{
+		Provider: p.ID,
+		State:    state,
+		Nonce:    nonce,
+		Verifier: verifier,
+	}, oidcLoginTTL)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.setOIDCStateCookie(w, cookie, int(oidcLoginTTL.Seconds()))
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// oidcCallback completes a sign-in: it checks the state against the cookie,
+// redeems the code and hands the frontend a session token in the URL
+// fragment, which browsers do not send to servers. When linking, it hands
+// over the verified account instead, for the signed-in user to confirm with
+// completeOIDCLink.
+func (s *Server) oidcCallback(w http.ResponseWriter, r *http.Request) {
+	p := s.oidcProvider(chi.URLParam(r, "provider"))
+	if p == nil {
+		http.Error(w, "Unknown sign-in provider", http.StatusNotFound)
+		return
+	}
+	s.setOIDCStateCookie(w, "", -1)
+
+	query := r.URL.Query()
+	if errCode := query.Get("error"); errCode != "" {
+		message := query.Get("error_description")
+		if message == "" {
+			message = fmt.Sprintf("%s sign-in failed: %s", p.Name, errCode)
+		}
+		s.oidcFail(w, r, message)
+		return
+	}
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil {
+		s.oidcFail(w, r, "Your sign-in expired, please try again")
+		return
+	}
+	state, err := auth.ValidateOIDCStateToken(cookie.Value)
+	if err != nil || state.Provider != p.ID || subtle.ConstantTimeCompare([]byte(state.State), []byte(query.Get("state"))) != 1 {
+		s.oidcFail(w, r, "Your sign-in expired, please try again")
+		return
+	}
+	code := query.Get("code")
+	if code == "" {
+		s.oidcFail(w, r, fmt.Sprintf("%s did not return an authorization code", p.Name))
+		return
+	}
+
+	claims, err := p.Exchange(r.Context(), s.oidcURL(p, "callback"), code, state.Verifier, state.Nonce)
+	if err != nil {
+		s.logger.WarnContext(r.Context(), "rejected OIDC sign-in", "provider", p.ID, "error", err)
+		s.oidcFail(w, r, fmt.Sprintf("Could not verify your %s sign-in", p.Name))
+		return
+	}
+	if state.Subject != "" {
+		s.oidcLinkCallback(w, r, p, claims, state.Subject)
+		return
+	}
+	user, problem, err := s.oidcUser(p, claims)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "failed to resolve OIDC user", "provider", p.ID, "error", err)
+		s.oidcFail(w, r, "Sign-in failed, please try again")
+		return
+	}
+	if problem != "" {
+		s.oidcFail(w, r, problem)
+		return
+	}
+
+	tokens, err := s.issueTokens(user, "")
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+	fragment := url.Values{"token": {tokens.Token}, "refresh_token": {tokens.RefreshToken}}
+	http.Redirect(w, r, s.oidcAppURL+"/auth/callback#"+fragment.Encode(), http.StatusFound)
+}
+
+// oidcLinkCallback hands the frontend the provider account verified for a
+// link, in the URL fragment. Linking takes the confirmation of the signed-in
+// user who started it, so a leaked link URL cannot attach someone else's
+// provider account to their ClaraTeach account.
+func (s *Server) oidcLinkCallback(w http.ResponseWriter, r *http.Request, p *oidc.Provider, claims *oidc.Claims, userID string) {
+	if _, ok := p.Access(claims); !ok {
+		s.oidcFail(w, r, fmt.Sprintf("Your %s account is not allowed to sign in", p.Name))
+		return
+	}
+	token, err := auth.GenerateOIDCIdentityToken(userID, auth.OIDCIdentityClaims{
+		Provider:        p.ID,
+		ProviderSubject: claims.Subject,
+		Email:           claims.Email,
+	}, oidcLinkTTL)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	fragment := url.Values{"link": {token}, "provider": {p.ID}}
+	http.Redirect(w, r, s.oidcAppURL+"/auth/callback#"+fragment.Encode(), http.StatusFound)
+}
+
+// oidcUser finds the account for a verified sign-in, creating it as the
+// provider's rules allow, and applies the role the provider grants. An
+// existing account with the same email is only signed in when the provider
+// is configured with trust_email; otherwise its owner must link the provider
+// account themselves. problem explains to the user why they may not sign in.
+func (s *Server) oidcUser(p *oidc.Provider, claims *oidc.Claims) (user *store.User, problem string, err error) {
+	access, ok := p.Access(claims)
+	if !ok {
+		return nil, fmt.Sprintf("Your %s account is not allowed to sign in", p.Name), nil
+	}
+
+	identity, err := s.store.GetUserIdentity(p.ID, claims.Subject)
+	if err != nil {
+		return nil, "", err
+	}
+	if identity != nil {
+		if user, err = s.store.GetUser(identity.UserID); err != nil {
+			return nil, "", err
+		}
+	} else {
+		if claims.Email == "" || !claims.EmailVerified {
+			return nil, fmt.Sprintf("%s did not share a verified email address", p.Name), nil
+		}
+		if user, err = s.store.GetUserByEmail(claims.Email); err != nil {
+			return nil, "", err
+		}
+		// Whoever controls the email at the provider is not necessarily the
+		// account's owner unless the provider is trusted to vouch for it
+		if user != nil && !p.TrustEmail {
+			return nil, fmt.Sprintf("An account already uses %s; sign in to it and link %s from your account page", claims.Email, p.Name), nil
+		}
+		if user == nil {
+			if !access.Provision {
+				return nil, fmt.Sprintf("No account uses %s; ask an admin for access", claims.Email), nil
+			}
+			user = &store.User{
+				ID:        "user-" + generateID(8),
+				Email:     claims.Email,
+				Name:      claims.Name,
+				Role:      "instructor",
+				CreatedAt: time.Now(),
+			}
+			if user.Name == "" {
+				user.Name = claims.Email
+			}
+			if access.Role != "" {
+				user.Role = access.Role
+			}
+			if err := s.store.CreateUser(user); err != nil {
+				return nil, "", err
+			}
+			s.logger.Info("provisioned user from OIDC sign-in", "provider", p.ID, "user_id", user.ID, "role", user.Role)
+		}
+		if problem, err := s.linkOIDCIdentity(p, claims.Subject, claims.Email, user); problem != "" || err != nil {
+			return nil, problem, err
+		}
+	}
+	if user == nil {
+		return nil, "Your account no longer exists", nil
+	}
+	if user.Disabled {
+		return nil, "Your account has been disabled", nil
+	}
+
+	if access.Role != "" && access.Role != user.Role {
+		if err := s.store.UpdateUserRole(user.ID, access.Role); err != nil {
+			return nil, "", err
+		}
+		s.logger.Info("updated user role from OIDC claims", "provider", p.ID, "user_id", user.ID, "role", access.Role)
+		user.Role = access.Role
+	}
+	return user, "", nil
+}
+
+// linkOIDCIdentity links the provider account subject to user, which may
+// have only one account per provider.
+func (s *Server) linkOIDCIdentity(p *oidc.Provider, subject, email string, user *store.User) (problem string, err error) {
+	identities, err := s.store.ListUserIdentities(user.ID)
+	if err != nil {
+		return "", err
+	}
+	if slices.ContainsFunc(identities, func(i *store.UserIdentity) bool { return i.Provider == p.ID }) {
+		return fmt.Sprintf("%s is linked to a different %s account", user.Email, p.Name), nil
+	}
+	return "", s.store.CreateUserIdentity(&store.UserIdentity{
+		Provider:  p.ID,
+		Subject:   subject,
+		UserID:    user.ID,
+		Email:     email,
+		CreatedAt: time.Now(),
+	})
+}
+
+// linkOIDCProvider returns the URL that starts linking an account at the
+// provider to the signed-in user. The browser must navigate to it, so it
+// carries a short lived token instead of the Authorization header; the link
+// is only made once the same user confirms it with completeOIDCLink.
+func (s *Server) linkOIDCProvider(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	p := s.oidcProvider(chi.URLParam(r, "provider"))
+	if p == nil {
+		http.Error(w, "Unknown sign-in provider", http.StatusNotFound)
+		return
+	}
+	token, err := auth.GenerateOIDCLinkToken(user.ID, p.ID, oidcLinkTTL)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"url": s.oidcURL(p, "login") + "?link=" + url.QueryEscape(token),
+	})
+}
+
+// completeOIDCLink links the provider account verified by oidcLinkCallback
+// to the signed-in user, who must be the one who started the link.
+func (s *Server) completeOIDCLink(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	p := s.oidcProvider(chi.URLParam(r, "provider"))
+	if p == nil {
+		http.Error(w, "Unknown sign-in provider", http.StatusNotFound)
+		return
+	}
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	claims, err := auth.ValidateOIDCIdentityToken(req.Token)
+	if err != nil || claims.Provider != p.ID {
+		http.Error(w, "The account link has expired, please try again", http.StatusUnauthorized)
+		return
+	}
+	if claims.Subject != user.ID {
+		http.Error(w, "This account link was started by another user", http.StatusForbidden)
+		return
+	}
+
+	identity, err := s.store.GetUserIdentity(p.ID, claims.ProviderSubject)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if identity != nil && identity.UserID != user.ID {
+		http.Error(w, fmt.Sprintf("This %s account is already linked to another user", p.Name), http.StatusConflict)
+		return
+	}
+	if identity == nil {
+		problem, err := s.linkOIDCIdentity(p, claims.ProviderSubject, claims.Email, user)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if problem != "" {
+			http.Error(w, problem, http.StatusConflict)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+func (s *Server) listIdentities(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	identities, err := s.store.ListUserIdentities(user.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if identities == nil {
+		identities = []*store.UserIdentity{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"identities":   identities,
+		"has_password": user.PasswordHash != "",
+	})
+}
+
+// unlinkIdentity removes a provider account from the signed-in user, unless
+// it is the only way left to sign in.
+func (s *Server) unlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	provider := chi.URLParam(r, "provider")
+	identities, err := s.store.ListUserIdentities(user.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !slices.ContainsFunc(identities, func(i *store.UserIdentity) bool { return i.Provider == provider }) {
+		http.Error(w, "Identity not found", http.StatusNotFound)
+		return
+	}
+	if user.PasswordHash == "" && len(identities) == 1 {
+		http.Error(w, "Cannot unlink the only way to sign in to this account", http.StatusConflict)
+		return
+	}
+	if err := s.store.DeleteUserIdentity(user.ID, provider); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/clarateach/backend/internal/auth"
+)
+
+// createPreviewToken issues a token for viewing the web app the learner runs
+// on a port of their seat. Unlike the workspace token it is safe to share: it
+// opens that one preview and nothing else. The access code is the credential.
+func (s *Server) createPreviewToken(w http.ResponseWriter, r *http.Request) {
+	registration, ok := s.sessionRegistration(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Port int `json:"port"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Port < 1 || req.Port > 65535 {
+		http.Error(w, "port must be between 1 and 65535", http.StatusBadRequest)
+		return
+	}
+
+	if registration.SeatID == nil {
+		http.Error(w, "Join the workshop before previewing", http.StatusConflict)
+		return
+	}
+	workshop, err := s.store.GetWorkshop(registration.WorkshopID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if workshop == nil || workshop.Status != "running" {
+		http.Error(w, "Workshop is not running", http.StatusConflict)
+		return
+	}
+
+	token, err := auth.GeneratePreviewToken(workshop.ID, *registration.SeatID, req.Port, registration.ID)
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"token":      token,
+		"port":       req.Port,
+		"expires_at": time.Now().Add(auth.PreviewTokenTTL),
+	})
+}
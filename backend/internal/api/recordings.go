@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/clarateach/backend/internal/provisioner"
+	"github.com/clarateach/backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// recordingCollectTimeout bounds collecting a workshop's recordings, so a slow
+// worker cannot hold up deleting its VM.
+const recordingCollectTimeout = 2 * time.Minute
+
+// collectRecordings copies the workshop's terminal recordings from its worker
+// into the store. It runs while stopping or deleting, before the VM is
+// deleted. Failures are logged and recorded on the timeline but never block
+// the stop.
+func (s *Server) collectRecordings(ctx context.Context, prov provisioner.Provisioner, workshopID string) {
+	source, ok := prov.(provisioner.RecordingSource)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, recordingCollectTimeout)
+	defer cancel()
+
+	recordings, err := source.ListRecordings(ctx, workshopID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to list terminal recordings", "error", err)
+		s.recordEvent(ctx, workshopID, eventError, 0, fmt.Sprintf("Failed to collect terminal recordings: %v", err))
+		return
+	}
+	if len(recordings) == 0 {
+		return
+	}
+
+	collected := 0
+	for _, info := range recordings {
+		data, err := source.FetchRecording(ctx, workshopID, info.Name)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to fetch terminal recording", "seat", info.SeatID, "name", info.Name, "error", err)
+			continue
+		}
+
+		rec := &store.TerminalRecording{
+			WorkshopID: workshopID,
+			SeatID:     info.SeatID,
+			Name:       info.Name,
+			SizeBytes:  int64(len(data)),
+			StartedAt:  info.StartedAt,
+			Data:       data,
+			CreatedAt:  time.Now(),
+		}
+		if session, err := s.store.GetSessionBySeat(workshopID, info.SeatID); err == nil && session != nil {
+			rec.LearnerName = session.Name
+		}
+		if err := s.store.CreateTerminalRecording(rec); err != nil {
+			s.logger.ErrorContext(ctx, "failed to store terminal recording", "seat", info.SeatID, "name", info.Name, "error", err)
+			continue
+		}
+		collected++
+	}
+
+	s.logger.InfoContext(ctx, "terminal recordings collected", "collected", collected, "total", len(recordings))
+	s.recordEvent(ctx, workshopID, eventRecordingsCollected, 0, fmt.Sprintf("Collected %d of %d terminal recordings", collected, len(recordings)))
+}
+
+// listRecordings lists the workshop's collected terminal recordings, oldest
+// first. ?seat=N limits the list to one learner's seat.
+func (s *Server) listRecordings(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	seat := 0
+	if v := r.URL.Query().Get("seat"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "Seat must be a positive number", http.StatusBadRequest)
+			return
+		}
+		seat = n
+	}
+
+	recordings, err := s.store.ListTerminalRecordings(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	result := make([]*store.TerminalRecording, 0, len(recordings))
+	for _, rec := range recordings {
+		if seat == 0 || rec.SeatID == seat {
+			result = append(result, rec)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"recordings": result})
+}
+
+// getRecording downloads one recording as an asciicast v2 file, playable with
+// asciinema or asciinema-player.
+func (s *Server) getRecording(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	recordingID, err := strconv.ParseInt(chi.URLParam(r, "recordingID"), 10, 64)
+	if err != nil {
+		http.Error(w, "Recording not found", http.StatusNotFound)
+		return
+	}
+
+	rec, err := s.store.GetTerminalRecording(recordingID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rec == nil || rec.WorkshopID != id {
+		http.Error(w, "Recording not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-asciicast")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("%s-%s", id, rec.Name)))
+	w.Write(rec.Data)
+}
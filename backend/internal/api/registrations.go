@@ -0,0 +1,359 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/clarateach/backend/internal/logging"
+	"github.com/clarateach/backend/internal/provisioner"
+	"github.com/clarateach/backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// listRegistrations returns every registration for the workshop, oldest first,
+// including access codes so staff can resend them.
+func (s *Server) listRegistrations(w http.ResponseWriter, r *http.Request) {
+	registrations, err := s.store.ListRegistrations(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if registrations == nil {
+		registrations = []*store.Registration{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"registrations": registrations})
+}
+
+// revokeRegistration cancels a registration: its access code stops working,
+// its seat is freed for someone else and workers reject its workspace tokens.
+// The learner's files stay on the seat until it is reset. The freed place
+// goes to the first learner on the waitlist.
+func (s *Server) revokeRegistration(w http.ResponseWriter, r *http.Request) {
+	registration, ok := s.workshopRegistration(w, r)
+	if !ok {
+		return
+	}
+
+	registration, vacated, status, err := s.cancelRegistration(r.Context(), registration.ID)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	// Workers and the waitlist are dealt with once seats are unlocked, as
+	// they may call out to the provisioner and the mail provider
+	ctx := logging.WithWorkshop(r.Context(), registration.WorkshopID)
+	if vacated != nil {
+		s.revokeSeatTokens(logging.WithSeat(ctx, registration.WorkshopID, *vacated), registration, *vacated)
+	}
+	s.promoteWaitlist(ctx, registration.WorkshopID)
+
+	s.logger.InfoContext(ctx, "registration revoked", "registration_id", registration.ID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"registration": registration})
+}
+
+// cancelRegistration marks the registration revoked and frees the seat it
+// occupied, returning that seat if the learner had joined on it. The
+// registration is reloaded under seatMu, since the learner may have joined
+// since it was looked up. On error it also returns the HTTP status to answer
+// with.
+func (s *Server) cancelRegistration(ctx context.Context, id string) (registration *store.Registration, vacated *int, status int, err error) {
+	s.seatMu.Lock()
+	defer s.seatMu.Unlock()
+
+	registration, err = s.store.GetRegistrationByID(id)
+	if err != nil {
+		return nil, nil, http.StatusInternalServerError, err
+	}
+	if registration == nil {
+		return nil, nil, http.StatusNotFound, fmt.Errorf("Registration not found")
+	}
+	if registration.Status == "revoked" {
+		return nil, nil, http.StatusConflict, fmt.Errorf("Registration is already revoked")
+	}
+
+	oldSeat := registration.SeatID
+	joined := registration.JoinedAt != nil
+	registration.Status = "revoked"
+	registration.SeatID = nil
+	registration.WaitlistPosition = 0
+	if err := s.store.UpdateRegistration(registration); err != nil {
+		return nil, nil, http.StatusInternalServerError, err
+	}
+	if oldSeat != nil && joined {
+		s.vacateSeat(logging.WithSeat(ctx, registration.WorkshopID, *oldSeat), registration.WorkshopID, *oldSeat)
+		vacated = oldSeat
+	}
+	return registration, vacated, 0, nil
+}
+
+// moveRegistration puts a learner on a different seat. A learner who has
+// joined moves at once and must reload their workspace, as tokens for the old
+// seat are revoked; otherwise the new seat is kept for them until they join.
+func (s *Server) moveRegistration(w http.ResponseWriter, r *http.Request) {
+	registration, ok := s.workshopRegistration(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Seat int `json:"seat"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	workshop, err := s.store.GetWorkshop(registration.WorkshopID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if req.Seat < 1 || req.Seat > workshop.Seats {
+		http.Error(w, fmt.Sprintf("Seat must be from 1 to %d", workshop.Seats), http.StatusBadRequest)
+		return
+	}
+
+	registration, vacated, status, err := s.moveToSeat(r.Context(), workshop, registration.ID, req.Seat)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	// The old seat's tokens are revoked once seats are unlocked, as that calls
+	// out to the workshop's workers
+	ctx := logging.WithWorkshop(r.Context(), workshop.ID)
+	if vacated != nil {
+		s.revokeSeatTokens(logging.WithSeat(ctx, workshop.ID, *vacated), registration, *vacated)
+	}
+
+	s.logger.InfoContext(ctx, "registration moved", "registration_id", registration.ID, "seat", req.Seat)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"registration": registration})
+}
+
+// moveToSeat assigns the registration to a seat that nobody else holds. If
+// the learner has joined, the new seat is occupied at once and the old seat
+// is freed and returned. The registration is reloaded under seatMu, since
+// the learner may have joined since it was looked up. On error it also
+// returns the HTTP status to answer with.
+func (s *Server) moveToSeat(ctx context.Context, workshop *store.Workshop, id string, seat int) (registration *store.Registration, vacated *int, status int, err error) {
+	s.seatMu.Lock()
+	defer s.seatMu.Unlock()
+
+	registration, err = s.store.GetRegistrationByID(id)
+	if err != nil {
+		return nil, nil, http.StatusInternalServerError, err
+	}
+	if registration == nil {
+		return nil, nil, http.StatusNotFound, fmt.Errorf("Registration not found")
+	}
+	if registration.Status == "revoked" || registration.Status == "waitlisted" {
+		return nil, nil, http.StatusConflict, fmt.Errorf("Registration is %s", registration.Status)
+	}
+	if registration.SeatID != nil && *registration.SeatID == seat {
+		return registration, nil, 0, nil
+	}
+
+	// The target seat must not be held by anyone else
+	registrations, err := s.store.ListRegistrations(workshop.ID)
+	if err != nil {
+		return nil, nil, http.StatusInternalServerError, err
+	}
+	for _, reg := range registrations {
+		if reg.ID != registration.ID && reg.SeatID != nil && *reg.SeatID == seat {
+			return nil, nil, http.StatusConflict, fmt.Errorf("Seat %d is taken by %s", seat, reg.Name)
+		}
+	}
+	target, err := s.store.GetSessionBySeat(workshop.ID, seat)
+	if err != nil {
+		return nil, nil, http.StatusInternalServerError, err
+	}
+	if target != nil && target.Name != "" {
+		return nil, nil, http.StatusConflict, fmt.Errorf("Seat %d is taken by %s", seat, target.Name)
+	}
+
+	oldSeat := registration.SeatID
+	joined := registration.JoinedAt != nil
+	if joined {
+		if target == nil || (target.Status != "ready" && target.Status != "provisioning") {
+			return nil, nil, http.StatusConflict, fmt.Errorf("Seat %d is not ready", seat)
+		}
+		target.Name = registration.Name
+		target.Status = "occupied"
+		target.ContainerID = fmt.Sprintf("seat-%d", seat)
+		if err := s.store.UpdateSession(target); err != nil {
+			return nil, nil, http.StatusInternalServerError, err
+		}
+		s.publishSeat(target)
+	}
+
+	registration.SeatID = &seat
+	if err := s.store.UpdateRegistration(registration); err != nil {
+		return nil, nil, http.StatusInternalServerError, err
+	}
+	if oldSeat != nil && joined {
+		s.vacateSeat(logging.WithSeat(ctx, workshop.ID, *oldSeat), workshop.ID, *oldSeat)
+		vacated = oldSeat
+	}
+	return registration, vacated, 0, nil
+}
+
+// vacateSeat frees a seat for the next learner. Callers hold seatMu and
+// revoke the previous learner's workspace tokens once they release it.
+func (s *Server) vacateSeat(ctx context.Context, workshopID string, seatID int) {
+	sess, err := s.store.GetSessionBySeat(workshopID, seatID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to load seat session", "error", err)
+		return
+	}
+	if sess == nil {
+		return
+	}
+	sess.Name = ""
+	if sess.Status == "occupied" {
+		sess.Status = "ready"
+	}
+	if err := s.store.UpdateSession(sess); err != nil {
+		s.logger.WarnContext(ctx, "failed to free seat", "error", err)
+	}
+	s.publishSeat(sess)
+}
+
+// revokeSeatTokens asks the workshop's workers to reject the workspace tokens
+// issued so far to the registration for a seat.
+func (s *Server) revokeSeatTokens(ctx context.Context, registration *store.Registration, seatID int) {
+	workshop, err := s.store.GetWorkshop(registration.WorkshopID)
+	if err != nil || workshop == nil || workshop.Status != "running" {
+		return
+	}
+	if revoker, ok := s.getProvisioner(workshop.RuntimeType).(provisioner.TokenRevoker); ok {
+		if err := revoker.RevokeWorkspaceTokens(ctx, workshop.ID, seatID, registration.ID); err != nil {
+			s.logger.WarnContext(ctx, "failed to revoke workspace tokens", "error", err)
+		}
+	}
+}
+
+// resetSeat replaces a free seat's workspace with a fresh one, so the next
+// learner does not inherit the previous learner's files. The seat is
+// "resetting" until the new workspace is up, then "ready" (or "error").
+func (s *Server) resetSeat(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	seat, err := strconv.Atoi(chi.URLParam(r, "seat"))
+	if err != nil {
+		http.Error(w, "Seat must be a number", http.StatusBadRequest)
+		return
+	}
+
+	workshop, err := s.store.GetWorkshop(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if workshop.Status != "running" {
+		http.Error(w, "Workshop is not running", http.StatusConflict)
+		return
+	}
+	scaler, ok := s.getProvisioner(workshop.RuntimeType).(provisioner.SeatScaler)
+	if !ok {
+		http.Error(w, fmt.Sprintf("The %s runtime cannot reset seats", workshop.RuntimeType), http.StatusConflict)
+		return
+	}
+
+	s.seatMu.Lock()
+	sess, err := s.store.GetSessionBySeat(id, seat)
+	if err != nil {
+		s.seatMu.Unlock()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if sess == nil {
+		s.seatMu.Unlock()
+		http.Error(w, "Seat not found", http.StatusNotFound)
+		return
+	}
+	reserved, err := s.reservedSeats(id)
+	if err != nil {
+		s.seatMu.Unlock()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if sess.Name != "" || reserved[seat] {
+		s.seatMu.Unlock()
+		http.Error(w, "Seat is taken; revoke or move its learner first", http.StatusConflict)
+		return
+	}
+	if sess.Status == "resetting" {
+		s.seatMu.Unlock()
+		http.Error(w, "Seat is already being reset", http.StatusConflict)
+		return
+	}
+	sess.Status = "resetting"
+	err = s.store.UpdateSession(sess)
+	s.seatMu.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.publishSeat(sess)
+
+	// The reset must finish even if the client goes away
+	ctx := logging.WithSeat(context.WithoutCancel(r.Context()), id, seat)
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+		defer cancel()
+
+		cfg := provisioner.DefaultConfig(id, workshop.Seats)
+		cfg.RuntimeType = workshop.RuntimeType
+		cfg.Events = s.eventRecorder(ctx, id)
+
+		if err := scaler.RemoveSeats(ctx, id, []int{seat}); err != nil {
+			s.logger.WarnContext(ctx, "failed to remove seat workspace for reset", "error", err)
+		}
+		err := scaler.AddSeats(ctx, cfg, []int{seat})
+
+		s.seatMu.Lock()
+		defer s.seatMu.Unlock()
+		sess, lookupErr := s.store.GetSessionBySeat(id, seat)
+		if lookupErr != nil || sess == nil {
+			return // Seat removed meanwhile
+		}
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to reset seat", "error", err)
+			s.recordEvent(ctx, id, eventError, seat, fmt.Sprintf("Seat reset failed: %v", err))
+			sess.Status = "error"
+		} else {
+			s.recordEvent(ctx, id, eventSeatReset, seat, "Seat workspace reset")
+			sess.Status = "ready"
+		}
+		if err := s.store.UpdateSession(sess); err != nil {
+			s.logger.WarnContext(ctx, "failed to update seat after reset", "error", err)
+		}
+		s.publishSeat(sess)
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{"seat": seat, "status": sess.Status})
+}
+
+// workshopRegistration looks up the {registrationID} URL parameter,
+// responding with 404 unless it belongs to the {id} workshop.
+func (s *Server) workshopRegistration(w http.ResponseWriter, r *http.Request) (*store.Registration, bool) {
+	registration, err := s.store.GetRegistrationByID(chi.URLParam(r, "registrationID"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if registration == nil || registration.WorkshopID != chi.URLParam(r, "id") {
+		http.Error(w, "Registration not found", http.StatusNotFound)
+		return nil, false
+	}
+	return registration, true
+}
@@ -0,0 +1,321 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/clarateach/backend/internal/events"
+	"github.com/clarateach/backend/internal/notify"
+	"github.com/clarateach/backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxRosterBytes caps the size of an imported roster CSV.
+const maxRosterBytes = 1 << 20
+
+// rosterRowError reports why one row of an imported roster was rejected.
+// Row is the 1-based line in the CSV, counting the header.
+type rosterRowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// importRegistrations registers a roster of learners from CSV with name, email
+// and optional seat columns. A header row naming the columns is optional;
+// without one the columns are taken in that order. Either every row is
+// registered or, if any row is invalid, none are and each problem is listed.
+func (s *Server) importRegistrations(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	workshop, err := s.store.GetWorkshop(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if workshop.Status == "ended" || workshop.Status == "deleted" {
+		http.Error(w, "Workshop has ended", http.StatusGone)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxRosterBytes)
+	body := io.Reader(r.Body)
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "Expected the roster CSV in a \"file\" field", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+	records, err := readRoster(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	registrations, rowErrors, status, err := s.registerRoster(workshop, records)
+	if len(rowErrors) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":  "Roster has invalid rows; no learners were registered",
+			"errors": rowErrors,
+		})
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	for _, reg := range registrations {
+		s.events.Publish(events.Event{
+			Type:       events.TypeRegistration,
+			WorkshopID: id,
+			Data:       map[string]interface{}{"name": reg.Name, "email": reg.Email},
+		})
+		s.notifyLearner(r.Context(), workshop, reg, notify.KindRegistration)
+		s.emitRegistrationWebhook(r.Context(), reg)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"imported":      len(registrations),
+		"registrations": registrations,
+	})
+}
+
+// registerRoster validates roster records against the workshop's existing
+// registrations and seats and, if every row is valid and fits, registers
+// them. Row problems are returned as rowErrors; otherwise on error it also
+// returns the HTTP status to answer with.
+func (s *Server) registerRoster(workshop *store.Workshop, records []rosterRecord) (registrations []*store.Registration, rowErrors []rosterRowError, status int, err error) {
+	// Registrations and seats must not change between checking them and
+	// registering
+	s.seatMu.Lock()
+	defer s.seatMu.Unlock()
+	s.waitlistMu.Lock()
+	defer s.waitlistMu.Unlock()
+
+	existing, err := s.store.ListRegistrations(workshop.ID)
+	if err != nil {
+		return nil, nil, http.StatusInternalServerError, err
+	}
+
+	// Seats already held by a registration or a learner who joined by code.
+	// Emails map to the status of their registration; revoked ones are kept
+	// since a revoked learner cannot register again.
+	taken := make(map[int]bool)
+	emails := make(map[string]string)
+	registered, waitlisted := 0, 0
+	for _, reg := range existing {
+		emails[strings.ToLower(reg.Email)] = reg.Status
+		switch reg.Status {
+		case "revoked":
+		case "waitlisted":
+			waitlisted++
+		default:
+			registered++
+		}
+		if reg.SeatID != nil {
+			taken[*reg.SeatID] = true
+		}
+	}
+	sessions, err := s.store.ListSessions(workshop.ID)
+	if err != nil {
+		return nil, nil, http.StatusInternalServerError, err
+	}
+	for _, sess := range sessions {
+		if sess.Name != "" {
+			taken[sess.SeatID] = true
+		}
+	}
+
+	registrations = make([]*store.Registration, 0, len(records))
+	for _, rec := range records {
+		reg, err := rosterRegistration(rec, workshop, emails, taken)
+		if err != nil {
+			rowErrors = append(rowErrors, rosterRowError{Row: rec.row, Error: err.Error()})
+			continue
+		}
+		registrations = append(registrations, reg)
+	}
+	if len(rowErrors) > 0 {
+		return nil, rowErrors, http.StatusBadRequest, nil
+	}
+
+	// Imported learners would take seats ahead of those already waiting
+	if waitlisted > 0 {
+		return nil, nil, http.StatusConflict, fmt.Errorf("Workshop has %d learners on its waitlist; promote or revoke them before importing a roster", waitlisted)
+	}
+	if registered+len(records) > workshop.Seats {
+		return nil, nil, http.StatusConflict, fmt.Errorf("Workshop has %d seats left, roster has %d learners", max(workshop.Seats-registered, 0), len(records))
+	}
+
+	if err := s.store.CreateRegistrations(registrations); err != nil {
+		return nil, nil, http.StatusInternalServerError, err
+	}
+	return registrations, nil, http.StatusOK, nil
+}
+
+// rosterRecord is one learner row of an imported roster.
+type rosterRecord struct {
+	row               int
+	name, email, seat string
+}
+
+// readRoster parses roster CSV into records, skipping blank lines.
+func readRoster(r io.Reader) ([]rosterRecord, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return nil, fmt.Errorf("Roster is larger than %d bytes", maxRosterBytes)
+		}
+		return nil, fmt.Errorf("Invalid CSV: %v", err)
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("Roster is empty")
+	}
+
+	// Column positions, from the header if there is one
+	nameCol, emailCol, seatCol := 0, 1, 2
+	start := 0
+	if header := rows[0]; len(header) > 0 && (strings.EqualFold(strings.TrimSpace(header[0]), "name") || strings.EqualFold(strings.TrimSpace(header[0]), "email")) {
+		nameCol, emailCol, seatCol = -1, -1, -1
+		for i, col := range header {
+			switch strings.ToLower(strings.TrimSpace(col)) {
+			case "name":
+				nameCol = i
+			case "email":
+				emailCol = i
+			case "seat", "seat_id":
+				seatCol = i
+			}
+		}
+		if nameCol < 0 || emailCol < 0 {
+			return nil, errors.New("Roster header must include name and email columns")
+		}
+		start = 1
+	}
+
+	field := func(row []string, col int) string {
+		if col < 0 || col >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[col])
+	}
+	var records []rosterRecord
+	for i, row := range rows[start:] {
+		if strings.TrimSpace(strings.Join(row, "")) == "" {
+			continue
+		}
+		records = append(records, rosterRecord{
+			row:   start + i + 1,
+			name:  field(row, nameCol),
+			email: field(row, emailCol),
+			seat:  field(row, seatCol),
+		})
+	}
+	if len(records) == 0 {
+		return nil, errors.New("Roster has no learners")
+	}
+	return records, nil
+}
+
+// rosterRegistration validates one roster record and builds its registration.
+// It records the email and seat as taken so later rows cannot reuse them.
+func rosterRegistration(rec rosterRecord, workshop *store.Workshop, emails map[string]string, taken map[int]bool) (*store.Registration, error) {
+	if rec.name == "" {
+		return nil, errors.New("Name is required")
+	}
+	if addr, err := mail.ParseAddress(rec.email); err != nil || addr.Address != rec.email {
+		return nil, fmt.Errorf("Invalid email %q", rec.email)
+	}
+	switch emails[strings.ToLower(rec.email)] {
+	case "":
+	case "revoked":
+		return nil, fmt.Errorf("%s was revoked and cannot be registered again", rec.email)
+	default:
+		return nil, fmt.Errorf("%s is already registered", rec.email)
+	}
+
+	reg := &store.Registration{
+		ID:         "reg-" + generateID(8),
+		AccessCode: generateAccessCode(),
+		Email:      rec.email,
+		Name:       rec.name,
+		WorkshopID: workshop.ID,
+		Status:     "registered",
+		CreatedAt:  time.Now(),
+	}
+	if rec.seat != "" {
+		seat, err := strconv.Atoi(rec.seat)
+		if err != nil || seat < 1 || seat > workshop.Seats {
+			return nil, fmt.Errorf("Seat must be a number from 1 to %d", workshop.Seats)
+		}
+		if taken[seat] {
+			return nil, fmt.Errorf("Seat %d is already taken", seat)
+		}
+		taken[seat] = true
+		reg.SeatID = &seat
+	}
+	emails[strings.ToLower(rec.email)] = reg.Status
+	return reg, nil
+}
+
+// exportRegistrations downloads the workshop roster as CSV for attendance
+// tracking: one row per registration, oldest first.
+func (s *Server) exportRegistrations(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	workshop, err := s.store.GetWorkshop(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	registrations, err := s.store.ListRegistrations(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", workshop.Code+"-roster.csv"))
+
+	out := csv.NewWriter(w)
+	out.Write([]string{"name", "email", "access_code", "seat", "status", "registered_at", "joined_at"})
+	for _, reg := range registrations {
+		var seat, joined string
+		if reg.SeatID != nil {
+			seat = strconv.Itoa(*reg.SeatID)
+		}
+		if reg.JoinedAt != nil {
+			joined = reg.JoinedAt.UTC().Format(time.RFC3339)
+		}
+		out.Write([]string{csvCell(reg.Name), csvCell(reg.Email), reg.AccessCode, seat, reg.Status, reg.CreatedAt.UTC().Format(time.RFC3339), joined})
+	}
+	out.Flush()
+}
+
+// csvCell keeps a learner-supplied value from being read as a formula when
+// the export is opened in a spreadsheet, by prefixing a quote to values that
+// start with a formula character.
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -3,20 +3,32 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"math/rand"
 	"net/http"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/clarateach/backend/internal/auth"
+	"github.com/clarateach/backend/internal/events"
+	"github.com/clarateach/backend/internal/jwks"
+	"github.com/clarateach/backend/internal/logging"
+	"github.com/clarateach/backend/internal/lti"
+	"github.com/clarateach/backend/internal/metrics"
+	"github.com/clarateach/backend/internal/notify"
+	"github.com/clarateach/backend/internal/oidc"
 	"github.com/clarateach/backend/internal/provisioner"
 	"github.com/clarateach/backend/internal/sshutil"
 	"github.com/clarateach/backend/internal/store"
+	"github.com/clarateach/backend/internal/tracing"
+	"github.com/clarateach/backend/internal/webhook"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type Server struct {
@@ -27,23 +39,53 @@ type Server struct {
 	gcpFirecrackerProvisioner *provisioner.GCPFirecrackerProvider    // GCP + Firecracker
 	useSpotVMs                bool
 	fcSnapshotName            string // Firecracker snapshot name for visibility
+	metrics                   *metrics.ServerMetrics
+	metricsToken              string // Optional bearer token required by /metrics
+	logger                    *slog.Logger
+	events                    *events.Bus     // Live updates for dashboard and learner streams
+	notifier                  notify.Notifier // Learner emails; nil disables them
+	publicURL                 string          // Frontend base URL for links in emails
+	loginLinkTTL              time.Duration   // How long learner login links work
+	refreshTokenTTL           time.Duration   // How long a sign-in lasts without being refreshed
+	webhookSender             *webhook.Sender // Posts webhook deliveries
+	webhookWake               chan struct{}   // Signals RunWebhooks that deliveries were queued
+	jwks                      *jwks.Cache     // Signing keys of LTI platforms
+	ltiKey                    *lti.ToolKey    // Signs LTI deep linking responses; nil disables LTI
+	ltiToolURL                string          // Backend base URL LTI platforms launch into
+	ltiAppURL                 string          // Frontend base URL LTI launches redirect to
+	ltiMu                     sync.Mutex      // Guards ltiNonces, used launch nonces and when they expire
+	ltiNonces                 map[string]time.Time
+	oidcProviders             []*oidc.Provider // Single sign-on providers; empty disables SSO
+	oidcBaseURL               string           // Backend base URL providers redirect back to
+	oidcAppURL                string           // Frontend base URL signed-in users are sent to
+	seatMu                    sync.Mutex       // Serializes seat allocation
+	waitlistMu                sync.Mutex       // Serializes registration against waitlist promotion
 }
 
 func NewServer(store store.Store, prov provisioner.Provisioner, useSpotVMs bool) *Server {
 	s := &Server{
-		store:       store,
-		provisioner: prov,
-		router:      chi.NewRouter(),
-		useSpotVMs:  useSpotVMs,
+		store:           store,
+		provisioner:     prov,
+		router:          chi.NewRouter(),
+		useSpotVMs:      useSpotVMs,
+		metrics:         metrics.NewServerMetrics(store),
+		logger:          logging.Component("api"),
+		events:          events.NewBus(),
+		loginLinkTTL:    30 * time.Minute,
+		refreshTokenTTL: 30 * 24 * time.Hour,
+		webhookSender:   webhook.NewSender(false),
+		webhookWake:     make(chan struct{}, 1),
+		jwks:            jwks.NewCache(),
+		ltiNonces:       make(map[string]time.Time),
 	}
 
 	// Initialize local Firecracker provisioner (optional - may fail if not on Linux with KVM)
 	fcProv, err := provisioner.NewFirecrackerProvisioner()
 	if err != nil {
-		log.Printf("Local Firecracker provisioner not available: %v", err)
+		s.logger.Info("local Firecracker provisioner not available", "error", err)
 	} else {
 		s.firecrackerProvisioner = fcProv
-		log.Printf("Local Firecracker provisioner initialized")
+		s.logger.Info("local Firecracker provisioner initialized")
 	}
 
 	s.routes()
@@ -54,7 +96,17 @@ func NewServer(store store.Store, prov provisioner.Provisioner, useSpotVMs bool)
 func (s *Server) SetGCPFirecrackerProvisioner(prov *provisioner.GCPFirecrackerProvider, snapshotName string) {
 	s.gcpFirecrackerProvisioner = prov
 	s.fcSnapshotName = snapshotName
-	log.Printf("GCP Firecracker provisioner initialized with snapshot: %s", snapshotName)
+	s.logger.Info("GCP Firecracker provisioner initialized", "snapshot", snapshotName)
+}
+
+// SetMetricsToken requires scrapers of /metrics to present the given bearer token
+func (s *Server) SetMetricsToken(token string) {
+	s.metricsToken = token
+}
+
+// Metrics returns the server's Prometheus collectors
+func (s *Server) Metrics() *metrics.ServerMetrics {
+	return s.metrics
 }
 
 // getProvisioner returns the appropriate provisioner based on runtime type
@@ -77,11 +129,13 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) routes() {
-	s.router.Use(middleware.Logger)
+	s.router.Use(logging.Middleware)
 	s.router.Use(middleware.Recoverer)
+	s.router.Use(tracing.Middleware)
+	s.router.Use(s.metrics.Middleware)
 	s.router.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"}, // In prod, be more restrictive
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
 		AllowCredentials: true,
 	}))
@@ -96,35 +150,106 @@ func (s *Server) routes() {
 		json.NewEncoder(w).Encode(resp)
 	})
 
+	// Prometheus metrics (token is checked per request so it can be set after construction)
+	s.router.Get("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		s.metrics.Handler(s.metricsToken).ServeHTTP(w, r)
+	})
+
 	s.router.Route("/api", func(r chi.Router) {
 		// Auth endpoints (public)
 		r.Route("/auth", func(r chi.Router) {
 			r.Post("/register", s.authRegister)
 			r.Post("/login", s.authLogin)
+			r.Post("/refresh", s.authRefresh)
 			r.Post("/logout", s.authLogout)
+			r.Get("/oidc/providers", s.listOIDCProviders)
+			r.Get("/oidc/{provider}/login", s.oidcLogin)
+			r.Get("/oidc/{provider}/callback", s.oidcCallback)
 		})
 
 		// Protected auth endpoint
 		r.Group(func(r chi.Router) {
 			r.Use(auth.AuthMiddleware(s.store))
 			r.Get("/auth/me", s.authMe)
+			r.Post("/auth/logout-all", s.authLogoutAll)
+			r.Get("/auth/identities", s.listIdentities)
+			r.Delete("/auth/identities/{provider}", s.unlinkIdentity)
+			r.Post("/auth/oidc/{provider}/link", s.linkOIDCProvider)
+			r.Post("/auth/oidc/{provider}/link/complete", s.completeOIDCLink)
 		})
 
 		// Learner registration (public)
 		r.Post("/register", s.registerForWorkshop)
 		r.Get("/session/{code}", s.getSessionByCode)
+		r.Get("/session/{code}/stream", s.streamSession)
+		r.Get("/session/{code}/announcements", s.listSessionAnnouncements)
+		r.Get("/session/{code}/announcements/stream", s.streamSessionAnnouncements)
+		r.Get("/session/{code}/help", s.getSessionHelp)
+		r.Post("/session/{code}/help", s.raiseHand)
+		r.Delete("/session/{code}/help", s.lowerHand)
+		r.Post("/session/{code}/preview", s.createPreviewToken)
+		r.Post("/session/login-link", s.requestLoginLink)
+		r.Post("/session/login", s.loginWithLink)
 		r.Post("/join", s.joinWorkshop)
 
+		// LTI 1.3 launches from an LMS
+		r.Route("/lti", func(r chi.Router) {
+			r.Use(s.requireLTI)
+			r.Get("/jwks", s.ltiKeySet)
+			r.Get("/login", s.ltiLogin)
+			r.Post("/login", s.ltiLogin)
+			r.Post("/launch", s.ltiLaunch)
+			r.Post("/deep-link/workshops", s.listLTIDeepLinkWorkshops)
+			r.Post("/deep-link", s.completeLTIDeepLink)
+		})
+
 		// Instructor routes (protected)
 		r.Route("/workshops", func(r chi.Router) {
 			r.Use(auth.AuthMiddleware(s.store))
 			r.Get("/", s.listWorkshops)
 			r.Post("/", s.createWorkshop)
 			r.Route("/{id}", func(r chi.Router) {
-				r.Get("/", s.getWorkshop)
-				r.Delete("/", s.deleteWorkshop)
-				r.Post("/start", s.startWorkshop)
-				r.Post("/stop", s.stopWorkshop)
+				r.With(s.requireWorkshop(permView)).Get("/", s.getWorkshop)
+				r.With(s.requireWorkshop(permStartStop)).Patch("/", s.updateWorkshop)
+				r.With(s.requireWorkshop(permDelete)).Delete("/", s.deleteWorkshop)
+				r.With(s.requireWorkshop(permStartStop)).Post("/start", s.startWorkshop)
+				r.With(s.requireWorkshop(permStartStop)).Post("/stop", s.stopWorkshop)
+				r.With(s.requireWorkshop(permView)).Get("/timeline", s.getWorkshopTimeline)
+				r.With(s.requireWorkshop(permView)).Get("/stream", s.streamWorkshop)
+				r.With(s.requireWorkshop(permManageLearners)).Get("/learners", s.listLearners)
+				r.With(s.requireWorkshop(permManageLearners)).Post("/learners/{seat}/observe", s.observeLearner)
+				r.With(s.requireWorkshop(permControlTerminal)).Post("/learners/{seat}/control", s.controlLearner)
+				r.With(s.requireWorkshop(permManageLearners)).Get("/recordings", s.listRecordings)
+				r.With(s.requireWorkshop(permView)).Get("/announcements", s.listAnnouncements)
+				r.With(s.requireWorkshop(permManageLearners)).Post("/announcements", s.createAnnouncement)
+				r.With(s.requireWorkshop(permManageLearners)).Get("/help-requests", s.listHelpRequests)
+				r.With(s.requireWorkshop(permManageLearners)).Post("/help-requests/{requestID}/claim", s.claimHelpRequest)
+				r.With(s.requireWorkshop(permManageLearners)).Post("/help-requests/{requestID}/resolve", s.completeHelpRequest)
+				r.With(s.requireWorkshop(permManageLearners)).Get("/recordings/{recordingID}", s.getRecording)
+				r.With(s.requireWorkshop(permManageLearners)).Post("/registrations/import", s.importRegistrations)
+				r.With(s.requireWorkshop(permManageLearners)).Get("/registrations.csv", s.exportRegistrations)
+				r.With(s.requireWorkshop(permManageLearners)).Get("/registrations", s.listRegistrations)
+				r.With(s.requireWorkshop(permManageLearners)).Delete("/registrations/{registrationID}", s.revokeRegistration)
+				r.With(s.requireWorkshop(permManageLearners)).Post("/registrations/{registrationID}/seat", s.moveRegistration)
+				r.With(s.requireWorkshop(permManageLearners)).Post("/registrations/{registrationID}/login-link", s.sendRegistrationLoginLink)
+				r.With(s.requireWorkshop(permManageLearners)).Post("/registrations/{registrationID}/rotate-code", s.rotateAccessCode)
+				r.With(s.requireWorkshop(permStartStop)).Post("/seats/{seat}/reset", s.resetSeat)
+				r.With(s.requireWorkshop(permManageLearners)).Get("/waitlist", s.listWaitlist)
+				r.With(s.requireWorkshop(permManageLearners)).Put("/waitlist", s.reorderWaitlist)
+				r.With(s.requireWorkshop(permManageLearners)).Get("/notifications", s.listNotifications)
+				r.With(s.requireWorkshop(permView)).Get("/notification-templates", s.listNotificationTemplates)
+				r.With(s.requireWorkshop(permStartStop)).Put("/notification-templates/{kind}", s.setNotificationTemplate)
+				r.With(s.requireWorkshop(permStartStop)).Delete("/notification-templates/{kind}", s.resetNotificationTemplate)
+				r.With(s.requireWorkshop(permStartStop)).Get("/webhooks", s.listWebhooks)
+				r.With(s.requireWorkshop(permStartStop)).Post("/webhooks", s.createWebhook)
+				r.With(s.requireWorkshop(permStartStop)).Delete("/webhooks/{webhookID}", s.deleteWebhook)
+				r.With(s.requireWorkshop(permStartStop)).Get("/webhooks/{webhookID}/deliveries", s.listWebhookDeliveries)
+				r.With(s.requireWorkshop(permStartStop)).Post("/webhooks/{webhookID}/deliveries/{deliveryID}/redeliver", s.redeliverWebhook)
+				r.Route("/members", func(r chi.Router) {
+					r.With(s.requireWorkshop(permView)).Get("/", s.listWorkshopMembers)
+					r.With(s.requireWorkshop(permManageMembers)).Post("/", s.addWorkshopMember)
+					r.With(s.requireWorkshop(permManageMembers)).Delete("/{userID}", s.removeWorkshopMember)
+				})
 			})
 		})
 
@@ -137,6 +262,18 @@ func (s *Server) routes() {
 			r.Get("/vms/{workshop_id}", s.getVMDetails)
 			r.Get("/vms/{workshop_id}/ssh-key", s.getSSHKey)
 			r.Get("/users", s.listUsers)
+			r.Patch("/users/{userID}", s.updateUser)
+			r.Post("/users/{userID}/logout", s.logoutUser)
+			r.Get("/stream", s.streamAdmin)
+			r.Get("/webhooks", s.listWebhooks)
+			r.Post("/webhooks", s.createWebhook)
+			r.Delete("/webhooks/{webhookID}", s.deleteWebhook)
+			r.Get("/webhooks/{webhookID}/deliveries", s.listWebhookDeliveries)
+			r.Post("/webhooks/{webhookID}/deliveries/{deliveryID}/redeliver", s.redeliverWebhook)
+			r.Get("/lti/platforms", s.listLTIPlatforms)
+			r.Post("/lti/platforms", s.createLTIPlatform)
+			r.Patch("/lti/platforms/{platformID}", s.updateLTIPlatform)
+			r.Delete("/lti/platforms/{platformID}", s.deleteLTIPlatform)
 		})
 
 		// Internal API for agent VMs (no auth - called from within GCP)
@@ -155,10 +292,24 @@ func (s *Server) listWorkshops(w http.ResponseWriter, r *http.Request) {
 	var workshops []*store.Workshop
 	var err error
 
-	// If auth is enabled, filter by owner (unless admin)
+	// If auth is enabled, filter to owned and shared workshops (unless admin)
 	user := auth.GetUserFromContext(r.Context())
 	if user != nil && user.Role != "admin" {
 		workshops, err = s.store.ListWorkshopsByOwner(user.ID)
+		if err == nil {
+			var shared []*store.Workshop
+			shared, err = s.store.ListWorkshopsByMember(user.ID)
+			// List a workshop once even if its owner also has a member row
+			listed := make(map[string]bool, len(workshops))
+			for _, workshop := range workshops {
+				listed[workshop.ID] = true
+			}
+			for _, workshop := range shared {
+				if !listed[workshop.ID] {
+					workshops = append(workshops, workshop)
+				}
+			}
+		}
 	} else {
 		workshops, err = s.store.ListWorkshops()
 	}
@@ -172,10 +323,12 @@ func (s *Server) listWorkshops(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) createWorkshop(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		Name        string `json:"name"`
-		Seats       int    `json:"seats"`
-		ApiKey      string `json:"api_key"`
-		RuntimeType string `json:"runtime_type"`
+		Name        string     `json:"name"`
+		Seats       int        `json:"seats"`
+		ApiKey      string     `json:"api_key"`
+		RuntimeType string     `json:"runtime_type"`
+		Waitlist    bool       `json:"waitlist"`
+		StartsAt    *time.Time `json:"starts_at"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -202,6 +355,8 @@ func (s *Server) createWorkshop(w http.ResponseWriter, r *http.Request) {
 		ApiKey:      req.ApiKey,
 		RuntimeType: req.RuntimeType,
 		Status:      "created",
+		Waitlist:    req.Waitlist,
+		StartsAt:    req.StartsAt,
 		OwnerID:     ownerID,
 		CreatedAt:   time.Now(),
 	}
@@ -210,6 +365,7 @@ func (s *Server) createWorkshop(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	ctx := logging.WithWorkshop(r.Context(), workshop.ID)
 
 	// Create placeholder sessions for seat tracking
 	for i := 1; i <= workshop.Seats; i++ {
@@ -221,26 +377,41 @@ func (s *Server) createWorkshop(w http.ResponseWriter, r *http.Request) {
 			JoinedAt:   time.Now(),
 		}
 		if err := s.store.CreateSession(session); err != nil {
-			log.Printf("Failed to create session record for seat %d: %v", i, err)
+			s.logger.ErrorContext(ctx, "failed to create session record", "seat_id", i, "error", err)
 		}
 	}
 
 	// Set status to provisioning immediately
-	log.Printf("Provisioning GCP VM for workshop %s (%s) with %d seats", workshop.Name, workshop.ID, workshop.Seats)
-	s.store.UpdateWorkshopStatus(workshop.ID, "provisioning")
+	s.logger.InfoContext(ctx, "provisioning workshop VM", "name", workshop.Name, "seats", workshop.Seats)
+	s.setWorkshopStatus(workshop.ID, "provisioning")
 	workshop.Status = "provisioning"
+	s.recordEvent(ctx, workshop.ID, eventProvisioningStarted, 0, fmt.Sprintf("Provisioning %d seats (%s runtime)", workshop.Seats, workshop.RuntimeType))
 
 	// Return response immediately - provisioning happens async
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{"workshop": workshop})
 
-	// Provision VM asynchronously
+	// Provision VM asynchronously using the runtime-specific provisioner
+	prov := s.getProvisioner(workshop.RuntimeType)
+	provName := provisioner.Name(prov)
+	traceCtx := context.WithoutCancel(ctx) // Keeps trace and log fields, not the request deadline
 	go func() {
+		traceCtx, span := tracing.Start(traceCtx, "createWorkshop",
+			attribute.String("workshop.id", workshop.ID),
+			attribute.Int("workshop.seats", workshop.Seats),
+			attribute.String("workshop.runtime", workshop.RuntimeType),
+			attribute.String("provisioner", provName),
+		)
+		defer span.End()
+
 		// Generate SSH key pair for debugging access
 		keyPair, err := sshutil.GenerateKeyPair(fmt.Sprintf("clarateach-%s", workshop.ID))
 		if err != nil {
-			log.Printf("Failed to generate SSH key: %v", err)
-			s.store.UpdateWorkshopStatus(workshop.ID, "error")
+			s.logger.ErrorContext(traceCtx, "failed to generate SSH key", "error", err)
+			tracing.Fail(span, err)
+			s.metrics.ProvisioningFailed(provName, fmt.Errorf("ssh key: %w", err))
+			s.recordEvent(traceCtx, workshop.ID, eventError, 0, fmt.Sprintf("Failed to generate SSH key: %v", err))
+			s.setWorkshopStatus(workshop.ID, "error")
 			return
 		}
 
@@ -249,6 +420,7 @@ func (s *Server) createWorkshop(w http.ResponseWriter, r *http.Request) {
 		vmConfig.Spot = s.useSpotVMs
 		vmConfig.SSHPublicKey = keyPair.PublicKey
 		vmConfig.RuntimeType = workshop.RuntimeType
+		vmConfig.Events = s.eventRecorder(traceCtx, workshop.ID)
 
 		// Track provisioning time
 		provisioningStartedAt := time.Now()
@@ -270,28 +442,33 @@ func (s *Server) createWorkshop(w http.ResponseWriter, r *http.Request) {
 		}
 
 		if err := s.store.CreateVM(workshopVM); err != nil {
-			log.Printf("Failed to create VM record: %v", err)
-			s.store.UpdateWorkshopStatus(workshop.ID, "error")
+			s.logger.ErrorContext(traceCtx, "failed to create VM record", "error", err)
+			tracing.Fail(span, err)
+			s.metrics.ProvisioningFailed(provName, fmt.Errorf("vm record: %w", err))
+			s.recordEvent(traceCtx, workshop.ID, eventError, 0, fmt.Sprintf("Failed to create VM record: %v", err))
+			s.setWorkshopStatus(workshop.ID, "error")
 			return
 		}
 
 		// Provision VM with background context (not tied to HTTP request)
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		ctx, cancel := context.WithTimeout(traceCtx, 5*time.Minute)
 		defer cancel()
 
-		// Use runtime-specific provisioner
-		prov := s.getProvisioner(workshop.RuntimeType)
 		vmInstance, err := prov.CreateVM(ctx, vmConfig)
 		if err != nil {
-			log.Printf("Failed to provision VM: %v", err)
-			s.store.UpdateWorkshopStatus(workshop.ID, "error")
+			s.logger.ErrorContext(ctx, "failed to provision VM", "error", err)
+			tracing.Fail(span, err)
+			s.metrics.ProvisioningFailed(provName, err)
+			s.recordEvent(ctx, workshop.ID, eventError, 0, err.Error())
+			s.setWorkshopStatus(workshop.ID, "error")
 			return
 		}
 
 		provisioningCompletedAt := time.Now()
 		provisioningDurationMs := provisioningCompletedAt.Sub(provisioningStartedAt).Milliseconds()
+		s.metrics.ObserveProvisioning(provName, workshop.RuntimeType, provisioningCompletedAt.Sub(provisioningStartedAt))
 
-		log.Printf("VM created: %s (IP: %s) in %dms", vmInstance.Name, vmInstance.ExternalIP, provisioningDurationMs)
+		s.logger.InfoContext(ctx, "VM created", "vm_name", vmInstance.Name, "external_ip", vmInstance.ExternalIP, "duration_ms", provisioningDurationMs)
 
 		// Update VM record with final details (tunnel_url may have been set during provisioning)
 		workshopVM.VMName = vmInstance.Name
@@ -305,7 +482,7 @@ func (s *Server) createWorkshop(w http.ResponseWriter, r *http.Request) {
 		workshopVM.UpdatedAt = time.Now()
 
 		if err := s.store.UpdateVM(workshopVM); err != nil {
-			log.Printf("Failed to update VM info: %v", err)
+			s.logger.ErrorContext(ctx, "failed to update VM info", "error", err)
 		}
 
 		// Update sessions to ready (containers run inside VM via startup script)
@@ -314,12 +491,16 @@ func (s *Server) createWorkshop(w http.ResponseWriter, r *http.Request) {
 			if sess != nil {
 				sess.Status = "ready"
 				sess.IP = vmInstance.ExternalIP
-				s.store.UpdateSession(sess)
+				if err := s.store.UpdateSession(sess); err == nil {
+					s.publishSeat(sess)
+				}
 			}
 		}
 
-		s.store.UpdateWorkshopStatus(workshop.ID, "running")
-		log.Printf("Workshop %s is now running", workshop.ID)
+		s.setWorkshopStatus(workshop.ID, "running")
+		s.recordEvent(ctx, workshop.ID, eventWorkshopRunning, 0, fmt.Sprintf("Workshop running after %dms", provisioningDurationMs))
+		s.logger.InfoContext(ctx, "workshop is now running")
+		s.notifyWorkshop(ctx, workshop.ID, notify.KindWorkspaceReady)
 	}()
 }
 
@@ -356,6 +537,220 @@ func (s *Server) getWorkshop(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]interface{}{"workshop": resp})
 }
 
+func (s *Server) updateWorkshop(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	// Omitted fields are left unchanged
+	var req struct {
+		Name        *string `json:"name"`
+		Seats       *int    `json:"seats"`
+		ApiKey      *string `json:"api_key"`
+		RuntimeType *string `json:"runtime_type"`
+		Waitlist    *bool   `json:"waitlist"`
+		StartsAt    *string `json:"starts_at"` // RFC 3339; "" clears it
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	workshop, err := s.store.GetWorkshop(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if workshop == nil {
+		http.Error(w, "Workshop not found", http.StatusNotFound)
+		return
+	}
+	if workshop.Status == "deleting" || workshop.Status == "deleted" {
+		http.Error(w, "Workshop has been deleted", http.StatusConflict)
+		return
+	}
+
+	// Validate everything before changing anything
+	if req.Name != nil && *req.Name == "" {
+		http.Error(w, "Name cannot be empty", http.StatusBadRequest)
+		return
+	}
+	if req.Seats != nil && *req.Seats < 1 {
+		http.Error(w, "Seats must be at least 1", http.StatusBadRequest)
+		return
+	}
+	if req.RuntimeType != nil && *req.RuntimeType != workshop.RuntimeType {
+		if *req.RuntimeType != "docker" && *req.RuntimeType != "firecracker" {
+			http.Error(w, "runtime_type must be docker or firecracker", http.StatusBadRequest)
+			return
+		}
+		if workshop.Status == "running" || workshop.Status == "provisioning" || workshop.Status == "stopping" {
+			http.Error(w, "Runtime can only be changed while the workshop is not running", http.StatusConflict)
+			return
+		}
+	}
+
+	var startsAt *time.Time
+	if req.StartsAt != nil && *req.StartsAt != "" {
+		t, err := time.Parse(time.RFC3339, *req.StartsAt)
+		if err != nil {
+			http.Error(w, "starts_at must be an RFC 3339 time", http.StatusBadRequest)
+			return
+		}
+		startsAt = &t
+	}
+
+	ctx := logging.WithWorkshop(r.Context(), id)
+	grown := false
+	if req.Seats != nil && *req.Seats != workshop.Seats {
+		grown = *req.Seats > workshop.Seats
+		if status, err := s.resizeWorkshop(ctx, workshop, *req.Seats); err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+	}
+
+	if req.Name != nil {
+		workshop.Name = *req.Name
+	}
+	if req.ApiKey != nil {
+		workshop.ApiKey = *req.ApiKey
+	}
+	if req.RuntimeType != nil {
+		workshop.RuntimeType = *req.RuntimeType
+	}
+	if req.Waitlist != nil {
+		workshop.Waitlist = *req.Waitlist
+	}
+	if req.StartsAt != nil {
+		workshop.StartsAt = startsAt
+	}
+	if err := s.store.UpdateWorkshop(workshop); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if grown {
+		s.promoteWaitlist(ctx, id)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"workshop": workshop})
+}
+
+// resizeWorkshop changes the workshop's seat count. On a running workshop new
+// seats get workspaces from the provisioner before their sessions are added,
+// and removed seats are released only after their sessions are deleted. On
+// error the int is the HTTP status to respond with.
+func (s *Server) resizeWorkshop(ctx context.Context, workshop *store.Workshop, seats int) (int, error) {
+	if workshop.Status == "provisioning" || workshop.Status == "stopping" {
+		return http.StatusConflict, fmt.Errorf("Workshop is %s; change seats once it has settled", workshop.Status)
+	}
+
+	running := workshop.Status == "running"
+	var scaler provisioner.SeatScaler
+	var vmIP string
+	if running {
+		var ok bool
+		scaler, ok = s.getProvisioner(workshop.RuntimeType).(provisioner.SeatScaler)
+		if !ok {
+			return http.StatusConflict, fmt.Errorf("The %s runtime cannot change seats while running; stop the workshop first", workshop.RuntimeType)
+		}
+		vm, err := s.store.GetVM(workshop.ID)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		if vm == nil {
+			return http.StatusConflict, errors.New("Workshop VM not found")
+		}
+		vmIP = vm.ExternalIP
+	}
+
+	// Seat changes must finish even if the client goes away
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Minute)
+	defer cancel()
+
+	var changed []int
+	var added []*store.Session
+	if seats > workshop.Seats {
+		for seatID := workshop.Seats + 1; seatID <= seats; seatID++ {
+			changed = append(changed, seatID)
+			added = append(added, &store.Session{
+				OdeHash:    generateID(5),
+				WorkshopID: workshop.ID,
+				SeatID:     seatID,
+				Status:     "pending",
+				JoinedAt:   time.Now(),
+			})
+		}
+
+		if running {
+			cfg := provisioner.DefaultConfig(workshop.ID, seats)
+			cfg.RuntimeType = workshop.RuntimeType
+			cfg.Events = s.eventRecorder(ctx, workshop.ID)
+			if err := scaler.AddSeats(ctx, cfg, changed); err != nil {
+				s.logger.ErrorContext(ctx, "failed to add seats", "seats", changed, "error", err)
+				if err := scaler.RemoveSeats(ctx, workshop.ID, changed); err != nil {
+					s.logger.WarnContext(ctx, "failed to clean up partially added seats", "error", err)
+				}
+				return http.StatusBadGateway, fmt.Errorf("Failed to add seats: %v", err)
+			}
+			for _, sess := range added {
+				sess.Status = "ready"
+				sess.IP = vmIP
+			}
+		}
+
+		if err := s.resizeWorkshopSeats(workshop.ID, seats, added); err != nil {
+			if running {
+				scaler.RemoveSeats(ctx, workshop.ID, changed)
+			}
+			return http.StatusInternalServerError, err
+		}
+		for _, sess := range added {
+			s.publishSeat(sess)
+		}
+	} else {
+		for seatID := seats + 1; seatID <= workshop.Seats; seatID++ {
+			changed = append(changed, seatID)
+		}
+
+		err := s.resizeWorkshopSeats(workshop.ID, seats, nil)
+		if errors.Is(err, store.ErrSeatsOccupied) {
+			return http.StatusConflict, errors.New("Cannot remove seats that are occupied")
+		}
+		if errors.Is(err, store.ErrTooManyRegistrations) {
+			return http.StatusConflict, errors.New("More learners are registered than the new seat count; revoke some registrations first")
+		}
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+
+		// The sessions are gone, so a failure here only leaks idle workspaces
+		if running {
+			if err := scaler.RemoveSeats(ctx, workshop.ID, changed); err != nil {
+				s.logger.WarnContext(ctx, "failed to remove seat workspaces", "seats", changed, "error", err)
+			}
+		}
+		for _, seatID := range changed {
+			s.publishSeat(&store.Session{WorkshopID: workshop.ID, SeatID: seatID, Status: "removed"})
+		}
+	}
+
+	s.logger.InfoContext(ctx, "workshop seats changed", "from", workshop.Seats, "to", seats)
+	s.recordEvent(ctx, workshop.ID, eventSeatsResized, 0, fmt.Sprintf("Seats changed from %d to %d", workshop.Seats, seats))
+	workshop.Seats = seats
+	return http.StatusOK, nil
+}
+
+// resizeWorkshopSeats changes the stored seats while no learner can register
+// or take a seat. The locks are taken in the order revokeRegistration takes
+// them.
+func (s *Server) resizeWorkshopSeats(workshopID string, seats int, added []*store.Session) error {
+	s.seatMu.Lock()
+	defer s.seatMu.Unlock()
+	s.waitlistMu.Lock()
+	defer s.waitlistMu.Unlock()
+	return s.store.ResizeWorkshopSeats(workshopID, seats, added)
+}
+
 func (s *Server) deleteWorkshop(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
@@ -370,34 +765,42 @@ func (s *Server) deleteWorkshop(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	runtimeType := workshop.RuntimeType
+	// A stopped workshop's recordings were collected when it stopped
+	hasVM := workshop.Status != "created" && workshop.Status != "stopped"
 
 	// Set status to "deleting" immediately for UI feedback
-	if err := s.store.UpdateWorkshopStatus(id, "deleting"); err != nil {
+	if err := s.setWorkshopStatus(id, "deleting"); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	// Delete the VM asynchronously, then set status to "deleted"
+	ctx := context.WithoutCancel(logging.WithWorkshop(r.Context(), id))
 	go func() {
-		log.Printf("Deleting VM for workshop %s (runtime: %s, async)", id, runtimeType)
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-		defer cancel()
-
+		s.logger.InfoContext(ctx, "deleting workshop VM", "runtime", runtimeType)
 		prov := s.getProvisioner(runtimeType)
+		if hasVM {
+			s.collectRecordings(ctx, prov, id)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+		defer cancel()
 		if err := prov.DeleteVM(ctx, id); err != nil {
-			log.Printf("Failed to delete VM for workshop %s: %v", id, err)
+			s.logger.ErrorContext(ctx, "failed to delete VM", "error", err)
+			s.recordEvent(ctx, id, eventError, 0, fmt.Sprintf("Failed to delete VM: %v", err))
 		} else {
-			log.Printf("VM deleted successfully for workshop %s", id)
+			s.logger.InfoContext(ctx, "VM deleted")
+			s.recordEvent(ctx, id, eventVMDeleted, 0, "VM deleted")
 		}
 
 		// Mark VM as removed in database
 		if err := s.store.MarkVMRemoved(id); err != nil {
-			log.Printf("Failed to mark VM as removed: %v", err)
+			s.logger.ErrorContext(ctx, "failed to mark VM as removed", "error", err)
 		}
 
 		// Update status to "deleted" after VM cleanup
-		if err := s.store.UpdateWorkshopStatus(id, "deleted"); err != nil {
-			log.Printf("Failed to update workshop status to deleted: %v", err)
+		if err := s.setWorkshopStatus(id, "deleted"); err != nil {
+			s.logger.ErrorContext(ctx, "failed to update workshop status", "status", "deleted", "error", err)
 		}
 	}()
 
@@ -419,7 +822,7 @@ func (s *Server) stopWorkshop(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Update status to "stopping" immediately for UI feedback
-	if err := s.store.UpdateWorkshopStatus(id, "stopping"); err != nil {
+	if err := s.setWorkshopStatus(id, "stopping"); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -427,26 +830,33 @@ func (s *Server) stopWorkshop(w http.ResponseWriter, r *http.Request) {
 	runtimeType := workshop.RuntimeType
 
 	// Delete the VM asynchronously, then set status to "stopped"
+	ctx := context.WithoutCancel(logging.WithWorkshop(r.Context(), id))
 	go func() {
-		log.Printf("Stopping workshop %s - deleting VM (runtime: %s, async)", id, runtimeType)
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-		defer cancel()
-
+		s.logger.InfoContext(ctx, "stopping workshop, deleting VM", "runtime", runtimeType)
 		prov := s.getProvisioner(runtimeType)
+
+		// Recordings live on the VM, so collect them before deleting it.
+		// Collection has its own deadline; deleting gets a full one after it.
+		s.collectRecordings(ctx, prov, id)
+
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+		defer cancel()
 		if err := prov.DeleteVM(ctx, id); err != nil {
-			log.Printf("Failed to delete VM for workshop %s: %v", id, err)
+			s.logger.ErrorContext(ctx, "failed to delete VM", "error", err)
+			s.recordEvent(ctx, id, eventError, 0, fmt.Sprintf("Failed to delete VM: %v", err))
 		} else {
-			log.Printf("VM deleted successfully for workshop %s", id)
+			s.logger.InfoContext(ctx, "VM deleted")
+			s.recordEvent(ctx, id, eventVMDeleted, 0, "VM deleted")
 		}
 
 		// Mark VM as removed in database
 		if err := s.store.MarkVMRemoved(id); err != nil {
-			log.Printf("Failed to mark VM as removed: %v", err)
+			s.logger.ErrorContext(ctx, "failed to mark VM as removed", "error", err)
 		}
 
 		// Update status to "stopped" after VM cleanup
-		if err := s.store.UpdateWorkshopStatus(id, "stopped"); err != nil {
-			log.Printf("Failed to update workshop status to stopped: %v", err)
+		if err := s.setWorkshopStatus(id, "stopped"); err != nil {
+			s.logger.ErrorContext(ctx, "failed to update workshop status", "status", "stopped", "error", err)
 		}
 	}()
 
@@ -468,18 +878,21 @@ func (s *Server) startWorkshop(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Update status to provisioning
-	if err := s.store.UpdateWorkshopStatus(id, "provisioning"); err != nil {
+	if err := s.setWorkshopStatus(id, "provisioning"); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	log.Printf("Provisioning GCP VM for workshop %s (%s) with %d seats", workshop.Name, id, workshop.Seats)
+	ctx := logging.WithWorkshop(r.Context(), id)
+	s.logger.InfoContext(ctx, "provisioning workshop VM", "name", workshop.Name, "seats", workshop.Seats)
+	s.recordEvent(ctx, id, eventProvisioningStarted, 0, fmt.Sprintf("Provisioning %d seats (%s runtime)", workshop.Seats, workshop.RuntimeType))
 
 	// Generate SSH key pair for debugging access
 	keyPair, err := sshutil.GenerateKeyPair(fmt.Sprintf("clarateach-%s", id))
 	if err != nil {
-		log.Printf("Failed to generate SSH key: %v", err)
-		s.store.UpdateWorkshopStatus(id, "error")
+		s.logger.ErrorContext(ctx, "failed to generate SSH key", "error", err)
+		s.recordEvent(ctx, id, eventError, 0, fmt.Sprintf("Failed to generate SSH key: %v", err))
+		s.setWorkshopStatus(id, "error")
 		http.Error(w, fmt.Sprintf("Failed to generate SSH key: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -489,25 +902,28 @@ func (s *Server) startWorkshop(w http.ResponseWriter, r *http.Request) {
 	vmConfig.Spot = s.useSpotVMs
 	vmConfig.SSHPublicKey = keyPair.PublicKey
 	vmConfig.RuntimeType = workshop.RuntimeType
+	vmConfig.Events = s.eventRecorder(ctx, id)
 
 	// Track provisioning time
 	provisioningStartedAt := time.Now()
 
 	// Provision VM using runtime-specific provisioner
-	ctx := r.Context()
 	prov := s.getProvisioner(workshop.RuntimeType)
 	vmInstance, err := prov.CreateVM(ctx, vmConfig)
 	if err != nil {
-		log.Printf("Failed to provision VM: %v", err)
-		s.store.UpdateWorkshopStatus(id, "error")
+		s.logger.ErrorContext(ctx, "failed to provision VM", "error", err)
+		s.metrics.ProvisioningFailed(provisioner.Name(prov), err)
+		s.recordEvent(ctx, id, eventError, 0, err.Error())
+		s.setWorkshopStatus(id, "error")
 		http.Error(w, fmt.Sprintf("Failed to provision VM: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	provisioningCompletedAt := time.Now()
 	provisioningDurationMs := provisioningCompletedAt.Sub(provisioningStartedAt).Milliseconds()
+	s.metrics.ObserveProvisioning(provisioner.Name(prov), workshop.RuntimeType, provisioningCompletedAt.Sub(provisioningStartedAt))
 
-	log.Printf("VM created: %s (IP: %s) in %dms", vmInstance.Name, vmInstance.ExternalIP, provisioningDurationMs)
+	s.logger.InfoContext(ctx, "VM created", "vm_name", vmInstance.Name, "external_ip", vmInstance.ExternalIP, "duration_ms", provisioningDurationMs)
 
 	// Store VM info in database
 	workshopVM := &store.WorkshopVM{
@@ -531,11 +947,13 @@ func (s *Server) startWorkshop(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := s.store.CreateVM(workshopVM); err != nil {
-		log.Printf("Failed to save VM info: %v", err)
+		s.logger.ErrorContext(ctx, "failed to save VM info", "error", err)
 	}
 
 	// Update workshop status
-	s.store.UpdateWorkshopStatus(id, "running")
+	s.setWorkshopStatus(id, "running")
+	s.recordEvent(ctx, id, eventWorkshopRunning, 0, fmt.Sprintf("Workshop running after %dms", provisioningDurationMs))
+	s.notifyWorkshop(ctx, id, notify.KindWorkspaceReady)
 
 	// Return success with VM info
 	w.Header().Set("Content-Type", "application/json")
@@ -550,6 +968,91 @@ func (s *Server) startWorkshop(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// Timeline events recorded by the server. Provisioners add their own through
+// VMConfig.Events (see the provisioner.Event* constants).
+const (
+	eventProvisioningStarted = "provisioning_started"
+	eventTunnelRegistered    = "tunnel_registered"
+	eventWorkshopRunning     = "workshop_running"
+	eventVMDeleted           = "vm_deleted"
+	eventSeatsResized        = "seats_resized"
+	eventRecordingsCollected = "recordings_collected"
+	eventSeatReset           = "seat_reset"
+	eventError               = "error"
+)
+
+// recordEvent appends an event to the workshop's timeline. Failures are logged
+// but never interrupt provisioning.
+func (s *Server) recordEvent(ctx context.Context, workshopID, eventType string, seatID int, message string) {
+	event := &store.WorkshopEvent{
+		WorkshopID: workshopID,
+		Type:       eventType,
+		Message:    message,
+		CreatedAt:  time.Now(),
+	}
+	if seatID > 0 {
+		event.SeatID = &seatID
+	}
+	if err := s.store.CreateWorkshopEvent(event); err != nil {
+		s.logger.WarnContext(ctx, "failed to record workshop event", "event", eventType, "error", err)
+	}
+	s.events.Publish(events.Event{
+		Type:       events.TypeTimeline,
+		WorkshopID: workshopID,
+		SeatID:     seatID,
+		Data:       map[string]interface{}{"event": eventType, "message": message},
+		Time:       event.CreatedAt,
+	})
+}
+
+// eventRecorder returns a provisioner.EventRecorder that writes to the workshop's timeline.
+func (s *Server) eventRecorder(ctx context.Context, workshopID string) provisioner.EventRecorder {
+	return func(eventType string, seatID int, message string) {
+		s.recordEvent(ctx, workshopID, eventType, seatID, message)
+	}
+}
+
+func (s *Server) getWorkshopTimeline(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	workshop, err := s.store.GetWorkshop(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if workshop == nil {
+		http.Error(w, "Workshop not found", http.StatusNotFound)
+		return
+	}
+
+	events, err := s.store.ListWorkshopEvents(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Elapsed time is measured from the first event so slow steps stand out
+	timeline := make([]map[string]interface{}, 0, len(events))
+	for _, e := range events {
+		entry := map[string]interface{}{
+			"type":       e.Type,
+			"message":    e.Message,
+			"created_at": e.CreatedAt,
+			"elapsed_ms": e.CreatedAt.Sub(events[0].CreatedAt).Milliseconds(),
+		}
+		if e.SeatID != nil {
+			entry["seat_id"] = *e.SeatID
+		}
+		timeline = append(timeline, entry)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"workshop_id": workshop.ID,
+		"status":      workshop.Status,
+		"events":      timeline,
+	})
+}
+
 func (s *Server) joinWorkshop(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Code    string `json:"code"`
@@ -583,15 +1086,23 @@ func (s *Server) joinWorkshop(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if session == nil {
+		s.seatMu.Lock()
+		defer s.seatMu.Unlock()
+
 		// Allocate Seat - find first available session
 		existing, err := s.store.ListSessions(workshop.ID)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		reserved, err := s.reservedSeats(workshop.ID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 
 		for _, sess := range existing {
-			if sess.Name == "" && (sess.Status == "ready" || sess.Status == "provisioning") {
+			if sess.Name == "" && (sess.Status == "ready" || sess.Status == "provisioning") && !reserved[sess.SeatID] {
 				session = sess
 				break
 			}
@@ -614,6 +1125,15 @@ func (s *Server) joinWorkshop(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+
+		// Reconnects with an odehash are not new joins
+		s.events.Publish(events.Event{
+			Type:       events.TypeJoin,
+			WorkshopID: workshop.ID,
+			SeatID:     session.SeatID,
+			Data:       map[string]interface{}{"name": session.Name},
+		})
+		s.emitWebhook(r.Context(), workshop.ID, webhook.EventLearnerJoined, map[string]interface{}{"name": session.Name, "seat_id": session.SeatID})
 	}
 
 	// 3. Get VM's external IP
@@ -631,8 +1151,9 @@ func (s *Server) joinWorkshop(w http.ResponseWriter, r *http.Request) {
 	session.IP = containerIP
 	session.ContainerID = fmt.Sprintf("seat-%d", session.SeatID)
 	if err := s.store.UpdateSession(session); err != nil {
-		log.Printf("Warning: failed to update session: %v", err)
+		s.logger.WarnContext(logging.WithSeat(r.Context(), workshop.ID, session.SeatID), "failed to update session", "error", err)
 	}
+	s.publishSeat(session)
 
 	// 4. Construct endpoint URL
 	endpoint := fmt.Sprintf("http://%s:8080", containerIP)
@@ -706,60 +1227,105 @@ func (s *Server) registerForWorkshop(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	registration, created, status, err := s.registerLearner(r.Context(), workshop, req.Email, req.Name)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	resp := map[string]interface{}{
+		"access_code":        registration.AccessCode,
+		"already_registered": !created,
+		"message":            "Registration successful",
+	}
+	switch {
+	case !created && registration.Status == "waitlisted":
+		resp["waitlisted"] = true
+		resp["waitlist_position"] = registration.WaitlistPosition
+		resp["message"] = "You are already on the waitlist for this workshop"
+	case !created:
+		resp["message"] = "You are already registered for this workshop"
+	case registration.Status == "waitlisted":
+		resp["waitlisted"] = true
+		resp["waitlist_position"] = registration.WaitlistPosition
+		resp["message"] = "The workshop is full; you are on the waitlist"
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// registerLearner registers email for the workshop, or puts it on the
+// waitlist when the workshop is full and has one. An existing registration
+// is returned as is with created false. On error it also returns the HTTP
+// status to answer with.
+func (s *Server) registerLearner(ctx context.Context, workshop *store.Workshop, email, name string) (registration *store.Registration, created bool, status int, err error) {
 	// Check workshop status
 	if workshop.Status == "ended" || workshop.Status == "deleted" {
-		http.Error(w, "Workshop has ended", http.StatusGone)
-		return
+		return nil, false, http.StatusGone, errors.New("Workshop has ended")
 	}
 
 	// Check if email already registered for this workshop
-	existing, err := s.store.GetRegistrationByEmail(workshop.ID, req.Email)
+	existing, err := s.store.GetRegistrationByEmail(workshop.ID, email)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return nil, false, http.StatusInternalServerError, err
+	}
+	if existing != nil && existing.Status == "revoked" {
+		return nil, false, http.StatusForbidden, errors.New("Registration has been revoked")
 	}
 	if existing != nil {
-		// Return existing registration
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"access_code":        existing.AccessCode,
-			"already_registered": true,
-			"message":            "You are already registered for this workshop",
-		})
-		return
+		return existing, false, http.StatusOK, nil
 	}
 
-	// Check if workshop is full
+	// The count must not change between checking it and registering
+	s.waitlistMu.Lock()
+	defer s.waitlistMu.Unlock()
+
+	// Check if workshop is full; with a waitlist, register the learner on it
 	registrationCount, err := s.store.CountRegistrations(workshop.ID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return nil, false, http.StatusInternalServerError, err
 	}
-	if registrationCount >= workshop.Seats {
-		http.Error(w, "Workshop is full", http.StatusConflict)
-		return
+	full := registrationCount >= workshop.Seats
+	if full && !workshop.Waitlist {
+		return nil, false, http.StatusConflict, errors.New("Workshop is full")
 	}
 
 	// Create registration
-	registration := &store.Registration{
+	registration = &store.Registration{
 		ID:         "reg-" + generateID(8),
 		AccessCode: generateAccessCode(),
-		Email:      req.Email,
-		Name:       req.Name,
+		Email:      email,
+		Name:       name,
 		WorkshopID: workshop.ID,
 		Status:     "registered",
 		CreatedAt:  time.Now(),
 	}
+	if full {
+		waitlist, err := s.waitlist(workshop.ID)
+		if err != nil {
+			return nil, false, http.StatusInternalServerError, err
+		}
+		registration.Status = "waitlisted"
+		registration.WaitlistPosition = 1
+		if len(waitlist) > 0 {
+			registration.WaitlistPosition = waitlist[len(waitlist)-1].WaitlistPosition + 1
+		}
+	}
 
 	if err := s.store.CreateRegistration(registration); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return nil, false, http.StatusInternalServerError, err
 	}
-
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"access_code":        registration.AccessCode,
-		"already_registered": false,
-		"message":            "Registration successful",
+	if full {
+		s.publishWaitlist(registration)
+		s.notifyLearner(ctx, workshop, registration, notify.KindWaitlisted)
+		return registration, true, http.StatusOK, nil
+	}
+	s.events.Publish(events.Event{
+		Type:       events.TypeRegistration,
+		WorkshopID: workshop.ID,
+		Data:       map[string]interface{}{"name": registration.Name, "email": registration.Email},
 	})
+	s.notifyLearner(ctx, workshop, registration, notify.KindRegistration)
+	s.emitRegistrationWebhook(ctx, registration)
+	return registration, true, http.StatusOK, nil
 }
 
 func (s *Server) getSessionByCode(w http.ResponseWriter, r *http.Request) {
@@ -769,70 +1335,130 @@ func (s *Server) getSessionByCode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	state, status, err := s.resolveSession(r.Context(), code)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	json.NewEncoder(w).Encode(state)
+}
+
+// sessionState is a learner's view of their workshop seat, returned by
+// GET /api/session/{code} and pushed by its stream.
+type sessionState struct {
+	Status        string `json:"status"` // waitlisted, pending, seat_assigned (stream only) or ready
+	Message       string `json:"message,omitempty"`
+	Endpoint      string `json:"endpoint,omitempty"`
+	Token         string `json:"token,omitempty"` // JWT for workspace WebSocket authentication
+	Seat          int    `json:"seat,omitempty"`
+	Name          string `json:"name,omitempty"`
+	WorkshopID    string `json:"workshop_id"`
+	RuntimeType   string `json:"runtime_type,omitempty"`
+	QueuePosition int    `json:"queue_position,omitempty"` // Pending learners without a seat, 1-based
+	SeatsReady    int    `json:"seats_ready,omitempty"`    // Seats created so far while pending
+	SeatsTotal    int    `json:"seats_total,omitempty"`
+	// Place on the waitlist, 1-based, while waitlisted
+	WaitlistPosition int `json:"waitlist_position,omitempty"`
+	// Set by POST /api/session/login so the learner can keep using the session
+	AccessCode string `json:"access_code,omitempty"`
+}
+
+// resolveSession looks up the registration for an access code, assigns it a
+// seat once the workshop VM is up and returns its state. On error the int is
+// the HTTP status to respond with.
+func (s *Server) resolveSession(ctx context.Context, code string) (*sessionState, int, error) {
 	// Find registration by access code
 	registration, err := s.store.GetRegistration(code)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return nil, http.StatusInternalServerError, err
 	}
 	if registration == nil {
-		http.Error(w, "Invalid access code", http.StatusNotFound)
-		return
+		return nil, http.StatusNotFound, errors.New("Invalid access code")
+	}
+	if registration.Status == "revoked" {
+		return nil, http.StatusForbidden, errors.New("Registration has been revoked")
+	}
+	if registration.Status == "waitlisted" {
+		return &sessionState{
+			Status:           "waitlisted",
+			Message:          "The workshop is full. You will get a seat when one frees up.",
+			WorkshopID:       registration.WorkshopID,
+			WaitlistPosition: registration.WaitlistPosition,
+		}, http.StatusOK, nil
 	}
 
 	// Get workshop
 	workshop, err := s.store.GetWorkshop(registration.WorkshopID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return nil, http.StatusInternalServerError, err
 	}
 	if workshop == nil {
-		http.Error(w, "Workshop not found", http.StatusNotFound)
-		return
+		return nil, http.StatusNotFound, errors.New("Workshop not found")
 	}
 
 	// Check workshop status
 	if workshop.Status == "ended" || workshop.Status == "deleted" {
-		http.Error(w, "Workshop has ended", http.StatusGone)
-		return
+		return nil, http.StatusGone, errors.New("Workshop has ended")
 	}
 
 	// Get VM info
 	vm, err := s.store.GetVM(workshop.ID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return nil, http.StatusInternalServerError, err
 	}
 	if vm == nil || vm.ExternalIP == "" {
 		// Workshop not started yet
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"status":      "pending",
-			"message":     "Workshop is starting. Please wait...",
-			"workshop_id": workshop.ID,
-		})
-		return
+		state := &sessionState{
+			Status:     "pending",
+			Message:    "Workshop is starting. Please wait...",
+			WorkshopID: workshop.ID,
+			SeatsTotal: workshop.Seats,
+		}
+		if err := s.fillQueueInfo(state, registration); err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		return state, http.StatusOK, nil
 	}
 
-	// If user doesn't have a seat yet, assign one
-	if registration.SeatID == nil {
+	// If user hasn't taken a seat yet, assign one. Learners imported with a
+	// fixed seat have SeatID set but no JoinedAt until they first arrive.
+	if registration.SeatID == nil || registration.JoinedAt == nil {
+		// Streams for every pending learner wake up together when the
+		// workshop starts, so seat allocation must be serialized
+		s.seatMu.Lock()
+		defer s.seatMu.Unlock()
+
 		// Find available seat
 		sessions, err := s.store.ListSessions(workshop.ID)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+			return nil, http.StatusInternalServerError, err
+		}
+		reserved, err := s.reservedSeats(workshop.ID)
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
 		}
 
 		var availableSeat *store.Session
 		for _, sess := range sessions {
-			if sess.Name == "" && (sess.Status == "ready" || sess.Status == "provisioning") {
+			if sess.Name != "" || (sess.Status != "ready" && sess.Status != "provisioning") {
+				continue
+			}
+			if registration.SeatID != nil {
+				if sess.SeatID == *registration.SeatID {
+					availableSeat = sess
+					break
+				}
+			} else if !reserved[sess.SeatID] {
 				availableSeat = sess
 				break
 			}
 		}
 
 		if availableSeat == nil {
-			http.Error(w, "No seats available", http.StatusConflict)
-			return
+			if registration.SeatID != nil {
+				return nil, http.StatusConflict, fmt.Errorf("Seat %d is not available", *registration.SeatID)
+			}
+			return nil, http.StatusConflict, errors.New("No seats available")
 		}
 
 		// Assign seat to registration
@@ -843,8 +1469,7 @@ func (s *Server) getSessionByCode(w http.ResponseWriter, r *http.Request) {
 		registration.JoinedAt = &now
 
 		if err := s.store.UpdateRegistration(registration); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+			return nil, http.StatusInternalServerError, err
 		}
 
 		// Update session with user info
@@ -853,42 +1478,102 @@ func (s *Server) getSessionByCode(w http.ResponseWriter, r *http.Request) {
 		availableSeat.IP = vm.ExternalIP
 		availableSeat.ContainerID = fmt.Sprintf("seat-%d", seatID)
 		if err := s.store.UpdateSession(availableSeat); err != nil {
-			log.Printf("Warning: failed to update session: %v", err)
+			s.logger.WarnContext(logging.WithSeat(ctx, workshop.ID, seatID), "failed to update session", "error", err)
 		}
+		s.publishSeat(availableSeat)
+		s.events.Publish(events.Event{
+			Type:       events.TypeJoin,
+			WorkshopID: workshop.ID,
+			SeatID:     seatID,
+			Data:       map[string]interface{}{"name": registration.Name},
+		})
+		s.emitWebhook(ctx, workshop.ID, webhook.EventLearnerJoined, map[string]interface{}{"name": registration.Name, "seat_id": seatID})
 	}
 
-	// Build endpoint URL based on runtime type and tunnel availability
-	var endpoint string
+	endpoint := workspaceEndpoint(workshop, vm)
+
+	// Generate workspace token for WebSocket authentication. Tying it to the
+	// registration lets workers reject it if the registration is revoked.
+	token, err := auth.GenerateLearnerToken(workshop.ID, *registration.SeatID, registration.ID)
+	if err != nil {
+		s.logger.ErrorContext(logging.WithSeat(ctx, workshop.ID, *registration.SeatID), "failed to generate workspace token", "error", err)
+		return nil, http.StatusInternalServerError, errors.New("Failed to generate access token")
+	}
+
+	return &sessionState{
+		Status:      "ready",
+		Endpoint:    endpoint,
+		Token:       token,
+		Seat:        *registration.SeatID,
+		Name:        registration.Name,
+		WorkshopID:  workshop.ID,
+		RuntimeType: workshop.RuntimeType,
+	}, http.StatusOK, nil
+}
+
+// workspaceEndpoint is the base URL learners' browsers use to reach the
+// workshop VM, based on runtime type and tunnel availability.
+func workspaceEndpoint(workshop *store.Workshop, vm *store.WorkshopVM) string {
 	if workshop.RuntimeType == "firecracker" {
 		// Prefer tunnel URL if available (HTTPS via Cloudflare)
 		if vm.TunnelURL != "" {
-			endpoint = vm.TunnelURL
-		} else {
-			// Fallback to direct IP (HTTP) - only for development
-			endpoint = fmt.Sprintf("http://%s:9090", vm.ExternalIP)
+			return vm.TunnelURL
 		}
-	} else {
-		// Docker workspace server runs on port 8080
-		endpoint = fmt.Sprintf("http://%s:8080", vm.ExternalIP)
+		// Fallback to direct IP (HTTP) - only for development
+		return fmt.Sprintf("http://%s:9090", vm.ExternalIP)
 	}
+	// Docker workspace server runs on port 8080
+	return fmt.Sprintf("http://%s:8080", vm.ExternalIP)
+}
 
-	// Generate workspace token for WebSocket authentication
-	token, err := auth.GenerateWorkspaceToken(workshop.ID, *registration.SeatID)
+// reservedSeats returns the seats fixed for imported learners who have not
+// joined yet, which are kept free for them.
+func (s *Server) reservedSeats(workshopID string) (map[int]bool, error) {
+	registrations, err := s.store.ListRegistrations(workshopID)
 	if err != nil {
-		log.Printf("Failed to generate workspace token: %v", err)
-		http.Error(w, "Failed to generate access token", http.StatusInternalServerError)
-		return
+		return nil, err
+	}
+	reserved := make(map[int]bool)
+	for _, reg := range registrations {
+		if reg.SeatID != nil && reg.JoinedAt == nil {
+			reserved[*reg.SeatID] = true
+		}
 	}
+	return reserved, nil
+}
 
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status":       "ready",
-		"endpoint":     endpoint,
-		"token":        token,
-		"seat":         *registration.SeatID,
-		"name":         registration.Name,
-		"workshop_id":  workshop.ID,
-		"runtime_type": workshop.RuntimeType,
-	})
+// fillQueueInfo sets the learner's place among registrations still waiting for
+// a seat and how many seats the current provisioning run has created.
+func (s *Server) fillQueueInfo(state *sessionState, registration *store.Registration) error {
+	if registration.SeatID == nil {
+		registrations, err := s.store.ListRegistrations(registration.WorkshopID)
+		if err != nil {
+			return err
+		}
+		for _, reg := range registrations {
+			if reg.SeatID != nil || reg.Status == "revoked" || reg.Status == "waitlisted" {
+				continue
+			}
+			state.QueuePosition++
+			if reg.ID == registration.ID {
+				break
+			}
+		}
+	}
+
+	timeline, err := s.store.ListWorkshopEvents(registration.WorkshopID)
+	if err != nil {
+		return err
+	}
+	for _, e := range timeline {
+		switch e.Type {
+		case eventProvisioningStarted:
+			state.SeatsReady = 0
+		case provisioner.EventSeatCreated:
+			state.SeatsReady++
+		}
+	}
+	return nil
 }
 
 // ================== Internal API Handlers ==================
@@ -926,7 +1611,9 @@ func (s *Server) registerTunnel(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Printf("Tunnel URL registered for workshop %s: %s", workshopID, req.TunnelURL)
+	ctx := logging.WithWorkshop(r.Context(), workshopID)
+	s.logger.InfoContext(ctx, "tunnel URL registered", "tunnel_url", req.TunnelURL)
+	s.recordEvent(ctx, workshopID, eventTunnelRegistered, 0, req.TunnelURL)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -1151,8 +1838,8 @@ func (s *Server) authRegister(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Generate token
-	token, err := auth.GenerateToken(user)
+	// Generate tokens
+	tokens, err := s.issueTokens(user, "")
 	if err != nil {
 		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
 		return
@@ -1160,8 +1847,10 @@ func (s *Server) authRegister(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"token": token,
-		"user":  user,
+		"token":         tokens.Token,
+		"refresh_token": tokens.RefreshToken,
+		"expires_at":    tokens.ExpiresAt,
+		"user":          user,
 	})
 }
 
@@ -1191,9 +1880,13 @@ func (s *Server) authLogin(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
+	if user.Disabled {
+		http.Error(w, "Account disabled", http.StatusForbidden)
+		return
+	}
 
-	// Generate token
-	token, err := auth.GenerateToken(user)
+	// Generate tokens
+	tokens, err := s.issueTokens(user, "")
 	if err != nil {
 		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
 		return
@@ -1201,18 +1894,13 @@ func (s *Server) authLogin(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"token": token,
-		"user":  user,
+		"token":         tokens.Token,
+		"refresh_token": tokens.RefreshToken,
+		"expires_at":    tokens.ExpiresAt,
+		"user":          user,
 	})
 }
 
-func (s *Server) authLogout(w http.ResponseWriter, r *http.Request) {
-	// JWT is stateless, so we just return success
-	// Client should delete the token
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]bool{"success": true})
-}
-
 func (s *Server) authMe(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUserFromContext(r.Context())
 	if user == nil {
@@ -1236,18 +1924,18 @@ func (s *Server) seedAdminUser() {
 	// Check if admin already exists
 	existing, err := s.store.GetUserByEmail(adminEmail)
 	if err != nil {
-		log.Printf("Failed to check for existing admin: %v", err)
+		s.logger.Error("failed to check for existing admin", "error", err)
 		return
 	}
 	if existing != nil {
-		log.Printf("Admin user already exists: %s", adminEmail)
+		s.logger.Info("admin user already exists", "email", adminEmail)
 		return
 	}
 
 	// Create admin user
 	hash, err := auth.HashPassword(adminPassword)
 	if err != nil {
-		log.Printf("Failed to hash admin password: %v", err)
+		s.logger.Error("failed to hash admin password", "error", err)
 		return
 	}
 
@@ -1261,9 +1949,9 @@ func (s *Server) seedAdminUser() {
 	}
 
 	if err := s.store.CreateUser(admin); err != nil {
-		log.Printf("Failed to create admin user: %v", err)
+		s.logger.Error("failed to create admin user", "error", err)
 		return
 	}
 
-	log.Printf("Created admin user: %s", adminEmail)
+	s.logger.Info("created admin user", "email", adminEmail)
 }
@@ -1,19 +1,41 @@
 package api
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/clarateach/backend/internal/auth"
+	"github.com/clarateach/backend/internal/events"
+	"github.com/clarateach/backend/internal/lti"
+	"github.com/clarateach/backend/internal/notify"
+	"github.com/clarateach/backend/internal/oidc"
 	"github.com/clarateach/backend/internal/provisioner"
 	"github.com/clarateach/backend/internal/store"
+	"github.com/clarateach/backend/internal/webhook"
+	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 // MockProvisioner implements provisioner.Provisioner for testing
@@ -23,6 +45,12 @@ type MockProvisioner struct {
 	GetVMError     error
 	CreatedVMs     map[string]*provisioner.VMInstance
 	DeletedVMs     []string
+	AddedSeats     []int
+	RemovedSeats   []int
+	Recordings     []provisioner.RecordingInfo // Served with RecordingData by name
+	RecordingData  map[string][]byte
+	RevokedTokens  []string // "seat/registration" for each revocation
+	OnRevoke       func()   // Called by RevokeWorkspaceTokens if set
 }
 
 func NewMockProvisioner() *MockProvisioner {
@@ -45,13 +73,34 @@ func (m *MockProvisioner) CreateVM(ctx context.Context, cfg provisioner.VMConfig
 		Zone:       "us-central1-a",
 	}
 	m.CreatedVMs[cfg.WorkshopID] = vm
+	if cfg.Events != nil {
+		for seat := 1; seat <= cfg.Seats; seat++ {
+			cfg.Events(provisioner.EventSeatCreated, seat, "mock seat")
+		}
+	}
 	return vm, nil
 }
 
+func (m *MockProvisioner) AddSeats(ctx context.Context, cfg provisioner.VMConfig, seatIDs []int) error {
+	if m.CreateVMError != nil {
+		return m.CreateVMError
+	}
+	m.AddedSeats = append(m.AddedSeats, seatIDs...)
+	return nil
+}
+
+func (m *MockProvisioner) RemoveSeats(ctx context.Context, workshopID string, seatIDs []int) error {
+	m.RemovedSeats = append(m.RemovedSeats, seatIDs...)
+	return nil
+}
+
 func (m *MockProvisioner) DeleteVM(ctx context.Context, workshopID string) error {
 	if m.DeleteVMError != nil {
 		return m.DeleteVMError
 	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	m.DeletedVMs = append(m.DeletedVMs, workshopID)
 	delete(m.CreatedVMs, workshopID)
 	return nil
@@ -79,6 +128,26 @@ func (m *MockProvisioner) ListVMs(ctx context.Context, workshopID string) ([]*pr
 	return vms, nil
 }
 
+func (m *MockProvisioner) ListRecordings(ctx context.Context, workshopID string) ([]provisioner.RecordingInfo, error) {
+	return m.Recordings, nil
+}
+
+func (m *MockProvisioner) FetchRecording(ctx context.Context, workshopID, name string) ([]byte, error) {
+	data, ok := m.RecordingData[name]
+	if !ok {
+		return nil, errors.New("recording not found")
+	}
+	return data, nil
+}
+
+func (m *MockProvisioner) RevokeWorkspaceTokens(ctx context.Context, workshopID string, seatID int, registrationID string) error {
+	m.RevokedTokens = append(m.RevokedTokens, fmt.Sprintf("%d/%s", seatID, registrationID))
+	if m.OnRevoke != nil {
+		m.OnRevoke()
+	}
+	return nil
+}
+
 func setupTestServer(t *testing.T) (*Server, func()) {
 	t.Helper()
 
@@ -236,6 +305,60 @@ func TestHealthEndpoint(t *testing.T) {
 	}
 }
 
+func TestMetricsEndpoint(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	// Generate some traffic so the route histogram has a sample
+	server.router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/health", nil))
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Metrics endpoint returned %d, want %d", rr.Code, http.StatusOK)
+	}
+	body := rr.Body.String()
+	for _, want := range []string{
+		`clarateach_http_request_duration_seconds_count{method="GET",route="/health",status="200"} 1`,
+		"clarateach_active_learners 0",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Metrics output missing %q", want)
+		}
+	}
+}
+
+func TestMetricsEndpointRequiresToken(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+	server.SetMetricsToken("scrape-secret")
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Metrics without token returned %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+
+	req = httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Authorization", "scrape-secret")
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Metrics with a token but no Bearer scheme returned %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+
+	req = httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer scrape-secret")
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("Metrics with token returned %d, want %d", rr.Code, http.StatusOK)
+	}
+}
+
 func TestAuthRegister(t *testing.T) {
 	server, _, cleanup := setupTestServerWithAuth(t)
 	defer cleanup()
@@ -1103,6 +1226,7 @@ func TestWorkshopStartProvisionerFailure(t *testing.T) {
 
 	// Create auth token
 	token := createTestUserToken(t, server, "start-fail@example.com")
+	owner, _ := s.GetUserByEmail("start-fail@example.com")
 
 	// Create a workshop without starting it (manually insert)
 	workshop := &store.Workshop{
@@ -1112,6 +1236,7 @@ func TestWorkshopStartProvisionerFailure(t *testing.T) {
 		Seats:     5,
 		ApiKey:    "sk-test",
 		Status:    "created",
+		OwnerID:   owner.ID,
 		CreatedAt: time.Now(),
 	}
 	s.CreateWorkshop(workshop)
@@ -1191,6 +1316,7 @@ func TestStartWorkshopSuccess(t *testing.T) {
 
 	// Create auth token
 	token := createTestUserToken(t, server, "start-success@example.com")
+	owner, _ := s.GetUserByEmail("start-success@example.com")
 
 	// Create a workshop in "created" status
 	workshop := &store.Workshop{
@@ -1200,6 +1326,7 @@ func TestStartWorkshopSuccess(t *testing.T) {
 		Seats:     5,
 		ApiKey:    "sk-test",
 		Status:    "created",
+		OwnerID:   owner.ID,
 		CreatedAt: time.Now(),
 	}
 	s.CreateWorkshop(workshop)
@@ -1237,6 +1364,75 @@ func TestStartWorkshopSuccess(t *testing.T) {
 	}
 }
 
+func TestWorkshopTimeline(t *testing.T) {
+	server, s, mockProv, cleanup := setupTestServerWithMock(t)
+	defer cleanup()
+
+	token := createTestUserToken(t, server, "timeline@example.com")
+	owner, _ := s.GetUserByEmail("timeline@example.com")
+
+	for _, id := range []string{"ws-timeline-ok", "ws-timeline-fail"} {
+		s.CreateWorkshop(&store.Workshop{
+			ID:        id,
+			Name:      "Timeline Workshop",
+			Code:      strings.ToUpper(id),
+			Seats:     2,
+			Status:    "created",
+			OwnerID:   owner.ID,
+			CreatedAt: time.Now(),
+		})
+	}
+
+	start := func(id string) {
+		req := httptest.NewRequest("POST", "/api/workshops/"+id+"/start", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		server.router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	timeline := func(id string) []map[string]interface{} {
+		req := httptest.NewRequest("GET", "/api/workshops/"+id+"/timeline", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Timeline failed: %d - %s", rr.Code, rr.Body.String())
+		}
+		var response struct {
+			Events []map[string]interface{} `json:"events"`
+		}
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		return response.Events
+	}
+	types := func(events []map[string]interface{}) []string {
+		var out []string
+		for _, e := range events {
+			out = append(out, e["type"].(string))
+		}
+		return out
+	}
+
+	start("ws-timeline-ok")
+	got := types(timeline("ws-timeline-ok"))
+	want := []string{"provisioning_started", "seat_created", "seat_created", "workshop_running"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Timeline = %v, want %v", got, want)
+	}
+
+	mockProv.CreateVMError = errors.New("quota exceeded")
+	start("ws-timeline-fail")
+	events := timeline("ws-timeline-fail")
+	if len(events) != 2 || events[1]["type"] != "error" || events[1]["message"] != "quota exceeded" {
+		t.Errorf("Failed timeline = %v, want provisioning_started then error", events)
+	}
+
+	req := httptest.NewRequest("GET", "/api/workshops/ws-missing/timeline", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for missing workshop, got %d", rr.Code)
+	}
+}
+
 func TestStartWorkshopNotFound(t *testing.T) {
 	server, _, _, cleanup := setupTestServerWithMock(t)
 	defer cleanup()
@@ -1295,6 +1491,7 @@ func TestJoinWorkshopSuccess(t *testing.T) {
 		UpdatedAt:  time.Now(),
 	}
 	s.CreateVM(vm)
+	s.CreateWebhook(&store.Webhook{ID: "hook-join", WorkshopID: workshop.ID, URL: "https://example.com/hook", Events: []string{webhook.EventLearnerJoined}, CreatedAt: time.Now()})
 
 	// Join the workshop
 	joinBody := map[string]interface{}{
@@ -1327,6 +1524,21 @@ func TestJoinWorkshopSuccess(t *testing.T) {
 	if seat < 1 || seat > 3 {
 		t.Errorf("seat = %v, want 1-3", seat)
 	}
+
+	// Reconnecting with the odehash keeps the seat and is not another join
+	rejoinBytes, _ := json.Marshal(map[string]interface{}{"code": "JOIN-TEST", "odehash": response["odehash"]})
+	req = httptest.NewRequest("POST", "/api/join", bytes.NewReader(rejoinBytes))
+	req.Header.Set("Content-Type", "application/json")
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	var rejoined map[string]interface{}
+	json.Unmarshal(rr.Body.Bytes(), &rejoined)
+	if rr.Code != http.StatusOK || rejoined["seat"] != seat {
+		t.Fatalf("Rejoin = %d - %s, want seat %v", rr.Code, rr.Body.String(), seat)
+	}
+	if got, _ := s.ListWebhookDeliveries("hook-join", 10); len(got) != 1 {
+		t.Errorf("learner.joined deliveries = %d, want 1", len(got))
+	}
 }
 
 func TestJoinWorkshopNotFound(t *testing.T) {
@@ -1699,3 +1911,2439 @@ func TestAuthLogout(t *testing.T) {
 		t.Error("Logout should return success: true")
 	}
 }
+
+func TestTracingContinuesIncomingTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prevTP, prevProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer func() {
+		otel.SetTracerProvider(prevTP)
+		otel.SetTextMapPropagator(prevProp)
+	}()
+
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest("GET", "/health", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	server.router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	if got := spans[0].SpanContext().TraceID().String(); got != traceID {
+		t.Errorf("Expected trace ID %s, got %s", traceID, got)
+	}
+	if spans[0].Name() != "GET /health" {
+		t.Errorf("Expected span name 'GET /health', got %q", spans[0].Name())
+	}
+}
+
+func TestRequestIDHeader(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	// Incoming request IDs are reused so logs correlate across services
+	req := httptest.NewRequest("GET", "/health", nil)
+	req.Header.Set("X-Request-ID", "req-123")
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	if got := rr.Header().Get("X-Request-ID"); got != "req-123" {
+		t.Errorf("Expected X-Request-ID 'req-123', got %q", got)
+	}
+
+	// Otherwise one is generated
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, httptest.NewRequest("GET", "/health", nil))
+	if rr.Header().Get("X-Request-ID") == "" {
+		t.Error("Expected a generated X-Request-ID")
+	}
+}
+
+func TestWorkshopEventStream(t *testing.T) {
+	server, s, _, cleanup := setupTestServerWithMock(t)
+	defer cleanup()
+
+	token := createTestUserToken(t, server, "stream@example.com")
+	owner, _ := s.GetUserByEmail("stream@example.com")
+	s.CreateWorkshop(&store.Workshop{
+		ID:        "ws-stream",
+		Name:      "Stream Workshop",
+		Code:      "STRM-1234",
+		Seats:     2,
+		Status:    "created",
+		OwnerID:   owner.ID,
+		CreatedAt: time.Now(),
+	})
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	// EventSource cannot set headers, so the token is passed in the query
+	req, _ := http.NewRequest("GET", ts.URL+"/api/workshops/ws-stream/stream?access_token="+token, nil)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Stream request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+
+	events := make(chan string, 16)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if name, ok := strings.CutPrefix(scanner.Text(), "event: "); ok {
+				events <- name
+			}
+		}
+		close(events)
+	}()
+	next := func() string {
+		select {
+		case name := <-events:
+			return name
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timed out waiting for event")
+			return ""
+		}
+	}
+
+	if name := next(); name != "snapshot" {
+		t.Fatalf("First event = %q, want snapshot", name)
+	}
+
+	body, _ := json.Marshal(map[string]string{
+		"workshop_code": "STRM-1234",
+		"email":         "learner@example.com",
+		"name":          "Learner",
+	})
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, httptest.NewRequest("POST", "/api/register", bytes.NewReader(body)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Register failed: %d - %s", rr.Code, rr.Body.String())
+	}
+	if name := next(); name != "registration" {
+		t.Errorf("Expected registration event, got %q", name)
+	}
+
+	// Without Accept: text/event-stream the query token is ignored
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/workshops/ws-stream/timeline?access_token="+token, nil))
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for query token on non-stream request, got %d", rr.Code)
+	}
+}
+
+func TestSessionStream(t *testing.T) {
+	server, s, _, cleanup := setupTestServerWithMock(t)
+	defer cleanup()
+
+	s.CreateWorkshop(&store.Workshop{
+		ID:        "ws-learner",
+		Name:      "Learner Stream Workshop",
+		Code:      "LRNR-1234",
+		Seats:     1,
+		Status:    "provisioning",
+		CreatedAt: time.Now(),
+	})
+	s.CreateSession(&store.Session{OdeHash: "seat1", WorkshopID: "ws-learner", SeatID: 1, Status: "pending", JoinedAt: time.Now()})
+	s.CreateRegistration(&store.Registration{
+		ID:         "reg-learner",
+		AccessCode: "LRN-0001",
+		Email:      "learner@example.com",
+		Name:       "Learner",
+		WorkshopID: "ws-learner",
+		Status:     "registered",
+		CreatedAt:  time.Now(),
+	})
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/session/LRN-0001/stream")
+	if err != nil {
+		t.Fatalf("Stream request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+
+	type sseEvent struct {
+		name string
+		data map[string]interface{}
+	}
+	stream := make(chan sseEvent, 16)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		var name string
+		for scanner.Scan() {
+			line := scanner.Text()
+			if v, ok := strings.CutPrefix(line, "event: "); ok {
+				name = v
+			} else if v, ok := strings.CutPrefix(line, "data: "); ok {
+				var data map[string]interface{}
+				json.Unmarshal([]byte(v), &data)
+				stream <- sseEvent{name, data}
+			}
+		}
+		close(stream)
+	}()
+	next := func() sseEvent {
+		select {
+		case e := <-stream:
+			return e
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timed out waiting for event")
+			return sseEvent{}
+		}
+	}
+
+	e := next()
+	if e.name != "pending" || e.data["queue_position"] != float64(1) {
+		t.Fatalf("First event = %s %v, want pending with queue_position 1", e.name, e.data)
+	}
+
+	// Bring the workshop up the way the provisioning goroutine does
+	s.CreateVM(&store.WorkshopVM{ID: "vm-learner", WorkshopID: "ws-learner", VMName: "clarateach-ws-learner", ExternalIP: "1.2.3.4", Status: "RUNNING", CreatedAt: time.Now(), UpdatedAt: time.Now()})
+	sess, _ := s.GetSessionBySeat("ws-learner", 1)
+	sess.Status = "ready"
+	s.UpdateSession(sess)
+	server.setWorkshopStatus("ws-learner", "running")
+
+	if e := next(); e.name != "seat_assigned" || e.data["seat"] != float64(1) {
+		t.Errorf("Expected seat_assigned for seat 1, got %s %v", e.name, e.data)
+	}
+	e = next()
+	if e.name != "ready" || e.data["endpoint"] != "http://1.2.3.4:8080" || e.data["token"] == "" {
+		t.Errorf("Expected ready with endpoint and token, got %s %v", e.name, e.data)
+	}
+	if _, open := <-stream; open {
+		t.Error("Stream should end after ready")
+	}
+}
+
+func TestUpdateWorkshopSeats(t *testing.T) {
+	server, s, mockProv, cleanup := setupTestServerWithMock(t)
+	defer cleanup()
+
+	token := createTestUserToken(t, server, "resize@example.com")
+	owner, _ := s.GetUserByEmail("resize@example.com")
+	s.CreateWorkshop(&store.Workshop{
+		ID:          "ws-resize",
+		Name:        "Resize Workshop",
+		Code:        "RSZE-1234",
+		Seats:       2,
+		RuntimeType: "docker", // Served by the mock provisioner
+		Status:      "running",
+		OwnerID:     owner.ID,
+		CreatedAt:   time.Now(),
+	})
+	s.CreateVM(&store.WorkshopVM{ID: "vm-resize", WorkshopID: "ws-resize", VMName: "clarateach-fc-ws-resize", ExternalIP: "1.2.3.4", Status: "RUNNING", CreatedAt: time.Now(), UpdatedAt: time.Now()})
+	s.CreateSession(&store.Session{OdeHash: "seat1", WorkshopID: "ws-resize", SeatID: 1, Status: "ready", JoinedAt: time.Now()})
+	s.CreateSession(&store.Session{OdeHash: "seat2", WorkshopID: "ws-resize", SeatID: 2, Name: "Alice", Status: "occupied", JoinedAt: time.Now()})
+
+	patch := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PATCH", "/api/workshops/ws-resize", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Grow: new seats get MicroVMs and ready sessions
+	rr := patch(`{"name": "Renamed Workshop", "seats": 4}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Grow failed: %d - %s", rr.Code, rr.Body.String())
+	}
+	if len(mockProv.AddedSeats) != 2 || mockProv.AddedSeats[0] != 3 || mockProv.AddedSeats[1] != 4 {
+		t.Errorf("Expected seats 3 and 4 to be added, got %v", mockProv.AddedSeats)
+	}
+	workshop, _ := s.GetWorkshop("ws-resize")
+	if workshop.Seats != 4 || workshop.Name != "Renamed Workshop" {
+		t.Errorf("Workshop = %d seats, name %q; want 4 seats, Renamed Workshop", workshop.Seats, workshop.Name)
+	}
+	if sess, _ := s.GetSessionBySeat("ws-resize", 4); sess == nil || sess.Status != "ready" || sess.IP != "1.2.3.4" {
+		t.Errorf("Seat 4 session = %+v, want ready at 1.2.3.4", sess)
+	}
+
+	// Shrinking below an occupied seat is refused
+	if rr := patch(`{"seats": 1}`); rr.Code != http.StatusConflict {
+		t.Errorf("Expected 409 shrinking below occupied seat, got %d", rr.Code)
+	}
+
+	// Shrinking below the number of registered learners is refused
+	s.CreateRegistration(&store.Registration{ID: "reg-r1", AccessCode: "RSZ-1", Email: "r1@example.com", Name: "R1", WorkshopID: "ws-resize", Status: "registered", CreatedAt: time.Now()})
+	s.CreateRegistration(&store.Registration{ID: "reg-r2", AccessCode: "RSZ-2", Email: "r2@example.com", Name: "R2", WorkshopID: "ws-resize", Status: "registered", CreatedAt: time.Now()})
+	s.CreateRegistration(&store.Registration{ID: "reg-r3", AccessCode: "RSZ-3", Email: "r3@example.com", Name: "R3", WorkshopID: "ws-resize", Status: "registered", CreatedAt: time.Now()})
+	if rr := patch(`{"seats": 2}`); rr.Code != http.StatusConflict {
+		t.Errorf("Expected 409 shrinking below registrations, got %d", rr.Code)
+	}
+	if len(mockProv.RemovedSeats) != 0 {
+		t.Errorf("Refused shrink removed seats %v", mockProv.RemovedSeats)
+	}
+	reg, _ := s.GetRegistrationByID("reg-r3")
+	reg.Status = "revoked"
+	s.UpdateRegistration(reg)
+
+	// Shrink to the occupied seat releases the free ones
+	if rr := patch(`{"seats": 2}`); rr.Code != http.StatusOK {
+		t.Fatalf("Shrink failed: %d - %s", rr.Code, rr.Body.String())
+	}
+	if len(mockProv.RemovedSeats) != 2 {
+		t.Errorf("Expected 2 seats removed, got %v", mockProv.RemovedSeats)
+	}
+	sessions, _ := s.ListSessions("ws-resize")
+	if len(sessions) != 2 {
+		t.Errorf("Expected 2 sessions after shrink, got %d", len(sessions))
+	}
+
+	// Runtime cannot change while running
+	if rr := patch(`{"runtime_type": "firecracker"}`); rr.Code != http.StatusConflict {
+		t.Errorf("Expected 409 changing runtime while running, got %d", rr.Code)
+	}
+}
+
+func TestWorkshopAccessControl(t *testing.T) {
+	server, s, cleanup := setupTestServerWithAuth(t)
+	defer cleanup()
+
+	ownerToken := createTestUserToken(t, server, "owner@example.com")
+	coToken := createTestUserToken(t, server, "co@example.com")
+	taToken := createTestUserToken(t, server, "ta@example.com")
+	outsiderToken := createTestUserToken(t, server, "outsider@example.com")
+	owner, _ := s.GetUserByEmail("owner@example.com")
+
+	s.CreateWorkshop(&store.Workshop{
+		ID:          "ws-owned",
+		Name:        "Owned Workshop",
+		Code:        "OWND-1234",
+		Seats:       2,
+		RuntimeType: "docker",
+		Status:      "created",
+		OwnerID:     owner.ID,
+		CreatedAt:   time.Now(),
+	})
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Outsiders cannot see the workshop at all
+	if rr := do("GET", "/api/workshops/ws-owned", outsiderToken, ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Outsider GET = %d, want 404", rr.Code)
+	}
+	if rr := do("DELETE", "/api/workshops/ws-owned", outsiderToken, ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Outsider DELETE = %d, want 404", rr.Code)
+	}
+
+	// Only the owner manages members
+	if rr := do("POST", "/api/workshops/ws-owned/members", outsiderToken, `{"email": "outsider@example.com", "role": "co_instructor"}`); rr.Code != http.StatusNotFound {
+		t.Errorf("Outsider adding member = %d, want 404", rr.Code)
+	}
+	if rr := do("POST", "/api/workshops/ws-owned/members", ownerToken, `{"email": "co@example.com", "role": "co_instructor"}`); rr.Code != http.StatusOK {
+		t.Fatalf("Add co-instructor failed: %d - %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("POST", "/api/workshops/ws-owned/members", ownerToken, `{"email": "ta@example.com", "role": "ta"}`); rr.Code != http.StatusOK {
+		t.Fatalf("Add TA failed: %d - %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("POST", "/api/workshops/ws-owned/members", ownerToken, `{"email": "ta@example.com", "role": "owner"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("Invalid role = %d, want 400", rr.Code)
+	}
+	if rr := do("POST", "/api/workshops/ws-owned/members", ownerToken, `{"email": "nobody@example.com", "role": "ta"}`); rr.Code != http.StatusNotFound {
+		t.Errorf("Unknown user = %d, want 404", rr.Code)
+	}
+
+	// Shared workshops show up in the member's list
+	rr := do("GET", "/api/workshops", taToken, "")
+	var list struct {
+		Workshops []store.Workshop `json:"workshops"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &list)
+	if len(list.Workshops) != 1 || list.Workshops[0].ID != "ws-owned" {
+		t.Errorf("TA workshop list = %+v, want ws-owned", list.Workshops)
+	}
+
+	// TAs can view but not change the workshop or its members
+	if rr := do("GET", "/api/workshops/ws-owned", taToken, ""); rr.Code != http.StatusOK {
+		t.Errorf("TA GET = %d, want 200", rr.Code)
+	}
+	if rr := do("GET", "/api/workshops/ws-owned/members", taToken, ""); rr.Code != http.StatusOK {
+		t.Errorf("TA listing members = %d, want 200", rr.Code)
+	}
+	if rr := do("POST", "/api/workshops/ws-owned/stop", taToken, ""); rr.Code != http.StatusForbidden {
+		t.Errorf("TA stop = %d, want 403", rr.Code)
+	}
+	if rr := do("DELETE", "/api/workshops/ws-owned/members/"+owner.ID, taToken, ""); rr.Code != http.StatusForbidden {
+		t.Errorf("TA removing member = %d, want 403", rr.Code)
+	}
+
+	// Co-instructors can update but not delete
+	if rr := do("PATCH", "/api/workshops/ws-owned", coToken, `{"name": "Renamed"}`); rr.Code != http.StatusOK {
+		t.Errorf("Co-instructor PATCH = %d, want 200: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("DELETE", "/api/workshops/ws-owned", coToken, ""); rr.Code != http.StatusForbidden {
+		t.Errorf("Co-instructor DELETE = %d, want 403", rr.Code)
+	}
+
+	// Removing a member revokes their access
+	ta, _ := s.GetUserByEmail("ta@example.com")
+	if rr := do("DELETE", "/api/workshops/ws-owned/members/"+ta.ID, ownerToken, ""); rr.Code != http.StatusOK {
+		t.Fatalf("Remove TA failed: %d - %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("GET", "/api/workshops/ws-owned", taToken, ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Removed TA GET = %d, want 404", rr.Code)
+	}
+
+	// An owner who also has a member row sees the workshop once
+	s.AddWorkshopMember(&store.WorkshopMember{WorkshopID: "ws-owned", UserID: owner.ID, Role: store.MemberRoleTA, AddedBy: owner.ID, CreatedAt: time.Now()})
+	rr = do("GET", "/api/workshops", ownerToken, "")
+	list.Workshops = nil
+	json.Unmarshal(rr.Body.Bytes(), &list)
+	if len(list.Workshops) != 1 {
+		t.Errorf("Owner workshop list = %+v, want ws-owned once", list.Workshops)
+	}
+
+	// Workshops without an owner are for admins only
+	adminToken := createTestAdminToken(t, s, "admin@example.com")
+	s.CreateWorkshop(&store.Workshop{ID: "ws-ownerless", Name: "Legacy", Code: "LGCY-1234", Seats: 1, RuntimeType: "docker", Status: "created", CreatedAt: time.Now()})
+	if rr := do("GET", "/api/workshops/ws-ownerless", outsiderToken, ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Instructor GET ownerless = %d, want 404", rr.Code)
+	}
+	if rr := do("DELETE", "/api/workshops/ws-ownerless", ownerToken, ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Instructor DELETE ownerless = %d, want 404", rr.Code)
+	}
+	if rr := do("GET", "/api/workshops/ws-ownerless", adminToken, ""); rr.Code != http.StatusOK {
+		t.Errorf("Admin GET ownerless = %d, want 200", rr.Code)
+	}
+}
+
+func TestObserveLearnerTerminal(t *testing.T) {
+	server, s, cleanup := setupTestServerWithAuth(t)
+	defer cleanup()
+
+	ownerToken := createTestUserToken(t, server, "owner@example.com")
+	taToken := createTestUserToken(t, server, "ta@example.com")
+	outsiderToken := createTestUserToken(t, server, "outsider@example.com")
+	owner, _ := s.GetUserByEmail("owner@example.com")
+	ta, _ := s.GetUserByEmail("ta@example.com")
+
+	s.CreateWorkshop(&store.Workshop{
+		ID:          "ws-observe",
+		Name:        "Observed Workshop",
+		Code:        "OBSV-1234",
+		Seats:       1,
+		RuntimeType: "firecracker",
+		Status:      "running",
+		OwnerID:     owner.ID,
+		CreatedAt:   time.Now(),
+	})
+	s.CreateVM(&store.WorkshopVM{ID: "vm-observe", WorkshopID: "ws-observe", VMName: "clarateach-fc-ws-observe", ExternalIP: "1.2.3.4", TunnelURL: "https://observe.trycloudflare.com", Status: "RUNNING", CreatedAt: time.Now(), UpdatedAt: time.Now()})
+	s.CreateSession(&store.Session{OdeHash: "seat1", WorkshopID: "ws-observe", SeatID: 1, Name: "Alice", Status: "occupied", JoinedAt: time.Now()})
+	s.AddWorkshopMember(&store.WorkshopMember{WorkshopID: "ws-observe", UserID: ta.ID, Role: store.MemberRoleTA, AddedBy: owner.ID, CreatedAt: time.Now()})
+
+	do := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// TAs can list learners
+	rr := do("GET", "/api/workshops/ws-observe/learners", taToken)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("TA listing learners = %d - %s", rr.Code, rr.Body.String())
+	}
+	if !strings.Contains(rr.Body.String(), `"name":"Alice"`) {
+		t.Errorf("Learner list missing Alice: %s", rr.Body.String())
+	}
+
+	// TAs get a read-only observer token for the seat
+	rr = do("POST", "/api/workshops/ws-observe/learners/1/observe", taToken)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("TA observe = %d - %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Token       string `json:"token"`
+		TerminalURL string `json:"terminal_url"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	claims, err := auth.ValidateWorkspaceToken(resp.Token)
+	if err != nil {
+		t.Fatalf("Observer token invalid: %v", err)
+	}
+	if !claims.IsObserver() || claims.Seat != 1 || claims.UserID != ta.ID {
+		t.Errorf("Observer claims = %+v, want observer of seat 1 for the TA", claims)
+	}
+	if !strings.HasPrefix(resp.TerminalURL, "wss://observe.trycloudflare.com/proxy/ws-observe/1/terminal?token=") {
+		t.Errorf("Terminal URL = %q", resp.TerminalURL)
+	}
+
+	if rr := do("POST", "/api/workshops/ws-observe/learners/9/observe", taToken); rr.Code != http.StatusNotFound {
+		t.Errorf("Observing missing seat = %d, want 404", rr.Code)
+	}
+	if rr := do("POST", "/api/workshops/ws-observe/learners/1/observe", outsiderToken); rr.Code != http.StatusNotFound {
+		t.Errorf("Outsider observe = %d, want 404", rr.Code)
+	}
+
+	// TAs cannot control the workshop or download SSH keys
+	if rr := do("POST", "/api/workshops/ws-observe/stop", taToken); rr.Code != http.StatusForbidden {
+		t.Errorf("TA stop = %d, want 403", rr.Code)
+	}
+	if rr := do("GET", "/api/admin/vms/ws-observe/ssh-key", taToken); rr.Code != http.StatusForbidden {
+		t.Errorf("TA SSH key = %d, want 403", rr.Code)
+	}
+	if rr := do("GET", "/api/workshops/ws-observe/learners", ownerToken); rr.Code != http.StatusOK {
+		t.Errorf("Owner listing learners = %d, want 200", rr.Code)
+	}
+
+	// Only instructors get tokens that can take control
+	if rr := do("POST", "/api/workshops/ws-observe/learners/1/control", taToken); rr.Code != http.StatusForbidden {
+		t.Errorf("TA control = %d, want 403", rr.Code)
+	}
+	rr = do("POST", "/api/workshops/ws-observe/learners/1/control", ownerToken)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Owner control = %d - %s", rr.Code, rr.Body.String())
+	}
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	if claims, err := auth.ValidateWorkspaceToken(resp.Token); err != nil || !claims.CanTakeControl() {
+		t.Errorf("Control token claims = %+v (%v), want instructor access", claims, err)
+	}
+}
+
+func TestTerminalRecordingsCollectedOnStop(t *testing.T) {
+	server, s, mockProv, cleanup := setupTestServerWithMock(t)
+	defer cleanup()
+
+	token := createTestUserToken(t, server, "recorder@example.com")
+	outsiderToken := createTestUserToken(t, server, "outsider@example.com")
+	owner, _ := s.GetUserByEmail("recorder@example.com")
+
+	s.CreateWorkshop(&store.Workshop{ID: "ws-rec", Name: "Recorded", Code: "RECD-1234", Seats: 2, RuntimeType: "docker", Status: "running", OwnerID: owner.ID, CreatedAt: time.Now()})
+	s.CreateSession(&store.Session{OdeHash: "seat1", WorkshopID: "ws-rec", SeatID: 1, Name: "Alice", Status: "occupied", JoinedAt: time.Now()})
+
+	started := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	mockProv.Recordings = []provisioner.RecordingInfo{
+		{SeatID: 1, Name: "seat-1-a.cast", SizeBytes: 4, StartedAt: started},
+		{SeatID: 2, Name: "seat-2-b.cast", SizeBytes: 4, StartedAt: started.Add(time.Minute)},
+		{SeatID: 2, Name: "seat-2-missing.cast", StartedAt: started.Add(2 * time.Minute)},
+	}
+	mockProv.RecordingData = map[string][]byte{
+		"seat-1-a.cast": []byte("one\n"),
+		"seat-2-b.cast": []byte("two\n"),
+	}
+
+	do := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := do("POST", "/api/workshops/ws-rec/stop", token); rr.Code != http.StatusOK {
+		t.Fatalf("Stop = %d - %s", rr.Code, rr.Body.String())
+	}
+	for i := 0; i < 50; i++ {
+		if ws, _ := s.GetWorkshop("ws-rec"); ws.Status == "stopped" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Recordings that could not be fetched are skipped
+	rr := do("GET", "/api/workshops/ws-rec/recordings", token)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("List recordings = %d - %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Recordings []store.TerminalRecording `json:"recordings"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	if len(resp.Recordings) != 2 {
+		t.Fatalf("Got %d recordings, want 2: %s", len(resp.Recordings), rr.Body.String())
+	}
+	if resp.Recordings[0].LearnerName != "Alice" || resp.Recordings[0].SeatID != 1 {
+		t.Errorf("First recording = %+v, want seat 1 by Alice", resp.Recordings[0])
+	}
+
+	rr = do("GET", "/api/workshops/ws-rec/recordings?seat=2", token)
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	if len(resp.Recordings) != 1 || resp.Recordings[0].Name != "seat-2-b.cast" {
+		t.Errorf("Seat 2 recordings = %+v, want seat-2-b.cast", resp.Recordings)
+	}
+
+	rr = do("GET", fmt.Sprintf("/api/workshops/ws-rec/recordings/%d", resp.Recordings[0].ID), token)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Download recording = %d - %s", rr.Code, rr.Body.String())
+	}
+	if rr.Body.String() != "two\n" || rr.Header().Get("Content-Type") != "application/x-asciicast" {
+		t.Errorf("Download = %q (%s), want asciicast data", rr.Body.String(), rr.Header().Get("Content-Type"))
+	}
+
+	// Recordings are only visible to workshop staff
+	if rr := do("GET", "/api/workshops/ws-rec/recordings", outsiderToken); rr.Code != http.StatusNotFound {
+		t.Errorf("Outsider listing recordings = %d, want 404", rr.Code)
+	}
+	if rr := do("GET", "/api/workshops/ws-rec/recordings/999", token); rr.Code != http.StatusNotFound {
+		t.Errorf("Unknown recording = %d, want 404", rr.Code)
+	}
+}
+
+func TestTerminalRecordingsCollectedOnDelete(t *testing.T) {
+	server, s, mockProv, cleanup := setupTestServerWithMock(t)
+	defer cleanup()
+
+	token := createTestUserToken(t, server, "recorder@example.com")
+	owner, _ := s.GetUserByEmail("recorder@example.com")
+	s.CreateWorkshop(&store.Workshop{ID: "ws-rec", Name: "Recorded", Code: "RECD-1234", Seats: 1, RuntimeType: "docker", Status: "running", OwnerID: owner.ID, CreatedAt: time.Now()})
+	mockProv.Recordings = []provisioner.RecordingInfo{{SeatID: 1, Name: "seat-1-a.cast", SizeBytes: 4, StartedAt: time.Now()}}
+	mockProv.RecordingData = map[string][]byte{"seat-1-a.cast": []byte("one\n")}
+
+	req := httptest.NewRequest("DELETE", "/api/workshops/ws-rec", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Delete = %d - %s", rr.Code, rr.Body.String())
+	}
+	for i := 0; i < 50; i++ {
+		if ws, _ := s.GetWorkshop("ws-rec"); ws.Status == "deleted" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The VM is deleted only after its recordings are saved
+	recordings, _ := s.ListTerminalRecordings("ws-rec")
+	if len(recordings) != 1 || recordings[0].Name != "seat-1-a.cast" {
+		t.Errorf("Recordings after delete = %+v, want seat-1-a.cast", recordings)
+	}
+	if len(mockProv.DeletedVMs) != 1 {
+		t.Errorf("Deleted VMs = %v, want ws-rec", mockProv.DeletedVMs)
+	}
+}
+
+func TestAnnouncements(t *testing.T) {
+	server, s, cleanup := setupTestServerWithAuth(t)
+	defer cleanup()
+
+	ownerToken := createTestUserToken(t, server, "owner@example.com")
+	outsiderToken := createTestUserToken(t, server, "outsider@example.com")
+	owner, _ := s.GetUserByEmail("owner@example.com")
+
+	s.CreateWorkshop(&store.Workshop{ID: "ws-announce", Name: "Announced", Code: "ANNC-1234", Seats: 2, Status: "running", OwnerID: owner.ID, CreatedAt: time.Now()})
+	s.CreateRegistration(&store.Registration{ID: "reg-announce", AccessCode: "ANN-0001", Email: "learner@example.com", Name: "Learner", WorkshopID: "ws-announce", Status: "registered", CreatedAt: time.Now()})
+
+	post := func(token, message string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{"message": message})
+		req := httptest.NewRequest("POST", "/api/workshops/ws-announce/announcements", bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := post(ownerToken, "Welcome to the workshop"); rr.Code != http.StatusOK {
+		t.Fatalf("Create announcement = %d - %s", rr.Code, rr.Body.String())
+	}
+	if rr := post(ownerToken, "   "); rr.Code != http.StatusBadRequest {
+		t.Errorf("Blank announcement = %d, want 400", rr.Code)
+	}
+	if rr := post(ownerToken, strings.Repeat("x", maxAnnouncementLength+1)); rr.Code != http.StatusBadRequest {
+		t.Errorf("Long announcement = %d, want 400", rr.Code)
+	}
+	if rr := post(outsiderToken, "Hello"); rr.Code != http.StatusNotFound {
+		t.Errorf("Outsider announcement = %d, want 404", rr.Code)
+	}
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	if resp, _ := http.Get(ts.URL + "/api/session/NOPE-0000/announcements/stream"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Stream with unknown code = %d, want 404", resp.StatusCode)
+	}
+
+	resp, err := http.Get(ts.URL + "/api/session/ANN-0001/announcements/stream")
+	if err != nil {
+		t.Fatalf("Stream request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	type sseEvent struct {
+		name string
+		data string
+	}
+	stream := make(chan sseEvent, 16)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		var name string
+		for scanner.Scan() {
+			line := scanner.Text()
+			if v, ok := strings.CutPrefix(line, "event: "); ok {
+				name = v
+			} else if v, ok := strings.CutPrefix(line, "data: "); ok {
+				stream <- sseEvent{name, v}
+			}
+		}
+		close(stream)
+	}()
+	next := func() sseEvent {
+		select {
+		case e := <-stream:
+			return e
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timed out waiting for event")
+			return sseEvent{}
+		}
+	}
+
+	// Learners joining late get the history first
+	if e := next(); e.name != "snapshot" || !strings.Contains(e.data, "Welcome to the workshop") {
+		t.Fatalf("First event = %s %s, want snapshot with the welcome", e.name, e.data)
+	}
+
+	// Other workshop activity is not sent to learners
+	server.setWorkshopStatus("ws-announce", "running")
+	post(ownerToken, "Break in 5 minutes")
+	e := next()
+	if e.name != "announcement" || !strings.Contains(e.data, "Break in 5 minutes") || !strings.Contains(e.data, `"author_name":"Test User"`) {
+		t.Errorf("Live event = %s %s, want the break announcement", e.name, e.data)
+	}
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/session/ANN-0001/announcements", nil))
+	var history struct {
+		Announcements []map[string]interface{} `json:"announcements"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &history)
+	if len(history.Announcements) != 2 || history.Announcements[1]["message"] != "Break in 5 minutes" {
+		t.Errorf("History = %s, want both announcements", rr.Body.String())
+	}
+}
+
+func TestCreatePreviewToken(t *testing.T) {
+	server, s, cleanup := setupTestServerWithAuth(t)
+	defer cleanup()
+
+	seat := 1
+	s.CreateWorkshop(&store.Workshop{ID: "ws-preview", Name: "Preview", Code: "PRVW-1234", Seats: 1, RuntimeType: "firecracker", Status: "running", CreatedAt: time.Now()})
+	s.CreateRegistration(&store.Registration{ID: "reg-alice", AccessCode: "PRV-0001", Email: "alice@example.com", Name: "Alice", WorkshopID: "ws-preview", SeatID: &seat, Status: "active", CreatedAt: time.Now()})
+	s.CreateRegistration(&store.Registration{ID: "reg-bob", AccessCode: "PRV-0002", Email: "bob@example.com", Name: "Bob", WorkshopID: "ws-preview", Status: "registered", CreatedAt: time.Now()})
+	s.CreateRegistration(&store.Registration{ID: "reg-carol", AccessCode: "PRV-0003", Email: "carol@example.com", Name: "Carol", WorkshopID: "ws-preview", Status: "revoked", CreatedAt: time.Now()})
+
+	post := func(code, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/session/"+code+"/preview", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := post("PRV-0001", `{"port": 5173}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Create preview token = %d - %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Token string `json:"token"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	claims, err := auth.ValidatePreviewToken(resp.Token)
+	if err != nil {
+		t.Fatalf("ValidatePreviewToken() error = %v", err)
+	}
+	if claims.WorkshopID != "ws-preview" || claims.Seat != 1 || claims.Port != 5173 || claims.RegistrationID != "reg-alice" {
+		t.Errorf("Preview claims = %+v, want ws-preview seat 1 port 5173 for reg-alice", claims)
+	}
+	if _, err := auth.ValidateWorkspaceToken(resp.Token); err == nil {
+		t.Error("Preview token should not be accepted as a workspace token")
+	}
+
+	if rr := post("PRV-0001", `{"port": 0}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("Invalid port = %d, want 400", rr.Code)
+	}
+	if rr := post("PRV-0002", `{"port": 5173}`); rr.Code != http.StatusConflict {
+		t.Errorf("Learner without a seat = %d, want 409", rr.Code)
+	}
+	if rr := post("PRV-0003", `{"port": 5173}`); rr.Code != http.StatusForbidden {
+		t.Errorf("Revoked learner = %d, want 403", rr.Code)
+	}
+	if rr := post("NOPE-0000", `{"port": 5173}`); rr.Code != http.StatusNotFound {
+		t.Errorf("Unknown access code = %d, want 404", rr.Code)
+	}
+}
+
+func TestHelpRequestQueue(t *testing.T) {
+	server, s, cleanup := setupTestServerWithAuth(t)
+	defer cleanup()
+
+	ownerToken := createTestUserToken(t, server, "owner@example.com")
+	taToken := createTestUserToken(t, server, "ta@example.com")
+	outsiderToken := createTestUserToken(t, server, "outsider@example.com")
+	owner, _ := s.GetUserByEmail("owner@example.com")
+	ta, _ := s.GetUserByEmail("ta@example.com")
+
+	seat1, seat2 := 1, 2
+	s.CreateWorkshop(&store.Workshop{ID: "ws-help", Name: "Help", Code: "HELP-1234", Seats: 2, Status: "running", OwnerID: owner.ID, CreatedAt: time.Now()})
+	s.CreateRegistration(&store.Registration{ID: "reg-alice", AccessCode: "HLP-0001", Email: "alice@example.com", Name: "Alice", WorkshopID: "ws-help", SeatID: &seat1, Status: "active", CreatedAt: time.Now()})
+	s.CreateRegistration(&store.Registration{ID: "reg-bob", AccessCode: "HLP-0002", Email: "bob@example.com", Name: "Bob", WorkshopID: "ws-help", SeatID: &seat2, Status: "active", CreatedAt: time.Now()})
+	s.CreateRegistration(&store.Registration{ID: "reg-carol", AccessCode: "HLP-0003", Email: "carol@example.com", Name: "Carol", WorkshopID: "ws-help", Status: "registered", CreatedAt: time.Now()})
+	s.AddWorkshopMember(&store.WorkshopMember{WorkshopID: "ws-help", UserID: ta.ID, Role: store.MemberRoleTA, AddedBy: owner.ID, CreatedAt: time.Now()})
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+	type helpResponse struct {
+		HelpRequest *struct {
+			ID     int64  `json:"id"`
+			SeatID int    `json:"seat_id"`
+			Status string `json:"status"`
+		} `json:"help_request"`
+		Position int `json:"position"`
+	}
+	decode := func(rr *httptest.ResponseRecorder) helpResponse {
+		var resp helpResponse
+		json.Unmarshal(rr.Body.Bytes(), &resp)
+		return resp
+	}
+
+	ch, unsubscribe := server.events.Subscribe("ws-help")
+	defer unsubscribe()
+
+	// Learners raise a hand; raising it again keeps their place
+	rr := do("POST", "/api/session/HLP-0001/help", "", `{"message":"Stuck on step 3"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Raise hand = %d - %s", rr.Code, rr.Body.String())
+	}
+	alice := decode(rr)
+	if alice.HelpRequest == nil || alice.HelpRequest.SeatID != 1 || alice.HelpRequest.Status != "open" || alice.Position != 1 {
+		t.Fatalf("Raise hand = %s, want open request for seat 1 at position 1", rr.Body.String())
+	}
+	if e := <-ch; e.Type != events.TypeHelpRequest || e.SeatID != 1 || e.Data["status"] != "open" {
+		t.Errorf("Event = %+v, want open help_request for seat 1", e)
+	}
+	bob := decode(do("POST", "/api/session/HLP-0002/help", "", ""))
+	if bob.Position != 2 {
+		t.Errorf("Second learner position = %d, want 2", bob.Position)
+	}
+	if again := decode(do("POST", "/api/session/HLP-0001/help", "", "")); again.HelpRequest.ID != alice.HelpRequest.ID {
+		t.Errorf("Raising a hand twice created request %d, want %d", again.HelpRequest.ID, alice.HelpRequest.ID)
+	}
+	if rr := do("POST", "/api/session/HLP-0003/help", "", ""); rr.Code != http.StatusConflict {
+		t.Errorf("Raise hand without a seat = %d, want 409", rr.Code)
+	}
+	if rr := do("POST", "/api/session/NOPE-0000/help", "", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Raise hand with unknown code = %d, want 404", rr.Code)
+	}
+
+	// TAs see the queue; outsiders cannot
+	rr = do("GET", "/api/workshops/ws-help/help-requests", taToken, "")
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"learner_name":"Alice"`) || !strings.Contains(rr.Body.String(), `"learner_name":"Bob"`) {
+		t.Fatalf("TA queue = %d - %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("GET", "/api/workshops/ws-help/help-requests", outsiderToken, ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Outsider queue = %d, want 404", rr.Code)
+	}
+
+	// Only one staff member can claim a request
+	claimPath := fmt.Sprintf("/api/workshops/ws-help/help-requests/%d/claim", alice.HelpRequest.ID)
+	rr = do("POST", claimPath, taToken, "")
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"claimed_by_name":"Test User"`) || !strings.Contains(rr.Body.String(), `"seat_id":1`) {
+		t.Fatalf("TA claim = %d - %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("POST", claimPath, ownerToken, ""); rr.Code != http.StatusConflict {
+		t.Errorf("Second claim = %d, want 409", rr.Code)
+	}
+	if rr := do("POST", "/api/workshops/ws-help/help-requests/9999/claim", ownerToken, ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Claim unknown request = %d, want 404", rr.Code)
+	}
+	if got := decode(do("GET", "/api/session/HLP-0002/help", "", "")); got.Position != 1 {
+		t.Errorf("Bob's position after Alice was claimed = %d, want 1", got.Position)
+	}
+
+	rr = do("POST", fmt.Sprintf("/api/workshops/ws-help/help-requests/%d/resolve", alice.HelpRequest.ID), taToken, "")
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"status":"resolved"`) {
+		t.Fatalf("Resolve = %d - %s", rr.Code, rr.Body.String())
+	}
+	if got := decode(do("GET", "/api/session/HLP-0001/help", "", "")); got.HelpRequest != nil {
+		t.Errorf("Learner help after resolve = %+v, want none", got.HelpRequest)
+	}
+
+	// Learners can lower their hand
+	if rr := do("DELETE", "/api/session/HLP-0002/help", "", ""); rr.Code != http.StatusOK {
+		t.Errorf("Lower hand = %d - %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("GET", "/api/workshops/ws-help/help-requests", ownerToken, ""); strings.Contains(rr.Body.String(), `"id"`) {
+		t.Errorf("Queue after all requests were resolved = %s, want empty", rr.Body.String())
+	}
+	if rr := do("GET", "/api/workshops/ws-help/help-requests?status=all", ownerToken, ""); strings.Count(rr.Body.String(), `"status":"resolved"`) != 2 {
+		t.Errorf("All requests = %s, want both resolved", rr.Body.String())
+	}
+}
+
+func TestRosterImportExport(t *testing.T) {
+	server, s, cleanup := setupTestServerWithAuth(t)
+	defer cleanup()
+
+	ownerToken := createTestUserToken(t, server, "owner@example.com")
+	owner, _ := s.GetUserByEmail("owner@example.com")
+
+	s.CreateWorkshop(&store.Workshop{ID: "ws-roster", Name: "Roster", Code: "ROST-1234", Seats: 3, Status: "created", OwnerID: owner.ID, CreatedAt: time.Now()})
+	s.CreateRegistration(&store.Registration{ID: "reg-existing", AccessCode: "RST-0001", Email: "carol@example.com", Name: "Carol", WorkshopID: "ws-roster", Status: "registered", CreatedAt: time.Now()})
+	s.CreateRegistration(&store.Registration{ID: "reg-revoked", AccessCode: "RST-0002", Email: "eve@example.com", Name: "=HYPERLINK(\"http://evil\")", WorkshopID: "ws-roster", Status: "revoked", CreatedAt: time.Now()})
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+ownerToken)
+		req.Header.Set("Content-Type", "text/csv")
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Any invalid row rejects the whole roster
+	rr := do("POST", "/api/workshops/ws-roster/registrations/import", "name,email,seat\nAlice,alice@example.com,1\nBob,not-an-email,\nCarol Again,CAROL@example.com,\nDave,dave@example.com,9\nEve,eve@example.com,\n")
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("Invalid roster = %d - %s", rr.Code, rr.Body.String())
+	}
+	var invalid struct {
+		Errors []rosterRowError `json:"errors"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &invalid)
+	if len(invalid.Errors) != 4 || invalid.Errors[0].Row != 3 || invalid.Errors[1].Row != 4 || invalid.Errors[2].Row != 5 || invalid.Errors[3].Row != 6 {
+		t.Fatalf("Row errors = %+v, want rows 3, 4, 5 and 6", invalid.Errors)
+	}
+	if !strings.Contains(invalid.Errors[3].Error, "revoked") {
+		t.Errorf("Revoked learner's row error = %q, want it to say they were revoked", invalid.Errors[3].Error)
+	}
+	if count, _ := s.CountRegistrations("ws-roster"); count != 1 {
+		t.Errorf("Registrations after rejected import = %d, want 1", count)
+	}
+
+	if rr := do("POST", "/api/workshops/ws-roster/registrations/import", "a@example.com,A\nb@example.com,B\nc@example.com,C\n"); rr.Code != http.StatusBadRequest {
+		t.Errorf("Headerless roster with swapped columns = %d, want 400", rr.Code)
+	}
+	if rr := do("POST", "/api/workshops/ws-roster/registrations/import", "name,email\nA,a@example.com\nB,b@example.com\nC,c@example.com\n"); rr.Code != http.StatusConflict {
+		t.Errorf("Roster larger than the seats left = %d, want 409", rr.Code)
+	}
+
+	rr = do("POST", "/api/workshops/ws-roster/registrations/import", "email,name,seat\nalice@example.com,Alice,1\nbob@example.com,Bob,\n")
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"imported":2`) {
+		t.Fatalf("Import = %d - %s", rr.Code, rr.Body.String())
+	}
+	alice, _ := s.GetRegistrationByEmail("ws-roster", "alice@example.com")
+	if alice == nil || alice.SeatID == nil || *alice.SeatID != 1 || alice.JoinedAt != nil {
+		t.Fatalf("Imported registration = %+v, want fixed seat 1 not yet joined", alice)
+	}
+
+	rr = do("GET", "/api/workshops/ws-roster/registrations.csv", "")
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "text/csv; charset=utf-8" {
+		t.Fatalf("Export = %d %s", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	rows, err := csv.NewReader(rr.Body).ReadAll()
+	if err != nil || len(rows) != 5 {
+		t.Fatalf("Export rows = %v, %v, want header and 4 learners", rows, err)
+	}
+	if rows[0][2] != "access_code" || rows[1][0] != "Carol" {
+		t.Errorf("Export = %v", rows)
+	}
+	for _, row := range rows[1:] {
+		// Values a spreadsheet would run as formulas are quoted
+		if row[1] == "eve@example.com" && row[0] != "'=HYPERLINK(\"http://evil\")" {
+			t.Errorf("Exported formula name = %q, want it prefixed with a quote", row[0])
+		}
+		if row[0] == "Alice" && (row[2] != alice.AccessCode || row[3] != "1") {
+			t.Errorf("Alice's export row = %v, want her access code and seat 1", row)
+		}
+	}
+
+	// The fixed seat is kept for Alice when the workshop runs
+	s.UpdateWorkshopStatus("ws-roster", "running")
+	s.CreateVM(&store.WorkshopVM{ID: "vm-roster", WorkshopID: "ws-roster", VMName: "clarateach-ws-roster", ExternalIP: "1.2.3.4", Status: "RUNNING", CreatedAt: time.Now(), UpdatedAt: time.Now()})
+	for seat := 1; seat <= 3; seat++ {
+		s.CreateSession(&store.Session{OdeHash: fmt.Sprintf("seat%d", seat), WorkshopID: "ws-roster", SeatID: seat, Status: "ready", JoinedAt: time.Now()})
+	}
+	session := func(code string) sessionState {
+		rr := do("GET", "/api/session/"+code, "")
+		var state sessionState
+		json.Unmarshal(rr.Body.Bytes(), &state)
+		return state
+	}
+	if got := session("RST-0001"); got.Seat != 2 {
+		t.Errorf("Carol's seat = %d, want 2 (seat 1 is reserved)", got.Seat)
+	}
+	if got := session(alice.AccessCode); got.Status != "ready" || got.Seat != 1 {
+		t.Errorf("Alice's session = %+v, want ready on seat 1", got)
+	}
+	if alice, _ = s.GetRegistrationByEmail("ws-roster", "alice@example.com"); alice.JoinedAt == nil || alice.Status != "active" {
+		t.Errorf("Alice after joining = %+v, want active with a join time", alice)
+	}
+	if seat1, _ := s.GetSessionBySeat("ws-roster", 1); seat1.Name != "Alice" {
+		t.Errorf("Seat 1 occupant = %q, want Alice", seat1.Name)
+	}
+}
+
+func TestRegistrationManagement(t *testing.T) {
+	server, s, mockProv, cleanup := setupTestServerWithMock(t)
+	defer cleanup()
+
+	token := createTestUserToken(t, server, "manage@example.com")
+	owner, _ := s.GetUserByEmail("manage@example.com")
+	s.CreateWorkshop(&store.Workshop{ID: "ws-manage", Name: "Manage", Code: "MNGE-1234", Seats: 3, RuntimeType: "docker", Status: "running", OwnerID: owner.ID, CreatedAt: time.Now()})
+	s.CreateVM(&store.WorkshopVM{ID: "vm-manage", WorkshopID: "ws-manage", VMName: "clarateach-ws-manage", ExternalIP: "1.2.3.4", Status: "RUNNING", CreatedAt: time.Now(), UpdatedAt: time.Now()})
+	for seat := 1; seat <= 3; seat++ {
+		s.CreateSession(&store.Session{OdeHash: fmt.Sprintf("seat%d", seat), WorkshopID: "ws-manage", SeatID: seat, Status: "ready", JoinedAt: time.Now()})
+	}
+	s.CreateRegistration(&store.Registration{ID: "reg-alice", AccessCode: "MNG-0001", Email: "alice@example.com", Name: "Alice", WorkshopID: "ws-manage", Status: "registered", CreatedAt: time.Now()})
+	s.CreateRegistration(&store.Registration{ID: "reg-bob", AccessCode: "MNG-0002", Email: "bob@example.com", Name: "Bob", WorkshopID: "ws-manage", Status: "registered", CreatedAt: time.Now()})
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Alice joins and gets a token tied to her registration
+	rr := do("GET", "/api/session/MNG-0001", "")
+	var state sessionState
+	json.Unmarshal(rr.Body.Bytes(), &state)
+	if state.Seat != 1 {
+		t.Fatalf("Alice's session = %d - %s, want seat 1", rr.Code, rr.Body.String())
+	}
+	if claims, err := auth.ValidateWorkspaceToken(state.Token); err != nil || claims.RegistrationID != "reg-alice" {
+		t.Errorf("Alice's token claims = %+v, %v, want registration reg-alice", claims, err)
+	}
+
+	rr = do("GET", "/api/workshops/ws-manage/registrations", "")
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"access_code":"MNG-0002"`) {
+		t.Fatalf("List registrations = %d - %s", rr.Code, rr.Body.String())
+	}
+
+	// Moving to a seat held by someone else is refused
+	bob, _ := s.GetRegistrationByID("reg-bob")
+	bobSeat := 3
+	bob.SeatID = &bobSeat
+	s.UpdateRegistration(bob)
+	if rr := do("POST", "/api/workshops/ws-manage/registrations/reg-alice/seat", `{"seat": 3}`); rr.Code != http.StatusConflict {
+		t.Errorf("Move to Bob's reserved seat = %d, want 409", rr.Code)
+	}
+	if rr := do("POST", "/api/workshops/ws-manage/registrations/reg-alice/seat", `{"seat": 4}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("Move to seat 4 of 3 = %d, want 400", rr.Code)
+	}
+
+	// Workers are asked to revoke tokens without holding up seat allocation
+	mockProv.OnRevoke = func() {
+		if !server.seatMu.TryLock() {
+			t.Error("Seat allocation is blocked while tokens are revoked")
+			return
+		}
+		server.seatMu.Unlock()
+	}
+
+	// Moving Alice frees seat 1 and revokes her tokens for it
+	if rr := do("POST", "/api/workshops/ws-manage/registrations/reg-alice/seat", `{"seat": 2}`); rr.Code != http.StatusOK {
+		t.Fatalf("Move Alice = %d - %s", rr.Code, rr.Body.String())
+	}
+	if seat1, _ := s.GetSessionBySeat("ws-manage", 1); seat1.Name != "" || seat1.Status != "ready" {
+		t.Errorf("Seat 1 after move = %+v, want free and ready", seat1)
+	}
+	if seat2, _ := s.GetSessionBySeat("ws-manage", 2); seat2.Name != "Alice" || seat2.Status != "occupied" {
+		t.Errorf("Seat 2 after move = %+v, want Alice", seat2)
+	}
+	if len(mockProv.RevokedTokens) != 1 || mockProv.RevokedTokens[0] != "1/reg-alice" {
+		t.Errorf("Revoked tokens = %v, want 1/reg-alice", mockProv.RevokedTokens)
+	}
+
+	// Resetting a taken seat is refused; a free one is rebuilt
+	if rr := do("POST", "/api/workshops/ws-manage/seats/2/reset", ""); rr.Code != http.StatusConflict {
+		t.Errorf("Reset occupied seat = %d, want 409", rr.Code)
+	}
+	if rr := do("POST", "/api/workshops/ws-manage/seats/3/reset", ""); rr.Code != http.StatusConflict {
+		t.Errorf("Reset reserved seat = %d, want 409", rr.Code)
+	}
+	if rr := do("POST", "/api/workshops/ws-manage/seats/1/reset", ""); rr.Code != http.StatusAccepted {
+		t.Fatalf("Reset seat 1 = %d - %s", rr.Code, rr.Body.String())
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		seat1, _ := s.GetSessionBySeat("ws-manage", 1)
+		if seat1.Status == "ready" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Seat 1 after reset = %+v, want ready", seat1)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(mockProv.RemovedSeats) != 1 || len(mockProv.AddedSeats) != 1 || mockProv.AddedSeats[0] != 1 {
+		t.Errorf("Reset removed %v and added %v, want seat 1", mockProv.RemovedSeats, mockProv.AddedSeats)
+	}
+
+	// Revoking Alice frees her seat and locks her out
+	rr = do("DELETE", "/api/workshops/ws-manage/registrations/reg-alice", "")
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"status":"revoked"`) {
+		t.Fatalf("Revoke Alice = %d - %s", rr.Code, rr.Body.String())
+	}
+	if seat2, _ := s.GetSessionBySeat("ws-manage", 2); seat2.Name != "" {
+		t.Errorf("Seat 2 after revoke = %+v, want free", seat2)
+	}
+	if len(mockProv.RevokedTokens) != 2 || mockProv.RevokedTokens[1] != "2/reg-alice" {
+		t.Errorf("Revoked tokens = %v, want 2/reg-alice added", mockProv.RevokedTokens)
+	}
+	if rr := do("GET", "/api/session/MNG-0001", ""); rr.Code != http.StatusForbidden {
+		t.Errorf("Revoked learner session = %d, want 403", rr.Code)
+	}
+	if rr := do("DELETE", "/api/workshops/ws-manage/registrations/reg-alice", ""); rr.Code != http.StatusConflict {
+		t.Errorf("Revoke twice = %d, want 409", rr.Code)
+	}
+	if count, _ := s.CountRegistrations("ws-manage"); count != 1 {
+		t.Errorf("CountRegistrations after revoke = %d, want 1", count)
+	}
+	if rr := do("DELETE", "/api/workshops/other/registrations/reg-bob", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Revoke through another workshop = %d, want 404", rr.Code)
+	}
+}
+
+func TestWaitlist(t *testing.T) {
+	server, s, _, cleanup := setupTestServerWithMock(t)
+	defer cleanup()
+
+	token := createTestUserToken(t, server, "waitlist@example.com")
+	owner, _ := s.GetUserByEmail("waitlist@example.com")
+	s.CreateWorkshop(&store.Workshop{ID: "ws-wait", Name: "Waitlist", Code: "WAIT-1234", Seats: 1, RuntimeType: "docker", Status: "created", Waitlist: true, OwnerID: owner.ID, CreatedAt: time.Now()})
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+	register := func(email string) map[string]interface{} {
+		rr := do("POST", "/api/register", fmt.Sprintf(`{"workshop_code": "WAIT-1234", "email": %q, "name": %q}`, email, email))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Register %s = %d - %s", email, rr.Code, rr.Body.String())
+		}
+		var resp map[string]interface{}
+		json.Unmarshal(rr.Body.Bytes(), &resp)
+		return resp
+	}
+	status := func(email string) (string, int) {
+		reg, _ := s.GetRegistrationByEmail("ws-wait", email)
+		return reg.Status, reg.WaitlistPosition
+	}
+
+	if resp := register("a@example.com"); resp["waitlisted"] != nil {
+		t.Errorf("First registration = %v, want a seat", resp)
+	}
+	b := register("b@example.com")
+	if b["waitlisted"] != true || b["waitlist_position"] != float64(1) {
+		t.Errorf("Second registration = %v, want waitlist position 1", b)
+	}
+	register("c@example.com")
+	if st, pos := status("c@example.com"); st != "waitlisted" || pos != 2 {
+		t.Errorf("Third registration = %s at %d, want waitlisted at 2", st, pos)
+	}
+
+	// An imported roster may not jump the waitlist
+	if rr := do("POST", "/api/workshops/ws-wait/registrations/import", "name,email\nD,d@example.com\n"); rr.Code != http.StatusConflict || !strings.Contains(rr.Body.String(), "waitlist") {
+		t.Errorf("Import with a waitlist = %d - %s, want 409", rr.Code, rr.Body.String())
+	}
+
+	// A waitlisted access code shows the position but opens nothing
+	rr := do("GET", "/api/session/"+b["access_code"].(string), "")
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"status":"waitlisted"`) {
+		t.Errorf("Waitlisted session = %d - %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("GET", "/api/session/"+b["access_code"].(string)+"/help", ""); rr.Code != http.StatusForbidden {
+		t.Errorf("Waitlisted help = %d, want 403", rr.Code)
+	}
+
+	// Instructors reorder the waitlist
+	bReg, _ := s.GetRegistrationByEmail("ws-wait", "b@example.com")
+	cReg, _ := s.GetRegistrationByEmail("ws-wait", "c@example.com")
+	if rr := do("PUT", "/api/workshops/ws-wait/waitlist", fmt.Sprintf(`{"registration_ids": [%q]}`, cReg.ID)); rr.Code != http.StatusBadRequest {
+		t.Errorf("Partial reorder = %d, want 400", rr.Code)
+	}
+	if rr := do("PUT", "/api/workshops/ws-wait/waitlist", fmt.Sprintf(`{"registration_ids": [%q, %q]}`, cReg.ID, bReg.ID)); rr.Code != http.StatusOK {
+		t.Fatalf("Reorder = %d - %s", rr.Code, rr.Body.String())
+	}
+	rr = do("GET", "/api/workshops/ws-wait/waitlist", "")
+	var list struct {
+		Waitlist []*store.Registration `json:"waitlist"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &list)
+	if len(list.Waitlist) != 2 || list.Waitlist[0].ID != cReg.ID || list.Waitlist[1].WaitlistPosition != 2 {
+		t.Errorf("Waitlist after reorder = %s", rr.Body.String())
+	}
+
+	// Revoking a registration promotes the head of the waitlist
+	aReg, _ := s.GetRegistrationByEmail("ws-wait", "a@example.com")
+	if rr := do("DELETE", "/api/workshops/ws-wait/registrations/"+aReg.ID, ""); rr.Code != http.StatusOK {
+		t.Fatalf("Revoke = %d - %s", rr.Code, rr.Body.String())
+	}
+	if st, _ := status("c@example.com"); st != "registered" {
+		t.Errorf("C after revocation = %s, want registered", st)
+	}
+	if st, pos := status("b@example.com"); st != "waitlisted" || pos != 1 {
+		t.Errorf("B after revocation = %s at %d, want waitlisted at 1", st, pos)
+	}
+
+	// Adding a seat promotes the next learner
+	if rr := do("PATCH", "/api/workshops/ws-wait", `{"seats": 2}`); rr.Code != http.StatusOK {
+		t.Fatalf("Grow = %d - %s", rr.Code, rr.Body.String())
+	}
+	if st, _ := status("b@example.com"); st != "registered" {
+		t.Errorf("B after adding a seat = %s, want registered", st)
+	}
+
+	// Without a waitlist a full workshop refuses registrations
+	if rr := do("PATCH", "/api/workshops/ws-wait", `{"waitlist": false}`); rr.Code != http.StatusOK {
+		t.Fatalf("Disable waitlist = %d - %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("POST", "/api/register", `{"workshop_code": "WAIT-1234", "email": "d@example.com", "name": "D"}`); rr.Code != http.StatusConflict {
+		t.Errorf("Register without waitlist = %d, want 409", rr.Code)
+	}
+}
+
+// recordingNotifier collects sent messages for tests
+type recordingNotifier struct {
+	mu   sync.Mutex
+	sent []notify.Message
+}
+
+func (n *recordingNotifier) Send(ctx context.Context, msg notify.Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sent = append(n.sent, msg)
+	return nil
+}
+
+// waitFor waits until count messages have been sent and returns them
+func (n *recordingNotifier) waitFor(t *testing.T, count int) []notify.Message {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		n.mu.Lock()
+		sent := append([]notify.Message(nil), n.sent...)
+		n.mu.Unlock()
+		if len(sent) >= count || time.Now().After(deadline) {
+			if len(sent) != count {
+				t.Fatalf("Sent %d messages, want %d: %+v", len(sent), count, sent)
+			}
+			return sent
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	server, s, _, cleanup := setupTestServerWithMock(t)
+	defer cleanup()
+
+	notifier := &recordingNotifier{}
+	server.SetNotifier(notifier, "https://teach.example.com/")
+
+	token := createTestUserToken(t, server, "notify@example.com")
+	owner, _ := s.GetUserByEmail("notify@example.com")
+	startsAt := time.Now().Add(time.Hour)
+	s.CreateWorkshop(&store.Workshop{ID: "ws-notify", Name: "Go 101", Code: "NOTIFY-12", Seats: 1, RuntimeType: "docker", Status: "created", Waitlist: true, StartsAt: &startsAt, OwnerID: owner.ID, CreatedAt: time.Now()})
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Registering emails the access code; a full workshop emails the waitlist place
+	rr := do("POST", "/api/register", `{"workshop_code": "NOTIFY-12", "email": "ada@example.com", "name": "Ada"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Register = %d - %s", rr.Code, rr.Body.String())
+	}
+	ada, _ := s.GetRegistrationByEmail("ws-notify", "ada@example.com")
+	sent := notifier.waitFor(t, 1)
+	if sent[0].To != "ada@example.com" || !strings.Contains(sent[0].Body, ada.AccessCode) || !strings.Contains(sent[0].Body, "https://teach.example.com/s/"+ada.AccessCode) {
+		t.Errorf("Registration email = %+v", sent[0])
+	}
+	do("POST", "/api/register", `{"workshop_code": "NOTIFY-12", "email": "bob@example.com", "name": "Bob"}`)
+	sent = notifier.waitFor(t, 2)
+	if sent[1].To != "bob@example.com" || !strings.Contains(sent[1].Subject, "waitlist") || !strings.Contains(sent[1].Body, "number 1") {
+		t.Errorf("Waitlist email = %+v", sent[1])
+	}
+
+	// Workshops customize templates; broken ones are rejected when saved
+	if rr := do("PUT", "/api/workshops/ws-notify/notification-templates/reminder", `{"subject": "Soon: {{.Nope}}", "body": "x"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("Invalid template = %d, want 400", rr.Code)
+	}
+	if rr := do("PUT", "/api/workshops/ws-notify/notification-templates/bogus", `{"subject": "s", "body": "b"}`); rr.Code != http.StatusNotFound {
+		t.Errorf("Unknown kind = %d, want 404", rr.Code)
+	}
+	if rr := do("PUT", "/api/workshops/ws-notify/notification-templates/reminder", `{"subject": "{{.WorkshopName}} in an hour", "body": "Code: {{.AccessCode}}"}`); rr.Code != http.StatusOK {
+		t.Fatalf("Set template = %d - %s", rr.Code, rr.Body.String())
+	}
+	rr = do("GET", "/api/workshops/ws-notify/notification-templates", "")
+	if !strings.Contains(rr.Body.String(), `"custom":true`) || !strings.Contains(rr.Body.String(), `"enabled":true`) {
+		t.Errorf("Templates = %s", rr.Body.String())
+	}
+
+	// Reminders go once to learners with a place, using the custom template
+	server.sendReminders(context.Background(), 24*time.Hour)
+	sent = notifier.waitFor(t, 3)
+	if sent[2].To != "ada@example.com" || sent[2].Subject != "Go 101 in an hour" || sent[2].Body != "Code: "+ada.AccessCode {
+		t.Errorf("Reminder email = %+v", sent[2])
+	}
+	server.sendReminders(context.Background(), 24*time.Hour)
+
+	// Starting the workshop tells learners their workspace is ready
+	if rr := do("POST", "/api/workshops/ws-notify/start", ""); rr.Code != http.StatusOK {
+		t.Fatalf("Start = %d - %s", rr.Code, rr.Body.String())
+	}
+	sent = notifier.waitFor(t, 4)
+	if sent[3].To != "ada@example.com" || !strings.Contains(sent[3].Subject, "ready") {
+		t.Errorf("Workspace ready email = %+v", sent[3])
+	}
+
+	rr = do("GET", "/api/workshops/ws-notify/notifications", "")
+	var list struct {
+		Notifications []*store.Notification `json:"notifications"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &list)
+	if len(list.Notifications) != 4 {
+		t.Fatalf("Notifications = %s", rr.Body.String())
+	}
+
+	// Resetting a template restores the built-in one
+	if rr := do("DELETE", "/api/workshops/ws-notify/notification-templates/reminder", ""); rr.Code != http.StatusOK {
+		t.Errorf("Reset template = %d", rr.Code)
+	}
+	if tmpl, _ := s.GetNotificationTemplate("ws-notify", "reminder"); tmpl != nil {
+		t.Errorf("Template after reset = %+v, want nil", tmpl)
+	}
+}
+
+func TestLoginLinks(t *testing.T) {
+	server, s, _, cleanup := setupTestServerWithMock(t)
+	defer cleanup()
+
+	notifier := &recordingNotifier{}
+	server.SetNotifier(notifier, "https://teach.example.com")
+
+	token := createTestUserToken(t, server, "links@example.com")
+	owner, _ := s.GetUserByEmail("links@example.com")
+	s.CreateWorkshop(&store.Workshop{ID: "ws-links", Name: "Go 101", Code: "LINKS-123", Seats: 1, RuntimeType: "docker", Status: "created", OwnerID: owner.ID, CreatedAt: time.Now()})
+	s.CreateRegistration(&store.Registration{ID: "reg-ada", AccessCode: "ADA-1111", Email: "ada@example.com", Name: "Ada", WorkshopID: "ws-links", Status: "registered", CreatedAt: time.Now()})
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+	linkToken := func(body string) string {
+		i := strings.Index(body, "https://teach.example.com/login/")
+		if i < 0 {
+			t.Fatalf("No login link in %q", body)
+		}
+		return strings.Fields(body[i+len("https://teach.example.com/login/"):])[0]
+	}
+
+	// Unknown emails get the same answer as registered ones, and no email
+	if rr := do("POST", "/api/session/login-link", `{"workshop_code": "LINKS-123", "email": "nobody@example.com"}`); rr.Code != http.StatusAccepted {
+		t.Errorf("Login link for unknown email = %d, want 202", rr.Code)
+	}
+	if rr := do("POST", "/api/session/login-link", `{"workshop_code": "LINKS-123", "email": "ada@example.com"}`); rr.Code != http.StatusAccepted {
+		t.Fatalf("Login link = %d - %s", rr.Code, rr.Body.String())
+	}
+	sent := notifier.waitFor(t, 1)
+	if sent[0].To != "ada@example.com" || !strings.Contains(sent[0].Body, "ADA-1111") {
+		t.Errorf("Login link email = %+v", sent[0])
+	}
+	first := linkToken(sent[0].Body)
+
+	// Asking again straight away does not send another
+	do("POST", "/api/session/login-link", `{"workshop_code": "LINKS-123", "email": "ada@example.com"}`)
+	time.Sleep(50 * time.Millisecond)
+	notifier.waitFor(t, 1)
+
+	// The link exchanges for the session once
+	rr := do("POST", "/api/session/login", `{"token": "`+first+`"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Login = %d - %s", rr.Code, rr.Body.String())
+	}
+	var state sessionState
+	json.Unmarshal(rr.Body.Bytes(), &state)
+	if state.AccessCode != "ADA-1111" || state.WorkshopID != "ws-links" {
+		t.Errorf("Login session = %+v", state)
+	}
+	if rr := do("POST", "/api/session/login", `{"token": "`+first+`"}`); rr.Code != http.StatusGone {
+		t.Errorf("Reused login link = %d, want 410", rr.Code)
+	}
+	if rr := do("POST", "/api/session/login", `{"token": "not-a-token"}`); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Invalid login link = %d, want 401", rr.Code)
+	}
+
+	// Staff can send a link, and get it back to share another way
+	rr = do("POST", "/api/workshops/ws-links/registrations/reg-ada/login-link", "")
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"emailed":true`) {
+		t.Fatalf("Staff login link = %d - %s", rr.Code, rr.Body.String())
+	}
+	sent = notifier.waitFor(t, 2)
+	outstanding := linkToken(sent[1].Body)
+
+	// Rotating the code keeps the registration, replaces the code and kills
+	// outstanding links
+	rr = do("POST", "/api/workshops/ws-links/registrations/reg-ada/rotate-code", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Rotate code = %d - %s", rr.Code, rr.Body.String())
+	}
+	reg, _ := s.GetRegistrationByID("reg-ada")
+	if reg.AccessCode == "ADA-1111" || reg.Status != "registered" {
+		t.Errorf("Registration after rotate = %+v", reg)
+	}
+	if old, _ := s.GetRegistration("ADA-1111"); old != nil {
+		t.Error("Old access code still works after rotate")
+	}
+	if rr := do("POST", "/api/session/login", `{"token": "`+outstanding+`"}`); rr.Code != http.StatusGone {
+		t.Errorf("Login link from before rotate = %d, want 410", rr.Code)
+	}
+	sent = notifier.waitFor(t, 3)
+	if !strings.Contains(sent[2].Body, reg.AccessCode) {
+		t.Errorf("Rotate email = %+v, want new code %s", sent[2], reg.AccessCode)
+	}
+	rr = do("POST", "/api/session/login", `{"token": "`+linkToken(sent[2].Body)+`"}`)
+	json.Unmarshal(rr.Body.Bytes(), &state)
+	if rr.Code != http.StatusOK || state.AccessCode != reg.AccessCode {
+		t.Errorf("Login after rotate = %d - %s", rr.Code, rr.Body.String())
+	}
+}
+
+// blockingNotifier holds every send until release is closed
+type blockingNotifier struct {
+	release chan struct{}
+}
+
+func (n *blockingNotifier) Send(ctx context.Context, msg notify.Message) error {
+	select {
+	case <-n.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestRotateAccessCodeDoesNotWaitForEmail(t *testing.T) {
+	server, s, cleanup := setupTestServerWithAuth(t)
+	defer cleanup()
+
+	notifier := &blockingNotifier{release: make(chan struct{})}
+	defer close(notifier.release)
+	server.SetNotifier(notifier, "https://teach.example.com")
+
+	token := createTestUserToken(t, server, "rotate@example.com")
+	owner, _ := s.GetUserByEmail("rotate@example.com")
+	s.CreateWorkshop(&store.Workshop{ID: "ws-rotate", Name: "Rotate", Code: "ROTA-1234", Seats: 1, RuntimeType: "docker", Status: "created", OwnerID: owner.ID, CreatedAt: time.Now()})
+	s.CreateRegistration(&store.Registration{ID: "reg-rotate", AccessCode: "ROT-1111", Email: "ada@example.com", Name: "Ada", WorkshopID: "ws-rotate", Status: "registered", CreatedAt: time.Now()})
+
+	done := make(chan *httptest.ResponseRecorder, 1)
+	go func() {
+		req := httptest.NewRequest("POST", "/api/workshops/ws-rotate/registrations/reg-rotate/rotate-code", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		done <- rr
+	}()
+
+	// The response does not wait for the email, and seats are not held up
+	select {
+	case rr := <-done:
+		if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"emailed":true`) {
+			t.Errorf("Rotate code = %d - %s", rr.Code, rr.Body.String())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Rotate code waited for the email to be sent")
+	}
+	if !server.seatMu.TryLock() {
+		t.Fatal("Seat allocation is blocked while the email is sent")
+	}
+	server.seatMu.Unlock()
+	if reg, _ := s.GetRegistrationByID("reg-rotate"); reg.AccessCode == "ROT-1111" {
+		t.Error("Access code not rotated")
+	}
+}
+
+// webhookReceiver records webhook requests and answers with the next queued
+// status code, or 200.
+type webhookReceiver struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+	statuses []int
+}
+
+func (rcv *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	rcv.requests = append(rcv.requests, r)
+	rcv.bodies = append(rcv.bodies, body)
+	status := http.StatusOK
+	if len(rcv.statuses) > 0 {
+		status, rcv.statuses = rcv.statuses[0], rcv.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func (rcv *webhookReceiver) received() ([]*http.Request, [][]byte) {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	return append([]*http.Request(nil), rcv.requests...), append([][]byte(nil), rcv.bodies...)
+}
+
+func TestWebhooks(t *testing.T) {
+	server, s, _, cleanup := setupTestServerWithMock(t)
+	defer cleanup()
+
+	receiver := &webhookReceiver{}
+	endpoint := httptest.NewServer(receiver)
+	defer endpoint.Close()
+
+	token := createTestUserToken(t, server, "hooks@example.com")
+	owner, _ := s.GetUserByEmail("hooks@example.com")
+	adminToken := createTestAdminToken(t, s, "hooks-admin@example.com")
+	s.CreateWorkshop(&store.Workshop{ID: "ws-hooks", Name: "Go 101", Code: "HOOKS-123", Seats: 2, RuntimeType: "docker", Status: "created", OwnerID: owner.ID, CreatedAt: time.Now()})
+
+	do := func(token, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+	deliveries := func(path string) []*store.WebhookDelivery {
+		var resp struct {
+			Deliveries []*store.WebhookDelivery `json:"deliveries"`
+		}
+		rr := do(token, "GET", path, "")
+		json.Unmarshal(rr.Body.Bytes(), &resp)
+		return resp.Deliveries
+	}
+
+	if rr := do(token, "POST", "/api/workshops/ws-hooks/webhooks", `{"url": "ftp://example.com", "events": ["learner.joined"]}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("Webhook with bad URL = %d, want 400", rr.Code)
+	}
+	if rr := do(token, "POST", "/api/workshops/ws-hooks/webhooks", `{"url": "https://example.com", "events": ["learner.left"]}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("Webhook with unknown event = %d, want 400", rr.Code)
+	}
+
+	rr := do(token, "POST", "/api/workshops/ws-hooks/webhooks", `{"url": "`+endpoint.URL+`", "events": ["learner.registered", "workshop.running"], "secret": "test-secret"}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Create webhook = %d - %s", rr.Code, rr.Body.String())
+	}
+	var created struct {
+		Webhook store.Webhook `json:"webhook"`
+		Secret  string        `json:"secret"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &created)
+	if created.Secret != "test-secret" {
+		t.Errorf("Created secret = %q", created.Secret)
+	}
+	hookPath := "/api/workshops/ws-hooks/webhooks/" + created.Webhook.ID
+	if rr := do(token, "GET", "/api/workshops/ws-hooks/webhooks", ""); strings.Contains(rr.Body.String(), "test-secret") {
+		t.Error("Listing webhooks should not reveal secrets")
+	}
+
+	// Endpoints on private addresses are refused unless allowed
+	do(token, "POST", "/api/register", `{"workshop_code": "HOOKS-123", "email": "ada@example.com", "name": "Ada"}`)
+	server.deliverWebhooks(context.Background())
+	if got := deliveries(hookPath + "/deliveries"); len(got) != 1 || got[0].Status != store.WebhookDeliveryPending || !strings.Contains(got[0].LastError, "private address") {
+		t.Fatalf("Delivery to a private address = %+v, want pending with an error", got)
+	}
+	if reqs, _ := receiver.received(); len(reqs) != 0 {
+		t.Fatalf("Private endpoint received %d requests, want 0", len(reqs))
+	}
+
+	// Once allowed, a retry is delivered, signed
+	server.SetWebhookAllowPrivate(true)
+	first := deliveries(hookPath + "/deliveries")[0]
+	first.NextAttemptAt = time.Now().Add(-time.Second)
+	s.UpdateWebhookDelivery(first)
+	server.deliverWebhooks(context.Background())
+	reqs, bodies := receiver.received()
+	if len(reqs) != 1 {
+		t.Fatalf("Received %d requests, want 1", len(reqs))
+	}
+	ts, _ := strconv.ParseInt(reqs[0].Header.Get(webhook.HeaderTimestamp), 10, 64)
+	if got, want := reqs[0].Header.Get(webhook.HeaderSignature), webhook.Sign("test-secret", time.Unix(ts, 0), bodies[0]); got != want {
+		t.Errorf("Signature = %q, want %q", got, want)
+	}
+	var payload webhook.Payload
+	json.Unmarshal(bodies[0], &payload)
+	if payload.Event != webhook.EventLearnerRegistered || payload.WorkshopID != "ws-hooks" || payload.Data["email"] != "ada@example.com" {
+		t.Errorf("Payload = %+v", payload)
+	}
+	if got := deliveries(hookPath + "/deliveries"); got[0].Status != store.WebhookDeliveryDelivered || got[0].Attempts != 2 || got[0].LastStatusCode != http.StatusOK {
+		t.Errorf("Delivery after retry = %+v", got[0])
+	}
+
+	// Failed deliveries back off and are retried; unsubscribed events are not sent
+	receiver.mu.Lock()
+	receiver.statuses = []int{http.StatusInternalServerError}
+	receiver.mu.Unlock()
+	server.setWorkshopStatus("ws-hooks", "provisioning")
+	server.setWorkshopStatus("ws-hooks", "running")
+	server.deliverWebhooks(context.Background())
+	got := deliveries(hookPath + "/deliveries")
+	if len(got) != 2 || got[0].Event != webhook.EventWorkshopRunning {
+		t.Fatalf("Deliveries = %+v, want workshop.running newest", got)
+	}
+	failed := got[0]
+	if failed.Status != store.WebhookDeliveryPending || failed.Attempts != 1 || failed.LastStatusCode != http.StatusInternalServerError || time.Until(failed.NextAttemptAt) < 20*time.Second {
+		t.Errorf("Failed delivery = %+v, want pending with backoff", failed)
+	}
+	server.deliverWebhooks(context.Background())
+	if reqs, _ := receiver.received(); len(reqs) != 2 {
+		t.Errorf("Delivery was retried before its backoff: %d requests", len(reqs))
+	}
+	failed.NextAttemptAt = time.Now().Add(-time.Second)
+	s.UpdateWebhookDelivery(failed)
+	server.deliverWebhooks(context.Background())
+	if got := deliveries(hookPath + "/deliveries"); got[0].Status != store.WebhookDeliveryDelivered || got[0].Attempts != 2 {
+		t.Errorf("Delivery after backoff = %+v", got[0])
+	}
+
+	// Delivered events can be sent again
+	if rr := do(token, "POST", fmt.Sprintf("%s/deliveries/%d/redeliver", hookPath, failed.ID), ""); rr.Code != http.StatusOK {
+		t.Fatalf("Redeliver = %d - %s", rr.Code, rr.Body.String())
+	}
+	server.deliverWebhooks(context.Background())
+	if reqs, _ := receiver.received(); len(reqs) != 4 {
+		t.Errorf("Received %d requests after redeliver, want 4", len(reqs))
+	}
+
+	// Global webhooks are admin only and receive every workshop's events
+	if rr := do(token, "POST", "/api/admin/webhooks", `{"url": "`+endpoint.URL+`", "events": ["workshop.error"]}`); rr.Code != http.StatusForbidden {
+		t.Errorf("Global webhook as instructor = %d, want 403", rr.Code)
+	}
+	rr = do(adminToken, "POST", "/api/admin/webhooks", `{"url": "`+endpoint.URL+`", "events": ["workshop.error"]}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Create global webhook = %d - %s", rr.Code, rr.Body.String())
+	}
+	var global struct {
+		Webhook store.Webhook `json:"webhook"`
+		Secret  string        `json:"secret"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &global)
+	if !strings.HasPrefix(global.Secret, "whsec_") || global.Webhook.WorkshopID != "" {
+		t.Errorf("Global webhook = %+v, secret %q", global.Webhook, global.Secret)
+	}
+	if rr := do(token, "DELETE", "/api/workshops/ws-hooks/webhooks/"+global.Webhook.ID, ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Deleting a global webhook from a workshop = %d, want 404", rr.Code)
+	}
+	server.setWorkshopStatus("ws-hooks", "error")
+	server.deliverWebhooks(context.Background())
+	reqs, bodies = receiver.received()
+	if len(reqs) != 5 || reqs[4].Header.Get(webhook.HeaderEvent) != webhook.EventWorkshopError {
+		t.Fatalf("Global webhook requests = %d, want workshop.error fifth", len(reqs))
+	}
+	json.Unmarshal(bodies[4], &payload)
+	if payload.Data["name"] != "Go 101" || payload.Data["status"] != "error" {
+		t.Errorf("Global payload = %+v", payload)
+	}
+
+	if rr := do(token, "DELETE", hookPath, ""); rr.Code != http.StatusOK {
+		t.Errorf("Delete webhook = %d", rr.Code)
+	}
+	if h, _ := s.GetWebhook(created.Webhook.ID); h != nil {
+		t.Error("Webhook still exists after delete")
+	}
+}
+
+// testLTIPlatform stands in for an LMS: it publishes a key set and signs the
+// ID tokens it launches tools with.
+type testLTIPlatform struct {
+	key      *rsa.PrivateKey
+	keySet   *httptest.Server
+	issuer   string
+	clientID string
+}
+
+func newTestLTIPlatform(t *testing.T) *testLTIPlatform {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate platform key: %v", err)
+	}
+	p := &testLTIPlatform{key: key, issuer: "https://lms.example.edu", clientID: "tool-client-1"}
+	p.keySet = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "platform-key",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	}))
+	t.Cleanup(p.keySet.Close)
+	return p
+}
+
+// idToken signs a launch for the nonce with the platform's standard claims
+// overridden by claims.
+func (p *testLTIPlatform) idToken(t *testing.T, key *rsa.PrivateKey, nonce string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.MapClaims{
+		"iss":                 p.issuer,
+		"aud":                 p.clientID,
+		"sub":                 "lms-user-1",
+		"iat":                 time.Now().Unix(),
+		"exp":                 time.Now().Add(5 * time.Minute).Unix(),
+		"nonce":               nonce,
+		lti.ClaimVersion:      lti.Version,
+		lti.ClaimDeploymentID: "deploy-1",
+		lti.ClaimMessageType:  lti.MessageResourceLink,
+		lti.ClaimResourceLink: map[string]string{"id": "link-1"},
+		lti.ClaimContext:      map[string]string{"id": "course-1", "title": "CS 101"},
+		lti.ClaimRoles:        []string{"http://purl.imsglobal.org/vocab/lis/v2/membership#Learner"},
+	}
+	for k, v := range claims {
+		token[k] = v
+	}
+	signed := jwt.NewWithClaims(jwt.SigningMethodRS256, token)
+	signed.Header["kid"] = "platform-key"
+	s, err := signed.SignedString(key)
+	if err != nil {
+		t.Fatalf("Failed to sign id_token: %v", err)
+	}
+	return s
+}
+
+func TestLTILaunch(t *testing.T) {
+	server, s, _, cleanup := setupTestServerWithMock(t)
+	defer cleanup()
+
+	// Without a key LTI is off
+	req := httptest.NewRequest("GET", "/api/lti/jwks", nil)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("JWKS without LTI = %d, want 503", rr.Code)
+	}
+
+	toolKey, err := lti.GenerateToolKey()
+	if err != nil {
+		t.Fatalf("GenerateToolKey() error = %v", err)
+	}
+	server.SetLTI(toolKey, "https://tool.example.com", "https://teach.example.com")
+	platform := newTestLTIPlatform(t)
+
+	adminToken := createTestAdminToken(t, s, "lti-admin@example.com")
+	instructorToken := createTestUserToken(t, server, "prof@example.edu")
+	prof, _ := s.GetUserByEmail("prof@example.edu")
+	s.CreateWorkshop(&store.Workshop{ID: "ws-lti", Name: "Linux Basics", Code: "LTI-123", Seats: 2, RuntimeType: "docker", Status: "created", OwnerID: prof.ID, CreatedAt: time.Now()})
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Only admins register platforms
+	body := `{"name": "Test LMS", "issuer": "` + platform.issuer + `", "client_id": "` + platform.clientID + `",
+		"auth_login_url": "https://lms.example.edu/auth", "jwks_url": "` + platform.keySet.URL + `", "deployment_ids": ["deploy-1"]}`
+	if rr := do("POST", "/api/admin/lti/platforms", instructorToken, body); rr.Code != http.StatusForbidden {
+		t.Errorf("Create platform as instructor = %d, want 403", rr.Code)
+	}
+	rr = do("POST", "/api/admin/lti/platforms", adminToken, body)
+	if rr.Code != http.StatusCreated || !strings.Contains(rr.Body.String(), `"trust_email":false`) {
+		t.Fatalf("Create platform = %d - %s", rr.Code, rr.Body.String())
+	}
+	var registered struct {
+		Platform store.LTIPlatform `json:"platform"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &registered)
+	if rr := do("POST", "/api/admin/lti/platforms", adminToken, body); rr.Code != http.StatusConflict {
+		t.Errorf("Duplicate platform = %d, want 409", rr.Code)
+	}
+	rr = do("GET", "/api/admin/lti/platforms", adminToken, "")
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"launch_url":"https://tool.example.com/api/lti/launch"`) {
+		t.Errorf("List platforms = %d - %s", rr.Code, rr.Body.String())
+	}
+
+	// login runs OIDC login initiation and returns the state and nonce the
+	// platform is sent back with
+	login := func() (string, string) {
+		t.Helper()
+		query := url.Values{"iss": {platform.issuer}, "login_hint": {"lms-user-1"}, "client_id": {platform.clientID}, "target_link_uri": {"https://tool.example.com/api/lti/launch"}}
+		rr := do("GET", "/api/lti/login?"+query.Encode(), "", "")
+		if rr.Code != http.StatusFound {
+			t.Fatalf("LTI login = %d - %s", rr.Code, rr.Body.String())
+		}
+		location, _ := url.Parse(rr.Header().Get("Location"))
+		auth := location.Query()
+		if location.Host != "lms.example.edu" || auth.Get("redirect_uri") != "https://tool.example.com/api/lti/launch" || auth.Get("response_mode") != "form_post" || auth.Get("login_hint") != "lms-user-1" {
+			t.Fatalf("LTI login redirect = %s", location)
+		}
+		return auth.Get("state"), auth.Get("nonce")
+	}
+	post := func(state, idToken string) *httptest.ResponseRecorder {
+		form := url.Values{"state": {state}, "id_token": {idToken}}
+		req := httptest.NewRequest("POST", "/api/lti/launch", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+	launch := func(claims jwt.MapClaims) *httptest.ResponseRecorder {
+		state, nonce := login()
+		return post(state, platform.idToken(t, platform.key, nonce, claims))
+	}
+
+	// Unknown platforms cannot start a login
+	if rr := do("GET", "/api/lti/login?iss=https://evil.example.com&login_hint=x", "", ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("Login from unknown platform = %d, want 400", rr.Code)
+	}
+
+	// Before an instructor links the course, students have nowhere to go
+	if rr := launch(jwt.MapClaims{"email": "student@example.edu", "name": "Stu Dent"}); rr.Code != http.StatusNotFound {
+		t.Errorf("Launch into unlinked course = %d, want 404 - %s", rr.Code, rr.Body.String())
+	}
+
+	// Deep linking: the instructor picks the workshop
+	deepLinking := jwt.MapClaims{
+		"email":                      "prof@example.edu",
+		lti.ClaimMessageType:         lti.MessageDeepLinkingRequest,
+		lti.ClaimRoles:               []string{"http://purl.imsglobal.org/vocab/lis/v2/membership#Instructor"},
+		lti.ClaimDeepLinkingSettings: map[string]interface{}{"deep_link_return_url": "https://lms.example.edu/deep_links", "accept_types": []string{"ltiResourceLink"}, "data": "opaque-data"},
+	}
+
+	// Until the platform is trusted to vouch for emails, its instructors
+	// cannot act as the ClaraTeach account with their LMS email
+	if rr := launch(deepLinking); rr.Code != http.StatusForbidden || !strings.Contains(rr.Body.String(), "trust_email") {
+		t.Errorf("Deep linking from an untrusted platform = %d - %s, want 403", rr.Code, rr.Body.String())
+	}
+	platformPath := "/api/admin/lti/platforms/" + registered.Platform.ID
+	if rr := do("PATCH", platformPath, instructorToken, `{"trust_email": true}`); rr.Code != http.StatusForbidden {
+		t.Errorf("Trust platform as instructor = %d, want 403", rr.Code)
+	}
+	if rr := do("PATCH", "/api/admin/lti/platforms/lti-missing", adminToken, `{"trust_email": true}`); rr.Code != http.StatusNotFound {
+		t.Errorf("Trust missing platform = %d, want 404", rr.Code)
+	}
+	if rr := do("PATCH", platformPath, adminToken, `{}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("Update platform without trust_email = %d, want 400", rr.Code)
+	}
+	if rr := do("PATCH", platformPath, adminToken, `{"trust_email": true}`); rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"trust_email":true`) {
+		t.Fatalf("Trust platform = %d - %s", rr.Code, rr.Body.String())
+	}
+
+	state, nonce := login()
+	idToken := platform.idToken(t, platform.key, nonce, deepLinking)
+	rr = post(state, idToken)
+	if rr.Code != http.StatusSeeOther || !strings.HasPrefix(rr.Header().Get("Location"), "https://teach.example.com/lti/deep-link/") {
+		t.Fatalf("Deep linking launch = %d %s - %s", rr.Code, rr.Header().Get("Location"), rr.Body.String())
+	}
+	session := strings.TrimPrefix(rr.Header().Get("Location"), "https://teach.example.com/lti/deep-link/")
+	if rr := post(state, idToken); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Replayed launch = %d, want 401", rr.Code)
+	}
+
+	rr = do("POST", "/api/lti/deep-link/workshops", "", `{"token": "`+session+`"}`)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"ws-lti"`) || !strings.Contains(rr.Body.String(), `"context_title":"CS 101"`) {
+		t.Fatalf("Deep link workshops = %d - %s", rr.Code, rr.Body.String())
+	}
+	rr = do("POST", "/api/lti/deep-link", "", `{"token": "`+session+`", "workshop_id": "ws-lti"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Deep link = %d - %s", rr.Code, rr.Body.String())
+	}
+	var linked struct {
+		ReturnURL string `json:"return_url"`
+		JWT       string `json:"jwt"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &linked)
+	if linked.ReturnURL != "https://lms.example.edu/deep_links" {
+		t.Errorf("Deep link return URL = %q", linked.ReturnURL)
+	}
+	response := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(linked.JWT, response, func(*jwt.Token) (interface{}, error) { return toolKey.PublicKey(), nil }, jwt.WithAudience(platform.issuer), jwt.WithIssuer(platform.clientID)); err != nil {
+		t.Fatalf("Deep linking response does not verify: %v", err)
+	}
+	items, _ := json.Marshal(response[lti.ClaimContentItems])
+	if response[lti.ClaimMessageType] != lti.MessageDeepLinkingResponse || response[lti.ClaimData] != "opaque-data" || !strings.Contains(string(items), `"workshop_id":"ws-lti"`) {
+		t.Errorf("Deep linking response = %+v", response)
+	}
+	rr = do("GET", "/api/lti/jwks", "", "")
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"kid":"`+toolKey.ID+`"`) {
+		t.Errorf("Tool JWKS = %d - %s", rr.Code, rr.Body.String())
+	}
+
+	// Other instructors cannot link workshops they do not run
+	other := &store.User{ID: "user-other", Email: "other@example.edu", Name: "Other", Role: "instructor", CreatedAt: time.Now()}
+	s.CreateUser(other)
+	otherSession, _ := auth.GenerateLTIDeepLinkToken(other.ID, auth.LTIDeepLinkClaims{PlatformID: "x", ReturnURL: "https://lms.example.edu/deep_links"}, time.Hour)
+	if rr := do("POST", "/api/lti/deep-link", "", `{"token": "`+otherSession+`", "workshop_id": "ws-lti"}`); rr.Code != http.StatusNotFound {
+		t.Errorf("Deep link to another's workshop = %d, want 404", rr.Code)
+	}
+
+	// Students launching from the linked course are registered and sent to
+	// their seat, the same one each time
+	student := jwt.MapClaims{"email": "student@example.edu", "given_name": "Stu", "family_name": "Dent"}
+	rr = launch(student)
+	if rr.Code != http.StatusSeeOther || !strings.HasPrefix(rr.Header().Get("Location"), "https://teach.example.com/s/") {
+		t.Fatalf("Student launch = %d %s - %s", rr.Code, rr.Header().Get("Location"), rr.Body.String())
+	}
+	reg, _ := s.GetRegistrationByEmail("ws-lti", "student@example.edu")
+	if reg == nil || reg.Name != "Stu Dent" || rr.Header().Get("Location") != "https://teach.example.com/s/"+reg.AccessCode {
+		t.Fatalf("Registration from launch = %+v, redirect %s", reg, rr.Header().Get("Location"))
+	}
+	if rr := launch(student); rr.Header().Get("Location") != "https://teach.example.com/s/"+reg.AccessCode {
+		t.Errorf("Second student launch redirect = %s, want same seat", rr.Header().Get("Location"))
+	}
+
+	// The custom parameter from deep linking works from the course it was
+	// linked into, but not when copied to another course or edited to name a
+	// workshop that was never linked
+	rr = launch(jwt.MapClaims{"email": "other-student@example.edu", lti.ClaimCustom: map[string]string{"workshop_id": "ws-lti"}})
+	if rr.Code != http.StatusSeeOther {
+		t.Errorf("Launch with custom workshop_id = %d - %s", rr.Code, rr.Body.String())
+	}
+	if rr := launch(jwt.MapClaims{"email": "third-student@example.edu", lti.ClaimContext: map[string]string{"id": "course-2"}, lti.ClaimCustom: map[string]string{"workshop_id": "ws-lti"}}); rr.Code != http.StatusForbidden {
+		t.Errorf("Launch with custom workshop_id from another course = %d, want 403", rr.Code)
+	}
+	s.CreateWorkshop(&store.Workshop{ID: "ws-lti-private", Name: "Private", Code: "LTI-456", Seats: 2, RuntimeType: "docker", Status: "created", OwnerID: prof.ID, CreatedAt: time.Now()})
+	if rr := launch(jwt.MapClaims{"email": "third-student@example.edu", lti.ClaimCustom: map[string]string{"workshop_id": "ws-lti-private"}}); rr.Code != http.StatusForbidden {
+		t.Errorf("Launch with custom workshop_id never linked = %d, want 403", rr.Code)
+	}
+	if reg, _ := s.GetRegistrationByEmail("ws-lti-private", "third-student@example.edu"); reg != nil {
+		t.Errorf("Registration from a refused launch = %+v", reg)
+	}
+
+	// Instructors go to the dashboard instead of taking a seat
+	rr = launch(jwt.MapClaims{"email": "prof@example.edu", lti.ClaimRoles: []string{"http://purl.imsglobal.org/vocab/lis/v2/membership#Instructor"}})
+	if rr.Header().Get("Location") != "https://teach.example.com/workshop/ws-lti" {
+		t.Errorf("Instructor launch = %d %s", rr.Code, rr.Header().Get("Location"))
+	}
+
+	// Forged tokens, unknown deployments and students without an email are refused
+	forger, _ := rsa.GenerateKey(rand.Reader, 2048)
+	state, nonce = login()
+	if rr := post(state, platform.idToken(t, forger, nonce, student)); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Launch signed with another key = %d, want 401", rr.Code)
+	}
+	if rr := launch(jwt.MapClaims{"email": "student@example.edu", lti.ClaimDeploymentID: "deploy-2"}); rr.Code != http.StatusForbidden {
+		t.Errorf("Launch from unknown deployment = %d, want 403", rr.Code)
+	}
+	if rr := launch(jwt.MapClaims{}); rr.Code != http.StatusBadRequest {
+		t.Errorf("Launch without email = %d, want 400", rr.Code)
+	}
+	state, nonce = login()
+	if rr := post("not-a-state", platform.idToken(t, platform.key, nonce, student)); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Launch with invalid state = %d, want 401", rr.Code)
+	}
+	if rr := post(state, platform.idToken(t, platform.key, "wrong-nonce", student)); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Launch with mismatched nonce = %d, want 401", rr.Code)
+	}
+}
+
+// testOIDCIssuer is a local OIDC provider: it serves discovery, a key set
+// and a token endpoint that checks the PKCE verifier before handing out an
+// ID token with the claims registered for the code.
+type testOIDCIssuer struct {
+	*httptest.Server
+	key   *rsa.PrivateKey
+	mu    sync.Mutex
+	codes map[string]testOIDCCode
+}
+
+type testOIDCCode struct {
+	nonce, challenge string
+	claims           jwt.MapClaims
+}
+
+func newTestOIDCIssuer(t *testing.T) *testOIDCIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate issuer key: %v", err)
+	}
+	issuer := &testOIDCIssuer{key: key, codes: make(map[string]testOIDCCode)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 issuer.URL,
+			"authorization_endpoint": issuer.URL + "/authorize",
+			"token_endpoint":         issuer.URL + "/token",
+			"jwks_uri":               issuer.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "issuer-key",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		clientID, secret, _ := r.BasicAuth()
+		r.ParseForm()
+		issuer.mu.Lock()
+		code, ok := issuer.codes[r.PostForm.Get("code")]
+		delete(issuer.codes, r.PostForm.Get("code"))
+		issuer.mu.Unlock()
+		if clientID != "teach-client" || secret != "teach-secret" || !ok || oidc.Challenge(r.PostForm.Get("code_verifier")) != code.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		token := jwt.MapClaims{
+			"iss":   issuer.URL,
+			"aud":   "teach-client",
+			"iat":   time.Now().Unix(),
+			"exp":   time.Now().Add(5 * time.Minute).Unix(),
+			"nonce": code.nonce,
+		}
+		for k, v := range code.claims {
+			token[k] = v
+		}
+		signed := jwt.NewWithClaims(jwt.SigningMethodRS256, token)
+		signed.Header["kid"] = "issuer-key"
+		idToken, _ := signed.SignedString(key)
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "access", "token_type": "Bearer", "id_token": idToken})
+	})
+	issuer.Server = httptest.NewServer(mux)
+	t.Cleanup(issuer.Close)
+	return issuer
+}
+
+// signIn runs a sign-in through the server as the user in claims and
+// returns the callback's response. tamper may change the authorization
+// request's parameters before the issuer records them.
+func (i *testOIDCIssuer) signIn(t *testing.T, server *Server, loginURL string, claims jwt.MapClaims, tamper func(url.Values)) *httptest.ResponseRecorder {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, httptest.NewRequest("GET", loginURL, nil))
+	if rr.Code != http.StatusFound {
+		t.Fatalf("OIDC login = %d, want 302: %s", rr.Code, rr.Body.String())
+	}
+	authorize, err := url.Parse(rr.Header().Get("Location"))
+	if err != nil || !strings.HasPrefix(authorize.String(), i.URL+"/authorize") {
+		t.Fatalf("OIDC login redirected to %q", rr.Header().Get("Location"))
+	}
+	params := authorize.Query()
+	if params.Get("code_challenge_method") != "S256" || params.Get("code_challenge") == "" {
+		t.Errorf("Authorization request without PKCE: %v", params)
+	}
+	if tamper != nil {
+		tamper(params)
+	}
+	code := generateID(12)
+	i.mu.Lock()
+	i.codes[code] = testOIDCCode{nonce: params.Get("nonce"), challenge: params.Get("code_challenge"), claims: claims}
+	i.mu.Unlock()
+
+	callback := params.Get("redirect_uri") + "?" + url.Values{"code": {code}, "state": {params.Get("state")}}.Encode()
+	req := httptest.NewRequest("GET", strings.TrimPrefix(callback, "https://api.teach.example.com"), nil)
+	for _, c := range rr.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	result := httptest.NewRecorder()
+	server.router.ServeHTTP(result, req)
+	return result
+}
+
+// oidcSessionUser returns the user a successful callback signed in.
+func oidcSessionUser(t *testing.T, rr *httptest.ResponseRecorder) *auth.Claims {
+	t.Helper()
+	location, err := url.Parse(rr.Header().Get("Location"))
+	if rr.Code != http.StatusFound || err != nil || location.Path != "/auth/callback" {
+		t.Fatalf("OIDC callback = %d to %q, want a session", rr.Code, rr.Header().Get("Location"))
+	}
+	fragment, _ := url.ParseQuery(location.Fragment)
+	if fragment.Get("refresh_token") == "" {
+		t.Errorf("OIDC callback fragment has no refresh token")
+	}
+	token := fragment.Get("token")
+	claims, err := auth.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("OIDC session token invalid: %v", err)
+	}
+	return claims
+}
+
+// oidcLinkToken returns the verified account a linking callback handed back.
+func oidcLinkToken(t *testing.T, rr *httptest.ResponseRecorder) string {
+	t.Helper()
+	location, err := url.Parse(rr.Header().Get("Location"))
+	if rr.Code != http.StatusFound || err != nil || location.Path != "/auth/callback" {
+		t.Fatalf("OIDC link callback = %d to %q, want an account to confirm", rr.Code, rr.Header().Get("Location"))
+	}
+	fragment, _ := url.ParseQuery(location.Fragment)
+	if fragment.Get("token") != "" || fragment.Get("link") == "" {
+		t.Fatalf("OIDC link callback fragment = %q, want only a link token", location.Fragment)
+	}
+	return fragment.Get("link")
+}
+
+func oidcError(rr *httptest.ResponseRecorder) string {
+	location, err := url.Parse(rr.Header().Get("Location"))
+	if err != nil || location.Path != "/login" {
+		return ""
+	}
+	return location.Query().Get("sso_error")
+}
+
+func TestOIDCSignIn(t *testing.T) {
+	server, s, cleanup := setupTestServerWithAuth(t)
+	defer cleanup()
+
+	issuer := newTestOIDCIssuer(t)
+	providers, err := oidc.ParseProviders(`[{
+		"id": "corp", "name": "Corp SSO", "issuer": "` + issuer.URL + `",
+		"client_id": "teach-client", "client_secret": "teach-secret",
+		"allowed_domains": ["example.com"],
+		"role_claim": "realm_access.roles", "admin_values": ["teach-admin"]
+	}, {
+		"id": "trusted", "name": "Trusted SSO", "issuer": "` + issuer.URL + `",
+		"client_id": "teach-client", "client_secret": "teach-secret",
+		"role_claim": "realm_access.roles", "admin_values": ["teach-admin"],
+		"trust_email": true
+	}]`)
+	if err != nil {
+		t.Fatalf("ParseProviders() error = %v", err)
+	}
+	server.SetOIDC(providers, "https://api.teach.example.com", "https://teach.example.com")
+	loginURL := "/api/auth/oidc/corp/login"
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/auth/oidc/providers", nil))
+	if !strings.Contains(rr.Body.String(), `"login_url":"https://api.teach.example.com/api/auth/oidc/corp/login"`) {
+		t.Errorf("Providers = %s", rr.Body.String())
+	}
+
+	// A verified email in an allowed domain gets a new instructor account
+	newUser := jwt.MapClaims{"sub": "corp-1", "email": "grace@example.com", "email_verified": true, "name": "Grace Hopper"}
+	session := oidcSessionUser(t, issuer.signIn(t, server, loginURL, newUser, nil))
+	user, _ := s.GetUser(session.UserID)
+	if user == nil || user.Email != "grace@example.com" || user.Name != "Grace Hopper" || user.Role != "instructor" || user.PasswordHash != "" {
+		t.Fatalf("Provisioned user = %+v", user)
+	}
+	if again := oidcSessionUser(t, issuer.signIn(t, server, loginURL, newUser, nil)); again.UserID != user.ID {
+		t.Errorf("Second sign-in user = %s, want %s", again.UserID, user.ID)
+	}
+
+	// A password account with the same verified email is not signed in by
+	// default: its owner has to link the provider account
+	passwordToken := createTestUserToken(t, server, "ada@example.com")
+	passwordUser, _ := s.GetUserByEmail("ada@example.com")
+	admin := jwt.MapClaims{"sub": "corp-2", "email": "ada@example.com", "email_verified": true,
+		"realm_access": map[string]interface{}{"roles": []string{"teach-admin"}}}
+	if rr := issuer.signIn(t, server, loginURL, admin, nil); !strings.Contains(oidcError(rr), "link") {
+		t.Errorf("Sign-in to an existing account = %q, want an sso_error asking to link", rr.Header().Get("Location"))
+	}
+	if identity, _ := s.GetUserIdentity("corp", "corp-2"); identity != nil {
+		t.Errorf("Identity after refused sign-in = %+v, want none", identity)
+	}
+
+	// With trust_email it is linked, and the role claim makes it an admin
+	trustedURL := "/api/auth/oidc/trusted/login"
+	if session := oidcSessionUser(t, issuer.signIn(t, server, trustedURL, admin, nil)); session.UserID != passwordUser.ID || session.Role != "admin" {
+		t.Errorf("Linked sign-in = %+v, want %s as admin", session, passwordUser.ID)
+	}
+	if identity, _ := s.GetUserIdentity("trusted", "corp-2"); identity == nil || identity.UserID != passwordUser.ID {
+		t.Errorf("Identity after linking = %+v", identity)
+	}
+	// Losing the admin role at the provider demotes the user on next sign-in
+	delete(admin, "realm_access")
+	if session := oidcSessionUser(t, issuer.signIn(t, server, trustedURL, admin, nil)); session.Role != "instructor" {
+		t.Errorf("Role after leaving the admin group = %s, want instructor", session.Role)
+	}
+
+	denied := []struct {
+		name   string
+		claims jwt.MapClaims
+		tamper func(url.Values)
+	}{
+		{"unverified email", jwt.MapClaims{"sub": "corp-3", "email": "eve@example.com", "email_verified": false}, nil},
+		{"domain not allowed", jwt.MapClaims{"sub": "corp-4", "email": "eve@evil.test", "email_verified": true}, nil},
+		{"state mismatch", newUser, func(v url.Values) { v.Set("state", "forged") }},
+		{"wrong verifier", newUser, func(v url.Values) { v.Set("code_challenge", oidc.Challenge("guessed")) }},
+		{"wrong nonce", newUser, func(v url.Values) { v.Set("nonce", "replayed") }},
+	}
+	for _, tc := range denied {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := issuer.signIn(t, server, loginURL, tc.claims, tc.tamper)
+			if oidcError(rr) == "" {
+				t.Errorf("Sign-in = %d to %q, want an sso_error", rr.Code, rr.Header().Get("Location"))
+			}
+		})
+	}
+
+	// A signed-in user links an account with a different email
+	req := httptest.NewRequest("POST", "/api/auth/oidc/corp/link", nil)
+	otherToken := createTestUserToken(t, server, "linus@other.test")
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	var link struct {
+		URL string `json:"url"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &link)
+	if rr.Code != http.StatusOK || !strings.Contains(link.URL, "?link=") {
+		t.Fatalf("Link = %d: %s", rr.Code, rr.Body.String())
+	}
+	linkURL := strings.TrimPrefix(link.URL, "https://api.teach.example.com")
+	otherUser, _ := s.GetUserByEmail("linus@other.test")
+	complete := func(token, linkToken string) int {
+		req := httptest.NewRequest("POST", "/api/auth/oidc/corp/link/complete", strings.NewReader(`{"token": "`+linkToken+`"}`))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// The callback hands back the verified account without linking it yet;
+	// only the user who started the link can confirm it, so a leaked link URL
+	// is of no use to anyone else
+	work := jwt.MapClaims{"sub": "corp-5", "email": "linus@example.com", "email_verified": true}
+	workLink := oidcLinkToken(t, issuer.signIn(t, server, linkURL, work, nil))
+	if identity, _ := s.GetUserIdentity("corp", "corp-5"); identity != nil {
+		t.Errorf("Identity before confirming = %+v, want none", identity)
+	}
+	if code := complete("", workLink); code != http.StatusUnauthorized {
+		t.Errorf("Confirm link signed out = %d, want 401", code)
+	}
+	if code := complete(passwordToken, workLink); code != http.StatusForbidden {
+		t.Errorf("Confirm link as another user = %d, want 403", code)
+	}
+	if code := complete(otherToken, workLink); code != http.StatusOK {
+		t.Fatalf("Confirm link = %d", code)
+	}
+	if session := oidcSessionUser(t, issuer.signIn(t, server, loginURL, work, nil)); session.UserID != otherUser.ID {
+		t.Errorf("Linked account signed in as %s, want %s", session.UserID, otherUser.ID)
+	}
+	// The provider account is taken, so nobody else can link it
+	if code := complete(otherToken, oidcLinkToken(t, issuer.signIn(t, server, linkURL, newUser, nil))); code != http.StatusConflict {
+		t.Errorf("Linking another user's account = %d, want 409", code)
+	}
+
+	// Password accounts can unlink; SSO-only accounts cannot lose their only sign-in
+	unlink := func(token string) int {
+		req := httptest.NewRequest("DELETE", "/api/auth/identities/trusted", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+	ssoToken, _ := auth.GenerateToken(user)
+	req = httptest.NewRequest("DELETE", "/api/auth/identities/corp", nil)
+	req.Header.Set("Authorization", "Bearer "+ssoToken)
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusConflict {
+		t.Errorf("Unlinking the only sign-in = %d, want 409", rr.Code)
+	}
+	if code := unlink(passwordToken); code != http.StatusOK {
+		t.Errorf("Unlinking from a password account = %d, want 200", code)
+	}
+	if code := unlink(passwordToken); code != http.StatusNotFound {
+		t.Errorf("Unlinking twice = %d, want 404", code)
+	}
+}
+
+func TestRefreshTokenSessions(t *testing.T) {
+	server, s, cleanup := setupTestServerWithAuth(t)
+	defer cleanup()
+
+	call := func(method, path, token string, body interface{}) *httptest.ResponseRecorder {
+		t.Helper()
+		var b []byte
+		if body != nil {
+			b, _ = json.Marshal(body)
+		}
+		req := httptest.NewRequest(method, path, bytes.NewReader(b))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+	type tokens struct {
+		Token        string    `json:"token"`
+		RefreshToken string    `json:"refresh_token"`
+		ExpiresAt    time.Time `json:"expires_at"`
+	}
+	login := func(email string) tokens {
+		t.Helper()
+		rr := call("POST", "/api/auth/login", "", map[string]string{"email": email, "password": "password123"})
+		var got tokens
+		json.Unmarshal(rr.Body.Bytes(), &got)
+		if rr.Code != http.StatusOK || got.Token == "" || got.RefreshToken == "" || got.ExpiresAt.IsZero() {
+			t.Fatalf("Login = %d %s, want tokens", rr.Code, rr.Body.String())
+		}
+		return got
+	}
+	refresh := func(refreshToken string) (tokens, int) {
+		t.Helper()
+		rr := call("POST", "/api/auth/refresh", "", map[string]string{"refresh_token": refreshToken})
+		var got tokens
+		json.Unmarshal(rr.Body.Bytes(), &got)
+		return got, rr.Code
+	}
+	authorized := func(token string) bool {
+		t.Helper()
+		return call("GET", "/api/auth/me", token, nil).Code == http.StatusOK
+	}
+
+	createTestUserToken(t, server, "ada@example.com")
+	session := login("ada@example.com")
+	if !authorized(session.Token) {
+		t.Fatal("Access token from login was rejected")
+	}
+
+	// Refreshing rotates the refresh token within the same session
+	rotated, code := refresh(session.RefreshToken)
+	if code != http.StatusOK || rotated.RefreshToken == session.RefreshToken || !authorized(rotated.Token) {
+		t.Fatalf("Refresh = %d %+v, want new tokens", code, rotated)
+	}
+	first, _ := auth.ValidateToken(session.Token)
+	second, _ := auth.ValidateToken(rotated.Token)
+	if first.SessionID == "" || first.SessionID != second.SessionID || first.ID == second.ID {
+		t.Errorf("Session IDs = %q and %q, token IDs = %q and %q", first.SessionID, second.SessionID, first.ID, second.ID)
+	}
+	// A refresh racing the one before it fails without ending the session
+	if _, code := refresh(session.RefreshToken); code != http.StatusUnauthorized {
+		t.Errorf("Refresh with a just-used token = %d, want 401", code)
+	}
+	if !authorized(rotated.Token) {
+		t.Error("Concurrent refresh ended the session")
+	}
+	if _, code := refresh("not-a-token"); code != http.StatusUnauthorized {
+		t.Errorf("Refresh with an unknown token = %d, want 401", code)
+	}
+
+	// Reusing a refresh token used a while ago ends the session
+	user, _ := s.GetUserByEmail("ada@example.com")
+	stolen, stolenID, _ := auth.NewRefreshToken()
+	now := time.Now()
+	s.CreateRefreshToken(&store.RefreshToken{
+		ID: stolenID, SessionID: second.SessionID, UserID: user.ID, AccessTokenID: "jti-stolen",
+		AccessExpiresAt: now.Add(time.Minute), ExpiresAt: now.Add(time.Hour), CreatedAt: now.Add(-time.Hour),
+	})
+	s.UseRefreshToken(stolenID, now.Add(-time.Minute))
+	if _, code := refresh(stolen); code != http.StatusUnauthorized {
+		t.Errorf("Refresh with a reused token = %d, want 401", code)
+	}
+	if authorized(rotated.Token) {
+		t.Error("Access token still works after its refresh token was reused")
+	}
+	if _, code := refresh(rotated.RefreshToken); code != http.StatusUnauthorized {
+		t.Errorf("Refresh after reuse = %d, want 401", code)
+	}
+
+	// Logging out ends only that session
+	phone, laptop := login("ada@example.com"), login("ada@example.com")
+	if rr := call("POST", "/api/auth/logout", phone.Token, map[string]string{"refresh_token": phone.RefreshToken}); rr.Code != http.StatusOK {
+		t.Fatalf("Logout = %d %s", rr.Code, rr.Body.String())
+	}
+	if authorized(phone.Token) {
+		t.Error("Access token still works after logout")
+	}
+	if _, code := refresh(phone.RefreshToken); code != http.StatusUnauthorized {
+		t.Errorf("Refresh after logout = %d, want 401", code)
+	}
+	if !authorized(laptop.Token) {
+		t.Error("Logout ended another session")
+	}
+
+	// Logging out everywhere ends every session
+	tablet := login("ada@example.com")
+	if rr := call("POST", "/api/auth/logout-all", laptop.Token, nil); rr.Code != http.StatusOK {
+		t.Fatalf("Logout all = %d %s", rr.Code, rr.Body.String())
+	}
+	if authorized(laptop.Token) || authorized(tablet.Token) {
+		t.Error("Access tokens still work after logging out everywhere")
+	}
+	if _, code := refresh(tablet.RefreshToken); code != http.StatusUnauthorized {
+		t.Errorf("Refresh after logging out everywhere = %d, want 401", code)
+	}
+
+	// Only admins manage users, and they cannot lock themselves out
+	adminToken := createTestAdminToken(t, s, "admin@example.com")
+	session = login("ada@example.com")
+	userToken, _ := auth.GenerateToken(user)
+	path := "/api/admin/users/" + user.ID
+	if rr := call("PATCH", path, session.Token, map[string]bool{"disabled": true}); rr.Code != http.StatusForbidden {
+		t.Errorf("Disable as instructor = %d, want 403", rr.Code)
+	}
+	if rr := call("PATCH", "/api/admin/users/admin-admin@example.com", adminToken, map[string]bool{"disabled": true}); rr.Code != http.StatusBadRequest {
+		t.Errorf("Disable self = %d, want 400", rr.Code)
+	}
+	if rr := call("PATCH", "/api/admin/users/user-missing", adminToken, map[string]bool{"disabled": true}); rr.Code != http.StatusNotFound {
+		t.Errorf("Disable unknown user = %d, want 404", rr.Code)
+	}
+
+	// Disabling a user signs them out and keeps them out
+	rr := call("PATCH", path, adminToken, map[string]bool{"disabled": true})
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"disabled":true`) {
+		t.Fatalf("Disable = %d %s", rr.Code, rr.Body.String())
+	}
+	if authorized(session.Token) || authorized(userToken) {
+		t.Error("Access tokens still work after the user was disabled")
+	}
+	if _, code := refresh(session.RefreshToken); code != http.StatusUnauthorized {
+		t.Errorf("Refresh for a disabled user = %d, want 401", code)
+	}
+	rr = call("POST", "/api/auth/login", "", map[string]string{"email": "ada@example.com", "password": "password123"})
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("Login for a disabled user = %d, want 403", rr.Code)
+	}
+
+	if rr := call("PATCH", path, adminToken, map[string]bool{"disabled": false}); rr.Code != http.StatusOK {
+		t.Fatalf("Enable = %d %s", rr.Code, rr.Body.String())
+	}
+	session = login("ada@example.com")
+	if rr := call("POST", path+"/logout", adminToken, nil); rr.Code != http.StatusOK {
+		t.Fatalf("Admin logout = %d %s", rr.Code, rr.Body.String())
+	}
+	if authorized(session.Token) {
+		t.Error("Access token still works after an admin signed the user out")
+	}
+}
@@ -0,0 +1,293 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/clarateach/backend/internal/auth"
+	"github.com/clarateach/backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// refreshReuseGrace is how long after a refresh token is used that seeing it
+// again is taken for a concurrent refresh, such as from a second browser tab,
+// rather than for a stolen token.
+const refreshReuseGrace = 10 * time.Second
+
+// SetRefreshTokenTTL sets how long a sign-in lasts without being refreshed.
+func (s *Server) SetRefreshTokenTTL(ttl time.Duration) {
+	s.refreshTokenTTL = ttl
+}
+
+// sessionTokens are the tokens handed to a signed-in user.
+type sessionTokens struct {
+	Token        string    `json:"token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"` // When Token expires
+}
+
+// issueTokens creates an access token and a refresh token for user. An empty
+// sessionID starts a new session; refreshing continues the old one.
+func (s *Server) issueTokens(user *store.User, sessionID string) (*sessionTokens, error) {
+	if sessionID == "" {
+		sessionID = "sess-" + generateID(16)
+	}
+	token, claims, err := auth.GenerateSessionToken(user, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, refreshID, err := auth.NewRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	err = s.store.CreateRefreshToken(&store.RefreshToken{
+		ID:              refreshID,
+		SessionID:       sessionID,
+		UserID:          user.ID,
+		AccessTokenID:   claims.ID,
+		AccessExpiresAt: claims.ExpiresAt.Time,
+		ExpiresAt:       now.Add(s.refreshTokenTTL),
+		CreatedAt:       now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &sessionTokens{Token: token, RefreshToken: refreshToken, ExpiresAt: claims.ExpiresAt.Time}, nil
+}
+
+// authRefresh swaps a refresh token for new tokens. Each refresh token works
+// once; presenting a used one again ends its session.
+func (s *Server) authRefresh(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.RefreshToken == "" {
+		http.Error(w, "refresh_token is required", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	id := auth.RefreshTokenID(req.RefreshToken)
+	refresh, err := s.store.GetRefreshToken(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if refresh == nil || refresh.RevokedAt != nil || !now.Before(refresh.ExpiresAt) {
+		http.Error(w, "Invalid or expired refresh token", http.StatusUnauthorized)
+		return
+	}
+	if refresh.UsedAt != nil {
+		if now.Sub(*refresh.UsedAt) > refreshReuseGrace {
+			// Both the user and whoever copied the token hold a dead end now
+			if err := s.store.RevokeSession(refresh.SessionID, now); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			s.logger.WarnContext(r.Context(), "revoked session after refresh token reuse", "user_id", refresh.UserID, "session_id", refresh.SessionID)
+		}
+		http.Error(w, "Invalid or expired refresh token", http.StatusUnauthorized)
+		return
+	}
+	used, err := s.store.UseRefreshToken(id, now)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !used {
+		http.Error(w, "Invalid or expired refresh token", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := s.store.GetUser(refresh.UserID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if user == nil || user.Disabled {
+		http.Error(w, "Invalid or expired refresh token", http.StatusUnauthorized)
+		return
+	}
+
+	tokens, err := s.issueTokens(user, refresh.SessionID)
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"token":         tokens.Token,
+		"refresh_token": tokens.RefreshToken,
+		"expires_at":    tokens.ExpiresAt,
+		"user":          user,
+	})
+}
+
+// authLogout ends the session of the bearer token and of the refresh token
+// in the body, either of which may be missing or already expired.
+func (s *Server) authLogout(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		if claims, err := auth.ValidateToken(token); err == nil && claims.ID != "" {
+			if err := s.revokeAccessToken(claims, now); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+	if req.RefreshToken != "" {
+		refresh, err := s.store.GetRefreshToken(auth.RefreshTokenID(req.RefreshToken))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if refresh != nil {
+			if err := s.store.RevokeSession(refresh.SessionID, now); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// authLogoutAll ends every session of the signed-in user.
+func (s *Server) authLogoutAll(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	now := time.Now()
+	if err := s.store.RevokeUserSessions(user.ID, now); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// The token making this request may belong to no session
+	if claims := auth.GetClaimsFromContext(r.Context()); claims != nil {
+		if err := s.revokeAccessToken(claims, now); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// revokeAccessToken revokes an access token along with its session.
+func (s *Server) revokeAccessToken(claims *auth.Claims, now time.Time) error {
+	if err := s.store.RevokeToken(claims.ID, claims.ExpiresAt.Time, now); err != nil {
+		return err
+	}
+	if claims.SessionID == "" {
+		return nil
+	}
+	return s.store.RevokeSession(claims.SessionID, now)
+}
+
+// updateUser lets an admin disable or re-enable a user. Disabling ends all
+// of the user's sessions.
+func (s *Server) updateUser(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Disabled *bool `json:"disabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Disabled == nil {
+		http.Error(w, "disabled is required", http.StatusBadRequest)
+		return
+	}
+
+	userID := chi.URLParam(r, "userID")
+	if admin := auth.GetUserFromContext(r.Context()); admin != nil && admin.ID == userID && *req.Disabled {
+		http.Error(w, "You cannot disable your own account", http.StatusBadRequest)
+		return
+	}
+	user, err := s.store.GetUser(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	if err := s.store.SetUserDisabled(user.ID, *req.Disabled); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if *req.Disabled {
+		if err := s.store.RevokeUserSessions(user.ID, time.Now()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	user.Disabled = *req.Disabled
+	s.logger.InfoContext(r.Context(), "updated user", "user_id", user.ID, "disabled", user.Disabled)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"user": user})
+}
+
+// logoutUser lets an admin end every session of a user.
+func (s *Server) logoutUser(w http.ResponseWriter, r *http.Request) {
+	user, err := s.store.GetUser(chi.URLParam(r, "userID"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err := s.store.RevokeUserSessions(user.ID, time.Now()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// RunTokenCleanup deletes expired refresh tokens and revocations every
+// interval until ctx is cancelled.
+func (s *Server) RunTokenCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.store.DeleteExpiredTokens(time.Now()); err != nil {
+			s.logger.WarnContext(ctx, "failed to delete expired tokens", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
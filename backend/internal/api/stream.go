@@ -0,0 +1,271 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/clarateach/backend/internal/events"
+	"github.com/clarateach/backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// streamKeepalive is how often an idle stream sends a comment line so proxies
+// and load balancers do not close the connection.
+const streamKeepalive = 15 * time.Second
+
+// streamWorkshop streams one workshop's status, seat, registration, join and
+// timeline events as Server-Sent Events.
+func (s *Server) streamWorkshop(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	workshop, err := s.store.GetWorkshop(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if workshop == nil {
+		http.Error(w, "Workshop not found", http.StatusNotFound)
+		return
+	}
+
+	// Subscribe before building the snapshot so no change falls in between
+	ch, unsubscribe := s.events.Subscribe(id)
+	defer unsubscribe()
+
+	sessions, err := s.store.ListSessions(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	seats := make([]map[string]interface{}, 0, len(sessions))
+	for _, sess := range sessions {
+		seats = append(seats, seatData(sess))
+	}
+
+	s.serveEvents(w, r, ch, events.Event{
+		Type:       events.TypeSnapshot,
+		WorkshopID: id,
+		Data: map[string]interface{}{
+			"status": workshop.Status,
+			"seats":  seats,
+		},
+	})
+}
+
+// streamAdmin streams events for all workshops as Server-Sent Events.
+func (s *Server) streamAdmin(w http.ResponseWriter, r *http.Request) {
+	ch, unsubscribe := s.events.Subscribe("")
+	defer unsubscribe()
+
+	workshops, err := s.store.ListWorkshops()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	summary := make([]map[string]interface{}, 0, len(workshops))
+	for _, ws := range workshops {
+		summary = append(summary, map[string]interface{}{
+			"id":     ws.ID,
+			"name":   ws.Name,
+			"status": ws.Status,
+			"seats":  ws.Seats,
+		})
+	}
+
+	s.serveEvents(w, r, ch, events.Event{
+		Type: events.TypeSnapshot,
+		Data: map[string]interface{}{"workshops": summary},
+	})
+}
+
+// serveEvents writes initial followed by every event received on ch until the
+// client disconnects.
+func (s *Server) serveEvents(w http.ResponseWriter, r *http.Request, ch <-chan events.Event, initial ...events.Event) {
+	rc := beginSSE(w)
+	for _, e := range initial {
+		if e.Time.IsZero() {
+			e.Time = time.Now()
+		}
+		if err := writeSSE(w, e.Type, e); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		s.logger.WarnContext(r.Context(), "event stream not supported by response writer", "error", err)
+		return
+	}
+
+	keepalive := time.NewTicker(streamKeepalive)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-ch:
+			if !ok {
+				return
+			}
+			if err := writeSSE(w, e.Type, e); err != nil {
+				return
+			}
+		case <-keepalive.C:
+			if _, err := io.WriteString(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// streamSession pushes a learner's session state as Server-Sent Events:
+// pending (with queue position) until the workshop VM is up, then
+// seat_assigned and finally ready with the endpoint and workspace token, after
+// which the stream ends. The access code is the credential, as for
+// GET /api/session/{code}.
+func (s *Server) streamSession(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+	registration, err := s.store.GetRegistration(code)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if registration == nil {
+		http.Error(w, "Invalid access code", http.StatusNotFound)
+		return
+	}
+
+	// Any change to the workshop may advance the learner, so re-resolve on each event
+	ch, unsubscribe := s.events.Subscribe(registration.WorkshopID)
+	defer unsubscribe()
+
+	state, status, err := s.resolveSession(r.Context(), code)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	rc := beginSSE(w)
+	var last []byte
+	// send writes state if it differs from the last one sent and reports
+	// whether the stream should continue.
+	send := func(state *sessionState) bool {
+		data, _ := json.Marshal(state)
+		if bytes.Equal(data, last) {
+			return true
+		}
+		last = data
+		if state.Status == "ready" {
+			assigned := &sessionState{
+				Status:     "seat_assigned",
+				Seat:       state.Seat,
+				Name:       state.Name,
+				WorkshopID: state.WorkshopID,
+			}
+			if writeSSE(w, assigned.Status, assigned) != nil {
+				return false
+			}
+		}
+		if writeSSE(w, state.Status, state) != nil || rc.Flush() != nil {
+			return false
+		}
+		return state.Status != "ready"
+	}
+	if !send(state) {
+		return
+	}
+
+	keepalive := time.NewTicker(streamKeepalive)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+			state, status, err := s.resolveSession(r.Context(), code)
+			if err != nil {
+				// Tell the learner why the stream ended (e.g. 410 when the workshop ends)
+				name := "error"
+				if status == http.StatusGone {
+					name = "ended"
+				}
+				writeSSE(w, name, map[string]string{"status": name, "message": err.Error()})
+				rc.Flush()
+				return
+			}
+			if !send(state) {
+				return
+			}
+		case <-keepalive.C:
+			if _, err := io.WriteString(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			if rc.Flush() != nil {
+				return
+			}
+		}
+	}
+}
+
+// beginSSE writes the Server-Sent Events response headers.
+func beginSSE(w http.ResponseWriter) *http.ResponseController {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable nginx response buffering
+	w.WriteHeader(http.StatusOK)
+	return http.NewResponseController(w)
+}
+
+// writeSSE writes v as JSON in a single Server-Sent Event called name.
+func writeSSE(w io.Writer, name string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data)
+	return err
+}
+
+// seatData is the seat payload shared by snapshots and seat.status events.
+func seatData(sess *store.Session) map[string]interface{} {
+	return map[string]interface{}{
+		"seat_id": sess.SeatID,
+		"status":  sess.Status,
+		"name":    sess.Name,
+	}
+}
+
+// setWorkshopStatus updates the workshop's status and publishes the transition.
+func (s *Server) setWorkshopStatus(workshopID, status string) error {
+	if err := s.store.UpdateWorkshopStatus(workshopID, status); err != nil {
+		return err
+	}
+	s.events.Publish(events.Event{
+		Type:       events.TypeWorkshopStatus,
+		WorkshopID: workshopID,
+		Data:       map[string]interface{}{"status": status},
+	})
+	s.emitWorkshopWebhook(context.Background(), workshopID, status)
+	return nil
+}
+
+// publishSeat announces a seat's current state.
+func (s *Server) publishSeat(sess *store.Session) {
+	s.events.Publish(events.Event{
+		Type:       events.TypeSeatStatus,
+		WorkshopID: sess.WorkshopID,
+		SeatID:     sess.SeatID,
+		Data:       seatData(sess),
+	})
+}
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/clarateach/backend/internal/events"
+	"github.com/clarateach/backend/internal/logging"
+	"github.com/clarateach/backend/internal/notify"
+	"github.com/clarateach/backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// listWaitlist returns the workshop's waitlisted registrations in the order
+// they will be promoted.
+func (s *Server) listWaitlist(w http.ResponseWriter, r *http.Request) {
+	waitlist, err := s.waitlist(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"waitlist": waitlist})
+}
+
+// reorderWaitlist sets the promotion order. The request must list every
+// waitlisted registration exactly once.
+func (s *Server) reorderWaitlist(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req struct {
+		RegistrationIDs []string `json:"registration_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	s.waitlistMu.Lock()
+	defer s.waitlistMu.Unlock()
+
+	waitlist, err := s.waitlist(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	byID := make(map[string]*store.Registration, len(waitlist))
+	for _, reg := range waitlist {
+		byID[reg.ID] = reg
+	}
+	seen := make(map[string]bool, len(req.RegistrationIDs))
+	for _, regID := range req.RegistrationIDs {
+		if byID[regID] == nil || seen[regID] {
+			http.Error(w, "registration_ids must list each waitlisted registration once", http.StatusBadRequest)
+			return
+		}
+		seen[regID] = true
+	}
+	if len(seen) != len(waitlist) {
+		http.Error(w, "registration_ids must list each waitlisted registration once", http.StatusBadRequest)
+		return
+	}
+
+	err = s.store.ReorderWaitlist(id, req.RegistrationIDs)
+	if errors.Is(err, store.ErrNotWaitlisted) {
+		http.Error(w, "The waitlist changed; reload it and try again", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	reordered := make([]*store.Registration, 0, len(req.RegistrationIDs))
+	for i, regID := range req.RegistrationIDs {
+		reg := byID[regID]
+		if reg.WaitlistPosition != i+1 {
+			reg.WaitlistPosition = i + 1
+			s.publishWaitlist(reg)
+		}
+		reordered = append(reordered, reg)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"waitlist": reordered})
+}
+
+// promoteWaitlist gives places freed by revocations or added seats to
+// waitlisted learners in waitlist order, activating and emailing their access
+// codes, then closes the gaps in the positions of those still waiting.
+// Failures are logged; the next revocation or resize tries again.
+func (s *Server) promoteWaitlist(ctx context.Context, workshopID string) {
+	s.waitlistMu.Lock()
+	defer s.waitlistMu.Unlock()
+
+	ctx = logging.WithWorkshop(ctx, workshopID)
+	workshop, err := s.store.GetWorkshop(workshopID)
+	if err != nil || workshop == nil || workshop.Status == "ended" || workshop.Status == "deleted" {
+		return
+	}
+	waitlist, err := s.waitlist(workshopID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to load waitlist", "error", err)
+		return
+	}
+	if len(waitlist) == 0 {
+		return
+	}
+	count, err := s.store.CountRegistrations(workshopID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to count registrations", "error", err)
+		return
+	}
+
+	free := min(max(workshop.Seats-count, 0), len(waitlist))
+	for _, reg := range waitlist[:free] {
+		reg.Status = "registered"
+		reg.WaitlistPosition = 0
+		if err := s.store.UpdateRegistration(reg); err != nil {
+			s.logger.WarnContext(ctx, "failed to promote waitlisted registration", "registration_id", reg.ID, "error", err)
+			return
+		}
+		s.logger.InfoContext(ctx, "promoted registration from waitlist", "registration_id", reg.ID)
+		s.publishWaitlist(reg)
+		s.notifyLearner(ctx, workshop, reg, notify.KindRegistration)
+		s.emitRegistrationWebhook(ctx, reg)
+	}
+
+	remaining := waitlist[free:]
+	ids := make([]string, 0, len(remaining))
+	renumber := false
+	for i, reg := range remaining {
+		ids = append(ids, reg.ID)
+		renumber = renumber || reg.WaitlistPosition != i+1
+	}
+	if !renumber {
+		return
+	}
+	if err := s.store.ReorderWaitlist(workshopID, ids); err != nil {
+		s.logger.WarnContext(ctx, "failed to renumber waitlist", "error", err)
+		return
+	}
+	for i, reg := range remaining {
+		if reg.WaitlistPosition != i+1 {
+			reg.WaitlistPosition = i + 1
+			s.publishWaitlist(reg)
+		}
+	}
+}
+
+// waitlist returns the workshop's waitlisted registrations by position.
+func (s *Server) waitlist(workshopID string) ([]*store.Registration, error) {
+	registrations, err := s.store.ListRegistrations(workshopID)
+	if err != nil {
+		return nil, err
+	}
+	waitlist := []*store.Registration{}
+	for _, reg := range registrations {
+		if reg.Status == "waitlisted" {
+			waitlist = append(waitlist, reg)
+		}
+	}
+	sort.SliceStable(waitlist, func(i, j int) bool {
+		return waitlist[i].WaitlistPosition < waitlist[j].WaitlistPosition
+	})
+	return waitlist, nil
+}
+
+// publishWaitlist announces a registration joining, moving on or leaving the
+// waitlist. Learner session streams re-resolve on it, so a promoted learner's
+// page moves on without reloading.
+func (s *Server) publishWaitlist(reg *store.Registration) {
+	s.events.Publish(events.Event{
+		Type:       events.TypeWaitlist,
+		WorkshopID: reg.WorkshopID,
+		Data: map[string]interface{}{
+			"name":              reg.Name,
+			"email":             reg.Email,
+			"status":            reg.Status,
+			"waitlist_position": reg.WaitlistPosition,
+		},
+		Time: time.Now(),
+	})
+}
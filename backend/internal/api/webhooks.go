@@ -0,0 +1,402 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/clarateach/backend/internal/auth"
+	"github.com/clarateach/backend/internal/logging"
+	"github.com/clarateach/backend/internal/store"
+	"github.com/clarateach/backend/internal/webhook"
+	"github.com/go-chi/chi/v5"
+)
+
+// webhookBatch is how many due deliveries are sent at once.
+const webhookBatch = 20
+
+// webhookLease is how long a claimed delivery is kept from other dispatchers
+// while it is being sent.
+const webhookLease = 2 * time.Minute
+
+// SetWebhookAllowPrivate lets webhooks reach loopback and private addresses,
+// for development and for endpoints inside the same network.
+func (s *Server) SetWebhookAllowPrivate(allow bool) {
+	s.webhookSender = webhook.NewSender(allow)
+}
+
+// emitWebhook queues event for every webhook of the workshop, and every
+// global webhook, subscribed to it.
+func (s *Server) emitWebhook(ctx context.Context, workshopID, event string, data map[string]interface{}) {
+	ctx = logging.WithWorkshop(ctx, workshopID)
+	hooks, err := s.store.ListWebhooks(workshopID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to list webhooks", "event", event, "error", err)
+		return
+	}
+	global, err := s.store.ListWebhooks("")
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to list global webhooks", "event", event, "error", err)
+		return
+	}
+	hooks = append(hooks, global...)
+
+	now := time.Now()
+	payload, err := json.Marshal(webhook.Payload{
+		ID:         "evt_" + generateID(16),
+		Event:      event,
+		WorkshopID: workshopID,
+		Time:       now,
+		Data:       data,
+	})
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to encode webhook payload", "event", event, "error", err)
+		return
+	}
+
+	queued := false
+	for _, h := range hooks {
+		if !subscribed(h, event) {
+			continue
+		}
+		d := &store.WebhookDelivery{
+			WebhookID:     h.ID,
+			WorkshopID:    workshopID,
+			Event:         event,
+			Payload:       string(payload),
+			Status:        store.WebhookDeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		}
+		if err := s.store.CreateWebhookDelivery(d); err != nil {
+			s.logger.WarnContext(ctx, "failed to queue webhook delivery", "webhook_id", h.ID, "event", event, "error", err)
+			continue
+		}
+		queued = true
+	}
+	if queued {
+		s.wakeWebhooks()
+	}
+}
+
+func subscribed(h *store.Webhook, event string) bool {
+	for _, e := range h.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// emitWorkshopWebhook queues a workshop.* event for a status transition, if
+// there is one for the status.
+func (s *Server) emitWorkshopWebhook(ctx context.Context, workshopID, status string) {
+	var event string
+	switch status {
+	case "running":
+		event = webhook.EventWorkshopRunning
+	case "error":
+		event = webhook.EventWorkshopError
+	case "stopped":
+		event = webhook.EventWorkshopStopped
+	default:
+		return
+	}
+	data := map[string]interface{}{"status": status}
+	if workshop, err := s.store.GetWorkshop(workshopID); err == nil && workshop != nil {
+		data["name"] = workshop.Name
+		data["code"] = workshop.Code
+	}
+	s.emitWebhook(ctx, workshopID, event, data)
+}
+
+// emitRegistrationWebhook queues learner.registered for a registration that
+// holds a place in the workshop.
+func (s *Server) emitRegistrationWebhook(ctx context.Context, registration *store.Registration) {
+	s.emitWebhook(ctx, registration.WorkshopID, webhook.EventLearnerRegistered, map[string]interface{}{
+		"registration_id": registration.ID,
+		"name":            registration.Name,
+		"email":           registration.Email,
+	})
+}
+
+// wakeWebhooks tells RunWebhooks there is something to send.
+func (s *Server) wakeWebhooks() {
+	select {
+	case s.webhookWake <- struct{}{}:
+	default:
+	}
+}
+
+// RunWebhooks sends queued webhook deliveries as they are queued, and
+// retries failed ones at least every interval, until ctx is cancelled.
+func (s *Server) RunWebhooks(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.deliverWebhooks(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.webhookWake:
+		}
+	}
+}
+
+// deliverWebhooks sends every delivery that is due. Each one is claimed first,
+// so several servers may share the queue.
+func (s *Server) deliverWebhooks(ctx context.Context) {
+	for ctx.Err() == nil {
+		now := time.Now()
+		due, err := s.store.ListDueWebhookDeliveries(now, webhookBatch)
+		if err != nil {
+			s.logger.WarnContext(ctx, "failed to list webhook deliveries", "error", err)
+			return
+		}
+
+		var wg sync.WaitGroup
+		for _, d := range due {
+			claimed, err := s.store.ClaimWebhookDelivery(d.ID, now, now.Add(webhookLease))
+			if err != nil {
+				s.logger.WarnContext(ctx, "failed to claim webhook delivery", "delivery_id", d.ID, "error", err)
+				continue
+			}
+			if !claimed {
+				continue
+			}
+			wg.Add(1)
+			go func(d *store.WebhookDelivery) {
+				defer wg.Done()
+				s.deliverWebhook(ctx, d)
+			}(d)
+		}
+		wg.Wait()
+
+		if len(due) < webhookBatch {
+			return
+		}
+	}
+}
+
+// deliverWebhook makes one attempt at a delivery and records the result,
+// scheduling a retry with backoff if it failed.
+func (s *Server) deliverWebhook(ctx context.Context, d *store.WebhookDelivery) {
+	ctx = logging.WithWorkshop(ctx, d.WorkshopID)
+	h, err := s.store.GetWebhook(d.WebhookID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to load webhook", "webhook_id", d.WebhookID, "error", err)
+		return // Retried when the lease runs out
+	}
+
+	d.Attempts++
+	if h == nil {
+		d.Status = store.WebhookDeliveryFailed
+		d.LastError = "webhook was deleted"
+	} else {
+		code, err := s.webhookSender.Send(ctx, h.URL, h.Secret, d.Event, strconv.FormatInt(d.ID, 10), []byte(d.Payload))
+		d.LastStatusCode = code
+		if err == nil {
+			now := time.Now()
+			d.Status = store.WebhookDeliveryDelivered
+			d.LastError = ""
+			d.DeliveredAt = &now
+		} else {
+			d.LastError = err.Error()
+			if d.Attempts >= webhook.MaxAttempts {
+				d.Status = store.WebhookDeliveryFailed
+			} else {
+				d.NextAttemptAt = time.Now().Add(webhook.Backoff(d.Attempts))
+			}
+			s.logger.WarnContext(ctx, "webhook delivery failed", "webhook_id", h.ID, "delivery_id", d.ID, "attempt", d.Attempts, "error", err)
+		}
+	}
+	if err := s.store.UpdateWebhookDelivery(d); err != nil {
+		s.logger.WarnContext(ctx, "failed to update webhook delivery", "delivery_id", d.ID, "error", err)
+	}
+}
+
+// The handlers below serve both /api/workshops/{id}/webhooks and the global
+// /api/admin/webhooks; the latter has no {id}, which scopes them to global
+// webhooks.
+
+// listWebhooks returns the workshop's webhooks, or the global ones.
+func (s *Server) listWebhooks(w http.ResponseWriter, r *http.Request) {
+	hooks, err := s.store.ListWebhooks(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if hooks == nil {
+		hooks = []*store.Webhook{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"webhooks": hooks,
+		"events":   webhook.Events,
+	})
+}
+
+// createWebhook registers an endpoint. The signing secret is generated unless
+// given, and only returned here.
+func (s *Server) createWebhook(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+		Secret string   `json:"secret"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := webhook.ValidateURL(req.URL); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.Events) == 0 {
+		http.Error(w, "At least one event is required", http.StatusBadRequest)
+		return
+	}
+	seen := make(map[string]bool, len(req.Events))
+	events := make([]string, 0, len(req.Events))
+	for _, e := range req.Events {
+		if !webhook.ValidEvent(e) {
+			http.Error(w, "Unknown event: "+e, http.StatusBadRequest)
+			return
+		}
+		if !seen[e] {
+			seen[e] = true
+			events = append(events, e)
+		}
+	}
+	if req.Secret == "" {
+		secret, err := webhook.GenerateSecret()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		req.Secret = secret
+	}
+
+	h := &store.Webhook{
+		ID:         "wh-" + generateID(12),
+		WorkshopID: chi.URLParam(r, "id"),
+		URL:        req.URL,
+		Secret:     req.Secret,
+		Events:     events,
+		CreatedBy:  auth.GetUserFromContext(r.Context()).ID,
+		CreatedAt:  time.Now(),
+	}
+	if err := s.store.CreateWebhook(h); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"webhook": h,
+		"secret":  h.Secret,
+	})
+}
+
+// deleteWebhook removes a webhook and its delivery log.
+func (s *Server) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	h, ok := s.scopedWebhook(w, r)
+	if !ok {
+		return
+	}
+	if err := s.store.DeleteWebhook(h.ID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// listWebhookDeliveries returns a webhook's most recent deliveries, newest
+// first; ?limit= sets how many (default 50, at most 200).
+func (s *Server) listWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	h, ok := s.scopedWebhook(w, r)
+	if !ok {
+		return
+	}
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "limit must be a positive number", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, 200)
+	}
+
+	deliveries, err := s.store.ListWebhookDeliveries(h.ID, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if deliveries == nil {
+		deliveries = []*store.WebhookDelivery{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"deliveries": deliveries})
+}
+
+// redeliverWebhook queues a delivery to be sent again now, with a fresh set
+// of retries.
+func (s *Server) redeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	h, ok := s.scopedWebhook(w, r)
+	if !ok {
+		return
+	}
+	deliveryID, err := strconv.ParseInt(chi.URLParam(r, "deliveryID"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid delivery ID", http.StatusBadRequest)
+		return
+	}
+	d, err := s.store.GetWebhookDelivery(deliveryID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if d == nil || d.WebhookID != h.ID {
+		http.Error(w, "Delivery not found", http.StatusNotFound)
+		return
+	}
+	if d.Status == store.WebhookDeliveryPending {
+		http.Error(w, "Delivery is already queued", http.StatusConflict)
+		return
+	}
+
+	d.Status = store.WebhookDeliveryPending
+	d.Attempts = 0
+	d.NextAttemptAt = time.Now()
+	if err := s.store.UpdateWebhookDelivery(d); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.wakeWebhooks()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"delivery": d})
+}
+
+// scopedWebhook looks up the {webhookID} URL parameter, responding with 404
+// unless it belongs to the {id} workshop (or is global, on admin routes).
+func (s *Server) scopedWebhook(w http.ResponseWriter, r *http.Request) (*store.Webhook, bool) {
+	h, err := s.store.GetWebhook(chi.URLParam(r, "webhookID"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if h == nil || h.WorkshopID != chi.URLParam(r, "id") {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return nil, false
+	}
+	return h, true
+}
@@ -2,6 +2,9 @@ package auth
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"net/http"
 	"os"
@@ -21,23 +24,147 @@ var (
 
 type contextKey string
 
-const UserContextKey contextKey = "user"
+const (
+	UserContextKey   contextKey = "user"
+	ClaimsContextKey contextKey = "claims"
+)
 
-// Claims represents JWT claims for user authentication
+// Claims represents JWT claims for user authentication. The token ID (jti)
+// is what gets revoked, and the session ID ties the token to the refresh
+// token it was issued with.
 type Claims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// AccessTokenTTL is how long a user's access token lasts. It is short so
+// that revoked tokens need only be remembered briefly; clients get new
+// ones with their refresh token.
+const AccessTokenTTL = 15 * time.Minute
+
 // WorkspaceClaims represents JWT claims for workspace access
 type WorkspaceClaims struct {
 	WorkshopID string `json:"workshop_id"`
 	Seat       int    `json:"seat"`
+	Access     string `json:"access,omitempty"`  // Empty for the seat's learner, otherwise WorkspaceAccess*
+	UserID     string `json:"user_id,omitempty"` // Staff user an observer token was issued to
+	// Registration the learner token was issued for, so it can be revoked
+	// when the registration is revoked or moved to another seat
+	RegistrationID string `json:"registration_id,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// PreviewClaims represents JWT claims for viewing the web app a learner runs
+// on one port of their seat's MicroVM. They carry previewAudience, so workers
+// never take them for workspace tokens.
+type PreviewClaims struct {
+	WorkspaceClaims
+	Port int `json:"port"`
+}
+
+// previewAudience keeps preview tokens, which are signed with the workspace
+// secret and may end up in shared links, from granting workspace access
+const previewAudience = "workspace-preview"
+
+// PreviewTokenTTL is how long a preview link works.
+const PreviewTokenTTL = time.Hour
+
+// LoginLinkClaims represents JWT claims for a one-time learner login link.
+// The token ID (jti) is stored so the link can be used only once.
+type LoginLinkClaims struct {
+	RegistrationID string `json:"registration_id"`
 	jwt.RegisteredClaims
 }
 
+// loginLinkAudience keeps login links from being accepted as other tokens
+// signed with the same secret, and the other way round
+const loginLinkAudience = "learner-login"
+
+// LTIStateClaims represents JWT claims for the state parameter of an LTI
+// login, carrying the nonce the platform's ID token must echo.
+type LTIStateClaims struct {
+	PlatformID string `json:"platform_id"`
+	Nonce      string `json:"nonce"`
+	jwt.RegisteredClaims
+}
+
+// LTIDeepLinkClaims represents JWT claims for an instructor's deep linking
+// session: what they may link and where the choice goes back to. The
+// subject is the instructor's user ID.
+type LTIDeepLinkClaims struct {
+	PlatformID   string `json:"platform_id"`
+	DeploymentID string `json:"deployment_id"`
+	ReturnURL    string `json:"return_url"`
+	Data         string `json:"data,omitempty"`
+	ContextID    string `json:"context_id,omitempty"`
+	ContextTitle string `json:"context_title,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// OIDCStateClaims represents JWT claims for the cookie that carries an OIDC
+// sign-in from the redirect to the provider back to the callback. The
+// subject is set when a signed-in user is linking the provider account.
+type OIDCStateClaims struct {
+	Provider string `json:"provider"`
+	State    string `json:"state"`    // Must match the callback's state parameter
+	Nonce    string `json:"nonce"`    // Must match the ID token's nonce
+	Verifier string `json:"verifier"` // PKCE code verifier
+	jwt.RegisteredClaims
+}
+
+// OIDCLinkClaims represents JWT claims for starting to link a provider
+// account to the signed-in user, whose ID is the subject.
+type OIDCLinkClaims struct {
+	Provider string `json:"provider"`
+	jwt.RegisteredClaims
+}
+
+// OIDCIdentityClaims represents JWT claims for a provider account verified
+// while linking, which the signed-in user who started the link (the subject)
+// must confirm before it is linked.
+type OIDCIdentityClaims struct {
+	Provider        string `json:"provider"`
+	ProviderSubject string `json:"provider_subject"` // The account's sub at the provider
+	Email           string `json:"email,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Audiences of the LTI and OIDC tokens, which like login links are signed
+// with the JWT secret
+const (
+	ltiStateAudience     = "lti-state"
+	ltiDeepLinkAudience  = "lti-deep-link"
+	oidcStateAudience    = "oidc-state"
+	oidcLinkAudience     = "oidc-link"
+	oidcIdentityAudience = "oidc-identity"
+)
+
+// Staff workspace access levels. Both may watch a seat's terminal but not
+// touch its files; only instructors may take control of the terminal.
+const (
+	WorkspaceAccessObserver   = "observer"
+	WorkspaceAccessInstructor = "instructor"
+)
+
+// IsObserver reports whether the token grants read-only observer access.
+func (c *WorkspaceClaims) IsObserver() bool {
+	return c.Access == WorkspaceAccessObserver
+}
+
+// IsStaff reports whether the token was issued to a staff user for watching
+// the learner's terminal rather than to the learner.
+func (c *WorkspaceClaims) IsStaff() bool {
+	return c.Access == WorkspaceAccessObserver || c.Access == WorkspaceAccessInstructor
+}
+
+// CanTakeControl reports whether the token may take over the learner's terminal.
+func (c *WorkspaceClaims) CanTakeControl() bool {
+	return c.Access == WorkspaceAccessInstructor
+}
+
 // GetJWTSecret returns the JWT secret from env or default
 func GetJWTSecret() []byte {
 	secret := os.Getenv("JWT_SECRET")
@@ -58,11 +185,47 @@ func GetWorkspaceTokenSecret() []byte {
 
 // GenerateWorkspaceToken creates a JWT token for workspace access
 func GenerateWorkspaceToken(workshopID string, seat int) (string, error) {
+	return GenerateLearnerToken(workshopID, seat, "")
+}
+
+// GenerateLearnerToken creates a workspace token for a registered learner's
+// seat. Workers can revoke it by registration ID.
+func GenerateLearnerToken(workshopID string, seat int, registrationID string) (string, error) {
+	claims := &WorkspaceClaims{
+		WorkshopID:     workshopID,
+		Seat:           seat,
+		RegistrationID: registrationID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(4 * time.Hour)), // 4 hour expiry
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Subject:   workshopID,
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(GetWorkspaceTokenSecret())
+}
+
+// GenerateObserverToken creates a read-only workspace token that lets a staff
+// user mirror a learner's terminal
+func GenerateObserverToken(workshopID string, seat int, userID string) (string, error) {
+	return generateStaffWorkspaceToken(workshopID, seat, userID, WorkspaceAccessObserver)
+}
+
+// GenerateInstructorToken creates a workspace token that lets an instructor
+// mirror a learner's terminal and take control of it
+func GenerateInstructorToken(workshopID string, seat int, userID string) (string, error) {
+	return generateStaffWorkspaceToken(workshopID, seat, userID, WorkspaceAccessInstructor)
+}
+
+func generateStaffWorkspaceToken(workshopID string, seat int, userID, access string) (string, error) {
 	claims := &WorkspaceClaims{
 		WorkshopID: workshopID,
 		Seat:       seat,
+		Access:     access,
+		UserID:     userID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(4 * time.Hour)), // 4 hour expiry
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(1 * time.Hour)), // Shorter than learner tokens
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Subject:   workshopID,
 		},
@@ -81,13 +244,248 @@ func ValidateWorkspaceToken(tokenString string) (*WorkspaceClaims, error) {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*WorkspaceClaims); ok && token.Valid {
+	// Preview tokens share the secret but are not workspace tokens
+	if claims, ok := token.Claims.(*WorkspaceClaims); ok && token.Valid && len(claims.Audience) == 0 {
 		return claims, nil
 	}
 
 	return nil, errors.New("invalid workspace token")
 }
 
+// GeneratePreviewToken creates a token for viewing the web app on one port of
+// a learner's seat. Workers can revoke it by registration ID like the
+// learner's workspace token.
+func GeneratePreviewToken(workshopID string, seat, port int, registrationID string) (string, error) {
+	claims := &PreviewClaims{
+		WorkspaceClaims: WorkspaceClaims{
+			WorkshopID:     workshopID,
+			Seat:           seat,
+			RegistrationID: registrationID,
+			RegisteredClaims: jwt.RegisteredClaims{
+				Audience:  jwt.ClaimStrings{previewAudience},
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(PreviewTokenTTL)),
+				IssuedAt:  jwt.NewNumericDate(time.Now()),
+				Subject:   workshopID,
+			},
+		},
+		Port: port,
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(GetWorkspaceTokenSecret())
+}
+
+// ValidatePreviewToken validates a preview token and returns its claims.
+func ValidatePreviewToken(tokenString string) (*PreviewClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &PreviewClaims{}, func(token *jwt.Token) (interface{}, error) {
+		return GetWorkspaceTokenSecret(), nil
+	}, jwt.WithAudience(previewAudience), jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}))
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(*PreviewClaims); ok && token.Valid {
+		return claims, nil
+	}
+
+	return nil, errors.New("invalid preview token")
+}
+
+// GenerateLoginLinkToken creates a signed login link token for a registration.
+// linkID becomes the token ID and must be recorded to enforce single use.
+func GenerateLoginLinkToken(registrationID, linkID string, expiresAt time.Time) (string, error) {
+	claims := &LoginLinkClaims{
+		RegistrationID: registrationID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        linkID,
+			Audience:  jwt.ClaimStrings{loginLinkAudience},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Subject:   registrationID,
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(GetJWTSecret())
+}
+
+// ValidateLoginLinkToken validates a login link token's signature, audience
+// and expiry and returns the claims. It does not check single use.
+func ValidateLoginLinkToken(tokenString string) (*LoginLinkClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &LoginLinkClaims{}, func(token *jwt.Token) (interface{}, error) {
+		return GetJWTSecret(), nil
+	}, jwt.WithAudience(loginLinkAudience), jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}))
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(*LoginLinkClaims); ok && token.Valid && claims.ID != "" && claims.RegistrationID != "" {
+		return claims, nil
+	}
+
+	return nil, errors.New("invalid login link")
+}
+
+// GenerateLTIStateToken creates the state for an LTI login that the
+// launch must return within ttl.
+func GenerateLTIStateToken(platformID, nonce string, ttl time.Duration) (string, error) {
+	claims := &LTIStateClaims{
+		PlatformID: platformID,
+		Nonce:      nonce,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{ltiStateAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(GetJWTSecret())
+}
+
+// ValidateLTIStateToken validates an LTI login state and returns the claims.
+// It does not check that the nonce is used only once.
+func ValidateLTIStateToken(tokenString string) (*LTIStateClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &LTIStateClaims{}, func(token *jwt.Token) (interface{}, error) {
+		return GetJWTSecret(), nil
+	}, jwt.WithAudience(ltiStateAudience), jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}))
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(*LTIStateClaims); ok && token.Valid && claims.PlatformID != "" && claims.Nonce != "" {
+		return claims, nil
+	}
+
+	return nil, errors.New("invalid LTI state")
+}
+
+// GenerateLTIDeepLinkToken creates a deep linking session token for a user.
+func GenerateLTIDeepLinkToken(userID string, claims LTIDeepLinkClaims, ttl time.Duration) (string, error) {
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		Audience:  jwt.ClaimStrings{ltiDeepLinkAudience},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		Subject:   userID,
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &claims)
+	return token.SignedString(GetJWTSecret())
+}
+
+// ValidateLTIDeepLinkToken validates a deep linking session token and
+// returns the claims.
+func ValidateLTIDeepLinkToken(tokenString string) (*LTIDeepLinkClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &LTIDeepLinkClaims{}, func(token *jwt.Token) (interface{}, error) {
+		return GetJWTSecret(), nil
+	}, jwt.WithAudience(ltiDeepLinkAudience), jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}))
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(*LTIDeepLinkClaims); ok && token.Valid && claims.Subject != "" && claims.ReturnURL != "" {
+		return claims, nil
+	}
+
+	return nil, errors.New("invalid deep linking session")
+}
+
+// GenerateOIDCStateToken creates the state cookie for an OIDC sign-in that
+// must complete within ttl. userID is empty unless linking an account.
+func GenerateOIDCStateToken(userID string, claims OIDCStateClaims, ttl time.Duration) (string, error) {
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		Audience:  jwt.ClaimStrings{oidcStateAudience},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		Subject:   userID,
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &claims)
+	return token.SignedString(GetJWTSecret())
+}
+
+// ValidateOIDCStateToken validates an OIDC state cookie and returns the
+// claims.
+func ValidateOIDCStateToken(tokenString string) (*OIDCStateClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &OIDCStateClaims{}, func(token *jwt.Token) (interface{}, error) {
+		return GetJWTSecret(), nil
+	}, jwt.WithAudience(oidcStateAudience), jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}))
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(*OIDCStateClaims); ok && token.Valid && claims.Provider != "" && claims.State != "" && claims.Nonce != "" && claims.Verifier != "" {
+		return claims, nil
+	}
+
+	return nil, errors.New("invalid OIDC state")
+}
+
+// GenerateOIDCLinkToken creates a token that lets a user start linking an
+// account at provider within ttl.
+func GenerateOIDCLinkToken(userID, provider string, ttl time.Duration) (string, error) {
+	claims := &OIDCLinkClaims{
+		Provider: provider,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{oidcLinkAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Subject:   userID,
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(GetJWTSecret())
+}
+
+// ValidateOIDCLinkToken validates an account linking token and returns the
+// claims.
+func ValidateOIDCLinkToken(tokenString string) (*OIDCLinkClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &OIDCLinkClaims{}, func(token *jwt.Token) (interface{}, error) {
+		return GetJWTSecret(), nil
+	}, jwt.WithAudience(oidcLinkAudience), jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}))
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(*OIDCLinkClaims); ok && token.Valid && claims.Subject != "" && claims.Provider != "" {
+		return claims, nil
+	}
+
+	return nil, errors.New("invalid account link")
+}
+
+// GenerateOIDCIdentityToken creates a token for a provider account verified
+// while userID was linking it, which must be confirmed within ttl.
+func GenerateOIDCIdentityToken(userID string, claims OIDCIdentityClaims, ttl time.Duration) (string, error) {
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		Audience:  jwt.ClaimStrings{oidcIdentityAudience},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		Subject:   userID,
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &claims)
+	return token.SignedString(GetJWTSecret())
+}
+
+// ValidateOIDCIdentityToken validates a verified provider account token and
+// returns the claims.
+func ValidateOIDCIdentityToken(tokenString string) (*OIDCIdentityClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &OIDCIdentityClaims{}, func(token *jwt.Token) (interface{}, error) {
+		return GetJWTSecret(), nil
+	}, jwt.WithAudience(oidcIdentityAudience), jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}))
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(*OIDCIdentityClaims); ok && token.Valid && claims.Subject != "" && claims.Provider != "" && claims.ProviderSubject != "" {
+		return claims, nil
+	}
+
+	return nil, errors.New("invalid account link")
+}
+
 // HashPassword hashes a password using bcrypt
 func HashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -100,33 +498,77 @@ func CheckPassword(password, hash string) bool {
 	return err == nil
 }
 
-// GenerateToken creates a JWT token for a user
+// GenerateToken creates an access token for a user that belongs to no
+// refresh token session
 func GenerateToken(user *store.User) (string, error) {
+	token, _, err := GenerateSessionToken(user, "")
+	return token, err
+}
+
+// GenerateSessionToken creates an access token for a user's refresh token
+// session and returns its claims, whose ID and expiry are needed to revoke it
+func GenerateSessionToken(user *store.User, sessionID string) (string, *Claims, error) {
+	tokenID, err := randomHex(16)
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
 	claims := &Claims{
-		UserID: user.ID,
-		Email:  user.Email,
-		Role:   user.Role,
+		UserID:    user.ID,
+		Email:     user.Email,
+		Role:      user.Role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        tokenID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
 			Subject:   user.ID,
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(GetJWTSecret())
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(GetJWTSecret())
+	if err != nil {
+		return "", nil, err
+	}
+	return token, claims, nil
+}
+
+// NewRefreshToken creates an opaque refresh token and the ID it is stored
+// under. Only the ID, a hash of the token, is kept.
+func NewRefreshToken() (token, id string, err error) {
+	token, err = randomHex(32)
+	if err != nil {
+		return "", "", err
+	}
+	return token, RefreshTokenID(token), nil
+}
+
+// RefreshTokenID returns the ID a refresh token is stored under
+func RefreshTokenID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
 }
 
 // ValidateToken validates a JWT token and returns the claims
 func ValidateToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		return GetJWTSecret(), nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}))
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+	// Login link, LTI and OIDC tokens share the secret but carry an audience
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid && len(claims.Audience) == 0 {
 		return claims, nil
 	}
 
@@ -139,6 +581,12 @@ func AuthMiddleware(s store.Store) func(http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Get token from Authorization header
 			authHeader := r.Header.Get("Authorization")
+			if authHeader == "" && isEventStream(r) {
+				// Browsers' EventSource cannot set headers, so streams may pass the token in the query
+				if token := r.URL.Query().Get("access_token"); token != "" {
+					authHeader = "Bearer " + token
+				}
+			}
 			if authHeader == "" {
 				http.Error(w, "Authorization required", http.StatusUnauthorized)
 				return
@@ -153,27 +601,47 @@ func AuthMiddleware(s store.Store) func(http.Handler) http.Handler {
 
 			tokenString := parts[1]
 
-			// Validate token
+			// Validate token. Tokens without an ID predate revocation and are refused.
 			claims, err := ValidateToken(tokenString)
-			if err != nil {
+			if err != nil || claims.ID == "" {
 				http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
 				return
 			}
 
+			revoked, err := s.IsTokenRevoked(claims.ID)
+			if err != nil {
+				http.Error(w, "Failed to check token", http.StatusInternalServerError)
+				return
+			}
+			if revoked {
+				http.Error(w, "Token has been revoked", http.StatusUnauthorized)
+				return
+			}
+
 			// Get user from database
 			user, err := s.GetUser(claims.UserID)
 			if err != nil || user == nil {
 				http.Error(w, "User not found", http.StatusUnauthorized)
 				return
 			}
+			if user.Disabled {
+				http.Error(w, "Account disabled", http.StatusUnauthorized)
+				return
+			}
 
-			// Add user to context
+			// Add user and token to context
 			ctx := context.WithValue(r.Context(), UserContextKey, user)
+			ctx = context.WithValue(ctx, ClaimsContextKey, claims)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// isEventStream reports whether r is a Server-Sent Events request
+func isEventStream(r *http.Request) bool {
+	return r.Method == http.MethodGet && strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
 // AdminMiddleware ensures the user is an admin
 func AdminMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -200,3 +668,12 @@ func GetUserFromContext(ctx context.Context) *store.User {
 	}
 	return user
 }
+
+// GetClaimsFromContext retrieves the access token's claims from context
+func GetClaimsFromContext(ctx context.Context) *Claims {
+	claims, ok := ctx.Value(ClaimsContextKey).(*Claims)
+	if !ok {
+		return nil
+	}
+	return claims
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/clarateach/backend/internal/store"
+	"github.com/golang-jwt/jwt/v5"
 )
 
 func TestHashPassword(t *testing.T) {
@@ -146,6 +147,37 @@ func TestValidateToken_Invalid(t *testing.T) {
 	}
 }
 
+func TestValidateToken_OtherTokens(t *testing.T) {
+	// Tokens for other flows are signed with the same secret but are never
+	// access tokens
+	loginLink, _ := GenerateLoginLinkToken("reg-1", "link-1", time.Now().Add(time.Hour))
+	ltiState, _ := GenerateLTIStateToken("lti-1", "nonce-1", time.Hour)
+	deepLink, _ := GenerateLTIDeepLinkToken("user-1", LTIDeepLinkClaims{PlatformID: "lti-1"}, time.Hour)
+	oidcState, _ := GenerateOIDCStateToken("user-1", OIDCStateClaims{Provider: "corp"}, time.Hour)
+	oidcLink, _ := GenerateOIDCLinkToken("user-1", "corp", time.Hour)
+	identity, _ := GenerateOIDCIdentityToken("user-1", OIDCIdentityClaims{Provider: "corp", ProviderSubject: "sub-1"}, time.Hour)
+
+	// Nor is a token signed with another algorithm, even with the secret
+	hs512, _ := jwt.NewWithClaims(jwt.SigningMethodHS512, &Claims{
+		UserID:           "user-1",
+		RegisteredClaims: jwt.RegisteredClaims{Subject: "user-1", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+	}).SignedString(GetJWTSecret())
+
+	for name, token := range map[string]string{
+		"login link":    loginLink,
+		"LTI state":     ltiState,
+		"deep link":     deepLink,
+		"OIDC state":    oidcState,
+		"OIDC link":     oidcLink,
+		"OIDC identity": identity,
+		"HS512":         hs512,
+	} {
+		if _, err := ValidateToken(token); err == nil {
+			t.Errorf("ValidateToken() accepted a %s token", name)
+		}
+	}
+}
+
 func TestGetUserFromContext(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -238,12 +270,14 @@ func TestAdminMiddleware(t *testing.T) {
 
 // MockStore implements store.Store for testing
 type MockStore struct {
-	users map[string]*store.User
+	users   map[string]*store.User
+	revoked map[string]bool
 }
 
 func NewMockStore() *MockStore {
 	return &MockStore{
-		users: make(map[string]*store.User),
+		users:   make(map[string]*store.User),
+		revoked: make(map[string]bool),
 	}
 }
 
@@ -257,7 +291,31 @@ func (m *MockStore) GetUserByEmail(email string) (*store.User, error) {
 	}
 	return nil, nil
 }
-func (m *MockStore) ListUsers() ([]*store.User, error) { return nil, nil }
+func (m *MockStore) ListUsers() ([]*store.User, error)             { return nil, nil }
+func (m *MockStore) UpdateUserRole(id, role string) error          { return nil }
+func (m *MockStore) SetUserDisabled(id string, disabled bool) error { return nil }
+
+
+// Token operations
+func (m *MockStore) CreateRefreshToken(t *store.RefreshToken) error         { return nil }
+func (m *MockStore) GetRefreshToken(id string) (*store.RefreshToken, error) { return nil, nil }
+func (m *MockStore) UseRefreshToken(id string, now time.Time) (bool, error) { return false, nil }
+func (m *MockStore) RevokeSession(sessionID string, now time.Time) error    { return nil }
+func (m *MockStore) RevokeUserSessions(userID string, now time.Time) error  { return nil }
+func (m *MockStore) IsTokenRevoked(tokenID string) (bool, error)            { return m.revoked[tokenID], nil }
+func (m *MockStore) DeleteExpiredTokens(now time.Time) error                { return nil }
+func (m *MockStore) RevokeToken(tokenID string, expiresAt, now time.Time) error {
+	m.revoked[tokenID] = true
+	return nil
+}
+
+// Identity operations
+func (m *MockStore) CreateUserIdentity(i *store.UserIdentity) error { return nil }
+func (m *MockStore) GetUserIdentity(provider, subject string) (*store.UserIdentity, error) {
+	return nil, nil
+}
+func (m *MockStore) ListUserIdentities(userID string) ([]*store.UserIdentity, error) { return nil, nil }
+func (m *MockStore) DeleteUserIdentity(userID, provider string) error                { return nil }
 
 // Workshop operations (minimal implementation for tests)
 func (m *MockStore) CreateWorkshop(w *store.Workshop) error                     { return nil }
@@ -266,7 +324,10 @@ func (m *MockStore) GetWorkshopByCode(code string) (*store.Workshop, error)
 func (m *MockStore) ListWorkshops() ([]*store.Workshop, error)                  { return nil, nil }
 func (m *MockStore) ListWorkshopsByOwner(ownerID string) ([]*store.Workshop, error) { return nil, nil }
 func (m *MockStore) UpdateWorkshopStatus(id string, status string) error        { return nil }
+func (m *MockStore) UpdateWorkshop(w *store.Workshop) error { return nil }
+func (m *MockStore) ResizeWorkshopSeats(workshopID string, seats int, added []*store.Session) error { return nil }
 func (m *MockStore) DeleteWorkshop(id string) error                             { return nil }
+func (m *MockStore) GetWorkshopStats() (*store.WorkshopStats, error)            { return nil, nil }
 
 // Session operations
 func (m *MockStore) CreateSession(s *store.Session) error                       { return nil }
@@ -280,6 +341,7 @@ func (m *MockStore) CreateVM(vm *store.WorkshopVM) error
 func (m *MockStore) GetVM(workshopID string) (*store.WorkshopVM, error)         { return nil, nil }
 func (m *MockStore) GetVMByID(id string) (*store.WorkshopVM, error)             { return nil, nil }
 func (m *MockStore) UpdateVM(vm *store.WorkshopVM) error                        { return nil }
+func (m *MockStore) UpdateVMTunnelURL(workshopID, tunnelURL string) error       { return nil }
 func (m *MockStore) MarkVMRemoved(workshopID string) error                      { return nil }
 func (m *MockStore) ListVMs() ([]*store.WorkshopVM, error)                      { return nil, nil }
 func (m *MockStore) ListAllVMs() ([]*store.WorkshopVM, error)                   { return nil, nil }
@@ -289,8 +351,61 @@ func (m *MockStore) GetVMPrivateKey(workshopID string) (string, error)
 func (m *MockStore) CreateRegistration(r *store.Registration) error             { return nil }
 func (m *MockStore) GetRegistration(accessCode string) (*store.Registration, error) { return nil, nil }
 func (m *MockStore) GetRegistrationByEmail(workshopID, email string) (*store.Registration, error) { return nil, nil }
+func (m *MockStore) GetRegistrationByID(id string) (*store.Registration, error) { return nil, nil }
+func (m *MockStore) CreateRegistrations(registrations []*store.Registration) error { return nil }
 func (m *MockStore) UpdateRegistration(r *store.Registration) error             { return nil }
 func (m *MockStore) CountRegistrations(workshopID string) (int, error)          { return 0, nil }
+func (m *MockStore) ListRegistrations(workshopID string) ([]*store.Registration, error) { return nil, nil }
+func (m *MockStore) UpdateRegistrationAccessCode(id, accessCode string) error { return nil }
+func (m *MockStore) ReorderWaitlist(workshopID string, registrationIDs []string) error { return nil }
+func (m *MockStore) CreateWorkshopEvent(e *store.WorkshopEvent) error            { return nil }
+func (m *MockStore) AddWorkshopMember(member *store.WorkshopMember) error { return nil }
+func (m *MockStore) GetWorkshopMember(workshopID, userID string) (*store.WorkshopMember, error) { return nil, nil }
+func (m *MockStore) ListWorkshopMembers(workshopID string) ([]*store.WorkshopMember, error) { return nil, nil }
+func (m *MockStore) RemoveWorkshopMember(workshopID, userID string) error { return nil }
+func (m *MockStore) CreateTerminalRecording(r *store.TerminalRecording) error { return nil }
+func (m *MockStore) ListTerminalRecordings(workshopID string) ([]*store.TerminalRecording, error) { return nil, nil }
+func (m *MockStore) GetTerminalRecording(id int64) (*store.TerminalRecording, error) { return nil, nil }
+func (m *MockStore) CreateAnnouncement(a *store.Announcement) error { return nil }
+func (m *MockStore) ListAnnouncements(workshopID string) ([]*store.Announcement, error) { return nil, nil }
+func (m *MockStore) CreateHelpRequest(h *store.HelpRequest) error { return nil }
+func (m *MockStore) GetHelpRequest(id int64) (*store.HelpRequest, error) { return nil, nil }
+func (m *MockStore) GetActiveHelpRequest(registrationID string) (*store.HelpRequest, error) { return nil, nil }
+func (m *MockStore) ListHelpRequests(workshopID string) ([]*store.HelpRequest, error) { return nil, nil }
+func (m *MockStore) ClaimHelpRequest(id int64, userID, userName string, at time.Time) (bool, error) { return false, nil }
+func (m *MockStore) ResolveHelpRequest(id int64, at time.Time) (bool, error) { return false, nil }
+func (m *MockStore) SetNotificationTemplate(t *store.NotificationTemplate) error { return nil }
+func (m *MockStore) GetNotificationTemplate(workshopID, kind string) (*store.NotificationTemplate, error) { return nil, nil }
+func (m *MockStore) ListNotificationTemplates(workshopID string) ([]*store.NotificationTemplate, error) { return nil, nil }
+func (m *MockStore) DeleteNotificationTemplate(workshopID, kind string) error { return nil }
+func (m *MockStore) ClaimNotification(n *store.Notification) (bool, error) { return false, nil }
+func (m *MockStore) UpdateNotification(n *store.Notification) error { return nil }
+func (m *MockStore) ListNotifications(workshopID string) ([]*store.Notification, error) { return nil, nil }
+func (m *MockStore) CreateLoginLink(l *store.LoginLink) error { return nil }
+func (m *MockStore) GetLatestLoginLink(registrationID string) (*store.LoginLink, error) { return nil, nil }
+func (m *MockStore) UseLoginLink(id, registrationID string, at time.Time) (bool, error) { return false, nil }
+func (m *MockStore) ExpireLoginLinks(registrationID string, at time.Time) error { return nil }
+func (m *MockStore) CreateWebhook(h *store.Webhook) error { return nil }
+func (m *MockStore) GetWebhook(id string) (*store.Webhook, error) { return nil, nil }
+func (m *MockStore) ListWebhooks(workshopID string) ([]*store.Webhook, error) { return nil, nil }
+func (m *MockStore) DeleteWebhook(id string) error { return nil }
+func (m *MockStore) CreateWebhookDelivery(d *store.WebhookDelivery) error { return nil }
+func (m *MockStore) GetWebhookDelivery(id int64) (*store.WebhookDelivery, error) { return nil, nil }
+func (m *MockStore) ListDueWebhookDeliveries(now time.Time, limit int) ([]*store.WebhookDelivery, error) { return nil, nil }
+func (m *MockStore) ClaimWebhookDelivery(id int64, now, leaseUntil time.Time) (bool, error) { return false, nil }
+func (m *MockStore) UpdateWebhookDelivery(d *store.WebhookDelivery) error { return nil }
+func (m *MockStore) ListWebhookDeliveries(webhookID string, limit int) ([]*store.WebhookDelivery, error) { return nil, nil }
+func (m *MockStore) CreateLTIPlatform(p *store.LTIPlatform) error { return nil }
+func (m *MockStore) GetLTIPlatform(id string) (*store.LTIPlatform, error) { return nil, nil }
+func (m *MockStore) ListLTIPlatforms() ([]*store.LTIPlatform, error) { return nil, nil }
+func (m *MockStore) SetLTIPlatformTrustEmail(id string, trust bool) error { return nil }
+func (m *MockStore) DeleteLTIPlatform(id string) error { return nil }
+func (m *MockStore) SetLTIContext(c *store.LTIContext) error { return nil }
+func (m *MockStore) GetLTIContext(platformID, contextID string) (*store.LTIContext, error) { return nil, nil }
+func (m *MockStore) AddLTILink(l *store.LTILink) error { return nil }
+func (m *MockStore) GetLTILink(platformID, contextID, workshopID string) (*store.LTILink, error) { return nil, nil }
+func (m *MockStore) ListWorkshopsByMember(userID string) ([]*store.Workshop, error) { return nil, nil }
+func (m *MockStore) ListWorkshopEvents(workshopID string) ([]*store.WorkshopEvent, error) { return nil, nil }
 
 func TestAuthMiddleware(t *testing.T) {
 	mockStore := NewMockStore()
@@ -352,3 +467,278 @@ func TestAuthMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestGeneratePreviewToken(t *testing.T) {
+	token, err := GeneratePreviewToken("ws-123", 2, 5173, "reg-1")
+	if err != nil {
+		t.Fatalf("GeneratePreviewToken() error = %v", err)
+	}
+
+	claims, err := ValidatePreviewToken(token)
+	if err != nil {
+		t.Fatalf("ValidatePreviewToken() error = %v", err)
+	}
+	if claims.WorkshopID != "ws-123" || claims.Seat != 2 || claims.Port != 5173 || claims.RegistrationID != "reg-1" {
+		t.Errorf("Preview claims = %+v, want ws-123 seat 2 port 5173 for reg-1", claims)
+	}
+
+	// Preview and workspace tokens are not interchangeable
+	if _, err := ValidateWorkspaceToken(token); err == nil {
+		t.Error("ValidateWorkspaceToken() should reject a preview token")
+	}
+	workspaceToken, _ := GenerateLearnerToken("ws-123", 2, "reg-1")
+	if _, err := ValidatePreviewToken(workspaceToken); err == nil {
+		t.Error("ValidatePreviewToken() should reject a workspace token")
+	}
+}
+
+func TestGenerateObserverToken(t *testing.T) {
+	token, err := GenerateObserverToken("ws-123", 2, "user-ta")
+	if err != nil {
+		t.Fatalf("GenerateObserverToken() error = %v", err)
+	}
+
+	claims, err := ValidateWorkspaceToken(token)
+	if err != nil {
+		t.Fatalf("ValidateWorkspaceToken() error = %v", err)
+	}
+	if !claims.IsObserver() || claims.UserID != "user-ta" || claims.WorkshopID != "ws-123" || claims.Seat != 2 {
+		t.Errorf("Observer claims = %+v, want observer of ws-123 seat 2 for user-ta", claims)
+	}
+
+	if claims.CanTakeControl() {
+		t.Error("Observer token should not be able to take control")
+	}
+
+	instructorToken, _ := GenerateInstructorToken("ws-123", 2, "user-co")
+	instructor, err := ValidateWorkspaceToken(instructorToken)
+	if err != nil {
+		t.Fatalf("ValidateWorkspaceToken() error = %v", err)
+	}
+	if instructor.IsObserver() || !instructor.IsStaff() || !instructor.CanTakeControl() {
+		t.Errorf("Instructor claims = %+v, want staff that can take control", instructor)
+	}
+
+	learnerToken, _ := GenerateWorkspaceToken("ws-123", 2)
+	learner, _ := ValidateWorkspaceToken(learnerToken)
+	if learner.IsObserver() {
+		t.Error("Learner workspace token should not be an observer token")
+	}
+	if learner.IsStaff() || learner.CanTakeControl() {
+		t.Error("Learner workspace token should not be a staff token")
+	}
+}
+
+func TestLoginLinkToken(t *testing.T) {
+	expires := time.Now().Add(30 * time.Minute)
+	token, err := GenerateLoginLinkToken("reg-1", "link-1", expires)
+	if err != nil {
+		t.Fatalf("GenerateLoginLinkToken() error = %v", err)
+	}
+
+	claims, err := ValidateLoginLinkToken(token)
+	if err != nil {
+		t.Fatalf("ValidateLoginLinkToken() error = %v", err)
+	}
+	if claims.RegistrationID != "reg-1" || claims.ID != "link-1" {
+		t.Errorf("Login link claims = %+v, want reg-1/link-1", claims)
+	}
+
+	expired, _ := GenerateLoginLinkToken("reg-1", "link-2", time.Now().Add(-time.Minute))
+	if _, err := ValidateLoginLinkToken(expired); err == nil {
+		t.Error("ValidateLoginLinkToken() should reject an expired link")
+	}
+
+	// Other tokens signed with the same secret must not work as login links
+	userToken, _ := GenerateToken(&store.User{ID: "user-1", Email: "test@example.com"})
+	if _, err := ValidateLoginLinkToken(userToken); err == nil {
+		t.Error("ValidateLoginLinkToken() should reject a user token")
+	}
+}
+
+func TestLTITokens(t *testing.T) {
+	state, err := GenerateLTIStateToken("lti-1", "nonce-1", 10*time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateLTIStateToken() error = %v", err)
+	}
+	claims, err := ValidateLTIStateToken(state)
+	if err != nil {
+		t.Fatalf("ValidateLTIStateToken() error = %v", err)
+	}
+	if claims.PlatformID != "lti-1" || claims.Nonce != "nonce-1" {
+		t.Errorf("LTI state claims = %+v, want lti-1/nonce-1", claims)
+	}
+
+	session, err := GenerateLTIDeepLinkToken("user-1", LTIDeepLinkClaims{PlatformID: "lti-1", ReturnURL: "https://lms.example.edu/deep_links"}, time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateLTIDeepLinkToken() error = %v", err)
+	}
+	deepLink, err := ValidateLTIDeepLinkToken(session)
+	if err != nil {
+		t.Fatalf("ValidateLTIDeepLinkToken() error = %v", err)
+	}
+	if deepLink.Subject != "user-1" || deepLink.ReturnURL != "https://lms.example.edu/deep_links" {
+		t.Errorf("Deep link claims = %+v", deepLink)
+	}
+
+	// Neither token works as the other, nor as a login link
+	if _, err := ValidateLTIStateToken(session); err == nil {
+		t.Error("ValidateLTIStateToken() should reject a deep linking session")
+	}
+	if _, err := ValidateLTIDeepLinkToken(state); err == nil {
+		t.Error("ValidateLTIDeepLinkToken() should reject a login state")
+	}
+	if _, err := ValidateLoginLinkToken(state); err == nil {
+		t.Error("ValidateLoginLinkToken() should reject a login state")
+	}
+
+	expired, _ := GenerateLTIStateToken("lti-1", "nonce-2", -time.Minute)
+	if _, err := ValidateLTIStateToken(expired); err == nil {
+		t.Error("ValidateLTIStateToken() should reject an expired state")
+	}
+}
+
+func TestOIDCTokens(t *testing.T) {
+	state, err := GenerateOIDCStateToken("", OIDCStateClaims{Provider: "google", State: "state-1", Nonce: "nonce-1", Verifier: "verifier-1"}, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateOIDCStateToken() error = %v", err)
+	}
+	claims, err := ValidateOIDCStateToken(state)
+	if err != nil {
+		t.Fatalf("ValidateOIDCStateToken() error = %v", err)
+	}
+	if claims.Provider != "google" || claims.State != "state-1" || claims.Verifier != "verifier-1" || claims.Subject != "" {
+		t.Errorf("OIDC state claims = %+v", claims)
+	}
+
+	link, err := GenerateOIDCLinkToken("user-1", "google", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateOIDCLinkToken() error = %v", err)
+	}
+	linkClaims, err := ValidateOIDCLinkToken(link)
+	if err != nil {
+		t.Fatalf("ValidateOIDCLinkToken() error = %v", err)
+	}
+	if linkClaims.Subject != "user-1" || linkClaims.Provider != "google" {
+		t.Errorf("OIDC link claims = %+v", linkClaims)
+	}
+
+	// Neither token works as the other, nor as an LTI state
+	if _, err := ValidateOIDCStateToken(link); err == nil {
+		t.Error("ValidateOIDCStateToken() should reject a link token")
+	}
+	if _, err := ValidateOIDCLinkToken(state); err == nil {
+		t.Error("ValidateOIDCLinkToken() should reject a state token")
+	}
+	if _, err := ValidateLTIStateToken(state); err == nil {
+		t.Error("ValidateLTIStateToken() should reject an OIDC state")
+	}
+
+	expired, _ := GenerateOIDCLinkToken("user-1", "google", -time.Minute)
+	if _, err := ValidateOIDCLinkToken(expired); err == nil {
+		t.Error("ValidateOIDCLinkToken() should reject an expired token")
+	}
+
+	identity, err := GenerateOIDCIdentityToken("user-1", OIDCIdentityClaims{Provider: "google", ProviderSubject: "google-123", Email: "ada@example.com"}, 5*time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateOIDCIdentityToken() error = %v", err)
+	}
+	identityClaims, err := ValidateOIDCIdentityToken(identity)
+	if err != nil {
+		t.Fatalf("ValidateOIDCIdentityToken() error = %v", err)
+	}
+	if identityClaims.Subject != "user-1" || identityClaims.Provider != "google" || identityClaims.ProviderSubject != "google-123" || identityClaims.Email != "ada@example.com" {
+		t.Errorf("OIDC identity claims = %+v", identityClaims)
+	}
+	// A link URL's token cannot stand in for a verified account, or the reverse
+	if _, err := ValidateOIDCIdentityToken(link); err == nil {
+		t.Error("ValidateOIDCIdentityToken() should reject a link token")
+	}
+	if _, err := ValidateOIDCLinkToken(identity); err == nil {
+		t.Error("ValidateOIDCLinkToken() should reject an identity token")
+	}
+}
+
+func TestSessionTokens(t *testing.T) {
+	user := &store.User{ID: "user-1", Email: "test@example.com", Role: "instructor"}
+	token, issued, err := GenerateSessionToken(user, "sess-1")
+	if err != nil {
+		t.Fatalf("GenerateSessionToken() error = %v", err)
+	}
+	claims, err := ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+	if claims.ID == "" || claims.ID != issued.ID || claims.SessionID != "sess-1" || claims.UserID != "user-1" {
+		t.Errorf("Session token claims = %+v, issued %+v", claims, issued)
+	}
+	if ttl := time.Until(claims.ExpiresAt.Time); ttl > AccessTokenTTL || ttl < AccessTokenTTL-time.Minute {
+		t.Errorf("Session token expires in %v, want %v", ttl, AccessTokenTTL)
+	}
+
+	// Every token gets its own ID so it can be revoked alone
+	other, _ := GenerateToken(user)
+	otherClaims, _ := ValidateToken(other)
+	if otherClaims.ID == "" || otherClaims.ID == claims.ID || otherClaims.SessionID != "" {
+		t.Errorf("GenerateToken() claims = %+v, want a new ID and no session", otherClaims)
+	}
+
+	refresh, id, err := NewRefreshToken()
+	if err != nil {
+		t.Fatalf("NewRefreshToken() error = %v", err)
+	}
+	if refresh == id || RefreshTokenID(refresh) != id {
+		t.Errorf("NewRefreshToken() id = %q, want the hash of the token", id)
+	}
+	if again, _, _ := NewRefreshToken(); again == refresh {
+		t.Error("NewRefreshToken() returned the same token twice")
+	}
+}
+
+func TestAuthMiddleware_RevokedAndDisabled(t *testing.T) {
+	mockStore := NewMockStore()
+	user := &store.User{ID: "user-1", Email: "test@example.com", Role: "instructor"}
+	mockStore.CreateUser(user)
+
+	serve := func(token string) (int, *Claims) {
+		var claims *Claims
+		handler := AuthMiddleware(mockStore)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims = GetClaimsFromContext(r.Context())
+			w.WriteHeader(http.StatusOK)
+		}))
+		req := httptest.NewRequest("GET", "/protected", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code, claims
+	}
+
+	token, issued, _ := GenerateSessionToken(user, "sess-1")
+	if code, claims := serve(token); code != http.StatusOK || claims == nil || claims.ID != issued.ID {
+		t.Fatalf("AuthMiddleware() = %d with claims %+v, want 200 with the token's claims", code, claims)
+	}
+
+	// Tokens issued before revocation existed have no ID
+	legacy := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{
+		UserID: user.ID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			Subject:   user.ID,
+		},
+	})
+	legacyToken, _ := legacy.SignedString(GetJWTSecret())
+	if code, _ := serve(legacyToken); code != http.StatusUnauthorized {
+		t.Errorf("AuthMiddleware() with a token without ID = %d, want 401", code)
+	}
+
+	mockStore.RevokeToken(issued.ID, issued.ExpiresAt.Time, time.Now())
+	if code, _ := serve(token); code != http.StatusUnauthorized {
+		t.Errorf("AuthMiddleware() with a revoked token = %d, want 401", code)
+	}
+
+	fresh, _ := GenerateToken(user)
+	user.Disabled = true
+	if code, _ := serve(fresh); code != http.StatusUnauthorized {
+		t.Errorf("AuthMiddleware() for a disabled user = %d, want 401", code)
+	}
+}
@@ -4,13 +4,13 @@ import (
 	"bufio"
 	"context"
 	"fmt"
-	"log"
 	"os"
 	"strings"
 	"time"
 
 	secretmanager "cloud.google.com/go/secretmanager/apiv1"
 	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"github.com/clarateach/backend/internal/logging"
 )
 
 // Config holds all application configuration
@@ -32,9 +32,46 @@ type Config struct {
 	FCAgentToken         string
 	BackendURL           string
 	WorkspaceTokenSecret string
+	RecordTerminals      bool // Record learner terminals on Firecracker workers
 
 	// CORS
 	CORSOrigins []string
+
+	// Metrics
+	MetricsToken string // Optional bearer token protecting /metrics
+
+	// Logging
+	LogLevel  string // debug, info, warn, error
+	LogFormat string // json or text
+
+	// Tracing
+	TracesExporter string // "otlp", "stdout", "file" or "none"
+	OTLPEndpoint   string // OTLP/HTTP collector URL, also passed to Firecracker agents
+	TracesFile     string // Output path for the "file" exporter
+
+	// Notifications
+	NotifyDriver string // "smtp", "file", "log" or "none"
+	NotifyFile   string // Output path for the "file" driver
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+	PublicURL    string        // Frontend base URL for links in learner emails
+	ReminderLead time.Duration // How long before starts_at to send reminders
+	LoginLinkTTL time.Duration // How long emailed learner login links work
+
+	// Sessions
+	RefreshTokenTTL time.Duration // How long a sign-in lasts without being refreshed
+
+	// Webhooks
+	WebhookAllowPrivate bool // Let webhooks reach loopback and private addresses
+
+	// LTI
+	LTIPrivateKey string // PEM RSA key signing LTI 1.3 messages; empty disables LTI
+
+	// Single sign-on
+	OIDCProviders string // JSON array of OIDC providers; empty disables SSO
 }
 
 // Load loads configuration from GCP Secret Manager with fallback to environment variables
@@ -54,7 +91,43 @@ func Load() (*Config, error) {
 		FCAgentToken:         getEnv("FC_AGENT_TOKEN", ""),
 		BackendURL:           getEnv("BACKEND_URL", ""),
 		WorkspaceTokenSecret: getEnv("WORKSPACE_TOKEN_SECRET", ""),
+		RecordTerminals:      getEnv("RECORD_TERMINALS", "") == "true",
+		MetricsToken:         getEnv("METRICS_TOKEN", ""),
+		LogLevel:             getEnv("LOG_LEVEL", "info"),
+		LogFormat:            getEnv("LOG_FORMAT", "json"),
+		TracesExporter:       getEnv("OTEL_TRACES_EXPORTER", "none"),
+		OTLPEndpoint:         getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+		TracesFile:           getEnv("TRACES_FILE", "traces.json"),
+		NotifyDriver:         getEnv("NOTIFY_DRIVER", "none"),
+		NotifyFile:           getEnv("NOTIFY_FILE", "notifications.log"),
+		SMTPHost:             getEnv("SMTP_HOST", ""),
+		SMTPPort:             getEnv("SMTP_PORT", "587"),
+		SMTPUsername:         getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:         getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:             getEnv("SMTP_FROM", ""),
+		PublicURL:            getEnv("PUBLIC_URL", ""),
+		WebhookAllowPrivate:  getEnv("WEBHOOK_ALLOW_PRIVATE", "") == "true",
+		LTIPrivateKey:        getEnv("LTI_PRIVATE_KEY", ""),
+		OIDCProviders:        getEnv("OIDC_PROVIDERS", ""),
+	}
+
+	reminderLead, err := time.ParseDuration(getEnv("REMINDER_LEAD", "24h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid REMINDER_LEAD: %w", err)
 	}
+	cfg.ReminderLead = reminderLead
+
+	loginLinkTTL, err := time.ParseDuration(getEnv("LOGIN_LINK_TTL", "30m"))
+	if err != nil || loginLinkTTL <= 0 {
+		return nil, fmt.Errorf("invalid LOGIN_LINK_TTL: %q", getEnv("LOGIN_LINK_TTL", "30m"))
+	}
+	cfg.LoginLinkTTL = loginLinkTTL
+
+	refreshTokenTTL, err := time.ParseDuration(getEnv("REFRESH_TOKEN_TTL", "720h"))
+	if err != nil || refreshTokenTTL <= 0 {
+		return nil, fmt.Errorf("invalid REFRESH_TOKEN_TTL: %q", getEnv("REFRESH_TOKEN_TTL", "720h"))
+	}
+	cfg.RefreshTokenTTL = refreshTokenTTL
 
 	// Load DATABASE_URL - try Secret Manager first, then env
 	databaseURL, err := getSecret(gcpProject, "DATABASE_URL")
@@ -70,6 +143,18 @@ func Load() (*Config, error) {
 	if secret, err := getSecret(gcpProject, "WORKSPACE_TOKEN_SECRET"); err == nil && secret != "" {
 		cfg.WorkspaceTokenSecret = secret
 	}
+	if token, err := getSecret(gcpProject, "METRICS_TOKEN"); err == nil && token != "" {
+		cfg.MetricsToken = token
+	}
+	if password, err := getSecret(gcpProject, "SMTP_PASSWORD"); err == nil && password != "" {
+		cfg.SMTPPassword = password
+	}
+	if key, err := getSecret(gcpProject, "LTI_PRIVATE_KEY"); err == nil && key != "" {
+		cfg.LTIPrivateKey = key
+	}
+	if providers, err := getSecret(gcpProject, "OIDC_PROVIDERS"); err == nil && providers != "" {
+		cfg.OIDCProviders = providers
+	}
 
 	// Parse CORS origins
 	corsOrigins := getEnv("CORS_ORIGINS", "*")
@@ -97,6 +182,12 @@ func (c *Config) Validate() error {
 	if c.GCPRegistry == "" {
 		return fmt.Errorf("GCP_REGISTRY is required")
 	}
+	if c.LTIPrivateKey != "" && (c.BackendURL == "" || c.PublicURL == "") {
+		return fmt.Errorf("BACKEND_URL and PUBLIC_URL are required when LTI_PRIVATE_KEY is set")
+	}
+	if c.OIDCProviders != "" && (c.BackendURL == "" || c.PublicURL == "") {
+		return fmt.Errorf("BACKEND_URL and PUBLIC_URL are required when OIDC_PROVIDERS is set")
+	}
 	return nil
 }
 
@@ -112,7 +203,7 @@ func getSecret(project, secretName string) (string, error) {
 
 	client, err := secretmanager.NewClient(ctx)
 	if err != nil {
-		log.Printf("Secret Manager client creation failed (falling back to env): %v", err)
+		logging.Component("config").Warn("secret manager client creation failed; falling back to env", "error", err)
 		return "", nil
 	}
 	defer client.Close()
@@ -147,7 +238,7 @@ func loadEnvFile(filename string) {
 	}
 	defer file.Close()
 
-	log.Printf("Loading environment from %s", filename)
+	logging.Component("config").Info("loading environment", "file", filename)
 
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
@@ -0,0 +1,110 @@
+// Package events provides an in-process publish/subscribe bus for workshop
+// activity.
+//
+// The API server publishes workshop status transitions, seat changes,
+// registrations, waitlist changes, joins, provisioning timeline entries,
+// announcements and help requests. Dashboard streams subscribe to a single workshop or to every
+// workshop. Delivery is best effort: a subscriber that falls behind misses
+// events rather than blocking publishers, so streams should send a fresh
+// snapshot when they (re)connect.
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types published by the API server.
+const (
+	TypeWorkshopStatus = "workshop.status"   // Data: status
+	TypeSeatStatus     = "seat.status"       // Data: status, name
+	TypeRegistration   = "registration"      // Data: name, email
+	TypeJoin           = "join"              // Data: name
+	TypeTimeline       = "workshop.timeline" // Data: event, message
+	TypeAnnouncement   = "announcement"      // Data: id, message, author_name
+	TypeHelpRequest    = "help_request"      // Data: the help request, on every change
+	TypeWaitlist       = "waitlist"          // Data: name, email, status, waitlist_position
+
+	// TypeSnapshot is sent first on every stream with the current state, so
+	// clients never depend on having seen earlier events.
+	TypeSnapshot = "snapshot"
+)
+
+// Event is a single notification about a workshop.
+type Event struct {
+	Type       string                 `json:"type"`
+	WorkshopID string                 `json:"workshop_id"`
+	SeatID     int                    `json:"seat_id,omitempty"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+	Time       time.Time              `json:"time"`
+}
+
+// subscriberBuffer is how many events a slow subscriber may lag behind before
+// further events are dropped for it.
+const subscriberBuffer = 64
+
+type subscriber struct {
+	workshopID string // Empty receives events for all workshops
+	ch         chan Event
+}
+
+// Bus fans published events out to subscribers. The zero value is not usable;
+// create one with NewBus.
+type Bus struct {
+	mu   sync.RWMutex
+	subs map[*subscriber]struct{}
+}
+
+// NewBus creates an empty bus.
+func NewBus() *Bus {
+	return &Bus{subs: make(map[*subscriber]struct{})}
+}
+
+// Publish delivers e to every matching subscriber without blocking. Time is
+// set to now if unset.
+func (b *Bus) Publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for sub := range b.subs {
+		if sub.workshopID != "" && sub.workshopID != e.WorkshopID {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			// Subscriber is not keeping up; drop rather than stall the publisher
+		}
+	}
+}
+
+// Subscribe returns a channel receiving events for workshopID, or for all
+// workshops if workshopID is empty. The returned func unsubscribes and closes
+// the channel; it must be called exactly once.
+func (b *Bus) Subscribe(workshopID string) (<-chan Event, func()) {
+	sub := &subscriber{
+		workshopID: workshopID,
+		ch:         make(chan Event, subscriberBuffer),
+	}
+
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+
+	return sub.ch, func() {
+		b.mu.Lock()
+		delete(b.subs, sub)
+		b.mu.Unlock()
+		close(sub.ch)
+	}
+}
+
+// Subscribers returns the current number of subscribers.
+func (b *Bus) Subscribers() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subs)
+}
@@ -0,0 +1,153 @@
+// Package jwks fetches and caches the JSON Web Key Sets that identity
+// providers and LMS platforms publish their token signing keys in.
+package jwks
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	setTTL     = time.Hour   // How long a fetched key set is trusted
+	setRefetch = time.Minute // Minimum time between fetches for an unknown key ID
+	setMaxSize = 1 << 20     // Largest key set document read
+)
+
+// Key is the subset of a JSON Web Key needed for RSA signatures.
+type Key struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// RSAKey returns the JSON Web Key for an RS256 signing key.
+func RSAKey(kid string, pub *rsa.PublicKey) Key {
+	return Key{
+		Kty: "RSA",
+		Kid: kid,
+		Use: "sig",
+		Alg: "RS256",
+		N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}
+}
+
+type keySet struct {
+	keys    map[string]*rsa.PublicKey
+	fetched time.Time
+}
+
+// Cache fetches and caches key sets by URL.
+type Cache struct {
+	client *http.Client
+	mu     sync.Mutex
+	sets   map[string]*keySet // By key set URL
+}
+
+// NewCache creates an empty cache.
+func NewCache() *Cache {
+	return &Cache{
+		client: &http.Client{Timeout: 10 * time.Second},
+		sets:   make(map[string]*keySet),
+	}
+}
+
+// Key returns the RSA key with the given ID from the key set at url. The
+// set is fetched again when it is stale or does not contain the key, so
+// issuers can rotate keys. An empty kid matches a set with one key.
+func (c *Cache) Key(ctx context.Context, url, kid string) (*rsa.PublicKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	set := c.sets[url]
+	if set != nil && time.Since(set.fetched) < setTTL {
+		if key := set.find(kid); key != nil {
+			return key, nil
+		}
+		if time.Since(set.fetched) < setRefetch {
+			return nil, fmt.Errorf("no key %q in key set", kid)
+		}
+	}
+
+	keys, err := c.fetch(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	set = &keySet{keys: keys, fetched: time.Now()}
+	c.sets[url] = set
+	if key := set.find(kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("no key %q in key set", kid)
+}
+
+func (s *keySet) find(kid string) *rsa.PublicKey {
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key
+		}
+	}
+	return s.keys[kid]
+}
+
+func (c *Cache) fetch(ctx context.Context, url string) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching key set: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching key set: %s", resp.Status)
+	}
+
+	var doc struct {
+		Keys []Key `json:"keys"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, setMaxSize)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decoding key set: %w", err)
+	}
+	keys := make(map[string]*rsa.PublicKey)
+	for _, key := range doc.Keys {
+		if key.Kty != "RSA" || (key.Use != "" && key.Use != "sig") {
+			continue
+		}
+		pub, err := key.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[key.Kid] = pub
+	}
+	return keys, nil
+}
+
+func (k Key) publicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid RSA key")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
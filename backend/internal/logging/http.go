@@ -0,0 +1,100 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Headers used to forward correlation fields between the control plane and agents.
+const (
+	HeaderRequestID  = "X-Request-ID"
+	HeaderWorkshopID = "X-Workshop-ID"
+	HeaderSeatID     = "X-Seat-ID"
+)
+
+// Middleware assigns each request an ID (reusing an incoming X-Request-ID),
+// picks up forwarded workshop/seat headers, echoes the ID in the response and
+// logs one structured line per request.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(HeaderRequestID)
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+		ctx := WithRequestID(r.Context(), requestID)
+		if workshopID := r.Header.Get(HeaderWorkshopID); workshopID != "" {
+			ctx = WithWorkshop(ctx, workshopID)
+			if seat, err := strconv.Atoi(r.Header.Get(HeaderSeatID)); err == nil && seat > 0 {
+				ctx = WithSeat(ctx, workshopID, seat)
+			}
+		}
+		w.Header().Set(HeaderRequestID, requestID)
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		start := time.Now()
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		level := slog.LevelInfo
+		if status >= 500 {
+			level = slog.LevelError
+		}
+		slog.Default().Log(ctx, level, "http request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", status,
+			"bytes", ww.BytesWritten(),
+			"duration_ms", time.Since(start).Milliseconds(),
+			"remote_addr", r.RemoteAddr,
+		)
+	})
+}
+
+// Transport wraps base so outgoing requests carry the correlation fields of
+// their request context as headers. A nil base uses http.DefaultTransport.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		ctx := req.Context()
+		requestID, workshopID, seat := RequestID(ctx), WorkshopID(ctx), SeatID(ctx)
+		if requestID == "" && workshopID == "" {
+			return base.RoundTrip(req)
+		}
+
+		// RoundTrippers must not modify the caller's request
+		req = req.Clone(ctx)
+		if requestID != "" {
+			req.Header.Set(HeaderRequestID, requestID)
+		}
+		if workshopID != "" {
+			req.Header.Set(HeaderWorkshopID, workshopID)
+		}
+		if seat != 0 {
+			req.Header.Set(HeaderSeatID, strconv.Itoa(seat))
+		}
+		return base.RoundTrip(req)
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,144 @@
+// Package logging provides the shared structured logger for the control plane,
+// worker agent and tooling.
+//
+// Logs are written with log/slog. Correlation fields (request_id, workshop_id,
+// seat_id) are stored on the context and added to every record logged with a
+// *Context method, so callers only attach them once per request or workshop.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Context fields added to every record by the handler.
+const (
+	KeyRequestID  = "request_id"
+	KeyWorkshopID = "workshop_id"
+	KeySeatID     = "seat_id"
+)
+
+// Config selects the log level and output format.
+type Config struct {
+	Level  string // debug, info, warn, error (default: info)
+	Format string // json (default) or text
+}
+
+// ConfigFromEnv reads LOG_LEVEL and LOG_FORMAT.
+func ConfigFromEnv() Config {
+	return Config{
+		Level:  os.Getenv("LOG_LEVEL"),
+		Format: os.Getenv("LOG_FORMAT"),
+	}
+}
+
+// Setup installs a logger writing to stderr as the slog default. Output from
+// the standard log package is routed through it as well.
+func Setup(cfg Config) *slog.Logger {
+	logger := New(os.Stderr, cfg)
+	slog.SetDefault(logger)
+	return logger
+}
+
+// New creates a logger that adds context correlation fields to each record.
+func New(w io.Writer, cfg Config) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: ParseLevel(cfg.Level)}
+
+	var h slog.Handler
+	if strings.EqualFold(cfg.Format, "text") {
+		h = slog.NewTextHandler(w, opts)
+	} else {
+		h = slog.NewJSONHandler(w, opts)
+	}
+	return slog.New(&contextHandler{Handler: h})
+}
+
+// ParseLevel converts a level name to a slog.Level, defaulting to info.
+func ParseLevel(s string) slog.Level {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+// Component returns the default logger tagged with a component name.
+func Component(name string) *slog.Logger {
+	return slog.Default().With("component", name)
+}
+
+type ctxKey int
+
+const (
+	requestIDKey ctxKey = iota
+	workshopIDKey
+	seatIDKey
+)
+
+// WithRequestID returns ctx carrying the request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// WithWorkshop returns ctx carrying the workshop ID.
+func WithWorkshop(ctx context.Context, workshopID string) context.Context {
+	return context.WithValue(ctx, workshopIDKey, workshopID)
+}
+
+// WithSeat returns ctx carrying the workshop and seat IDs.
+func WithSeat(ctx context.Context, workshopID string, seatID int) context.Context {
+	return context.WithValue(WithWorkshop(ctx, workshopID), seatIDKey, seatID)
+}
+
+// RequestID returns the request ID from ctx, or "".
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// WorkshopID returns the workshop ID from ctx, or "".
+func WorkshopID(ctx context.Context) string {
+	id, _ := ctx.Value(workshopIDKey).(string)
+	return id
+}
+
+// SeatID returns the seat ID from ctx, or 0.
+func SeatID(ctx context.Context) int {
+	id, _ := ctx.Value(seatIDKey).(int)
+	return id
+}
+
+// contextHandler adds correlation fields from the context to each record.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx != nil {
+		if id := RequestID(ctx); id != "" {
+			r.AddAttrs(slog.String(KeyRequestID, id))
+		}
+		if id := WorkshopID(ctx); id != "" {
+			r.AddAttrs(slog.String(KeyWorkshopID, id))
+		}
+		if seat := SeatID(ctx); seat != 0 {
+			r.AddAttrs(slog.Int(KeySeatID, seat))
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,116 @@
+package lti
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/clarateach/backend/internal/jwks"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Lifetime of deep linking responses
+const responseTTL = 5 * time.Minute
+
+// ToolKey is the key the tool signs its messages to platforms with.
+type ToolKey struct {
+	ID  string
+	key *rsa.PrivateKey
+}
+
+// ParseToolKey reads a PEM encoded RSA private key in PKCS #1 or PKCS #8
+// form.
+func ParseToolKey(data []byte) (*ToolKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block in LTI private key")
+	}
+	var key *rsa.PrivateKey
+	if k, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		key = k
+	} else {
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing LTI private key: %w", err)
+		}
+		rsaKey, ok := parsed.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("LTI private key is not an RSA key")
+		}
+		key = rsaKey
+	}
+	return newToolKey(key)
+}
+
+// GenerateToolKey creates a new 2048-bit tool key.
+func GenerateToolKey() (*ToolKey, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	return newToolKey(key)
+}
+
+// newToolKey derives the key ID from the public key, so it stays the same
+// across restarts and changes when the key is rotated.
+func newToolKey(key *rsa.PrivateKey) (*ToolKey, error) {
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(der)
+	return &ToolKey{ID: hex.EncodeToString(sum[:8]), key: key}, nil
+}
+
+// PublicKey returns the public half of the key.
+func (t *ToolKey) PublicKey() *rsa.PublicKey {
+	return &t.key.PublicKey
+}
+
+// KeySet returns the tool's public key set document for platforms to verify
+// its messages with.
+func (t *ToolKey) KeySet() map[string]interface{} {
+	return map[string]interface{}{"keys": []jwks.Key{jwks.RSAKey(t.ID, &t.key.PublicKey)}}
+}
+
+// ContentItem is an item returned to the platform from deep linking.
+type ContentItem struct {
+	Type   string            `json:"type"` // Always "ltiResourceLink" here
+	Title  string            `json:"title,omitempty"`
+	URL    string            `json:"url,omitempty"`
+	Custom map[string]string `json:"custom,omitempty"`
+}
+
+// DeepLinkingResponse returns the signed LtiDeepLinkingResponse JWT that
+// hands items back to the platform. data is the value from the request's
+// deep linking settings.
+func (t *ToolKey) DeepLinkingResponse(p Platform, deploymentID, data string, items []ContentItem) (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":             p.ClientID,
+		"aud":             p.Issuer,
+		"iat":             now.Unix(),
+		"exp":             now.Add(responseTTL).Unix(),
+		"nonce":           hex.EncodeToString(nonce),
+		ClaimMessageType:  MessageDeepLinkingResponse,
+		ClaimVersion:      Version,
+		ClaimDeploymentID: deploymentID,
+		ClaimContentItems: items,
+	}
+	if data != "" {
+		claims[ClaimData] = data
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = t.ID
+	return token.SignedString(t.key)
+}
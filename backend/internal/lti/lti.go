@@ -0,0 +1,176 @@
+// Package lti implements the tool side of LTI 1.3, so an LMS such as Canvas
+// or Moodle can launch learners into workshops.
+//
+// A launch starts with OIDC third-party login initiation: the platform sends
+// the browser to the tool's login endpoint, the tool redirects it back to the
+// platform's authorization endpoint with a nonce, and the platform posts a
+// signed ID token describing the user and course to the tool's launch
+// endpoint. ID tokens are verified against the platform's published key set.
+// Deep linking responses, which tell the platform which workshop an
+// instructor picked, are signed with the tool's own key.
+package lti
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/clarateach/backend/internal/jwks"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Version is the only LTI version accepted.
+const Version = "1.3.0"
+
+// Message types.
+const (
+	MessageResourceLink        = "LtiResourceLinkRequest"
+	MessageDeepLinkingRequest  = "LtiDeepLinkingRequest"
+	MessageDeepLinkingResponse = "LtiDeepLinkingResponse"
+)
+
+// Claim names from the LTI core and deep linking specifications.
+const (
+	ClaimMessageType         = "https://purl.imsglobal.org/spec/lti/claim/message_type"
+	ClaimVersion             = "https://purl.imsglobal.org/spec/lti/claim/version"
+	ClaimDeploymentID        = "https://purl.imsglobal.org/spec/lti/claim/deployment_id"
+	ClaimTargetLinkURI       = "https://purl.imsglobal.org/spec/lti/claim/target_link_uri"
+	ClaimResourceLink        = "https://purl.imsglobal.org/spec/lti/claim/resource_link"
+	ClaimContext             = "https://purl.imsglobal.org/spec/lti/claim/context"
+	ClaimRoles               = "https://purl.imsglobal.org/spec/lti/claim/roles"
+	ClaimCustom              = "https://purl.imsglobal.org/spec/lti/claim/custom"
+	ClaimDeepLinkingSettings = "https://purl.imsglobal.org/spec/lti-dl/claim/deep_linking_settings"
+	ClaimContentItems        = "https://purl.imsglobal.org/spec/lti-dl/claim/content_items"
+	ClaimData                = "https://purl.imsglobal.org/spec/lti-dl/claim/data"
+)
+
+// Context roles that count as teaching staff.
+var instructorRoles = []string{
+	"http://purl.imsglobal.org/vocab/lis/v2/membership#Instructor",
+	"http://purl.imsglobal.org/vocab/lis/v2/membership#ContentDeveloper",
+	"http://purl.imsglobal.org/vocab/lis/v2/membership#Administrator",
+	"http://purl.imsglobal.org/vocab/lis/v2/institution/person#Administrator",
+}
+
+// Platform identifies an LMS the tool is registered with.
+type Platform struct {
+	Issuer   string // iss of the platform's ID tokens
+	ClientID string // The tool's client ID on the platform
+	JWKSURL  string // Where the platform publishes its signing keys
+}
+
+// ResourceLink is the link in the course that was launched.
+type ResourceLink struct {
+	ID    string `json:"id"`
+	Title string `json:"title,omitempty"`
+}
+
+// Context is the course the launch came from.
+type Context struct {
+	ID    string `json:"id"`
+	Label string `json:"label,omitempty"`
+	Title string `json:"title,omitempty"`
+}
+
+// DeepLinkingSettings says where to send the instructor's selection.
+type DeepLinkingSettings struct {
+	ReturnURL   string   `json:"deep_link_return_url"`
+	AcceptTypes []string `json:"accept_types"`
+	Data        string   `json:"data,omitempty"` // Must be echoed back unchanged
+}
+
+// LaunchClaims are the claims of a platform's ID token.
+type LaunchClaims struct {
+	Nonce         string                 `json:"nonce"`
+	AuthorizedBy  string                 `json:"azp,omitempty"`
+	Email         string                 `json:"email,omitempty"`
+	Name          string                 `json:"name,omitempty"`
+	GivenName     string                 `json:"given_name,omitempty"`
+	FamilyName    string                 `json:"family_name,omitempty"`
+	MessageType   string                 `json:"https://purl.imsglobal.org/spec/lti/claim/message_type"`
+	Version       string                 `json:"https://purl.imsglobal.org/spec/lti/claim/version"`
+	DeploymentID  string                 `json:"https://purl.imsglobal.org/spec/lti/claim/deployment_id"`
+	TargetLinkURI string                 `json:"https://purl.imsglobal.org/spec/lti/claim/target_link_uri,omitempty"`
+	ResourceLink  *ResourceLink          `json:"https://purl.imsglobal.org/spec/lti/claim/resource_link,omitempty"`
+	Context       *Context               `json:"https://purl.imsglobal.org/spec/lti/claim/context,omitempty"`
+	Roles         []string               `json:"https://purl.imsglobal.org/spec/lti/claim/roles"`
+	Custom        map[string]interface{} `json:"https://purl.imsglobal.org/spec/lti/claim/custom,omitempty"`
+	DeepLinking   *DeepLinkingSettings   `json:"https://purl.imsglobal.org/spec/lti-dl/claim/deep_linking_settings,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// IsInstructor reports whether the user teaches or administers the course.
+func (c *LaunchClaims) IsInstructor() bool {
+	for _, role := range c.Roles {
+		if slices.Contains(instructorRoles, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// CustomValue returns a custom parameter as a string, or "" if it is not set.
+func (c *LaunchClaims) CustomValue(key string) string {
+	if v, ok := c.Custom[key]; ok && v != nil {
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+// DisplayName returns the user's full name, built from its parts if the
+// platform did not send one.
+func (c *LaunchClaims) DisplayName() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return strings.TrimSpace(c.GivenName + " " + c.FamilyName)
+}
+
+// ParseLaunch verifies an ID token posted to the launch endpoint: its
+// signature against the platform's keys from keys, issuer, audience, expiry, LTI
+// version and the claims its message type requires. Checking the nonce and
+// deployment is up to the caller.
+func ParseLaunch(ctx context.Context, keys *jwks.Cache, p Platform, idToken string) (*LaunchClaims, error) {
+	claims := &LaunchClaims{}
+	_, err := jwt.ParseWithClaims(idToken, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return keys.Key(ctx, p.JWKSURL, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithIssuer(p.Issuer),
+		jwt.WithAudience(p.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id_token: %w", err)
+	}
+
+	// With several audiences the token must say it was issued for this tool
+	if len(claims.Audience) > 1 && claims.AuthorizedBy != p.ClientID {
+		return nil, errors.New("invalid id_token: azp does not match the client ID")
+	}
+	if claims.Version != Version {
+		return nil, fmt.Errorf("unsupported LTI version %q", claims.Version)
+	}
+	if claims.Subject == "" || claims.DeploymentID == "" {
+		return nil, errors.New("id_token is missing sub or deployment_id")
+	}
+	switch claims.MessageType {
+	case MessageResourceLink:
+		if claims.ResourceLink == nil || claims.ResourceLink.ID == "" {
+			return nil, errors.New("resource link launch is missing resource_link")
+		}
+	case MessageDeepLinkingRequest:
+		if claims.DeepLinking == nil || claims.DeepLinking.ReturnURL == "" {
+			return nil, errors.New("deep linking request is missing deep_linking_settings")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported message type %q", claims.MessageType)
+	}
+	return claims, nil
+}
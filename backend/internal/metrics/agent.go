@@ -0,0 +1,215 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TunnelStatus reports the state of the agent's Cloudflare tunnel.
+// It is satisfied by *tunnel.Manager.
+type TunnelStatus interface {
+	IsRegistered() bool
+	TunnelURL() string
+}
+
+// AgentConfig describes the worker whose metrics are being exported.
+type AgentConfig struct {
+	WorkerID string
+	Capacity int
+	DiskPath string     // Filesystem checked for free space (e.g., the MicroVM socket dir)
+	VMCount  func() int // Returns the number of running MicroVMs
+}
+
+// AgentMetrics holds the collectors exported by the worker agent.
+type AgentMetrics struct {
+	registry        *prometheus.Registry
+	createDuration  *prometheus.HistogramVec
+	destroyDuration *prometheus.HistogramVec
+	wsConnections   *prometheus.GaugeVec
+	proxiedBytes    *prometheus.CounterVec
+	fileProxyErrors *prometheus.CounterVec
+	agent           *agentCollector
+}
+
+// NewAgentMetrics creates the worker agent collectors.
+func NewAgentMetrics(cfg AgentConfig) *AgentMetrics {
+	m := &AgentMetrics{
+		registry: newRegistry(),
+		createDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "clarateach_agent_vm_create_duration_seconds",
+			Help:    "MicroVM create latency by result.",
+			Buckets: []float64{.1, .25, .5, 1, 2, 5, 10, 20, 30, 60},
+		}, []string{"result"}),
+		destroyDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "clarateach_agent_vm_destroy_duration_seconds",
+			Help:    "MicroVM destroy latency by result.",
+			Buckets: []float64{.05, .1, .25, .5, 1, 2, 5, 10, 30},
+		}, []string{"result"}),
+		wsConnections: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "clarateach_agent_proxy_websocket_connections",
+			Help: "Open proxied WebSocket connections by kind.",
+		}, []string{"kind"}),
+		proxiedBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "clarateach_agent_proxied_bytes_total",
+			Help: "Bytes proxied to and from learner MicroVMs.",
+		}, []string{"workshop_id", "seat_id", "kind", "direction"}),
+		fileProxyErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "clarateach_agent_file_proxy_errors_total",
+			Help: "File proxy failures by reason.",
+		}, []string{"reason"}),
+		agent: newAgentCollector(cfg),
+	}
+
+	m.registry.MustRegister(m.createDuration, m.destroyDuration,
+		m.wsConnections, m.proxiedBytes, m.fileProxyErrors, m.agent)
+	return m
+}
+
+// Handler serves the agent metrics, optionally protected by token.
+func (m *AgentMetrics) Handler(token string) http.Handler {
+	return Handler(m.registry, token)
+}
+
+// SetTunnel attaches the tunnel manager once it has been started.
+func (m *AgentMetrics) SetTunnel(t TunnelStatus) {
+	m.agent.setTunnel(t)
+}
+
+// CreateStarted marks a MicroVM create as in flight and returns a func that
+// records its latency once the outcome is known.
+func (m *AgentMetrics) CreateStarted() func(err error) {
+	start := time.Now()
+	m.agent.creating.Add(1)
+	return func(err error) {
+		m.agent.creating.Add(-1)
+		m.createDuration.WithLabelValues(result(err)).Observe(time.Since(start).Seconds())
+	}
+}
+
+// ObserveDestroy records how long a MicroVM destroy took.
+func (m *AgentMetrics) ObserveDestroy(d time.Duration, err error) {
+	m.destroyDuration.WithLabelValues(result(err)).Observe(d.Seconds())
+}
+
+// WebSocketOpened counts an open proxied WebSocket and returns a func to call when it closes.
+func (m *AgentMetrics) WebSocketOpened(kind string) func() {
+	g := m.wsConnections.WithLabelValues(kind)
+	g.Inc()
+	return g.Dec
+}
+
+// AddProxiedBytes records bytes moved for a seat. Direction is "in" (client to
+// MicroVM) or "out" (MicroVM to client).
+func (m *AgentMetrics) AddProxiedBytes(workshopID string, seatID int, kind, direction string, n int) {
+	if n <= 0 {
+		return
+	}
+	m.proxiedBytes.WithLabelValues(workshopID, strconv.Itoa(seatID), kind, direction).Add(float64(n))
+}
+
+// ForgetWorkshop drops a workshop's per-seat series once its last MicroVM is
+// gone, so a long-lived worker does not export every seat it ever hosted.
+func (m *AgentMetrics) ForgetWorkshop(workshopID string) {
+	m.proxiedBytes.DeletePartialMatch(prometheus.Labels{"workshop_id": workshopID})
+}
+
+// FileProxyError counts a failed file proxy request.
+func (m *AgentMetrics) FileProxyError(reason string) {
+	m.fileProxyErrors.WithLabelValues(reason).Inc()
+}
+
+func result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
+
+// agentCollector reports VM counts, tunnel status and host headroom at scrape time.
+type agentCollector struct {
+	cfg      AgentConfig
+	creating atomic.Int64 // MicroVM create requests in flight
+
+	mu     sync.RWMutex
+	tunnel TunnelStatus
+
+	vms            *prometheus.Desc
+	capacity       *prometheus.Desc
+	tunnelUp       *prometheus.Desc
+	memAvailable   *prometheus.Desc
+	memTotal       *prometheus.Desc
+	diskAvailable  *prometheus.Desc
+	load1          *prometheus.Desc
+	availableSlots *prometheus.Desc
+}
+
+func newAgentCollector(cfg AgentConfig) *agentCollector {
+	constLabels := prometheus.Labels{"worker_id": cfg.WorkerID}
+	return &agentCollector{
+		cfg:            cfg,
+		vms:            prometheus.NewDesc("clarateach_agent_vms", "MicroVMs by state: creating or running.", []string{"state"}, constLabels),
+		capacity:       prometheus.NewDesc("clarateach_agent_capacity", "Maximum MicroVMs this worker may host.", nil, constLabels),
+		availableSlots: prometheus.NewDesc("clarateach_agent_available_slots", "Remaining MicroVM slots before the worker is at capacity.", nil, constLabels),
+		tunnelUp:       prometheus.NewDesc("clarateach_agent_tunnel_up", "1 if the Cloudflare tunnel is registered with the control plane.", []string{"tunnel_url"}, constLabels),
+		memAvailable:   prometheus.NewDesc("clarateach_agent_host_memory_available_bytes", "Host memory available for new MicroVMs.", nil, constLabels),
+		memTotal:       prometheus.NewDesc("clarateach_agent_host_memory_total_bytes", "Total host memory.", nil, constLabels),
+		diskAvailable:  prometheus.NewDesc("clarateach_agent_host_disk_available_bytes", "Free space on the MicroVM rootfs filesystem.", []string{"path"}, constLabels),
+		load1:          prometheus.NewDesc("clarateach_agent_host_load1", "Host 1-minute load average.", nil, constLabels),
+	}
+}
+
+func (c *agentCollector) setTunnel(t TunnelStatus) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tunnel = t
+}
+
+func (c *agentCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{c.vms, c.capacity, c.availableSlots, c.tunnelUp, c.memAvailable, c.memTotal, c.diskAvailable, c.load1} {
+		ch <- d
+	}
+}
+
+func (c *agentCollector) Collect(ch chan<- prometheus.Metric) {
+	running := 0
+	if c.cfg.VMCount != nil {
+		running = c.cfg.VMCount()
+	}
+	ch <- prometheus.MustNewConstMetric(c.vms, prometheus.GaugeValue, float64(c.creating.Load()), "creating")
+	ch <- prometheus.MustNewConstMetric(c.vms, prometheus.GaugeValue, float64(running), "running")
+	ch <- prometheus.MustNewConstMetric(c.capacity, prometheus.GaugeValue, float64(c.cfg.Capacity))
+	ch <- prometheus.MustNewConstMetric(c.availableSlots, prometheus.GaugeValue, float64(c.cfg.Capacity-running))
+
+	c.mu.RLock()
+	tunnel := c.tunnel
+	c.mu.RUnlock()
+	if tunnel != nil {
+		up := 0.0
+		if tunnel.IsRegistered() {
+			up = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.tunnelUp, prometheus.GaugeValue, up, tunnel.TunnelURL())
+	}
+
+	if host, err := readHostStats(c.cfg.DiskPath); err == nil {
+		ch <- prometheus.MustNewConstMetric(c.memAvailable, prometheus.GaugeValue, float64(host.MemAvailable))
+		ch <- prometheus.MustNewConstMetric(c.memTotal, prometheus.GaugeValue, float64(host.MemTotal))
+		ch <- prometheus.MustNewConstMetric(c.load1, prometheus.GaugeValue, host.Load1)
+		if c.cfg.DiskPath != "" {
+			ch <- prometheus.MustNewConstMetric(c.diskAvailable, prometheus.GaugeValue, float64(host.DiskAvailable), c.cfg.DiskPath)
+		}
+	}
+}
+
+// hostStats is a point-in-time view of host resources relevant to MicroVM placement.
+type hostStats struct {
+	MemTotal      uint64
+	MemAvailable  uint64
+	DiskAvailable uint64
+	Load1         float64
+}
@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestAgentMetrics(t *testing.T) {
+	m := NewAgentMetrics(AgentConfig{WorkerID: "worker-1", Capacity: 4, VMCount: func() int { return 3 }})
+
+	done := m.CreateStarted()
+	if got := m.agent.creating.Load(); got != 1 {
+		t.Errorf("VMs creating during create = %v, want 1", got)
+	}
+	done(errors.New("boom"))
+	if got := m.agent.creating.Load(); got != 0 {
+		t.Errorf("VMs creating after create = %v, want 0", got)
+	}
+	m.ObserveDestroy(time.Second, nil)
+
+	closed := m.WebSocketOpened("terminal")
+	if got := testutil.ToFloat64(m.wsConnections.WithLabelValues("terminal")); got != 1 {
+		t.Errorf("websocket_connections = %v, want 1", got)
+	}
+	closed()
+
+	m.AddProxiedBytes("ws-1", 1, "terminal", "out", 100)
+	m.AddProxiedBytes("ws-1", 1, "terminal", "out", 50)
+	m.AddProxiedBytes("ws-1", 2, "terminal", "out", 10)
+	m.AddProxiedBytes("ws-1", 1, "files", "in", 0) // Ignored
+	m.AddProxiedBytes("ws-2", 1, "preview", "in", 7)
+	if got := testutil.ToFloat64(m.proxiedBytes.WithLabelValues("ws-1", "1", "terminal", "out")); got != 150 {
+		t.Errorf("proxied_bytes for ws-1 seat 1 = %v, want 150", got)
+	}
+	m.FileProxyError("vm_unreachable")
+
+	rr := httptest.NewRecorder()
+	m.Handler("").ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	body := rr.Body.String()
+	for _, want := range []string{
+		`clarateach_agent_vm_create_duration_seconds_count{result="error"} 1`,
+		`clarateach_agent_vm_destroy_duration_seconds_count{result="success"} 1`,
+		`clarateach_agent_proxy_websocket_connections{kind="terminal"} 0`,
+		`clarateach_agent_proxied_bytes_total{direction="out",kind="terminal",seat_id="1",workshop_id="ws-1"} 150`,
+		`clarateach_agent_proxied_bytes_total{direction="out",kind="terminal",seat_id="2",workshop_id="ws-1"} 10`,
+		`clarateach_agent_file_proxy_errors_total{reason="vm_unreachable"} 1`,
+		`clarateach_agent_vms{state="creating",worker_id="worker-1"} 0`,
+		`clarateach_agent_vms{state="running",worker_id="worker-1"} 3`,
+		`clarateach_agent_available_slots{worker_id="worker-1"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Metrics output missing %q", want)
+		}
+	}
+	// A torn-down workshop's seat series go away; other workshops' stay
+	m.ForgetWorkshop("ws-1")
+	if n := testutil.CollectAndCount(m.proxiedBytes); n != 1 {
+		t.Errorf("proxied_bytes series after ForgetWorkshop = %d, want 1", n)
+	}
+}
+
+func TestHandlerRequiresBearerToken(t *testing.T) {
+	m := NewAgentMetrics(AgentConfig{WorkerID: "worker-1"})
+	h := m.Handler("scrape-secret")
+
+	for header, want := range map[string]int{
+		"":                     http.StatusUnauthorized,
+		"scrape-secret":        http.StatusUnauthorized,
+		"Bearer wrong":         http.StatusUnauthorized,
+		"Bearer scrape-secret": http.StatusOK,
+	} {
+		req := httptest.NewRequest("GET", "/metrics", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		if rr.Code != want {
+			t.Errorf("Authorization %q = %d, want %d", header, rr.Code, want)
+		}
+	}
+}
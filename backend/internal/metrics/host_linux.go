@@ -0,0 +1,57 @@
+//go:build linux
+
+package metrics
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// readHostStats reads memory from /proc/meminfo, load from /proc/loadavg and
+// free disk space for diskPath (skipped when empty).
+func readHostStats(diskPath string) (*hostStats, error) {
+	stats := &hostStats{}
+
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Lines look like "MemAvailable:   12345678 kB"
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[0] {
+		case "MemTotal:":
+			stats.MemTotal = kb * 1024
+		case "MemAvailable:":
+			stats.MemAvailable = kb * 1024
+		}
+	}
+
+	if data, err := os.ReadFile("/proc/loadavg"); err == nil {
+		if fields := strings.Fields(string(data)); len(fields) > 0 {
+			stats.Load1, _ = strconv.ParseFloat(fields[0], 64)
+		}
+	}
+
+	if diskPath != "" {
+		var fs syscall.Statfs_t
+		if err := syscall.Statfs(diskPath, &fs); err == nil {
+			stats.DiskAvailable = fs.Bavail * uint64(fs.Bsize)
+		}
+	}
+
+	return stats, nil
+}
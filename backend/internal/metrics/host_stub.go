@@ -0,0 +1,10 @@
+//go:build !linux
+
+package metrics
+
+import "errors"
+
+// readHostStats is unavailable on non-Linux platforms; host gauges are omitted.
+func readHostStats(diskPath string) (*hostStats, error) {
+	return nil, errors.New("host stats require Linux")
+}
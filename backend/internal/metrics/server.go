@@ -0,0 +1,164 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/clarateach/backend/internal/store"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ServerMetrics holds the collectors exported by the control-plane server.
+type ServerMetrics struct {
+	registry             *prometheus.Registry
+	httpDuration         *prometheus.HistogramVec
+	provisioningDuration *prometheus.HistogramVec
+	provisioningFailures *prometheus.CounterVec
+	dbQueryDuration      *prometheus.HistogramVec
+}
+
+// NewServerMetrics creates the control-plane collectors. Workshop, learner and
+// registration gauges are computed from the store at scrape time.
+func NewServerMetrics(st store.Store) *ServerMetrics {
+	m := &ServerMetrics{
+		registry: newRegistry(),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "clarateach_http_request_duration_seconds",
+			Help:    "HTTP request latency by chi route pattern.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		provisioningDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "clarateach_provisioning_duration_seconds",
+			Help:    "Time from provisioning start until the workshop VM is ready.",
+			Buckets: []float64{5, 15, 30, 60, 90, 120, 180, 240, 300, 450, 600},
+		}, []string{"provisioner", "runtime"}),
+		provisioningFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "clarateach_provisioning_failures_total",
+			Help: "Failed workshop provisioning attempts by provisioner and error class.",
+		}, []string{"provisioner", "error_class"}),
+		dbQueryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "clarateach_db_query_duration_seconds",
+			Help:    "Database query latency by operation.",
+			Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"operation"}),
+	}
+
+	m.registry.MustRegister(m.httpDuration, m.provisioningDuration, m.provisioningFailures, m.dbQueryDuration)
+	m.registry.MustRegister(newStoreCollector(st))
+	return m
+}
+
+// Handler serves the control-plane metrics, optionally protected by token.
+func (m *ServerMetrics) Handler(token string) http.Handler {
+	return Handler(m.registry, token)
+}
+
+// Middleware records request latency labelled by the matched chi route pattern,
+// so /api/workshops/{id} is one series regardless of workshop ID.
+func (m *ServerMetrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		start := time.Now()
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		m.httpDuration.WithLabelValues(r.Method, route, strconv.Itoa(status)).Observe(time.Since(start).Seconds())
+	})
+}
+
+// ObserveProvisioning records a successful provisioning run.
+func (m *ServerMetrics) ObserveProvisioning(provisionerName, runtimeType string, d time.Duration) {
+	m.provisioningDuration.WithLabelValues(provisionerName, runtimeType).Observe(d.Seconds())
+}
+
+// ProvisioningFailed counts a failed provisioning run, classifying err.
+func (m *ServerMetrics) ProvisioningFailed(provisionerName string, err error) {
+	m.provisioningFailures.WithLabelValues(provisionerName, ErrorClass(err)).Inc()
+}
+
+// ObserveDBQuery records a database query duration. It matches store.QueryObserver.
+func (m *ServerMetrics) ObserveDBQuery(operation string, d time.Duration) {
+	m.dbQueryDuration.WithLabelValues(operation).Observe(d.Seconds())
+}
+
+// ErrorClass maps a provisioning error to a small fixed set of labels.
+func ErrorClass(err error) string {
+	if err == nil {
+		return "none"
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "timeout"
+	}
+	if errors.Is(err, context.Canceled) {
+		return "canceled"
+	}
+
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "agent health"):
+		return "agent_health"
+	case strings.Contains(msg, "microvm"):
+		return "microvm"
+	case strings.Contains(msg, "quota"):
+		return "quota"
+	case strings.Contains(msg, "ssh key"):
+		return "ssh_key"
+	case strings.Contains(msg, "vm record"):
+		return "store"
+	case strings.Contains(msg, "compute client"), strings.Contains(msg, "vm creation"), strings.Contains(msg, "gcp vm"), strings.Contains(msg, "create vm"):
+		return "gce"
+	case strings.Contains(msg, "timeout"):
+		return "timeout"
+	}
+	return "other"
+}
+
+// storeCollector reads workshop, learner and registration counts from the store on each scrape.
+type storeCollector struct {
+	store         store.Store
+	workshops     *prometheus.Desc
+	activeLearner *prometheus.Desc
+	registrations *prometheus.Desc
+}
+
+func newStoreCollector(st store.Store) *storeCollector {
+	return &storeCollector{
+		store:         st,
+		workshops:     prometheus.NewDesc("clarateach_workshops", "Workshops by status.", []string{"status"}, nil),
+		activeLearner: prometheus.NewDesc("clarateach_active_learners", "Occupied seats across live workshops.", nil, nil),
+		registrations: prometheus.NewDesc("clarateach_registrations", "Learner registrations across live workshops.", nil, nil),
+	}
+}
+
+func (c *storeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.workshops
+	ch <- c.activeLearner
+	ch <- c.registrations
+}
+
+func (c *storeCollector) Collect(ch chan<- prometheus.Metric) {
+	stats, err := c.store.GetWorkshopStats()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.workshops, err)
+		return
+	}
+
+	for status, n := range stats.ByStatus {
+		ch <- prometheus.MustNewConstMetric(c.workshops, prometheus.GaugeValue, float64(n), status)
+	}
+	ch <- prometheus.MustNewConstMetric(c.activeLearner, prometheus.GaugeValue, float64(stats.ActiveLearners))
+	ch <- prometheus.MustNewConstMetric(c.registrations, prometheus.GaugeValue, float64(stats.Registrations))
+}
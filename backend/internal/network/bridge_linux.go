@@ -5,9 +5,10 @@ package network
 
 import (
 	"fmt"
-	"log"
 	"os/exec"
 	"strings"
+
+	"github.com/clarateach/backend/internal/logging"
 )
 
 // BridgeConfig holds configuration for the MicroVM bridge network.
@@ -27,11 +28,12 @@ func DefaultBridgeConfig() BridgeConfig {
 // SetupBridge ensures the MicroVM bridge network exists.
 // This replaces the setup-microvm-network.sh script.
 func SetupBridge(cfg BridgeConfig) error {
-	log.Printf("[network] Setting up bridge %s with IP %s", cfg.BridgeName, cfg.BridgeIP)
+	logger := logging.Component("network").With("bridge", cfg.BridgeName)
+	logger.Info("setting up bridge", "ip", cfg.BridgeIP)
 
 	// Check if bridge already exists
 	if bridgeExists(cfg.BridgeName) {
-		log.Printf("[network] Bridge %s already exists", cfg.BridgeName)
+		logger.Info("bridge already exists")
 		return nil
 	}
 
@@ -39,7 +41,7 @@ func SetupBridge(cfg BridgeConfig) error {
 	if err := runCmd("ip", "link", "add", "name", cfg.BridgeName, "type", "bridge"); err != nil {
 		return fmt.Errorf("failed to create bridge: %w", err)
 	}
-	log.Printf("[network] Created bridge %s", cfg.BridgeName)
+	logger.Info("created bridge")
 
 	// Add IP address
 	if err := runCmd("ip", "addr", "add", cfg.BridgeIP, "dev", cfg.BridgeName); err != nil {
@@ -48,13 +50,13 @@ func SetupBridge(cfg BridgeConfig) error {
 			return fmt.Errorf("failed to add IP to bridge: %w", err)
 		}
 	}
-	log.Printf("[network] Added IP %s to bridge", cfg.BridgeIP)
+	logger.Info("added IP to bridge", "ip", cfg.BridgeIP)
 
 	// Bring bridge up
 	if err := runCmd("ip", "link", "set", cfg.BridgeName, "up"); err != nil {
 		return fmt.Errorf("failed to bring bridge up: %w", err)
 	}
-	log.Printf("[network] Bridge %s is up", cfg.BridgeName)
+	logger.Info("bridge is up")
 
 	return nil
 }
@@ -3,7 +3,7 @@
 // Package network handles network setup for the agent.
 package network
 
-import "log"
+import "github.com/clarateach/backend/internal/logging"
 
 // BridgeConfig holds configuration for the MicroVM bridge network.
 type BridgeConfig struct {
@@ -21,6 +21,6 @@ func DefaultBridgeConfig() BridgeConfig {
 
 // SetupBridge is a no-op on non-Linux platforms.
 func SetupBridge(cfg BridgeConfig) error {
-	log.Printf("[network] Bridge setup skipped (not Linux)")
+	logging.Component("network").Info("bridge setup skipped (not Linux)")
 	return nil
 }
@@ -0,0 +1,66 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileNotifier appends each message to a file instead of sending it, for local
+// testing.
+type FileNotifier struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// NewFileNotifier opens (or creates) path for appending.
+func NewFileNotifier(path string) (*FileNotifier, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open notification file: %w", err)
+	}
+	return &FileNotifier{f: f}, nil
+}
+
+// Send writes msg followed by a blank line.
+func (n *FileNotifier) Send(ctx context.Context, msg Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	_, err := fmt.Fprintf(n.f, "Date: %s\nTo: %s\nSubject: %s\n\n%s\n\n", time.Now().Format(time.RFC3339), msg.To, msg.Subject, msg.Body)
+	return err
+}
+
+// Close closes the file.
+func (n *FileNotifier) Close() error {
+	return n.f.Close()
+}
+
+// LogNotifier writes each message to a logger instead of sending it.
+type LogNotifier struct {
+	logger *slog.Logger
+}
+
+// NewLogNotifier creates a notifier that logs at info level.
+func NewLogNotifier(logger *slog.Logger) *LogNotifier {
+	return &LogNotifier{logger: logger}
+}
+
+// Send logs msg.
+func (n *LogNotifier) Send(ctx context.Context, msg Message) error {
+	n.logger.InfoContext(ctx, "notification", "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+	return nil
+}
+
+// parseAddress returns the bare address from an RFC 5322 address such as
+// "Jane <jane@example.com>".
+func parseAddress(s string) (string, error) {
+	addr, err := mail.ParseAddress(s)
+	if err != nil {
+		return "", err
+	}
+	return addr.Address, nil
+}
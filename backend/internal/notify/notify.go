@@ -0,0 +1,64 @@
+// Package notify sends email to learners: registration confirmations with
+// their access code, reminders before a workshop starts and a notice when
+// their workspace is ready.
+//
+// Messages go through a Notifier. Production uses SMTP; for local testing they
+// can be appended to a file or written to the log instead. Bodies are rendered
+// from text/template templates, which a workshop may override per kind.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// Driver names accepted in Config.Driver.
+const (
+	DriverNone = "none"
+	DriverSMTP = "smtp"
+	DriverFile = "file"
+	DriverLog  = "log"
+)
+
+// Message is a plain-text email to one recipient.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier delivers messages.
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Config selects how messages are delivered.
+type Config struct {
+	Driver   string     // "smtp", "file", "log" or "none" (default)
+	SMTP     SMTPConfig // For the "smtp" driver
+	FilePath string     // Output file for the "file" driver
+}
+
+// New creates the Notifier selected by cfg. It returns nil for the "none"
+// driver, meaning no email is sent.
+func New(cfg Config) (Notifier, error) {
+	switch cfg.Driver {
+	case "", DriverNone:
+		return nil, nil
+	case DriverSMTP:
+		if cfg.SMTP.Host == "" || cfg.SMTP.From == "" {
+			return nil, fmt.Errorf("smtp notifier requires a host and from address")
+		}
+		return NewSMTPNotifier(cfg.SMTP), nil
+	case DriverFile:
+		if cfg.FilePath == "" {
+			return nil, fmt.Errorf("file notifier requires a file path")
+		}
+		return NewFileNotifier(cfg.FilePath)
+	case DriverLog:
+		return NewLogNotifier(slog.Default().With("component", "notify")), nil
+	default:
+		return nil, fmt.Errorf("unknown notify driver %q (want smtp, file, log or none)", cfg.Driver)
+	}
+}
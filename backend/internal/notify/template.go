@@ -0,0 +1,165 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Kinds of message a workshop sends to its learners.
+const (
+	KindRegistration   = "registration"    // After registering, or on leaving the waitlist
+	KindWaitlisted     = "waitlisted"      // After joining the waitlist of a full workshop
+	KindReminder       = "reminder"        // Before the workshop's starts_at
+	KindWorkspaceReady = "workspace_ready" // When the workshop starts running
+	KindLoginLink      = "login_link"      // When a learner or staff asks for a one-time login link
+)
+
+// Kinds lists every message kind, in the order they are usually sent.
+var Kinds = []string{KindRegistration, KindWaitlisted, KindReminder, KindWorkspaceReady, KindLoginLink}
+
+// ValidKind reports whether kind is a known message kind.
+func ValidKind(kind string) bool {
+	for _, k := range Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Template is a text/template subject and body for one kind of message.
+type Template struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Data is available to templates as the dot value.
+type Data struct {
+	LearnerName      string
+	LearnerEmail     string
+	WorkshopName     string
+	WorkshopCode     string
+	AccessCode       string
+	AccessURL        string // Link that opens the learner's workspace, if a public URL is configured
+	StartsAt         *time.Time
+	WaitlistPosition int
+	LoginURL         string    // One-time login link, for login_link messages
+	LoginExpiresAt   time.Time // When LoginURL stops working
+}
+
+var defaultTemplates = map[string]Template{
+	KindRegistration: {
+		Subject: `Your access code for {{.WorkshopName}}`,
+		Body: `Hi {{.LearnerName}},
+
+You're registered for {{.WorkshopName}}.{{if .StartsAt}} It starts at {{.StartsAt.Format "Mon 2 Jan 2006 15:04 MST"}}.{{end}}
+
+Your access code is: {{.AccessCode}}
+{{if .AccessURL}}
+Open your workspace at {{.AccessURL}}
+{{end}}`,
+	},
+	KindWaitlisted: {
+		Subject: `You're on the waitlist for {{.WorkshopName}}`,
+		Body: `Hi {{.LearnerName}},
+
+{{.WorkshopName}} is full, so you're number {{.WaitlistPosition}} on the waitlist.
+We'll email you your access code if a place opens up.
+`,
+	},
+	KindReminder: {
+		Subject: `Reminder: {{.WorkshopName}} starts soon`,
+		Body: `Hi {{.LearnerName}},
+
+{{.WorkshopName}} starts at {{.StartsAt.Format "Mon 2 Jan 2006 15:04 MST"}}.
+
+Your access code is: {{.AccessCode}}
+{{if .AccessURL}}
+Open your workspace at {{.AccessURL}}
+{{end}}`,
+	},
+	KindWorkspaceReady: {
+		Subject: `Your workspace for {{.WorkshopName}} is ready`,
+		Body: `Hi {{.LearnerName}},
+
+{{.WorkshopName}} has started and your workspace is ready.
+
+Your access code is: {{.AccessCode}}
+{{if .AccessURL}}
+Open your workspace at {{.AccessURL}}
+{{end}}`,
+	},
+	KindLoginLink: {
+		Subject: `Your login link for {{.WorkshopName}}`,
+		Body: `Hi {{.LearnerName}},
+
+Open your workspace for {{.WorkshopName}} with this link:
+
+{{.LoginURL}}
+
+The link works once and expires at {{.LoginExpiresAt.Format "Mon 2 Jan 2006 15:04 MST"}}.
+
+Your access code is: {{.AccessCode}}
+`,
+	},
+}
+
+// DefaultTemplate returns the built-in template for kind.
+func DefaultTemplate(kind string) Template {
+	return defaultTemplates[kind]
+}
+
+// Validate parses t and renders it against sample data, so mistakes such as
+// unknown fields are caught when a template is saved rather than when it is
+// sent.
+func (t Template) Validate() error {
+	if strings.TrimSpace(t.Subject) == "" || strings.TrimSpace(t.Body) == "" {
+		return fmt.Errorf("subject and body are required")
+	}
+	starts := time.Now()
+	_, err := t.Render(Data{
+		LearnerName:      "Ada",
+		LearnerEmail:     "ada@example.com",
+		WorkshopName:     "Workshop",
+		WorkshopCode:     "ABC123",
+		AccessCode:       "ABCD-1234",
+		AccessURL:        "https://example.com/s/ABCD-1234",
+		StartsAt:         &starts,
+		WaitlistPosition: 1,
+		LoginURL:         "https://example.com/login/token",
+		LoginExpiresAt:   starts,
+	})
+	return err
+}
+
+// Render executes t against data. Line breaks are removed from the subject.
+func (t Template) Render(data Data) (Message, error) {
+	subject, err := execute("subject", t.Subject, data)
+	if err != nil {
+		return Message{}, err
+	}
+	body, err := execute("body", t.Body, data)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{
+		To:      data.LearnerEmail,
+		Subject: strings.Join(strings.Fields(subject), " "),
+		Body:    body,
+	}, nil
+}
+
+func execute(name, text string, data Data) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s template: %w", name, err)
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("invalid %s template: %w", name, err)
+	}
+	return b.String(), nil
+}
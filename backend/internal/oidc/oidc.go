@@ -0,0 +1,383 @@
+// Package oidc implements OpenID Connect sign-in for staff accounts, using
+// the authorization code flow with PKCE.
+//
+// The browser is sent to the provider's authorization endpoint with a code
+// challenge and a nonce. The provider redirects it back with a code, which is
+// exchanged at the token endpoint along with the code verifier for an ID
+// token. ID tokens are verified against the key set the provider publishes
+// in its discovery document. Only RS256 signed ID tokens are accepted.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/clarateach/backend/internal/jwks"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	discoveryTTL    = time.Hour // How long a fetched discovery document is trusted
+	responseMaxSize = 1 << 20   // Largest discovery or token response read
+)
+
+var providerIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// Config is a provider as configured in OIDC_PROVIDERS.
+type Config struct {
+	ID           string   `json:"id"`   // URL slug, e.g. "google"
+	Name         string   `json:"name"` // Shown on the sign-in button
+	Issuer       string   `json:"issuer"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret,omitempty"` // Empty for public clients
+	Scopes       []string `json:"scopes,omitempty"`        // Defaults to openid, email and profile
+
+	// Who may sign in, and as what. See Provider.Access.
+	AllowedDomains   []string `json:"allowed_domains,omitempty"`   // Email domains that may create accounts
+	RoleClaim        string   `json:"role_claim,omitempty"`        // Claim holding groups or roles, dotted for nested claims
+	AdminValues      []string `json:"admin_values,omitempty"`      // Role claim values that make a user an admin
+	InstructorValues []string `json:"instructor_values,omitempty"` // Role claim values that make a user an instructor
+	TrustEmail       bool     `json:"trust_email,omitempty"`       // Treat emails as verified when email_verified is absent, and sign in existing accounts by email
+}
+
+// Claims are the parts of a verified ID token the server uses.
+type Claims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Roles         []string // Values of the provider's role claim
+}
+
+// Access is what a provider's rules grant a signed-in user.
+type Access struct {
+	Role      string // Role to give the user, or "" to keep their current role
+	Provision bool   // Whether a new account may be created for the user
+}
+
+type discovery struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	TokenAuthMethods      []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+// Provider is a configured OIDC provider.
+type Provider struct {
+	Config
+	keys   *jwks.Cache
+	client *http.Client
+
+	mu        sync.Mutex
+	discovery *discovery
+	fetched   time.Time
+}
+
+// ParseProviders reads the OIDC_PROVIDERS JSON array.
+func ParseProviders(data string) ([]*Provider, error) {
+	var configs []Config
+	if err := json.Unmarshal([]byte(data), &configs); err != nil {
+		return nil, fmt.Errorf("parsing OIDC providers: %w", err)
+	}
+	keys := jwks.NewCache()
+	providers := make([]*Provider, 0, len(configs))
+	seen := make(map[string]bool)
+	for _, c := range configs {
+		if !providerIDPattern.MatchString(c.ID) {
+			return nil, fmt.Errorf("OIDC provider ID %q must be lowercase letters, digits and dashes", c.ID)
+		}
+		if seen[c.ID] {
+			return nil, fmt.Errorf("duplicate OIDC provider ID %q", c.ID)
+		}
+		seen[c.ID] = true
+		if c.Issuer == "" || c.ClientID == "" {
+			return nil, fmt.Errorf("OIDC provider %q needs an issuer and client_id", c.ID)
+		}
+		if c.Name == "" {
+			c.Name = c.ID
+		}
+		if len(c.Scopes) == 0 {
+			c.Scopes = []string{"openid", "email", "profile"}
+		} else if !slices.Contains(c.Scopes, "openid") {
+			c.Scopes = append([]string{"openid"}, c.Scopes...)
+		}
+		for i, domain := range c.AllowedDomains {
+			c.AllowedDomains[i] = strings.ToLower(strings.TrimPrefix(domain, "@"))
+		}
+		providers = append(providers, &Provider{
+			Config: c,
+			keys:   keys,
+			client: &http.Client{Timeout: 10 * time.Second},
+		})
+	}
+	return providers, nil
+}
+
+// NewVerifier returns a random PKCE code verifier.
+func NewVerifier() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Challenge returns the S256 code challenge for a verifier.
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL returns the authorization endpoint URL that starts a sign-in.
+func (p *Provider) AuthCodeURL(ctx context.Context, redirectURI, state, nonce, verifier string) (string, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(d.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid authorization endpoint: %w", err)
+	}
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", p.ClientID)
+	q.Set("redirect_uri", redirectURI)
+	q.Set("scope", strings.Join(p.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", Challenge(verifier))
+	q.Set("code_challenge_method", "S256")
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// Exchange redeems an authorization code and returns the verified claims of
+// the ID token, which must carry nonce.
+func (p *Provider) Exchange(ctx context.Context, redirectURI, code, verifier, nonce string) (*Claims, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	}
+	// client_secret_basic is the default; use the form only when it is all
+	// the provider supports, or for public clients
+	useBasic := p.ClientSecret != "" && (len(d.TokenAuthMethods) == 0 || slices.Contains(d.TokenAuthMethods, "client_secret_basic"))
+	if !useBasic {
+		form.Set("client_id", p.ClientID)
+		if p.ClientSecret != "" {
+			form.Set("client_secret", p.ClientSecret)
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if useBasic {
+		req.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("exchanging authorization code: %w", err)
+	}
+	defer resp.Body.Close()
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, responseMaxSize)).Decode(&token); err != nil {
+		return nil, fmt.Errorf("exchanging authorization code: %s", resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK || token.Error != "" {
+		return nil, fmt.Errorf("exchanging authorization code: %s %s", token.Error, token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+	return p.verify(ctx, d, token.IDToken, nonce)
+}
+
+// verify checks an ID token's signature, issuer, audience, expiry and nonce.
+func (p *Provider) verify(ctx context.Context, d *discovery, idToken, nonce string) (*Claims, error) {
+	raw := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(idToken, raw, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.keys.Key(ctx, d.JWKSURI, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithIssuer(p.Issuer),
+		jwt.WithAudience(p.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id_token: %w", err)
+	}
+
+	if got, _ := raw["nonce"].(string); got == "" || got != nonce {
+		return nil, errors.New("invalid id_token: nonce does not match")
+	}
+	// With several audiences the token must say it was issued for this client
+	if aud, _ := raw.GetAudience(); len(aud) > 1 {
+		if azp, _ := raw["azp"].(string); azp != p.ClientID {
+			return nil, errors.New("invalid id_token: azp does not match the client ID")
+		}
+	}
+
+	claims := &Claims{}
+	claims.Subject, _ = raw.GetSubject()
+	if claims.Subject == "" {
+		return nil, errors.New("invalid id_token: missing sub")
+	}
+	claims.Email, _ = raw["email"].(string)
+	switch v := raw["email_verified"].(type) {
+	case bool:
+		claims.EmailVerified = v
+	case string: // Some providers send "true"
+		claims.EmailVerified = v == "true"
+	case nil:
+		claims.EmailVerified = p.TrustEmail
+	}
+	claims.Name, _ = raw["name"].(string)
+	if claims.Name == "" {
+		given, _ := raw["given_name"].(string)
+		family, _ := raw["family_name"].(string)
+		claims.Name = strings.TrimSpace(given + " " + family)
+	}
+	if p.RoleClaim != "" {
+		claims.Roles = claimValues(raw, p.RoleClaim)
+	}
+	return claims, nil
+}
+
+// claimValues returns the strings at a dotted claim path, such as
+// "realm_access.roles", whether the claim is one string or a list.
+func claimValues(claims map[string]interface{}, path string) []string {
+	var v interface{} = claims
+	for _, part := range strings.Split(path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[part]
+	}
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var values []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// Access applies the provider's rules to a signed-in user. The role claim
+// decides the role: admin_values make an admin, and instructor_values (or
+// any value, when none are listed) an instructor. A verified email in
+// allowed_domains, or a matching role claim value, lets a new account be
+// created. When allowed_domains or instructor_values are set, users matching
+// neither may not sign in at all; otherwise only existing accounts may. ok
+// is false when the user may not sign in.
+func (p *Provider) Access(c *Claims) (access Access, ok bool) {
+	matched := false
+	if p.RoleClaim != "" {
+		switch {
+		case intersects(c.Roles, p.AdminValues):
+			access.Role, matched = "admin", true
+		case intersects(c.Roles, p.InstructorValues):
+			access.Role, matched = "instructor", true
+		case len(p.InstructorValues) == 0:
+			access.Role = "instructor"
+		}
+	}
+	access.Provision = matched || p.AllowsEmail(c)
+	restricted := len(p.AllowedDomains) > 0 || len(p.InstructorValues) > 0
+	return access, access.Provision || !restricted
+}
+
+// AllowsEmail reports whether the user's email is verified and in one of
+// the allowed domains.
+func (p *Provider) AllowsEmail(c *Claims) bool {
+	if !c.EmailVerified {
+		return false
+	}
+	at := strings.LastIndex(c.Email, "@")
+	if at < 0 {
+		return false
+	}
+	return slices.Contains(p.AllowedDomains, strings.ToLower(c.Email[at+1:]))
+}
+
+func intersects(values, wanted []string) bool {
+	for _, v := range values {
+		if slices.Contains(wanted, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// discover returns the provider's discovery document, fetching it when it
+// is stale.
+func (p *Provider) discover(ctx context.Context) (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil && time.Since(p.fetched) < discoveryTTL {
+		return p.discovery, nil
+	}
+
+	wellKnown := strings.TrimSuffix(p.Issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnown, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching OIDC discovery document: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching OIDC discovery document: %s", resp.Status)
+	}
+	d := &discovery{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, responseMaxSize)).Decode(d); err != nil {
+		return nil, fmt.Errorf("decoding OIDC discovery document: %w", err)
+	}
+	if d.Issuer != p.Issuer {
+		return nil, fmt.Errorf("OIDC discovery document is for issuer %q, not %q", d.Issuer, p.Issuer)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, errors.New("OIDC discovery document is missing endpoints")
+	}
+	p.discovery, p.fetched = d, time.Now()
+	return d, nil
+}
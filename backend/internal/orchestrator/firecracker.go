@@ -6,16 +6,20 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"sync"
 
+	"github.com/clarateach/backend/internal/logging"
+	"github.com/clarateach/backend/internal/tracing"
 	firecracker "github.com/firecracker-microvm/firecracker-go-sdk"
 	"github.com/firecracker-microvm/firecracker-go-sdk/client/models"
 	"github.com/sirupsen/logrus"
 	"github.com/vishvananda/netlink"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // FirecrackerConfig holds configuration for the Firecracker provider.
@@ -60,7 +64,7 @@ type FirecrackerProvider struct {
 	config FirecrackerConfig
 	vms    map[string]*vmState // key: "workshopID-seatID"
 	mu     sync.RWMutex
-	logger *logrus.Logger
+	logger *slog.Logger
 }
 
 // NewFirecrackerProvider creates a new FirecrackerProvider with default configuration.
@@ -75,13 +79,10 @@ func NewFirecrackerProviderWithConfig(cfg FirecrackerConfig) (*FirecrackerProvid
 		return nil, fmt.Errorf("failed to create socket directory: %w", err)
 	}
 
-	logger := logrus.New()
-	logger.SetLevel(logrus.InfoLevel)
-
 	return &FirecrackerProvider{
 		config: cfg,
 		vms:    make(map[string]*vmState),
-		logger: logger,
+		logger: logging.Component("orchestrator"),
 	}, nil
 }
 
@@ -91,7 +92,16 @@ func vmKey(workshopID string, seatID int) string {
 }
 
 // Create provisions a new Firecracker MicroVM instance.
-func (f *FirecrackerProvider) Create(ctx context.Context, cfg InstanceConfig) (*Instance, error) {
+func (f *FirecrackerProvider) Create(ctx context.Context, cfg InstanceConfig) (_ *Instance, err error) {
+	ctx, span := tracing.Start(ctx, "FirecrackerProvider.Create",
+		attribute.String("workshop.id", cfg.WorkshopID),
+		attribute.Int("seat.id", cfg.SeatID),
+	)
+	defer func() {
+		tracing.Fail(span, err)
+		span.End()
+	}()
+
 	key := vmKey(cfg.WorkshopID, cfg.SeatID)
 
 	f.mu.Lock()
@@ -103,6 +113,8 @@ func (f *FirecrackerProvider) Create(ctx context.Context, cfg InstanceConfig) (*
 	}
 
 	// 1. Ensure bridge exists and is configured
+	_, netSpan := tracing.Start(ctx, "firecracker.setupNetwork")
+	defer netSpan.End()
 	if err := f.ensureBridge(); err != nil {
 		return nil, fmt.Errorf("failed to setup bridge: %w", err)
 	}
@@ -120,6 +132,7 @@ func (f *FirecrackerProvider) Create(ctx context.Context, cfg InstanceConfig) (*
 	if err := f.createTAP(tapName); err != nil {
 		return nil, fmt.Errorf("failed to create TAP device: %w", err)
 	}
+	netSpan.End()
 
 	// 3. Calculate IP for this VM
 	vmIP := fmt.Sprintf("192.168.100.%d", 10+cfg.SeatID)
@@ -127,7 +140,10 @@ func (f *FirecrackerProvider) Create(ctx context.Context, cfg InstanceConfig) (*
 
 	// 4. Copy rootfs for this VM
 	vmRootfs := filepath.Join(f.config.SocketDir, fmt.Sprintf("rootfs-%s.ext4", key))
-	if err := copyFile(f.config.RootfsPath, vmRootfs); err != nil {
+	_, copySpan := tracing.Start(ctx, "firecracker.copyRootfs")
+	err = copyFile(f.config.RootfsPath, vmRootfs)
+	copySpan.End()
+	if err != nil {
 		f.deleteTAP(tapName)
 		return nil, fmt.Errorf("failed to copy rootfs: %w", err)
 	}
@@ -176,8 +192,10 @@ func (f *FirecrackerProvider) Create(ctx context.Context, cfg InstanceConfig) (*
 		Build(context.Background())
 
 	// Use background context for the machine so it survives beyond the HTTP request
+	_, startSpan := tracing.Start(ctx, "firecracker.startMachine")
+	defer startSpan.End()
 	machineCtx := context.Background()
-	machine, err := firecracker.NewMachine(machineCtx, fcCfg, firecracker.WithProcessRunner(cmd), firecracker.WithLogger(logrus.NewEntry(f.logger)))
+	machine, err := firecracker.NewMachine(machineCtx, fcCfg, firecracker.WithProcessRunner(cmd), firecracker.WithLogger(sdkLogger(ctx, f.logger)))
 	if err != nil {
 		os.Remove(vmRootfs)
 		f.deleteTAP(tapName)
@@ -190,6 +208,7 @@ func (f *FirecrackerProvider) Create(ctx context.Context, cfg InstanceConfig) (*
 		f.deleteTAP(tapName)
 		return nil, fmt.Errorf("failed to start Firecracker machine: %w", err)
 	}
+	startSpan.End()
 
 	// Track the VM
 	f.vms[key] = &vmState{
@@ -199,7 +218,7 @@ func (f *FirecrackerProvider) Create(ctx context.Context, cfg InstanceConfig) (*
 		ip:         vmIP,
 	}
 
-	f.logger.Infof("Started VM %s with IP %s", key, vmIP)
+	f.logger.InfoContext(ctx, "started VM", "ip", vmIP)
 
 	return &Instance{
 		WorkshopID: cfg.WorkshopID,
@@ -376,7 +395,7 @@ func (f *FirecrackerProvider) Destroy(ctx context.Context, workshopID string, se
 
 	// Stop the VM
 	if err := vm.machine.StopVMM(); err != nil {
-		f.logger.Warnf("Failed to stop VMM for %s: %v", key, err)
+		f.logger.WarnContext(ctx, "failed to stop VMM", "vm", key, "error", err)
 	}
 
 	// Cleanup resources
@@ -388,12 +407,13 @@ func (f *FirecrackerProvider) Destroy(ctx context.Context, workshopID string, se
 	os.Remove(socketPath)
 
 	delete(f.vms, key)
-	f.logger.Infof("Destroyed VM %s", key)
+	f.logger.InfoContext(ctx, "destroyed VM", "vm", key)
 
 	return nil
 }
 
 // List lists all active Firecracker MicroVM instances for a workshop.
+// An empty workshopID lists instances across all workshops.
 func (f *FirecrackerProvider) List(ctx context.Context, workshopID string) ([]*Instance, error) {
 	f.mu.RLock()
 	defer f.mu.RUnlock()
@@ -401,16 +421,18 @@ func (f *FirecrackerProvider) List(ctx context.Context, workshopID string) ([]*I
 	var instances []*Instance
 	prefix := workshopID + "-"
 	for key, vm := range f.vms {
-		if strings.HasPrefix(key, prefix) {
-			parts := strings.Split(key, "-")
-			seatID := 0
-			fmt.Sscanf(parts[len(parts)-1], "%d", &seatID)
-			instances = append(instances, &Instance{
-				WorkshopID: workshopID,
-				SeatID:     seatID,
-				IP:         vm.ip,
-			})
+		if workshopID != "" && !strings.HasPrefix(key, prefix) {
+			continue
 		}
+		// Keys are "workshopID-seatID"; workshop IDs may themselves contain dashes
+		sep := strings.LastIndex(key, "-")
+		seatID := 0
+		fmt.Sscanf(key[sep+1:], "%d", &seatID)
+		instances = append(instances, &Instance{
+			WorkshopID: key[:sep],
+			SeatID:     seatID,
+			IP:         vm.ip,
+		})
 	}
 	return instances, nil
 }
@@ -456,3 +478,45 @@ func runCommand(name string, arg ...string) error {
 	}
 	return nil
 }
+
+// sdkLogger returns a logrus entry for the Firecracker SDK, which only accepts
+// logrus, that forwards its records to logger with the correlation fields of ctx.
+func sdkLogger(ctx context.Context, logger *slog.Logger) *logrus.Entry {
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	l.SetLevel(logrus.DebugLevel) // Filtering is left to the slog handler
+	l.AddHook(&slogHook{logger: logger.With("source", "firecracker-sdk")})
+	return logrus.NewEntry(l).WithContext(ctx)
+}
+
+// slogHook forwards logrus entries to a slog logger.
+type slogHook struct {
+	logger *slog.Logger
+}
+
+func (h *slogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *slogHook) Fire(e *logrus.Entry) error {
+	level := slog.LevelInfo
+	switch e.Level {
+	case logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel:
+		level = slog.LevelError
+	case logrus.WarnLevel:
+		level = slog.LevelWarn
+	case logrus.DebugLevel, logrus.TraceLevel:
+		level = slog.LevelDebug
+	}
+
+	ctx := e.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	args := make([]any, 0, len(e.Data)*2)
+	for k, v := range e.Data {
+		args = append(args, k, v)
+	}
+	h.logger.Log(ctx, level, e.Message, args...)
+	return nil
+}
@@ -39,8 +39,10 @@ func (f *FirecrackerProvisioner) CreateVM(ctx context.Context, cfg VMConfig) (*V
 		})
 		if err != nil {
 			lastErr = err
+			cfg.recordEvent(EventSeatFailed, seatID, err.Error())
 			continue
 		}
+		cfg.recordEvent(EventSeatCreated, seatID, fmt.Sprintf("MicroVM started at %s", instance.IP))
 		if firstInstance == nil {
 			firstInstance = instance
 		}
@@ -63,6 +65,33 @@ func (f *FirecrackerProvisioner) CreateVM(ctx context.Context, cfg VMConfig) (*V
 	}, nil
 }
 
+// AddSeats starts MicroVMs for additional seats on a running workshop.
+func (f *FirecrackerProvisioner) AddSeats(ctx context.Context, cfg VMConfig, seatIDs []int) error {
+	for _, seatID := range seatIDs {
+		instance, err := f.provider.Create(ctx, orchestrator.InstanceConfig{
+			WorkshopID: cfg.WorkshopID,
+			SeatID:     seatID,
+		})
+		if err != nil {
+			cfg.recordEvent(EventSeatFailed, seatID, err.Error())
+			return fmt.Errorf("failed to create VM for seat %d: %w", seatID, err)
+		}
+		cfg.recordEvent(EventSeatCreated, seatID, fmt.Sprintf("MicroVM started at %s", instance.IP))
+	}
+	return nil
+}
+
+// RemoveSeats destroys the MicroVMs for the given seats.
+func (f *FirecrackerProvisioner) RemoveSeats(ctx context.Context, workshopID string, seatIDs []int) error {
+	var lastErr error
+	for _, seatID := range seatIDs {
+		if err := f.provider.Destroy(ctx, workshopID, seatID); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
 // DeleteVM destroys all MicroVMs for a workshop.
 func (f *FirecrackerProvisioner) DeleteVM(ctx context.Context, workshopID string) error {
 	instances, err := f.provider.List(ctx, workshopID)
@@ -21,6 +21,14 @@ func (f *FirecrackerProvisioner) CreateVM(ctx context.Context, cfg VMConfig) (*V
 	return nil, fmt.Errorf("Firecracker not supported on this platform")
 }
 
+func (f *FirecrackerProvisioner) AddSeats(ctx context.Context, cfg VMConfig, seatIDs []int) error {
+	return fmt.Errorf("Firecracker not supported on this platform")
+}
+
+func (f *FirecrackerProvisioner) RemoveSeats(ctx context.Context, workshopID string, seatIDs []int) error {
+	return fmt.Errorf("Firecracker not supported on this platform")
+}
+
 func (f *FirecrackerProvisioner) DeleteVM(ctx context.Context, workshopID string) error {
 	return fmt.Errorf("Firecracker not supported on this platform")
 }
@@ -155,6 +155,7 @@ func (p *GCPProvider) CreateVM(ctx context.Context, cfg VMConfig) (*VMInstance,
 	}
 
 	// Create the VM
+	cfg.recordEvent(EventVMCreateRequested, 0, fmt.Sprintf("Creating %s (%s)", vmName, cfg.MachineType))
 	op, err := client.Insert(ctx, &computepb.InsertInstanceRequest{
 		Project:          p.project,
 		Zone:             p.zone,
@@ -168,6 +169,7 @@ func (p *GCPProvider) CreateVM(ctx context.Context, cfg VMConfig) (*VMInstance,
 	if err := op.Wait(ctx); err != nil {
 		return nil, fmt.Errorf("failed waiting for VM creation: %w", err)
 	}
+	cfg.recordEvent(EventGCEOperationDone, 0, "GCE insert operation completed")
 
 	// Get the created instance details
 	return p.GetVM(ctx, cfg.WorkshopID)
@@ -6,13 +6,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
 	compute "cloud.google.com/go/compute/apiv1"
 	"cloud.google.com/go/compute/apiv1/computepb"
+	"github.com/clarateach/backend/internal/logging"
+	"github.com/clarateach/backend/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -29,7 +34,11 @@ type GCPFirecrackerProvider struct {
 	agentToken           string
 	backendURL           string // Backend URL for tunnel registration
 	workspaceTokenSecret string // Secret for workspace JWT validation
+	metricsToken         string // Bearer token protecting the agent's /metrics endpoint
+	otlpEndpoint         string // OTLP collector the agent exports traces to
+	recordTerminals      bool   // Whether agents record learner terminals
 	httpClient           *http.Client
+	logger               *slog.Logger
 }
 
 // GCPFirecrackerConfig holds configuration for the GCP Firecracker provider
@@ -43,6 +52,9 @@ type GCPFirecrackerConfig struct {
 	AgentToken           string // Token for agent authentication
 	BackendURL           string // Backend URL for tunnel registration (e.g., https://learn.claramap.com)
 	WorkspaceTokenSecret string // Secret for workspace JWT validation
+	MetricsToken         string // Optional: bearer token for scraping the agent's /metrics
+	OTLPEndpoint         string // Optional: OTLP/HTTP collector URL for agent traces
+	RecordTerminals      bool   // Optional: record learner terminals on the agent
 }
 
 // NewGCPFirecrackerProvider creates a new GCP Firecracker provisioner
@@ -67,9 +79,15 @@ func NewGCPFirecrackerProvider(cfg GCPFirecrackerConfig) *GCPFirecrackerProvider
 		agentToken:           cfg.AgentToken,
 		backendURL:           cfg.BackendURL,
 		workspaceTokenSecret: cfg.WorkspaceTokenSecret,
+		metricsToken:         cfg.MetricsToken,
+		otlpEndpoint:         cfg.OTLPEndpoint,
+		recordTerminals:      cfg.RecordTerminals,
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
+			// Propagates trace context and request/workshop IDs to the agent
+			Transport: tracing.Transport(logging.Transport(nil)),
 		},
+		logger: logging.Component("provisioner").With("provisioner", "gcp-firecracker"),
 	}
 }
 
@@ -79,12 +97,24 @@ func (p *GCPFirecrackerProvider) vmName(workshopID string) string {
 }
 
 // CreateVM provisions a new GCE VM from snapshot and creates MicroVMs via the agent
-func (p *GCPFirecrackerProvider) CreateVM(ctx context.Context, cfg VMConfig) (*VMInstance, error) {
+func (p *GCPFirecrackerProvider) CreateVM(ctx context.Context, cfg VMConfig) (vm *VMInstance, err error) {
+	ctx, span := tracing.Start(ctx, "GCPFirecrackerProvider.CreateVM",
+		attribute.String("workshop.id", cfg.WorkshopID),
+		attribute.Int("workshop.seats", cfg.Seats),
+	)
+	defer func() {
+		tracing.Fail(span, err)
+		span.End()
+	}()
+
 	// Step 1: Create GCP VM from snapshot
-	vm, err := p.createGCPVM(ctx, cfg)
+	cfg.recordEvent(EventVMCreateRequested, 0, fmt.Sprintf("Creating %s from snapshot %s (%s)", p.vmName(cfg.WorkshopID), p.snapshotName, p.machineType))
+	p.logger.InfoContext(ctx, "creating GCE VM from snapshot", "snapshot", p.snapshotName, "machine_type", p.machineType, "spot", cfg.Spot)
+	vm, err = p.createGCPVM(ctx, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create GCP VM: %w", err)
 	}
+	p.logger.InfoContext(ctx, "GCE VM created, waiting for agent", "vm_name", vm.Name, "external_ip", vm.ExternalIP)
 
 	vmName := p.vmName(cfg.WorkshopID)
 
@@ -95,9 +125,11 @@ func (p *GCPFirecrackerProvider) CreateVM(ctx context.Context, cfg VMConfig) (*V
 		// Check serial console: gcloud compute instances get-serial-port-output %s --zone=%s
 		return nil, fmt.Errorf("agent health check failed (VM %s kept for debugging): %w", vmName, err)
 	}
+	cfg.recordEvent(EventAgentHealthy, 0, fmt.Sprintf("Agent responding at %s", agentURL))
 
 	// Step 3: Create MicroVMs for each seat
-	if err := p.createMicroVMs(ctx, agentURL, cfg.WorkshopID, cfg.Seats); err != nil {
+	p.logger.InfoContext(ctx, "agent healthy, creating MicroVMs", "seats", cfg.Seats)
+	if err := p.createMicroVMs(ctx, agentURL, cfg); err != nil {
 		// Don't delete VM on failure - keep it for debugging
 		return nil, fmt.Errorf("failed to create MicroVMs (VM %s kept for debugging): %w", vmName, err)
 	}
@@ -135,6 +167,25 @@ func (p *GCPFirecrackerProvider) createGCPVM(ctx context.Context, cfg VMConfig)
 			Value: proto.String(p.workspaceTokenSecret),
 		})
 	}
+	if p.metricsToken != "" {
+		metadata = append(metadata, &computepb.Items{
+			Key:   proto.String("metrics-token"),
+			Value: proto.String(p.metricsToken),
+		})
+	}
+	if p.otlpEndpoint != "" {
+		metadata = append(metadata, &computepb.Items{
+			Key:   proto.String("otlp-endpoint"),
+			Value: proto.String(p.otlpEndpoint),
+		})
+	}
+
+	if p.recordTerminals {
+		metadata = append(metadata, &computepb.Items{
+			Key:   proto.String("record-terminals"),
+			Value: proto.String("true"),
+		})
+	}
 
 	// Add SSH key if provided
 	if cfg.SSHPublicKey != "" {
@@ -214,26 +265,43 @@ func (p *GCPFirecrackerProvider) createGCPVM(ctx context.Context, cfg VMConfig)
 	}
 
 	// Create the VM
-	op, err := client.Insert(ctx, &computepb.InsertInstanceRequest{
+	insertCtx, span := tracing.Start(ctx, "gce.instances.insert", attribute.String("gce.instance", vmName))
+	op, err := client.Insert(insertCtx, &computepb.InsertInstanceRequest{
 		Project:          p.project,
 		Zone:             p.zone,
 		InstanceResource: instance,
 	})
 	if err != nil {
+		tracing.Fail(span, err)
+		span.End()
 		return nil, fmt.Errorf("failed to create VM: %w", err)
 	}
 
 	// Wait for operation to complete
-	if err := op.Wait(ctx); err != nil {
+	if err := op.Wait(insertCtx); err != nil {
+		tracing.Fail(span, err)
+		span.End()
 		return nil, fmt.Errorf("failed waiting for VM creation: %w", err)
 	}
+	span.End()
+	cfg.recordEvent(EventGCEOperationDone, 0, "GCE insert operation completed")
 
 	// Get the created instance details (includes external IP)
-	return p.GetVM(ctx, cfg.WorkshopID)
+	getCtx, span := tracing.Start(ctx, "gce.instances.get", attribute.String("gce.instance", vmName))
+	defer span.End()
+	vm, err := p.GetVM(getCtx, cfg.WorkshopID)
+	tracing.Fail(span, err)
+	return vm, err
 }
 
 // waitForAgentHealth polls the agent health endpoint until it responds
-func (p *GCPFirecrackerProvider) waitForAgentHealth(ctx context.Context, agentURL string, timeout time.Duration) error {
+func (p *GCPFirecrackerProvider) waitForAgentHealth(ctx context.Context, agentURL string, timeout time.Duration) (err error) {
+	ctx, span := tracing.Start(ctx, "waitForAgentHealth", attribute.String("agent.url", agentURL))
+	defer func() {
+		tracing.Fail(span, err)
+		span.End()
+	}()
+
 	healthURL := fmt.Sprintf("%s/health", agentURL)
 	deadline := time.Now().Add(timeout)
 	ticker := time.NewTicker(5 * time.Second)
@@ -255,7 +323,11 @@ func (p *GCPFirecrackerProvider) waitForAgentHealth(ctx context.Context, agentUR
 				return fmt.Errorf("timeout waiting for agent to become healthy")
 			}
 
-			resp, err := p.httpClient.Get(healthURL)
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, healthURL, nil)
+			if err != nil {
+				return err
+			}
+			resp, err := p.httpClient.Do(req)
 			if err != nil {
 				continue // Agent not ready yet
 			}
@@ -269,39 +341,204 @@ func (p *GCPFirecrackerProvider) waitForAgentHealth(ctx context.Context, agentUR
 }
 
 // createMicroVMs calls the agent API to create a MicroVM for each seat
-func (p *GCPFirecrackerProvider) createMicroVMs(ctx context.Context, agentURL string, workshopID string, seats int) error {
+func (p *GCPFirecrackerProvider) createMicroVMs(ctx context.Context, agentURL string, cfg VMConfig) error {
 	createURL := fmt.Sprintf("%s/vms", agentURL)
 
-	for seatID := 1; seatID <= seats; seatID++ {
-		reqBody := map[string]interface{}{
-			"workshop_id": workshopID,
-			"seat_id":     seatID,
-		}
-		jsonBody, err := json.Marshal(reqBody)
-		if err != nil {
-			return fmt.Errorf("failed to marshal request for seat %d: %w", seatID, err)
+	for seatID := 1; seatID <= cfg.Seats; seatID++ {
+		if err := p.createMicroVM(ctx, createURL, cfg.WorkshopID, seatID); err != nil {
+			cfg.recordEvent(EventSeatFailed, seatID, err.Error())
+			return err
 		}
+		cfg.recordEvent(EventSeatCreated, seatID, "MicroVM created by agent")
+	}
 
-		req, err := http.NewRequestWithContext(ctx, http.MethodPost, createURL, bytes.NewReader(jsonBody))
-		if err != nil {
-			return fmt.Errorf("failed to create request for seat %d: %w", seatID, err)
-		}
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.agentToken))
+	return nil
+}
 
-		resp, err := p.httpClient.Do(req)
-		if err != nil {
-			return fmt.Errorf("failed to create MicroVM for seat %d: %w", seatID, err)
+// createMicroVM asks the agent to create the MicroVM for a single seat
+func (p *GCPFirecrackerProvider) createMicroVM(ctx context.Context, createURL string, workshopID string, seatID int) (err error) {
+	ctx = logging.WithSeat(ctx, workshopID, seatID)
+	ctx, span := tracing.Start(ctx, "agent.createMicroVM",
+		attribute.String("workshop.id", workshopID),
+		attribute.Int("seat.id", seatID),
+	)
+	defer func() {
+		tracing.Fail(span, err)
+		span.End()
+	}()
+
+	reqBody := map[string]interface{}{
+		"workshop_id": workshopID,
+		"seat_id":     seatID,
+	}
+	jsonBody, err := json.Marshal(reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request for seat %d: %w", seatID, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, createURL, bytes.NewReader(jsonBody))
+	if err != nil {
+		return fmt.Errorf("failed to create request for seat %d: %w", seatID, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.agentToken))
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to create MicroVM for seat %d: %w", seatID, err)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("failed to create MicroVM for seat %d: status %d, body: %s", seatID, resp.StatusCode, string(body))
+	}
+
+	p.logger.DebugContext(ctx, "MicroVM created")
+	return nil
+}
+
+// AddSeats asks the workshop's agent to create MicroVMs for additional seats
+func (p *GCPFirecrackerProvider) AddSeats(ctx context.Context, cfg VMConfig, seatIDs []int) error {
+	agentURL, err := p.agentURL(ctx, cfg.WorkshopID)
+	if err != nil {
+		return err
+	}
+
+	createURL := fmt.Sprintf("%s/vms", agentURL)
+	for _, seatID := range seatIDs {
+		if err := p.createMicroVM(ctx, createURL, cfg.WorkshopID, seatID); err != nil {
+			cfg.recordEvent(EventSeatFailed, seatID, err.Error())
+			return err
 		}
+		cfg.recordEvent(EventSeatCreated, seatID, "MicroVM created by agent")
+	}
+	return nil
+}
 
-		body, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
+// RemoveSeats asks the workshop's agent to destroy the MicroVMs for the given seats
+func (p *GCPFirecrackerProvider) RemoveSeats(ctx context.Context, workshopID string, seatIDs []int) error {
+	agentURL, err := p.agentURL(ctx, workshopID)
+	if err != nil {
+		return err
+	}
 
-		if resp.StatusCode != http.StatusCreated {
-			return fmt.Errorf("failed to create MicroVM for seat %d: status %d, body: %s", seatID, resp.StatusCode, string(body))
+	var lastErr error
+	for _, seatID := range seatIDs {
+		if err := p.destroyMicroVM(ctx, agentURL, workshopID, seatID); err != nil {
+			lastErr = err
 		}
 	}
+	return lastErr
+}
+
+// agentURL returns the agent API base URL for a running workshop VM
+func (p *GCPFirecrackerProvider) agentURL(ctx context.Context, workshopID string) (string, error) {
+	vm, err := p.GetVM(ctx, workshopID)
+	if err != nil {
+		return "", err
+	}
+	if vm.ExternalIP == "" {
+		return "", fmt.Errorf("VM %s has no external IP", vm.Name)
+	}
+	return fmt.Sprintf("http://%s:%d", vm.ExternalIP, p.agentPort), nil
+}
+
+// maxRecordingSize is the largest terminal recording fetched from an agent
+const maxRecordingSize = 64 << 20
+
+// ListRecordings lists the terminal recordings on the workshop's agent
+func (p *GCPFirecrackerProvider) ListRecordings(ctx context.Context, workshopID string) ([]RecordingInfo, error) {
+	agentURL, err := p.agentURL(ctx, workshopID)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/recordings?workshop_id=%s", agentURL, url.QueryEscape(workshopID)), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.agentToken))
 
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list recordings: status %d", resp.StatusCode)
+	}
+
+	var listResp struct {
+		Recordings []RecordingInfo `json:"recordings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&listResp); err != nil {
+		return nil, err
+	}
+	return listResp.Recordings, nil
+}
+
+// FetchRecording downloads one recording from the workshop's agent
+func (p *GCPFirecrackerProvider) FetchRecording(ctx context.Context, workshopID, name string) ([]byte, error) {
+	agentURL, err := p.agentURL(ctx, workshopID)
+	if err != nil {
+		return nil, err
+	}
+	fetchURL := fmt.Sprintf("%s/recordings/%s/%s", agentURL, url.PathEscape(workshopID), url.PathEscape(name))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fetchURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.agentToken))
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch recording %s: status %d", name, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRecordingSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxRecordingSize {
+		return nil, fmt.Errorf("recording %s is larger than %d bytes", name, maxRecordingSize)
+	}
+	return data, nil
+}
+
+// RevokeWorkspaceTokens asks the workshop's agent to reject a learner's
+// tokens for a seat
+func (p *GCPFirecrackerProvider) RevokeWorkspaceTokens(ctx context.Context, workshopID string, seatID int, registrationID string) error {
+	agentURL, err := p.agentURL(ctx, workshopID)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"workshop_id":     workshopID,
+		"seat_id":         seatID,
+		"registration_id": registrationID,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, agentURL+"/tokens/revoke", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.agentToken))
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("failed to revoke tokens for seat %d: status %d", seatID, resp.StatusCode)
+	}
 	return nil
 }
 
@@ -312,10 +549,13 @@ func (p *GCPFirecrackerProvider) DeleteVM(ctx context.Context, workshopID string
 	if err == nil && vm.ExternalIP != "" {
 		// Try to clean up MicroVMs via agent (best effort)
 		agentURL := fmt.Sprintf("http://%s:%d", vm.ExternalIP, p.agentPort)
-		_ = p.destroyMicroVMs(ctx, agentURL, workshopID)
+		if err := p.destroyMicroVMs(ctx, agentURL, workshopID); err != nil {
+			p.logger.WarnContext(ctx, "failed to destroy MicroVMs via agent", "error", err)
+		}
 	}
 
 	// Delete the GCP VM
+	p.logger.InfoContext(ctx, "deleting GCE VM", "vm_name", p.vmName(workshopID))
 	return p.deleteGCPVM(ctx, workshopID)
 }
 
@@ -349,22 +589,32 @@ func (p *GCPFirecrackerProvider) destroyMicroVMs(ctx context.Context, agentURL s
 		return err
 	}
 
-	// Delete each VM
+	// Delete each VM (best effort)
 	for _, vm := range listResp.VMs {
-		deleteURL := fmt.Sprintf("%s/vms/%s/%d", agentURL, workshopID, vm.SeatID)
-		req, err := http.NewRequestWithContext(ctx, http.MethodDelete, deleteURL, nil)
-		if err != nil {
-			continue
-		}
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.agentToken))
+		p.destroyMicroVM(ctx, agentURL, workshopID, vm.SeatID)
+	}
 
-		resp, err := p.httpClient.Do(req)
-		if err != nil {
-			continue
-		}
-		resp.Body.Close()
+	return nil
+}
+
+// destroyMicroVM asks the agent to destroy the MicroVM for a single seat
+func (p *GCPFirecrackerProvider) destroyMicroVM(ctx context.Context, agentURL string, workshopID string, seatID int) error {
+	deleteURL := fmt.Sprintf("%s/vms/%s/%d", agentURL, workshopID, seatID)
+	req, err := http.NewRequestWithContext(logging.WithSeat(ctx, workshopID, seatID), http.MethodDelete, deleteURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.agentToken))
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to destroy MicroVM for seat %d: %w", seatID, err)
 	}
+	resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("failed to destroy MicroVM for seat %d: status %d", seatID, resp.StatusCode)
+	}
 	return nil
 }
 
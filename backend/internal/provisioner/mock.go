@@ -42,6 +42,23 @@ func (m *MockProvider) CreateVM(ctx context.Context, cfg VMConfig) (*VMInstance,
 	return vm, nil
 }
 
+// AddSeats records the new seats as created; mock VMs have no per-seat state
+func (m *MockProvider) AddSeats(ctx context.Context, cfg VMConfig, seatIDs []int) error {
+	if _, err := m.GetVM(ctx, cfg.WorkshopID); err != nil {
+		return err
+	}
+	for _, seatID := range seatIDs {
+		cfg.recordEvent(EventSeatCreated, seatID, "Mock seat created")
+	}
+	return nil
+}
+
+// RemoveSeats is a no-op for mock VMs
+func (m *MockProvider) RemoveSeats(ctx context.Context, workshopID string, seatIDs []int) error {
+	_, err := m.GetVM(ctx, workshopID)
+	return err
+}
+
 func (m *MockProvider) DeleteVM(ctx context.Context, workshopID string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
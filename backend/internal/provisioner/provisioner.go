@@ -13,10 +13,31 @@ type VMConfig struct {
 	RuntimeType    string // "docker" (default) or "firecracker"
 	MachineType    string // e.g., "e2-standard-4"
 	DiskSizeGB     int
-	Spot           bool   // Use spot/preemptible VMs
-	SSHPublicKey   string // Optional SSH public key for debugging
-	EnableOpsAgent bool   // Install Google Cloud Ops Agent
-	AuthDisabled   bool   // Disable JWT auth in workspace containers
+	Spot           bool          // Use spot/preemptible VMs
+	SSHPublicKey   string        // Optional SSH public key for debugging
+	EnableOpsAgent bool          // Install Google Cloud Ops Agent
+	AuthDisabled   bool          // Disable JWT auth in workspace containers
+	Events         EventRecorder // Optional: receives provisioning timeline events
+}
+
+// Provisioning timeline event types reported through VMConfig.Events
+const (
+	EventVMCreateRequested = "vm_create_requested"
+	EventGCEOperationDone  = "gce_operation_done"
+	EventAgentHealthy      = "agent_healthy"
+	EventSeatCreated       = "seat_created"
+	EventSeatFailed        = "seat_failed"
+)
+
+// EventRecorder receives a provisioning timeline event. seatID is 0 for
+// events that apply to the whole workshop.
+type EventRecorder func(eventType string, seatID int, message string)
+
+// recordEvent forwards an event to cfg.Events if one is set
+func (cfg VMConfig) recordEvent(eventType string, seatID int, message string) {
+	if cfg.Events != nil {
+		cfg.Events(eventType, seatID, message)
+	}
 }
 
 // VMInstance represents a provisioned VM
@@ -27,7 +48,7 @@ type VMInstance struct {
 	InternalIP string `json:"internal_ip"` // Private IP address
 	Status     string `json:"status"`      // RUNNING, TERMINATED, etc.
 	Zone       string `json:"zone"`
-	SelfLink   string `json:"self_link"`   // Full resource URL
+	SelfLink   string `json:"self_link"` // Full resource URL
 }
 
 // Provisioner defines the interface for VM lifecycle management
@@ -48,6 +69,45 @@ type Provisioner interface {
 	ListVMs(ctx context.Context, workshopID string) ([]*VMInstance, error)
 }
 
+// SeatScaler is implemented by provisioners that can add or remove seats on a
+// running workshop without recreating its VM. Provisioners that bake the seat
+// count into the VM (e.g. the Docker startup script) do not implement it.
+type SeatScaler interface {
+	// AddSeats creates workspaces for seatIDs, reporting progress through cfg.Events
+	AddSeats(ctx context.Context, cfg VMConfig, seatIDs []int) error
+
+	// RemoveSeats destroys the workspaces for seatIDs
+	RemoveSeats(ctx context.Context, workshopID string, seatIDs []int) error
+}
+
+// RecordingSource is implemented by provisioners whose workers record learner
+// terminals, so the control plane can collect recordings before the VM is
+// deleted.
+type RecordingSource interface {
+	// ListRecordings lists the terminal recordings stored on the workshop's worker
+	ListRecordings(ctx context.Context, workshopID string) ([]RecordingInfo, error)
+
+	// FetchRecording downloads one recording in asciicast v2 format
+	FetchRecording(ctx context.Context, workshopID, name string) ([]byte, error)
+}
+
+// TokenRevoker is implemented by provisioners whose workers check learner
+// workspace tokens, so the control plane can cut a learner off when their
+// registration is revoked or moved to another seat.
+type TokenRevoker interface {
+	// RevokeWorkspaceTokens rejects the registration's tokens for the seat
+	// issued so far and ends the seat's terminal session
+	RevokeWorkspaceTokens(ctx context.Context, workshopID string, seatID int, registrationID string) error
+}
+
+// RecordingInfo describes a terminal recording stored on a worker
+type RecordingInfo struct {
+	SeatID    int       `json:"seat_id"`
+	Name      string    `json:"name"` // Unique per workshop
+	SizeBytes int64     `json:"size_bytes"`
+	StartedAt time.Time `json:"started_at"`
+}
+
 // DefaultConfig returns sensible defaults for VM configuration
 func DefaultConfig(workshopID string, seats int) VMConfig {
 	return VMConfig{
@@ -55,7 +115,23 @@ func DefaultConfig(workshopID string, seats int) VMConfig {
 		Seats:          seats,
 		MachineType:    "e2-standard-4",
 		DiskSizeGB:     50,
-		Spot:           true, // Default to spot for cost savings
+		Spot:           true,  // Default to spot for cost savings
 		EnableOpsAgent: false, // COS doesn't support Ops Agent installation
 	}
 }
+
+// Name returns a short, stable identifier for a provisioner, used as a metrics label.
+func Name(p Provisioner) string {
+	switch p.(type) {
+	case *GCPFirecrackerProvider:
+		return "gcp-firecracker"
+	case *FirecrackerProvisioner:
+		return "firecracker"
+	case *GCPProvider:
+		return "gcp"
+	case *MockProvider:
+		return "mock"
+	default:
+		return "other"
+	}
+}
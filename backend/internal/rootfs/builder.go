@@ -7,12 +7,13 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 
-	"github.com/sirupsen/logrus"
+	"github.com/clarateach/backend/internal/logging"
 )
 
 // Config holds the configuration for building a rootfs image.
@@ -41,19 +42,17 @@ type Config struct {
 
 // Builder creates rootfs images for Firecracker MicroVMs.
 type Builder struct {
-	logger *logrus.Logger
+	logger *slog.Logger
 }
 
 // NewBuilder creates a new rootfs Builder.
 func NewBuilder() *Builder {
-	logger := logrus.New()
-	logger.SetLevel(logrus.InfoLevel)
-	return &Builder{logger: logger}
+	return &Builder{logger: logging.Component("rootfs")}
 }
 
-// SetLogLevel sets the logging level.
-func (b *Builder) SetLogLevel(level logrus.Level) {
-	b.logger.SetLevel(level)
+// SetLogger replaces the builder's logger.
+func (b *Builder) SetLogger(logger *slog.Logger) {
+	b.logger = logger
 }
 
 // Build creates a rootfs image from a Docker container.
@@ -75,7 +74,7 @@ func (b *Builder) Build(ctx context.Context, cfg Config) error {
 	}
 	defer b.cleanup(tmpDir)
 
-	b.logger.Infof("Using temp directory: %s", tmpDir)
+	b.logger.InfoContext(ctx, "using temp directory", "path", tmpDir)
 
 	// Step 1: Ensure Docker image exists
 	if err := b.ensureDockerImage(ctx, cfg); err != nil {
@@ -114,7 +113,7 @@ func (b *Builder) Build(ctx context.Context, cfg Config) error {
 
 	// Step 6: Ensure required utilities exist
 	if err := b.ensureUtilities(ctx, cfg.DockerImage, mountPoint); err != nil {
-		b.logger.Warnf("Failed to ensure utilities: %v", err)
+		b.logger.WarnContext(ctx, "failed to ensure utilities", "error", err)
 	}
 
 	// Step 7: Unmount
@@ -127,7 +126,7 @@ func (b *Builder) Build(ctx context.Context, cfg Config) error {
 		return fmt.Errorf("failed to move output: %w", err)
 	}
 
-	b.logger.Infof("Rootfs build complete: %s", cfg.OutputPath)
+	b.logger.InfoContext(ctx, "rootfs build complete", "output", cfg.OutputPath)
 	return nil
 }
 
@@ -179,7 +178,7 @@ func (b *Builder) findMkfsExt4() string {
 }
 
 func (b *Builder) cleanup(tmpDir string) {
-	b.logger.Info("Cleaning up...")
+	b.logger.Info("cleaning up")
 	// Try to unmount any mounted filesystems
 	mntPath := filepath.Join(tmpDir, "mnt")
 	b.unmount(mntPath)
@@ -195,7 +194,7 @@ func (b *Builder) ensureDockerImage(ctx context.Context, cfg Config) error {
 	}
 
 	if strings.TrimSpace(string(output)) != "" {
-		b.logger.Infof("Docker image %s found", cfg.DockerImage)
+		b.logger.InfoContext(ctx, "Docker image found", "image", cfg.DockerImage)
 		return nil
 	}
 
@@ -204,7 +203,7 @@ func (b *Builder) ensureDockerImage(ctx context.Context, cfg Config) error {
 		return fmt.Errorf("Docker image %s not found and no Dockerfile specified", cfg.DockerImage)
 	}
 
-	b.logger.Infof("Building Docker image %s from %s", cfg.DockerImage, cfg.DockerfilePath)
+	b.logger.InfoContext(ctx, "building Docker image", "image", cfg.DockerImage, "dockerfile", cfg.DockerfilePath)
 
 	dockerContext := cfg.DockerContext
 	if dockerContext == "" {
@@ -226,7 +225,7 @@ func (b *Builder) ensureDockerImage(ctx context.Context, cfg Config) error {
 }
 
 func (b *Builder) createExt4(ctx context.Context, path, size string) error {
-	b.logger.Infof("Creating ext4 image: %s (size: %s)", path, size)
+	b.logger.InfoContext(ctx, "creating ext4 image", "path", path, "size", size)
 
 	// Create sparse file with truncate
 	cmd := exec.CommandContext(ctx, "truncate", "-s", size, path)
@@ -245,7 +244,7 @@ func (b *Builder) createExt4(ctx context.Context, path, size string) error {
 }
 
 func (b *Builder) mount(ctx context.Context, source, target string) error {
-	b.logger.Infof("Mounting %s to %s", source, target)
+	b.logger.InfoContext(ctx, "mounting image", "source", source, "target", target)
 	cmd := exec.CommandContext(ctx, "sudo", "mount", source, target)
 	if output, err := cmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("mount failed: %w\n%s", err, output)
@@ -260,7 +259,7 @@ func (b *Builder) unmount(target string) error {
 }
 
 func (b *Builder) exportDocker(ctx context.Context, imageName, mountPoint string) error {
-	b.logger.Infof("Exporting Docker image %s to %s", imageName, mountPoint)
+	b.logger.InfoContext(ctx, "exporting Docker image", "image", imageName, "target", mountPoint)
 
 	// Create container
 	createCmd := exec.CommandContext(ctx, "docker", "create", imageName)
@@ -273,7 +272,7 @@ func (b *Builder) exportDocker(ctx context.Context, imageName, mountPoint string
 		exec.Command("docker", "rm", containerID).Run()
 	}()
 
-	b.logger.Infof("Created container %s", containerID)
+	b.logger.DebugContext(ctx, "created container", "container_id", containerID)
 
 	// Export and extract
 	// Use: docker export <container> | sudo tar -xf - -C <mountpoint>
@@ -313,7 +312,7 @@ func (b *Builder) exportDocker(ctx context.Context, imageName, mountPoint string
 
 func (b *Builder) injectInitScript(mountPoint, script string) error {
 	initPath := filepath.Join(mountPoint, "sbin", "init")
-	b.logger.Infof("Injecting init script to %s", initPath)
+	b.logger.Info("injecting init script", "path", initPath)
 
 	// Ensure /sbin exists
 	sbinDir := filepath.Join(mountPoint, "sbin")
@@ -356,7 +355,7 @@ func (b *Builder) ensureUtilities(ctx context.Context, imageName, mountPoint str
 		}
 	}
 
-	b.logger.Warn("'ip' command not found in rootfs, attempting to install iproute2...")
+	b.logger.Warn("'ip' command not found in rootfs, attempting to install iproute2")
 
 	// Try to install via docker run
 	cmd := exec.CommandContext(ctx, "docker", "run", "--rm",
@@ -369,7 +368,7 @@ func (b *Builder) ensureUtilities(ctx context.Context, imageName, mountPoint str
 }
 
 func (b *Builder) moveOutput(src, dst string) error {
-	b.logger.Infof("Moving %s to %s", src, dst)
+	b.logger.Info("moving image", "source", src, "target", dst)
 
 	// Ensure output directory exists
 	dstDir := filepath.Dir(dst)
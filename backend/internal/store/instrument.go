@@ -0,0 +1,92 @@
+package store
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// QueryObserver receives the duration of each database query, keyed by a
+// short operation name such as "select workshops" or "update sessions".
+type QueryObserver func(operation string, d time.Duration)
+
+// observedDB wraps *sql.DB and reports query latency to an optional observer.
+type observedDB struct {
+	*sql.DB
+	observe QueryObserver
+}
+
+func (db *observedDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	defer db.track(query, time.Now())
+	return db.DB.Exec(query, args...)
+}
+
+func (db *observedDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	defer db.track(query, time.Now())
+	return db.DB.Query(query, args...)
+}
+
+// QueryRow executes the query immediately; only Scan is deferred to the caller.
+func (db *observedDB) QueryRow(query string, args ...interface{}) *sql.Row {
+	defer db.track(query, time.Now())
+	return db.DB.QueryRow(query, args...)
+}
+
+// Begin starts a transaction whose queries are reported like the DB's own.
+func (db *observedDB) Begin() (*observedTx, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &observedTx{Tx: tx, observe: db.observe}, nil
+}
+
+func (db *observedDB) track(query string, start time.Time) {
+	trackQuery(db.observe, query, start)
+}
+
+// observedTx wraps *sql.Tx and reports query latency like observedDB.
+type observedTx struct {
+	*sql.Tx
+	observe QueryObserver
+}
+
+func (tx *observedTx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	defer trackQuery(tx.observe, query, time.Now())
+	return tx.Tx.Exec(query, args...)
+}
+
+func (tx *observedTx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	defer trackQuery(tx.observe, query, time.Now())
+	return tx.Tx.Query(query, args...)
+}
+
+func (tx *observedTx) QueryRow(query string, args ...interface{}) *sql.Row {
+	defer trackQuery(tx.observe, query, time.Now())
+	return tx.Tx.QueryRow(query, args...)
+}
+
+func trackQuery(observe QueryObserver, query string, start time.Time) {
+	if observe != nil {
+		observe(queryOperation(query), time.Since(start))
+	}
+}
+
+// queryOperation derives a low-cardinality label from a SQL statement:
+// the leading verb followed by the first table it touches.
+func queryOperation(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "unknown"
+	}
+
+	verb := strings.ToLower(fields[0])
+	for i := 0; i < len(fields)-1; i++ {
+		switch strings.ToUpper(fields[i]) {
+		case "FROM", "INTO", "UPDATE":
+			table := strings.Trim(fields[i+1], "(),;")
+			return verb + " " + strings.ToLower(table)
+		}
+	}
+	return verb
+}
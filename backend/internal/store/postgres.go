@@ -3,16 +3,23 @@ package store
 import (
 	"context"
 	"database/sql"
+	"strings"
+	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
 type PostgresStore struct {
-	db *sql.DB
+	db *observedDB
 }
 
 func NewPostgresStore(db *sql.DB) *PostgresStore {
-	return &PostgresStore{db: db}
+	return &PostgresStore{db: &observedDB{DB: db}}
+}
+
+// SetQueryObserver registers a callback that receives the latency of every query.
+func (s *PostgresStore) SetQueryObserver(fn QueryObserver) {
+	s.db.observe = fn
 }
 
 // InitPostgresDB initializes a PostgreSQL database connection
@@ -40,8 +47,8 @@ func (s *PostgresStore) CreateUser(u *User) error {
 
 func (s *PostgresStore) GetUser(id string) (*User, error) {
 	u := &User{}
-	query := `SELECT id, email, password_hash, name, role, created_at FROM users WHERE id = $1`
-	err := s.db.QueryRow(query, id).Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Name, &u.Role, &u.CreatedAt)
+	query := `SELECT id, email, password_hash, name, role, disabled, created_at FROM users WHERE id = $1`
+	err := s.db.QueryRow(query, id).Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Name, &u.Role, &u.Disabled, &u.CreatedAt)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -50,8 +57,8 @@ func (s *PostgresStore) GetUser(id string) (*User, error) {
 
 func (s *PostgresStore) GetUserByEmail(email string) (*User, error) {
 	u := &User{}
-	query := `SELECT id, email, password_hash, name, role, created_at FROM users WHERE email = $1`
-	err := s.db.QueryRow(query, email).Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Name, &u.Role, &u.CreatedAt)
+	query := `SELECT id, email, password_hash, name, role, disabled, created_at FROM users WHERE email = $1`
+	err := s.db.QueryRow(query, email).Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Name, &u.Role, &u.Disabled, &u.CreatedAt)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -59,7 +66,7 @@ func (s *PostgresStore) GetUserByEmail(email string) (*User, error) {
 }
 
 func (s *PostgresStore) ListUsers() ([]*User, error) {
-	query := `SELECT id, email, password_hash, name, role, created_at FROM users ORDER BY created_at DESC`
+	query := `SELECT id, email, password_hash, name, role, disabled, created_at FROM users ORDER BY created_at DESC`
 	rows, err := s.db.Query(query)
 	if err != nil {
 		return nil, err
@@ -69,7 +76,7 @@ func (s *PostgresStore) ListUsers() ([]*User, error) {
 	var users []*User
 	for rows.Next() {
 		u := &User{}
-		if err := rows.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Name, &u.Role, &u.CreatedAt); err != nil {
+		if err := rows.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Name, &u.Role, &u.Disabled, &u.CreatedAt); err != nil {
 			return nil, err
 		}
 		users = append(users, u)
@@ -77,23 +84,151 @@ func (s *PostgresStore) ListUsers() ([]*User, error) {
 	return users, nil
 }
 
+func (s *PostgresStore) UpdateUserRole(id, role string) error {
+	_, err := s.db.Exec(`UPDATE users SET role = $1 WHERE id = $2`, role, id)
+	return err
+}
+
+func (s *PostgresStore) SetUserDisabled(id string, disabled bool) error {
+	_, err := s.db.Exec(`UPDATE users SET disabled = $1 WHERE id = $2`, disabled, id)
+	return err
+}
+
+// -- Identity Operations --
+
+func (s *PostgresStore) CreateUserIdentity(i *UserIdentity) error {
+	query := `INSERT INTO user_identities (provider, subject, user_id, email, created_at) VALUES ($1, $2, $3, $4, $5)`
+	_, err := s.db.Exec(query, i.Provider, i.Subject, i.UserID, i.Email, i.CreatedAt)
+	return err
+}
+
+func (s *PostgresStore) GetUserIdentity(provider, subject string) (*UserIdentity, error) {
+	i := &UserIdentity{}
+	query := `SELECT provider, subject, user_id, email, created_at FROM user_identities WHERE provider = $1 AND subject = $2`
+	err := s.db.QueryRow(query, provider, subject).Scan(&i.Provider, &i.Subject, &i.UserID, &i.Email, &i.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+func (s *PostgresStore) ListUserIdentities(userID string) ([]*UserIdentity, error) {
+	query := `SELECT provider, subject, user_id, email, created_at FROM user_identities WHERE user_id = $1 ORDER BY created_at`
+	rows, err := s.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var identities []*UserIdentity
+	for rows.Next() {
+		i := &UserIdentity{}
+		if err := rows.Scan(&i.Provider, &i.Subject, &i.UserID, &i.Email, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		identities = append(identities, i)
+	}
+	return identities, rows.Err()
+}
+
+func (s *PostgresStore) DeleteUserIdentity(userID, provider string) error {
+	_, err := s.db.Exec(`DELETE FROM user_identities WHERE user_id = $1 AND provider = $2`, userID, provider)
+	return err
+}
+
+// -- Token Operations --
+
+func (s *PostgresStore) CreateRefreshToken(t *RefreshToken) error {
+	query := `INSERT INTO refresh_tokens (id, session_id, user_id, access_token_id, access_expires_at, expires_at, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err := s.db.Exec(query, t.ID, t.SessionID, t.UserID, t.AccessTokenID, t.AccessExpiresAt, t.ExpiresAt, t.CreatedAt)
+	return err
+}
+
+func (s *PostgresStore) GetRefreshToken(id string) (*RefreshToken, error) {
+	t := &RefreshToken{}
+	query := `SELECT id, session_id, user_id, access_token_id, access_expires_at, expires_at, created_at, used_at, revoked_at FROM refresh_tokens WHERE id = $1`
+	err := s.db.QueryRow(query, id).Scan(&t.ID, &t.SessionID, &t.UserID, &t.AccessTokenID, &t.AccessExpiresAt, &t.ExpiresAt, &t.CreatedAt, &t.UsedAt, &t.RevokedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (s *PostgresStore) UseRefreshToken(id string, now time.Time) (bool, error) {
+	query := `UPDATE refresh_tokens SET used_at = $1 WHERE id = $2 AND used_at IS NULL AND revoked_at IS NULL AND expires_at > $3`
+	result, err := s.db.Exec(query, now, id, now)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+func (s *PostgresStore) RevokeSession(sessionID string, now time.Time) error {
+	return s.revokeSessions(`session_id = $2`, sessionID, now)
+}
+
+func (s *PostgresStore) RevokeUserSessions(userID string, now time.Time) error {
+	return s.revokeSessions(`user_id = $2`, userID, now)
+}
+
+// revokeSessions revokes the refresh tokens matching where, whose second
+// parameter is arg, and the access tokens issued with them that have not
+// expired yet.
+func (s *PostgresStore) revokeSessions(where, arg string, now time.Time) error {
+	query := `INSERT INTO revoked_tokens (token_id, expires_at, revoked_at)
+		SELECT access_token_id, access_expires_at, $1::timestamp FROM refresh_tokens WHERE ` + where + ` AND access_expires_at > $3
+		ON CONFLICT (token_id) DO NOTHING`
+	if _, err := s.db.Exec(query, now, arg, now); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`UPDATE refresh_tokens SET revoked_at = $1 WHERE `+where+` AND revoked_at IS NULL`, now, arg)
+	return err
+}
+
+func (s *PostgresStore) RevokeToken(tokenID string, expiresAt, now time.Time) error {
+	query := `INSERT INTO revoked_tokens (token_id, expires_at, revoked_at) VALUES ($1, $2, $3) ON CONFLICT (token_id) DO NOTHING`
+	_, err := s.db.Exec(query, tokenID, expiresAt, now)
+	return err
+}
+
+func (s *PostgresStore) IsTokenRevoked(tokenID string) (bool, error) {
+	var n int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM revoked_tokens WHERE token_id = $1`, tokenID).Scan(&n)
+	return n > 0, err
+}
+
+func (s *PostgresStore) DeleteExpiredTokens(now time.Time) error {
+	if _, err := s.db.Exec(`DELETE FROM refresh_tokens WHERE expires_at <= $1`, now); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`DELETE FROM revoked_tokens WHERE expires_at <= $1`, now)
+	return err
+}
+
 // -- Workshop Operations --
 
 func (s *PostgresStore) CreateWorkshop(w *Workshop) error {
-	query := `INSERT INTO workshops (id, name, code, seats, api_key, runtime_type, status, owner_id, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	query := `INSERT INTO workshops (id, name, code, seats, api_key, runtime_type, status, waitlist, starts_at, owner_id, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
 	// Convert empty owner_id to NULL for foreign key constraint
 	var ownerID interface{} = w.OwnerID
 	if w.OwnerID == "" {
 		ownerID = nil
 	}
-	_, err := s.db.Exec(query, w.ID, w.Name, w.Code, w.Seats, w.ApiKey, w.RuntimeType, w.Status, ownerID, w.CreatedAt)
+	_, err := s.db.Exec(query, w.ID, w.Name, w.Code, w.Seats, w.ApiKey, w.RuntimeType, w.Status, w.Waitlist, w.StartsAt, ownerID, w.CreatedAt)
 	return err
 }
 
 func (s *PostgresStore) GetWorkshop(id string) (*Workshop, error) {
 	w := &Workshop{}
-	query := `SELECT id, name, code, seats, api_key, runtime_type, status, COALESCE(owner_id, ''), created_at FROM workshops WHERE id = $1`
-	err := s.db.QueryRow(query, id).Scan(&w.ID, &w.Name, &w.Code, &w.Seats, &w.ApiKey, &w.RuntimeType, &w.Status, &w.OwnerID, &w.CreatedAt)
+	query := `SELECT id, name, code, seats, api_key, runtime_type, status, waitlist, starts_at, COALESCE(owner_id, ''), created_at FROM workshops WHERE id = $1`
+	err := s.db.QueryRow(query, id).Scan(&w.ID, &w.Name, &w.Code, &w.Seats, &w.ApiKey, &w.RuntimeType, &w.Status, &w.Waitlist, &w.StartsAt, &w.OwnerID, &w.CreatedAt)
 	if err == sql.ErrNoRows {
 		return nil, nil // Not found
 	}
@@ -102,8 +237,8 @@ func (s *PostgresStore) GetWorkshop(id string) (*Workshop, error) {
 
 func (s *PostgresStore) GetWorkshopByCode(code string) (*Workshop, error) {
 	w := &Workshop{}
-	query := `SELECT id, name, code, seats, api_key, runtime_type, status, COALESCE(owner_id, ''), created_at FROM workshops WHERE code = $1`
-	err := s.db.QueryRow(query, code).Scan(&w.ID, &w.Name, &w.Code, &w.Seats, &w.ApiKey, &w.RuntimeType, &w.Status, &w.OwnerID, &w.CreatedAt)
+	query := `SELECT id, name, code, seats, api_key, runtime_type, status, waitlist, starts_at, COALESCE(owner_id, ''), created_at FROM workshops WHERE code = $1`
+	err := s.db.QueryRow(query, code).Scan(&w.ID, &w.Name, &w.Code, &w.Seats, &w.ApiKey, &w.RuntimeType, &w.Status, &w.Waitlist, &w.StartsAt, &w.OwnerID, &w.CreatedAt)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -111,7 +246,7 @@ func (s *PostgresStore) GetWorkshopByCode(code string) (*Workshop, error) {
 }
 
 func (s *PostgresStore) ListWorkshops() ([]*Workshop, error) {
-	query := `SELECT id, name, code, seats, api_key, runtime_type, status, COALESCE(owner_id, ''), created_at FROM workshops ORDER BY created_at DESC`
+	query := `SELECT id, name, code, seats, api_key, runtime_type, status, waitlist, starts_at, COALESCE(owner_id, ''), created_at FROM workshops ORDER BY created_at DESC`
 	rows, err := s.db.Query(query)
 	if err != nil {
 		return nil, err
@@ -121,7 +256,7 @@ func (s *PostgresStore) ListWorkshops() ([]*Workshop, error) {
 	var workshops []*Workshop
 	for rows.Next() {
 		w := &Workshop{}
-		if err := rows.Scan(&w.ID, &w.Name, &w.Code, &w.Seats, &w.ApiKey, &w.RuntimeType, &w.Status, &w.OwnerID, &w.CreatedAt); err != nil {
+		if err := rows.Scan(&w.ID, &w.Name, &w.Code, &w.Seats, &w.ApiKey, &w.RuntimeType, &w.Status, &w.Waitlist, &w.StartsAt, &w.OwnerID, &w.CreatedAt); err != nil {
 			return nil, err
 		}
 		workshops = append(workshops, w)
@@ -130,7 +265,7 @@ func (s *PostgresStore) ListWorkshops() ([]*Workshop, error) {
 }
 
 func (s *PostgresStore) ListWorkshopsByOwner(ownerID string) ([]*Workshop, error) {
-	query := `SELECT id, name, code, seats, api_key, runtime_type, status, COALESCE(owner_id, ''), created_at FROM workshops WHERE owner_id = $1 ORDER BY created_at DESC`
+	query := `SELECT id, name, code, seats, api_key, runtime_type, status, waitlist, starts_at, COALESCE(owner_id, ''), created_at FROM workshops WHERE owner_id = $1 ORDER BY created_at DESC`
 	rows, err := s.db.Query(query, ownerID)
 	if err != nil {
 		return nil, err
@@ -140,7 +275,7 @@ func (s *PostgresStore) ListWorkshopsByOwner(ownerID string) ([]*Workshop, error
 	var workshops []*Workshop
 	for rows.Next() {
 		w := &Workshop{}
-		if err := rows.Scan(&w.ID, &w.Name, &w.Code, &w.Seats, &w.ApiKey, &w.RuntimeType, &w.Status, &w.OwnerID, &w.CreatedAt); err != nil {
+		if err := rows.Scan(&w.ID, &w.Name, &w.Code, &w.Seats, &w.ApiKey, &w.RuntimeType, &w.Status, &w.Waitlist, &w.StartsAt, &w.OwnerID, &w.CreatedAt); err != nil {
 			return nil, err
 		}
 		workshops = append(workshops, w)
@@ -154,16 +289,139 @@ func (s *PostgresStore) UpdateWorkshopStatus(id string, status string) error {
 	return err
 }
 
+func (s *PostgresStore) UpdateWorkshop(w *Workshop) error {
+	query := `UPDATE workshops SET name = $1, api_key = $2, runtime_type = $3, waitlist = $4, starts_at = $5 WHERE id = $6`
+	_, err := s.db.Exec(query, w.Name, w.ApiKey, w.RuntimeType, w.Waitlist, w.StartsAt, w.ID)
+	return err
+}
+
+func (s *PostgresStore) ResizeWorkshopSeats(workshopID string, seats int, added []*Session) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Refuse to drop seats that a learner has joined or been assigned
+	var occupied int
+	query := `SELECT (SELECT COUNT(*) FROM sessions WHERE workshop_id = $1 AND seat_id > $2 AND (name != '' OR status = 'occupied'))
+		+ (SELECT COUNT(*) FROM registrations WHERE workshop_id = $3 AND seat_id > $4)`
+	if err := tx.QueryRow(query, workshopID, seats, workshopID, seats).Scan(&occupied); err != nil {
+		return err
+	}
+	if occupied > 0 {
+		return ErrSeatsOccupied
+	}
+
+	// Every active registration must keep a place
+	var registered int
+	query = `SELECT COUNT(*) FROM registrations WHERE workshop_id = $1 AND status NOT IN ('revoked', 'waitlisted')`
+	if err := tx.QueryRow(query, workshopID).Scan(&registered); err != nil {
+		return err
+	}
+	if registered > seats {
+		return ErrTooManyRegistrations
+	}
+
+	if _, err := tx.Exec(`DELETE FROM sessions WHERE workshop_id = $1 AND seat_id > $2`, workshopID, seats); err != nil {
+		return err
+	}
+	for _, session := range added {
+		query := `INSERT INTO sessions (odehash, workshop_id, seat_id, name, status, container_id, ip, joined_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+		if _, err := tx.Exec(query, session.OdeHash, session.WorkshopID, session.SeatID, session.Name, session.Status, session.ContainerID, session.IP, session.JoinedAt); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`UPDATE workshops SET seats = $1 WHERE id = $2`, seats, workshopID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 func (s *PostgresStore) DeleteWorkshop(id string) error {
 	// First delete related sessions
 	_, err := s.db.Exec(`DELETE FROM sessions WHERE workshop_id = $1`, id)
 	if err != nil {
 		return err
 	}
+	_, err = s.db.Exec(`DELETE FROM workshop_members WHERE workshop_id = $1`, id)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`DELETE FROM terminal_recordings WHERE workshop_id = $1`, id)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`DELETE FROM announcements WHERE workshop_id = $1`, id)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`DELETE FROM help_requests WHERE workshop_id = $1`, id)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`DELETE FROM notification_templates WHERE workshop_id = $1`, id)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`DELETE FROM notifications WHERE workshop_id = $1`, id)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`DELETE FROM login_links WHERE workshop_id = $1`, id)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`DELETE FROM webhook_deliveries WHERE webhook_id IN (SELECT id FROM webhooks WHERE workshop_id = $1)`, id)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`DELETE FROM webhooks WHERE workshop_id = $1`, id)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`DELETE FROM lti_contexts WHERE workshop_id = $1`, id)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`DELETE FROM lti_links WHERE workshop_id = $1`, id)
+	if err != nil {
+		return err
+	}
 	_, err = s.db.Exec(`DELETE FROM workshops WHERE id = $1`, id)
 	return err
 }
 
+func (s *PostgresStore) GetWorkshopStats() (*WorkshopStats, error) {
+	stats := &WorkshopStats{ByStatus: make(map[string]int)}
+	rows, err := s.db.Query(`SELECT status, COUNT(*) FROM workshops GROUP BY status`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		stats.ByStatus[status] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT
+		(SELECT COUNT(*) FROM sessions s JOIN workshops w ON w.id = s.workshop_id
+			WHERE w.status NOT IN ('stopped', 'deleted') AND s.status = 'occupied' AND s.name != ''),
+		(SELECT COUNT(*) FROM registrations r JOIN workshops w ON w.id = r.workshop_id
+			WHERE w.status NOT IN ('stopped', 'deleted') AND r.status NOT IN ('revoked', 'waitlisted'))`
+	if err := s.db.QueryRow(query).Scan(&stats.ActiveLearners, &stats.Registrations); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
 // -- Session Operations --
 
 func (s *PostgresStore) CreateSession(session *Session) error {
@@ -339,15 +597,41 @@ func (s *PostgresStore) GetVMPrivateKey(workshopID string) (string, error) {
 // -- Registration Operations --
 
 func (s *PostgresStore) CreateRegistration(r *Registration) error {
-	query := `INSERT INTO registrations (id, access_code, email, name, workshop_id, seat_id, status, created_at, joined_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
-	_, err := s.db.Exec(query, r.ID, r.AccessCode, r.Email, r.Name, r.WorkshopID, r.SeatID, r.Status, r.CreatedAt, r.JoinedAt)
+	query := `INSERT INTO registrations (id, access_code, email, name, workshop_id, seat_id, status, waitlist_position, created_at, joined_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	_, err := s.db.Exec(query, r.ID, r.AccessCode, r.Email, r.Name, r.WorkshopID, r.SeatID, r.Status, r.WaitlistPosition, r.CreatedAt, r.JoinedAt)
 	return err
 }
 
+func (s *PostgresStore) CreateRegistrations(registrations []*Registration) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO registrations (id, access_code, email, name, workshop_id, seat_id, status, waitlist_position, created_at, joined_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	for _, r := range registrations {
+		if _, err := tx.Exec(query, r.ID, r.AccessCode, r.Email, r.Name, r.WorkshopID, r.SeatID, r.Status, r.WaitlistPosition, r.CreatedAt, r.JoinedAt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
 func (s *PostgresStore) GetRegistration(accessCode string) (*Registration, error) {
 	r := &Registration{}
-	query := `SELECT id, access_code, email, name, workshop_id, seat_id, status, created_at, joined_at FROM registrations WHERE access_code = $1`
-	err := s.db.QueryRow(query, accessCode).Scan(&r.ID, &r.AccessCode, &r.Email, &r.Name, &r.WorkshopID, &r.SeatID, &r.Status, &r.CreatedAt, &r.JoinedAt)
+	query := `SELECT id, access_code, email, name, workshop_id, seat_id, status, waitlist_position, created_at, joined_at FROM registrations WHERE access_code = $1`
+	err := s.db.QueryRow(query, accessCode).Scan(&r.ID, &r.AccessCode, &r.Email, &r.Name, &r.WorkshopID, &r.SeatID, &r.Status, &r.WaitlistPosition, &r.CreatedAt, &r.JoinedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return r, err
+}
+
+func (s *PostgresStore) GetRegistrationByID(id string) (*Registration, error) {
+	r := &Registration{}
+	query := `SELECT id, access_code, email, name, workshop_id, seat_id, status, waitlist_position, created_at, joined_at FROM registrations WHERE id = $1`
+	err := s.db.QueryRow(query, id).Scan(&r.ID, &r.AccessCode, &r.Email, &r.Name, &r.WorkshopID, &r.SeatID, &r.Status, &r.WaitlistPosition, &r.CreatedAt, &r.JoinedAt)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -356,8 +640,8 @@ func (s *PostgresStore) GetRegistration(accessCode string) (*Registration, error
 
 func (s *PostgresStore) GetRegistrationByEmail(workshopID, email string) (*Registration, error) {
 	r := &Registration{}
-	query := `SELECT id, access_code, email, name, workshop_id, seat_id, status, created_at, joined_at FROM registrations WHERE workshop_id = $1 AND email = $2`
-	err := s.db.QueryRow(query, workshopID, email).Scan(&r.ID, &r.AccessCode, &r.Email, &r.Name, &r.WorkshopID, &r.SeatID, &r.Status, &r.CreatedAt, &r.JoinedAt)
+	query := `SELECT id, access_code, email, name, workshop_id, seat_id, status, waitlist_position, created_at, joined_at FROM registrations WHERE workshop_id = $1 AND email = $2`
+	err := s.db.QueryRow(query, workshopID, email).Scan(&r.ID, &r.AccessCode, &r.Email, &r.Name, &r.WorkshopID, &r.SeatID, &r.Status, &r.WaitlistPosition, &r.CreatedAt, &r.JoinedAt)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -365,14 +649,603 @@ func (s *PostgresStore) GetRegistrationByEmail(workshopID, email string) (*Regis
 }
 
 func (s *PostgresStore) UpdateRegistration(r *Registration) error {
-	query := `UPDATE registrations SET seat_id = $1, status = $2, joined_at = $3 WHERE id = $4`
-	_, err := s.db.Exec(query, r.SeatID, r.Status, r.JoinedAt, r.ID)
+	query := `UPDATE registrations SET seat_id = $1, status = $2, waitlist_position = $3, joined_at = $4 WHERE id = $5`
+	_, err := s.db.Exec(query, r.SeatID, r.Status, r.WaitlistPosition, r.JoinedAt, r.ID)
+	return err
+}
+
+func (s *PostgresStore) UpdateRegistrationAccessCode(id, accessCode string) error {
+	_, err := s.db.Exec(`UPDATE registrations SET access_code = $1 WHERE id = $2`, accessCode, id)
 	return err
 }
 
 func (s *PostgresStore) CountRegistrations(workshopID string) (int, error) {
 	var count int
-	query := `SELECT COUNT(*) FROM registrations WHERE workshop_id = $1`
+	query := `SELECT COUNT(*) FROM registrations WHERE workshop_id = $1 AND status NOT IN ('revoked', 'waitlisted')`
 	err := s.db.QueryRow(query, workshopID).Scan(&count)
 	return count, err
 }
+
+func (s *PostgresStore) ListRegistrations(workshopID string) ([]*Registration, error) {
+	query := `SELECT id, access_code, email, name, workshop_id, seat_id, status, waitlist_position, created_at, joined_at FROM registrations WHERE workshop_id = $1 ORDER BY created_at`
+	rows, err := s.db.Query(query, workshopID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var registrations []*Registration
+	for rows.Next() {
+		r := &Registration{}
+		if err := rows.Scan(&r.ID, &r.AccessCode, &r.Email, &r.Name, &r.WorkshopID, &r.SeatID, &r.Status, &r.WaitlistPosition, &r.CreatedAt, &r.JoinedAt); err != nil {
+			return nil, err
+		}
+		registrations = append(registrations, r)
+	}
+	return registrations, rows.Err()
+}
+
+func (s *PostgresStore) ReorderWaitlist(workshopID string, registrationIDs []string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE registrations SET waitlist_position = $1 WHERE id = $2 AND workshop_id = $3 AND status = 'waitlisted'`
+	for i, id := range registrationIDs {
+		res, err := tx.Exec(query, i+1, id, workshopID)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return ErrNotWaitlisted
+		}
+	}
+	return tx.Commit()
+}
+
+// -- Workshop Member Operations --
+
+func (s *PostgresStore) AddWorkshopMember(m *WorkshopMember) error {
+	query := `INSERT INTO workshop_members (workshop_id, user_id, role, added_by, created_at) VALUES ($1, $2, $3, $4, $5)`
+	_, err := s.db.Exec(query, m.WorkshopID, m.UserID, m.Role, m.AddedBy, m.CreatedAt)
+	return err
+}
+
+func (s *PostgresStore) GetWorkshopMember(workshopID, userID string) (*WorkshopMember, error) {
+	m := &WorkshopMember{}
+	query := `SELECT workshop_id, user_id, role, COALESCE(added_by, ''), created_at FROM workshop_members WHERE workshop_id = $1 AND user_id = $2`
+	err := s.db.QueryRow(query, workshopID, userID).Scan(&m.WorkshopID, &m.UserID, &m.Role, &m.AddedBy, &m.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return m, err
+}
+
+func (s *PostgresStore) ListWorkshopMembers(workshopID string) ([]*WorkshopMember, error) {
+	query := `SELECT workshop_id, user_id, role, COALESCE(added_by, ''), created_at FROM workshop_members WHERE workshop_id = $1 ORDER BY created_at`
+	rows, err := s.db.Query(query, workshopID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []*WorkshopMember
+	for rows.Next() {
+		m := &WorkshopMember{}
+		if err := rows.Scan(&m.WorkshopID, &m.UserID, &m.Role, &m.AddedBy, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+func (s *PostgresStore) RemoveWorkshopMember(workshopID, userID string) error {
+	_, err := s.db.Exec(`DELETE FROM workshop_members WHERE workshop_id = $1 AND user_id = $2`, workshopID, userID)
+	return err
+}
+
+func (s *PostgresStore) ListWorkshopsByMember(userID string) ([]*Workshop, error) {
+	query := `SELECT w.id, w.name, w.code, w.seats, w.api_key, w.runtime_type, w.status, w.waitlist, w.starts_at, COALESCE(w.owner_id, ''), w.created_at
+		FROM workshops w JOIN workshop_members m ON m.workshop_id = w.id
+		WHERE m.user_id = $1 ORDER BY w.created_at DESC`
+	rows, err := s.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var workshops []*Workshop
+	for rows.Next() {
+		w := &Workshop{}
+		if err := rows.Scan(&w.ID, &w.Name, &w.Code, &w.Seats, &w.ApiKey, &w.RuntimeType, &w.Status, &w.Waitlist, &w.StartsAt, &w.OwnerID, &w.CreatedAt); err != nil {
+			return nil, err
+		}
+		workshops = append(workshops, w)
+	}
+	return workshops, rows.Err()
+}
+
+// -- Workshop Event Operations --
+
+func (s *PostgresStore) CreateWorkshopEvent(e *WorkshopEvent) error {
+	query := `INSERT INTO workshop_events (workshop_id, type, seat_id, message, created_at) VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	return s.db.QueryRow(query, e.WorkshopID, e.Type, e.SeatID, e.Message, e.CreatedAt).Scan(&e.ID)
+}
+
+func (s *PostgresStore) ListWorkshopEvents(workshopID string) ([]*WorkshopEvent, error) {
+	query := `SELECT id, workshop_id, type, seat_id, message, created_at FROM workshop_events WHERE workshop_id = $1 ORDER BY id`
+	rows, err := s.db.Query(query, workshopID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*WorkshopEvent
+	for rows.Next() {
+		e := &WorkshopEvent{}
+		if err := rows.Scan(&e.ID, &e.WorkshopID, &e.Type, &e.SeatID, &e.Message, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// -- Terminal Recording Operations --
+
+func (s *PostgresStore) CreateTerminalRecording(r *TerminalRecording) error {
+	query := `INSERT INTO terminal_recordings (workshop_id, seat_id, name, learner_name, size_bytes, started_at, data, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) ON CONFLICT (workshop_id, name) DO NOTHING RETURNING id`
+	err := s.db.QueryRow(query, r.WorkshopID, r.SeatID, r.Name, r.LearnerName, r.SizeBytes, r.StartedAt, r.Data, r.CreatedAt).Scan(&r.ID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	return err
+}
+
+func (s *PostgresStore) ListTerminalRecordings(workshopID string) ([]*TerminalRecording, error) {
+	query := `SELECT id, workshop_id, seat_id, name, learner_name, size_bytes, started_at, created_at FROM terminal_recordings WHERE workshop_id = $1 ORDER BY started_at, id`
+	rows, err := s.db.Query(query, workshopID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recordings []*TerminalRecording
+	for rows.Next() {
+		r := &TerminalRecording{}
+		if err := rows.Scan(&r.ID, &r.WorkshopID, &r.SeatID, &r.Name, &r.LearnerName, &r.SizeBytes, &r.StartedAt, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		recordings = append(recordings, r)
+	}
+	return recordings, rows.Err()
+}
+
+func (s *PostgresStore) GetTerminalRecording(id int64) (*TerminalRecording, error) {
+	r := &TerminalRecording{}
+	query := `SELECT id, workshop_id, seat_id, name, learner_name, size_bytes, started_at, data, created_at FROM terminal_recordings WHERE id = $1`
+	err := s.db.QueryRow(query, id).Scan(&r.ID, &r.WorkshopID, &r.SeatID, &r.Name, &r.LearnerName, &r.SizeBytes, &r.StartedAt, &r.Data, &r.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return r, err
+}
+
+// -- Announcement Operations --
+
+func (s *PostgresStore) CreateAnnouncement(a *Announcement) error {
+	query := `INSERT INTO announcements (workshop_id, message, author_id, author_name, created_at) VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	return s.db.QueryRow(query, a.WorkshopID, a.Message, a.AuthorID, a.AuthorName, a.CreatedAt).Scan(&a.ID)
+}
+
+func (s *PostgresStore) ListAnnouncements(workshopID string) ([]*Announcement, error) {
+	query := `SELECT id, workshop_id, message, COALESCE(author_id, ''), author_name, created_at FROM announcements WHERE workshop_id = $1 ORDER BY id`
+	rows, err := s.db.Query(query, workshopID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var announcements []*Announcement
+	for rows.Next() {
+		a := &Announcement{}
+		if err := rows.Scan(&a.ID, &a.WorkshopID, &a.Message, &a.AuthorID, &a.AuthorName, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		announcements = append(announcements, a)
+	}
+	return announcements, rows.Err()
+}
+
+// -- Help Request Operations --
+
+func (s *PostgresStore) CreateHelpRequest(h *HelpRequest) error {
+	query := `INSERT INTO help_requests (workshop_id, registration_id, seat_id, learner_name, message, status, claimed_by_name, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+	return s.db.QueryRow(query, h.WorkshopID, h.RegistrationID, h.SeatID, h.LearnerName, h.Message, h.Status, h.ClaimedByName, h.CreatedAt).Scan(&h.ID)
+}
+
+func (s *PostgresStore) GetHelpRequest(id int64) (*HelpRequest, error) {
+	h := &HelpRequest{}
+	query := `SELECT id, workshop_id, registration_id, seat_id, learner_name, message, status, COALESCE(claimed_by, ''), claimed_by_name, created_at, claimed_at, resolved_at FROM help_requests WHERE id = $1`
+	err := s.db.QueryRow(query, id).Scan(&h.ID, &h.WorkshopID, &h.RegistrationID, &h.SeatID, &h.LearnerName, &h.Message, &h.Status, &h.ClaimedBy, &h.ClaimedByName, &h.CreatedAt, &h.ClaimedAt, &h.ResolvedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return h, err
+}
+
+func (s *PostgresStore) GetActiveHelpRequest(registrationID string) (*HelpRequest, error) {
+	h := &HelpRequest{}
+	query := `SELECT id, workshop_id, registration_id, seat_id, learner_name, message, status, COALESCE(claimed_by, ''), claimed_by_name, created_at, claimed_at, resolved_at FROM help_requests WHERE registration_id = $1 AND status <> 'resolved' ORDER BY id DESC LIMIT 1`
+	err := s.db.QueryRow(query, registrationID).Scan(&h.ID, &h.WorkshopID, &h.RegistrationID, &h.SeatID, &h.LearnerName, &h.Message, &h.Status, &h.ClaimedBy, &h.ClaimedByName, &h.CreatedAt, &h.ClaimedAt, &h.ResolvedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return h, err
+}
+
+func (s *PostgresStore) ListHelpRequests(workshopID string) ([]*HelpRequest, error) {
+	query := `SELECT id, workshop_id, registration_id, seat_id, learner_name, message, status, COALESCE(claimed_by, ''), claimed_by_name, created_at, claimed_at, resolved_at FROM help_requests WHERE workshop_id = $1 ORDER BY id`
+	rows, err := s.db.Query(query, workshopID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var requests []*HelpRequest
+	for rows.Next() {
+		h := &HelpRequest{}
+		if err := rows.Scan(&h.ID, &h.WorkshopID, &h.RegistrationID, &h.SeatID, &h.LearnerName, &h.Message, &h.Status, &h.ClaimedBy, &h.ClaimedByName, &h.CreatedAt, &h.ClaimedAt, &h.ResolvedAt); err != nil {
+			return nil, err
+		}
+		requests = append(requests, h)
+	}
+	return requests, rows.Err()
+}
+
+func (s *PostgresStore) ClaimHelpRequest(id int64, userID, userName string, at time.Time) (bool, error) {
+	query := `UPDATE help_requests SET status = 'claimed', claimed_by = $1, claimed_by_name = $2, claimed_at = $3 WHERE id = $4 AND status = 'open'`
+	res, err := s.db.Exec(query, userID, userName, at, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (s *PostgresStore) ResolveHelpRequest(id int64, at time.Time) (bool, error) {
+	query := `UPDATE help_requests SET status = 'resolved', resolved_at = $1 WHERE id = $2 AND status <> 'resolved'`
+	res, err := s.db.Exec(query, at, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// -- Notification Operations --
+
+func (s *PostgresStore) SetNotificationTemplate(t *NotificationTemplate) error {
+	query := `INSERT INTO notification_templates (workshop_id, kind, subject, body, updated_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (workshop_id, kind) DO UPDATE SET subject = EXCLUDED.subject, body = EXCLUDED.body, updated_at = EXCLUDED.updated_at`
+	_, err := s.db.Exec(query, t.WorkshopID, t.Kind, t.Subject, t.Body, t.UpdatedAt)
+	return err
+}
+
+func (s *PostgresStore) GetNotificationTemplate(workshopID, kind string) (*NotificationTemplate, error) {
+	t := &NotificationTemplate{}
+	query := `SELECT workshop_id, kind, subject, body, updated_at FROM notification_templates WHERE workshop_id = $1 AND kind = $2`
+	err := s.db.QueryRow(query, workshopID, kind).Scan(&t.WorkshopID, &t.Kind, &t.Subject, &t.Body, &t.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return t, err
+}
+
+func (s *PostgresStore) ListNotificationTemplates(workshopID string) ([]*NotificationTemplate, error) {
+	query := `SELECT workshop_id, kind, subject, body, updated_at FROM notification_templates WHERE workshop_id = $1 ORDER BY kind`
+	rows, err := s.db.Query(query, workshopID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []*NotificationTemplate
+	for rows.Next() {
+		t := &NotificationTemplate{}
+		if err := rows.Scan(&t.WorkshopID, &t.Kind, &t.Subject, &t.Body, &t.UpdatedAt); err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, rows.Err()
+}
+
+func (s *PostgresStore) DeleteNotificationTemplate(workshopID, kind string) error {
+	_, err := s.db.Exec(`DELETE FROM notification_templates WHERE workshop_id = $1 AND kind = $2`, workshopID, kind)
+	return err
+}
+
+func (s *PostgresStore) ClaimNotification(n *Notification) (bool, error) {
+	query := `INSERT INTO notifications (workshop_id, registration_id, kind, recipient, status, error, created_at) VALUES ($1, $2, $3, $4, 'pending', '', $5)
+		ON CONFLICT (registration_id, kind) DO UPDATE SET recipient = EXCLUDED.recipient, status = 'pending', error = '', created_at = EXCLUDED.created_at
+		WHERE notifications.status = 'failed'`
+	res, err := s.db.Exec(query, n.WorkshopID, n.RegistrationID, n.Kind, n.Recipient, n.CreatedAt)
+	if err != nil {
+		return false, err
+	}
+	count, err := res.RowsAffected()
+	if err != nil || count == 0 {
+		return false, err
+	}
+	n.Status = NotificationPending
+	n.Error = ""
+	return true, nil
+}
+
+func (s *PostgresStore) UpdateNotification(n *Notification) error {
+	query := `UPDATE notifications SET status = $1, error = $2, sent_at = $3 WHERE registration_id = $4 AND kind = $5`
+	_, err := s.db.Exec(query, n.Status, n.Error, n.SentAt, n.RegistrationID, n.Kind)
+	return err
+}
+
+func (s *PostgresStore) ListNotifications(workshopID string) ([]*Notification, error) {
+	query := `SELECT workshop_id, registration_id, kind, recipient, status, error, created_at, sent_at FROM notifications WHERE workshop_id = $1 ORDER BY created_at`
+	rows, err := s.db.Query(query, workshopID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []*Notification
+	for rows.Next() {
+		n := &Notification{}
+		if err := rows.Scan(&n.WorkshopID, &n.RegistrationID, &n.Kind, &n.Recipient, &n.Status, &n.Error, &n.CreatedAt, &n.SentAt); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+// -- Login Link Operations --
+
+func (s *PostgresStore) CreateLoginLink(l *LoginLink) error {
+	query := `INSERT INTO login_links (id, registration_id, workshop_id, created_at, expires_at) VALUES ($1, $2, $3, $4, $5)`
+	_, err := s.db.Exec(query, l.ID, l.RegistrationID, l.WorkshopID, l.CreatedAt, l.ExpiresAt)
+	return err
+}
+
+func (s *PostgresStore) GetLatestLoginLink(registrationID string) (*LoginLink, error) {
+	query := `SELECT id, registration_id, workshop_id, created_at, expires_at, used_at FROM login_links WHERE registration_id = $1 ORDER BY created_at DESC LIMIT 1`
+	l := &LoginLink{}
+	err := s.db.QueryRow(query, registrationID).Scan(&l.ID, &l.RegistrationID, &l.WorkshopID, &l.CreatedAt, &l.ExpiresAt, &l.UsedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (s *PostgresStore) UseLoginLink(id, registrationID string, at time.Time) (bool, error) {
+	query := `UPDATE login_links SET used_at = $1 WHERE id = $2 AND registration_id = $3 AND used_at IS NULL AND expires_at > $4`
+	res, err := s.db.Exec(query, at, id, registrationID, at)
+	if err != nil {
+		return false, err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (s *PostgresStore) ExpireLoginLinks(registrationID string, at time.Time) error {
+	_, err := s.db.Exec(`UPDATE login_links SET used_at = $1 WHERE registration_id = $2 AND used_at IS NULL`, at, registrationID)
+	return err
+}
+
+// -- Webhook Operations --
+
+func (s *PostgresStore) CreateWebhook(h *Webhook) error {
+	query := `INSERT INTO webhooks (id, workshop_id, url, secret, events, created_by, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err := s.db.Exec(query, h.ID, h.WorkshopID, h.URL, h.Secret, strings.Join(h.Events, ","), h.CreatedBy, h.CreatedAt)
+	return err
+}
+
+func (s *PostgresStore) GetWebhook(id string) (*Webhook, error) {
+	query := `SELECT id, workshop_id, url, secret, events, created_by, created_at FROM webhooks WHERE id = $1`
+	h, err := scanWebhook(s.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return h, err
+}
+
+func (s *PostgresStore) ListWebhooks(workshopID string) ([]*Webhook, error) {
+	query := `SELECT id, workshop_id, url, secret, events, created_by, created_at FROM webhooks WHERE workshop_id = $1 ORDER BY created_at`
+	rows, err := s.db.Query(query, workshopID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []*Webhook
+	for rows.Next() {
+		h, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, h)
+	}
+	return webhooks, rows.Err()
+}
+
+func (s *PostgresStore) DeleteWebhook(id string) error {
+	_, err := s.db.Exec(`DELETE FROM webhook_deliveries WHERE webhook_id = $1`, id)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`DELETE FROM webhooks WHERE id = $1`, id)
+	return err
+}
+
+func (s *PostgresStore) CreateWebhookDelivery(d *WebhookDelivery) error {
+	query := `INSERT INTO webhook_deliveries (webhook_id, workshop_id, event, payload, status, attempts, next_attempt_at, last_status_code, last_error, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`
+	return s.db.QueryRow(query, d.WebhookID, d.WorkshopID, d.Event, d.Payload, d.Status, d.Attempts, d.NextAttemptAt, d.LastStatusCode, d.LastError, d.CreatedAt).Scan(&d.ID)
+}
+
+func (s *PostgresStore) GetWebhookDelivery(id int64) (*WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE id = $1`
+	d, err := scanWebhookDelivery(s.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return d, err
+}
+
+func (s *PostgresStore) ListDueWebhookDeliveries(now time.Time, limit int) ([]*WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE status = 'pending' AND next_attempt_at <= $1 ORDER BY next_attempt_at, id LIMIT $2`
+	return s.queryWebhookDeliveries(query, now, limit)
+}
+
+func (s *PostgresStore) ClaimWebhookDelivery(id int64, now, leaseUntil time.Time) (bool, error) {
+	query := `UPDATE webhook_deliveries SET next_attempt_at = $1 WHERE id = $2 AND status = 'pending' AND next_attempt_at <= $3`
+	res, err := s.db.Exec(query, leaseUntil, id, now)
+	if err != nil {
+		return false, err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (s *PostgresStore) UpdateWebhookDelivery(d *WebhookDelivery) error {
+	query := `UPDATE webhook_deliveries SET status = $1, attempts = $2, next_attempt_at = $3, last_status_code = $4, last_error = $5, delivered_at = $6 WHERE id = $7`
+	_, err := s.db.Exec(query, d.Status, d.Attempts, d.NextAttemptAt, d.LastStatusCode, d.LastError, d.DeliveredAt, d.ID)
+	return err
+}
+
+func (s *PostgresStore) ListWebhookDeliveries(webhookID string, limit int) ([]*WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE webhook_id = $1 ORDER BY id DESC LIMIT $2`
+	return s.queryWebhookDeliveries(query, webhookID, limit)
+}
+
+func (s *PostgresStore) queryWebhookDeliveries(query string, args ...interface{}) ([]*WebhookDelivery, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*WebhookDelivery
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// -- LTI Operations --
+
+func (s *PostgresStore) CreateLTIPlatform(p *LTIPlatform) error {
+	query := `INSERT INTO lti_platforms (` + ltiPlatformColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	_, err := s.db.Exec(query, p.ID, p.Name, p.Issuer, p.ClientID, p.AuthLoginURL, p.JWKSURL, strings.Join(p.DeploymentIDs, ","), p.TrustEmail, p.CreatedAt)
+	return err
+}
+
+func (s *PostgresStore) SetLTIPlatformTrustEmail(id string, trust bool) error {
+	_, err := s.db.Exec(`UPDATE lti_platforms SET trust_email = $1 WHERE id = $2`, trust, id)
+	return err
+}
+
+func (s *PostgresStore) GetLTIPlatform(id string) (*LTIPlatform, error) {
+	query := `SELECT ` + ltiPlatformColumns + ` FROM lti_platforms WHERE id = $1`
+	p, err := scanLTIPlatform(s.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return p, err
+}
+
+func (s *PostgresStore) ListLTIPlatforms() ([]*LTIPlatform, error) {
+	rows, err := s.db.Query(`SELECT ` + ltiPlatformColumns + ` FROM lti_platforms ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var platforms []*LTIPlatform
+	for rows.Next() {
+		p, err := scanLTIPlatform(rows)
+		if err != nil {
+			return nil, err
+		}
+		platforms = append(platforms, p)
+	}
+	return platforms, rows.Err()
+}
+
+func (s *PostgresStore) DeleteLTIPlatform(id string) error {
+	_, err := s.db.Exec(`DELETE FROM lti_contexts WHERE platform_id = $1`, id)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`DELETE FROM lti_links WHERE platform_id = $1`, id)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`DELETE FROM lti_platforms WHERE id = $1`, id)
+	return err
+}
+
+func (s *PostgresStore) SetLTIContext(c *LTIContext) error {
+	query := `INSERT INTO lti_contexts (platform_id, context_id, workshop_id, title, updated_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (platform_id, context_id) DO UPDATE SET workshop_id = EXCLUDED.workshop_id, title = EXCLUDED.title, updated_at = EXCLUDED.updated_at`
+	_, err := s.db.Exec(query, c.PlatformID, c.ContextID, c.WorkshopID, c.Title, c.UpdatedAt)
+	return err
+}
+
+func (s *PostgresStore) GetLTIContext(platformID, contextID string) (*LTIContext, error) {
+	c := &LTIContext{}
+	query := `SELECT platform_id, context_id, workshop_id, title, updated_at FROM lti_contexts WHERE platform_id = $1 AND context_id = $2`
+	err := s.db.QueryRow(query, platformID, contextID).Scan(&c.PlatformID, &c.ContextID, &c.WorkshopID, &c.Title, &c.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *PostgresStore) AddLTILink(l *LTILink) error {
+	query := `INSERT INTO lti_links (platform_id, context_id, workshop_id, created_at) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING`
+	_, err := s.db.Exec(query, l.PlatformID, l.ContextID, l.WorkshopID, l.CreatedAt)
+	return err
+}
+
+func (s *PostgresStore) GetLTILink(platformID, contextID, workshopID string) (*LTILink, error) {
+	l := &LTILink{}
+	query := `SELECT platform_id, context_id, workshop_id, created_at FROM lti_links WHERE platform_id = $1 AND context_id = $2 AND workshop_id = $3`
+	err := s.db.QueryRow(query, platformID, contextID, workshopID).Scan(&l.PlatformID, &l.ContextID, &l.WorkshopID, &l.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
@@ -2,16 +2,23 @@ package store
 
 import (
 	"database/sql"
+	"strings"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
 type SQLiteStore struct {
-	db *sql.DB
+	db *observedDB
 }
 
 func NewSQLiteStore(db *sql.DB) *SQLiteStore {
-	return &SQLiteStore{db: db}
+	return &SQLiteStore{db: &observedDB{DB: db}}
+}
+
+// SetQueryObserver registers a callback that receives the latency of every query.
+func (s *SQLiteStore) SetQueryObserver(fn QueryObserver) {
+	s.db.observe = fn
 }
 
 const schema = `
@@ -21,6 +28,7 @@ CREATE TABLE IF NOT EXISTS users (
 	password_hash TEXT NOT NULL,
 	name TEXT NOT NULL,
 	role TEXT NOT NULL DEFAULT 'instructor',
+	disabled BOOLEAN NOT NULL DEFAULT 0,
 	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 );
 
@@ -34,6 +42,8 @@ CREATE TABLE IF NOT EXISTS workshops (
 	api_key TEXT NOT NULL,
 	runtime_type TEXT NOT NULL DEFAULT 'docker',
 	status TEXT NOT NULL,
+	waitlist BOOLEAN NOT NULL DEFAULT 0,
+	starts_at DATETIME,
 	owner_id TEXT,
 	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 	FOREIGN KEY(owner_id) REFERENCES users(id)
@@ -86,6 +96,7 @@ CREATE TABLE IF NOT EXISTS registrations (
 	workshop_id TEXT NOT NULL,
 	seat_id INTEGER,
 	status TEXT NOT NULL DEFAULT 'registered',
+	waitlist_position INTEGER NOT NULL DEFAULT 0,
 	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 	joined_at DATETIME,
 	FOREIGN KEY(workshop_id) REFERENCES workshops(id),
@@ -94,6 +105,216 @@ CREATE TABLE IF NOT EXISTS registrations (
 
 CREATE INDEX IF NOT EXISTS idx_registrations_access_code ON registrations(access_code);
 CREATE INDEX IF NOT EXISTS idx_registrations_workshop_id ON registrations(workshop_id);
+
+CREATE TABLE IF NOT EXISTS workshop_events (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	workshop_id TEXT NOT NULL,
+	type TEXT NOT NULL,
+	seat_id INTEGER,
+	message TEXT NOT NULL DEFAULT '',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY(workshop_id) REFERENCES workshops(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_workshop_events_workshop_id ON workshop_events(workshop_id);
+
+CREATE TABLE IF NOT EXISTS workshop_members (
+	workshop_id TEXT NOT NULL,
+	user_id TEXT NOT NULL,
+	role TEXT NOT NULL,
+	added_by TEXT,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (workshop_id, user_id),
+	FOREIGN KEY(workshop_id) REFERENCES workshops(id),
+	FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_workshop_members_user_id ON workshop_members(user_id);
+
+CREATE TABLE IF NOT EXISTS terminal_recordings (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	workshop_id TEXT NOT NULL,
+	seat_id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	learner_name TEXT NOT NULL DEFAULT '',
+	size_bytes INTEGER NOT NULL DEFAULT 0,
+	started_at DATETIME NOT NULL,
+	data BLOB,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE(workshop_id, name),
+	FOREIGN KEY(workshop_id) REFERENCES workshops(id)
+);
+
+CREATE TABLE IF NOT EXISTS announcements (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	workshop_id TEXT NOT NULL,
+	message TEXT NOT NULL,
+	author_id TEXT,
+	author_name TEXT NOT NULL DEFAULT '',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY(workshop_id) REFERENCES workshops(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_announcements_workshop_id ON announcements(workshop_id);
+
+CREATE TABLE IF NOT EXISTS help_requests (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	workshop_id TEXT NOT NULL,
+	registration_id TEXT NOT NULL,
+	seat_id INTEGER NOT NULL,
+	learner_name TEXT NOT NULL DEFAULT '',
+	message TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL DEFAULT 'open',
+	claimed_by TEXT,
+	claimed_by_name TEXT NOT NULL DEFAULT '',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	claimed_at DATETIME,
+	resolved_at DATETIME,
+	FOREIGN KEY(workshop_id) REFERENCES workshops(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_help_requests_workshop_id ON help_requests(workshop_id);
+CREATE INDEX IF NOT EXISTS idx_help_requests_registration_id ON help_requests(registration_id);
+
+CREATE TABLE IF NOT EXISTS notification_templates (
+	workshop_id TEXT NOT NULL,
+	kind TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	body TEXT NOT NULL,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY(workshop_id, kind),
+	FOREIGN KEY(workshop_id) REFERENCES workshops(id)
+);
+
+CREATE TABLE IF NOT EXISTS notifications (
+	workshop_id TEXT NOT NULL,
+	registration_id TEXT NOT NULL,
+	kind TEXT NOT NULL,
+	recipient TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'pending',
+	error TEXT NOT NULL DEFAULT '',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	sent_at DATETIME,
+	PRIMARY KEY(registration_id, kind),
+	FOREIGN KEY(workshop_id) REFERENCES workshops(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_workshop_id ON notifications(workshop_id);
+
+CREATE TABLE IF NOT EXISTS login_links (
+	id TEXT PRIMARY KEY,
+	registration_id TEXT NOT NULL,
+	workshop_id TEXT NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	expires_at DATETIME NOT NULL,
+	used_at DATETIME,
+	FOREIGN KEY(workshop_id) REFERENCES workshops(id),
+	FOREIGN KEY(registration_id) REFERENCES registrations(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_links_registration_id ON login_links(registration_id);
+
+CREATE TABLE IF NOT EXISTS webhooks (
+	id TEXT PRIMARY KEY,
+	workshop_id TEXT NOT NULL DEFAULT '',
+	url TEXT NOT NULL,
+	secret TEXT NOT NULL,
+	events TEXT NOT NULL,
+	created_by TEXT NOT NULL DEFAULT '',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_workshop_id ON webhooks(workshop_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	webhook_id TEXT NOT NULL,
+	workshop_id TEXT NOT NULL,
+	event TEXT NOT NULL,
+	payload TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'pending',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	next_attempt_at DATETIME NOT NULL,
+	last_status_code INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL DEFAULT '',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	delivered_at DATETIME,
+	FOREIGN KEY(webhook_id) REFERENCES webhooks(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(status, next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS lti_platforms (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	issuer TEXT NOT NULL,
+	client_id TEXT NOT NULL,
+	auth_login_url TEXT NOT NULL,
+	jwks_url TEXT NOT NULL,
+	deployment_ids TEXT NOT NULL DEFAULT '',
+	trust_email BOOLEAN NOT NULL DEFAULT 0,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE(issuer, client_id)
+);
+
+CREATE TABLE IF NOT EXISTS lti_contexts (
+	platform_id TEXT NOT NULL,
+	context_id TEXT NOT NULL,
+	workshop_id TEXT NOT NULL,
+	title TEXT NOT NULL DEFAULT '',
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (platform_id, context_id),
+	FOREIGN KEY(platform_id) REFERENCES lti_platforms(id),
+	FOREIGN KEY(workshop_id) REFERENCES workshops(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_lti_contexts_workshop_id ON lti_contexts(workshop_id);
+
+CREATE TABLE IF NOT EXISTS lti_links (
+	platform_id TEXT NOT NULL,
+	context_id TEXT NOT NULL,
+	workshop_id TEXT NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (platform_id, context_id, workshop_id),
+	FOREIGN KEY(platform_id) REFERENCES lti_platforms(id),
+	FOREIGN KEY(workshop_id) REFERENCES workshops(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_lti_links_workshop_id ON lti_links(workshop_id);
+
+CREATE TABLE IF NOT EXISTS user_identities (
+	provider TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	user_id TEXT NOT NULL,
+	email TEXT NOT NULL DEFAULT '',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (provider, subject),
+	UNIQUE(user_id, provider),
+	FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+	id TEXT PRIMARY KEY,
+	session_id TEXT NOT NULL,
+	user_id TEXT NOT NULL,
+	access_token_id TEXT NOT NULL,
+	access_expires_at DATETIME NOT NULL,
+	expires_at DATETIME NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	used_at DATETIME,
+	revoked_at DATETIME,
+	FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session_id ON refresh_tokens(session_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+	token_id TEXT PRIMARY KEY,
+	expires_at DATETIME NOT NULL,
+	revoked_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
 `
 
 // InitDB initializes a SQLite database (for testing/local development)
@@ -120,8 +341,8 @@ func (s *SQLiteStore) CreateUser(u *User) error {
 
 func (s *SQLiteStore) GetUser(id string) (*User, error) {
 	u := &User{}
-	query := `SELECT id, email, password_hash, name, role, created_at FROM users WHERE id = ?`
-	err := s.db.QueryRow(query, id).Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Name, &u.Role, &u.CreatedAt)
+	query := `SELECT id, email, password_hash, name, role, disabled, created_at FROM users WHERE id = ?`
+	err := s.db.QueryRow(query, id).Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Name, &u.Role, &u.Disabled, &u.CreatedAt)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -130,8 +351,8 @@ func (s *SQLiteStore) GetUser(id string) (*User, error) {
 
 func (s *SQLiteStore) GetUserByEmail(email string) (*User, error) {
 	u := &User{}
-	query := `SELECT id, email, password_hash, name, role, created_at FROM users WHERE email = ?`
-	err := s.db.QueryRow(query, email).Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Name, &u.Role, &u.CreatedAt)
+	query := `SELECT id, email, password_hash, name, role, disabled, created_at FROM users WHERE email = ?`
+	err := s.db.QueryRow(query, email).Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Name, &u.Role, &u.Disabled, &u.CreatedAt)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -139,7 +360,7 @@ func (s *SQLiteStore) GetUserByEmail(email string) (*User, error) {
 }
 
 func (s *SQLiteStore) ListUsers() ([]*User, error) {
-	query := `SELECT id, email, password_hash, name, role, created_at FROM users ORDER BY created_at DESC`
+	query := `SELECT id, email, password_hash, name, role, disabled, created_at FROM users ORDER BY created_at DESC`
 	rows, err := s.db.Query(query)
 	if err != nil {
 		return nil, err
@@ -149,7 +370,7 @@ func (s *SQLiteStore) ListUsers() ([]*User, error) {
 	var users []*User
 	for rows.Next() {
 		u := &User{}
-		if err := rows.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Name, &u.Role, &u.CreatedAt); err != nil {
+		if err := rows.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Name, &u.Role, &u.Disabled, &u.CreatedAt); err != nil {
 			return nil, err
 		}
 		users = append(users, u)
@@ -157,18 +378,146 @@ func (s *SQLiteStore) ListUsers() ([]*User, error) {
 	return users, nil
 }
 
+func (s *SQLiteStore) UpdateUserRole(id, role string) error {
+	_, err := s.db.Exec(`UPDATE users SET role = ? WHERE id = ?`, role, id)
+	return err
+}
+
+func (s *SQLiteStore) SetUserDisabled(id string, disabled bool) error {
+	_, err := s.db.Exec(`UPDATE users SET disabled = ? WHERE id = ?`, disabled, id)
+	return err
+}
+
+// -- Identity Operations --
+
+func (s *SQLiteStore) CreateUserIdentity(i *UserIdentity) error {
+	query := `INSERT INTO user_identities (provider, subject, user_id, email, created_at) VALUES (?, ?, ?, ?, ?)`
+	_, err := s.db.Exec(query, i.Provider, i.Subject, i.UserID, i.Email, i.CreatedAt)
+	return err
+}
+
+func (s *SQLiteStore) GetUserIdentity(provider, subject string) (*UserIdentity, error) {
+	i := &UserIdentity{}
+	query := `SELECT provider, subject, user_id, email, created_at FROM user_identities WHERE provider = ? AND subject = ?`
+	err := s.db.QueryRow(query, provider, subject).Scan(&i.Provider, &i.Subject, &i.UserID, &i.Email, &i.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+func (s *SQLiteStore) ListUserIdentities(userID string) ([]*UserIdentity, error) {
+	query := `SELECT provider, subject, user_id, email, created_at FROM user_identities WHERE user_id = ? ORDER BY created_at`
+	rows, err := s.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var identities []*UserIdentity
+	for rows.Next() {
+		i := &UserIdentity{}
+		if err := rows.Scan(&i.Provider, &i.Subject, &i.UserID, &i.Email, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		identities = append(identities, i)
+	}
+	return identities, rows.Err()
+}
+
+func (s *SQLiteStore) DeleteUserIdentity(userID, provider string) error {
+	_, err := s.db.Exec(`DELETE FROM user_identities WHERE user_id = ? AND provider = ?`, userID, provider)
+	return err
+}
+
+// -- Token Operations --
+
+func (s *SQLiteStore) CreateRefreshToken(t *RefreshToken) error {
+	query := `INSERT INTO refresh_tokens (id, session_id, user_id, access_token_id, access_expires_at, expires_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	_, err := s.db.Exec(query, t.ID, t.SessionID, t.UserID, t.AccessTokenID, t.AccessExpiresAt, t.ExpiresAt, t.CreatedAt)
+	return err
+}
+
+func (s *SQLiteStore) GetRefreshToken(id string) (*RefreshToken, error) {
+	t := &RefreshToken{}
+	query := `SELECT id, session_id, user_id, access_token_id, access_expires_at, expires_at, created_at, used_at, revoked_at FROM refresh_tokens WHERE id = ?`
+	err := s.db.QueryRow(query, id).Scan(&t.ID, &t.SessionID, &t.UserID, &t.AccessTokenID, &t.AccessExpiresAt, &t.ExpiresAt, &t.CreatedAt, &t.UsedAt, &t.RevokedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (s *SQLiteStore) UseRefreshToken(id string, now time.Time) (bool, error) {
+	query := `UPDATE refresh_tokens SET used_at = ? WHERE id = ? AND used_at IS NULL AND revoked_at IS NULL AND expires_at > ?`
+	result, err := s.db.Exec(query, now, id, now)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+func (s *SQLiteStore) RevokeSession(sessionID string, now time.Time) error {
+	return s.revokeSessions(`session_id = ?`, sessionID, now)
+}
+
+func (s *SQLiteStore) RevokeUserSessions(userID string, now time.Time) error {
+	return s.revokeSessions(`user_id = ?`, userID, now)
+}
+
+// revokeSessions revokes the refresh tokens matching where, whose second
+// parameter is arg, and the access tokens issued with them that have not
+// expired yet.
+func (s *SQLiteStore) revokeSessions(where, arg string, now time.Time) error {
+	query := `INSERT INTO revoked_tokens (token_id, expires_at, revoked_at)
+		SELECT access_token_id, access_expires_at, ? FROM refresh_tokens WHERE ` + where + ` AND access_expires_at > ?
+		ON CONFLICT (token_id) DO NOTHING`
+	if _, err := s.db.Exec(query, now, arg, now); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`UPDATE refresh_tokens SET revoked_at = ? WHERE `+where+` AND revoked_at IS NULL`, now, arg)
+	return err
+}
+
+func (s *SQLiteStore) RevokeToken(tokenID string, expiresAt, now time.Time) error {
+	query := `INSERT INTO revoked_tokens (token_id, expires_at, revoked_at) VALUES (?, ?, ?) ON CONFLICT (token_id) DO NOTHING`
+	_, err := s.db.Exec(query, tokenID, expiresAt, now)
+	return err
+}
+
+func (s *SQLiteStore) IsTokenRevoked(tokenID string) (bool, error) {
+	var n int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM revoked_tokens WHERE token_id = ?`, tokenID).Scan(&n)
+	return n > 0, err
+}
+
+func (s *SQLiteStore) DeleteExpiredTokens(now time.Time) error {
+	if _, err := s.db.Exec(`DELETE FROM refresh_tokens WHERE expires_at <= ?`, now); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`DELETE FROM revoked_tokens WHERE expires_at <= ?`, now)
+	return err
+}
+
 // -- Workshop Operations --
 
 func (s *SQLiteStore) CreateWorkshop(w *Workshop) error {
-	query := `INSERT INTO workshops (id, name, code, seats, api_key, runtime_type, status, owner_id, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
-	_, err := s.db.Exec(query, w.ID, w.Name, w.Code, w.Seats, w.ApiKey, w.RuntimeType, w.Status, w.OwnerID, w.CreatedAt)
+	query := `INSERT INTO workshops (id, name, code, seats, api_key, runtime_type, status, waitlist, starts_at, owner_id, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := s.db.Exec(query, w.ID, w.Name, w.Code, w.Seats, w.ApiKey, w.RuntimeType, w.Status, w.Waitlist, w.StartsAt, w.OwnerID, w.CreatedAt)
 	return err
 }
 
 func (s *SQLiteStore) GetWorkshop(id string) (*Workshop, error) {
 	w := &Workshop{}
-	query := `SELECT id, name, code, seats, api_key, runtime_type, status, COALESCE(owner_id, ''), created_at FROM workshops WHERE id = ?`
-	err := s.db.QueryRow(query, id).Scan(&w.ID, &w.Name, &w.Code, &w.Seats, &w.ApiKey, &w.RuntimeType, &w.Status, &w.OwnerID, &w.CreatedAt)
+	query := `SELECT id, name, code, seats, api_key, runtime_type, status, waitlist, starts_at, COALESCE(owner_id, ''), created_at FROM workshops WHERE id = ?`
+	err := s.db.QueryRow(query, id).Scan(&w.ID, &w.Name, &w.Code, &w.Seats, &w.ApiKey, &w.RuntimeType, &w.Status, &w.Waitlist, &w.StartsAt, &w.OwnerID, &w.CreatedAt)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -177,8 +526,8 @@ func (s *SQLiteStore) GetWorkshop(id string) (*Workshop, error) {
 
 func (s *SQLiteStore) GetWorkshopByCode(code string) (*Workshop, error) {
 	w := &Workshop{}
-	query := `SELECT id, name, code, seats, api_key, runtime_type, status, COALESCE(owner_id, ''), created_at FROM workshops WHERE code = ?`
-	err := s.db.QueryRow(query, code).Scan(&w.ID, &w.Name, &w.Code, &w.Seats, &w.ApiKey, &w.RuntimeType, &w.Status, &w.OwnerID, &w.CreatedAt)
+	query := `SELECT id, name, code, seats, api_key, runtime_type, status, waitlist, starts_at, COALESCE(owner_id, ''), created_at FROM workshops WHERE code = ?`
+	err := s.db.QueryRow(query, code).Scan(&w.ID, &w.Name, &w.Code, &w.Seats, &w.ApiKey, &w.RuntimeType, &w.Status, &w.Waitlist, &w.StartsAt, &w.OwnerID, &w.CreatedAt)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -186,7 +535,7 @@ func (s *SQLiteStore) GetWorkshopByCode(code string) (*Workshop, error) {
 }
 
 func (s *SQLiteStore) ListWorkshops() ([]*Workshop, error) {
-	query := `SELECT id, name, code, seats, api_key, runtime_type, status, COALESCE(owner_id, ''), created_at FROM workshops ORDER BY created_at DESC`
+	query := `SELECT id, name, code, seats, api_key, runtime_type, status, waitlist, starts_at, COALESCE(owner_id, ''), created_at FROM workshops ORDER BY created_at DESC`
 	rows, err := s.db.Query(query)
 	if err != nil {
 		return nil, err
@@ -196,7 +545,7 @@ func (s *SQLiteStore) ListWorkshops() ([]*Workshop, error) {
 	var workshops []*Workshop
 	for rows.Next() {
 		w := &Workshop{}
-		if err := rows.Scan(&w.ID, &w.Name, &w.Code, &w.Seats, &w.ApiKey, &w.RuntimeType, &w.Status, &w.OwnerID, &w.CreatedAt); err != nil {
+		if err := rows.Scan(&w.ID, &w.Name, &w.Code, &w.Seats, &w.ApiKey, &w.RuntimeType, &w.Status, &w.Waitlist, &w.StartsAt, &w.OwnerID, &w.CreatedAt); err != nil {
 			return nil, err
 		}
 		workshops = append(workshops, w)
@@ -205,7 +554,7 @@ func (s *SQLiteStore) ListWorkshops() ([]*Workshop, error) {
 }
 
 func (s *SQLiteStore) ListWorkshopsByOwner(ownerID string) ([]*Workshop, error) {
-	query := `SELECT id, name, code, seats, api_key, runtime_type, status, COALESCE(owner_id, ''), created_at FROM workshops WHERE owner_id = ? ORDER BY created_at DESC`
+	query := `SELECT id, name, code, seats, api_key, runtime_type, status, waitlist, starts_at, COALESCE(owner_id, ''), created_at FROM workshops WHERE owner_id = ? ORDER BY created_at DESC`
 	rows, err := s.db.Query(query, ownerID)
 	if err != nil {
 		return nil, err
@@ -215,7 +564,7 @@ func (s *SQLiteStore) ListWorkshopsByOwner(ownerID string) ([]*Workshop, error)
 	var workshops []*Workshop
 	for rows.Next() {
 		w := &Workshop{}
-		if err := rows.Scan(&w.ID, &w.Name, &w.Code, &w.Seats, &w.ApiKey, &w.RuntimeType, &w.Status, &w.OwnerID, &w.CreatedAt); err != nil {
+		if err := rows.Scan(&w.ID, &w.Name, &w.Code, &w.Seats, &w.ApiKey, &w.RuntimeType, &w.Status, &w.Waitlist, &w.StartsAt, &w.OwnerID, &w.CreatedAt); err != nil {
 			return nil, err
 		}
 		workshops = append(workshops, w)
@@ -229,15 +578,138 @@ func (s *SQLiteStore) UpdateWorkshopStatus(id string, status string) error {
 	return err
 }
 
+func (s *SQLiteStore) UpdateWorkshop(w *Workshop) error {
+	query := `UPDATE workshops SET name = ?, api_key = ?, runtime_type = ?, waitlist = ?, starts_at = ? WHERE id = ?`
+	_, err := s.db.Exec(query, w.Name, w.ApiKey, w.RuntimeType, w.Waitlist, w.StartsAt, w.ID)
+	return err
+}
+
+func (s *SQLiteStore) ResizeWorkshopSeats(workshopID string, seats int, added []*Session) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Refuse to drop seats that a learner has joined or been assigned
+	var occupied int
+	query := `SELECT (SELECT COUNT(*) FROM sessions WHERE workshop_id = ? AND seat_id > ? AND (name != '' OR status = 'occupied'))
+		+ (SELECT COUNT(*) FROM registrations WHERE workshop_id = ? AND seat_id > ?)`
+	if err := tx.QueryRow(query, workshopID, seats, workshopID, seats).Scan(&occupied); err != nil {
+		return err
+	}
+	if occupied > 0 {
+		return ErrSeatsOccupied
+	}
+
+	// Every active registration must keep a place
+	var registered int
+	query = `SELECT COUNT(*) FROM registrations WHERE workshop_id = ? AND status NOT IN ('revoked', 'waitlisted')`
+	if err := tx.QueryRow(query, workshopID).Scan(&registered); err != nil {
+		return err
+	}
+	if registered > seats {
+		return ErrTooManyRegistrations
+	}
+
+	if _, err := tx.Exec(`DELETE FROM sessions WHERE workshop_id = ? AND seat_id > ?`, workshopID, seats); err != nil {
+		return err
+	}
+	for _, session := range added {
+		query := `INSERT INTO sessions (odehash, workshop_id, seat_id, name, status, container_id, ip, joined_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+		if _, err := tx.Exec(query, session.OdeHash, session.WorkshopID, session.SeatID, session.Name, session.Status, session.ContainerID, session.IP, session.JoinedAt); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`UPDATE workshops SET seats = ? WHERE id = ?`, seats, workshopID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 func (s *SQLiteStore) DeleteWorkshop(id string) error {
 	_, err := s.db.Exec(`DELETE FROM sessions WHERE workshop_id = ?`, id)
 	if err != nil {
 		return err
 	}
+	_, err = s.db.Exec(`DELETE FROM workshop_members WHERE workshop_id = ?`, id)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`DELETE FROM terminal_recordings WHERE workshop_id = ?`, id)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`DELETE FROM announcements WHERE workshop_id = ?`, id)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`DELETE FROM help_requests WHERE workshop_id = ?`, id)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`DELETE FROM notification_templates WHERE workshop_id = ?`, id)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`DELETE FROM notifications WHERE workshop_id = ?`, id)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`DELETE FROM login_links WHERE workshop_id = ?`, id)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`DELETE FROM webhook_deliveries WHERE webhook_id IN (SELECT id FROM webhooks WHERE workshop_id = ?)`, id)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`DELETE FROM webhooks WHERE workshop_id = ?`, id)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`DELETE FROM lti_contexts WHERE workshop_id = ?`, id)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`DELETE FROM lti_links WHERE workshop_id = ?`, id)
+	if err != nil {
+		return err
+	}
 	_, err = s.db.Exec(`DELETE FROM workshops WHERE id = ?`, id)
 	return err
 }
 
+func (s *SQLiteStore) GetWorkshopStats() (*WorkshopStats, error) {
+	stats := &WorkshopStats{ByStatus: make(map[string]int)}
+	rows, err := s.db.Query(`SELECT status, COUNT(*) FROM workshops GROUP BY status`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		stats.ByStatus[status] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT
+		(SELECT COUNT(*) FROM sessions s JOIN workshops w ON w.id = s.workshop_id
+			WHERE w.status NOT IN ('stopped', 'deleted') AND s.status = 'occupied' AND s.name != ''),
+		(SELECT COUNT(*) FROM registrations r JOIN workshops w ON w.id = r.workshop_id
+			WHERE w.status NOT IN ('stopped', 'deleted') AND r.status NOT IN ('revoked', 'waitlisted'))`
+	if err := s.db.QueryRow(query).Scan(&stats.ActiveLearners, &stats.Registrations); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
 // -- Session Operations --
 
 func (s *SQLiteStore) CreateSession(session *Session) error {
@@ -413,15 +885,41 @@ func (s *SQLiteStore) GetVMPrivateKey(workshopID string) (string, error) {
 // -- Registration Operations --
 
 func (s *SQLiteStore) CreateRegistration(r *Registration) error {
-	query := `INSERT INTO registrations (id, access_code, email, name, workshop_id, seat_id, status, created_at, joined_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
-	_, err := s.db.Exec(query, r.ID, r.AccessCode, r.Email, r.Name, r.WorkshopID, r.SeatID, r.Status, r.CreatedAt, r.JoinedAt)
+	query := `INSERT INTO registrations (id, access_code, email, name, workshop_id, seat_id, status, waitlist_position, created_at, joined_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := s.db.Exec(query, r.ID, r.AccessCode, r.Email, r.Name, r.WorkshopID, r.SeatID, r.Status, r.WaitlistPosition, r.CreatedAt, r.JoinedAt)
 	return err
 }
 
+func (s *SQLiteStore) CreateRegistrations(registrations []*Registration) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO registrations (id, access_code, email, name, workshop_id, seat_id, status, waitlist_position, created_at, joined_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	for _, r := range registrations {
+		if _, err := tx.Exec(query, r.ID, r.AccessCode, r.Email, r.Name, r.WorkshopID, r.SeatID, r.Status, r.WaitlistPosition, r.CreatedAt, r.JoinedAt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
 func (s *SQLiteStore) GetRegistration(accessCode string) (*Registration, error) {
 	r := &Registration{}
-	query := `SELECT id, access_code, email, name, workshop_id, seat_id, status, created_at, joined_at FROM registrations WHERE access_code = ?`
-	err := s.db.QueryRow(query, accessCode).Scan(&r.ID, &r.AccessCode, &r.Email, &r.Name, &r.WorkshopID, &r.SeatID, &r.Status, &r.CreatedAt, &r.JoinedAt)
+	query := `SELECT id, access_code, email, name, workshop_id, seat_id, status, waitlist_position, created_at, joined_at FROM registrations WHERE access_code = ?`
+	err := s.db.QueryRow(query, accessCode).Scan(&r.ID, &r.AccessCode, &r.Email, &r.Name, &r.WorkshopID, &r.SeatID, &r.Status, &r.WaitlistPosition, &r.CreatedAt, &r.JoinedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return r, err
+}
+
+func (s *SQLiteStore) GetRegistrationByID(id string) (*Registration, error) {
+	r := &Registration{}
+	query := `SELECT id, access_code, email, name, workshop_id, seat_id, status, waitlist_position, created_at, joined_at FROM registrations WHERE id = ?`
+	err := s.db.QueryRow(query, id).Scan(&r.ID, &r.AccessCode, &r.Email, &r.Name, &r.WorkshopID, &r.SeatID, &r.Status, &r.WaitlistPosition, &r.CreatedAt, &r.JoinedAt)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -430,8 +928,8 @@ func (s *SQLiteStore) GetRegistration(accessCode string) (*Registration, error)
 
 func (s *SQLiteStore) GetRegistrationByEmail(workshopID, email string) (*Registration, error) {
 	r := &Registration{}
-	query := `SELECT id, access_code, email, name, workshop_id, seat_id, status, created_at, joined_at FROM registrations WHERE workshop_id = ? AND email = ?`
-	err := s.db.QueryRow(query, workshopID, email).Scan(&r.ID, &r.AccessCode, &r.Email, &r.Name, &r.WorkshopID, &r.SeatID, &r.Status, &r.CreatedAt, &r.JoinedAt)
+	query := `SELECT id, access_code, email, name, workshop_id, seat_id, status, waitlist_position, created_at, joined_at FROM registrations WHERE workshop_id = ? AND email = ?`
+	err := s.db.QueryRow(query, workshopID, email).Scan(&r.ID, &r.AccessCode, &r.Email, &r.Name, &r.WorkshopID, &r.SeatID, &r.Status, &r.WaitlistPosition, &r.CreatedAt, &r.JoinedAt)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -439,14 +937,627 @@ func (s *SQLiteStore) GetRegistrationByEmail(workshopID, email string) (*Registr
 }
 
 func (s *SQLiteStore) UpdateRegistration(r *Registration) error {
-	query := `UPDATE registrations SET seat_id = ?, status = ?, joined_at = ? WHERE id = ?`
-	_, err := s.db.Exec(query, r.SeatID, r.Status, r.JoinedAt, r.ID)
+	query := `UPDATE registrations SET seat_id = ?, status = ?, waitlist_position = ?, joined_at = ? WHERE id = ?`
+	_, err := s.db.Exec(query, r.SeatID, r.Status, r.WaitlistPosition, r.JoinedAt, r.ID)
+	return err
+}
+
+func (s *SQLiteStore) UpdateRegistrationAccessCode(id, accessCode string) error {
+	_, err := s.db.Exec(`UPDATE registrations SET access_code = ? WHERE id = ?`, accessCode, id)
 	return err
 }
 
 func (s *SQLiteStore) CountRegistrations(workshopID string) (int, error) {
 	var count int
-	query := `SELECT COUNT(*) FROM registrations WHERE workshop_id = ?`
+	query := `SELECT COUNT(*) FROM registrations WHERE workshop_id = ? AND status NOT IN ('revoked', 'waitlisted')`
 	err := s.db.QueryRow(query, workshopID).Scan(&count)
 	return count, err
 }
+
+func (s *SQLiteStore) ListRegistrations(workshopID string) ([]*Registration, error) {
+	query := `SELECT id, access_code, email, name, workshop_id, seat_id, status, waitlist_position, created_at, joined_at FROM registrations WHERE workshop_id = ? ORDER BY created_at`
+	rows, err := s.db.Query(query, workshopID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var registrations []*Registration
+	for rows.Next() {
+		r := &Registration{}
+		if err := rows.Scan(&r.ID, &r.AccessCode, &r.Email, &r.Name, &r.WorkshopID, &r.SeatID, &r.Status, &r.WaitlistPosition, &r.CreatedAt, &r.JoinedAt); err != nil {
+			return nil, err
+		}
+		registrations = append(registrations, r)
+	}
+	return registrations, rows.Err()
+}
+
+func (s *SQLiteStore) ReorderWaitlist(workshopID string, registrationIDs []string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE registrations SET waitlist_position = ? WHERE id = ? AND workshop_id = ? AND status = 'waitlisted'`
+	for i, id := range registrationIDs {
+		res, err := tx.Exec(query, i+1, id, workshopID)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return ErrNotWaitlisted
+		}
+	}
+	return tx.Commit()
+}
+
+// -- Workshop Member Operations --
+
+func (s *SQLiteStore) AddWorkshopMember(m *WorkshopMember) error {
+	query := `INSERT INTO workshop_members (workshop_id, user_id, role, added_by, created_at) VALUES (?, ?, ?, ?, ?)`
+	_, err := s.db.Exec(query, m.WorkshopID, m.UserID, m.Role, m.AddedBy, m.CreatedAt)
+	return err
+}
+
+func (s *SQLiteStore) GetWorkshopMember(workshopID, userID string) (*WorkshopMember, error) {
+	m := &WorkshopMember{}
+	query := `SELECT workshop_id, user_id, role, COALESCE(added_by, ''), created_at FROM workshop_members WHERE workshop_id = ? AND user_id = ?`
+	err := s.db.QueryRow(query, workshopID, userID).Scan(&m.WorkshopID, &m.UserID, &m.Role, &m.AddedBy, &m.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return m, err
+}
+
+func (s *SQLiteStore) ListWorkshopMembers(workshopID string) ([]*WorkshopMember, error) {
+	query := `SELECT workshop_id, user_id, role, COALESCE(added_by, ''), created_at FROM workshop_members WHERE workshop_id = ? ORDER BY created_at`
+	rows, err := s.db.Query(query, workshopID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []*WorkshopMember
+	for rows.Next() {
+		m := &WorkshopMember{}
+		if err := rows.Scan(&m.WorkshopID, &m.UserID, &m.Role, &m.AddedBy, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+func (s *SQLiteStore) RemoveWorkshopMember(workshopID, userID string) error {
+	_, err := s.db.Exec(`DELETE FROM workshop_members WHERE workshop_id = ? AND user_id = ?`, workshopID, userID)
+	return err
+}
+
+func (s *SQLiteStore) ListWorkshopsByMember(userID string) ([]*Workshop, error) {
+	query := `SELECT w.id, w.name, w.code, w.seats, w.api_key, w.runtime_type, w.status, w.waitlist, w.starts_at, COALESCE(w.owner_id, ''), w.created_at
+		FROM workshops w JOIN workshop_members m ON m.workshop_id = w.id
+		WHERE m.user_id = ? ORDER BY w.created_at DESC`
+	rows, err := s.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var workshops []*Workshop
+	for rows.Next() {
+		w := &Workshop{}
+		if err := rows.Scan(&w.ID, &w.Name, &w.Code, &w.Seats, &w.ApiKey, &w.RuntimeType, &w.Status, &w.Waitlist, &w.StartsAt, &w.OwnerID, &w.CreatedAt); err != nil {
+			return nil, err
+		}
+		workshops = append(workshops, w)
+	}
+	return workshops, rows.Err()
+}
+
+// -- Workshop Event Operations --
+
+func (s *SQLiteStore) CreateWorkshopEvent(e *WorkshopEvent) error {
+	query := `INSERT INTO workshop_events (workshop_id, type, seat_id, message, created_at) VALUES (?, ?, ?, ?, ?)`
+	res, err := s.db.Exec(query, e.WorkshopID, e.Type, e.SeatID, e.Message, e.CreatedAt)
+	if err != nil {
+		return err
+	}
+	e.ID, err = res.LastInsertId()
+	return err
+}
+
+func (s *SQLiteStore) ListWorkshopEvents(workshopID string) ([]*WorkshopEvent, error) {
+	query := `SELECT id, workshop_id, type, seat_id, message, created_at FROM workshop_events WHERE workshop_id = ? ORDER BY id`
+	rows, err := s.db.Query(query, workshopID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*WorkshopEvent
+	for rows.Next() {
+		e := &WorkshopEvent{}
+		if err := rows.Scan(&e.ID, &e.WorkshopID, &e.Type, &e.SeatID, &e.Message, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// -- Terminal Recording Operations --
+
+func (s *SQLiteStore) CreateTerminalRecording(r *TerminalRecording) error {
+	query := `INSERT OR IGNORE INTO terminal_recordings (workshop_id, seat_id, name, learner_name, size_bytes, started_at, data, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	res, err := s.db.Exec(query, r.WorkshopID, r.SeatID, r.Name, r.LearnerName, r.SizeBytes, r.StartedAt, r.Data, r.CreatedAt)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+	r.ID, err = res.LastInsertId()
+	return err
+}
+
+func (s *SQLiteStore) ListTerminalRecordings(workshopID string) ([]*TerminalRecording, error) {
+	query := `SELECT id, workshop_id, seat_id, name, learner_name, size_bytes, started_at, created_at FROM terminal_recordings WHERE workshop_id = ? ORDER BY started_at, id`
+	rows, err := s.db.Query(query, workshopID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recordings []*TerminalRecording
+	for rows.Next() {
+		r := &TerminalRecording{}
+		if err := rows.Scan(&r.ID, &r.WorkshopID, &r.SeatID, &r.Name, &r.LearnerName, &r.SizeBytes, &r.StartedAt, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		recordings = append(recordings, r)
+	}
+	return recordings, rows.Err()
+}
+
+func (s *SQLiteStore) GetTerminalRecording(id int64) (*TerminalRecording, error) {
+	r := &TerminalRecording{}
+	query := `SELECT id, workshop_id, seat_id, name, learner_name, size_bytes, started_at, data, created_at FROM terminal_recordings WHERE id = ?`
+	err := s.db.QueryRow(query, id).Scan(&r.ID, &r.WorkshopID, &r.SeatID, &r.Name, &r.LearnerName, &r.SizeBytes, &r.StartedAt, &r.Data, &r.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return r, err
+}
+
+// -- Announcement Operations --
+
+func (s *SQLiteStore) CreateAnnouncement(a *Announcement) error {
+	query := `INSERT INTO announcements (workshop_id, message, author_id, author_name, created_at) VALUES (?, ?, ?, ?, ?)`
+	res, err := s.db.Exec(query, a.WorkshopID, a.Message, a.AuthorID, a.AuthorName, a.CreatedAt)
+	if err != nil {
+		return err
+	}
+	a.ID, err = res.LastInsertId()
+	return err
+}
+
+func (s *SQLiteStore) ListAnnouncements(workshopID string) ([]*Announcement, error) {
+	query := `SELECT id, workshop_id, message, COALESCE(author_id, ''), author_name, created_at FROM announcements WHERE workshop_id = ? ORDER BY id`
+	rows, err := s.db.Query(query, workshopID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var announcements []*Announcement
+	for rows.Next() {
+		a := &Announcement{}
+		if err := rows.Scan(&a.ID, &a.WorkshopID, &a.Message, &a.AuthorID, &a.AuthorName, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		announcements = append(announcements, a)
+	}
+	return announcements, rows.Err()
+}
+
+// -- Help Request Operations --
+
+func (s *SQLiteStore) CreateHelpRequest(h *HelpRequest) error {
+	query := `INSERT INTO help_requests (workshop_id, registration_id, seat_id, learner_name, message, status, claimed_by_name, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	res, err := s.db.Exec(query, h.WorkshopID, h.RegistrationID, h.SeatID, h.LearnerName, h.Message, h.Status, h.ClaimedByName, h.CreatedAt)
+	if err != nil {
+		return err
+	}
+	h.ID, err = res.LastInsertId()
+	return err
+}
+
+func (s *SQLiteStore) GetHelpRequest(id int64) (*HelpRequest, error) {
+	h := &HelpRequest{}
+	query := `SELECT id, workshop_id, registration_id, seat_id, learner_name, message, status, COALESCE(claimed_by, ''), claimed_by_name, created_at, claimed_at, resolved_at FROM help_requests WHERE id = ?`
+	err := s.db.QueryRow(query, id).Scan(&h.ID, &h.WorkshopID, &h.RegistrationID, &h.SeatID, &h.LearnerName, &h.Message, &h.Status, &h.ClaimedBy, &h.ClaimedByName, &h.CreatedAt, &h.ClaimedAt, &h.ResolvedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return h, err
+}
+
+func (s *SQLiteStore) GetActiveHelpRequest(registrationID string) (*HelpRequest, error) {
+	h := &HelpRequest{}
+	query := `SELECT id, workshop_id, registration_id, seat_id, learner_name, message, status, COALESCE(claimed_by, ''), claimed_by_name, created_at, claimed_at, resolved_at FROM help_requests WHERE registration_id = ? AND status <> 'resolved' ORDER BY id DESC LIMIT 1`
+	err := s.db.QueryRow(query, registrationID).Scan(&h.ID, &h.WorkshopID, &h.RegistrationID, &h.SeatID, &h.LearnerName, &h.Message, &h.Status, &h.ClaimedBy, &h.ClaimedByName, &h.CreatedAt, &h.ClaimedAt, &h.ResolvedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return h, err
+}
+
+func (s *SQLiteStore) ListHelpRequests(workshopID string) ([]*HelpRequest, error) {
+	query := `SELECT id, workshop_id, registration_id, seat_id, learner_name, message, status, COALESCE(claimed_by, ''), claimed_by_name, created_at, claimed_at, resolved_at FROM help_requests WHERE workshop_id = ? ORDER BY id`
+	rows, err := s.db.Query(query, workshopID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var requests []*HelpRequest
+	for rows.Next() {
+		h := &HelpRequest{}
+		if err := rows.Scan(&h.ID, &h.WorkshopID, &h.RegistrationID, &h.SeatID, &h.LearnerName, &h.Message, &h.Status, &h.ClaimedBy, &h.ClaimedByName, &h.CreatedAt, &h.ClaimedAt, &h.ResolvedAt); err != nil {
+			return nil, err
+		}
+		requests = append(requests, h)
+	}
+	return requests, rows.Err()
+}
+
+func (s *SQLiteStore) ClaimHelpRequest(id int64, userID, userName string, at time.Time) (bool, error) {
+	query := `UPDATE help_requests SET status = 'claimed', claimed_by = ?, claimed_by_name = ?, claimed_at = ? WHERE id = ? AND status = 'open'`
+	res, err := s.db.Exec(query, userID, userName, at, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (s *SQLiteStore) ResolveHelpRequest(id int64, at time.Time) (bool, error) {
+	query := `UPDATE help_requests SET status = 'resolved', resolved_at = ? WHERE id = ? AND status <> 'resolved'`
+	res, err := s.db.Exec(query, at, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// -- Notification Operations --
+
+func (s *SQLiteStore) SetNotificationTemplate(t *NotificationTemplate) error {
+	query := `INSERT INTO notification_templates (workshop_id, kind, subject, body, updated_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (workshop_id, kind) DO UPDATE SET subject = excluded.subject, body = excluded.body, updated_at = excluded.updated_at`
+	_, err := s.db.Exec(query, t.WorkshopID, t.Kind, t.Subject, t.Body, t.UpdatedAt)
+	return err
+}
+
+func (s *SQLiteStore) GetNotificationTemplate(workshopID, kind string) (*NotificationTemplate, error) {
+	t := &NotificationTemplate{}
+	query := `SELECT workshop_id, kind, subject, body, updated_at FROM notification_templates WHERE workshop_id = ? AND kind = ?`
+	err := s.db.QueryRow(query, workshopID, kind).Scan(&t.WorkshopID, &t.Kind, &t.Subject, &t.Body, &t.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return t, err
+}
+
+func (s *SQLiteStore) ListNotificationTemplates(workshopID string) ([]*NotificationTemplate, error) {
+	query := `SELECT workshop_id, kind, subject, body, updated_at FROM notification_templates WHERE workshop_id = ? ORDER BY kind`
+	rows, err := s.db.Query(query, workshopID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []*NotificationTemplate
+	for rows.Next() {
+		t := &NotificationTemplate{}
+		if err := rows.Scan(&t.WorkshopID, &t.Kind, &t.Subject, &t.Body, &t.UpdatedAt); err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, rows.Err()
+}
+
+func (s *SQLiteStore) DeleteNotificationTemplate(workshopID, kind string) error {
+	_, err := s.db.Exec(`DELETE FROM notification_templates WHERE workshop_id = ? AND kind = ?`, workshopID, kind)
+	return err
+}
+
+func (s *SQLiteStore) ClaimNotification(n *Notification) (bool, error) {
+	query := `INSERT INTO notifications (workshop_id, registration_id, kind, recipient, status, error, created_at) VALUES (?, ?, ?, ?, 'pending', '', ?)
+		ON CONFLICT (registration_id, kind) DO UPDATE SET recipient = excluded.recipient, status = 'pending', error = '', created_at = excluded.created_at
+		WHERE notifications.status = 'failed'`
+	res, err := s.db.Exec(query, n.WorkshopID, n.RegistrationID, n.Kind, n.Recipient, n.CreatedAt)
+	if err != nil {
+		return false, err
+	}
+	count, err := res.RowsAffected()
+	if err != nil || count == 0 {
+		return false, err
+	}
+	n.Status = NotificationPending
+	n.Error = ""
+	return true, nil
+}
+
+func (s *SQLiteStore) UpdateNotification(n *Notification) error {
+	query := `UPDATE notifications SET status = ?, error = ?, sent_at = ? WHERE registration_id = ? AND kind = ?`
+	_, err := s.db.Exec(query, n.Status, n.Error, n.SentAt, n.RegistrationID, n.Kind)
+	return err
+}
+
+func (s *SQLiteStore) ListNotifications(workshopID string) ([]*Notification, error) {
+	query := `SELECT workshop_id, registration_id, kind, recipient, status, error, created_at, sent_at FROM notifications WHERE workshop_id = ? ORDER BY created_at`
+	rows, err := s.db.Query(query, workshopID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []*Notification
+	for rows.Next() {
+		n := &Notification{}
+		if err := rows.Scan(&n.WorkshopID, &n.RegistrationID, &n.Kind, &n.Recipient, &n.Status, &n.Error, &n.CreatedAt, &n.SentAt); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+// -- Login Link Operations --
+
+func (s *SQLiteStore) CreateLoginLink(l *LoginLink) error {
+	query := `INSERT INTO login_links (id, registration_id, workshop_id, created_at, expires_at) VALUES (?, ?, ?, ?, ?)`
+	_, err := s.db.Exec(query, l.ID, l.RegistrationID, l.WorkshopID, l.CreatedAt, l.ExpiresAt)
+	return err
+}
+
+func (s *SQLiteStore) GetLatestLoginLink(registrationID string) (*LoginLink, error) {
+	query := `SELECT id, registration_id, workshop_id, created_at, expires_at, used_at FROM login_links WHERE registration_id = ? ORDER BY created_at DESC LIMIT 1`
+	l := &LoginLink{}
+	err := s.db.QueryRow(query, registrationID).Scan(&l.ID, &l.RegistrationID, &l.WorkshopID, &l.CreatedAt, &l.ExpiresAt, &l.UsedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (s *SQLiteStore) UseLoginLink(id, registrationID string, at time.Time) (bool, error) {
+	query := `UPDATE login_links SET used_at = ? WHERE id = ? AND registration_id = ? AND used_at IS NULL AND expires_at > ?`
+	res, err := s.db.Exec(query, at, id, registrationID, at)
+	if err != nil {
+		return false, err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (s *SQLiteStore) ExpireLoginLinks(registrationID string, at time.Time) error {
+	_, err := s.db.Exec(`UPDATE login_links SET used_at = ? WHERE registration_id = ? AND used_at IS NULL`, at, registrationID)
+	return err
+}
+
+// -- Webhook Operations --
+
+func (s *SQLiteStore) CreateWebhook(h *Webhook) error {
+	query := `INSERT INTO webhooks (id, workshop_id, url, secret, events, created_by, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	_, err := s.db.Exec(query, h.ID, h.WorkshopID, h.URL, h.Secret, strings.Join(h.Events, ","), h.CreatedBy, h.CreatedAt)
+	return err
+}
+
+func (s *SQLiteStore) GetWebhook(id string) (*Webhook, error) {
+	query := `SELECT id, workshop_id, url, secret, events, created_by, created_at FROM webhooks WHERE id = ?`
+	h, err := scanWebhook(s.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return h, err
+}
+
+func (s *SQLiteStore) ListWebhooks(workshopID string) ([]*Webhook, error) {
+	query := `SELECT id, workshop_id, url, secret, events, created_by, created_at FROM webhooks WHERE workshop_id = ? ORDER BY created_at`
+	rows, err := s.db.Query(query, workshopID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []*Webhook
+	for rows.Next() {
+		h, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, h)
+	}
+	return webhooks, rows.Err()
+}
+
+func (s *SQLiteStore) DeleteWebhook(id string) error {
+	_, err := s.db.Exec(`DELETE FROM webhook_deliveries WHERE webhook_id = ?`, id)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`DELETE FROM webhooks WHERE id = ?`, id)
+	return err
+}
+
+func (s *SQLiteStore) CreateWebhookDelivery(d *WebhookDelivery) error {
+	query := `INSERT INTO webhook_deliveries (webhook_id, workshop_id, event, payload, status, attempts, next_attempt_at, last_status_code, last_error, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	res, err := s.db.Exec(query, d.WebhookID, d.WorkshopID, d.Event, d.Payload, d.Status, d.Attempts, d.NextAttemptAt, d.LastStatusCode, d.LastError, d.CreatedAt)
+	if err != nil {
+		return err
+	}
+	d.ID, err = res.LastInsertId()
+	return err
+}
+
+func (s *SQLiteStore) GetWebhookDelivery(id int64) (*WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE id = ?`
+	d, err := scanWebhookDelivery(s.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return d, err
+}
+
+func (s *SQLiteStore) ListDueWebhookDeliveries(now time.Time, limit int) ([]*WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE status = 'pending' AND next_attempt_at <= ? ORDER BY next_attempt_at, id LIMIT ?`
+	return s.queryWebhookDeliveries(query, now, limit)
+}
+
+func (s *SQLiteStore) ClaimWebhookDelivery(id int64, now, leaseUntil time.Time) (bool, error) {
+	query := `UPDATE webhook_deliveries SET next_attempt_at = ? WHERE id = ? AND status = 'pending' AND next_attempt_at <= ?`
+	res, err := s.db.Exec(query, leaseUntil, id, now)
+	if err != nil {
+		return false, err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (s *SQLiteStore) UpdateWebhookDelivery(d *WebhookDelivery) error {
+	query := `UPDATE webhook_deliveries SET status = ?, attempts = ?, next_attempt_at = ?, last_status_code = ?, last_error = ?, delivered_at = ? WHERE id = ?`
+	_, err := s.db.Exec(query, d.Status, d.Attempts, d.NextAttemptAt, d.LastStatusCode, d.LastError, d.DeliveredAt, d.ID)
+	return err
+}
+
+func (s *SQLiteStore) ListWebhookDeliveries(webhookID string, limit int) ([]*WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE webhook_id = ? ORDER BY id DESC LIMIT ?`
+	return s.queryWebhookDeliveries(query, webhookID, limit)
+}
+
+func (s *SQLiteStore) queryWebhookDeliveries(query string, args ...interface{}) ([]*WebhookDelivery, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*WebhookDelivery
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// -- LTI Operations --
+
+func (s *SQLiteStore) CreateLTIPlatform(p *LTIPlatform) error {
+	query := `INSERT INTO lti_platforms (` + ltiPlatformColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := s.db.Exec(query, p.ID, p.Name, p.Issuer, p.ClientID, p.AuthLoginURL, p.JWKSURL, strings.Join(p.DeploymentIDs, ","), p.TrustEmail, p.CreatedAt)
+	return err
+}
+
+func (s *SQLiteStore) SetLTIPlatformTrustEmail(id string, trust bool) error {
+	_, err := s.db.Exec(`UPDATE lti_platforms SET trust_email = ? WHERE id = ?`, trust, id)
+	return err
+}
+
+func (s *SQLiteStore) GetLTIPlatform(id string) (*LTIPlatform, error) {
+	query := `SELECT ` + ltiPlatformColumns + ` FROM lti_platforms WHERE id = ?`
+	p, err := scanLTIPlatform(s.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return p, err
+}
+
+func (s *SQLiteStore) ListLTIPlatforms() ([]*LTIPlatform, error) {
+	rows, err := s.db.Query(`SELECT ` + ltiPlatformColumns + ` FROM lti_platforms ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var platforms []*LTIPlatform
+	for rows.Next() {
+		p, err := scanLTIPlatform(rows)
+		if err != nil {
+			return nil, err
+		}
+		platforms = append(platforms, p)
+	}
+	return platforms, rows.Err()
+}
+
+func (s *SQLiteStore) DeleteLTIPlatform(id string) error {
+	_, err := s.db.Exec(`DELETE FROM lti_contexts WHERE platform_id = ?`, id)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`DELETE FROM lti_links WHERE platform_id = ?`, id)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`DELETE FROM lti_platforms WHERE id = ?`, id)
+	return err
+}
+
+func (s *SQLiteStore) SetLTIContext(c *LTIContext) error {
+	query := `INSERT INTO lti_contexts (platform_id, context_id, workshop_id, title, updated_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (platform_id, context_id) DO UPDATE SET workshop_id = excluded.workshop_id, title = excluded.title, updated_at = excluded.updated_at`
+	_, err := s.db.Exec(query, c.PlatformID, c.ContextID, c.WorkshopID, c.Title, c.UpdatedAt)
+	return err
+}
+
+func (s *SQLiteStore) GetLTIContext(platformID, contextID string) (*LTIContext, error) {
+	c := &LTIContext{}
+	query := `SELECT platform_id, context_id, workshop_id, title, updated_at FROM lti_contexts WHERE platform_id = ? AND context_id = ?`
+	err := s.db.QueryRow(query, platformID, contextID).Scan(&c.PlatformID, &c.ContextID, &c.WorkshopID, &c.Title, &c.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *SQLiteStore) AddLTILink(l *LTILink) error {
+	query := `INSERT INTO lti_links (platform_id, context_id, workshop_id, created_at) VALUES (?, ?, ?, ?) ON CONFLICT DO NOTHING`
+	_, err := s.db.Exec(query, l.PlatformID, l.ContextID, l.WorkshopID, l.CreatedAt)
+	return err
+}
+
+func (s *SQLiteStore) GetLTILink(platformID, contextID, workshopID string) (*LTILink, error) {
+	l := &LTILink{}
+	query := `SELECT platform_id, context_id, workshop_id, created_at FROM lti_links WHERE platform_id = ? AND context_id = ? AND workshop_id = ?`
+	err := s.db.QueryRow(query, platformID, contextID, workshopID).Scan(&l.PlatformID, &l.ContextID, &l.WorkshopID, &l.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
@@ -1,29 +1,54 @@
 package store
 
 import (
+	"errors"
+	"strings"
 	"time"
 )
 
+// ErrSeatsOccupied is returned by ResizeWorkshopSeats when a seat that would be
+// removed is assigned to a learner.
+var ErrSeatsOccupied = errors.New("cannot remove occupied seats")
+
+// ErrTooManyRegistrations is returned by ResizeWorkshopSeats when the workshop
+// has more active registrations than the new seat count.
+var ErrTooManyRegistrations = errors.New("more registrations than seats")
+
+// ErrNotWaitlisted is returned by ReorderWaitlist when a registration is not on
+// the workshop's waitlist.
+var ErrNotWaitlisted = errors.New("registration is not waitlisted")
+
 // User represents an instructor or admin
 type User struct {
 	ID           string    `json:"id"`
 	Email        string    `json:"email"`
 	PasswordHash string    `json:"-"` // Never return in JSON
 	Name         string    `json:"name"`
-	Role         string    `json:"role"` // "instructor" or "admin"
+	Role         string    `json:"role"`     // "instructor" or "admin"
+	Disabled     bool      `json:"disabled"` // Disabled users cannot sign in
 	CreatedAt    time.Time `json:"created_at"`
 }
 
+// WorkshopStats are workshop counts for metrics. Live workshops are those
+// neither stopped nor deleted.
+type WorkshopStats struct {
+	ByStatus       map[string]int // Workshops by status
+	ActiveLearners int            // Occupied seats of live workshops
+	Registrations  int            // Registrations of live workshops, excluding revoked and waitlisted
+}
+
 type Workshop struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	Code        string    `json:"code"`
-	Seats       int       `json:"seats"`
-	ApiKey      string    `json:"-"`
-	RuntimeType string    `json:"runtime_type"` // "docker" or "firecracker"
-	Status      string    `json:"status"`
-	OwnerID     string    `json:"owner_id"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Code        string     `json:"code"`
+	Seats       int        `json:"seats"`
+	ApiKey      string     `json:"-"`
+	RuntimeType string     `json:"runtime_type"` // "docker" or "firecracker"
+	Status      string     `json:"status"`
+	Waitlist    bool       `json:"waitlist"`  // Queue registrations beyond the seat count
+	StartsAt    *time.Time `json:"starts_at"` // Scheduled start, for reminder emails; optional
+	OwnerID     string     `json:"owner_id"`
+	CreatedAt   time.Time  `json:"created_at"`
 }
 
 // WorkshopVM represents a GCP VM provisioned for a workshop
@@ -72,15 +97,222 @@ type Session struct {
 
 // Registration represents a learner's registration for a workshop
 type Registration struct {
-	ID          string     `json:"id"`
-	AccessCode  string     `json:"access_code"`   // User-facing code like "FZL-7X9K"
-	Email       string     `json:"email"`
-	Name        string     `json:"name"`
-	WorkshopID  string     `json:"workshop_id"`
-	SeatID      *int       `json:"seat_id"`       // NULL until first join
-	Status      string     `json:"status"`        // registered, active, expired
-	CreatedAt   time.Time  `json:"created_at"`
-	JoinedAt    *time.Time `json:"joined_at"`     // When they first accessed workspace
+	ID               string     `json:"id"`
+	AccessCode       string     `json:"access_code"` // User-facing code like "FZL-7X9K"
+	Email            string     `json:"email"`
+	Name             string     `json:"name"`
+	WorkshopID       string     `json:"workshop_id"`
+	SeatID           *int       `json:"seat_id"`                     // NULL until first join
+	Status           string     `json:"status"`                      // registered, active, expired, revoked, waitlisted
+	WaitlistPosition int        `json:"waitlist_position,omitempty"` // 1-based while waitlisted
+	CreatedAt        time.Time  `json:"created_at"`
+	JoinedAt         *time.Time `json:"joined_at"` // When they first accessed workspace
+}
+
+// Workshop member roles
+const (
+	MemberRoleCoInstructor = "co_instructor"
+	MemberRoleTA           = "ta"
+)
+
+// WorkshopMember grants a user other than the owner access to a workshop
+type WorkshopMember struct {
+	WorkshopID string    `json:"workshop_id"`
+	UserID     string    `json:"user_id"`
+	Role       string    `json:"role"` // "co_instructor" or "ta"
+	AddedBy    string    `json:"added_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// WorkshopEvent is one step in a workshop's provisioning timeline
+type WorkshopEvent struct {
+	ID         int64     `json:"id"`
+	WorkshopID string    `json:"workshop_id"`
+	Type       string    `json:"type"`              // e.g. vm_create_requested, agent_healthy, seat_created, error
+	SeatID     *int      `json:"seat_id,omitempty"` // Set for per-seat events
+	Message    string    `json:"message"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TerminalRecording is an asciicast recording of one learner terminal
+// connection, collected from the workshop VM when the workshop stops
+type TerminalRecording struct {
+	ID          int64     `json:"id"`
+	WorkshopID  string    `json:"workshop_id"`
+	SeatID      int       `json:"seat_id"`
+	Name        string    `json:"name"` // File name on the VM, unique per workshop
+	LearnerName string    `json:"learner_name"`
+	SizeBytes   int64     `json:"size_bytes"`
+	StartedAt   time.Time `json:"started_at"`
+	Data        []byte    `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Announcement is a message from workshop staff to every learner
+type Announcement struct {
+	ID         int64     `json:"id"`
+	WorkshopID string    `json:"workshop_id"`
+	Message    string    `json:"message"`
+	AuthorID   string    `json:"author_id"`
+	AuthorName string    `json:"author_name"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Help request statuses
+const (
+	HelpRequestOpen     = "open"
+	HelpRequestClaimed  = "claimed"
+	HelpRequestResolved = "resolved"
+)
+
+// HelpRequest is a learner's raised hand, queued for workshop staff
+type HelpRequest struct {
+	ID             int64      `json:"id"`
+	WorkshopID     string     `json:"workshop_id"`
+	RegistrationID string     `json:"registration_id"`
+	SeatID         int        `json:"seat_id"`
+	LearnerName    string     `json:"learner_name"`
+	Message        string     `json:"message"`
+	Status         string     `json:"status"`     // open, claimed, resolved
+	ClaimedBy      string     `json:"claimed_by"` // User ID of the staff member helping
+	ClaimedByName  string     `json:"claimed_by_name"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ClaimedAt      *time.Time `json:"claimed_at"`
+	ResolvedAt     *time.Time `json:"resolved_at"`
+}
+
+// NotificationTemplate overrides the built-in email for one kind of message
+// in a workshop
+type NotificationTemplate struct {
+	WorkshopID string    `json:"workshop_id"`
+	Kind       string    `json:"kind"`    // registration, waitlisted, reminder, workspace_ready
+	Subject    string    `json:"subject"` // text/template source
+	Body       string    `json:"body"`    // text/template source
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Notification statuses
+const (
+	NotificationPending = "pending"
+	NotificationSent    = "sent"
+	NotificationFailed  = "failed"
+)
+
+// Notification records an email sent to a learner. Each kind is sent at most
+// once per registration.
+type Notification struct {
+	WorkshopID     string     `json:"workshop_id"`
+	RegistrationID string     `json:"registration_id"`
+	Kind           string     `json:"kind"`
+	Recipient      string     `json:"recipient"`
+	Status         string     `json:"status"` // pending, sent, failed
+	Error          string     `json:"error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	SentAt         *time.Time `json:"sent_at"`
+}
+
+// LoginLink is a one-time login link emailed to a learner in place of their
+// access code. ID is the signed token's ID.
+type LoginLink struct {
+	ID             string     `json:"id"`
+	RegistrationID string     `json:"registration_id"`
+	WorkshopID     string     `json:"workshop_id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	UsedAt         *time.Time `json:"used_at"`
+}
+
+// Webhook delivery statuses
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// Webhook is an HTTP endpoint that receives a workshop's lifecycle events, or
+// every workshop's when WorkshopID is empty.
+type Webhook struct {
+	ID         string    `json:"id"`
+	WorkshopID string    `json:"workshop_id"` // Empty for global webhooks
+	URL        string    `json:"url"`
+	Secret     string    `json:"-"` // Signs payloads; only shown when created
+	Events     []string  `json:"events"`
+	CreatedBy  string    `json:"created_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// WebhookDelivery is one event queued for, or sent to, a webhook.
+type WebhookDelivery struct {
+	ID             int64      `json:"id"`
+	WebhookID      string     `json:"webhook_id"`
+	WorkshopID     string     `json:"workshop_id"`
+	Event          string     `json:"event"`
+	Payload        string     `json:"payload"`
+	Status         string     `json:"status"` // pending, delivered, failed
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+}
+
+// LTIPlatform is an LMS (such as Canvas or Moodle) registered to launch
+// workshops over LTI 1.3.
+type LTIPlatform struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	Issuer        string    `json:"issuer"`         // iss of the platform's ID tokens
+	ClientID      string    `json:"client_id"`      // The tool's client ID on the platform
+	AuthLoginURL  string    `json:"auth_login_url"` // Platform OIDC authorization endpoint
+	JWKSURL       string    `json:"jwks_url"`       // Platform public key set
+	DeploymentIDs []string  `json:"deployment_ids"` // Empty accepts every deployment
+	TrustEmail    bool      `json:"trust_email"`    // Deep linking acts as the account with the LMS email
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// LTIContext links an LMS course on a platform to the workshop its launches
+// open.
+type LTIContext struct {
+	PlatformID string    `json:"platform_id"`
+	ContextID  string    `json:"context_id"`
+	WorkshopID string    `json:"workshop_id"`
+	Title      string    `json:"title"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// LTILink records a workshop deep linked into an LMS course, so launches of
+// the resource link are only accepted from that course.
+type LTILink struct {
+	PlatformID string    `json:"platform_id"`
+	ContextID  string    `json:"context_id"` // Empty when the platform sent no course
+	WorkshopID string    `json:"workshop_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// UserIdentity links a user to their account at an OIDC provider, so they
+// can sign in there instead of with a password.
+type UserIdentity struct {
+	Provider  string    `json:"provider"` // Provider ID from OIDC_PROVIDERS
+	Subject   string    `json:"-"`        // sub of the provider's ID tokens
+	UserID    string    `json:"user_id"`
+	Email     string    `json:"email"` // Email at the provider when linked
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RefreshToken is a stored refresh token. Refreshing uses it up and issues
+// a new one in the same session; presenting a used token again means it was
+// copied, so the whole session is revoked.
+type RefreshToken struct {
+	ID              string // SHA-256 of the token, which is never stored
+	SessionID       string // Shared by the tokens that replaced each other
+	UserID          string
+	AccessTokenID   string    // jti of the access token issued with it
+	AccessExpiresAt time.Time // When that access token expires
+	ExpiresAt       time.Time
+	CreatedAt       time.Time
+	UsedAt          *time.Time // When it was exchanged for a new one
+	RevokedAt       *time.Time
 }
 
 type Store interface {
@@ -89,6 +321,24 @@ type Store interface {
 	GetUser(id string) (*User, error)
 	GetUserByEmail(email string) (*User, error)
 	ListUsers() ([]*User, error)
+	UpdateUserRole(id, role string) error
+	SetUserDisabled(id string, disabled bool) error
+
+	// Identity Operations
+	CreateUserIdentity(i *UserIdentity) error
+	GetUserIdentity(provider, subject string) (*UserIdentity, error)
+	ListUserIdentities(userID string) ([]*UserIdentity, error) // Oldest first
+	DeleteUserIdentity(userID, provider string) error
+
+	// Token Operations
+	CreateRefreshToken(t *RefreshToken) error
+	GetRefreshToken(id string) (*RefreshToken, error)
+	UseRefreshToken(id string, now time.Time) (bool, error)     // Marks an unused, unrevoked and unexpired token used; false if it was not
+	RevokeSession(sessionID string, now time.Time) error        // Revokes its refresh tokens and their unexpired access tokens
+	RevokeUserSessions(userID string, now time.Time) error      // RevokeSession for every session of the user
+	RevokeToken(tokenID string, expiresAt, now time.Time) error // Adds an access token to the revocation list
+	IsTokenRevoked(tokenID string) (bool, error)
+	DeleteExpiredTokens(now time.Time) error // Prunes expired refresh tokens and revocation list entries
 
 	// Workshop Operations
 	CreateWorkshop(w *Workshop) error
@@ -97,7 +347,10 @@ type Store interface {
 	ListWorkshops() ([]*Workshop, error)
 	ListWorkshopsByOwner(ownerID string) ([]*Workshop, error)
 	UpdateWorkshopStatus(id string, status string) error
+	UpdateWorkshop(w *Workshop) error                                          // Updates name, api_key, runtime_type, waitlist and starts_at
+	ResizeWorkshopSeats(workshopID string, seats int, added []*Session) error // Sets seats, inserts added and deletes sessions above seats in one transaction
 	DeleteWorkshop(id string) error
+	GetWorkshopStats() (*WorkshopStats, error)
 
 	// Session Operations
 	CreateSession(s *Session) error
@@ -119,9 +372,126 @@ type Store interface {
 
 	// Registration Operations
 	CreateRegistration(r *Registration) error
+	CreateRegistrations(registrations []*Registration) error // All or nothing
 	GetRegistration(accessCode string) (*Registration, error)
+	GetRegistrationByID(id string) (*Registration, error)
 	GetRegistrationByEmail(workshopID, email string) (*Registration, error)
 	UpdateRegistration(r *Registration) error
-	CountRegistrations(workshopID string) (int, error)
+	CountRegistrations(workshopID string) (int, error)            // Excludes revoked and waitlisted registrations
+	ListRegistrations(workshopID string) ([]*Registration, error) // Oldest first
+	UpdateRegistrationAccessCode(id, accessCode string) error
+	ReorderWaitlist(workshopID string, registrationIDs []string) error
+
+	// Workshop Member Operations
+	AddWorkshopMember(m *WorkshopMember) error
+	GetWorkshopMember(workshopID, userID string) (*WorkshopMember, error)
+	ListWorkshopMembers(workshopID string) ([]*WorkshopMember, error)
+	RemoveWorkshopMember(workshopID, userID string) error
+	ListWorkshopsByMember(userID string) ([]*Workshop, error) // Workshops shared with the user, newest first
+
+	// Workshop Event Operations
+	CreateWorkshopEvent(e *WorkshopEvent) error                    // Sets e.ID
+	ListWorkshopEvents(workshopID string) ([]*WorkshopEvent, error) // Oldest first
+
+	// Terminal Recording Operations
+	CreateTerminalRecording(r *TerminalRecording) error                     // No-op if the workshop already has a recording with r.Name
+	ListTerminalRecordings(workshopID string) ([]*TerminalRecording, error) // Oldest first, without Data
+	GetTerminalRecording(id int64) (*TerminalRecording, error)              // Includes Data
+
+	// Announcement Operations
+	CreateAnnouncement(a *Announcement) error                     // Sets a.ID
+	ListAnnouncements(workshopID string) ([]*Announcement, error) // Oldest first
+
+	// Help Request Operations
+	CreateHelpRequest(h *HelpRequest) error // Sets h.ID
+	GetHelpRequest(id int64) (*HelpRequest, error)
+	GetActiveHelpRequest(registrationID string) (*HelpRequest, error)               // Open or claimed
+	ListHelpRequests(workshopID string) ([]*HelpRequest, error)                     // Oldest first
+	ClaimHelpRequest(id int64, userID, userName string, at time.Time) (bool, error) // False if the request is not open
+	ResolveHelpRequest(id int64, at time.Time) (bool, error)                        // False if already resolved
+
+	// Notification Operations
+	SetNotificationTemplate(t *NotificationTemplate) error // Inserts or replaces the workshop's template for t.Kind
+	GetNotificationTemplate(workshopID, kind string) (*NotificationTemplate, error)
+	ListNotificationTemplates(workshopID string) ([]*NotificationTemplate, error)
+	DeleteNotificationTemplate(workshopID, kind string) error
+	ClaimNotification(n *Notification) (bool, error)              // Records n as pending; false if already pending or sent (failed ones may be claimed again)
+	UpdateNotification(n *Notification) error                     // Updates status, error and sent_at
+	ListNotifications(workshopID string) ([]*Notification, error) // Oldest first
+
+	// Login Link Operations
+	CreateLoginLink(l *LoginLink) error
+	GetLatestLoginLink(registrationID string) (*LoginLink, error)      // Most recently created
+	UseLoginLink(id, registrationID string, at time.Time) (bool, error) // Marks the link used; false if already used or expired
+	ExpireLoginLinks(registrationID string, at time.Time) error         // Marks every unused link used
+
+	// Webhook Operations
+	CreateWebhook(h *Webhook) error
+	GetWebhook(id string) (*Webhook, error)
+	ListWebhooks(workshopID string) ([]*Webhook, error) // Empty workshopID lists global webhooks
+	DeleteWebhook(id string) error                      // Also deletes its deliveries
+	CreateWebhookDelivery(d *WebhookDelivery) error     // Sets d.ID
+	GetWebhookDelivery(id int64) (*WebhookDelivery, error)
+	ListDueWebhookDeliveries(now time.Time, limit int) ([]*WebhookDelivery, error)     // Pending and due, oldest first
+	ClaimWebhookDelivery(id int64, now, leaseUntil time.Time) (bool, error)             // Pushes a due delivery's next attempt to leaseUntil; false if not due
+	UpdateWebhookDelivery(d *WebhookDelivery) error                                     // Updates status, attempts, next attempt and result
+	ListWebhookDeliveries(webhookID string, limit int) ([]*WebhookDelivery, error)      // Newest first
+
+	// LTI Operations
+	CreateLTIPlatform(p *LTIPlatform) error
+	GetLTIPlatform(id string) (*LTIPlatform, error)
+	SetLTIPlatformTrustEmail(id string, trust bool) error
+	ListLTIPlatforms() ([]*LTIPlatform, error) // Oldest first
+	DeleteLTIPlatform(id string) error         // Also deletes its course links
+	SetLTIContext(c *LTIContext) error         // Inserts or replaces the link for the platform and context
+	GetLTIContext(platformID, contextID string) (*LTIContext, error)
+	AddLTILink(l *LTILink) error // Records a deep linked workshop; adding it again is a no-op
+	GetLTILink(platformID, contextID, workshopID string) (*LTILink, error)
+}
+
+
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
 }
 
+// scanWebhook reads the columns id, workshop_id, url, secret, events,
+// created_by, created_at. Events are stored comma-separated.
+func scanWebhook(row rowScanner) (*Webhook, error) {
+	h := &Webhook{}
+	var events string
+	if err := row.Scan(&h.ID, &h.WorkshopID, &h.URL, &h.Secret, &events, &h.CreatedBy, &h.CreatedAt); err != nil {
+		return nil, err
+	}
+	if events != "" {
+		h.Events = strings.Split(events, ",")
+	}
+	return h, nil
+}
+
+const webhookDeliveryColumns = `id, webhook_id, workshop_id, event, payload, status, attempts, next_attempt_at, last_status_code, last_error, created_at, delivered_at`
+
+func scanWebhookDelivery(row rowScanner) (*WebhookDelivery, error) {
+	d := &WebhookDelivery{}
+	err := row.Scan(&d.ID, &d.WebhookID, &d.WorkshopID, &d.Event, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.CreatedAt, &d.DeliveredAt)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+const ltiPlatformColumns = `id, name, issuer, client_id, auth_login_url, jwks_url, deployment_ids, trust_email, created_at`
+
+// scanLTIPlatform reads ltiPlatformColumns. Deployment IDs are stored
+// comma-separated.
+func scanLTIPlatform(row rowScanner) (*LTIPlatform, error) {
+	p := &LTIPlatform{}
+	var deployments string
+	if err := row.Scan(&p.ID, &p.Name, &p.Issuer, &p.ClientID, &p.AuthLoginURL, &p.JWKSURL, &deployments, &p.TrustEmail, &p.CreatedAt); err != nil {
+		return nil, err
+	}
+	if deployments != "" {
+		p.DeploymentIDs = strings.Split(deployments, ",")
+	}
+	return p, nil
+}
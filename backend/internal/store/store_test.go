@@ -1,6 +1,7 @@
 package store
 
 import (
+	"fmt"
 	"os"
 	"testing"
 	"time"
@@ -677,3 +678,851 @@ func TestUpdateRegistration(t *testing.T) {
 		t.Errorf("UpdateRegistration() SeatID = %v, want 5", got.SeatID)
 	}
 }
+
+func TestWorkshopEvents(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	workshop := &Workshop{
+		ID:        "workshop-123",
+		Name:      "Test Workshop",
+		Code:      "ABC123",
+		Seats:     3,
+		Status:    "provisioning",
+		CreatedAt: time.Now(),
+	}
+	store.CreateWorkshop(workshop)
+
+	seatID := 3
+	start := time.Now()
+	events := []*WorkshopEvent{
+		{WorkshopID: workshop.ID, Type: "vm_create_requested", Message: "Creating VM", CreatedAt: start},
+		{WorkshopID: workshop.ID, Type: "seat_created", SeatID: &seatID, Message: "MicroVM created", CreatedAt: start.Add(time.Second)},
+		{WorkshopID: "other-workshop", Type: "error", Message: "unrelated", CreatedAt: start},
+	}
+	for _, e := range events {
+		if err := store.CreateWorkshopEvent(e); err != nil {
+			t.Fatalf("CreateWorkshopEvent() error = %v", err)
+		}
+		if e.ID == 0 {
+			t.Error("CreateWorkshopEvent() should set ID")
+		}
+	}
+
+	got, err := store.ListWorkshopEvents(workshop.ID)
+	if err != nil {
+		t.Fatalf("ListWorkshopEvents() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("ListWorkshopEvents() returned %d events, want 2", len(got))
+	}
+	if got[0].Type != "vm_create_requested" || got[0].SeatID != nil {
+		t.Errorf("first event = %+v, want vm_create_requested without seat", got[0])
+	}
+	if got[1].Type != "seat_created" || got[1].SeatID == nil || *got[1].SeatID != 3 {
+		t.Errorf("second event = %+v, want seat_created for seat 3", got[1])
+	}
+}
+
+func TestResizeWorkshopSeats(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	workshop := &Workshop{
+		ID:        "workshop-123",
+		Name:      "Test Workshop",
+		Code:      "ABC123",
+		Seats:     2,
+		Status:    "running",
+		CreatedAt: time.Now(),
+	}
+	store.CreateWorkshop(workshop)
+	store.CreateSession(&Session{OdeHash: "seat1", WorkshopID: workshop.ID, SeatID: 1, Name: "Alice", Status: "occupied", JoinedAt: time.Now()})
+	store.CreateSession(&Session{OdeHash: "seat2", WorkshopID: workshop.ID, SeatID: 2, Status: "ready", JoinedAt: time.Now()})
+
+	// Grow to 3 seats
+	added := []*Session{{OdeHash: "seat3", WorkshopID: workshop.ID, SeatID: 3, Status: "ready", JoinedAt: time.Now()}}
+	if err := store.ResizeWorkshopSeats(workshop.ID, 3, added); err != nil {
+		t.Fatalf("ResizeWorkshopSeats() grow error = %v", err)
+	}
+	got, _ := store.GetWorkshop(workshop.ID)
+	sessions, _ := store.ListSessions(workshop.ID)
+	if got.Seats != 3 || len(sessions) != 3 {
+		t.Errorf("after grow: seats = %d, sessions = %d, want 3 and 3", got.Seats, len(sessions))
+	}
+
+	// Shrinking below the occupied seat fails without changing anything
+	if err := store.ResizeWorkshopSeats(workshop.ID, 0, nil); err != ErrSeatsOccupied {
+		t.Errorf("ResizeWorkshopSeats() below occupied seat error = %v, want ErrSeatsOccupied", err)
+	}
+	sessions, _ = store.ListSessions(workshop.ID)
+	if len(sessions) != 3 {
+		t.Errorf("failed shrink should not delete sessions, got %d", len(sessions))
+	}
+
+	// Registered learners without a seat yet still need places; revoked and
+	// waitlisted ones do not
+	store.CreateRegistration(&Registration{ID: "reg-1", AccessCode: "CODE-1", Email: "a@example.com", Name: "A", WorkshopID: workshop.ID, Status: "registered", CreatedAt: time.Now()})
+	store.CreateRegistration(&Registration{ID: "reg-2", AccessCode: "CODE-2", Email: "b@example.com", Name: "B", WorkshopID: workshop.ID, Status: "registered", CreatedAt: time.Now()})
+	store.CreateRegistration(&Registration{ID: "reg-3", AccessCode: "CODE-3", Email: "c@example.com", Name: "C", WorkshopID: workshop.ID, Status: "revoked", CreatedAt: time.Now()})
+	store.CreateRegistration(&Registration{ID: "reg-4", AccessCode: "CODE-4", Email: "d@example.com", Name: "D", WorkshopID: workshop.ID, Status: "waitlisted", WaitlistPosition: 1, CreatedAt: time.Now()})
+	if err := store.ResizeWorkshopSeats(workshop.ID, 1, nil); err != ErrTooManyRegistrations {
+		t.Errorf("ResizeWorkshopSeats() below registrations error = %v, want ErrTooManyRegistrations", err)
+	}
+	got, _ = store.GetWorkshop(workshop.ID)
+	if got.Seats != 3 {
+		t.Errorf("failed shrink should not change seats, got %d", got.Seats)
+	}
+	store.UpdateRegistration(&Registration{ID: "reg-2", AccessCode: "CODE-2", Email: "b@example.com", Name: "B", WorkshopID: workshop.ID, Status: "revoked", CreatedAt: time.Now()})
+
+	// Shrink to 1 removes the free seats
+	if err := store.ResizeWorkshopSeats(workshop.ID, 1, nil); err != nil {
+		t.Fatalf("ResizeWorkshopSeats() shrink error = %v", err)
+	}
+	got, _ = store.GetWorkshop(workshop.ID)
+	sessions, _ = store.ListSessions(workshop.ID)
+	if got.Seats != 1 || len(sessions) != 1 || sessions[0].SeatID != 1 {
+		t.Errorf("after shrink: seats = %d, sessions = %v, want only seat 1", got.Seats, sessions)
+	}
+}
+
+func TestQueryObserver(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	var ops []string
+	store.SetQueryObserver(func(operation string, d time.Duration) {
+		ops = append(ops, operation)
+	})
+
+	store.GetWorkshop("missing")
+	store.UpdateWorkshopStatus("missing", "running")
+	// Queries inside transactions are observed too
+	store.ResizeWorkshopSeats("missing", 1, nil)
+
+	want := []string{"select workshops", "update workshops", "select sessions", "select registrations", "delete sessions", "update workshops"}
+	if len(ops) != len(want) {
+		t.Fatalf("observed %v, want %v", ops, want)
+	}
+	for i := range want {
+		if ops[i] != want[i] {
+			t.Errorf("operation[%d] = %q, want %q", i, ops[i], want[i])
+		}
+	}
+}
+
+func TestGetWorkshopStats(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	store.CreateWorkshop(&Workshop{ID: "ws-live", Name: "Live", Code: "LIVE01", Seats: 2, Status: "running", CreatedAt: now})
+	store.CreateWorkshop(&Workshop{ID: "ws-done", Name: "Done", Code: "DONE01", Seats: 1, Status: "stopped", CreatedAt: now})
+	store.CreateSession(&Session{OdeHash: "live-1", WorkshopID: "ws-live", SeatID: 1, Name: "Ada", Status: "occupied", JoinedAt: now})
+	store.CreateSession(&Session{OdeHash: "live-2", WorkshopID: "ws-live", SeatID: 2, Status: "ready", JoinedAt: now})
+	store.CreateSession(&Session{OdeHash: "done-1", WorkshopID: "ws-done", SeatID: 1, Name: "Bob", Status: "occupied", JoinedAt: now})
+	for i, status := range []string{"registered", "revoked", "waitlisted"} {
+		store.CreateRegistration(&Registration{ID: fmt.Sprintf("reg-%d", i), AccessCode: fmt.Sprintf("AC%d", i), Email: fmt.Sprintf("l%d@example.com", i), Name: "Learner", WorkshopID: "ws-live", Status: status, CreatedAt: now})
+	}
+	store.CreateRegistration(&Registration{ID: "reg-done", AccessCode: "ACD", Email: "done@example.com", Name: "Learner", WorkshopID: "ws-done", Status: "registered", CreatedAt: now})
+
+	stats, err := store.GetWorkshopStats()
+	if err != nil {
+		t.Fatalf("GetWorkshopStats() error = %v", err)
+	}
+	if stats.ByStatus["running"] != 1 || stats.ByStatus["stopped"] != 1 || stats.ActiveLearners != 1 || stats.Registrations != 1 {
+		t.Errorf("GetWorkshopStats() = %+v, want 1 running, 1 stopped, 1 learner and 1 registration", stats)
+	}
+}
+
+func TestWorkshopMembers(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store.CreateUser(&User{ID: "owner", Email: "owner@example.com", Role: "instructor", CreatedAt: time.Now()})
+	store.CreateUser(&User{ID: "ta", Email: "ta@example.com", Role: "instructor", CreatedAt: time.Now()})
+	store.CreateWorkshop(&Workshop{ID: "workshop-123", Name: "Shared", Code: "ABC123", Seats: 2, Status: "created", OwnerID: "owner", CreatedAt: time.Now()})
+
+	member := &WorkshopMember{WorkshopID: "workshop-123", UserID: "ta", Role: MemberRoleTA, AddedBy: "owner", CreatedAt: time.Now()}
+	if err := store.AddWorkshopMember(member); err != nil {
+		t.Fatalf("AddWorkshopMember() error = %v", err)
+	}
+
+	got, err := store.GetWorkshopMember("workshop-123", "ta")
+	if err != nil {
+		t.Fatalf("GetWorkshopMember() error = %v", err)
+	}
+	if got == nil || got.Role != MemberRoleTA || got.AddedBy != "owner" {
+		t.Errorf("GetWorkshopMember() = %+v, want ta added by owner", got)
+	}
+	if got, _ := store.GetWorkshopMember("workshop-123", "owner"); got != nil {
+		t.Errorf("GetWorkshopMember() for non-member = %+v, want nil", got)
+	}
+
+	members, _ := store.ListWorkshopMembers("workshop-123")
+	if len(members) != 1 {
+		t.Errorf("ListWorkshopMembers() returned %d members, want 1", len(members))
+	}
+	shared, _ := store.ListWorkshopsByMember("ta")
+	if len(shared) != 1 || shared[0].ID != "workshop-123" {
+		t.Errorf("ListWorkshopsByMember() = %v, want workshop-123", shared)
+	}
+
+	if err := store.RemoveWorkshopMember("workshop-123", "ta"); err != nil {
+		t.Fatalf("RemoveWorkshopMember() error = %v", err)
+	}
+	if got, _ := store.GetWorkshopMember("workshop-123", "ta"); got != nil {
+		t.Error("Member should be gone after RemoveWorkshopMember()")
+	}
+}
+
+func TestTerminalRecordings(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store.CreateWorkshop(&Workshop{ID: "workshop-123", Name: "Recorded", Code: "ABC123", Seats: 2, Status: "stopping", CreatedAt: time.Now()})
+
+	started := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	rec := &TerminalRecording{
+		WorkshopID:  "workshop-123",
+		SeatID:      1,
+		Name:        "seat-1-a.cast",
+		LearnerName: "Ada",
+		SizeBytes:   5,
+		StartedAt:   started,
+		Data:        []byte("hello"),
+		CreatedAt:   time.Now(),
+	}
+	if err := store.CreateTerminalRecording(rec); err != nil {
+		t.Fatalf("CreateTerminalRecording() error = %v", err)
+	}
+	if rec.ID == 0 {
+		t.Error("CreateTerminalRecording() should set ID")
+	}
+
+	// Collecting the same file again is a no-op
+	dup := &TerminalRecording{WorkshopID: "workshop-123", SeatID: 1, Name: "seat-1-a.cast", StartedAt: started, Data: []byte("other"), CreatedAt: time.Now()}
+	if err := store.CreateTerminalRecording(dup); err != nil {
+		t.Fatalf("CreateTerminalRecording() duplicate error = %v", err)
+	}
+	store.CreateTerminalRecording(&TerminalRecording{WorkshopID: "workshop-123", SeatID: 2, Name: "seat-2-b.cast", StartedAt: started.Add(time.Minute), CreatedAt: time.Now()})
+
+	recordings, err := store.ListTerminalRecordings("workshop-123")
+	if err != nil {
+		t.Fatalf("ListTerminalRecordings() error = %v", err)
+	}
+	if len(recordings) != 2 || recordings[0].Name != "seat-1-a.cast" || recordings[1].SeatID != 2 {
+		t.Fatalf("ListTerminalRecordings() = %+v, want seat 1 then seat 2", recordings)
+	}
+	if recordings[0].Data != nil {
+		t.Error("ListTerminalRecordings() should not load data")
+	}
+
+	got, err := store.GetTerminalRecording(rec.ID)
+	if err != nil {
+		t.Fatalf("GetTerminalRecording() error = %v", err)
+	}
+	if got == nil || string(got.Data) != "hello" || got.LearnerName != "Ada" {
+		t.Errorf("GetTerminalRecording() = %+v, want original data", got)
+	}
+	if got, _ := store.GetTerminalRecording(rec.ID + 100); got != nil {
+		t.Errorf("GetTerminalRecording() for unknown ID = %+v, want nil", got)
+	}
+
+	store.DeleteWorkshop("workshop-123")
+	if recordings, _ := store.ListTerminalRecordings("workshop-123"); len(recordings) != 0 {
+		t.Errorf("Recordings should be deleted with the workshop, got %d", len(recordings))
+	}
+}
+
+func TestAnnouncements(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store.CreateWorkshop(&Workshop{ID: "workshop-123", Name: "Announced", Code: "ABC123", Seats: 2, Status: "running", CreatedAt: time.Now()})
+
+	first := &Announcement{WorkshopID: "workshop-123", Message: "Welcome!", AuthorID: "owner", AuthorName: "Olivia", CreatedAt: time.Now()}
+	if err := store.CreateAnnouncement(first); err != nil {
+		t.Fatalf("CreateAnnouncement() error = %v", err)
+	}
+	if first.ID == 0 {
+		t.Error("CreateAnnouncement() should set ID")
+	}
+	store.CreateAnnouncement(&Announcement{WorkshopID: "workshop-123", Message: "Break in 5 minutes", AuthorID: "owner", AuthorName: "Olivia", CreatedAt: time.Now()})
+
+	announcements, err := store.ListAnnouncements("workshop-123")
+	if err != nil {
+		t.Fatalf("ListAnnouncements() error = %v", err)
+	}
+	if len(announcements) != 2 || announcements[0].Message != "Welcome!" || announcements[1].AuthorName != "Olivia" {
+		t.Errorf("ListAnnouncements() = %+v, want both oldest first", announcements)
+	}
+	if other, _ := store.ListAnnouncements("other"); len(other) != 0 {
+		t.Errorf("ListAnnouncements() for another workshop = %d, want 0", len(other))
+	}
+
+	store.DeleteWorkshop("workshop-123")
+	if announcements, _ := store.ListAnnouncements("workshop-123"); len(announcements) != 0 {
+		t.Errorf("Announcements should be deleted with the workshop, got %d", len(announcements))
+	}
+}
+
+func TestHelpRequests(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store.CreateWorkshop(&Workshop{ID: "workshop-123", Name: "Help", Code: "ABC123", Seats: 2, Status: "running", CreatedAt: time.Now()})
+
+	h := &HelpRequest{WorkshopID: "workshop-123", RegistrationID: "reg-1", SeatID: 2, LearnerName: "Alice", Message: "Stuck on step 3", Status: HelpRequestOpen, CreatedAt: time.Now()}
+	if err := store.CreateHelpRequest(h); err != nil {
+		t.Fatalf("CreateHelpRequest() error = %v", err)
+	}
+	if h.ID == 0 {
+		t.Error("CreateHelpRequest() should set ID")
+	}
+	store.CreateHelpRequest(&HelpRequest{WorkshopID: "workshop-123", RegistrationID: "reg-2", SeatID: 1, LearnerName: "Bob", Status: HelpRequestOpen, CreatedAt: time.Now()})
+
+	active, err := store.GetActiveHelpRequest("reg-1")
+	if err != nil || active == nil || active.ID != h.ID {
+		t.Fatalf("GetActiveHelpRequest() = %+v, %v, want request %d", active, err, h.ID)
+	}
+
+	// Only one staff member can claim a request
+	if ok, err := store.ClaimHelpRequest(h.ID, "ta-1", "Tess", time.Now()); err != nil || !ok {
+		t.Fatalf("ClaimHelpRequest() = %v, %v, want true", ok, err)
+	}
+	if ok, _ := store.ClaimHelpRequest(h.ID, "ta-2", "Tom", time.Now()); ok {
+		t.Error("ClaimHelpRequest() on a claimed request should return false")
+	}
+	got, _ := store.GetHelpRequest(h.ID)
+	if got.Status != HelpRequestClaimed || got.ClaimedBy != "ta-1" || got.ClaimedByName != "Tess" || got.ClaimedAt == nil {
+		t.Errorf("GetHelpRequest() after claim = %+v", got)
+	}
+
+	if ok, err := store.ResolveHelpRequest(h.ID, time.Now()); err != nil || !ok {
+		t.Fatalf("ResolveHelpRequest() = %v, %v, want true", ok, err)
+	}
+	if ok, _ := store.ResolveHelpRequest(h.ID, time.Now()); ok {
+		t.Error("ResolveHelpRequest() twice should return false")
+	}
+	if active, _ := store.GetActiveHelpRequest("reg-1"); active != nil {
+		t.Errorf("GetActiveHelpRequest() after resolve = %+v, want nil", active)
+	}
+	if missing, _ := store.GetHelpRequest(9999); missing != nil {
+		t.Errorf("GetHelpRequest() for unknown ID = %+v, want nil", missing)
+	}
+
+	requests, err := store.ListHelpRequests("workshop-123")
+	if err != nil {
+		t.Fatalf("ListHelpRequests() error = %v", err)
+	}
+	if len(requests) != 2 || requests[0].LearnerName != "Alice" || requests[0].Status != HelpRequestResolved || requests[1].Status != HelpRequestOpen {
+		t.Errorf("ListHelpRequests() = %+v, want both oldest first", requests)
+	}
+
+	store.DeleteWorkshop("workshop-123")
+	if requests, _ := store.ListHelpRequests("workshop-123"); len(requests) != 0 {
+		t.Errorf("Help requests should be deleted with the workshop, got %d", len(requests))
+	}
+}
+
+func TestWaitlist(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store.CreateWorkshop(&Workshop{ID: "workshop-123", Name: "Waitlist", Code: "ABC123", Seats: 1, Status: "created", Waitlist: true, CreatedAt: time.Now()})
+	if w, _ := store.GetWorkshop("workshop-123"); !w.Waitlist {
+		t.Error("GetWorkshop() should return Waitlist = true")
+	}
+	if w, _ := store.GetWorkshopByCode("ABC123"); !w.Waitlist {
+		t.Error("GetWorkshopByCode() should return Waitlist = true")
+	}
+	if list, _ := store.ListWorkshops(); len(list) != 1 || !list[0].Waitlist {
+		t.Error("ListWorkshops() should return Waitlist = true")
+	}
+
+	// The setting round-trips through UpdateWorkshop both ways
+	w, _ := store.GetWorkshop("workshop-123")
+	w.Waitlist = false
+	if err := store.UpdateWorkshop(w); err != nil {
+		t.Fatalf("UpdateWorkshop() error = %v", err)
+	}
+	if w, _ := store.GetWorkshop("workshop-123"); w.Waitlist {
+		t.Error("GetWorkshop() after disabling should return Waitlist = false")
+	}
+	w.Waitlist = true
+	store.UpdateWorkshop(w)
+	if w, _ := store.GetWorkshop("workshop-123"); !w.Waitlist {
+		t.Error("GetWorkshop() after re-enabling should return Waitlist = true")
+	}
+
+	store.CreateRegistration(&Registration{ID: "reg-1", AccessCode: "CODE-1", Email: "a@example.com", Name: "A", WorkshopID: "workshop-123", Status: "registered", CreatedAt: time.Now()})
+	store.CreateRegistration(&Registration{ID: "reg-2", AccessCode: "CODE-2", Email: "b@example.com", Name: "B", WorkshopID: "workshop-123", Status: "waitlisted", WaitlistPosition: 1, CreatedAt: time.Now()})
+	store.CreateRegistration(&Registration{ID: "reg-3", AccessCode: "CODE-3", Email: "c@example.com", Name: "C", WorkshopID: "workshop-123", Status: "waitlisted", WaitlistPosition: 2, CreatedAt: time.Now()})
+
+	if count, _ := store.CountRegistrations("workshop-123"); count != 1 {
+		t.Errorf("CountRegistrations() = %d, want 1 (waitlisted excluded)", count)
+	}
+
+	if err := store.ReorderWaitlist("workshop-123", []string{"reg-3", "reg-2"}); err != nil {
+		t.Fatalf("ReorderWaitlist() error = %v", err)
+	}
+	if r, _ := store.GetRegistrationByID("reg-3"); r.WaitlistPosition != 1 {
+		t.Errorf("reg-3 position = %d, want 1", r.WaitlistPosition)
+	}
+	if r, _ := store.GetRegistrationByID("reg-2"); r.WaitlistPosition != 2 {
+		t.Errorf("reg-2 position = %d, want 2", r.WaitlistPosition)
+	}
+
+	// Registrations off the waitlist cannot be reordered, and nothing changes
+	if err := store.ReorderWaitlist("workshop-123", []string{"reg-2", "reg-1"}); err != ErrNotWaitlisted {
+		t.Errorf("ReorderWaitlist() with a registered learner error = %v, want ErrNotWaitlisted", err)
+	}
+	if r, _ := store.GetRegistrationByID("reg-2"); r.WaitlistPosition != 2 {
+		t.Errorf("reg-2 position after failed reorder = %d, want 2", r.WaitlistPosition)
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	startsAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	store.CreateWorkshop(&Workshop{ID: "workshop-123", Name: "Notify", Code: "ABC123", Seats: 1, Status: "created", StartsAt: &startsAt, CreatedAt: time.Now()})
+	if w, _ := store.GetWorkshop("workshop-123"); w.StartsAt == nil || !w.StartsAt.Equal(startsAt) {
+		t.Errorf("GetWorkshop() StartsAt = %v, want %v", w.StartsAt, startsAt)
+	}
+
+	// Templates are upserted per kind
+	store.SetNotificationTemplate(&NotificationTemplate{WorkshopID: "workshop-123", Kind: "reminder", Subject: "Old", Body: "Old", UpdatedAt: time.Now()})
+	store.SetNotificationTemplate(&NotificationTemplate{WorkshopID: "workshop-123", Kind: "reminder", Subject: "New", Body: "New", UpdatedAt: time.Now()})
+	if tmpl, _ := store.GetNotificationTemplate("workshop-123", "reminder"); tmpl == nil || tmpl.Subject != "New" {
+		t.Errorf("GetNotificationTemplate() = %+v, want subject New", tmpl)
+	}
+	if templates, _ := store.ListNotificationTemplates("workshop-123"); len(templates) != 1 {
+		t.Errorf("ListNotificationTemplates() returned %d templates, want 1", len(templates))
+	}
+	store.DeleteNotificationTemplate("workshop-123", "reminder")
+	if tmpl, _ := store.GetNotificationTemplate("workshop-123", "reminder"); tmpl != nil {
+		t.Errorf("GetNotificationTemplate() after delete = %+v, want nil", tmpl)
+	}
+
+	// Each kind is claimed once per registration; failed sends may be claimed again
+	n := &Notification{WorkshopID: "workshop-123", RegistrationID: "reg-1", Kind: "reminder", Recipient: "a@example.com", CreatedAt: time.Now()}
+	if ok, err := store.ClaimNotification(n); !ok || err != nil {
+		t.Fatalf("ClaimNotification() = %v, %v, want true", ok, err)
+	}
+	if ok, _ := store.ClaimNotification(&Notification{WorkshopID: "workshop-123", RegistrationID: "reg-1", Kind: "reminder", Recipient: "a@example.com", CreatedAt: time.Now()}); ok {
+		t.Error("ClaimNotification() of a pending notification should return false")
+	}
+	n.Status = NotificationFailed
+	n.Error = "connection refused"
+	store.UpdateNotification(n)
+	if ok, _ := store.ClaimNotification(n); !ok {
+		t.Error("ClaimNotification() of a failed notification should return true")
+	}
+	now := time.Now()
+	n.Status = NotificationSent
+	n.SentAt = &now
+	store.UpdateNotification(n)
+	if ok, _ := store.ClaimNotification(n); ok {
+		t.Error("ClaimNotification() of a sent notification should return false")
+	}
+
+	notifications, err := store.ListNotifications("workshop-123")
+	if err != nil || len(notifications) != 1 {
+		t.Fatalf("ListNotifications() = %v, %v", notifications, err)
+	}
+	if got := notifications[0]; got.Status != NotificationSent || got.Error != "" || got.SentAt == nil {
+		t.Errorf("ListNotifications()[0] = %+v, want sent without error", got)
+	}
+
+	if err := store.DeleteWorkshop("workshop-123"); err != nil {
+		t.Fatalf("DeleteWorkshop() error = %v", err)
+	}
+	if notifications, _ := store.ListNotifications("workshop-123"); len(notifications) != 0 {
+		t.Errorf("ListNotifications() after DeleteWorkshop() = %d, want 0", len(notifications))
+	}
+}
+
+func TestLoginLinks(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store.CreateWorkshop(&Workshop{ID: "workshop-123", Name: "Login", Code: "ABC123", Seats: 1, Status: "created", CreatedAt: time.Now()})
+	store.CreateRegistration(&Registration{ID: "reg-1", AccessCode: "AAA-1111", Email: "a@example.com", Name: "Ada", WorkshopID: "workshop-123", Status: "registered", CreatedAt: time.Now()})
+
+	if l, err := store.GetLatestLoginLink("reg-1"); l != nil || err != nil {
+		t.Errorf("GetLatestLoginLink() = %+v, %v, want nil", l, err)
+	}
+
+	now := time.Now()
+	store.CreateLoginLink(&LoginLink{ID: "link-1", RegistrationID: "reg-1", WorkshopID: "workshop-123", CreatedAt: now.Add(-time.Minute), ExpiresAt: now.Add(time.Hour)})
+	store.CreateLoginLink(&LoginLink{ID: "link-2", RegistrationID: "reg-1", WorkshopID: "workshop-123", CreatedAt: now, ExpiresAt: now.Add(time.Hour)})
+	store.CreateLoginLink(&LoginLink{ID: "link-old", RegistrationID: "reg-1", WorkshopID: "workshop-123", CreatedAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(-time.Hour)})
+	if l, _ := store.GetLatestLoginLink("reg-1"); l == nil || l.ID != "link-2" {
+		t.Errorf("GetLatestLoginLink() = %+v, want link-2", l)
+	}
+
+	// Links can be used once, before they expire, by their own registration
+	if ok, _ := store.UseLoginLink("link-1", "reg-other", now); ok {
+		t.Error("UseLoginLink() with another registration should return false")
+	}
+	if ok, err := store.UseLoginLink("link-1", "reg-1", now); !ok || err != nil {
+		t.Fatalf("UseLoginLink() = %v, %v, want true", ok, err)
+	}
+	if ok, _ := store.UseLoginLink("link-1", "reg-1", now); ok {
+		t.Error("UseLoginLink() of a used link should return false")
+	}
+	if ok, _ := store.UseLoginLink("link-old", "reg-1", now); ok {
+		t.Error("UseLoginLink() of an expired link should return false")
+	}
+
+	// Expiring a registration's links makes the outstanding ones unusable
+	if err := store.ExpireLoginLinks("reg-1", now); err != nil {
+		t.Fatalf("ExpireLoginLinks() error = %v", err)
+	}
+	if ok, _ := store.UseLoginLink("link-2", "reg-1", now); ok {
+		t.Error("UseLoginLink() after ExpireLoginLinks() should return false")
+	}
+
+	// Rotating the access code keeps everything else about the registration
+	if err := store.UpdateRegistrationAccessCode("reg-1", "BBB-2222"); err != nil {
+		t.Fatalf("UpdateRegistrationAccessCode() error = %v", err)
+	}
+	if r, _ := store.GetRegistration("AAA-1111"); r != nil {
+		t.Errorf("GetRegistration() with the old code = %+v, want nil", r)
+	}
+	if r, _ := store.GetRegistration("BBB-2222"); r == nil || r.ID != "reg-1" || r.Email != "a@example.com" {
+		t.Errorf("GetRegistration() with the new code = %+v, want reg-1", r)
+	}
+
+	if err := store.DeleteWorkshop("workshop-123"); err != nil {
+		t.Fatalf("DeleteWorkshop() error = %v", err)
+	}
+	if l, _ := store.GetLatestLoginLink("reg-1"); l != nil {
+		t.Errorf("GetLatestLoginLink() after DeleteWorkshop() = %+v, want nil", l)
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store.CreateWorkshop(&Workshop{ID: "workshop-123", Name: "Hooks", Code: "ABC123", Seats: 1, Status: "created", CreatedAt: time.Now()})
+	store.CreateWebhook(&Webhook{ID: "wh-1", WorkshopID: "workshop-123", URL: "https://example.com/hook", Secret: "s3cret", Events: []string{"workshop.running", "learner.joined"}, CreatedAt: time.Now()})
+	store.CreateWebhook(&Webhook{ID: "wh-global", URL: "https://example.com/all", Secret: "s3cret", Events: []string{"workshop.error"}, CreatedAt: time.Now()})
+
+	h, err := store.GetWebhook("wh-1")
+	if err != nil || h == nil {
+		t.Fatalf("GetWebhook() = %v, %v", h, err)
+	}
+	if h.Secret != "s3cret" || len(h.Events) != 2 || h.Events[1] != "learner.joined" {
+		t.Errorf("GetWebhook() = %+v", h)
+	}
+	if hooks, _ := store.ListWebhooks("workshop-123"); len(hooks) != 1 || hooks[0].ID != "wh-1" {
+		t.Errorf("ListWebhooks(workshop) = %+v, want wh-1", hooks)
+	}
+	if hooks, _ := store.ListWebhooks(""); len(hooks) != 1 || hooks[0].ID != "wh-global" {
+		t.Errorf("ListWebhooks(global) = %+v, want wh-global", hooks)
+	}
+
+	now := time.Now()
+	due := &WebhookDelivery{WebhookID: "wh-1", WorkshopID: "workshop-123", Event: "workshop.running", Payload: "{}", Status: WebhookDeliveryPending, NextAttemptAt: now.Add(-time.Second), CreatedAt: now}
+	later := &WebhookDelivery{WebhookID: "wh-1", WorkshopID: "workshop-123", Event: "learner.joined", Payload: "{}", Status: WebhookDeliveryPending, NextAttemptAt: now.Add(time.Hour), CreatedAt: now}
+	if err := store.CreateWebhookDelivery(due); err != nil || due.ID == 0 {
+		t.Fatalf("CreateWebhookDelivery() = %v, ID %d", err, due.ID)
+	}
+	store.CreateWebhookDelivery(later)
+
+	deliveries, err := store.ListDueWebhookDeliveries(now, 10)
+	if err != nil || len(deliveries) != 1 || deliveries[0].ID != due.ID {
+		t.Fatalf("ListDueWebhookDeliveries() = %+v, %v, want only the due one", deliveries, err)
+	}
+
+	// A claimed delivery is leased and not due again until the lease ends
+	if ok, err := store.ClaimWebhookDelivery(due.ID, now, now.Add(time.Minute)); !ok || err != nil {
+		t.Fatalf("ClaimWebhookDelivery() = %v, %v, want true", ok, err)
+	}
+	if ok, _ := store.ClaimWebhookDelivery(due.ID, now, now.Add(time.Minute)); ok {
+		t.Error("ClaimWebhookDelivery() of a leased delivery should return false")
+	}
+	if deliveries, _ := store.ListDueWebhookDeliveries(now, 10); len(deliveries) != 0 {
+		t.Errorf("ListDueWebhookDeliveries() while leased = %d, want 0", len(deliveries))
+	}
+
+	due.Status = WebhookDeliveryDelivered
+	due.Attempts = 1
+	due.LastStatusCode = 200
+	due.DeliveredAt = &now
+	store.UpdateWebhookDelivery(due)
+	if d, _ := store.GetWebhookDelivery(due.ID); d == nil || d.Status != WebhookDeliveryDelivered || d.Attempts != 1 || d.DeliveredAt == nil {
+		t.Errorf("GetWebhookDelivery() after update = %+v", d)
+	}
+
+	if deliveries, _ := store.ListWebhookDeliveries("wh-1", 10); len(deliveries) != 2 || deliveries[0].ID != later.ID {
+		t.Errorf("ListWebhookDeliveries() = %+v, want newest first", deliveries)
+	}
+
+	// Deleting the workshop removes its webhooks and their deliveries, but not global ones
+	if err := store.DeleteWorkshop("workshop-123"); err != nil {
+		t.Fatalf("DeleteWorkshop() error = %v", err)
+	}
+	if h, _ := store.GetWebhook("wh-1"); h != nil {
+		t.Errorf("GetWebhook() after DeleteWorkshop() = %+v, want nil", h)
+	}
+	if deliveries, _ := store.ListWebhookDeliveries("wh-1", 10); len(deliveries) != 0 {
+		t.Errorf("ListWebhookDeliveries() after DeleteWorkshop() = %d, want 0", len(deliveries))
+	}
+	if h, _ := store.GetWebhook("wh-global"); h == nil {
+		t.Error("Global webhook should survive DeleteWorkshop()")
+	}
+	store.DeleteWebhook("wh-global")
+	if h, _ := store.GetWebhook("wh-global"); h != nil {
+		t.Errorf("GetWebhook() after DeleteWebhook() = %+v, want nil", h)
+	}
+}
+
+func TestLTIPlatformsAndContexts(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store.CreateWorkshop(&Workshop{ID: "workshop-123", Name: "LTI", Code: "ABC123", Seats: 1, Status: "created", CreatedAt: time.Now()})
+	store.CreateWorkshop(&Workshop{ID: "workshop-456", Name: "LTI 2", Code: "DEF456", Seats: 1, Status: "created", CreatedAt: time.Now()})
+	platform := &LTIPlatform{
+		ID:            "lti-1",
+		Name:          "Canvas",
+		Issuer:        "https://canvas.example.edu",
+		ClientID:      "10000000000001",
+		AuthLoginURL:  "https://canvas.example.edu/api/lti/authorize_redirect",
+		JWKSURL:       "https://canvas.example.edu/api/lti/security/jwks",
+		DeploymentIDs: []string{"1:abc", "2:def"},
+		CreatedAt:     time.Now(),
+	}
+	if err := store.CreateLTIPlatform(platform); err != nil {
+		t.Fatalf("CreateLTIPlatform() error = %v", err)
+	}
+	duplicate := *platform
+	duplicate.ID = "lti-2"
+	if err := store.CreateLTIPlatform(&duplicate); err == nil {
+		t.Error("CreateLTIPlatform() with the same issuer and client ID should fail")
+	}
+
+	p, err := store.GetLTIPlatform("lti-1")
+	if err != nil || p == nil {
+		t.Fatalf("GetLTIPlatform() = %v, %v", p, err)
+	}
+	if p.Issuer != platform.Issuer || len(p.DeploymentIDs) != 2 || p.DeploymentIDs[1] != "2:def" || p.TrustEmail {
+		t.Errorf("GetLTIPlatform() = %+v", p)
+	}
+	if err := store.SetLTIPlatformTrustEmail("lti-1", true); err != nil {
+		t.Fatalf("SetLTIPlatformTrustEmail() error = %v", err)
+	}
+	if platforms, _ := store.ListLTIPlatforms(); len(platforms) != 1 || !platforms[0].TrustEmail {
+		t.Errorf("ListLTIPlatforms() = %+v, want 1 trusting emails", platforms)
+	}
+
+	if c, err := store.GetLTIContext("lti-1", "course-1"); c != nil || err != nil {
+		t.Errorf("GetLTIContext() before SetLTIContext() = %+v, %v, want nil", c, err)
+	}
+	store.SetLTIContext(&LTIContext{PlatformID: "lti-1", ContextID: "course-1", WorkshopID: "workshop-123", Title: "CS 101", UpdatedAt: time.Now()})
+	store.SetLTIContext(&LTIContext{PlatformID: "lti-1", ContextID: "course-1", WorkshopID: "workshop-456", Title: "CS 101", UpdatedAt: time.Now()})
+	if c, _ := store.GetLTIContext("lti-1", "course-1"); c == nil || c.WorkshopID != "workshop-456" {
+		t.Errorf("GetLTIContext() after relinking = %+v, want workshop-456", c)
+	}
+
+	// A course keeps every workshop deep linked into it
+	if l, err := store.GetLTILink("lti-1", "course-1", "workshop-123"); l != nil || err != nil {
+		t.Errorf("GetLTILink() before AddLTILink() = %+v, %v, want nil", l, err)
+	}
+	for _, id := range []string{"workshop-123", "workshop-456", "workshop-123"} {
+		if err := store.AddLTILink(&LTILink{PlatformID: "lti-1", ContextID: "course-1", WorkshopID: id, CreatedAt: time.Now()}); err != nil {
+			t.Fatalf("AddLTILink(%s) error = %v", id, err)
+		}
+	}
+	for _, id := range []string{"workshop-123", "workshop-456"} {
+		if l, _ := store.GetLTILink("lti-1", "course-1", id); l == nil || l.WorkshopID != id {
+			t.Errorf("GetLTILink(%s) = %+v", id, l)
+		}
+	}
+	if l, _ := store.GetLTILink("lti-1", "course-2", "workshop-123"); l != nil {
+		t.Errorf("GetLTILink() for another course = %+v, want nil", l)
+	}
+
+	// Deleting the workshop removes its course links
+	store.DeleteWorkshop("workshop-456")
+	if c, _ := store.GetLTIContext("lti-1", "course-1"); c != nil {
+		t.Errorf("GetLTIContext() after DeleteWorkshop() = %+v, want nil", c)
+	}
+	if l, _ := store.GetLTILink("lti-1", "course-1", "workshop-456"); l != nil {
+		t.Errorf("GetLTILink() after DeleteWorkshop() = %+v, want nil", l)
+	}
+
+	store.SetLTIContext(&LTIContext{PlatformID: "lti-1", ContextID: "course-2", WorkshopID: "workshop-123", UpdatedAt: time.Now()})
+	if err := store.DeleteLTIPlatform("lti-1"); err != nil {
+		t.Fatalf("DeleteLTIPlatform() error = %v", err)
+	}
+	if p, _ := store.GetLTIPlatform("lti-1"); p != nil {
+		t.Errorf("GetLTIPlatform() after delete = %+v, want nil", p)
+	}
+	if c, _ := store.GetLTIContext("lti-1", "course-2"); c != nil {
+		t.Errorf("GetLTIContext() after DeleteLTIPlatform() = %+v, want nil", c)
+	}
+	if l, _ := store.GetLTILink("lti-1", "course-1", "workshop-123"); l != nil {
+		t.Errorf("GetLTILink() after DeleteLTIPlatform() = %+v, want nil", l)
+	}
+}
+
+func TestUserIdentities(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store.CreateUser(&User{ID: "user-1", Email: "ada@example.com", Name: "Ada", Role: "instructor", CreatedAt: time.Now()})
+	if err := store.UpdateUserRole("user-1", "admin"); err != nil {
+		t.Fatalf("UpdateUserRole() error = %v", err)
+	}
+	if u, _ := store.GetUser("user-1"); u == nil || u.Role != "admin" {
+		t.Errorf("GetUser() after UpdateUserRole() = %+v, want admin", u)
+	}
+
+	if i, err := store.GetUserIdentity("google", "sub-1"); i != nil || err != nil {
+		t.Errorf("GetUserIdentity() before linking = %+v, %v, want nil", i, err)
+	}
+	identity := &UserIdentity{Provider: "google", Subject: "sub-1", UserID: "user-1", Email: "ada@example.com", CreatedAt: time.Now()}
+	if err := store.CreateUserIdentity(identity); err != nil {
+		t.Fatalf("CreateUserIdentity() error = %v", err)
+	}
+	// One account per provider and user, and one user per provider account
+	if err := store.CreateUserIdentity(&UserIdentity{Provider: "google", Subject: "sub-2", UserID: "user-1", CreatedAt: time.Now()}); err == nil {
+		t.Error("CreateUserIdentity() with a second google account should fail")
+	}
+	if err := store.CreateUserIdentity(&UserIdentity{Provider: "google", Subject: "sub-1", UserID: "user-2", CreatedAt: time.Now()}); err == nil {
+		t.Error("CreateUserIdentity() with a linked subject should fail")
+	}
+	store.CreateUserIdentity(&UserIdentity{Provider: "okta", Subject: "00u1", UserID: "user-1", CreatedAt: time.Now().Add(time.Second)})
+
+	i, err := store.GetUserIdentity("google", "sub-1")
+	if err != nil || i == nil || i.UserID != "user-1" || i.Email != "ada@example.com" {
+		t.Errorf("GetUserIdentity() = %+v, %v", i, err)
+	}
+	identities, err := store.ListUserIdentities("user-1")
+	if err != nil || len(identities) != 2 || identities[0].Provider != "google" {
+		t.Fatalf("ListUserIdentities() = %+v, %v, want google then okta", identities, err)
+	}
+
+	if err := store.DeleteUserIdentity("user-1", "google"); err != nil {
+		t.Fatalf("DeleteUserIdentity() error = %v", err)
+	}
+	if i, _ := store.GetUserIdentity("google", "sub-1"); i != nil {
+		t.Errorf("GetUserIdentity() after delete = %+v, want nil", i)
+	}
+	if identities, _ := store.ListUserIdentities("user-1"); len(identities) != 1 {
+		t.Errorf("ListUserIdentities() after delete = %d, want 1", len(identities))
+	}
+}
+
+func TestRefreshTokensAndRevocation(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	store.CreateUser(&User{ID: "user-1", Email: "ada@example.com", Name: "Ada", Role: "instructor", CreatedAt: now})
+	if err := store.SetUserDisabled("user-1", true); err != nil {
+		t.Fatalf("SetUserDisabled() error = %v", err)
+	}
+	if u, _ := store.GetUser("user-1"); u == nil || !u.Disabled {
+		t.Errorf("GetUser() after SetUserDisabled() = %+v, want disabled", u)
+	}
+	if u, _ := store.GetUserByEmail("ada@example.com"); u == nil || !u.Disabled {
+		t.Errorf("GetUserByEmail() after SetUserDisabled() = %+v, want disabled", u)
+	}
+
+	if rt, err := store.GetRefreshToken("missing"); rt != nil || err != nil {
+		t.Errorf("GetRefreshToken() for missing token = %+v, %v, want nil", rt, err)
+	}
+	newToken := func(id, session, accessID string, expiresAt time.Time) {
+		t.Helper()
+		err := store.CreateRefreshToken(&RefreshToken{
+			ID: id, SessionID: session, UserID: "user-1", AccessTokenID: accessID,
+			AccessExpiresAt: now.Add(15 * time.Minute), ExpiresAt: expiresAt, CreatedAt: now,
+		})
+		if err != nil {
+			t.Fatalf("CreateRefreshToken(%s) error = %v", id, err)
+		}
+	}
+	newToken("rt-1", "sess-1", "jti-1", now.Add(time.Hour))
+	newToken("rt-2", "sess-1", "jti-2", now.Add(time.Hour))
+	newToken("rt-3", "sess-2", "jti-3", now.Add(time.Hour))
+	newToken("rt-old", "sess-3", "jti-old", now.Add(-time.Minute))
+
+	rt, err := store.GetRefreshToken("rt-1")
+	if err != nil || rt == nil || rt.SessionID != "sess-1" || rt.AccessTokenID != "jti-1" || rt.UsedAt != nil || rt.RevokedAt != nil {
+		t.Fatalf("GetRefreshToken() = %+v, %v", rt, err)
+	}
+
+	// Each refresh token is used once, and never after it expires
+	if used, err := store.UseRefreshToken("rt-1", now); !used || err != nil {
+		t.Errorf("UseRefreshToken() = %v, %v, want true", used, err)
+	}
+	if used, _ := store.UseRefreshToken("rt-1", now); used {
+		t.Error("UseRefreshToken() twice should fail")
+	}
+	if used, _ := store.UseRefreshToken("rt-old", now); used {
+		t.Error("UseRefreshToken() on an expired token should fail")
+	}
+	if rt, _ := store.GetRefreshToken("rt-1"); rt == nil || rt.UsedAt == nil {
+		t.Errorf("GetRefreshToken() after use = %+v, want used_at", rt)
+	}
+
+	// Revoking a session revokes its refresh tokens and their access tokens
+	if err := store.RevokeSession("sess-1", now); err != nil {
+		t.Fatalf("RevokeSession() error = %v", err)
+	}
+	for id, want := range map[string]bool{"jti-1": true, "jti-2": true, "jti-3": false} {
+		if revoked, err := store.IsTokenRevoked(id); revoked != want || err != nil {
+			t.Errorf("IsTokenRevoked(%s) = %v, %v, want %v", id, revoked, err, want)
+		}
+	}
+	if rt, _ := store.GetRefreshToken("rt-2"); rt == nil || rt.RevokedAt == nil {
+		t.Errorf("GetRefreshToken() after RevokeSession() = %+v, want revoked_at", rt)
+	}
+	if used, _ := store.UseRefreshToken("rt-2", now); used {
+		t.Error("UseRefreshToken() on a revoked token should fail")
+	}
+	if err := store.RevokeSession("sess-1", now); err != nil {
+		t.Errorf("RevokeSession() twice error = %v", err)
+	}
+
+	if err := store.RevokeUserSessions("user-1", now); err != nil {
+		t.Fatalf("RevokeUserSessions() error = %v", err)
+	}
+	if revoked, _ := store.IsTokenRevoked("jti-3"); !revoked {
+		t.Error("IsTokenRevoked() after RevokeUserSessions() = false, want true")
+	}
+
+	if err := store.RevokeToken("jti-lone", now.Add(-time.Second), now); err != nil {
+		t.Fatalf("RevokeToken() error = %v", err)
+	}
+	if err := store.RevokeToken("jti-lone", now.Add(-time.Second), now); err != nil {
+		t.Errorf("RevokeToken() twice error = %v", err)
+	}
+	if revoked, _ := store.IsTokenRevoked("jti-lone"); !revoked {
+		t.Error("IsTokenRevoked() after RevokeToken() = false, want true")
+	}
+
+	if err := store.DeleteExpiredTokens(now); err != nil {
+		t.Fatalf("DeleteExpiredTokens() error = %v", err)
+	}
+	if rt, _ := store.GetRefreshToken("rt-old"); rt != nil {
+		t.Errorf("GetRefreshToken() for expired token after cleanup = %+v, want nil", rt)
+	}
+	if rt, _ := store.GetRefreshToken("rt-3"); rt == nil {
+		t.Error("GetRefreshToken() for live token after cleanup = nil")
+	}
+	if revoked, _ := store.IsTokenRevoked("jti-lone"); revoked {
+		t.Error("IsTokenRevoked() for expired token after cleanup = true, want false")
+	}
+	if revoked, _ := store.IsTokenRevoked("jti-3"); !revoked {
+		t.Error("IsTokenRevoked() for live token after cleanup = false, want true")
+	}
+}
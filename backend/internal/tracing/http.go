@@ -0,0 +1,35 @@
+package tracing
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// Middleware starts a server span for each request, continuing any trace
+// context sent by the caller. Spans are named "METHOD /chi/route/{pattern}"
+// once routing has resolved the pattern.
+func Middleware(next http.Handler) http.Handler {
+	return otelhttp.NewHandler(next, "http.request",
+		otelhttp.WithSpanNameFormatter(spanName),
+	)
+}
+
+// spanName names server spans by chi route pattern rather than raw path, so
+// /vms/{workshopID}/{seatID} is one span name regardless of IDs.
+func spanName(_ string, r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+		return r.Method + " " + rctx.RoutePattern()
+	}
+	return r.Method
+}
+
+// Transport wraps base so outgoing requests carry the trace context of their
+// request context. A nil base uses http.DefaultTransport.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return otelhttp.NewTransport(base)
+}
@@ -8,13 +8,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"os/exec"
 	"regexp"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/clarateach/backend/internal/logging"
 )
 
 // Manager handles the cloudflared tunnel lifecycle.
@@ -29,8 +31,9 @@ type Manager struct {
 	registerErr  error
 	registerDone chan struct{}
 	closeOnce    sync.Once
-	ctx          context.Context
+	ctx          context.Context // Carries workshop_id for logging
 	cancel       context.CancelFunc
+	logger       *slog.Logger
 }
 
 // Config holds tunnel manager configuration.
@@ -45,7 +48,7 @@ func NewManager(cfg Config) *Manager {
 	if cfg.LocalPort == 0 {
 		cfg.LocalPort = 9090
 	}
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(logging.WithWorkshop(context.Background(), cfg.WorkshopID))
 	return &Manager{
 		workshopID:   cfg.WorkshopID,
 		backendURL:   strings.TrimSuffix(cfg.BackendURL, "/"),
@@ -53,6 +56,7 @@ func NewManager(cfg Config) *Manager {
 		registerDone: make(chan struct{}),
 		ctx:          ctx,
 		cancel:       cancel,
+		logger:       logging.Component("tunnel"),
 	}
 }
 
@@ -72,9 +76,7 @@ func (m *Manager) Start() error {
 		return fmt.Errorf("backend URL is required")
 	}
 
-	log.Printf("[tunnel] Starting Cloudflare Quick Tunnel for workshop %s", m.workshopID)
-	log.Printf("[tunnel] Backend URL: %s", m.backendURL)
-	log.Printf("[tunnel] Local port: %d", m.localPort)
+	m.logger.InfoContext(m.ctx, "starting Cloudflare Quick Tunnel", "backend_url", m.backendURL, "local_port", m.localPort)
 
 	// Start cloudflared
 	localURL := fmt.Sprintf("http://localhost:%d", m.localPort)
@@ -94,7 +96,7 @@ func (m *Manager) Start() error {
 		return fmt.Errorf("failed to start cloudflared: %w", err)
 	}
 
-	log.Printf("[tunnel] cloudflared started with PID %d", m.cmd.Process.Pid)
+	m.logger.InfoContext(m.ctx, "cloudflared started", "pid", m.cmd.Process.Pid)
 
 	// Process output in goroutines
 	go m.processOutput(stdout, "stdout")
@@ -123,7 +125,7 @@ func (m *Manager) Start() error {
 	go func() {
 		err := m.cmd.Wait()
 		if err != nil && m.ctx.Err() == nil {
-			log.Printf("[tunnel] cloudflared exited with error: %v", err)
+			m.logger.ErrorContext(m.ctx, "cloudflared exited", "error", err)
 			// If cloudflared exits before we capture URL, signal failure
 			m.mu.Lock()
 			if m.tunnelURL == "" {
@@ -146,14 +148,14 @@ func (m *Manager) processOutput(r io.Reader, source string) {
 
 	for scanner.Scan() {
 		line := scanner.Text()
-		log.Printf("[tunnel:%s] %s", source, line)
+		m.logger.DebugContext(m.ctx, line, "source", source)
 
 		// Check if line contains tunnel URL
 		if match := urlPattern.FindString(line); match != "" {
 			m.mu.Lock()
 			if m.tunnelURL == "" {
 				m.tunnelURL = match
-				log.Printf("[tunnel] Captured tunnel URL: %s", m.tunnelURL)
+				m.logger.InfoContext(m.ctx, "captured tunnel URL", "tunnel_url", m.tunnelURL)
 				// Register in background
 				go m.registerTunnelURL()
 			}
@@ -162,7 +164,7 @@ func (m *Manager) processOutput(r io.Reader, source string) {
 	}
 
 	if err := scanner.Err(); err != nil && m.ctx.Err() == nil {
-		log.Printf("[tunnel:%s] scanner error: %v", source, err)
+		m.logger.ErrorContext(m.ctx, "cloudflared output scanner error", "source", source, "error", err)
 	}
 }
 
@@ -178,7 +180,7 @@ func (m *Manager) registerTunnelURL() {
 		m.mu.Lock()
 		m.registerErr = fmt.Errorf("no tunnel URL captured")
 		m.mu.Unlock()
-		log.Printf("[tunnel] ERROR: No tunnel URL to register")
+		m.logger.ErrorContext(m.ctx, "no tunnel URL to register")
 		return
 	}
 
@@ -186,10 +188,13 @@ func (m *Manager) registerTunnelURL() {
 	payload := map[string]string{"tunnel_url": url}
 	body, _ := json.Marshal(payload)
 
-	log.Printf("[tunnel] Registering tunnel URL with backend: %s", endpoint)
+	m.logger.InfoContext(m.ctx, "registering tunnel URL with backend", "endpoint", endpoint)
 
 	// Retry up to 5 times
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: logging.Transport(nil), // Sends X-Workshop-ID for backend log correlation
+	}
 	var lastErr error
 
 	for attempt := 1; attempt <= 5; attempt++ {
@@ -203,7 +208,7 @@ func (m *Manager) registerTunnelURL() {
 		resp, err := client.Do(req)
 		if err != nil {
 			lastErr = err
-			log.Printf("[tunnel] Registration attempt %d failed: %v", attempt, err)
+			m.logger.WarnContext(m.ctx, "tunnel registration attempt failed", "attempt", attempt, "error", err)
 			time.Sleep(2 * time.Second)
 			continue
 		}
@@ -215,19 +220,19 @@ func (m *Manager) registerTunnelURL() {
 			m.mu.Lock()
 			m.registered = true
 			m.mu.Unlock()
-			log.Printf("[tunnel] Successfully registered tunnel URL")
+			m.logger.InfoContext(m.ctx, "registered tunnel URL")
 			return
 		}
 
 		lastErr = fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(respBody))
-		log.Printf("[tunnel] Registration attempt %d failed: %v", attempt, lastErr)
+		m.logger.WarnContext(m.ctx, "tunnel registration attempt failed", "attempt", attempt, "error", lastErr)
 		time.Sleep(2 * time.Second)
 	}
 
 	m.mu.Lock()
 	m.registerErr = fmt.Errorf("failed after 5 attempts: %w", lastErr)
 	m.mu.Unlock()
-	log.Printf("[tunnel] ERROR: Failed to register tunnel URL after 5 attempts: %v", lastErr)
+	m.logger.ErrorContext(m.ctx, "failed to register tunnel URL after 5 attempts", "error", lastErr)
 }
 
 // WaitForRegistration blocks until tunnel registration completes or timeout.
@@ -262,7 +267,7 @@ func (m *Manager) IsRegistered() bool {
 
 // Stop terminates the cloudflared process.
 func (m *Manager) Stop() {
-	log.Printf("[tunnel] Stopping tunnel manager")
+	m.logger.InfoContext(m.ctx, "stopping tunnel manager")
 	m.cancel()
 	if m.cmd != nil && m.cmd.Process != nil {
 		m.cmd.Process.Kill()
@@ -0,0 +1,97 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// errPrivateAddress is returned when an endpoint resolves to an address the
+// sender is not allowed to reach.
+var errPrivateAddress = errors.New("webhook endpoint resolves to a private address")
+
+// Sender posts signed deliveries.
+type Sender struct {
+	client *http.Client
+}
+
+// NewSender creates a sender. Unless allowPrivate is set, endpoints on
+// loopback, private and link-local addresses (such as the cloud metadata
+// server) are refused; the check is made on the resolved address when
+// connecting, so DNS cannot be used to get around it.
+func NewSender(allowPrivate bool) *Sender {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+				return errPrivateAddress
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &Sender{client: &http.Client{
+		Transport: transport,
+		Timeout:   15 * time.Second,
+		// Redirects could lead anywhere; endpoints must answer directly
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+// Send posts body to endpoint, signed with secret. It returns the response
+// status code (0 if there was none) and an error unless the endpoint
+// answered 2xx.
+func (s *Sender) Send(ctx context.Context, endpoint, secret, event, deliveryID string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ClaraTeach-Webhook/1")
+	req.Header.Set(HeaderEvent, event)
+	req.Header.Set(HeaderDelivery, deliveryID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(secret, now, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		return resp.StatusCode, nil
+	}
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+	return resp.StatusCode, fmt.Errorf("endpoint returned %s: %s", resp.Status, bytes.TrimSpace(snippet))
+}
+
+// ValidateURL checks that endpoint is an absolute http or https URL.
+func ValidateURL(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url must be an absolute http or https URL")
+	}
+	if u.User != nil {
+		return errors.New("url must not contain credentials")
+	}
+	return nil
+}
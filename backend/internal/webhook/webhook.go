@@ -0,0 +1,89 @@
+// Package webhook delivers workshop lifecycle events to HTTP endpoints
+// registered by instructors and admins.
+//
+// Each delivery is a JSON POST signed with the endpoint's secret: the
+// X-ClaraTeach-Signature header is "sha256=" followed by the hex HMAC-SHA256
+// of the X-ClaraTeach-Timestamp header, a ".", and the request body. The API
+// server queues deliveries in the database and retries failed ones with
+// exponential backoff.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+// Event types an endpoint may subscribe to.
+const (
+	EventWorkshopRunning   = "workshop.running"   // Data: name, code, status
+	EventWorkshopError     = "workshop.error"     // Data: name, code, status
+	EventWorkshopStopped   = "workshop.stopped"   // Data: name, code, status
+	EventLearnerRegistered = "learner.registered" // Data: registration_id, name, email
+	EventLearnerJoined     = "learner.joined"     // Data: name, seat_id
+)
+
+// Events lists every event type.
+var Events = []string{EventWorkshopRunning, EventWorkshopError, EventWorkshopStopped, EventLearnerRegistered, EventLearnerJoined}
+
+// ValidEvent reports whether event is a known event type.
+func ValidEvent(event string) bool {
+	for _, e := range Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Request headers sent with every delivery.
+const (
+	HeaderEvent     = "X-ClaraTeach-Event"
+	HeaderDelivery  = "X-ClaraTeach-Delivery"
+	HeaderTimestamp = "X-ClaraTeach-Timestamp"
+	HeaderSignature = "X-ClaraTeach-Signature"
+)
+
+// Payload is the JSON body of a delivery.
+type Payload struct {
+	ID         string                 `json:"id"` // Same for every endpoint the event is sent to
+	Event      string                 `json:"event"`
+	WorkshopID string                 `json:"workshop_id"`
+	Time       time.Time              `json:"time"`
+	Data       map[string]interface{} `json:"data"`
+}
+
+// MaxAttempts is how many times a delivery is tried before it is marked
+// failed.
+const MaxAttempts = 8
+
+// Backoff returns how long to wait after the given failed attempt (1-based)
+// before trying again: 30 seconds, doubling each time, at most an hour.
+func Backoff(attempt int) time.Duration {
+	d := 30 * time.Second
+	for i := 1; i < attempt && d < time.Hour; i++ {
+		d *= 2
+	}
+	return min(d, time.Hour)
+}
+
+// Sign returns the X-ClaraTeach-Signature value for body sent at timestamp.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// GenerateSecret returns a random signing secret.
+func GenerateSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
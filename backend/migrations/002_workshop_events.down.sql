@@ -0,0 +1,6 @@
+-- Migration: 002_workshop_events (rollback)
+
+DROP INDEX IF EXISTS idx_workshop_events_workshop_id;
+DROP TABLE IF EXISTS workshop_events;
+
+DELETE FROM schema_migrations WHERE version = 2;
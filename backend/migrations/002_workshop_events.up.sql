@@ -0,0 +1,17 @@
+-- Migration: 002_workshop_events
+-- Description: Per-workshop provisioning timeline events
+
+CREATE TABLE IF NOT EXISTS workshop_events (
+    id BIGSERIAL PRIMARY KEY,
+    workshop_id TEXT NOT NULL,
+    type TEXT NOT NULL,
+    seat_id INTEGER,
+    message TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(workshop_id) REFERENCES workshops(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_workshop_events_workshop_id ON workshop_events(workshop_id);
+
+-- Record this migration
+INSERT INTO schema_migrations (version) VALUES (2) ON CONFLICT DO NOTHING;
@@ -0,0 +1,6 @@
+-- Migration: 003_workshop_members (rollback)
+
+DROP INDEX IF EXISTS idx_workshop_members_user_id;
+DROP TABLE IF EXISTS workshop_members;
+
+DELETE FROM schema_migrations WHERE version = 3;
@@ -0,0 +1,18 @@
+-- Migration: 003_workshop_members
+-- Description: Co-instructors and teaching assistants shared on a workshop
+
+CREATE TABLE IF NOT EXISTS workshop_members (
+    workshop_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    role TEXT NOT NULL,
+    added_by TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (workshop_id, user_id),
+    FOREIGN KEY(workshop_id) REFERENCES workshops(id),
+    FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_workshop_members_user_id ON workshop_members(user_id);
+
+-- Record this migration
+INSERT INTO schema_migrations (version) VALUES (3) ON CONFLICT DO NOTHING;
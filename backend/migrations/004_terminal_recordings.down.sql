@@ -0,0 +1,5 @@
+-- Migration: 004_terminal_recordings (rollback)
+
+DROP TABLE IF EXISTS terminal_recordings;
+
+DELETE FROM schema_migrations WHERE version = 4;
@@ -0,0 +1,19 @@
+-- Migration: 004_terminal_recordings
+-- Description: Learner terminal recordings collected when a workshop stops
+
+CREATE TABLE IF NOT EXISTS terminal_recordings (
+    id BIGSERIAL PRIMARY KEY,
+    workshop_id TEXT NOT NULL,
+    seat_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    learner_name TEXT NOT NULL DEFAULT '',
+    size_bytes BIGINT NOT NULL DEFAULT 0,
+    started_at TIMESTAMP NOT NULL,
+    data BYTEA,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(workshop_id, name),
+    FOREIGN KEY(workshop_id) REFERENCES workshops(id)
+);
+
+-- Record this migration
+INSERT INTO schema_migrations (version) VALUES (4) ON CONFLICT DO NOTHING;
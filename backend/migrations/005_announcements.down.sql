@@ -0,0 +1,6 @@
+-- Migration: 005_announcements (rollback)
+
+DROP INDEX IF EXISTS idx_announcements_workshop_id;
+DROP TABLE IF EXISTS announcements;
+
+DELETE FROM schema_migrations WHERE version = 5;
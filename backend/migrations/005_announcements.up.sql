@@ -0,0 +1,17 @@
+-- Migration: 005_announcements
+-- Description: Staff announcements broadcast to every learner in a workshop
+
+CREATE TABLE IF NOT EXISTS announcements (
+    id BIGSERIAL PRIMARY KEY,
+    workshop_id TEXT NOT NULL,
+    message TEXT NOT NULL,
+    author_id TEXT,
+    author_name TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(workshop_id) REFERENCES workshops(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_announcements_workshop_id ON announcements(workshop_id);
+
+-- Record this migration
+INSERT INTO schema_migrations (version) VALUES (5) ON CONFLICT DO NOTHING;
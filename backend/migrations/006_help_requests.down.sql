@@ -0,0 +1,7 @@
+-- Migration: 006_help_requests (rollback)
+
+DROP INDEX IF EXISTS idx_help_requests_registration_id;
+DROP INDEX IF EXISTS idx_help_requests_workshop_id;
+DROP TABLE IF EXISTS help_requests;
+
+DELETE FROM schema_migrations WHERE version = 6;
@@ -0,0 +1,24 @@
+-- Migration: 006_help_requests
+-- Description: Learner help requests queued for workshop staff
+
+CREATE TABLE IF NOT EXISTS help_requests (
+    id BIGSERIAL PRIMARY KEY,
+    workshop_id TEXT NOT NULL,
+    registration_id TEXT NOT NULL,
+    seat_id INTEGER NOT NULL,
+    learner_name TEXT NOT NULL DEFAULT '',
+    message TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open',
+    claimed_by TEXT,
+    claimed_by_name TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    claimed_at TIMESTAMP,
+    resolved_at TIMESTAMP,
+    FOREIGN KEY(workshop_id) REFERENCES workshops(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_help_requests_workshop_id ON help_requests(workshop_id);
+CREATE INDEX IF NOT EXISTS idx_help_requests_registration_id ON help_requests(registration_id);
+
+-- Record this migration
+INSERT INTO schema_migrations (version) VALUES (6) ON CONFLICT DO NOTHING;
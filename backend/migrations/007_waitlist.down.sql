@@ -0,0 +1,6 @@
+-- Migration: 007_waitlist (rollback)
+
+ALTER TABLE registrations DROP COLUMN IF EXISTS waitlist_position;
+ALTER TABLE workshops DROP COLUMN IF EXISTS waitlist;
+
+DELETE FROM schema_migrations WHERE version = 7;
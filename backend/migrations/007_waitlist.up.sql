@@ -0,0 +1,8 @@
+-- Migration: 007_waitlist
+-- Description: Optional waitlist for full workshops
+
+ALTER TABLE workshops ADD COLUMN IF NOT EXISTS waitlist BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE registrations ADD COLUMN IF NOT EXISTS waitlist_position INTEGER NOT NULL DEFAULT 0;
+
+-- Record this migration
+INSERT INTO schema_migrations (version) VALUES (7) ON CONFLICT DO NOTHING;
@@ -0,0 +1,8 @@
+-- Migration: 008_notifications (rollback)
+
+DROP INDEX IF EXISTS idx_notifications_workshop_id;
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS notification_templates;
+ALTER TABLE workshops DROP COLUMN IF EXISTS starts_at;
+
+DELETE FROM schema_migrations WHERE version = 8;
@@ -0,0 +1,32 @@
+-- Migration: 008_notifications
+-- Description: Learner email templates, sent log and scheduled start times
+
+ALTER TABLE workshops ADD COLUMN IF NOT EXISTS starts_at TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS notification_templates (
+    workshop_id TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    body TEXT NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY(workshop_id, kind),
+    FOREIGN KEY(workshop_id) REFERENCES workshops(id)
+);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    workshop_id TEXT NOT NULL,
+    registration_id TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    recipient TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    sent_at TIMESTAMP,
+    PRIMARY KEY(registration_id, kind),
+    FOREIGN KEY(workshop_id) REFERENCES workshops(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_workshop_id ON notifications(workshop_id);
+
+-- Record this migration
+INSERT INTO schema_migrations (version) VALUES (8) ON CONFLICT DO NOTHING;
@@ -0,0 +1,6 @@
+-- Migration: 009_login_links (rollback)
+
+DROP INDEX IF EXISTS idx_login_links_registration_id;
+DROP TABLE IF EXISTS login_links;
+
+DELETE FROM schema_migrations WHERE version = 9;
@@ -0,0 +1,18 @@
+-- Migration: 009_login_links
+-- Description: One-time login links emailed to learners
+
+CREATE TABLE IF NOT EXISTS login_links (
+    id TEXT PRIMARY KEY,
+    registration_id TEXT NOT NULL,
+    workshop_id TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    FOREIGN KEY(workshop_id) REFERENCES workshops(id),
+    FOREIGN KEY(registration_id) REFERENCES registrations(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_links_registration_id ON login_links(registration_id);
+
+-- Record this migration
+INSERT INTO schema_migrations (version) VALUES (9) ON CONFLICT DO NOTHING;
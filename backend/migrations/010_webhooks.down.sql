@@ -0,0 +1,9 @@
+-- Migration: 010_webhooks (rollback)
+
+DROP INDEX IF EXISTS idx_webhook_deliveries_due;
+DROP INDEX IF EXISTS idx_webhook_deliveries_webhook_id;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP INDEX IF EXISTS idx_webhooks_workshop_id;
+DROP TABLE IF EXISTS webhooks;
+
+DELETE FROM schema_migrations WHERE version = 10;
@@ -0,0 +1,7 @@
+-- Migration: 011_lti (rollback)
+
+DROP INDEX IF EXISTS idx_lti_contexts_workshop_id;
+DROP TABLE IF EXISTS lti_contexts;
+DROP TABLE IF EXISTS lti_platforms;
+
+DELETE FROM schema_migrations WHERE version = 11;
@@ -0,0 +1,30 @@
+-- Migration: 011_lti
+-- Description: LTI 1.3 platform registrations and LMS course to workshop links
+
+CREATE TABLE IF NOT EXISTS lti_platforms (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    issuer TEXT NOT NULL,
+    client_id TEXT NOT NULL,
+    auth_login_url TEXT NOT NULL,
+    jwks_url TEXT NOT NULL,
+    deployment_ids TEXT NOT NULL DEFAULT '', -- Comma-separated; empty accepts every deployment
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(issuer, client_id)
+);
+
+CREATE TABLE IF NOT EXISTS lti_contexts (
+    platform_id TEXT NOT NULL,
+    context_id TEXT NOT NULL,
+    workshop_id TEXT NOT NULL,
+    title TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (platform_id, context_id),
+    FOREIGN KEY(platform_id) REFERENCES lti_platforms(id),
+    FOREIGN KEY(workshop_id) REFERENCES workshops(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_lti_contexts_workshop_id ON lti_contexts(workshop_id);
+
+-- Record this migration
+INSERT INTO schema_migrations (version) VALUES (11) ON CONFLICT DO NOTHING;
@@ -0,0 +1,16 @@
+-- Migration: 012_user_identities
+-- Description: Links from staff accounts to OIDC provider accounts for single sign-on
+
+CREATE TABLE IF NOT EXISTS user_identities (
+    provider TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    email TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (provider, subject),
+    UNIQUE(user_id, provider),
+    FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+-- Record this migration
+INSERT INTO schema_migrations (version) VALUES (12) ON CONFLICT DO NOTHING;
@@ -0,0 +1,7 @@
+-- Migration: 013_refresh_tokens (rollback)
+
+DROP TABLE IF EXISTS revoked_tokens;
+DROP TABLE IF EXISTS refresh_tokens;
+ALTER TABLE users DROP COLUMN IF EXISTS disabled;
+
+DELETE FROM schema_migrations WHERE version = 13;
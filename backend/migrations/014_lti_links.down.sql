@@ -0,0 +1,6 @@
+-- Migration: 014_lti_links (rollback)
+
+DROP INDEX IF EXISTS idx_lti_links_workshop_id;
+DROP TABLE IF EXISTS lti_links;
+
+DELETE FROM schema_migrations WHERE version = 14;
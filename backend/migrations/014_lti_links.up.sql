@@ -0,0 +1,17 @@
+-- Migration: 014_lti_links
+-- Description: Workshops deep linked into LMS courses, to check resource link launches against
+
+CREATE TABLE IF NOT EXISTS lti_links (
+    platform_id TEXT NOT NULL,
+    context_id TEXT NOT NULL,
+    workshop_id TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (platform_id, context_id, workshop_id),
+    FOREIGN KEY(platform_id) REFERENCES lti_platforms(id),
+    FOREIGN KEY(workshop_id) REFERENCES workshops(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_lti_links_workshop_id ON lti_links(workshop_id);
+
+-- Record this migration
+INSERT INTO schema_migrations (version) VALUES (14) ON CONFLICT DO NOTHING;
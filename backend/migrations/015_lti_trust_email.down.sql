@@ -0,0 +1,5 @@
+-- Migration: 015_lti_trust_email (rollback)
+
+ALTER TABLE lti_platforms DROP COLUMN IF EXISTS trust_email;
+
+DELETE FROM schema_migrations WHERE version = 15;
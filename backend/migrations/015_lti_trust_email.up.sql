@@ -0,0 +1,7 @@
+-- Migration: 015_lti_trust_email
+-- Description: Per-platform trust in the LMS email claim for deep linking
+
+ALTER TABLE lti_platforms ADD COLUMN IF NOT EXISTS trust_email BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Record this migration
+INSERT INTO schema_migrations (version) VALUES (15) ON CONFLICT DO NOTHING;
@@ -8,8 +8,12 @@ import { Register } from './pages/Register';
 import { Registered } from './pages/Registered';
 import { SessionWorkspace } from './pages/SessionWorkspace';
 import { Workspace } from './pages/Workspace';
+import { Account } from './pages/Account';
 import { Admin } from './pages/Admin';
+import { AuthCallback } from './pages/AuthCallback';
 import { Login } from './pages/Login';
+import { LoginLink } from './pages/LoginLink';
+import { LtiDeepLink } from './pages/LtiDeepLink';
 import { Signup } from './pages/Signup';
 
 export default function App() {
@@ -18,7 +22,10 @@ export default function App() {
       <Routes>
         <Route path="/" element={<Landing />} />
         <Route path="/login" element={<Login />} />
+        <Route path="/login/:token" element={<LoginLink />} />
         <Route path="/signup" element={<Signup />} />
+        <Route path="/auth/callback" element={<AuthCallback />} />
+        <Route path="/account" element={<Account />} />
         <Route path="/dashboard" element={<Dashboard />} />
         <Route path="/workshop/:id" element={<WorkshopView />} />
         <Route path="/join" element={<Join />} />
@@ -27,6 +34,7 @@ export default function App() {
         <Route path="/s/:code" element={<SessionWorkspace />} />
         <Route path="/workspace" element={<Workspace />} />
         <Route path="/admin" element={<Admin />} />
+        <Route path="/lti/deep-link/:token" element={<LtiDeepLink />} />
       </Routes>
     </AuthProvider>
   );
@@ -1,23 +1,54 @@
+import { useState, useEffect } from 'react';
 import { Globe, RefreshCw, ExternalLink } from 'lucide-react';
 import { getWorkspaceSession } from '../lib/workspaceSession';
+import { api } from '@/lib/api';
+
+// Default port for previews: Vite's dev server. The agent only proxies the
+// ports on its PREVIEW_PORTS allowlist.
+const DEFAULT_PREVIEW_PORT = '5173';
 
 export function Browser() {
   const session = getWorkspaceSession();
+  const isPreview = session?.runtime_type === 'firecracker' && !!session.workshop_id;
+  const [port, setPort] = useState(DEFAULT_PREVIEW_PORT);
+  // Previews take their own token, scoped to this seat and port, never the
+  // workspace token
+  const [previewToken, setPreviewToken] = useState('');
+
+  useEffect(() => {
+    setPreviewToken('');
+    if (!isPreview || !session?.code || !port) return;
+    let cancelled = false;
+    const timer = setTimeout(() => {
+      api
+        .createPreviewToken(session.code!, Number(port))
+        .then((res) => {
+          if (!cancelled) setPreviewToken(res.token);
+        })
+        .catch(() => {});
+    }, 300);
+    return () => {
+      cancelled = true;
+      clearTimeout(timer);
+    };
+  }, [isPreview, session?.code, port]);
+
   let baseUrl = '';
   if (session) {
     const url = new URL(session.endpoint);
     // Build path based on runtime type
-    if (session.runtime_type === 'firecracker' && session.workshop_id) {
-      // Firecracker proxy: /proxy/{workshopID}/{seatID}/browser/
-      baseUrl = `${url.origin}/proxy/${session.workshop_id}/${session.seat}/browser/`;
+    if (isPreview) {
+      // Firecracker preview proxy: /proxy/{workshopID}/{seatID}/preview/{port}/
+      baseUrl = `${url.origin}/proxy/${session.workshop_id}/${session.seat}/preview/${port}/`;
     } else {
       // Docker workspace: /vm/{seat}/browser/
       const basePath = url.pathname === '/' ? '' : url.pathname;
       baseUrl = `${url.origin}${basePath}/vm/${session.seat}/browser/`;
     }
   }
-  const tokenParam = session?.token ? `?token=${encodeURIComponent(session.token)}` : '';
-  const browserUrl = `${baseUrl}${tokenParam}`;
+  const token = isPreview ? previewToken : session?.token;
+  const tokenParam = token ? `?token=${encodeURIComponent(token)}` : '';
+  const browserUrl = isPreview && !previewToken ? 'about:blank' : `${baseUrl}${tokenParam}`;
 
   const handleRefresh = () => {
     const iframe = document.getElementById('neko-frame') as HTMLIFrameElement;
@@ -37,6 +68,17 @@ export function Browser() {
         <div className="flex items-center gap-2">
           <Globe className="w-4 h-4 text-vscode-text" />
           <span className="text-vscode-text text-sm">Browser Preview</span>
+          {isPreview && (
+            <label className="flex items-center gap-1 text-xs text-gray-400">
+              port
+              <input
+                value={port}
+                onChange={(e) => setPort(e.target.value.replace(/\D/g, ''))}
+                className="w-16 bg-vscode-bg border border-vscode-border rounded px-1 text-vscode-text"
+                title="Port your app listens on inside the workspace"
+              />
+            </label>
+          )}
         </div>
         <div className="flex items-center gap-2">
           <button
@@ -70,7 +112,7 @@ export function Browser() {
       {/* Info bar */}
       <div className="bg-vscode-sidebar border-t border-vscode-border px-4 py-1 flex items-center justify-between flex-shrink-0">
         <span className="text-xs text-gray-500">
-          neko browser preview
+          {isPreview ? `app preview on port ${port}` : 'neko browser preview'}
         </span>
         <span className="text-xs text-gray-500">
           {session ? session.endpoint : 'missing workspace session'}
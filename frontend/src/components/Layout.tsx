@@ -1,6 +1,6 @@
 import { ReactNode } from 'react';
 import { useNavigate, Link } from 'react-router-dom';
-import { GraduationCap, LogOut, User, Settings } from 'lucide-react';
+import { GraduationCap, LogOut, User, Settings, KeyRound } from 'lucide-react';
 import { useAuth } from '@/lib/auth';
 import { Button } from '@/components/ui/button';
 import {
@@ -66,6 +66,10 @@ export function Layout({ children, title, showAuth = true }: LayoutProps) {
                         <Settings className="w-4 h-4 mr-2" />
                         Dashboard
                       </DropdownMenuItem>
+                      <DropdownMenuItem onClick={() => navigate('/account')}>
+                        <KeyRound className="w-4 h-4 mr-2" />
+                        Sign-in methods
+                      </DropdownMenuItem>
                       {isAdmin && (
                         <DropdownMenuItem onClick={() => navigate('/admin')}>
                           <Settings className="w-4 h-4 mr-2" />
@@ -0,0 +1,146 @@
+import { useState, useEffect } from 'react';
+import { GraduationCap, Trash2 } from 'lucide-react';
+import { Button } from '@/components/ui/button';
+import { Card, CardContent, CardHeader, CardTitle } from '@/components/ui/card';
+import { api, type LTIPlatform, type LTIToolInfo } from '@/lib/api';
+
+const emptyForm = { name: '', issuer: '', client_id: '', auth_login_url: '', jwks_url: '', deployment_ids: '' };
+
+// Registers the LMSs allowed to launch workshops over LTI 1.3, and shows the
+// URLs to register ClaraTeach with on them.
+export function LtiPlatformsCard() {
+  const [platforms, setPlatforms] = useState<LTIPlatform[]>([]);
+  const [tool, setTool] = useState<LTIToolInfo | null>(null);
+  const [form, setForm] = useState(emptyForm);
+  const [trustEmail, setTrustEmail] = useState(false);
+
+  useEffect(() => {
+    loadPlatforms();
+  }, []);
+
+  const loadPlatforms = async () => {
+    try {
+      const data = await api.listLTIPlatforms();
+      setPlatforms(data.platforms);
+      setTool(data.tool);
+    } catch (err) {
+      console.error('Failed to load LTI platforms:', err);
+    }
+  };
+
+  const handleCreate = async (e: React.FormEvent) => {
+    e.preventDefault();
+    try {
+      await api.createLTIPlatform({
+        name: form.name.trim(),
+        issuer: form.issuer.trim(),
+        client_id: form.client_id.trim(),
+        auth_login_url: form.auth_login_url.trim(),
+        jwks_url: form.jwks_url.trim(),
+        deployment_ids: form.deployment_ids.split(',').map((d) => d.trim()).filter(Boolean),
+        trust_email: trustEmail,
+      });
+      setForm(emptyForm);
+      setTrustEmail(false);
+      loadPlatforms();
+    } catch (err) {
+      alert(err instanceof Error ? err.message : 'Failed to add platform');
+    }
+  };
+
+  const handleTrustEmail = async (platform: LTIPlatform, trust: boolean) => {
+    try {
+      await api.setLTIPlatformTrustEmail(platform.id, trust);
+      loadPlatforms();
+    } catch (err) {
+      alert(err instanceof Error ? err.message : 'Failed to update platform');
+    }
+  };
+
+  const handleDelete = async (platform: LTIPlatform) => {
+    if (!confirm(`Remove ${platform.name}? Launches from it will stop working.`)) return;
+    try {
+      await api.deleteLTIPlatform(platform.id);
+      loadPlatforms();
+    } catch (err) {
+      alert(err instanceof Error ? err.message : 'Failed to remove platform');
+    }
+  };
+
+  const field = (key: keyof typeof emptyForm, placeholder: string, required = true) => (
+    <input
+      placeholder={placeholder}
+      value={form[key]}
+      onChange={(e) => setForm({ ...form, [key]: e.target.value })}
+      className="w-full rounded-md border border-gray-300 px-3 py-2 text-sm focus:outline-none focus:ring-2 focus:ring-indigo-500"
+      required={required}
+    />
+  );
+
+  return (
+    <Card className="mt-6">
+      <CardHeader>
+        <div className="flex items-center gap-2">
+          <GraduationCap className="w-5 h-5" />
+          <CardTitle>LMS Integration (LTI 1.3)</CardTitle>
+        </div>
+      </CardHeader>
+      <CardContent className="space-y-4">
+        {tool && !tool.enabled && (
+          <p className="text-sm text-amber-700">
+            LTI is off. Set LTI_PRIVATE_KEY, BACKEND_URL and PUBLIC_URL on the server to enable launches.
+          </p>
+        )}
+        {tool?.enabled && (
+          <div className="p-3 bg-gray-50 rounded-lg text-sm space-y-1">
+            <p className="text-gray-700">Register ClaraTeach on your LMS with:</p>
+            <p>Login URL: <span className="font-mono break-all">{tool.login_url}</span></p>
+            <p>Redirect URL: <span className="font-mono break-all">{tool.launch_url}</span></p>
+            <p>Public key set: <span className="font-mono break-all">{tool.jwks_url}</span></p>
+          </div>
+        )}
+
+        <div className="space-y-2">
+          {platforms.map((platform) => (
+            <div key={platform.id} className="flex items-center justify-between gap-2 p-3 bg-gray-50 rounded-lg">
+              <div className="min-w-0">
+                <p className="font-medium text-gray-900">{platform.name}</p>
+                <p className="text-sm text-gray-500 truncate">
+                  {platform.issuer} · client {platform.client_id}
+                  {platform.deployment_ids?.length ? ` · ${platform.deployment_ids.length} deployments` : ''}
+                </p>
+              </div>
+              <div className="flex items-center gap-2 shrink-0">
+                <label className="flex items-center gap-1 text-sm text-gray-600">
+                  <input
+                    type="checkbox"
+                    checked={platform.trust_email}
+                    onChange={(e) => handleTrustEmail(platform, e.target.checked)}
+                  />
+                  Trust emails
+                </label>
+                <Button size="icon" variant="ghost" onClick={() => handleDelete(platform)}>
+                  <Trash2 className="w-4 h-4" />
+                </Button>
+              </div>
+            </div>
+          ))}
+        </div>
+
+        <form onSubmit={handleCreate} className="space-y-2">
+          {field('name', 'Name, e.g. Canvas', false)}
+          {field('issuer', 'Issuer, e.g. https://canvas.instructure.com')}
+          {field('client_id', 'Client ID')}
+          {field('auth_login_url', 'Authorization URL')}
+          {field('jwks_url', 'Public key set URL')}
+          {field('deployment_ids', 'Deployment IDs, comma-separated (optional)', false)}
+          <label className="flex items-center gap-2 text-sm text-gray-600">
+            <input type="checkbox" checked={trustEmail} onChange={(e) => setTrustEmail(e.target.checked)} />
+            Trust LMS emails, so instructors can add workshops to courses as the account with their LMS email
+          </label>
+          <Button type="submit">Add platform</Button>
+        </form>
+      </CardContent>
+    </Card>
+  );
+}
@@ -1,4 +1,4 @@
-import { useEffect, useRef } from 'react';
+import { useEffect, useRef, useState } from 'react';
 import { Terminal as XTerm } from 'xterm';
 import { FitAddon } from 'xterm-addon-fit';
 import { WebLinksAddon } from 'xterm-addon-web-links';
@@ -6,11 +6,17 @@ import { Terminal as TerminalIcon } from 'lucide-react';
 import { getWorkspaceSession } from '../lib/workspaceSession';
 import 'xterm/css/xterm.css';
 
+// Reconnect attempts after a dropped connection before giving up. Together
+// with the backoff this spans the agent's two minute resume grace period.
+const MAX_RECONNECT_ATTEMPTS = 15;
+
 export function Terminal() {
   const terminalRef = useRef<HTMLDivElement>(null);
   const xtermRef = useRef<XTerm | null>(null);
   const wsRef = useRef<WebSocket | null>(null);
   const fitAddonRef = useRef<FitAddon | null>(null);
+  // Set while an instructor has taken control of this terminal
+  const [instructorControl, setInstructorControl] = useState(false);
 
   const focusTerminal = () => {
     xtermRef.current?.focus();
@@ -65,44 +71,46 @@ export function Terminal() {
 
     const session = getWorkspaceSession();
     let ws: WebSocket | null = null;
-
-    if (!session) {
-      xterm.write('\r\n\x1b[31mMissing workspace session. Provide token/seat/endpoint.\x1b[0m\r\n');
-    } else {
-      const endpointUrl = new URL(session.endpoint);
-      const wsProtocol = endpointUrl.protocol === 'https:' ? 'wss:' : 'ws:';
-      const tokenParam = session.token ? `?token=${encodeURIComponent(session.token)}` : '';
-
-      // Build path based on runtime type
-      let wsPath: string;
-      if (session.runtime_type === 'firecracker' && session.workshop_id) {
-        // Firecracker proxy: /proxy/{workshopID}/{seatID}/terminal
-        wsPath = `/proxy/${session.workshop_id}/${session.seat}/terminal`;
-      } else {
-        // Docker workspace: /vm/{seat}/terminal
-        const basePath = endpointUrl.pathname === '/' ? '' : endpointUrl.pathname;
-        wsPath = `${basePath}/vm/${session.seat}/terminal`;
-      }
-
-      const wsUrl = `${wsProtocol}//${endpointUrl.host}${wsPath}${tokenParam}`;
-      ws = new WebSocket(wsUrl);
-      wsRef.current = ws;
-
-      ws.onopen = () => {
+    // Issued by the Firecracker agent; reconnecting with it within the grace
+    // period reattaches to the same shell and replays missed output
+    let resumeToken: string | null = null;
+    let reconnectTimer: ReturnType<typeof setTimeout> | undefined;
+    let reconnectAttempts = 0;
+    let disposed = false;
+
+    const connect = (wsUrl: string) => {
+      const url = resumeToken ? `${wsUrl}${wsUrl.includes('?') ? '&' : '?'}resume=${encodeURIComponent(resumeToken)}` : wsUrl;
+      const socket = new WebSocket(url);
+      ws = socket;
+      wsRef.current = socket;
+
+      socket.onopen = () => {
         console.log('Terminal WebSocket connected');
+        reconnectAttempts = 0;
         // Send initial resize
-        ws?.send(JSON.stringify({
+        socket.send(JSON.stringify({
           type: 'resize',
           cols: xterm.cols,
           rows: xterm.rows,
         }));
       };
 
-      ws.onmessage = (event) => {
+      socket.onmessage = (event) => {
         try {
           const msg = JSON.parse(event.data);
           if (msg.type === 'output') {
             xterm.write(msg.data);
+          } else if (msg.type === 'session') {
+            const previousToken = resumeToken;
+            resumeToken = msg.resume_token;
+            // The buffered output is replayed next, so start from a clean screen
+            if (msg.resumed) {
+              xterm.reset();
+            } else if (previousToken) {
+              xterm.write('\r\n\x1b[33mPrevious session expired. Started a new shell.\x1b[0m\r\n');
+            }
+          } else if (msg.type === 'control') {
+            setInstructorControl(msg.controller === 'instructor');
           }
         } catch {
           // Handle raw data
@@ -110,15 +118,49 @@ export function Terminal() {
         }
       };
 
-      ws.onerror = (error) => {
+      socket.onerror = (error) => {
         console.error('Terminal WebSocket error:', error);
-        xterm.write('\r\n\x1b[31mConnection error. Is the workspace server running?\x1b[0m\r\n');
+        if (!resumeToken) {
+          xterm.write('\r\n\x1b[31mConnection error. Is the workspace server running?\x1b[0m\r\n');
+        }
       };
 
-      ws.onclose = () => {
+      socket.onclose = () => {
         console.log('Terminal WebSocket closed');
+        if (disposed) return;
+        if (resumeToken && reconnectAttempts < MAX_RECONNECT_ATTEMPTS) {
+          // Back off 1s, 2s, 4s... up to 10s between attempts
+          const delay = Math.min(1000 * 2 ** reconnectAttempts, 10000);
+          reconnectAttempts++;
+          if (reconnectAttempts === 1) {
+            xterm.write('\r\n\x1b[33mConnection lost. Reconnecting...\x1b[0m\r\n');
+          }
+          reconnectTimer = setTimeout(() => connect(wsUrl), delay);
+          return;
+        }
         xterm.write('\r\n\x1b[33mConnection closed.\x1b[0m\r\n');
       };
+    };
+
+    if (!session) {
+      xterm.write('\r\n\x1b[31mMissing workspace session. Provide token/seat/endpoint.\x1b[0m\r\n');
+    } else {
+      const endpointUrl = new URL(session.endpoint);
+      const wsProtocol = endpointUrl.protocol === 'https:' ? 'wss:' : 'ws:';
+      const tokenParam = session.token ? `?token=${encodeURIComponent(session.token)}` : '';
+
+      // Build path based on runtime type
+      let wsPath: string;
+      if (session.runtime_type === 'firecracker' && session.workshop_id) {
+        // Firecracker proxy: /proxy/{workshopID}/{seatID}/terminal
+        wsPath = `/proxy/${session.workshop_id}/${session.seat}/terminal`;
+      } else {
+        // Docker workspace: /vm/{seat}/terminal
+        const basePath = endpointUrl.pathname === '/' ? '' : endpointUrl.pathname;
+        wsPath = `${basePath}/vm/${session.seat}/terminal`;
+      }
+
+      connect(`${wsProtocol}//${endpointUrl.host}${wsPath}${tokenParam}`);
     }
 
     // Handle terminal input
@@ -149,6 +191,8 @@ export function Terminal() {
     resizeObserver.observe(terminalRef.current);
 
     return () => {
+      disposed = true;
+      clearTimeout(reconnectTimer);
       window.removeEventListener('resize', handleResize);
       resizeObserver.disconnect();
       ws?.close();
@@ -166,6 +210,11 @@ export function Terminal() {
       <div className="bg-vscode-sidebar border-b border-vscode-border px-4 py-2 flex items-center gap-2 flex-shrink-0">
         <TerminalIcon className="w-4 h-4 text-vscode-text" />
         <span className="text-vscode-text text-sm">Terminal</span>
+        {instructorControl && (
+          <span className="ml-auto text-xs text-yellow-400">
+            An instructor is controlling your terminal
+          </span>
+        )}
       </div>
 
       {/* Terminal Content */}
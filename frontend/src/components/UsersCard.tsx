@@ -0,0 +1,84 @@
+import { useState, useEffect } from 'react';
+import { LogOut, UserCheck, UserX, Users } from 'lucide-react';
+import { Button } from '@/components/ui/button';
+import { Card, CardContent, CardHeader, CardTitle } from '@/components/ui/card';
+import { api, type User } from '@/lib/api';
+import { useAuth } from '@/lib/auth';
+
+// Lists instructors and admins, and lets an admin disable an account or sign
+// it out of every session.
+export function UsersCard() {
+  const { user: currentUser } = useAuth();
+  const [users, setUsers] = useState<User[]>([]);
+
+  useEffect(() => {
+    loadUsers();
+  }, []);
+
+  const loadUsers = async () => {
+    try {
+      const data = await api.listUsers();
+      setUsers(data.users ?? []);
+    } catch (err) {
+      console.error('Failed to load users:', err);
+    }
+  };
+
+  const handleToggleDisabled = async (user: User) => {
+    if (!user.disabled && !confirm(`Disable ${user.email}? They will be signed out everywhere and cannot sign in again until re-enabled.`)) return;
+    try {
+      await api.setUserDisabled(user.id, !user.disabled);
+      loadUsers();
+    } catch (err) {
+      alert(err instanceof Error ? err.message : 'Failed to update user');
+    }
+  };
+
+  const handleLogout = async (user: User) => {
+    if (!confirm(`Sign ${user.email} out of every session?`)) return;
+    try {
+      await api.logoutUser(user.id);
+    } catch (err) {
+      alert(err instanceof Error ? err.message : 'Failed to sign user out');
+    }
+  };
+
+  return (
+    <Card className="mt-6">
+      <CardHeader>
+        <div className="flex items-center gap-2">
+          <Users className="w-5 h-5" />
+          <CardTitle>Users</CardTitle>
+        </div>
+      </CardHeader>
+      <CardContent className="space-y-2">
+        {users.map((user) => (
+          <div key={user.id} className="flex items-center justify-between gap-2 p-3 bg-gray-50 rounded-lg">
+            <div className="min-w-0">
+              <p className={`font-medium ${user.disabled ? 'text-gray-400 line-through' : 'text-gray-900'}`}>{user.name}</p>
+              <p className="text-sm text-gray-500 truncate">
+                {user.email} · {user.role}
+                {user.disabled ? ' · disabled' : ''}
+              </p>
+            </div>
+            {user.id !== currentUser?.id && (
+              <div className="flex gap-1">
+                <Button size="icon" variant="ghost" title="Sign out everywhere" onClick={() => handleLogout(user)}>
+                  <LogOut className="w-4 h-4" />
+                </Button>
+                <Button
+                  size="icon"
+                  variant="ghost"
+                  title={user.disabled ? 'Enable' : 'Disable'}
+                  onClick={() => handleToggleDisabled(user)}
+                >
+                  {user.disabled ? <UserCheck className="w-4 h-4" /> : <UserX className="w-4 h-4" />}
+                </Button>
+              </div>
+            )}
+          </div>
+        ))}
+      </CardContent>
+    </Card>
+  );
+}
@@ -0,0 +1,175 @@
+import { useState, useEffect } from 'react';
+import { Webhook as WebhookIcon, Trash2, RefreshCw } from 'lucide-react';
+import { Button } from '@/components/ui/button';
+import { Card, CardContent, CardHeader, CardTitle } from '@/components/ui/card';
+import { api, type Webhook, type WebhookDelivery, type WebhookEvent } from '@/lib/api';
+
+// Manages a workshop's webhooks, or the global ones when workshopId is null.
+export function WebhooksCard({ workshopId }: { workshopId: string | null }) {
+  const [webhooks, setWebhooks] = useState<Webhook[]>([]);
+  const [events, setEvents] = useState<WebhookEvent[]>([]);
+  const [url, setUrl] = useState('');
+  const [selected, setSelected] = useState<WebhookEvent[]>([]);
+  const [newSecret, setNewSecret] = useState<string | null>(null);
+  const [openWebhook, setOpenWebhook] = useState<string | null>(null);
+  const [deliveries, setDeliveries] = useState<WebhookDelivery[]>([]);
+
+  useEffect(() => {
+    loadWebhooks();
+  }, [workshopId]);
+
+  const loadWebhooks = async () => {
+    try {
+      const data = await api.listWebhooks(workshopId);
+      setWebhooks(data.webhooks);
+      setEvents(data.events);
+    } catch (err) {
+      console.error('Failed to load webhooks:', err);
+    }
+  };
+
+  const loadDeliveries = async (webhookId: string) => {
+    try {
+      const data = await api.listWebhookDeliveries(workshopId, webhookId);
+      setDeliveries(data.deliveries);
+    } catch (err) {
+      console.error('Failed to load webhook deliveries:', err);
+    }
+  };
+
+  const toggleEvent = (event: WebhookEvent) => {
+    setSelected((prev) => (prev.includes(event) ? prev.filter((e) => e !== event) : [...prev, event]));
+  };
+
+  const handleCreate = async (e: React.FormEvent) => {
+    e.preventDefault();
+    try {
+      const { secret } = await api.createWebhook(workshopId, { url: url.trim(), events: selected });
+      setNewSecret(secret);
+      setUrl('');
+      setSelected([]);
+      loadWebhooks();
+    } catch (err) {
+      alert(err instanceof Error ? err.message : 'Failed to add webhook');
+    }
+  };
+
+  const handleDelete = async (webhook: Webhook) => {
+    if (!confirm(`Delete the webhook for ${webhook.url}? Its delivery log is deleted too.`)) return;
+    try {
+      await api.deleteWebhook(workshopId, webhook.id);
+      if (openWebhook === webhook.id) setOpenWebhook(null);
+      loadWebhooks();
+    } catch (err) {
+      alert(err instanceof Error ? err.message : 'Failed to delete webhook');
+    }
+  };
+
+  const handleToggleDeliveries = (webhook: Webhook) => {
+    if (openWebhook === webhook.id) {
+      setOpenWebhook(null);
+      return;
+    }
+    setOpenWebhook(webhook.id);
+    setDeliveries([]);
+    loadDeliveries(webhook.id);
+  };
+
+  const handleRedeliver = async (delivery: WebhookDelivery) => {
+    try {
+      await api.redeliverWebhook(workshopId, delivery.webhook_id, delivery.id);
+      loadDeliveries(delivery.webhook_id);
+    } catch (err) {
+      alert(err instanceof Error ? err.message : 'Failed to redeliver');
+    }
+  };
+
+  const statusClass = (status: WebhookDelivery['status']) =>
+    status === 'delivered' ? 'text-green-600' : status === 'failed' ? 'text-red-600' : 'text-amber-600';
+
+  return (
+    <Card className="mt-6">
+      <CardHeader>
+        <div className="flex items-center gap-2">
+          <WebhookIcon className="w-5 h-5" />
+          <CardTitle>{workshopId ? 'Webhooks' : 'Global Webhooks'}</CardTitle>
+        </div>
+      </CardHeader>
+      <CardContent className="space-y-4">
+        {newSecret && (
+          <div className="p-3 bg-amber-50 border border-amber-200 rounded-lg text-sm">
+            <p className="text-gray-700">Signing secret (shown once):</p>
+            <p className="font-mono break-all">{newSecret}</p>
+            <Button size="sm" variant="outline" className="mt-2" onClick={() => setNewSecret(null)}>
+              Done
+            </Button>
+          </div>
+        )}
+
+        <div className="space-y-2">
+          {webhooks.map((webhook) => (
+            <div key={webhook.id} className="p-3 bg-gray-50 rounded-lg">
+              <div className="flex flex-col gap-2 sm:flex-row sm:items-center sm:justify-between">
+                <div className="min-w-0">
+                  <p className="text-gray-900 font-mono text-sm truncate">{webhook.url}</p>
+                  <p className="text-sm text-gray-500">{webhook.events.join(', ')}</p>
+                </div>
+                <div className="flex gap-2">
+                  <Button size="sm" variant="outline" onClick={() => handleToggleDeliveries(webhook)}>
+                    {openWebhook === webhook.id ? 'Hide log' : 'Deliveries'}
+                  </Button>
+                  <Button size="icon" variant="ghost" onClick={() => handleDelete(webhook)}>
+                    <Trash2 className="w-4 h-4" />
+                  </Button>
+                </div>
+              </div>
+              {openWebhook === webhook.id && (
+                <div className="mt-3 space-y-1 text-sm">
+                  {deliveries.length === 0 && <p className="text-gray-500">No deliveries yet</p>}
+                  {deliveries.map((delivery) => (
+                    <div key={delivery.id} className="flex items-center justify-between gap-2">
+                      <p className="min-w-0 truncate">
+                        <span className={statusClass(delivery.status)}>{delivery.status}</span>
+                        {' · '}{delivery.event}
+                        {' · '}{new Date(delivery.created_at).toLocaleString()}
+                        {delivery.attempts > 1 && ` · ${delivery.attempts} attempts`}
+                        {delivery.last_error && <span className="text-gray-500"> · {delivery.last_error}</span>}
+                      </p>
+                      {delivery.status !== 'pending' && (
+                        <Button size="icon" variant="ghost" onClick={() => handleRedeliver(delivery)}>
+                          <RefreshCw className="w-4 h-4" />
+                        </Button>
+                      )}
+                    </div>
+                  ))}
+                </div>
+              )}
+            </div>
+          ))}
+        </div>
+
+        <form onSubmit={handleCreate} className="space-y-3">
+          <input
+            type="url"
+            placeholder="https://example.com/hooks/clarateach"
+            value={url}
+            onChange={(e) => setUrl(e.target.value)}
+            className="w-full rounded-md border border-gray-300 px-3 py-2 text-sm focus:outline-none focus:ring-2 focus:ring-indigo-500"
+            required
+          />
+          <div className="flex flex-wrap gap-3">
+            {events.map((event) => (
+              <label key={event} className="flex items-center gap-1 text-sm text-gray-700">
+                <input type="checkbox" checked={selected.includes(event)} onChange={() => toggleEvent(event)} />
+                {event}
+              </label>
+            ))}
+          </div>
+          <Button type="submit" disabled={!url.trim() || selected.length === 0}>
+            Add webhook
+          </Button>
+        </form>
+      </CardContent>
+    </Card>
+  );
+}
@@ -2,6 +2,7 @@ import type { Workshop, Session, JoinResponse, ApiError } from './types';
 
 const API_BASE = '/api';
 const TOKEN_KEY = 'clarateach_token';
+const REFRESH_TOKEN_KEY = 'clarateach_refresh_token';
 
 // User type
 export interface User {
@@ -9,14 +10,63 @@ export interface User {
   email: string;
   name: string;
   role: 'instructor' | 'admin';
+  disabled?: boolean;
   created_at: string;
 }
 
 class ApiClient {
+  private refreshing: Promise<boolean> | null = null;
+
   private getToken(): string | null {
     return localStorage.getItem(TOKEN_KEY);
   }
 
+  // Stores the tokens of a new or refreshed sign-in
+  setSession(tokens: { token: string; refresh_token?: string }) {
+    localStorage.setItem(TOKEN_KEY, tokens.token);
+    if (tokens.refresh_token) {
+      localStorage.setItem(REFRESH_TOKEN_KEY, tokens.refresh_token);
+    }
+  }
+
+  clearSession() {
+    localStorage.removeItem(TOKEN_KEY);
+    localStorage.removeItem(REFRESH_TOKEN_KEY);
+  }
+
+  // Gets a new access token with the refresh token. Concurrent callers share
+  // one refresh, since each refresh token works only once.
+  private refreshSession(): Promise<boolean> {
+    if (!this.refreshing) {
+      this.refreshing = this.doRefresh().finally(() => {
+        this.refreshing = null;
+      });
+    }
+    return this.refreshing;
+  }
+
+  private async doRefresh(): Promise<boolean> {
+    const refreshToken = localStorage.getItem(REFRESH_TOKEN_KEY);
+    if (!refreshToken) return false;
+    try {
+      const response = await fetch(`${API_BASE}/auth/refresh`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ refresh_token: refreshToken }),
+      });
+      if (response.ok) {
+        this.setSession(JSON.parse(await response.text()));
+        return true;
+      }
+    } catch {
+      return false;
+    }
+    // Another tab may have refreshed with the same token first
+    if (localStorage.getItem(REFRESH_TOKEN_KEY) !== refreshToken) return true;
+    this.clearSession();
+    return false;
+  }
+
   private getAuthHeaders(): Record<string, string> {
     const token = this.getToken();
     if (token) {
@@ -25,7 +75,7 @@ class ApiClient {
     return {};
   }
 
-  private async request<T>(path: string, options?: RequestInit & { auth?: boolean }): Promise<T> {
+  private async request<T>(path: string, options?: RequestInit & { auth?: boolean; retried?: boolean }): Promise<T> {
     const authHeaders = options?.auth !== false ? this.getAuthHeaders() : {};
 
     const response = await fetch(`${API_BASE}${path}`, {
@@ -48,6 +98,11 @@ class ApiClient {
       }
     }
 
+    // Access tokens are short-lived; refresh once and try again
+    if (response.status === 401 && authHeaders.Authorization && !options?.retried && await this.refreshSession()) {
+      return this.request(path, { ...options, retried: true });
+    }
+
     if (!response.ok) {
       const error = (data?.error as ApiError | undefined);
       throw new Error(error?.message || text || `Request failed (${response.status})`);
@@ -77,7 +132,7 @@ class ApiClient {
     return this.request(`/workshops/${id}`);
   }
 
-  async createWorkshop(data: { name: string; seats: number; api_key: string; runtime_type?: 'docker' | 'firecracker' }): Promise<{ workshop: Workshop }> {
+  async createWorkshop(data: { name: string; seats: number; api_key: string; runtime_type?: 'docker' | 'firecracker'; waitlist?: boolean; starts_at?: string }): Promise<{ workshop: Workshop }> {
     return this.request('/workshops', {
       method: 'POST',
       body: JSON.stringify(data),
@@ -102,6 +157,152 @@ class ApiClient {
     });
   }
 
+  async listAnnouncements(id: string): Promise<{ announcements: Announcement[] }> {
+    return this.request(`/workshops/${id}/announcements`);
+  }
+
+  async createAnnouncement(id: string, message: string): Promise<{ announcement: Announcement }> {
+    return this.request(`/workshops/${id}/announcements`, {
+      method: 'POST',
+      body: JSON.stringify({ message }),
+    });
+  }
+
+  async listHelpRequests(id: string): Promise<{ help_requests: HelpRequest[] }> {
+    return this.request(`/workshops/${id}/help-requests`);
+  }
+
+  async claimHelpRequest(id: string, requestId: number): Promise<{ help_request: HelpRequest }> {
+    return this.request(`/workshops/${id}/help-requests/${requestId}/claim`, { method: 'POST' });
+  }
+
+  async resolveHelpRequest(id: string, requestId: number): Promise<{ help_request: HelpRequest }> {
+    return this.request(`/workshops/${id}/help-requests/${requestId}/resolve`, { method: 'POST' });
+  }
+
+  // Roster CSV (name, email, optional seat). Invalid rows are returned in
+  // `errors` and nothing is imported.
+  async importRoster(id: string, csv: string): Promise<RosterImportResponse> {
+    const response = await fetch(`${API_BASE}/workshops/${id}/registrations/import`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'text/csv', ...this.getAuthHeaders() },
+      body: csv,
+    });
+    const text = await response.text();
+    if (response.headers.get('Content-Type')?.includes('application/json')) {
+      return JSON.parse(text);
+    }
+    if (!response.ok) {
+      throw new Error(text || `Import failed (${response.status})`);
+    }
+    return JSON.parse(text);
+  }
+
+  async exportRoster(id: string): Promise<Blob> {
+    const response = await fetch(`${API_BASE}/workshops/${id}/registrations.csv`, {
+      headers: this.getAuthHeaders(),
+    });
+    if (!response.ok) {
+      throw new Error((await response.text()) || `Export failed (${response.status})`);
+    }
+    return response.blob();
+  }
+
+  async listRegistrations(id: string): Promise<{ registrations: Registration[] }> {
+    return this.request(`/workshops/${id}/registrations`);
+  }
+
+  // Revoking frees the learner's seat and invalidates their access code and
+  // workspace tokens.
+  async revokeRegistration(id: string, registrationId: string): Promise<{ registration: Registration }> {
+    return this.request(`/workshops/${id}/registrations/${registrationId}`, { method: 'DELETE' });
+  }
+
+  async moveRegistration(id: string, registrationId: string, seat: number): Promise<{ registration: Registration }> {
+    return this.request(`/workshops/${id}/registrations/${registrationId}/seat`, {
+      method: 'POST',
+      body: JSON.stringify({ seat }),
+    });
+  }
+
+  // Issues a one-time login link and emails it when the server sends email.
+  async sendLoginLink(id: string, registrationId: string): Promise<{ login_url: string; expires_at: string; emailed: boolean }> {
+    return this.request(`/workshops/${id}/registrations/${registrationId}/login-link`, { method: 'POST' });
+  }
+
+  // Replaces a leaked access code; the learner keeps their seat and is
+  // emailed a login link with the new code.
+  async rotateAccessCode(id: string, registrationId: string): Promise<{ registration: Registration; emailed: boolean }> {
+    return this.request(`/workshops/${id}/registrations/${registrationId}/rotate-code`, { method: 'POST' });
+  }
+
+  // Rebuilds a free seat's workspace; the seat reports "resetting" until done.
+  async resetSeat(id: string, seat: number): Promise<{ seat: number; status: string }> {
+    return this.request(`/workshops/${id}/seats/${seat}/reset`, { method: 'POST' });
+  }
+
+  async listWaitlist(id: string): Promise<{ waitlist: Registration[] }> {
+    return this.request(`/workshops/${id}/waitlist`);
+  }
+
+  // Sets the promotion order; must list every waitlisted registration once.
+  async reorderWaitlist(id: string, registrationIds: string[]): Promise<{ waitlist: Registration[] }> {
+    return this.request(`/workshops/${id}/waitlist`, {
+      method: 'PUT',
+      body: JSON.stringify({ registration_ids: registrationIds }),
+    });
+  }
+
+  async listNotificationTemplates(id: string): Promise<{ enabled: boolean; templates: NotificationTemplate[] }> {
+    return this.request(`/workshops/${id}/notification-templates`);
+  }
+
+  // Templates use Go text/template syntax; invalid ones are rejected with 400.
+  async setNotificationTemplate(id: string, kind: NotificationKind, template: { subject: string; body: string }): Promise<{ template: NotificationTemplate }> {
+    return this.request(`/workshops/${id}/notification-templates/${kind}`, {
+      method: 'PUT',
+      body: JSON.stringify(template),
+    });
+  }
+
+  async resetNotificationTemplate(id: string, kind: NotificationKind): Promise<{ success: boolean }> {
+    return this.request(`/workshops/${id}/notification-templates/${kind}`, { method: 'DELETE' });
+  }
+
+  async listNotifications(id: string): Promise<{ notifications: SentNotification[] }> {
+    return this.request(`/workshops/${id}/notifications`);
+  }
+
+  // Webhooks belong to a workshop, or are global (admin only) when
+  // workshopId is null.
+  private webhooksPath(workshopId: string | null): string {
+    return workshopId ? `/workshops/${workshopId}/webhooks` : '/admin/webhooks';
+  }
+
+  async listWebhooks(workshopId: string | null): Promise<{ webhooks: Webhook[]; events: WebhookEvent[] }> {
+    return this.request(this.webhooksPath(workshopId));
+  }
+
+  // The signing secret is generated unless given, and only returned here.
+  async createWebhook(workshopId: string | null, data: { url: string; events: WebhookEvent[]; secret?: string }): Promise<{ webhook: Webhook; secret: string }> {
+    return this.request(this.webhooksPath(workshopId), {
+      method: 'POST',
+      body: JSON.stringify(data),
+    });
+  }
+
+  async deleteWebhook(workshopId: string | null, webhookId: string): Promise<{ success: boolean }> {
+    return this.request(`${this.webhooksPath(workshopId)}/${webhookId}`, { method: 'DELETE' });
+  }
+
+  async listWebhookDeliveries(workshopId: string | null, webhookId: string): Promise<{ deliveries: WebhookDelivery[] }> {
+    return this.request(`${this.webhooksPath(workshopId)}/${webhookId}/deliveries`);
+  }
+
+  async redeliverWebhook(workshopId: string | null, webhookId: string, deliveryId: number): Promise<{ delivery: WebhookDelivery }> {
+    return this.request(`${this.webhooksPath(workshopId)}/${webhookId}/deliveries/${deliveryId}/redeliver`, { method: 'POST' });
+  }
+
   async getWorkshopLearners(id: string): Promise<{ learners: Session[]; connected: number }> {
     return this.request(`/workshops/${id}/learners`);
   }
@@ -126,6 +327,77 @@ class ApiClient {
     return this.request(`/session/${accessCode}`);
   }
 
+  // Always succeeds so it does not reveal who is registered
+  async requestLoginLink(data: { workshop_code: string; email: string }): Promise<{ message: string }> {
+    return this.request('/session/login-link', {
+      method: 'POST',
+      body: JSON.stringify(data),
+      auth: false,
+    });
+  }
+
+  // Exchanges a one-time login link for the session and its access code
+  async loginWithLink(token: string): Promise<SessionResponse & { access_code: string }> {
+    return this.request('/session/login', {
+      method: 'POST',
+      body: JSON.stringify({ token }),
+      auth: false,
+    });
+  }
+
+  // LTI deep linking: an instructor launched from their LMS picks the workshop
+  // the course links to; token is the session from /lti/deep-link/:token
+  async listLTIDeepLinkWorkshops(token: string): Promise<{ workshops: Workshop[]; context_title: string }> {
+    return this.request('/lti/deep-link/workshops', {
+      method: 'POST',
+      body: JSON.stringify({ token }),
+      auth: false,
+    });
+  }
+
+  // Returns the signed response to post back to the LMS as the JWT form field
+  async completeLTIDeepLink(token: string, workshopId: string): Promise<{ return_url: string; jwt: string }> {
+    return this.request('/lti/deep-link', {
+      method: 'POST',
+      body: JSON.stringify({ token, workshop_id: workshopId }),
+      auth: false,
+    });
+  }
+
+  // Server-Sent Events stream of session state (pending -> seat_assigned -> ready)
+  sessionStreamUrl(accessCode: string): string {
+    return `${API_BASE}/session/${accessCode}/stream`;
+  }
+
+  // Help requests ("raise hand"); position is the place in the queue while open
+  async getSessionHelp(accessCode: string): Promise<SessionHelpResponse> {
+    return this.request(`/session/${accessCode}/help`);
+  }
+
+  async raiseHand(accessCode: string, message?: string): Promise<SessionHelpResponse> {
+    return this.request(`/session/${accessCode}/help`, {
+      method: 'POST',
+      body: JSON.stringify({ message: message ?? '' }),
+    });
+  }
+
+  async lowerHand(accessCode: string): Promise<SessionHelpResponse> {
+    return this.request(`/session/${accessCode}/help`, { method: 'DELETE' });
+  }
+
+  // Short-lived token for one app preview port (Firecracker only)
+  async createPreviewToken(accessCode: string, port: number): Promise<PreviewTokenResponse> {
+    return this.request(`/session/${accessCode}/preview`, {
+      method: 'POST',
+      body: JSON.stringify({ port }),
+    });
+  }
+
+  // Server-Sent Events stream of instructor announcements (snapshot, then announcement events)
+  sessionAnnouncementsStreamUrl(accessCode: string): string {
+    return `${API_BASE}/session/${accessCode}/announcements/stream`;
+  }
+
   // Admin
   async adminOverview(): Promise<{ workshops: AdminWorkshopView[]; total: number }> {
     return this.request('/admin/overview');
@@ -139,6 +411,44 @@ class ApiClient {
     return this.request(`/admin/vms/${workshopId}`);
   }
 
+  async listLTIPlatforms(): Promise<{ platforms: LTIPlatform[]; tool: LTIToolInfo }> {
+    return this.request('/admin/lti/platforms');
+  }
+
+  async createLTIPlatform(data: Omit<LTIPlatform, 'id' | 'created_at'>): Promise<{ platform: LTIPlatform }> {
+    return this.request('/admin/lti/platforms', {
+      method: 'POST',
+      body: JSON.stringify(data),
+    });
+  }
+
+  async setLTIPlatformTrustEmail(platformId: string, trustEmail: boolean): Promise<{ platform: LTIPlatform }> {
+    return this.request(`/admin/lti/platforms/${platformId}`, {
+      method: 'PATCH',
+      body: JSON.stringify({ trust_email: trustEmail }),
+    });
+  }
+
+  async deleteLTIPlatform(platformId: string): Promise<{ success: boolean }> {
+    return this.request(`/admin/lti/platforms/${platformId}`, { method: 'DELETE' });
+  }
+
+  async listUsers(): Promise<{ users: User[] }> {
+    return this.request('/admin/users');
+  }
+
+  async setUserDisabled(userId: string, disabled: boolean): Promise<{ user: User }> {
+    return this.request(`/admin/users/${userId}`, {
+      method: 'PATCH',
+      body: JSON.stringify({ disabled }),
+    });
+  }
+
+  // Ends every session of another user
+  async logoutUser(userId: string): Promise<{ success: boolean }> {
+    return this.request(`/admin/users/${userId}/logout`, { method: 'POST' });
+  }
+
   getSSHKeyDownloadUrl(workshopId: string): string {
     return `${API_BASE}/admin/vms/${workshopId}/ssh-key`;
   }
@@ -160,12 +470,20 @@ class ApiClient {
     });
   }
 
+  // Revokes the current session on the server
   async authLogout(): Promise<{ success: boolean }> {
     return this.request('/auth/logout', {
       method: 'POST',
+      body: JSON.stringify({ refresh_token: localStorage.getItem(REFRESH_TOKEN_KEY) ?? undefined }),
+      retried: true,
     });
   }
 
+  // Signs the current user out of every session, on every device
+  async authLogoutAll(): Promise<{ success: boolean }> {
+    return this.request('/auth/logout-all', { method: 'POST' });
+  }
+
   async authMe(token?: string): Promise<{ user: User }> {
     const headers: Record<string, string> = {};
     if (token) {
@@ -173,17 +491,60 @@ class ApiClient {
     }
     return this.request('/auth/me', { headers });
   }
+
+  // Single sign-on providers; sign-in starts by navigating to login_url
+  async listOIDCProviders(): Promise<{ providers: OIDCProvider[] }> {
+    return this.request('/auth/oidc/providers', { auth: false });
+  }
+
+  // Returns the URL that links an account at the provider to the signed-in user
+  async linkOIDCProvider(provider: string): Promise<{ url: string }> {
+    return this.request(`/auth/oidc/${provider}/link`, { method: 'POST' });
+  }
+
+  // Confirms the provider account the link callback verified; only the user
+  // who started the link can
+  async completeOIDCLink(provider: string, token: string): Promise<{ success: boolean }> {
+    return this.request(`/auth/oidc/${provider}/link/complete`, {
+      method: 'POST',
+      body: JSON.stringify({ token }),
+    });
+  }
+
+  async listIdentities(): Promise<{ identities: UserIdentity[]; has_password: boolean }> {
+    return this.request('/auth/identities');
+  }
+
+  async unlinkIdentity(provider: string): Promise<{ success: boolean }> {
+    return this.request(`/auth/identities/${provider}`, { method: 'DELETE' });
+  }
+}
+
+// Single sign-on types
+export interface OIDCProvider {
+  id: string;
+  name: string;
+  login_url: string;
+}
+
+export interface UserIdentity {
+  provider: string;
+  user_id: string;
+  email: string;
+  created_at: string;
 }
 
 // Registration types
 export interface RegisterResponse {
   access_code: string;
   already_registered: boolean;
+  waitlisted?: boolean;
+  waitlist_position?: number;
   message: string;
 }
 
 export interface SessionResponse {
-  status: 'pending' | 'ready';
+  status: 'waitlisted' | 'pending' | 'seat_assigned' | 'ready';
   message?: string;
   endpoint?: string;
   token?: string;  // JWT token for workspace WebSocket authentication
@@ -191,9 +552,129 @@ export interface SessionResponse {
   name?: string;
   workshop_id?: string;
   runtime_type?: 'docker' | 'firecracker';
+  queue_position?: number;  // Place among learners waiting for a seat
+  seats_ready?: number;     // Seats created so far while pending
+  seats_total?: number;
+  waitlist_position?: number;  // Place on the waitlist while waitlisted
 }
 
 // Admin types
+export interface Announcement {
+  id: number;
+  message: string;
+  author_name: string;
+  created_at: string;
+}
+
+export interface HelpRequest {
+  id: number;
+  seat_id: number;
+  learner_name: string;
+  message: string;
+  status: 'open' | 'claimed' | 'resolved';
+  claimed_by_name: string;
+  created_at: string;
+  claimed_at: string | null;
+  resolved_at: string | null;
+}
+
+export interface Registration {
+  id: string;
+  access_code: string;
+  email: string;
+  name: string;
+  workshop_id: string;
+  seat_id: number | null;
+  status: 'registered' | 'active' | 'expired' | 'revoked' | 'waitlisted';
+  waitlist_position?: number;
+  created_at: string;
+  joined_at: string | null;
+}
+
+export type WebhookEvent = 'workshop.running' | 'workshop.error' | 'workshop.stopped' | 'learner.registered' | 'learner.joined';
+
+export interface Webhook {
+  id: string;
+  workshop_id: string;  // Empty for global webhooks
+  url: string;
+  events: WebhookEvent[];
+  created_by: string;
+  created_at: string;
+}
+
+export interface WebhookDelivery {
+  id: number;
+  webhook_id: string;
+  workshop_id: string;
+  event: WebhookEvent;
+  payload: string;
+  status: 'pending' | 'delivered' | 'failed';
+  attempts: number;
+  next_attempt_at: string;
+  last_status_code?: number;
+  last_error?: string;
+  created_at: string;
+  delivered_at: string | null;
+}
+
+export interface LTIPlatform {
+  id: string;
+  name: string;
+  issuer: string;
+  client_id: string;
+  auth_login_url: string;
+  jwks_url: string;
+  deployment_ids: string[] | null;  // Empty accepts every deployment
+  trust_email: boolean;  // Deep linking acts as the account with the LMS email
+  created_at: string;
+}
+
+// URLs to register ClaraTeach with on an LMS; only enabled is set when LTI is off
+export interface LTIToolInfo {
+  enabled: boolean;
+  login_url?: string;
+  launch_url?: string;
+  jwks_url?: string;
+}
+
+export type NotificationKind = 'registration' | 'waitlisted' | 'reminder' | 'workspace_ready' | 'login_link';
+
+export interface NotificationTemplate {
+  kind: NotificationKind;
+  custom: boolean;  // False while the built-in template is used
+  subject: string;
+  body: string;
+  updated_at?: string;
+}
+
+export interface SentNotification {
+  workshop_id: string;
+  registration_id: string;
+  kind: NotificationKind;
+  recipient: string;
+  status: 'pending' | 'sent' | 'failed';
+  error?: string;
+  created_at: string;
+  sent_at: string | null;
+}
+
+export interface RosterImportResponse {
+  imported?: number;
+  error?: string;
+  errors?: { row: number; error: string }[];
+}
+
+export interface SessionHelpResponse {
+  help_request: HelpRequest | null;
+  position?: number;
+}
+
+export interface PreviewTokenResponse {
+  token: string;
+  port: number;
+  expires_at: string;
+}
+
 export interface WorkshopVM {
   id: string;
   workshop_id: string;
@@ -244,6 +725,8 @@ export interface VMDetails {
 // Auth response type
 export interface AuthResponse {
   token: string;
+  refresh_token: string;
+  expires_at: string;
   user: User;
 }
 
@@ -6,8 +6,10 @@ interface AuthContextType {
   token: string | null;
   loading: boolean;
   login: (email: string, password: string) => Promise<void>;
+  loginWithToken: (token: string, refreshToken?: string) => Promise<void>;
   register: (email: string, password: string, name: string) => Promise<void>;
   logout: () => void;
+  logoutEverywhere: () => Promise<void>;
   isAuthenticated: boolean;
   isAdmin: boolean;
 }
@@ -26,14 +28,14 @@ export function AuthProvider({ children }: { children: ReactNode }) {
     const savedToken = localStorage.getItem(TOKEN_KEY);
     if (savedToken) {
       setToken(savedToken);
-      // Verify token by fetching current user
-      api.authMe(savedToken)
+      // Verify token by fetching current user, refreshing it if it expired
+      api.authMe()
         .then(response => {
           setUser(response.user);
         })
         .catch(() => {
-          // Token invalid, clear it
-          localStorage.removeItem(TOKEN_KEY);
+          // Session over, clear it
+          api.clearSession();
           setToken(null);
         })
         .finally(() => {
@@ -48,20 +50,37 @@ export function AuthProvider({ children }: { children: ReactNode }) {
     const response = await api.authLogin(email, password);
     setToken(response.token);
     setUser(response.user);
-    localStorage.setItem(TOKEN_KEY, response.token);
+    api.setSession(response);
+  };
+
+  // Completes a single sign-on, which hands back the tokens without the user
+  const loginWithToken = async (newToken: string, refreshToken?: string) => {
+    const response = await api.authMe(newToken);
+    setToken(newToken);
+    setUser(response.user);
+    api.setSession({ token: newToken, refresh_token: refreshToken });
   };
 
   const register = async (email: string, password: string, name: string) => {
     const response = await api.authRegister(email, password, name);
     setToken(response.token);
     setUser(response.user);
-    localStorage.setItem(TOKEN_KEY, response.token);
+    api.setSession(response);
   };
 
   const logout = () => {
+    // Revoke the session on the server without holding up the sign-out
+    api.authLogout().catch(() => {});
+    setToken(null);
+    setUser(null);
+    api.clearSession();
+  };
+
+  const logoutEverywhere = async () => {
+    await api.authLogoutAll();
     setToken(null);
     setUser(null);
-    localStorage.removeItem(TOKEN_KEY);
+    api.clearSession();
   };
 
   return (
@@ -71,8 +90,10 @@ export function AuthProvider({ children }: { children: ReactNode }) {
         token,
         loading,
         login,
+        loginWithToken,
         register,
         logout,
+        logoutEverywhere,
         isAuthenticated: !!user,
         isAdmin: user?.role === 'admin',
       }}
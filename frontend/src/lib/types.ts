@@ -7,6 +7,8 @@ export interface Workshop {
   seats: number;
   status: WorkshopStatus;
   runtime_type?: 'docker' | 'firecracker';
+  waitlist?: boolean;
+  starts_at?: string | null;
   created_at: string;
   vm_name?: string;
   vm_ip?: string;
@@ -0,0 +1,151 @@
+import { useState, useEffect } from 'react';
+import { useNavigate } from 'react-router-dom';
+import { KeyRound, Link2, Loader2, LogOut, Trash2 } from 'lucide-react';
+import { Button } from '@/components/ui/button';
+import { Card, CardContent, CardDescription, CardHeader, CardTitle } from '@/components/ui/card';
+import { Layout } from '@/components/Layout';
+import { api, type OIDCProvider, type UserIdentity } from '@/lib/api';
+import { useAuth } from '@/lib/auth';
+
+// Lets a signed-in user link and unlink single sign-on accounts, and sign
+// out of every session.
+export function Account() {
+  const navigate = useNavigate();
+  const { isAuthenticated, loading: authLoading, logoutEverywhere } = useAuth();
+  const [providers, setProviders] = useState<OIDCProvider[]>([]);
+  const [identities, setIdentities] = useState<UserIdentity[]>([]);
+  const [hasPassword, setHasPassword] = useState(true);
+  const [loading, setLoading] = useState(true);
+
+  useEffect(() => {
+    if (!authLoading && !isAuthenticated) {
+      navigate('/login');
+      return;
+    }
+    if (!authLoading && isAuthenticated) {
+      loadIdentities();
+    }
+  }, [authLoading, isAuthenticated, navigate]);
+
+  const loadIdentities = async () => {
+    try {
+      const [providerData, identityData] = await Promise.all([api.listOIDCProviders(), api.listIdentities()]);
+      setProviders(providerData.providers);
+      setIdentities(identityData.identities);
+      setHasPassword(identityData.has_password);
+    } catch (err) {
+      console.error('Failed to load sign-in methods:', err);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const handleLink = async (provider: OIDCProvider) => {
+    try {
+      const { url } = await api.linkOIDCProvider(provider.id);
+      window.location.href = url;
+    } catch (err) {
+      alert(err instanceof Error ? err.message : 'Failed to link account');
+    }
+  };
+
+  const handleUnlink = async (provider: OIDCProvider) => {
+    if (!confirm(`Unlink your ${provider.name} account? You will no longer be able to sign in with it.`)) return;
+    try {
+      await api.unlinkIdentity(provider.id);
+      loadIdentities();
+    } catch (err) {
+      alert(err instanceof Error ? err.message : 'Failed to unlink account');
+    }
+  };
+
+  const handleLogoutEverywhere = async () => {
+    if (!confirm('Sign out of every device, including this one?')) return;
+    try {
+      await logoutEverywhere();
+      navigate('/login');
+    } catch (err) {
+      alert(err instanceof Error ? err.message : 'Failed to sign out');
+    }
+  };
+
+  if (authLoading || loading) {
+    return (
+      <Layout>
+        <div className="flex items-center justify-center py-12">
+          <Loader2 className="w-8 h-8 animate-spin text-indigo-600" />
+        </div>
+      </Layout>
+    );
+  }
+
+  const onlySignIn = !hasPassword && identities.length === 1;
+
+  return (
+    <Layout>
+      <div className="max-w-2xl">
+        <Card>
+          <CardHeader>
+            <div className="flex items-center gap-2">
+              <KeyRound className="w-5 h-5" />
+              <CardTitle>Sign-in methods</CardTitle>
+            </div>
+            <CardDescription>
+              {hasPassword ? 'You can sign in with your password' : 'Your account has no password'}
+              {providers.length > 0 && ' or with any account linked below.'}
+            </CardDescription>
+          </CardHeader>
+          <CardContent className="space-y-2">
+            {providers.length === 0 && (
+              <p className="text-sm text-gray-500">Single sign-on is not set up on this server.</p>
+            )}
+            {providers.map((provider) => {
+              const identity = identities.find((i) => i.provider === provider.id);
+              return (
+                <div key={provider.id} className="flex items-center justify-between gap-2 p-3 bg-gray-50 rounded-lg">
+                  <div className="min-w-0">
+                    <p className="font-medium text-gray-900">{provider.name}</p>
+                    <p className="text-sm text-gray-500 truncate">
+                      {identity ? `Linked as ${identity.email || 'your account'}` : 'Not linked'}
+                    </p>
+                  </div>
+                  {identity ? (
+                    <Button
+                      size="icon"
+                      variant="ghost"
+                      disabled={onlySignIn}
+                      title={onlySignIn ? 'This is the only way to sign in to your account' : undefined}
+                      onClick={() => handleUnlink(provider)}
+                    >
+                      <Trash2 className="w-4 h-4" />
+                    </Button>
+                  ) : (
+                    <Button size="sm" variant="outline" onClick={() => handleLink(provider)}>
+                      <Link2 className="w-4 h-4 mr-2" />
+                      Link
+                    </Button>
+                  )}
+                </div>
+              );
+            })}
+          </CardContent>
+        </Card>
+
+        <Card className="mt-6">
+          <CardHeader>
+            <div className="flex items-center gap-2">
+              <LogOut className="w-5 h-5" />
+              <CardTitle>Sessions</CardTitle>
+            </div>
+            <CardDescription>Signed in on a device you no longer have? End every session at once.</CardDescription>
+          </CardHeader>
+          <CardContent>
+            <Button variant="outline" onClick={handleLogoutEverywhere}>
+              Sign out everywhere
+            </Button>
+          </CardContent>
+        </Card>
+      </div>
+    </Layout>
+  );
+}
@@ -2,6 +2,9 @@ import { useState, useEffect } from 'react';
 import { Server, Users, Clock, Copy, Download, ExternalLink, RefreshCw, AlertCircle } from 'lucide-react';
 import { Button } from '@/components/ui/button';
 import { Card, CardContent, CardDescription, CardHeader, CardTitle } from '@/components/ui/card';
+import { LtiPlatformsCard } from '@/components/LtiPlatformsCard';
+import { UsersCard } from '@/components/UsersCard';
+import { WebhooksCard } from '@/components/WebhooksCard';
 import { api, type AdminWorkshopView, type VMWithWorkshop } from '@/lib/api';
 
 export function Admin() {
@@ -307,6 +310,10 @@ export function Admin() {
               </CardContent>
             </Card>
           )}
+
+          <WebhooksCard workshopId={null} />
+          <LtiPlatformsCard />
+          <UsersCard />
         </div>
       </div>
     </div>
@@ -0,0 +1,70 @@
+import { useEffect, useState } from 'react';
+import { Link, useNavigate } from 'react-router-dom';
+import { Loader2, XCircle } from 'lucide-react';
+import { Card, CardDescription, CardHeader, CardTitle } from '@/components/ui/card';
+import { useAuth } from '@/lib/auth';
+import { api } from '@/lib/api';
+
+// Finishes a single sign-on. The server puts the session tokens in the URL
+// fragment so they never reach a server log. When linking an account it puts
+// the verified account there instead, which the signed-in user confirms.
+export function AuthCallback() {
+  const navigate = useNavigate();
+  const { loginWithToken } = useAuth();
+  const [error, setError] = useState('');
+
+  useEffect(() => {
+    const fragment = new URLSearchParams(window.location.hash.slice(1));
+    const token = fragment.get('token');
+    const link = fragment.get('link');
+    const provider = fragment.get('provider');
+    window.history.replaceState(null, '', window.location.pathname);
+    if (link && provider) {
+      api
+        .completeOIDCLink(provider, link)
+        .then(() => navigate('/account', { replace: true }))
+        .catch((err) => {
+          console.error('Linking account failed:', err);
+          setError(err instanceof Error ? err.message : 'Failed to link account');
+        });
+      return;
+    }
+    if (!token) {
+      setError('The sign-in response was empty');
+      return;
+    }
+    loginWithToken(token, fragment.get('refresh_token') ?? undefined)
+      .then(() => navigate('/dashboard', { replace: true }))
+      .catch((err) => {
+        console.error('Single sign-on failed:', err);
+        setError(err instanceof Error ? err.message : 'Failed to sign in');
+      });
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, []);
+
+  return (
+    <div className="min-h-screen bg-gradient-to-br from-slate-50 to-slate-100 flex items-center justify-center p-4">
+      <Card className="w-full max-w-md">
+        {error ? (
+          <CardHeader className="text-center">
+            <div className="w-16 h-16 bg-red-100 rounded-full flex items-center justify-center mx-auto mb-4">
+              <XCircle className="w-8 h-8 text-red-600" />
+            </div>
+            <CardTitle>Could not sign you in</CardTitle>
+            <CardDescription>
+              {error}.{' '}
+              <Link to="/login" className="text-indigo-600 hover:underline font-medium">
+                Try again
+              </Link>
+            </CardDescription>
+          </CardHeader>
+        ) : (
+          <CardHeader className="text-center">
+            <Loader2 className="w-8 h-8 animate-spin text-indigo-600 mx-auto mb-4" />
+            <CardTitle>Signing you in...</CardTitle>
+          </CardHeader>
+        )}
+      </Card>
+    </div>
+  );
+}
@@ -22,6 +22,8 @@ export function Dashboard() {
     seats: '10',
     api_key: '',
     runtime_type: 'firecracker' as 'firecracker',
+    waitlist: false,
+    starts_at: '',
   });
 
   useEffect(() => {
@@ -58,10 +60,12 @@ export function Dashboard() {
         seats: parseInt(formData.seats),
         api_key: formData.api_key,
         runtime_type: formData.runtime_type,
+        waitlist: formData.waitlist,
+        starts_at: formData.starts_at ? new Date(formData.starts_at).toISOString() : undefined,
       });
       // Close form and reset state immediately
       setShowCreateForm(false);
-      setFormData({ name: '', seats: '10', api_key: '', runtime_type: 'firecracker' });
+      setFormData({ name: '', seats: '10', api_key: '', runtime_type: 'firecracker', waitlist: false, starts_at: '' });
       setCreating(false);
       // Then refresh the list
       await loadWorkshops();
@@ -173,6 +177,24 @@ export function Dashboard() {
                   />
                   <p className="text-sm text-gray-500 mt-1">Each learner gets their own workspace</p>
                 </div>
+                <label className="flex items-center gap-2 text-sm text-gray-700">
+                  <input
+                    type="checkbox"
+                    checked={formData.waitlist}
+                    onChange={(e) => setFormData({ ...formData, waitlist: e.target.checked })}
+                  />
+                  Put registrations on a waitlist once the seats are full
+                </label>
+                <div>
+                  <Label htmlFor="starts_at">Starts At (optional)</Label>
+                  <Input
+                    id="starts_at"
+                    type="datetime-local"
+                    value={formData.starts_at}
+                    onChange={(e) => setFormData({ ...formData, starts_at: e.target.value })}
+                  />
+                  <p className="text-sm text-gray-500 mt-1">Learners are emailed a reminder with their access code beforehand</p>
+                </div>
                 <div>
                   <Label htmlFor="api_key">Anthropic API Key</Label>
                   <Input
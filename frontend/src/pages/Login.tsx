@@ -1,20 +1,29 @@
-import { useState } from 'react';
-import { useNavigate, Link } from 'react-router-dom';
+import { useState, useEffect } from 'react';
+import { useNavigate, Link, useSearchParams } from 'react-router-dom';
 import { LogIn, ArrowLeft, Loader2, GraduationCap } from 'lucide-react';
 import { Button } from '@/components/ui/button';
 import { Card, CardContent, CardDescription, CardHeader, CardTitle } from '@/components/ui/card';
 import { Input } from '@/components/ui/input';
 import { Label } from '@/components/ui/label';
 import { useAuth } from '@/lib/auth';
+import { api, type OIDCProvider } from '@/lib/api';
 
 export function Login() {
   const navigate = useNavigate();
   const { login } = useAuth();
+  const [searchParams] = useSearchParams();
 
   const [email, setEmail] = useState('');
   const [password, setPassword] = useState('');
-  const [error, setError] = useState('');
+  const [error, setError] = useState(searchParams.get('sso_error') || '');
   const [loading, setLoading] = useState(false);
+  const [providers, setProviders] = useState<OIDCProvider[]>([]);
+
+  useEffect(() => {
+    api.listOIDCProviders()
+      .then((data) => setProviders(data.providers))
+      .catch((err) => console.error('Failed to load sign-in providers:', err));
+  }, []);
 
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
@@ -113,6 +122,23 @@ export function Login() {
                 </Button>
               </form>
 
+              {providers.length > 0 && (
+                <div className="mt-6 space-y-2">
+                  <p className="text-center text-sm text-gray-500">or</p>
+                  {providers.map((provider) => (
+                    <Button
+                      key={provider.id}
+                      type="button"
+                      variant="outline"
+                      className="w-full"
+                      onClick={() => { window.location.href = provider.login_url; }}
+                    >
+                      Continue with {provider.name}
+                    </Button>
+                  ))}
+                </div>
+              )}
+
               <div className="mt-6 pt-4 border-t text-center">
                 <p className="text-sm text-gray-500">
                   Don't have an account?{' '}
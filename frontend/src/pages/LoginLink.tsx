@@ -0,0 +1,67 @@
+import { useEffect, useRef, useState } from 'react';
+import { useNavigate, useParams } from 'react-router-dom';
+import { Loader2, XCircle } from 'lucide-react';
+import { Button } from '@/components/ui/button';
+import { Card, CardContent, CardDescription, CardHeader, CardTitle } from '@/components/ui/card';
+import { api } from '@/lib/api';
+
+// Exchanges an emailed one-time login link for the learner's session, then
+// opens their workspace by access code.
+export function LoginLink() {
+  const navigate = useNavigate();
+  const { token } = useParams<{ token: string }>();
+  const [error, setError] = useState('');
+  // Links work once, so the exchange must not run twice (e.g. in StrictMode)
+  const exchanged = useRef(false);
+
+  useEffect(() => {
+    if (!token || exchanged.current) return;
+    exchanged.current = true;
+
+    api.loginWithLink(token)
+      .then((session) => {
+        localStorage.setItem('clarateach_access_code', session.access_code);
+        navigate(`/s/${session.access_code}`, { replace: true });
+      })
+      .catch((err) => {
+        console.error('Failed to log in with link:', err);
+        setError(err instanceof Error ? err.message : 'Failed to log in');
+      });
+  }, [token, navigate]);
+
+  return (
+    <div className="min-h-screen bg-gradient-to-br from-slate-50 to-slate-100 flex items-center justify-center p-4">
+      <div className="w-full max-w-md">
+        <Card>
+          {error ? (
+            <>
+              <CardHeader className="text-center">
+                <div className="w-16 h-16 bg-red-100 rounded-full flex items-center justify-center mx-auto mb-4">
+                  <XCircle className="w-8 h-8 text-red-600" />
+                </div>
+                <CardTitle>Login link not valid</CardTitle>
+                <CardDescription>{error}</CardDescription>
+              </CardHeader>
+              <CardContent className="space-y-3">
+                <p className="text-sm text-gray-600 text-center">
+                  Login links work once and expire after a while. Ask for a new one, or use your access code.
+                </p>
+                <Button className="w-full" onClick={() => navigate('/register')}>
+                  Get a new login link
+                </Button>
+                <Button variant="outline" className="w-full" onClick={() => navigate('/join')}>
+                  Enter access code
+                </Button>
+              </CardContent>
+            </>
+          ) : (
+            <CardHeader className="text-center">
+              <Loader2 className="w-8 h-8 animate-spin text-indigo-600 mx-auto mb-4" />
+              <CardTitle>Logging you in...</CardTitle>
+            </CardHeader>
+          )}
+        </Card>
+      </div>
+    </div>
+  );
+}
@@ -0,0 +1,105 @@
+import { useEffect, useRef, useState } from 'react';
+import { useParams } from 'react-router-dom';
+import { Loader2, XCircle } from 'lucide-react';
+import { Button } from '@/components/ui/button';
+import { Card, CardContent, CardDescription, CardHeader, CardTitle } from '@/components/ui/card';
+import { api } from '@/lib/api';
+import type { Workshop } from '@/lib/types';
+
+// Lets an instructor launched from their LMS pick the workshop a course
+// links to, then posts the signed selection back to the LMS.
+export function LtiDeepLink() {
+  const { token } = useParams<{ token: string }>();
+  const [workshops, setWorkshops] = useState<Workshop[] | null>(null);
+  const [contextTitle, setContextTitle] = useState('');
+  const [error, setError] = useState('');
+  const [linking, setLinking] = useState<string | null>(null);
+  const form = useRef<HTMLFormElement>(null);
+  const [response, setResponse] = useState<{ return_url: string; jwt: string } | null>(null);
+
+  useEffect(() => {
+    if (!token) return;
+    api.listLTIDeepLinkWorkshops(token)
+      .then((data) => {
+        setWorkshops(data.workshops);
+        setContextTitle(data.context_title);
+      })
+      .catch((err) => {
+        console.error('Failed to load workshops:', err);
+        setError(err instanceof Error ? err.message : 'Failed to load workshops');
+      });
+  }, [token]);
+
+  // The LMS expects the response as a form post from the browser
+  useEffect(() => {
+    if (response) form.current?.submit();
+  }, [response]);
+
+  const handleLink = async (workshop: Workshop) => {
+    if (!token) return;
+    setLinking(workshop.id);
+    try {
+      setResponse(await api.completeLTIDeepLink(token, workshop.id));
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Failed to link workshop');
+      setLinking(null);
+    }
+  };
+
+  return (
+    <div className="min-h-screen bg-gradient-to-br from-slate-50 to-slate-100 flex items-center justify-center p-4">
+      <div className="w-full max-w-lg">
+        <Card>
+          {error ? (
+            <CardHeader className="text-center">
+              <div className="w-16 h-16 bg-red-100 rounded-full flex items-center justify-center mx-auto mb-4">
+                <XCircle className="w-8 h-8 text-red-600" />
+              </div>
+              <CardTitle>Could not link a workshop</CardTitle>
+              <CardDescription>{error}</CardDescription>
+            </CardHeader>
+          ) : workshops === null ? (
+            <CardHeader className="text-center">
+              <Loader2 className="w-8 h-8 animate-spin text-indigo-600 mx-auto mb-4" />
+              <CardTitle>Loading your workshops...</CardTitle>
+            </CardHeader>
+          ) : (
+            <>
+              <CardHeader>
+                <CardTitle>Add a workshop{contextTitle && ` to ${contextTitle}`}</CardTitle>
+                <CardDescription>
+                  Students who open the link are registered and taken straight to their seat.
+                </CardDescription>
+              </CardHeader>
+              <CardContent className="space-y-2">
+                {workshops.length === 0 && (
+                  <p className="text-sm text-gray-500">
+                    You have no workshops to link. Create one in ClaraTeach first, then add it from your course again.
+                  </p>
+                )}
+                {workshops.map((workshop) => (
+                  <div key={workshop.id} className="flex items-center justify-between gap-2 p-3 bg-gray-50 rounded-lg">
+                    <div className="min-w-0">
+                      <p className="font-medium text-gray-900 truncate">{workshop.name}</p>
+                      <p className="text-sm text-gray-500">
+                        {workshop.code} · {workshop.seats} seats · {workshop.status}
+                      </p>
+                    </div>
+                    <Button size="sm" disabled={linking !== null} onClick={() => handleLink(workshop)}>
+                      {linking === workshop.id ? <Loader2 className="w-4 h-4 animate-spin" /> : 'Link'}
+                    </Button>
+                  </div>
+                ))}
+              </CardContent>
+            </>
+          )}
+        </Card>
+        {response && (
+          <form ref={form} method="POST" action={response.return_url} className="hidden">
+            <input type="hidden" name="JWT" value={response.jwt} />
+          </form>
+        )}
+      </div>
+    </div>
+  );
+}
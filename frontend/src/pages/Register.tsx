@@ -1,6 +1,6 @@
 import { useState } from 'react';
 import { useNavigate, useSearchParams, Link } from 'react-router-dom';
-import { UserPlus, ArrowLeft, Loader2, GraduationCap } from 'lucide-react';
+import { UserPlus, ArrowLeft, Loader2, GraduationCap, Mail } from 'lucide-react';
 import { Button } from '@/components/ui/button';
 import { Card, CardContent, CardDescription, CardHeader, CardTitle } from '@/components/ui/card';
 import { Input } from '@/components/ui/input';
@@ -17,6 +17,8 @@ export function Register() {
   const [name, setName] = useState('');
   const [error, setError] = useState('');
   const [registering, setRegistering] = useState(false);
+  const [sendingLink, setSendingLink] = useState(false);
+  const [linkMessage, setLinkMessage] = useState('');
 
   const handleRegister = async (e: React.FormEvent) => {
     e.preventDefault();
@@ -49,7 +51,10 @@ export function Register() {
 
       // Navigate to registered page with the code
       navigate(`/registered/${response.access_code}`, {
-        state: { alreadyRegistered: response.already_registered }
+        state: {
+          alreadyRegistered: response.already_registered,
+          waitlistPosition: response.waitlisted ? response.waitlist_position : undefined,
+        }
       });
     } catch (err) {
       console.error('Failed to register:', err);
@@ -59,6 +64,31 @@ export function Register() {
     }
   };
 
+  // Registered learners who lost their access code can get a login link instead
+  const handleSendLoginLink = async () => {
+    setError('');
+    setLinkMessage('');
+
+    if (!workshopCode.trim() || !email.trim()) {
+      setError('Please enter the workshop code and your email');
+      return;
+    }
+
+    setSendingLink(true);
+    try {
+      const response = await api.requestLoginLink({
+        workshop_code: workshopCode.toUpperCase(),
+        email: email.trim(),
+      });
+      setLinkMessage(response.message);
+    } catch (err) {
+      console.error('Failed to request login link:', err);
+      setError(err instanceof Error ? err.message : 'Failed to request login link');
+    } finally {
+      setSendingLink(false);
+    }
+  };
+
   const formatWorkshopCode = (value: string) => {
     const cleaned = value.replace(/[^A-Za-z0-9]/g, '').toUpperCase();
     if (cleaned.length > 5) {
@@ -154,6 +184,27 @@ export function Register() {
                   </>
                 )}
               </Button>
+
+              <Button
+                type="button"
+                variant="outline"
+                className="w-full"
+                onClick={handleSendLoginLink}
+                disabled={sendingLink}
+              >
+                {sendingLink ? (
+                  <Loader2 className="w-4 h-4 mr-2 animate-spin" />
+                ) : (
+                  <Mail className="w-4 h-4 mr-2" />
+                )}
+                Already registered? Email me a login link
+              </Button>
+
+              {linkMessage && (
+                <div className="p-3 bg-green-50 border border-green-200 rounded-lg">
+                  <p className="text-sm text-green-700">{linkMessage}</p>
+                </div>
+              )}
             </form>
 
             <div className="mt-6 p-4 bg-green-50 rounded-lg">
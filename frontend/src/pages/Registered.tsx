@@ -8,7 +8,8 @@ export function Registered() {
   const navigate = useNavigate();
   const { code } = useParams<{ code: string }>();
   const location = useLocation();
-  const alreadyRegistered = (location.state as { alreadyRegistered?: boolean })?.alreadyRegistered;
+  const { alreadyRegistered, waitlistPosition } =
+    (location.state as { alreadyRegistered?: boolean; waitlistPosition?: number }) ?? {};
 
   const [copied, setCopied] = useState(false);
 
@@ -42,6 +43,12 @@ export function Registered() {
                 ? 'You were already registered. Here\'s your access code.'
                 : 'Save your access code to join anytime.'}
             </CardDescription>
+            {waitlistPosition ? (
+              <p className="text-sm text-amber-700 mt-2">
+                The workshop is full. You are #{waitlistPosition} on the waitlist; this code will open
+                your workspace once a seat frees up.
+              </p>
+            ) : null}
           </CardHeader>
           <CardContent className="space-y-6">
             {/* Access Code Display */}
@@ -4,8 +4,8 @@ import { Panel, PanelGroup, PanelResizeHandle } from 'react-resizable-panels';
 import { Terminal } from '@/components/Terminal';
 import { Editor } from '@/components/Editor';
 import { Browser } from '@/components/Browser';
-import { User, LogOut, Loader2, AlertCircle, RefreshCw } from 'lucide-react';
-import { api, SessionResponse } from '@/lib/api';
+import { User, LogOut, Loader2, AlertCircle, RefreshCw, Megaphone, X, Hand } from 'lucide-react';
+import { api, Announcement, SessionHelpResponse, SessionResponse } from '@/lib/api';
 import { setWorkspaceSession } from '@/lib/workspaceSession';
 import { Button } from '@/components/ui/button';
 import { Card, CardContent, CardDescription, CardHeader, CardTitle } from '@/components/ui/card';
@@ -20,6 +20,28 @@ export function SessionWorkspace() {
   const [error, setError] = useState<string>('');
   const [session, setSession] = useState<SessionResponse | null>(null);
   const [isNarrow, setIsNarrow] = useState(false);
+  const [announcement, setAnnouncement] = useState<Announcement | null>(null);
+  const [help, setHelp] = useState<SessionHelpResponse>({ help_request: null });
+
+  const applySession = useCallback((response: SessionResponse) => {
+    if (response.status === 'ready') {
+      // Store in workspace session for components to use
+      setWorkspaceSession({
+        endpoint: response.endpoint!,
+        seat: response.seat!,
+        token: response.token, // JWT token for WebSocket authentication
+        code,
+        name: response.name,
+        workshop_id: response.workshop_id,
+        runtime_type: response.runtime_type,
+      });
+      setSession(response);
+      setStatus('ready');
+    } else {
+      setStatus('pending');
+      setSession(response);
+    }
+  }, [code]);
 
   const fetchSession = useCallback(async () => {
     if (!code) {
@@ -29,24 +51,7 @@ export function SessionWorkspace() {
     }
 
     try {
-      const response = await api.getSession(code);
-
-      if (response.status === 'pending') {
-        setStatus('pending');
-        setSession(response);
-      } else if (response.status === 'ready') {
-        // Store in workspace session for components to use
-        setWorkspaceSession({
-          endpoint: response.endpoint!,
-          seat: response.seat!,
-          token: response.token, // JWT token for WebSocket authentication
-          name: response.name,
-          workshop_id: response.workshop_id,
-          runtime_type: response.runtime_type,
-        });
-        setSession(response);
-        setStatus('ready');
-      }
+      applySession(await api.getSession(code));
     } catch (err) {
       console.error('Failed to fetch session:', err);
       const message = err instanceof Error ? err.message : 'Failed to load session';
@@ -62,23 +67,80 @@ export function SessionWorkspace() {
         setError(message);
       }
     }
-  }, [code]);
+  }, [code, applySession]);
 
   // Initial load
   useEffect(() => {
     fetchSession();
   }, [fetchSession]);
 
-  // Poll while pending
+  // Stream updates while pending, falling back to polling if the stream fails
+  const [streamFailed, setStreamFailed] = useState(false);
   useEffect(() => {
-    if (status !== 'pending') return;
+    if (status !== 'pending' || !code) return;
+
+    if (streamFailed || typeof EventSource === 'undefined') {
+      const interval = setInterval(() => {
+        fetchSession();
+      }, 5000); // Check every 5 seconds
+      return () => clearInterval(interval);
+    }
 
-    const interval = setInterval(() => {
-      fetchSession();
-    }, 5000); // Check every 5 seconds
+    const source = new EventSource(api.sessionStreamUrl(code));
+    const onState = (e: MessageEvent) => applySession(JSON.parse(e.data));
+    source.addEventListener('waitlisted', onState);
+    source.addEventListener('pending', onState);
+    source.addEventListener('seat_assigned', onState);
+    source.addEventListener('ready', (e) => {
+      source.close();
+      onState(e as MessageEvent);
+    });
+    source.addEventListener('ended', () => {
+      source.close();
+      fetchSession(); // Reports the ended state
+    });
+    source.onerror = () => {
+      source.close();
+      setStreamFailed(true);
+    };
+    return () => source.close();
+  }, [status, code, streamFailed, fetchSession, applySession]);
 
+  // Show the latest instructor announcement while in the workspace
+  useEffect(() => {
+    if (status !== 'ready' || !code || typeof EventSource === 'undefined') return;
+
+    const source = new EventSource(api.sessionAnnouncementsStreamUrl(code));
+    source.addEventListener('snapshot', (e) => {
+      const announcements: Announcement[] = JSON.parse((e as MessageEvent).data).data.announcements;
+      if (announcements.length > 0) setAnnouncement(announcements[announcements.length - 1]);
+    });
+    source.addEventListener('announcement', (e) => {
+      setAnnouncement(JSON.parse((e as MessageEvent).data).data);
+    });
+    return () => source.close();
+  }, [status, code]);
+
+  // Keep the learner's help request status current while their hand is up
+  useEffect(() => {
+    if (status !== 'ready' || !code) return;
+
+    const refresh = () => api.getSessionHelp(code).then(setHelp).catch(() => {});
+    refresh();
+    if (!help.help_request) return;
+    const interval = setInterval(refresh, 10000);
     return () => clearInterval(interval);
-  }, [status, fetchSession]);
+  }, [status, code, help.help_request?.id]);
+
+  const handleToggleHand = async () => {
+    if (!code) return;
+    try {
+      setHelp(help.help_request ? await api.lowerHand(code) : await api.raiseHand(code));
+    } catch (err) {
+      console.error('Failed to update help request:', err);
+      alert(err instanceof Error ? err.message : 'Failed to update help request');
+    }
+  };
 
   // Responsive layout
   useEffect(() => {
@@ -112,7 +174,7 @@ export function SessionWorkspace() {
     );
   }
 
-  // Pending state (workshop starting)
+  // Pending state (waitlisted or workshop starting)
   if (status === 'pending') {
     return (
       <div className="min-h-screen bg-gradient-to-br from-indigo-50 to-blue-100 flex items-center justify-center p-4">
@@ -121,12 +183,28 @@ export function SessionWorkspace() {
             <div className="w-16 h-16 bg-indigo-100 rounded-full flex items-center justify-center mx-auto mb-4">
               <RefreshCw className="w-8 h-8 text-indigo-600 animate-spin" />
             </div>
-            <CardTitle>Workshop Starting</CardTitle>
+            <CardTitle>{session?.status === 'waitlisted' ? 'On the Waitlist' : 'Workshop Starting'}</CardTitle>
             <CardDescription>
               {session?.message || 'Please wait while the workshop is being prepared...'}
             </CardDescription>
           </CardHeader>
           <CardContent className="text-center">
+            {session?.status === 'seat_assigned' ? (
+              <p className="text-sm text-gray-700 mb-2">Seat {session.seat} assigned. Connecting...</p>
+            ) : session?.status === 'waitlisted' ? (
+              <p className="text-sm text-gray-700 mb-2">You are #{session.waitlist_position} on the waitlist.</p>
+            ) : (
+              <>
+                {session?.queue_position ? (
+                  <p className="text-sm text-gray-700 mb-2">You are #{session.queue_position} in line for a seat.</p>
+                ) : null}
+                {session?.seats_total ? (
+                  <p className="text-sm text-gray-700 mb-2">
+                    {session.seats_ready ?? 0} of {session.seats_total} seats ready
+                  </p>
+                ) : null}
+              </>
+            )}
             <p className="text-sm text-gray-500 mb-4">
               This page will refresh automatically when ready.
             </p>
@@ -180,6 +258,23 @@ export function SessionWorkspace() {
             <User className="w-4 h-4" />
             <span className="text-sm">{session?.name || 'Learner'}</span>
           </div>
+          {help.help_request?.status === 'claimed' && (
+            <span className="text-sm text-green-400">
+              {help.help_request.claimed_by_name || 'An instructor'} is on the way
+            </span>
+          )}
+          {help.help_request?.status === 'open' && help.position ? (
+            <span className="text-sm text-gray-400">#{help.position} in the help queue</span>
+          ) : null}
+          <button
+            onClick={handleToggleHand}
+            className={`flex items-center gap-2 transition-colors ${
+              help.help_request ? 'text-yellow-400 hover:text-yellow-300' : 'text-gray-300 hover:text-white'
+            }`}
+          >
+            <Hand className="w-4 h-4" />
+            <span className="text-sm">{help.help_request ? 'Lower hand' : 'Raise hand'}</span>
+          </button>
           <button
             onClick={handleLeave}
             className="flex items-center gap-2 text-gray-300 hover:text-white transition-colors"
@@ -190,6 +285,23 @@ export function SessionWorkspace() {
         </div>
       </header>
 
+      {/* Announcement */}
+      {announcement && (
+        <div className="bg-indigo-600 text-white px-4 py-2 flex items-start gap-3 flex-shrink-0">
+          <Megaphone className="w-4 h-4 mt-0.5 flex-shrink-0" />
+          <p className="text-sm flex-1 whitespace-pre-wrap">
+            <span className="font-semibold">{announcement.author_name || 'Instructor'}:</span> {announcement.message}
+          </p>
+          <button
+            onClick={() => setAnnouncement(null)}
+            className="text-indigo-100 hover:text-white"
+            aria-label="Dismiss announcement"
+          >
+            <X className="w-4 h-4" />
+          </button>
+        </div>
+      )}
+
       {/* Main Content */}
       <div className="flex-1 overflow-hidden">
         <PanelGroup direction={isNarrow ? 'vertical' : 'horizontal'}>
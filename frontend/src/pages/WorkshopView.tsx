@@ -1,10 +1,11 @@
 import { useState, useEffect } from 'react';
 import { useParams, useNavigate } from 'react-router-dom';
-import { Users, Copy, Check, ArrowLeft, StopCircle, RefreshCw } from 'lucide-react';
+import { Users, Copy, Check, ArrowLeft, StopCircle, RefreshCw, Megaphone, Hand, Upload, Download, UserX, ArrowRightLeft, ChevronUp, ChevronDown, Mail, KeyRound } from 'lucide-react';
 import { Button } from '@/components/ui/button';
 import { Card, CardContent, CardHeader, CardTitle } from '@/components/ui/card';
 import { Layout } from '@/components/Layout';
-import { api } from '@/lib/api';
+import { WebhooksCard } from '@/components/WebhooksCard';
+import { api, HelpRequest, NotificationTemplate, Registration, RosterImportResponse, SentNotification } from '@/lib/api';
 import type { Workshop, Session } from '@/lib/types';
 
 export function WorkshopView() {
@@ -16,6 +17,16 @@ export function WorkshopView() {
   const [copied, setCopied] = useState(false);
   const [loading, setLoading] = useState(true);
   const [stopping, setStopping] = useState(false);
+  const [announcement, setAnnouncement] = useState('');
+  const [announcing, setAnnouncing] = useState(false);
+  const [helpRequests, setHelpRequests] = useState<HelpRequest[]>([]);
+  const [rosterResult, setRosterResult] = useState<RosterImportResponse | null>(null);
+  const [registrations, setRegistrations] = useState<Registration[]>([]);
+  const [waitlist, setWaitlist] = useState<Registration[]>([]);
+  const [emailsEnabled, setEmailsEnabled] = useState(false);
+  const [templates, setTemplates] = useState<NotificationTemplate[]>([]);
+  const [editingTemplate, setEditingTemplate] = useState<NotificationTemplate | null>(null);
+  const [notifications, setNotifications] = useState<SentNotification[]>([]);
 
   useEffect(() => {
     if (id) {
@@ -30,6 +41,7 @@ export function WorkshopView() {
       const data = await api.getWorkshop(id!);
       setWorkshop(data.workshop);
       loadLearners();
+      loadTemplates();
     } catch (err) {
       console.error('Failed to load workshop:', err);
       navigate('/dashboard');
@@ -43,11 +55,171 @@ export function WorkshopView() {
       const data = await api.getWorkshopLearners(id!);
       setLearners(data.learners);
       setConnectedCount(typeof data.connected === 'number' ? data.connected : data.learners.length);
+      loadHelpRequests();
+      loadRegistrations();
     } catch (err) {
       console.error('Failed to load learners:', err);
     }
   };
 
+  const loadHelpRequests = async () => {
+    try {
+      const data = await api.listHelpRequests(id!);
+      setHelpRequests(data.help_requests);
+    } catch (err) {
+      console.error('Failed to load help requests:', err);
+    }
+  };
+
+  const handleHelpRequest = async (request: HelpRequest) => {
+    try {
+      if (request.status === 'open') {
+        await api.claimHelpRequest(id!, request.id);
+      } else {
+        await api.resolveHelpRequest(id!, request.id);
+      }
+    } catch (err) {
+      alert(err instanceof Error ? err.message : 'Failed to update help request');
+    }
+    loadHelpRequests();
+  };
+
+  const loadRegistrations = async () => {
+    try {
+      const data = await api.listRegistrations(id!);
+      setRegistrations(data.registrations);
+      setWaitlist(
+        data.registrations
+          .filter((r) => r.status === 'waitlisted')
+          .sort((a, b) => (a.waitlist_position ?? 0) - (b.waitlist_position ?? 0))
+      );
+    } catch (err) {
+      console.error('Failed to load registrations:', err);
+    }
+    try {
+      const data = await api.listNotifications(id!);
+      setNotifications(data.notifications);
+    } catch (err) {
+      console.error('Failed to load notifications:', err);
+    }
+  };
+
+  const loadTemplates = async () => {
+    try {
+      const data = await api.listNotificationTemplates(id!);
+      setEmailsEnabled(data.enabled);
+      setTemplates(data.templates);
+    } catch (err) {
+      console.error('Failed to load email templates:', err);
+    }
+  };
+
+  const handleSaveTemplate = async (e: React.FormEvent) => {
+    e.preventDefault();
+    if (!editingTemplate) return;
+    try {
+      await api.setNotificationTemplate(id!, editingTemplate.kind, {
+        subject: editingTemplate.subject,
+        body: editingTemplate.body,
+      });
+      setEditingTemplate(null);
+      loadTemplates();
+    } catch (err) {
+      alert(err instanceof Error ? err.message : 'Failed to save template');
+    }
+  };
+
+  const handleResetTemplate = async (template: NotificationTemplate) => {
+    if (!confirm(`Go back to the built-in ${template.kind.replace('_', ' ')} email?`)) return;
+    try {
+      await api.resetNotificationTemplate(id!, template.kind);
+      setEditingTemplate(null);
+      loadTemplates();
+    } catch (err) {
+      alert(err instanceof Error ? err.message : 'Failed to reset template');
+    }
+  };
+
+  const handleMoveWaitlisted = async (index: number, offset: number) => {
+    const order = waitlist.map((r) => r.id);
+    [order[index], order[index + offset]] = [order[index + offset], order[index]];
+    try {
+      const data = await api.reorderWaitlist(id!, order);
+      setWaitlist(data.waitlist);
+    } catch (err) {
+      alert(err instanceof Error ? err.message : 'Failed to reorder waitlist');
+      loadRegistrations();
+    }
+  };
+
+  // Offers to wipe a seat a learner just left, so the next learner starts clean
+  const offerSeatReset = async (seat: number | null) => {
+    if (seat === null || workshop?.status !== 'running') return;
+    if (!confirm(`Reset seat ${seat} to a clean workspace?`)) return;
+    try {
+      await api.resetSeat(id!, seat);
+    } catch (err) {
+      alert(err instanceof Error ? err.message : 'Failed to reset seat');
+    }
+  };
+
+  const handleRevokeRegistration = async (registration: Registration) => {
+    if (!confirm(`Revoke ${registration.name}'s registration? Their access code will stop working.`)) return;
+    try {
+      await api.revokeRegistration(id!, registration.id);
+      if (registration.joined_at) {
+        await offerSeatReset(registration.seat_id);
+      }
+    } catch (err) {
+      alert(err instanceof Error ? err.message : 'Failed to revoke registration');
+    }
+    loadLearners();
+  };
+
+  const handleMoveRegistration = async (registration: Registration) => {
+    const input = prompt(`Move ${registration.name} to seat (1-${workshop?.seats}):`);
+    const seat = Number(input);
+    if (!input || !Number.isInteger(seat)) return;
+    try {
+      await api.moveRegistration(id!, registration.id, seat);
+      if (registration.joined_at) {
+        await offerSeatReset(registration.seat_id);
+      }
+    } catch (err) {
+      alert(err instanceof Error ? err.message : 'Failed to move learner');
+    }
+    loadLearners();
+  };
+
+  const handleSendLoginLink = async (registration: Registration) => {
+    try {
+      const { login_url, emailed } = await api.sendLoginLink(id!, registration.id);
+      if (emailed) {
+        alert(`Login link emailed to ${registration.email}.`);
+      } else {
+        // Emails are off, so staff pass the link on themselves
+        const url = login_url.startsWith('/') ? window.location.origin + login_url : login_url;
+        await navigator.clipboard.writeText(url);
+        alert('Login link copied to the clipboard. It works once.');
+      }
+    } catch (err) {
+      alert(err instanceof Error ? err.message : 'Failed to send login link');
+    }
+  };
+
+  const handleRotateCode = async (registration: Registration) => {
+    if (!confirm(`Give ${registration.name} a new access code? The old code and any login links stop working; they keep their seat.`)) return;
+    try {
+      const { registration: updated, emailed } = await api.rotateAccessCode(id!, registration.id);
+      alert(emailed
+        ? `New access code ${updated.access_code} emailed to ${registration.email}.`
+        : `New access code: ${updated.access_code}`);
+    } catch (err) {
+      alert(err instanceof Error ? err.message : 'Failed to rotate access code');
+    }
+    loadLearners();
+  };
+
   const handleCopyCode = () => {
     if (workshop) {
       navigator.clipboard.writeText(workshop.code);
@@ -70,6 +242,47 @@ export function WorkshopView() {
     }
   };
 
+  const handleAnnounce = async (e: React.FormEvent) => {
+    e.preventDefault();
+    if (!announcement.trim()) return;
+
+    setAnnouncing(true);
+    try {
+      await api.createAnnouncement(id!, announcement.trim());
+      setAnnouncement('');
+    } catch (err) {
+      console.error('Failed to send announcement:', err);
+      alert(err instanceof Error ? err.message : 'Failed to send announcement');
+    } finally {
+      setAnnouncing(false);
+    }
+  };
+
+  const handleImportRoster = async (e: React.ChangeEvent<HTMLInputElement>) => {
+    const file = e.target.files?.[0];
+    e.target.value = '';
+    if (!file) return;
+
+    try {
+      setRosterResult(await api.importRoster(id!, await file.text()));
+    } catch (err) {
+      setRosterResult({ error: err instanceof Error ? err.message : 'Failed to import roster' });
+    }
+  };
+
+  const handleExportRoster = async () => {
+    try {
+      const url = URL.createObjectURL(await api.exportRoster(id!));
+      const link = document.createElement('a');
+      link.href = url;
+      link.download = `${workshop?.code ?? id}-roster.csv`;
+      link.click();
+      URL.revokeObjectURL(url);
+    } catch (err) {
+      alert(err instanceof Error ? err.message : 'Failed to export roster');
+    }
+  };
+
   const getTimeSince = (dateStr: string) => {
     const date = new Date(dateStr);
     const minutes = Math.floor((Date.now() - date.getTime()) / 1000 / 60);
@@ -197,10 +410,111 @@ export function WorkshopView() {
                 </div>
               </CardContent>
             </Card>
+
+            <Card>
+              <CardHeader>
+                <div className="flex items-center gap-2">
+                  <Megaphone className="w-5 h-5" />
+                  <CardTitle>Announce</CardTitle>
+                </div>
+              </CardHeader>
+              <CardContent>
+                <form onSubmit={handleAnnounce} className="space-y-3">
+                  <textarea
+                    value={announcement}
+                    onChange={(e) => setAnnouncement(e.target.value)}
+                    maxLength={1000}
+                    rows={3}
+                    placeholder="Shown to every learner in their workspace"
+                    className="w-full rounded-md border border-gray-300 px-3 py-2 text-sm focus:outline-none focus:ring-2 focus:ring-indigo-500"
+                  />
+                  <Button type="submit" className="w-full" disabled={announcing || !announcement.trim()}>
+                    {announcing ? 'Sending...' : 'Send to learners'}
+                  </Button>
+                </form>
+              </CardContent>
+            </Card>
+
+            <Card>
+              <CardHeader>
+                <CardTitle>Roster</CardTitle>
+              </CardHeader>
+              <CardContent className="space-y-3">
+                <p className="text-sm text-gray-600">
+                  Register learners from a CSV with <code>name</code>, <code>email</code> and an optional <code>seat</code> column.
+                </p>
+                <div className="flex gap-2">
+                  <label className="flex-1">
+                    <input type="file" accept=".csv,text/csv" className="hidden" onChange={handleImportRoster} />
+                    <span className="inline-flex w-full items-center justify-center rounded-md border border-gray-300 px-3 py-2 text-sm cursor-pointer hover:bg-gray-50">
+                      <Upload className="w-4 h-4 mr-2" />
+                      Import CSV
+                    </span>
+                  </label>
+                  <Button variant="outline" className="flex-1" onClick={handleExportRoster}>
+                    <Download className="w-4 h-4 mr-2" />
+                    Export CSV
+                  </Button>
+                </div>
+                {rosterResult?.imported !== undefined && (
+                  <p className="text-sm text-green-700">Registered {rosterResult.imported} learners.</p>
+                )}
+                {rosterResult?.error && (
+                  <div className="text-sm text-red-700 space-y-1">
+                    <p>{rosterResult.error}</p>
+                    {rosterResult.errors?.map((e) => (
+                      <p key={e.row}>Row {e.row}: {e.error}</p>
+                    ))}
+                  </div>
+                )}
+              </CardContent>
+            </Card>
           </div>
 
           {/* Learners List */}
           <div className="lg:col-span-2">
+            {helpRequests.length > 0 && (
+              <Card className="mb-6">
+                <CardHeader>
+                  <div className="flex items-center gap-2">
+                    <Hand className="w-5 h-5 text-yellow-500" />
+                    <CardTitle>Help Queue</CardTitle>
+                  </div>
+                </CardHeader>
+                <CardContent>
+                  <div className="space-y-2">
+                    {helpRequests.map((request) => (
+                      <div
+                        key={request.id}
+                        className="flex flex-col gap-3 sm:flex-row sm:items-center sm:justify-between p-4 bg-yellow-50 rounded-lg"
+                      >
+                        <div className="flex items-center gap-3">
+                          <div className="w-8 h-8 rounded-full bg-yellow-100 flex items-center justify-center text-yellow-700 font-medium">
+                            {request.seat_id}
+                          </div>
+                          <div>
+                            <p className="text-gray-900">{request.learner_name || `Learner ${request.seat_id}`}</p>
+                            {request.message && <p className="text-sm text-gray-600">{request.message}</p>}
+                            <p className="text-sm text-gray-500">
+                              {request.status === 'claimed'
+                                ? `${request.claimed_by_name || 'Staff'} is helping`
+                                : `Waiting since ${getTimeSince(request.created_at)}`}
+                            </p>
+                          </div>
+                        </div>
+                        <Button
+                          size="sm"
+                          variant={request.status === 'open' ? 'default' : 'outline'}
+                          onClick={() => handleHelpRequest(request)}
+                        >
+                          {request.status === 'open' ? 'Claim' : 'Resolve'}
+                        </Button>
+                      </div>
+                    ))}
+                  </div>
+                </CardContent>
+              </Card>
+            )}
             <Card>
               <CardHeader>
                 <div className="flex items-center justify-between">
@@ -246,6 +560,164 @@ export function WorkshopView() {
                 )}
               </CardContent>
             </Card>
+            {waitlist.length > 0 && (
+              <Card className="mt-6">
+                <CardHeader>
+                  <CardTitle>Waitlist</CardTitle>
+                </CardHeader>
+                <CardContent>
+                  <p className="text-sm text-gray-600 mb-3">
+                    Learners get a seat in this order when one frees up.
+                  </p>
+                  <div className="space-y-2">
+                    {waitlist.map((registration, index) => (
+                      <div key={registration.id} className="flex items-center justify-between p-3 bg-gray-50 rounded-lg">
+                        <div className="flex items-center gap-3">
+                          <span className="w-6 text-right text-gray-500">{index + 1}.</span>
+                          <div>
+                            <p className="text-gray-900">{registration.name}</p>
+                            <p className="text-sm text-gray-500">{registration.email}</p>
+                          </div>
+                        </div>
+                        <div className="flex gap-1">
+                          <Button size="icon" variant="ghost" disabled={index === 0} onClick={() => handleMoveWaitlisted(index, -1)}>
+                            <ChevronUp className="w-4 h-4" />
+                          </Button>
+                          <Button size="icon" variant="ghost" disabled={index === waitlist.length - 1} onClick={() => handleMoveWaitlisted(index, 1)}>
+                            <ChevronDown className="w-4 h-4" />
+                          </Button>
+                          <Button size="icon" variant="ghost" onClick={() => handleRevokeRegistration(registration)}>
+                            <UserX className="w-4 h-4" />
+                          </Button>
+                        </div>
+                      </div>
+                    ))}
+                  </div>
+                </CardContent>
+              </Card>
+            )}
+            {registrations.length > 0 && (
+              <Card className="mt-6">
+                <CardHeader>
+                  <CardTitle>Registrations</CardTitle>
+                </CardHeader>
+                <CardContent>
+                  <div className="space-y-2">
+                    {registrations.map((registration) => (
+                      <div
+                        key={registration.id}
+                        className="flex flex-col gap-3 sm:flex-row sm:items-center sm:justify-between p-4 bg-gray-50 rounded-lg"
+                      >
+                        <div>
+                          <p className={registration.status === 'revoked' ? 'text-gray-400 line-through' : 'text-gray-900'}>
+                            {registration.name}
+                          </p>
+                          <p className="text-sm text-gray-500">
+                            {registration.email} · <span className="font-mono">{registration.access_code}</span>
+                            {registration.seat_id !== null && ` · Seat ${registration.seat_id}`}
+                            {` · ${registration.status}`}
+                          </p>
+                        </div>
+                        {registration.status !== 'revoked' && registration.status !== 'waitlisted' && (
+                          <div className="flex gap-2">
+                            <Button size="sm" variant="outline" onClick={() => handleMoveRegistration(registration)}>
+                              <ArrowRightLeft className="w-4 h-4 mr-1" />
+                              Move
+                            </Button>
+                            <Button size="sm" variant="outline" onClick={() => handleSendLoginLink(registration)}>
+                              <Mail className="w-4 h-4 mr-1" />
+                              Login link
+                            </Button>
+                            <Button size="sm" variant="outline" onClick={() => handleRotateCode(registration)}>
+                              <KeyRound className="w-4 h-4 mr-1" />
+                              New code
+                            </Button>
+                            <Button size="sm" variant="outline" onClick={() => handleRevokeRegistration(registration)}>
+                              <UserX className="w-4 h-4 mr-1" />
+                              Revoke
+                            </Button>
+                          </div>
+                        )}
+                      </div>
+                    ))}
+                  </div>
+                </CardContent>
+              </Card>
+            )}
+            {emailsEnabled && (
+              <Card className="mt-6">
+                <CardHeader>
+                  <div className="flex items-center gap-2">
+                    <Mail className="w-5 h-5" />
+                    <CardTitle>Learner Emails</CardTitle>
+                  </div>
+                </CardHeader>
+                <CardContent className="space-y-4">
+                  {editingTemplate ? (
+                    <form onSubmit={handleSaveTemplate} className="space-y-3">
+                      <p className="text-sm text-gray-600">
+                        Editing the {editingTemplate.kind.replace('_', ' ')} email. Use fields such as{' '}
+                        <code>{'{{.LearnerName}}'}</code>, <code>{'{{.AccessCode}}'}</code> and <code>{'{{.AccessURL}}'}</code>.
+                      </p>
+                      <input
+                        value={editingTemplate.subject}
+                        onChange={(e) => setEditingTemplate({ ...editingTemplate, subject: e.target.value })}
+                        className="w-full rounded-md border border-gray-300 px-3 py-2 text-sm focus:outline-none focus:ring-2 focus:ring-indigo-500"
+                      />
+                      <textarea
+                        value={editingTemplate.body}
+                        onChange={(e) => setEditingTemplate({ ...editingTemplate, body: e.target.value })}
+                        rows={10}
+                        className="w-full rounded-md border border-gray-300 px-3 py-2 font-mono text-sm focus:outline-none focus:ring-2 focus:ring-indigo-500"
+                      />
+                      <div className="flex gap-2">
+                        <Button type="submit">Save</Button>
+                        <Button type="button" variant="outline" onClick={() => setEditingTemplate(null)}>
+                          Cancel
+                        </Button>
+                      </div>
+                    </form>
+                  ) : (
+                    <div className="space-y-2">
+                      {templates.map((template) => (
+                        <div key={template.kind} className="flex items-center justify-between p-3 bg-gray-50 rounded-lg">
+                          <div>
+                            <p className="text-gray-900 capitalize">{template.kind.replace('_', ' ')}</p>
+                            <p className="text-sm text-gray-500">{template.custom ? 'Customized' : 'Built-in'}</p>
+                          </div>
+                          <div className="flex gap-2">
+                            <Button size="sm" variant="outline" onClick={() => setEditingTemplate(template)}>
+                              Edit
+                            </Button>
+                            {template.custom && (
+                              <Button size="sm" variant="outline" onClick={() => handleResetTemplate(template)}>
+                                Reset
+                              </Button>
+                            )}
+                          </div>
+                        </div>
+                      ))}
+                    </div>
+                  )}
+                  {notifications.some((n) => n.status === 'failed') && (
+                    <div className="text-sm text-red-600">
+                      {notifications
+                        .filter((n) => n.status === 'failed')
+                        .map((n) => (
+                          <p key={`${n.registration_id}-${n.kind}`}>
+                            {n.kind.replace('_', ' ')} email to {n.recipient} failed: {n.error}
+                          </p>
+                        ))}
+                    </div>
+                  )}
+                  <p className="text-sm text-gray-500">
+                    {notifications.filter((n) => n.status === 'sent').length} emails sent
+                  </p>
+                </CardContent>
+              </Card>
+            )}
+
+            <WebhooksCard workshopId={id!} />
           </div>
         </div>
       </div>